@@ -1,6 +1,10 @@
 package auth
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"errors"
 	"time"
 
@@ -29,20 +33,34 @@ func CheckPasswordHash(password, hash string) bool {
 	return err == nil
 }
 
-// Claims represents the JWT claims
+// Claims represents the JWT claims. ArtistID is the artist an admin with
+// the artist role is linked to, and SessionID the admin session the token
+// was issued for. The token's ID (jti) is what gets revoked. SetupOnly
+// marks the token of an admin who must set up two-factor authentication
+// before doing anything else.
 type Claims struct {
-	AdminID string `json:"admin_id"`
-	Email   string `json:"email"`
+	AdminID   string `json:"admin_id"`
+	Email     string `json:"email"`
+	Role      string `json:"role"`
+	ArtistID  *int   `json:"artist_id,omitempty"`
+	SessionID string `json:"sid"`
+	SetupOnly bool   `json:"setup_only,omitempty"`
 	jwt.RegisteredClaims
 }
 
-// GenerateToken generates a JWT token for admin authentication
-func GenerateToken(adminID, email string) (string, error) {
+// GenerateToken generates a JWT access token for a session of an admin,
+// with the ID tokenID, expiring at expiresAt.
+func GenerateToken(adminID, email, role string, artistID *int, sessionID, tokenID string, setupOnly bool, expiresAt time.Time) (string, error) {
 	claims := Claims{
-		AdminID: adminID,
-		Email:   email,
+		AdminID:   adminID,
+		Email:     email,
+		Role:      role,
+		ArtistID:  artistID,
+		SessionID: sessionID,
+		SetupOnly: setupOnly,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)),
+			ID:        tokenID,
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 			NotBefore: jwt.NewNumericDate(time.Now()),
 		},
@@ -52,7 +70,17 @@ func GenerateToken(adminID, email string) (string, error) {
 	return token.SignedString(jwtSecret)
 }
 
-// ValidateToken validates a JWT token and returns the claims
+// NewTokenID returns a random ID for an access token.
+func NewTokenID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// ValidateToken validates a JWT token and returns the claims. Tokens with
+// an audience, such as challenge tokens, are refused.
 func ValidateToken(tokenString string) (*Claims, error) {
 	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
 		return jwtSecret, nil
@@ -62,9 +90,26 @@ func ValidateToken(tokenString string) (*Claims, error) {
 		return nil, err
 	}
 
-	if claims, ok := token.Claims.(*Claims); ok && token.Valid {
+	if claims, ok := token.Claims.(*Claims); ok && token.Valid && len(claims.Audience) == 0 {
 		return claims, nil
 	}
 
 	return nil, errors.New("invalid token")
 }
+
+// NewOpaqueToken returns a random, URL-safe token for links handed to
+// customers, and the hash to store in its place.
+func NewOpaqueToken() (token, hash string, err error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	token = base64.RawURLEncoding.EncodeToString(b)
+	return token, HashOpaqueToken(token), nil
+}
+
+// HashOpaqueToken returns the stored hash of a token from NewOpaqueToken.
+func HashOpaqueToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
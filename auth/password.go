@@ -0,0 +1,53 @@
+package auth
+
+import (
+	"fmt"
+	"unicode"
+)
+
+// maxPasswordBytes is the longest password bcrypt hashes in full; longer
+// ones are refused rather than silently cut short.
+const maxPasswordBytes = 72
+
+// PasswordPolicy is what admin passwords must satisfy. MinClasses is how
+// many of lowercase letters, uppercase letters, digits and symbols they must
+// mix.
+type PasswordPolicy struct {
+	MinLength  int
+	MinClasses int
+}
+
+// Check returns an error explaining how password falls short of p, or nil.
+func (p PasswordPolicy) Check(password string) error {
+	if len([]rune(password)) < p.MinLength {
+		return fmt.Errorf("password must be at least %d characters", p.MinLength)
+	}
+	if len(password) > maxPasswordBytes {
+		return fmt.Errorf("password must be at most %d bytes", maxPasswordBytes)
+	}
+
+	var lower, upper, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsDigit(r):
+			digit = true
+		default:
+			symbol = true
+		}
+	}
+	classes := 0
+	for _, has := range []bool{lower, upper, digit, symbol} {
+		if has {
+			classes++
+		}
+	}
+	if classes < p.MinClasses {
+		return fmt.Errorf("password must mix at least %d of lowercase letters, uppercase letters, digits and symbols", p.MinClasses)
+	}
+
+	return nil
+}
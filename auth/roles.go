@@ -0,0 +1,79 @@
+package auth
+
+// Admin roles
+const (
+	RoleOwner        = "owner"
+	RoleManager      = "manager"
+	RoleArtist       = "artist"
+	RoleReceptionist = "receptionist"
+	RoleInstructor   = "instructor"
+)
+
+// Roles lists every admin role.
+var Roles = []string{RoleOwner, RoleManager, RoleArtist, RoleReceptionist, RoleInstructor}
+
+// Permission is something an admin role may do.
+type Permission string
+
+const (
+	PermViewAppointments   Permission = "appointments:read"
+	PermManageAppointments Permission = "appointments:write"
+	PermViewClasses        Permission = "classes:read"
+	PermManageClasses      Permission = "classes:write"
+	PermViewCustomers      Permission = "customers:read"
+	PermManageCustomers    Permission = "customers:write"
+	// PermManageCatalog covers services, class offerings and artists.
+	PermManageCatalog  Permission = "catalog:write"
+	PermManageCohorts  Permission = "cohorts:write"
+	PermManageWaitlist Permission = "waitlist:write"
+	// PermNotifications covers the outbox, customer channels and scheduled
+	// jobs.
+	PermNotifications  Permission = "notifications"
+	PermManageWebhooks Permission = "webhooks:write"
+	PermManageAdmins   Permission = "admins:write"
+)
+
+// rolePermissions is what each role may do. Artists hold the appointment
+// permissions only for appointments assigned to them.
+var rolePermissions = map[string][]Permission{
+	RoleOwner: {
+		PermViewAppointments, PermManageAppointments, PermViewClasses, PermManageClasses,
+		PermViewCustomers, PermManageCustomers, PermManageCatalog, PermManageCohorts, PermManageWaitlist, PermNotifications,
+		PermManageWebhooks, PermManageAdmins,
+	},
+	RoleManager: {
+		PermViewAppointments, PermManageAppointments, PermViewClasses, PermManageClasses,
+		PermViewCustomers, PermManageCustomers, PermManageCatalog, PermManageCohorts, PermManageWaitlist, PermNotifications,
+	},
+	RoleReceptionist: {
+		PermViewAppointments, PermManageAppointments, PermViewClasses, PermManageClasses,
+		PermViewCustomers, PermManageCustomers, PermManageWaitlist, PermNotifications,
+	},
+	RoleArtist: {
+		PermViewAppointments, PermManageAppointments,
+	},
+	RoleInstructor: {
+		PermViewClasses, PermManageClasses, PermManageCohorts,
+	},
+}
+
+// ValidRole reports whether role is one of Roles.
+func ValidRole(role string) bool {
+	_, ok := rolePermissions[role]
+	return ok
+}
+
+// HasPermission reports whether role may do perm.
+func HasPermission(role string, perm Permission) bool {
+	for _, p := range rolePermissions[role] {
+		if p == perm {
+			return true
+		}
+	}
+	return false
+}
+
+// Permissions returns what role may do.
+func Permissions(role string) []Permission {
+	return append([]Permission(nil), rolePermissions[role]...)
+}
@@ -0,0 +1,62 @@
+package auth
+
+import "testing"
+
+func TestHasPermission(t *testing.T) {
+	tests := []struct {
+		perm Permission
+		// Roles allowed perm; every other role is refused
+		allowed []string
+	}{
+		{PermViewAppointments, []string{RoleOwner, RoleManager, RoleReceptionist, RoleArtist}},
+		{PermManageAppointments, []string{RoleOwner, RoleManager, RoleReceptionist, RoleArtist}},
+		{PermViewClasses, []string{RoleOwner, RoleManager, RoleReceptionist, RoleInstructor}},
+		{PermManageClasses, []string{RoleOwner, RoleManager, RoleReceptionist, RoleInstructor}},
+		{PermViewCustomers, []string{RoleOwner, RoleManager, RoleReceptionist}},
+		{PermManageCustomers, []string{RoleOwner, RoleManager, RoleReceptionist}},
+		{PermManageCatalog, []string{RoleOwner, RoleManager}},
+		{PermManageCohorts, []string{RoleOwner, RoleManager, RoleInstructor}},
+		{PermManageWaitlist, []string{RoleOwner, RoleManager, RoleReceptionist}},
+		{PermNotifications, []string{RoleOwner, RoleManager, RoleReceptionist}},
+		{PermManageWebhooks, []string{RoleOwner}},
+		{PermManageAdmins, []string{RoleOwner}},
+	}
+	for _, tt := range tests {
+		allowed := map[string]bool{}
+		for _, role := range tt.allowed {
+			allowed[role] = true
+		}
+		for _, role := range Roles {
+			if got := HasPermission(role, tt.perm); got != allowed[role] {
+				t.Errorf("HasPermission(%s, %s) = %t, want %t", role, tt.perm, got, allowed[role])
+			}
+		}
+		// Unknown roles, such as an empty one from a missing claim, get nothing
+		for _, role := range []string{"", "admin", "Owner"} {
+			if HasPermission(role, tt.perm) {
+				t.Errorf("HasPermission(%q, %s) = true, want false", role, tt.perm)
+			}
+		}
+	}
+}
+
+func TestValidRole(t *testing.T) {
+	for _, role := range Roles {
+		if !ValidRole(role) {
+			t.Errorf("ValidRole(%s) = false, want true", role)
+		}
+	}
+	for _, role := range []string{"", "admin", "OWNER", "owner "} {
+		if ValidRole(role) {
+			t.Errorf("ValidRole(%q) = true, want false", role)
+		}
+	}
+}
+
+func TestPermissionsIsACopy(t *testing.T) {
+	perms := Permissions(RoleArtist)
+	perms[0] = PermManageAdmins
+	if HasPermission(RoleArtist, PermManageAdmins) {
+		t.Error("changing the result of Permissions changed the artist role")
+	}
+}
@@ -0,0 +1,31 @@
+package auth
+
+import "time"
+
+// ThrottlePolicy decides how long failed logins hold off the next attempt.
+// After MaxFailures failures the next attempt waits Lockout. Before that,
+// from the second failure on, it waits Delay, doubled for every further
+// failure; a zero Delay leaves out the wait.
+type ThrottlePolicy struct {
+	MaxFailures int
+	Lockout     time.Duration
+	Delay       time.Duration
+}
+
+// BlockedUntil returns when the next attempt may be made after failures
+// failed logins, the last at lastFailure. It is the zero time if the next
+// attempt needn't wait.
+func (p ThrottlePolicy) BlockedUntil(failures int, lastFailure time.Time) time.Time {
+	if p.MaxFailures > 0 && failures >= p.MaxFailures {
+		return lastFailure.Add(p.Lockout)
+	}
+	if p.Delay <= 0 || failures < 2 {
+		return time.Time{}
+	}
+
+	delay := p.Delay
+	for i := 2; i < failures && delay < p.Lockout; i++ {
+		delay *= 2
+	}
+	return lastFailure.Add(min(delay, p.Lockout))
+}
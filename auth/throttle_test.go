@@ -0,0 +1,42 @@
+package auth
+
+import (
+	"testing"
+	"time"
+)
+
+func TestThrottlePolicyBlockedUntil(t *testing.T) {
+	last := time.Date(2030, 1, 7, 10, 0, 0, 0, time.UTC)
+	policy := ThrottlePolicy{MaxFailures: 5, Lockout: 15 * time.Minute, Delay: time.Second}
+
+	tests := []struct {
+		name     string
+		policy   ThrottlePolicy
+		failures int
+		wait     time.Duration
+	}{
+		{"no failures", policy, 0, 0},
+		{"one failure", policy, 1, 0},
+		{"second failure waits the delay", policy, 2, time.Second},
+		{"third doubles it", policy, 3, 2 * time.Second},
+		{"fourth doubles it again", policy, 4, 4 * time.Second},
+		{"locked out at the limit", policy, 5, 15 * time.Minute},
+		{"still locked out past it", policy, 9, 15 * time.Minute},
+		{"the delay never passes the lockout", ThrottlePolicy{MaxFailures: 50, Lockout: time.Minute, Delay: time.Second}, 20, time.Minute},
+		{"no delay", ThrottlePolicy{MaxFailures: 20, Lockout: time.Hour}, 19, 0},
+		{"no delay, locked out", ThrottlePolicy{MaxFailures: 20, Lockout: time.Hour}, 20, time.Hour},
+		{"no limit", ThrottlePolicy{Lockout: time.Minute, Delay: time.Second}, 100, time.Minute},
+	}
+	for _, tt := range tests {
+		until := tt.policy.BlockedUntil(tt.failures, last)
+		if tt.wait == 0 {
+			if !until.IsZero() {
+				t.Errorf("%s: blocked until %v, want no wait", tt.name, until)
+			}
+			continue
+		}
+		if got := until.Sub(last); got != tt.wait {
+			t.Errorf("%s: waits %v, want %v", tt.name, got, tt.wait)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfc6238Secret is the SHA1 key from RFC 6238's test vectors, base32 encoded.
+var rfc6238Secret = totpEncoding.EncodeToString([]byte("12345678901234567890"))
+
+func TestTOTPCodeMatchesRFC6238(t *testing.T) {
+	// RFC 6238 appendix B gives eight digit codes; ours are their last six
+	tests := []struct {
+		unix int64
+		code string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+	for _, tt := range tests {
+		if got := totpCode([]byte("12345678901234567890"), tt.unix/totpPeriod); got != tt.code {
+			t.Errorf("code at %d = %s, want %s", tt.unix, got, tt.code)
+		}
+
+		step, ok := ValidateTOTP(rfc6238Secret, tt.code, time.Unix(tt.unix, 0))
+		if !ok || step != tt.unix/totpPeriod {
+			t.Errorf("ValidateTOTP(%s) at %d = %d, %t; want step %d", tt.code, tt.unix, step, ok, tt.unix/totpPeriod)
+		}
+	}
+}
+
+func TestValidateTOTPSkew(t *testing.T) {
+	// 1111111111 is step 37037037; its code was made at that step
+	code := "050471"
+	step := int64(1111111111 / totpPeriod)
+	tests := []struct {
+		name  string
+		steps int64
+		ok    bool
+	}{
+		{"same step", 0, true},
+		{"one step early", -1, true},
+		{"one step late", 1, true},
+		{"two steps early", -2, false},
+		{"two steps late", 2, false},
+	}
+	for _, tt := range tests {
+		now := time.Unix((step+tt.steps)*totpPeriod, 0)
+		matched, ok := ValidateTOTP(rfc6238Secret, code, now)
+		if ok != tt.ok {
+			t.Errorf("%s: ok = %t, want %t", tt.name, ok, tt.ok)
+		}
+		if ok && matched != step {
+			t.Errorf("%s: matched step %d, want %d", tt.name, matched, step)
+		}
+	}
+}
+
+func TestValidateTOTPRefuses(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	tests := []struct {
+		name   string
+		secret string
+		code   string
+		ok     bool
+	}{
+		{"spaces typed in the code", rfc6238Secret, "050 471", true},
+		{"lowercase secret", strings.ToLower(rfc6238Secret), "050471", true},
+		{"wrong code", rfc6238Secret, "050472", false},
+		{"too short", rfc6238Secret, "50471", false},
+		{"too long", rfc6238Secret, "0504710", false},
+		{"empty", rfc6238Secret, "", false},
+		{"secret isn't base32", "not base32!", "050471", false},
+	}
+	for _, tt := range tests {
+		if _, ok := ValidateTOTP(tt.secret, tt.code, now); ok != tt.ok {
+			t.Errorf("%s: ok = %t, want %t", tt.name, ok, tt.ok)
+		}
+	}
+}
+
+func TestNewRecoveryCodes(t *testing.T) {
+	codes, hashes, err := NewRecoveryCodes(10)
+	if err != nil {
+		t.Fatalf("NewRecoveryCodes: %v", err)
+	}
+	if len(codes) != 10 || len(hashes) != 10 {
+		t.Fatalf("got %d codes and %d hashes, want 10 of each", len(codes), len(hashes))
+	}
+
+	seen := map[string]bool{}
+	for i, code := range codes {
+		if len(code) != 11 || code[5] != '-' {
+			t.Errorf("code %q isn't formatted like 3f9a1-c07e2", code)
+		}
+		if seen[code] {
+			t.Errorf("code %q was handed out twice", code)
+		}
+		seen[code] = true
+
+		if hashes[i] != HashRecoveryCode(code) {
+			t.Errorf("hash %d doesn't match its code", i)
+		}
+		// However it is typed back, a code hashes the same
+		for _, typed := range []string{strings.ToUpper(code), strings.ReplaceAll(code, "-", ""), " " + code[:5] + " " + code[6:]} {
+			if HashRecoveryCode(typed) != hashes[i] {
+				t.Errorf("%q hashes differently from %q", typed, code)
+			}
+		}
+	}
+}
@@ -3,6 +3,9 @@ package config
 import (
 	"log"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -13,6 +16,149 @@ type Config struct {
 	SupabaseServiceKey string
 	JWTSecret          string
 	Port               string
+
+	// TrustedProxies are the addresses or CIDR ranges of the reverse proxies
+	// in front of the server, whose X-Forwarded-For header is believed.
+	// Other clients are known by the address they connect from, so they
+	// can't choose the IP address logins and bookings are counted against.
+	// Empty trusts no proxy.
+	TrustedProxies []string
+
+	// DatabaseDriver selects the Store backend: "supabase" (default),
+	// "postgres" for a direct connection to DatabaseURL, or "memory" for
+	// local development without a database.
+	DatabaseDriver   string
+	DatabaseURL      string
+	DatabaseMaxConns int
+
+	// Admin onboarding. Signup is closed except to create the first owner
+	// with AdminBootstrapToken; everyone after is invited by an owner. Invites
+	// last AdminInviteTTL, and AdminInviteURL is the frontend page they are
+	// accepted on, with "{token}" replaced by the invite token.
+	AdminBootstrapToken string
+	AdminInviteTTL      time.Duration
+	AdminInviteURL      string
+
+	// Admin sessions. Access tokens last AccessTokenTTL; a session can be
+	// refreshed for a new one until it has gone unused for RefreshTokenTTL.
+	AccessTokenTTL  time.Duration
+	RefreshTokenTTL time.Duration
+
+	// Admin passwords must be at least PasswordMinLength characters and mix
+	// PasswordMinClasses of lowercase, uppercase, digits and symbols.
+	// Password reset links last PasswordResetTTL, and PasswordResetURL is the
+	// frontend page they open, with "{token}" replaced by the reset token.
+	PasswordMinLength  int
+	PasswordMinClasses int
+	PasswordResetTTL   time.Duration
+	PasswordResetURL   string
+
+	// Login throttling. After two failed logins for an email, each attempt
+	// waits LoginDelay, doubling with every further failure; at
+	// LoginMaxFailures the email is locked out for LoginLockout. An IP
+	// address is locked out after LoginIPMaxFailures failures for any email.
+	// Counts start over once LoginLockout passes without a failure.
+	LoginMaxFailures   int
+	LoginIPMaxFailures int
+	LoginLockout       time.Duration
+	LoginDelay         time.Duration
+
+	// PasswordResetEmailLimit and PasswordResetIPLimit are token buckets
+	// like "3/1h" bounding password reset requests per email and per IP
+	// address, apart from the login throttle; "0" disables them.
+	PasswordResetEmailLimit string
+	PasswordResetIPLimit    string
+
+	// Spam protection for public bookings. BookingIPLimit and
+	// BookingContactLimit are token buckets like "20/1h" per IP address and
+	// per email or phone number; "0" disables them. An identical booking
+	// within DuplicateWindow is rejected. BookingVerifier "captcha" checks a
+	// CAPTCHA response with the provider at CaptchaVerifyURL, "pow" a proof
+	// of work with PowDifficulty leading zero bits; empty checks neither.
+	BookingIPLimit      string
+	BookingContactLimit string
+	DuplicateWindow     time.Duration
+	BookingVerifier     string
+	CaptchaVerifyURL    string
+	CaptchaSecret       string
+	PowDifficulty       int
+
+	// IdempotencyKeyTTL is how long the response to a request sent with an
+	// Idempotency-Key is kept and replayed for retries with the same key.
+	IdempotencyKeyTTL time.Duration
+
+	// DefaultPhoneCountry is the ISO 3166 code of the country phone numbers
+	// written without a country code are in. Numbers are stored in E.164.
+	DefaultPhoneCountry string
+
+	// Scheduling: weekly business hours, the duration and buffer of services
+	// missing from the catalog, blackout dates and the spacing of offered
+	// slots. See scheduling.NewEngine for the formats.
+	BusinessHours          string
+	DefaultServiceDuration time.Duration
+	DefaultServiceBuffer   time.Duration
+	BlackoutDates          string
+	SlotInterval           time.Duration
+	AvailabilityMaxDays    int
+
+	// StudioTimeZone is the IANA time zone appointment dates and times are
+	// in. Appointments must be booked at least BookingMinLeadTime ahead and
+	// at most BookingMaxAdvanceDays days ahead; zero disables either rule.
+	StudioTimeZone        string
+	BookingMinLeadTime    time.Duration
+	BookingMaxAdvanceDays int
+
+	// CohortFullPolicy is what happens to an enrollment in a full class
+	// cohort: "reject" it or "waitlist" it.
+	CohortFullPolicy string
+
+	// WaitlistMode is how a freed seat or slot goes to the next person on the
+	// waitlist: "auto" books it for them, "offer" holds it for WaitlistHold
+	// and releases it to the next person unless they accept in time.
+	WaitlistMode string
+	WaitlistHold time.Duration
+
+	// CancellationCutoff and RescheduleCutoff are how long before an
+	// appointment, or the start of a cohort, customers stop being able to
+	// cancel or reschedule through their manage link. Zero disables the rule.
+	CancellationCutoff time.Duration
+	RescheduleCutoff   time.Duration
+
+	// Email notifications. Without SMTPHost messages are written to the log
+	// instead of sent. StudioEmail receives a copy of new bookings and
+	// cancellations, and ManageURL is the frontend page customers manage a
+	// booking from; "{kind}" and "{token}" are replaced with "appointments"
+	// or "classes" and the manage token.
+	SMTPHost     string
+	SMTPPort     int
+	SMTPUsername string
+	SMTPPassword string
+	SMTPFrom     string
+	StudioName   string
+	StudioEmail  string
+	ManageURL    string
+
+	// SMS and WhatsApp notifications. With MessagingProvider "http" messages
+	// are posted to MessagingAPIURL, and with "fake" only logged, for local
+	// development. Without a provider they are marked failed unsent.
+	// MessagingStatusURL is where the provider reports delivery status,
+	// authenticated with MessagingWebhookSecret.
+	MessagingProvider      string
+	MessagingAPIURL        string
+	MessagingAPIKey        string
+	MessagingSMSFrom       string
+	MessagingWhatsAppFrom  string
+	MessagingStatusURL     string
+	MessagingWebhookSecret string
+
+	// Webhook URLs must be https and reach only public addresses, so an
+	// admin can't point them at the server's own network.
+	// WebhookAllowLocalURLs lifts both rules for local development.
+	WebhookAllowLocalURLs bool
+
+	// ReminderOffsets are how long before an appointment, and before each
+	// class session, customers are sent a reminder.
+	ReminderOffsets []time.Duration
 }
 
 func LoadConfig() *Config {
@@ -27,6 +173,74 @@ func LoadConfig() *Config {
 		SupabaseServiceKey: getEnv("SUPABASE_SERVICE_ROLE_KEY", ""),
 		JWTSecret:          getEnv("JWT_SECRET", "default-secret-key"),
 		Port:               getEnv("PORT", "8080"),
+		TrustedProxies:     getEnvList("TRUSTED_PROXIES"),
+		DatabaseDriver:     getEnv("DATABASE_DRIVER", "supabase"),
+		DatabaseURL:        getEnv("DATABASE_URL", ""),
+		DatabaseMaxConns:   getEnvInt("DATABASE_MAX_CONNS", 10),
+
+		AdminBootstrapToken:     getEnv("ADMIN_BOOTSTRAP_TOKEN", ""),
+		AdminInviteTTL:          getEnvDuration("ADMIN_INVITE_TTL", 72*time.Hour),
+		AdminInviteURL:          getEnv("ADMIN_INVITE_URL", ""),
+		AccessTokenTTL:          getEnvDuration("ACCESS_TOKEN_TTL", 15*time.Minute),
+		RefreshTokenTTL:         getEnvDuration("REFRESH_TOKEN_TTL", 30*24*time.Hour),
+		PasswordMinLength:       getEnvInt("PASSWORD_MIN_LENGTH", 10),
+		PasswordMinClasses:      getEnvInt("PASSWORD_MIN_CLASSES", 2),
+		PasswordResetTTL:        getEnvDuration("PASSWORD_RESET_TTL", time.Hour),
+		PasswordResetURL:        getEnv("PASSWORD_RESET_URL", ""),
+		LoginMaxFailures:        getEnvInt("LOGIN_MAX_FAILURES", 5),
+		LoginIPMaxFailures:      getEnvInt("LOGIN_IP_MAX_FAILURES", 20),
+		LoginLockout:            getEnvDuration("LOGIN_LOCKOUT", 15*time.Minute),
+		LoginDelay:              getEnvDuration("LOGIN_DELAY", time.Second),
+		PasswordResetEmailLimit: getEnv("PASSWORD_RESET_EMAIL_LIMIT", "3/1h"),
+		PasswordResetIPLimit:    getEnv("PASSWORD_RESET_IP_LIMIT", "10/1h"),
+		BookingIPLimit:          getEnv("BOOKING_IP_LIMIT", "20/1h"),
+		BookingContactLimit:     getEnv("BOOKING_CONTACT_LIMIT", "5/1h"),
+		DuplicateWindow:         getEnvDuration("DUPLICATE_WINDOW", 10*time.Minute),
+		BookingVerifier:         getEnv("BOOKING_VERIFIER", ""),
+		CaptchaVerifyURL:        getEnv("CAPTCHA_VERIFY_URL", ""),
+		CaptchaSecret:           getEnv("CAPTCHA_SECRET", ""),
+		PowDifficulty:           getEnvInt("POW_DIFFICULTY", 20),
+		IdempotencyKeyTTL:       getEnvDuration("IDEMPOTENCY_KEY_TTL", 24*time.Hour),
+		DefaultPhoneCountry:     getEnv("DEFAULT_PHONE_COUNTRY", "NG"),
+
+		BusinessHours:          getEnv("BUSINESS_HOURS", "mon-fri=09:00-18:00;sat=09:00-16:00"),
+		DefaultServiceDuration: getEnvDuration("DEFAULT_SERVICE_DURATION", time.Hour),
+		DefaultServiceBuffer:   getEnvDuration("DEFAULT_SERVICE_BUFFER", 15*time.Minute),
+		BlackoutDates:          getEnv("BLACKOUT_DATES", ""),
+		SlotInterval:           getEnvDuration("SLOT_INTERVAL", 30*time.Minute),
+		AvailabilityMaxDays:    getEnvInt("AVAILABILITY_MAX_DAYS", 31),
+
+		StudioTimeZone:        getEnv("STUDIO_TIME_ZONE", "Africa/Lagos"),
+		BookingMinLeadTime:    getEnvDuration("BOOKING_MIN_LEAD_TIME", 2*time.Hour),
+		BookingMaxAdvanceDays: getEnvInt("BOOKING_MAX_ADVANCE_DAYS", 180),
+
+		CohortFullPolicy: getEnv("COHORT_FULL_POLICY", "waitlist"),
+		WaitlistMode:     getEnv("WAITLIST_MODE", "auto"),
+		WaitlistHold:     getEnvDuration("WAITLIST_HOLD", 24*time.Hour),
+
+		CancellationCutoff: getEnvDuration("CANCELLATION_CUTOFF", 24*time.Hour),
+		RescheduleCutoff:   getEnvDuration("RESCHEDULE_CUTOFF", 24*time.Hour),
+
+		SMTPHost:     getEnv("SMTP_HOST", ""),
+		SMTPPort:     getEnvInt("SMTP_PORT", 587),
+		SMTPUsername: getEnv("SMTP_USERNAME", ""),
+		SMTPPassword: getEnv("SMTP_PASSWORD", ""),
+		SMTPFrom:     getEnv("SMTP_FROM", "Mumuni <no-reply@mumuni.com>"),
+		StudioName:   getEnv("STUDIO_NAME", "Mumuni"),
+		StudioEmail:  getEnv("STUDIO_EMAIL", ""),
+		ManageURL:    getEnv("MANAGE_URL", ""),
+
+		MessagingProvider:      getEnv("MESSAGING_PROVIDER", ""),
+		MessagingAPIURL:        getEnv("MESSAGING_API_URL", ""),
+		MessagingAPIKey:        getEnv("MESSAGING_API_KEY", ""),
+		MessagingSMSFrom:       getEnv("MESSAGING_SMS_FROM", ""),
+		MessagingWhatsAppFrom:  getEnv("MESSAGING_WHATSAPP_FROM", ""),
+		MessagingStatusURL:     getEnv("MESSAGING_STATUS_URL", ""),
+		MessagingWebhookSecret: getEnv("MESSAGING_WEBHOOK_SECRET", ""),
+
+		WebhookAllowLocalURLs: getEnvBool("WEBHOOK_ALLOW_LOCAL_URLS", false),
+
+		ReminderOffsets: getEnvDurations("REMINDER_OFFSETS", []time.Duration{48 * time.Hour, 2 * time.Hour}),
 	}
 }
 
@@ -36,3 +250,76 @@ func getEnv(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+func getEnvInt(key string, defaultValue int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		log.Printf("Invalid %s %q, using %d", key, value, defaultValue)
+		return defaultValue
+	}
+	return n
+}
+
+func getEnvBool(key string, defaultValue bool) bool {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		log.Printf("Invalid %s %q, using %t", key, value, defaultValue)
+		return defaultValue
+	}
+	return b
+}
+
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		log.Printf("Invalid %s %q, using %s", key, value, defaultValue)
+		return defaultValue
+	}
+	return d
+}
+
+// getEnvList reads a comma-separated list, dropping empty entries.
+func getEnvList(key string) []string {
+	var list []string
+	for _, part := range strings.Split(os.Getenv(key), ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			list = append(list, part)
+		}
+	}
+	return list
+}
+
+// getEnvDurations reads a comma-separated list of durations. "none" turns
+// the list off.
+func getEnvDurations(key string, defaultValue []time.Duration) []time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	if value == "none" {
+		return nil
+	}
+
+	var durations []time.Duration
+	for _, part := range strings.Split(value, ",") {
+		d, err := time.ParseDuration(strings.TrimSpace(part))
+		if err != nil || d <= 0 {
+			log.Printf("Invalid %s %q, using %v", key, value, defaultValue)
+			return defaultValue
+		}
+		durations = append(durations, d)
+	}
+	return durations
+}
@@ -2,14 +2,22 @@ package database
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"log"
+	"math"
 	"mumuni_backend/config"
+	"mumuni_backend/lifecycle"
 	"mumuni_backend/models"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/supabase-community/supabase-go"
 	"github.com/supabase/postgrest-go"
 )
 
+// Database is the Store backed by Supabase's PostgREST API.
 type Database struct {
 	client *supabase.Client
 }
@@ -36,20 +44,43 @@ func NewDatabase(cfg *config.Config) (*Database, error) {
 }
 
 // Appointment methods
-func (db *Database) CreateAppointment(ctx context.Context, req *models.AppointmentRequest) (*models.Appointment, error) {
+// CreateAppointment runs check against the active appointments on the same
+// date before inserting. PostgREST has no transactions, so unlike the
+// Postgres backend two simultaneous requests can still race past the check.
+func (db *Database) CreateAppointment(ctx context.Context, req *models.AppointmentRequest, manageTokenHash string, check BookingCheck) (*models.Appointment, error) {
+	if check != nil {
+		existing, err := db.GetActiveAppointments(ctx, req.Date, req.Date)
+		if err != nil {
+			return nil, err
+		}
+		if err := check(existing); err != nil {
+			return nil, err
+		}
+	}
+
+	customerID, err := db.matchCustomer(req.Name, req.Email, req.Phone)
+	if err != nil {
+		return nil, err
+	}
+
 	appointment := map[string]interface{}{
-		"name":             req.Name,
-		"email":            req.Email,
-		"phone":            req.Phone,
-		"appointment_date": req.Date,
-		"appointment_time": req.Time,
-		"service":          req.Service,
-		"message":          req.Message,
-		"status":           "pending",
+		"name":              req.Name,
+		"email":             req.Email,
+		"phone":             req.Phone,
+		"appointment_date":  req.Date,
+		"appointment_time":  req.Time,
+		"starts_at":         appointmentStart(req),
+		"service":           req.Service,
+		"message":           req.Message,
+		"artist_id":         req.ArtistID,
+		"customer_id":       customerID,
+		"channels":          bookingChannels(req.Channels),
+		"status":            "pending",
+		"manage_token_hash": manageTokenHash,
 	}
 
 	var result []models.Appointment
-	_, err := db.client.From("appointments").Insert(appointment, false, "", "", "").ExecuteTo(&result)
+	_, err = db.client.From("appointments").Insert(appointment, false, "", "", "").ExecuteTo(&result)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create appointment: %w", err)
 	}
@@ -61,162 +92,2766 @@ func (db *Database) CreateAppointment(ctx context.Context, req *models.Appointme
 	return &result[0], nil
 }
 
-func (db *Database) GetAppointments(ctx context.Context) ([]models.Appointment, error) {
+func (db *Database) GetAppointments(ctx context.Context, filter AppointmentFilter, page Page) ([]models.Appointment, int, error) {
+	query := db.client.From("appointments").Select("*", "exact", false)
+	// PostgREST takes one filter per column, so the upper date bound and the
+	// search go into a single "and" group.
+	var and []string
+	if filter.ArtistID != nil {
+		query = query.Eq("artist_id", strconv.Itoa(*filter.ArtistID))
+	}
+	if filter.CustomerID != nil {
+		query = query.Eq("customer_id", strconv.Itoa(*filter.CustomerID))
+	}
+	if filter.Date != "" {
+		query = query.Eq("appointment_date", filter.Date)
+	}
+	if filter.DateFrom != "" {
+		and = append(and, "appointment_date.gte."+filter.DateFrom)
+	}
+	if filter.DateTo != "" {
+		and = append(and, "appointment_date.lte."+filter.DateTo)
+	}
+	if filter.Status != "" {
+		query = query.Eq("status", filter.Status)
+	}
+	if filter.Service != "" {
+		query = query.Eq("service", filter.Service)
+	}
+	if filter.Email != "" {
+		query = query.Ilike("email", escapeLike(filter.Email))
+	}
+	if filter.Phone != "" {
+		query = query.Eq("phone", filter.Phone)
+	}
+	if filter.Search != "" {
+		and = append(and, searchGroup(filter.Search, "name", "email", "message"))
+	}
+	if len(and) > 0 {
+		query = query.Or("and("+strings.Join(and, ",")+")", "")
+	}
+
+	var appointments []models.Appointment
+	total, err := orderPage(query, page, AppointmentSorts).ExecuteTo(&appointments)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get appointments: %w", err)
+	}
+
+	return appointments, int(total), nil
+}
+
+func (db *Database) GetAppointment(ctx context.Context, appointmentID int) (*models.Appointment, error) {
+	var result []models.Appointment
+	_, err := db.client.From("appointments").Select("*", "", false).Eq("id", strconv.Itoa(appointmentID)).ExecuteTo(&result)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get appointment: %w", err)
+	}
+
+	if len(result) == 0 {
+		return nil, ErrAppointmentNotFound
+	}
+
+	return &result[0], nil
+}
+
+func (db *Database) GetAppointmentByManageToken(ctx context.Context, tokenHash string) (*models.Appointment, error) {
+	var result []models.Appointment
+	_, err := db.client.From("appointments").Select("*", "", false).Eq("manage_token_hash", tokenHash).ExecuteTo(&result)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get appointment: %w", err)
+	}
+
+	if len(result) == 0 {
+		return nil, ErrAppointmentNotFound
+	}
+
+	return &result[0], nil
+}
+
+// RescheduleAppointment checks and updates in separate requests, so unlike
+// the postgres driver two reschedules racing for the same slot can both win.
+func (db *Database) RescheduleAppointment(ctx context.Context, appointmentID int, req *models.AppointmentRequest, check BookingCheck) (*models.Appointment, error) {
+	var current []models.Appointment
+	_, err := db.client.From("appointments").Select("*", "", false).Eq("id", strconv.Itoa(appointmentID)).ExecuteTo(&current)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reschedule appointment: %w", err)
+	}
+	if len(current) == 0 {
+		return nil, ErrAppointmentNotFound
+	}
+	if !isActiveStatus(current[0].Status) {
+		return nil, ErrBookingClosed
+	}
+
+	if check != nil {
+		active, err := db.GetActiveAppointments(ctx, req.Date, req.Date)
+		if err != nil {
+			return nil, err
+		}
+		existing := []models.Appointment{}
+		for _, other := range active {
+			if other.ID != appointmentID {
+				existing = append(existing, other)
+			}
+		}
+		if err := check(existing); err != nil {
+			return nil, err
+		}
+	}
+
+	updateData := map[string]interface{}{
+		"appointment_date": req.Date,
+		"appointment_time": req.Time,
+		"starts_at":        appointmentStart(req),
+		"artist_id":        req.ArtistID,
+	}
+
+	var result []models.Appointment
+	_, err = db.client.From("appointments").Update(updateData, "", "").
+		Eq("id", strconv.Itoa(appointmentID)).
+		Eq("status", current[0].Status).
+		ExecuteTo(&result)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reschedule appointment: %w", err)
+	}
+
+	if len(result) == 0 {
+		return nil, ErrBookingClosed
+	}
+
+	return &result[0], nil
+}
+
+func (db *Database) UpdateAppointmentChannels(ctx context.Context, appointmentID int, channels []string) (*models.Appointment, error) {
+	updateData := map[string]interface{}{
+		"channels": bookingChannels(channels),
+	}
+
+	var result []models.Appointment
+	_, err := db.client.From("appointments").Update(updateData, "", "").
+		Eq("id", strconv.Itoa(appointmentID)).
+		ExecuteTo(&result)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update appointment channels: %w", err)
+	}
+
+	if len(result) == 0 {
+		return nil, ErrAppointmentNotFound
+	}
+
+	return &result[0], nil
+}
+
+func (db *Database) GetActiveAppointments(ctx context.Context, fromDate, toDate string) ([]models.Appointment, error) {
+	// postgrest-go keeps one filter per column, so the range is expressed as
+	// an IN list of dates rather than gte/lte on the same column.
+	dates, err := datesBetween(fromDate, toDate)
+	if err != nil {
+		return nil, err
+	}
+
 	var appointments []models.Appointment
-	_, err := db.client.From("appointments").Select("*", "", false).Order("created_at", &postgrest.OrderOpts{Ascending: false}).ExecuteTo(&appointments)
+	_, err = db.client.From("appointments").Select("*", "", false).
+		In("appointment_date", dates).
+		In("status", activeStatuses).
+		ExecuteTo(&appointments)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get appointments: %w", err)
+		return nil, fmt.Errorf("failed to get active appointments: %w", err)
 	}
 
 	return appointments, nil
 }
 
-// Class methods
-func (db *Database) CreateClass(ctx context.Context, req *models.ClassRequest) (*models.Class, error) {
-	class := map[string]interface{}{
-		"name":               req.Name,
-		"email":              req.Email,
-		"phone":              req.Phone,
-		"class_type":         req.ClassType,
-		"experience_level":   req.Experience,
-		"goals":              req.Goals,
-		"preferred_schedule": req.Schedule,
-		"status":             "pending",
+// Service catalog methods
+func (db *Database) CreateService(ctx context.Context, req *models.ServiceRequest) (*models.Service, error) {
+	var result []models.Service
+	_, err := db.client.From("services").Insert(serviceRow(req), false, "", "", "").ExecuteTo(&result)
+	if isPostgrestUniqueViolation(err) {
+		return nil, ErrServiceExists
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create service: %w", err)
 	}
 
-	var result []models.Class
-	_, err := db.client.From("classes").Insert(class, false, "", "", "").ExecuteTo(&result)
+	if len(result) == 0 {
+		return nil, fmt.Errorf("no service created")
+	}
+
+	return &result[0], nil
+}
+
+func (db *Database) GetServices(ctx context.Context, activeOnly bool) ([]models.Service, error) {
+	query := db.client.From("services").Select("*", "", false)
+	if activeOnly {
+		query = query.Eq("active", "true")
+	}
+
+	var services []models.Service
+	_, err := query.
+		Order("display_order", &postgrest.OrderOpts{Ascending: true}).
+		Order("name", &postgrest.OrderOpts{Ascending: true}).
+		ExecuteTo(&services)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create class enrollment: %w", err)
+		return nil, fmt.Errorf("failed to get services: %w", err)
+	}
+
+	return services, nil
+}
+
+func (db *Database) GetService(ctx context.Context, serviceID int) (*models.Service, error) {
+	var services []models.Service
+	_, err := db.client.From("services").Select("*", "", false).Eq("id", strconv.Itoa(serviceID)).ExecuteTo(&services)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get service: %w", err)
+	}
+
+	if len(services) == 0 {
+		return nil, ErrServiceNotFound
+	}
+
+	return &services[0], nil
+}
+
+func (db *Database) UpdateService(ctx context.Context, serviceID int, req *models.ServiceRequest) (*models.Service, error) {
+	var result []models.Service
+	_, err := db.client.From("services").Update(serviceRow(req), "", "").Eq("id", strconv.Itoa(serviceID)).ExecuteTo(&result)
+	if isPostgrestUniqueViolation(err) {
+		return nil, ErrServiceExists
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to update service: %w", err)
 	}
 
 	if len(result) == 0 {
-		return nil, fmt.Errorf("no class enrollment created")
+		return nil, ErrServiceNotFound
 	}
 
 	return &result[0], nil
 }
 
-func (db *Database) GetClasses(ctx context.Context) ([]models.Class, error) {
-	var classes []models.Class
-	_, err := db.client.From("classes").Select("*", "", false).Order("created_at", &postgrest.OrderOpts{Ascending: false}).ExecuteTo(&classes)
+func (db *Database) DeleteService(ctx context.Context, serviceID int) error {
+	var result []models.Service
+	_, err := db.client.From("services").Delete("", "").Eq("id", strconv.Itoa(serviceID)).ExecuteTo(&result)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get classes: %w", err)
+		return fmt.Errorf("failed to delete service: %w", err)
 	}
 
-	return classes, nil
+	if len(result) == 0 {
+		return ErrServiceNotFound
+	}
+
+	return nil
 }
 
-// Admin methods
-func (db *Database) CreateAdmin(ctx context.Context, email, passwordHash, name string) (*models.AdminUser, error) {
-	admin := map[string]interface{}{
-		"email":         email,
-		"password_hash": passwordHash,
-		"name":          name,
+func serviceRow(req *models.ServiceRequest) map[string]interface{} {
+	return map[string]interface{}{
+		"name":             req.Name,
+		"description":      req.Description,
+		"duration_minutes": req.DurationMinutes,
+		"buffer_minutes":   req.BufferMinutes,
+		"price":            req.Price,
+		"deposit_amount":   req.DepositAmount,
+		"active":           serviceActive(req),
+		"display_order":    req.DisplayOrder,
 	}
+}
 
-	var result []models.AdminUser
-	_, err := db.client.From("admin_users").Insert(admin, false, "", "", "").ExecuteTo(&result)
+// Artist methods
+func (db *Database) CreateArtist(ctx context.Context, req *models.ArtistRequest) (*models.Artist, error) {
+	var result []models.Artist
+	_, err := db.client.From("artists").Insert(artistRow(req), false, "", "", "").ExecuteTo(&result)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create admin: %w", err)
+		return nil, fmt.Errorf("failed to create artist: %w", err)
 	}
 
 	if len(result) == 0 {
-		return nil, fmt.Errorf("no admin created")
+		return nil, fmt.Errorf("no artist created")
 	}
 
 	return &result[0], nil
 }
 
-func (db *Database) GetAdminByEmail(ctx context.Context, email string) (*models.AdminUser, error) {
-	var admins []models.AdminUser
-	_, err := db.client.From("admin_users").Select("*", "", false).Eq("email", email).ExecuteTo(&admins)
+func (db *Database) GetArtists(ctx context.Context, activeOnly bool) ([]models.Artist, error) {
+	query := db.client.From("artists").Select("*", "", false)
+	if activeOnly {
+		query = query.Eq("active", "true")
+	}
+
+	var artists []models.Artist
+	_, err := query.Order("name", &postgrest.OrderOpts{Ascending: true}).ExecuteTo(&artists)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get admin by email: %w", err)
+		return nil, fmt.Errorf("failed to get artists: %w", err)
 	}
 
-	if len(admins) == 0 {
-		return nil, fmt.Errorf("admin not found")
+	return artists, nil
+}
+
+func (db *Database) GetArtist(ctx context.Context, artistID int) (*models.Artist, error) {
+	var artists []models.Artist
+	_, err := db.client.From("artists").Select("*", "", false).Eq("id", strconv.Itoa(artistID)).ExecuteTo(&artists)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get artist: %w", err)
 	}
 
-	return &admins[0], nil
+	if len(artists) == 0 {
+		return nil, ErrArtistNotFound
+	}
+
+	return &artists[0], nil
 }
 
-func (db *Database) GetAdminPasswordHash(ctx context.Context, email string) (string, error) {
-	var result []struct {
-		PasswordHash string `json:"password_hash"`
+func (db *Database) UpdateArtist(ctx context.Context, artistID int, req *models.ArtistRequest) (*models.Artist, error) {
+	var result []models.Artist
+	_, err := db.client.From("artists").Update(artistRow(req), "", "").Eq("id", strconv.Itoa(artistID)).ExecuteTo(&result)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update artist: %w", err)
 	}
 
-	_, err := db.client.From("admin_users").Select("password_hash", "", false).Eq("email", email).ExecuteTo(&result)
+	if len(result) == 0 {
+		return nil, ErrArtistNotFound
+	}
+
+	return &result[0], nil
+}
+
+func (db *Database) DeleteArtist(ctx context.Context, artistID int) error {
+	var result []models.Artist
+	_, err := db.client.From("artists").Delete("", "").Eq("id", strconv.Itoa(artistID)).ExecuteTo(&result)
 	if err != nil {
-		return "", fmt.Errorf("failed to get admin password hash: %w", err)
+		return fmt.Errorf("failed to delete artist: %w", err)
 	}
 
 	if len(result) == 0 {
-		return "", fmt.Errorf("admin not found")
+		return ErrArtistNotFound
 	}
 
-	return result[0].PasswordHash, nil
+	return nil
 }
 
-// Update appointment status
-func (db *Database) UpdateAppointmentStatus(ctx context.Context, appointmentID int, status string) (*models.Appointment, error) {
-	// Validate status
-	validStatuses := map[string]bool{
-		"pending":   true,
-		"confirmed": true,
-		"cancelled": true,
-		"completed": true,
+func artistRow(req *models.ArtistRequest) map[string]interface{} {
+	return map[string]interface{}{
+		"name":          req.Name,
+		"email":         req.Email,
+		"phone":         req.Phone,
+		"skills":        req.Skills,
+		"working_hours": req.WorkingHours,
+		"active":        artistActive(req),
 	}
+}
 
-	if !validStatuses[status] {
-		return nil, fmt.Errorf("invalid status: %s", status)
+// Class catalog methods
+func (db *Database) CreateClassOffering(ctx context.Context, req *models.ClassOfferingRequest) (*models.ClassOffering, error) {
+	var result []models.ClassOffering
+	_, err := db.client.From("class_offerings").Insert(offeringRow(req), false, "", "", "").ExecuteTo(&result)
+	if isPostgrestUniqueViolation(err) {
+		return nil, ErrOfferingExists
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create class offering: %w", err)
 	}
 
-	updateData := map[string]interface{}{
-		"status": status,
+	if len(result) == 0 {
+		return nil, fmt.Errorf("no class offering created")
 	}
 
-	var result []models.Appointment
-	_, err := db.client.From("appointments").Update(updateData, "", "").Eq("id", fmt.Sprintf("%d", appointmentID)).ExecuteTo(&result)
+	return &result[0], nil
+}
+
+func (db *Database) GetClassOfferings(ctx context.Context, activeOnly bool) ([]models.ClassOffering, error) {
+	query := db.client.From("class_offerings").Select("*", "", false)
+	if activeOnly {
+		query = query.Eq("active", "true")
+	}
+
+	var offerings []models.ClassOffering
+	_, err := query.
+		Order("display_order", &postgrest.OrderOpts{Ascending: true}).
+		Order("name", &postgrest.OrderOpts{Ascending: true}).
+		ExecuteTo(&offerings)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get class offerings: %w", err)
+	}
+
+	return offerings, nil
+}
+
+func (db *Database) GetClassOffering(ctx context.Context, offeringID int) (*models.ClassOffering, error) {
+	var offerings []models.ClassOffering
+	_, err := db.client.From("class_offerings").Select("*", "", false).Eq("id", strconv.Itoa(offeringID)).ExecuteTo(&offerings)
 	if err != nil {
-		return nil, fmt.Errorf("failed to update appointment status: %w", err)
+		return nil, fmt.Errorf("failed to get class offering: %w", err)
+	}
+
+	if len(offerings) == 0 {
+		return nil, ErrOfferingNotFound
+	}
+
+	return &offerings[0], nil
+}
+
+func (db *Database) UpdateClassOffering(ctx context.Context, offeringID int, req *models.ClassOfferingRequest) (*models.ClassOffering, error) {
+	var result []models.ClassOffering
+	_, err := db.client.From("class_offerings").Update(offeringRow(req), "", "").Eq("id", strconv.Itoa(offeringID)).ExecuteTo(&result)
+	if isPostgrestUniqueViolation(err) {
+		return nil, ErrOfferingExists
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to update class offering: %w", err)
 	}
 
 	if len(result) == 0 {
-		return nil, fmt.Errorf("appointment not found")
+		return nil, ErrOfferingNotFound
 	}
 
 	return &result[0], nil
 }
 
-// Update class status
-func (db *Database) UpdateClassStatus(ctx context.Context, classID int, status string) (*models.Class, error) {
-	// Validate status
-	validStatuses := map[string]bool{
-		"pending":   true,
-		"confirmed": true,
-		"cancelled": true,
-		"completed": true,
+func (db *Database) DeleteClassOffering(ctx context.Context, offeringID int) error {
+	var result []models.ClassOffering
+	_, err := db.client.From("class_offerings").Delete("", "").Eq("id", strconv.Itoa(offeringID)).ExecuteTo(&result)
+	if err != nil {
+		return fmt.Errorf("failed to delete class offering: %w", err)
 	}
 
-	if !validStatuses[status] {
-		return nil, fmt.Errorf("invalid status: %s", status)
+	if len(result) == 0 {
+		return ErrOfferingNotFound
 	}
 
-	updateData := map[string]interface{}{
-		"status": status,
+	return nil
+}
+
+func offeringRow(req *models.ClassOfferingRequest) map[string]interface{} {
+	return map[string]interface{}{
+		"name":          req.Name,
+		"description":   req.Description,
+		"price":         req.Price,
+		"active":        offeringActive(req),
+		"display_order": req.DisplayOrder,
 	}
+}
 
-	var result []models.Class
-	_, err := db.client.From("classes").Update(updateData, "", "").Eq("id", fmt.Sprintf("%d", classID)).ExecuteTo(&result)
+// Cohort methods
+func (db *Database) CreateCohort(ctx context.Context, req *models.CohortRequest) (*models.Cohort, error) {
+	var result []models.Cohort
+	_, err := db.client.From("class_cohorts").Insert(cohortRow(req), false, "", "", "").ExecuteTo(&result)
+	if isPostgrestForeignKeyViolation(err) {
+		return nil, ErrOfferingNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cohort: %w", err)
+	}
+
+	if len(result) == 0 {
+		return nil, fmt.Errorf("no cohort created")
+	}
+
+	// A new cohort has no enrollments yet
+	result[0].SetSeatsTaken(0)
+	return &result[0], nil
+}
+
+func (db *Database) GetCohorts(ctx context.Context, filter CohortFilter) ([]models.Cohort, error) {
+	query := db.client.From("class_cohorts").Select("*", "", false)
+	if filter.OfferingID != nil {
+		query = query.Eq("offering_id", strconv.Itoa(*filter.OfferingID))
+	}
+	if filter.ActiveOnly {
+		query = query.Eq("active", "true")
+	}
+	if filter.StartsFrom != "" {
+		query = query.Gte("start_date", filter.StartsFrom)
+	}
+
+	var cohorts []models.Cohort
+	_, err := query.
+		Order("start_date", &postgrest.OrderOpts{Ascending: true}).
+		Order("id", &postgrest.OrderOpts{Ascending: true}).
+		ExecuteTo(&cohorts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cohorts: %w", err)
+	}
+
+	if err := db.fillSeats(cohorts); err != nil {
+		return nil, fmt.Errorf("failed to get cohorts: %w", err)
+	}
+
+	return cohorts, nil
+}
+
+func (db *Database) GetCohort(ctx context.Context, cohortID int) (*models.Cohort, error) {
+	var cohorts []models.Cohort
+	_, err := db.client.From("class_cohorts").Select("*", "", false).Eq("id", strconv.Itoa(cohortID)).ExecuteTo(&cohorts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cohort: %w", err)
+	}
+
+	if len(cohorts) == 0 {
+		return nil, ErrCohortNotFound
+	}
+
+	if err := db.fillSeats(cohorts); err != nil {
+		return nil, fmt.Errorf("failed to get cohort: %w", err)
+	}
+
+	return &cohorts[0], nil
+}
+
+func (db *Database) UpdateCohort(ctx context.Context, cohortID int, req *models.CohortRequest) (*models.Cohort, error) {
+	var result []models.Cohort
+	_, err := db.client.From("class_cohorts").Update(cohortRow(req), "", "").Eq("id", strconv.Itoa(cohortID)).ExecuteTo(&result)
+	if isPostgrestForeignKeyViolation(err) {
+		return nil, ErrOfferingNotFound
+	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to update class status: %w", err)
+		return nil, fmt.Errorf("failed to update cohort: %w", err)
 	}
 
 	if len(result) == 0 {
-		return nil, fmt.Errorf("class not found")
+		return nil, ErrCohortNotFound
+	}
+
+	if err := db.fillSeats(result); err != nil {
+		return nil, fmt.Errorf("failed to update cohort: %w", err)
 	}
 
 	return &result[0], nil
 }
+
+func (db *Database) DeleteCohort(ctx context.Context, cohortID int) error {
+	var result []models.Cohort
+	_, err := db.client.From("class_cohorts").Delete("", "").Eq("id", strconv.Itoa(cohortID)).ExecuteTo(&result)
+	if err != nil {
+		return fmt.Errorf("failed to delete cohort: %w", err)
+	}
+
+	if len(result) == 0 {
+		return ErrCohortNotFound
+	}
+
+	return nil
+}
+
+// fillSeats counts the pending and confirmed enrollments in each cohort.
+func (db *Database) fillSeats(cohorts []models.Cohort) error {
+	if len(cohorts) == 0 {
+		return nil
+	}
+
+	ids := make([]string, len(cohorts))
+	for i, c := range cohorts {
+		ids[i] = strconv.Itoa(c.ID)
+	}
+
+	var enrollments []struct {
+		CohortID int `json:"cohort_id"`
+	}
+	_, err := db.client.From("classes").Select("cohort_id", "", false).
+		In("cohort_id", ids).
+		In("status", activeStatuses).
+		ExecuteTo(&enrollments)
+	if err != nil {
+		return err
+	}
+
+	taken := make(map[int]int)
+	for _, e := range enrollments {
+		taken[e.CohortID]++
+	}
+	for i := range cohorts {
+		cohorts[i].SetSeatsTaken(taken[cohorts[i].ID])
+	}
+
+	return nil
+}
+
+func cohortRow(req *models.CohortRequest) map[string]interface{} {
+	return map[string]interface{}{
+		"offering_id":   req.OfferingID,
+		"name":          req.Name,
+		"start_date":    req.StartDate,
+		"end_date":      req.EndDate,
+		"meeting_times": req.MeetingTimes,
+		"instructor":    req.Instructor,
+		"capacity":      req.Capacity,
+		"active":        cohortActive(req),
+	}
+}
+
+// Class methods
+// CreateClass counts the cohort's seats before inserting. As with
+// CreateAppointment, PostgREST offers no transaction to hold between the two,
+// so simultaneous enrollments can overfill a cohort by a seat or two.
+func (db *Database) CreateClass(ctx context.Context, req *models.ClassRequest, manageTokenHash string, waitlistWhenFull bool) (*models.Class, error) {
+	status := "pending"
+	if req.CohortID != nil {
+		cohort, err := db.GetCohort(ctx, *req.CohortID)
+		if err != nil {
+			return nil, err
+		}
+		if status, err = enrollmentStatus(cohort.Capacity, cohort.SeatsTaken, waitlistWhenFull); err != nil {
+			return nil, err
+		}
+	}
+
+	customerID, err := db.matchCustomer(req.Name, req.Email, req.Phone)
+	if err != nil {
+		return nil, err
+	}
+
+	class := map[string]interface{}{
+		"name":               req.Name,
+		"email":              req.Email,
+		"phone":              req.Phone,
+		"class_type":         req.ClassType,
+		"experience_level":   req.Experience,
+		"goals":              req.Goals,
+		"preferred_schedule": req.Schedule,
+		"cohort_id":          req.CohortID,
+		"customer_id":        customerID,
+		"channels":           bookingChannels(req.Channels),
+		"status":             status,
+		"manage_token_hash":  manageTokenHash,
+	}
+
+	var result []models.Class
+	_, err = db.client.From("classes").Insert(class, false, "", "", "").ExecuteTo(&result)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create class enrollment: %w", err)
+	}
+
+	if len(result) == 0 {
+		return nil, fmt.Errorf("no class enrollment created")
+	}
+
+	return &result[0], nil
+}
+
+func (db *Database) GetClasses(ctx context.Context, filter ClassFilter, page Page) ([]models.Class, int, error) {
+	query := db.client.From("classes").Select("*", "exact", false)
+	var and []string
+	if filter.Status != "" {
+		query = query.Eq("status", filter.Status)
+	}
+	if filter.ClassType != "" {
+		query = query.Eq("class_type", filter.ClassType)
+	}
+	if filter.CohortID != nil {
+		query = query.Eq("cohort_id", strconv.Itoa(*filter.CohortID))
+	}
+	if filter.CustomerID != nil {
+		query = query.Eq("customer_id", strconv.Itoa(*filter.CustomerID))
+	}
+	if filter.Email != "" {
+		query = query.Ilike("email", escapeLike(filter.Email))
+	}
+	if filter.Phone != "" {
+		query = query.Eq("phone", filter.Phone)
+	}
+	if filter.CreatedFrom != "" {
+		and = append(and, "created_at.gte."+filter.CreatedFrom)
+	}
+	if filter.CreatedTo != "" {
+		next, err := dayAfter(filter.CreatedTo)
+		if err != nil {
+			return nil, 0, err
+		}
+		and = append(and, "created_at.lt."+next)
+	}
+	if filter.Search != "" {
+		and = append(and, searchGroup(filter.Search, "name", "email", "goals"))
+	}
+	if len(and) > 0 {
+		query = query.Or("and("+strings.Join(and, ",")+")", "")
+	}
+
+	var classes []models.Class
+	total, err := orderPage(query, page, ClassSorts).ExecuteTo(&classes)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get classes: %w", err)
+	}
+
+	return classes, int(total), nil
+}
+
+// orderPage sorts query and limits it to page.
+func orderPage(query *postgrest.FilterBuilder, page Page, sorts []string) *postgrest.FilterBuilder {
+	opts := &postgrest.OrderOpts{Ascending: !page.Descending}
+	query = query.Order(sortColumn(page, sorts), opts).Order("id", opts)
+	if page.Limit > 0 {
+		return query.Range(page.Offset, page.Offset+page.Limit-1, "")
+	}
+	if page.Offset > 0 {
+		// PostgREST has no offset without a limit, so ask for every row.
+		return query.Range(page.Offset, math.MaxInt32, "")
+	}
+	return query
+}
+
+// searchGroup matches rows where any of columns contains search, ignoring
+// case. The pattern is quoted so that commas and parentheses in it don't
+// break the filter.
+func searchGroup(search string, columns ...string) string {
+	pattern := `"*` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(escapeLike(search)) + `*"`
+	conditions := make([]string, len(columns))
+	for i, column := range columns {
+		conditions[i] = column + ".ilike." + pattern
+	}
+	return "or(" + strings.Join(conditions, ",") + ")"
+}
+
+func (db *Database) GetClass(ctx context.Context, classID int) (*models.Class, error) {
+	var result []models.Class
+	_, err := db.client.From("classes").Select("*", "", false).Eq("id", strconv.Itoa(classID)).ExecuteTo(&result)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get class enrollment: %w", err)
+	}
+
+	if len(result) == 0 {
+		return nil, ErrClassNotFound
+	}
+
+	return &result[0], nil
+}
+
+func (db *Database) GetClassByManageToken(ctx context.Context, tokenHash string) (*models.Class, error) {
+	var result []models.Class
+	_, err := db.client.From("classes").Select("*", "", false).Eq("manage_token_hash", tokenHash).ExecuteTo(&result)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get class enrollment: %w", err)
+	}
+
+	if len(result) == 0 {
+		return nil, ErrClassNotFound
+	}
+
+	return &result[0], nil
+}
+
+// ChangeClassCohort counts seats and moves the enrollment in separate
+// requests, so like CreateClass it can overfill a cohort under contention.
+func (db *Database) ChangeClassCohort(ctx context.Context, classID, cohortID int) (*models.Class, error) {
+	cohort, err := db.GetCohort(ctx, cohortID)
+	if err != nil {
+		return nil, err
+	}
+
+	var current []models.Class
+	_, err = db.client.From("classes").Select("*", "", false).Eq("id", strconv.Itoa(classID)).ExecuteTo(&current)
+	if err != nil {
+		return nil, fmt.Errorf("failed to change cohort: %w", err)
+	}
+	if len(current) == 0 {
+		return nil, ErrClassNotFound
+	}
+	if !isActiveStatus(current[0].Status) {
+		return nil, ErrBookingClosed
+	}
+	if _, err := enrollmentStatus(cohort.Capacity, cohort.SeatsTaken, false); err != nil {
+		return nil, err
+	}
+
+	updateData := map[string]interface{}{
+		"cohort_id": cohortID,
+	}
+
+	var result []models.Class
+	_, err = db.client.From("classes").Update(updateData, "", "").
+		Eq("id", strconv.Itoa(classID)).
+		Eq("status", current[0].Status).
+		ExecuteTo(&result)
+	if err != nil {
+		return nil, fmt.Errorf("failed to change cohort: %w", err)
+	}
+
+	if len(result) == 0 {
+		return nil, ErrBookingClosed
+	}
+
+	return &result[0], nil
+}
+
+func (db *Database) UpdateClassChannels(ctx context.Context, classID int, channels []string) (*models.Class, error) {
+	updateData := map[string]interface{}{
+		"channels": bookingChannels(channels),
+	}
+
+	var result []models.Class
+	_, err := db.client.From("classes").Update(updateData, "", "").
+		Eq("id", strconv.Itoa(classID)).
+		ExecuteTo(&result)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update enrollment channels: %w", err)
+	}
+
+	if len(result) == 0 {
+		return nil, ErrClassNotFound
+	}
+
+	return &result[0], nil
+}
+
+// Waitlist methods
+func (db *Database) CreateWaitlistEntry(ctx context.Context, entry *models.WaitlistEntry) (*models.WaitlistEntry, error) {
+	status := entry.Status
+	if status == "" {
+		status = "waiting"
+	}
+
+	row := map[string]interface{}{
+		"kind":             entry.Kind,
+		"name":             entry.Name,
+		"email":            entry.Email,
+		"phone":            entry.Phone,
+		"cohort_id":        entry.CohortID,
+		"class_id":         entry.ClassID,
+		"service":          entry.Service,
+		"appointment_date": entry.AppointmentDate,
+		"preferred_time":   entry.PreferredTime,
+		"artist_id":        entry.ArtistID,
+		"status":           status,
+		"token_hash":       entry.TokenHash,
+	}
+
+	var result []models.WaitlistEntry
+	_, err := db.client.From("waitlist_entries").Insert(row, false, "", "", "").ExecuteTo(&result)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create waitlist entry: %w", err)
+	}
+
+	if len(result) == 0 {
+		return nil, fmt.Errorf("no waitlist entry created")
+	}
+
+	return &result[0], nil
+}
+
+func (db *Database) GetWaitlistEntries(ctx context.Context, filter WaitlistFilter) ([]models.WaitlistEntry, error) {
+	query := db.client.From("waitlist_entries").Select("*", "", false)
+	if filter.Kind != "" {
+		query = query.Eq("kind", filter.Kind)
+	}
+	if filter.Status != "" {
+		query = query.Eq("status", filter.Status)
+	}
+	if filter.CohortID != nil {
+		query = query.Eq("cohort_id", strconv.Itoa(*filter.CohortID))
+	}
+	if filter.ClassID != nil {
+		query = query.Eq("class_id", strconv.Itoa(*filter.ClassID))
+	}
+	if filter.AppointmentID != nil {
+		query = query.Eq("appointment_id", strconv.Itoa(*filter.AppointmentID))
+	}
+	if filter.Date != "" {
+		query = query.Eq("appointment_date", filter.Date)
+	}
+
+	var entries []models.WaitlistEntry
+	_, err := query.
+		Order("created_at", &postgrest.OrderOpts{Ascending: true}).
+		Order("id", &postgrest.OrderOpts{Ascending: true}).
+		ExecuteTo(&entries)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get waitlist entries: %w", err)
+	}
+
+	return entries, nil
+}
+
+func (db *Database) GetWaitlistEntry(ctx context.Context, entryID int) (*models.WaitlistEntry, error) {
+	var entries []models.WaitlistEntry
+	_, err := db.client.From("waitlist_entries").Select("*", "", false).Eq("id", strconv.Itoa(entryID)).ExecuteTo(&entries)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get waitlist entry: %w", err)
+	}
+
+	if len(entries) == 0 {
+		return nil, ErrWaitlistNotFound
+	}
+
+	return &entries[0], nil
+}
+
+func (db *Database) GetWaitlistEntryByToken(ctx context.Context, tokenHash string) (*models.WaitlistEntry, error) {
+	var entries []models.WaitlistEntry
+	_, err := db.client.From("waitlist_entries").Select("*", "", false).Eq("token_hash", tokenHash).ExecuteTo(&entries)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get waitlist entry: %w", err)
+	}
+
+	if len(entries) == 0 {
+		return nil, ErrWaitlistNotFound
+	}
+
+	return &entries[0], nil
+}
+
+func (db *Database) UpdateWaitlistEntry(ctx context.Context, entry *models.WaitlistEntry) (*models.WaitlistEntry, error) {
+	updateData := map[string]interface{}{
+		"status":          entry.Status,
+		"hold_expires_at": entry.HoldExpiresAt,
+		"appointment_id":  entry.AppointmentID,
+	}
+
+	var result []models.WaitlistEntry
+	_, err := db.client.From("waitlist_entries").Update(updateData, "", "").Eq("id", strconv.Itoa(entry.ID)).ExecuteTo(&result)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update waitlist entry: %w", err)
+	}
+
+	if len(result) == 0 {
+		return nil, ErrWaitlistNotFound
+	}
+
+	return &result[0], nil
+}
+
+// PromoteWaitlistedClass counts the cohort's seats, changes the enrollment's
+// status and saves the entry in separate requests, so like CreateClass it
+// can overfill a cohort under contention.
+func (db *Database) PromoteWaitlistedClass(ctx context.Context, entry *models.WaitlistEntry, change StatusChange, overfill bool) (*models.Class, *models.WaitlistEntry, error) {
+	if err := validateClassStatus(change.Status); err != nil {
+		return nil, nil, err
+	}
+	if entry.ClassID == nil {
+		return nil, nil, ErrClassNotFound
+	}
+	if entry.CohortID != nil && !overfill {
+		cohort, err := db.GetCohort(ctx, *entry.CohortID)
+		if err != nil {
+			return nil, nil, err
+		}
+		if _, err := enrollmentStatus(cohort.Capacity, cohort.SeatsTaken, false); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	var result []models.Class
+	if err := db.changeStatus("classes", "class", *entry.ClassID, change, &result); err != nil {
+		return nil, nil, statusChangeError(err, "failed to promote waitlisted class")
+	}
+	if len(result) == 0 {
+		return nil, nil, ErrClassNotFound
+	}
+
+	updated, err := db.UpdateWaitlistEntry(ctx, entry)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &result[0], updated, nil
+}
+
+// notificationRow reads a notification with its sealed secret, which the
+// notification never returns to API clients.
+type notificationRow struct {
+	models.Notification
+	Secret string `json:"secret"`
+}
+
+func notificationsFromRows(rows []notificationRow) []models.Notification {
+	notifications := make([]models.Notification, len(rows))
+	for i, row := range rows {
+		notifications[i] = row.Notification
+		notifications[i].Secret = row.Secret
+	}
+	return notifications
+}
+
+// Notification outbox methods
+func (db *Database) CreateNotification(ctx context.Context, notification *models.Notification) (*models.Notification, error) {
+	channel := notification.Channel
+	if channel == "" {
+		channel = "email"
+	}
+	nextAttempt := notification.NextAttemptAt
+	if nextAttempt.IsZero() {
+		nextAttempt = time.Now()
+	}
+
+	row := map[string]interface{}{
+		"channel":         channel,
+		"event":           notification.Event,
+		"resource_type":   notification.ResourceType,
+		"resource_id":     notification.ResourceID,
+		"recipient":       notification.Recipient,
+		"subject":         notification.Subject,
+		"body":            notification.Body,
+		"secret":          notification.Secret,
+		"next_attempt_at": nextAttempt.UTC(),
+	}
+
+	var result []notificationRow
+	_, err := db.client.From("notification_outbox").Insert(row, false, "", "", "").ExecuteTo(&result)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create notification: %w", err)
+	}
+
+	if len(result) == 0 {
+		return nil, fmt.Errorf("no notification created")
+	}
+
+	return &notificationsFromRows(result)[0], nil
+}
+
+// ClaimNotifications claims each due row with an update conditioned on its
+// attempt count, so a row another instance claimed first is skipped.
+func (db *Database) ClaimNotifications(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]models.Notification, error) {
+	var due []notificationRow
+	_, err := db.client.From("notification_outbox").Select("*", "", false).
+		Eq("status", "pending").
+		Lte("next_attempt_at", now.UTC().Format(time.RFC3339Nano)).
+		Order("next_attempt_at", &postgrest.OrderOpts{Ascending: true}).
+		Limit(limit, "").
+		ExecuteTo(&due)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim notifications: %w", err)
+	}
+
+	claimed := []models.Notification{}
+	for _, n := range due {
+		updateData := map[string]interface{}{
+			"attempts":        n.Attempts + 1,
+			"next_attempt_at": now.Add(lease).UTC(),
+		}
+
+		var result []notificationRow
+		_, err := db.client.From("notification_outbox").Update(updateData, "", "").
+			Eq("id", strconv.Itoa(n.ID)).
+			Eq("attempts", strconv.Itoa(n.Attempts)).
+			ExecuteTo(&result)
+		if err != nil {
+			return nil, fmt.Errorf("failed to claim notifications: %w", err)
+		}
+		claimed = append(claimed, notificationsFromRows(result)...)
+	}
+
+	return claimed, nil
+}
+
+func (db *Database) GetNotifications(ctx context.Context, filter NotificationFilter) ([]models.Notification, error) {
+	query := db.client.From("notification_outbox").Select("*", "", false)
+	if filter.Channel != "" {
+		query = query.Eq("channel", filter.Channel)
+	}
+	if filter.Status != "" {
+		query = query.Eq("status", filter.Status)
+	}
+	if filter.ResourceType != "" {
+		query = query.Eq("resource_type", filter.ResourceType)
+	}
+	if filter.ResourceID != nil {
+		query = query.Eq("resource_id", strconv.Itoa(*filter.ResourceID))
+	}
+	if filter.ProviderMessageID != "" {
+		query = query.Eq("provider_message_id", filter.ProviderMessageID)
+	}
+
+	var rows []notificationRow
+	_, err := query.
+		Order("created_at", &postgrest.OrderOpts{Ascending: true}).
+		Order("id", &postgrest.OrderOpts{Ascending: true}).
+		ExecuteTo(&rows)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get notifications: %w", err)
+	}
+
+	return notificationsFromRows(rows), nil
+}
+
+func (db *Database) UpdateNotification(ctx context.Context, notification *models.Notification) (*models.Notification, error) {
+	updateData := map[string]interface{}{
+		"status":              notification.Status,
+		"last_error":          notification.LastError,
+		"next_attempt_at":     notification.NextAttemptAt.UTC(),
+		"sent_at":             notification.SentAt,
+		"provider_message_id": notification.ProviderMessageID,
+		"delivered_at":        notification.DeliveredAt,
+		"secret":              notification.Secret,
+	}
+
+	var result []notificationRow
+	_, err := db.client.From("notification_outbox").Update(updateData, "", "").Eq("id", strconv.Itoa(notification.ID)).ExecuteTo(&result)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update notification: %w", err)
+	}
+
+	if len(result) == 0 {
+		return nil, ErrNotificationNotFound
+	}
+
+	return &notificationsFromRows(result)[0], nil
+}
+
+// Notification preference methods
+func (db *Database) GetNotificationPreference(ctx context.Context, email string) (*models.NotificationPreference, error) {
+	var result []models.NotificationPreference
+	_, err := db.client.From("notification_preferences").Select("*", "", false).Eq("email", preferenceKey(email)).ExecuteTo(&result)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get notification preference: %w", err)
+	}
+
+	if len(result) == 0 {
+		return nil, ErrPreferenceNotFound
+	}
+
+	return &result[0], nil
+}
+
+func (db *Database) SaveNotificationPreference(ctx context.Context, email string, channels []string) (*models.NotificationPreference, error) {
+	row := map[string]interface{}{
+		"email":    preferenceKey(email),
+		"channels": channels,
+	}
+
+	var result []models.NotificationPreference
+	_, err := db.client.From("notification_preferences").Insert(row, true, "email", "", "").ExecuteTo(&result)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save notification preference: %w", err)
+	}
+
+	if len(result) == 0 {
+		return nil, fmt.Errorf("no notification preference saved")
+	}
+
+	return &result[0], nil
+}
+
+// Job methods
+func (db *Database) CreateJob(ctx context.Context, job *models.Job) (*models.Job, error) {
+	payload := job.Payload
+	if len(payload) == 0 {
+		payload = json.RawMessage(`{}`)
+	}
+
+	row := map[string]interface{}{
+		"kind":          job.Kind,
+		"resource_type": job.ResourceType,
+		"resource_id":   job.ResourceID,
+		"payload":       payload,
+		"run_at":        job.RunAt.UTC(),
+	}
+
+	var result []models.Job
+	_, err := db.client.From("scheduled_jobs").Insert(row, false, "", "", "").ExecuteTo(&result)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create job: %w", err)
+	}
+
+	if len(result) == 0 {
+		return nil, fmt.Errorf("no job created")
+	}
+
+	return &result[0], nil
+}
+
+func (db *Database) GetJobs(ctx context.Context, filter JobFilter) ([]models.Job, error) {
+	query := db.client.From("scheduled_jobs").Select("*", "", false)
+	if filter.Kind != "" {
+		query = query.Eq("kind", filter.Kind)
+	}
+	if filter.Status != "" {
+		query = query.Eq("status", filter.Status)
+	}
+	if filter.ResourceType != "" {
+		query = query.Eq("resource_type", filter.ResourceType)
+	}
+	if filter.ResourceID != nil {
+		query = query.Eq("resource_id", strconv.Itoa(*filter.ResourceID))
+	}
+
+	var jobs []models.Job
+	_, err := query.
+		Order("run_at", &postgrest.OrderOpts{Ascending: true}).
+		Order("id", &postgrest.OrderOpts{Ascending: true}).
+		ExecuteTo(&jobs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get jobs: %w", err)
+	}
+
+	return jobs, nil
+}
+
+// ClaimJobs claims each due job with an update conditioned on its attempt
+// count, so a job another instance claimed first is skipped.
+func (db *Database) ClaimJobs(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]models.Job, error) {
+	var due []models.Job
+	_, err := db.client.From("scheduled_jobs").Select("*", "", false).
+		Eq("status", "pending").
+		Lte("run_at", now.UTC().Format(time.RFC3339Nano)).
+		Order("run_at", &postgrest.OrderOpts{Ascending: true}).
+		Limit(limit, "").
+		ExecuteTo(&due)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim jobs: %w", err)
+	}
+
+	claimed := []models.Job{}
+	for _, j := range due {
+		updateData := map[string]interface{}{
+			"attempts": j.Attempts + 1,
+			"run_at":   now.Add(lease).UTC(),
+		}
+
+		var result []models.Job
+		_, err := db.client.From("scheduled_jobs").Update(updateData, "", "").
+			Eq("id", strconv.Itoa(j.ID)).
+			Eq("attempts", strconv.Itoa(j.Attempts)).
+			ExecuteTo(&result)
+		if err != nil {
+			return nil, fmt.Errorf("failed to claim jobs: %w", err)
+		}
+		claimed = append(claimed, result...)
+	}
+
+	return claimed, nil
+}
+
+func (db *Database) UpdateJob(ctx context.Context, job *models.Job) (*models.Job, error) {
+	updateData := map[string]interface{}{
+		"status":     job.Status,
+		"last_error": job.LastError,
+		"run_at":     job.RunAt.UTC(),
+	}
+
+	var result []models.Job
+	_, err := db.client.From("scheduled_jobs").Update(updateData, "", "").Eq("id", strconv.Itoa(job.ID)).ExecuteTo(&result)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update job: %w", err)
+	}
+
+	if len(result) == 0 {
+		return nil, ErrJobNotFound
+	}
+
+	return &result[0], nil
+}
+
+func (db *Database) CancelJobs(ctx context.Context, resourceType string, resourceID int) error {
+	_, _, err := db.client.From("scheduled_jobs").Update(map[string]interface{}{"status": "cancelled"}, "minimal", "").
+		Eq("resource_type", resourceType).
+		Eq("resource_id", strconv.Itoa(resourceID)).
+		Eq("status", "pending").
+		Execute()
+	if err != nil {
+		return fmt.Errorf("failed to cancel jobs: %w", err)
+	}
+
+	return nil
+}
+
+// Webhook subscription methods
+
+// webhookSelect leaves out the secret, which is only read by GetWebhookSecret.
+const webhookSelect = "id,url,events,description,active,created_at,updated_at"
+
+func (db *Database) CreateWebhookSubscription(ctx context.Context, req *models.WebhookSubscriptionRequest) (*models.WebhookSubscription, error) {
+	row := webhookRow(req)
+	row["secret"] = req.Secret
+
+	var result []models.WebhookSubscription
+	_, err := db.client.From("webhook_subscriptions").Insert(row, false, "", "", "").ExecuteTo(&result)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create webhook subscription: %w", err)
+	}
+
+	if len(result) == 0 {
+		return nil, fmt.Errorf("no webhook subscription created")
+	}
+
+	return &result[0], nil
+}
+
+func (db *Database) GetWebhookSubscriptions(ctx context.Context, activeOnly bool) ([]models.WebhookSubscription, error) {
+	query := db.client.From("webhook_subscriptions").Select(webhookSelect, "", false)
+	if activeOnly {
+		query = query.Eq("active", "true")
+	}
+
+	var subscriptions []models.WebhookSubscription
+	_, err := query.Order("id", &postgrest.OrderOpts{Ascending: true}).ExecuteTo(&subscriptions)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhook subscriptions: %w", err)
+	}
+
+	return subscriptions, nil
+}
+
+func (db *Database) GetWebhookSubscription(ctx context.Context, subscriptionID int) (*models.WebhookSubscription, error) {
+	var result []models.WebhookSubscription
+	_, err := db.client.From("webhook_subscriptions").Select(webhookSelect, "", false).
+		Eq("id", strconv.Itoa(subscriptionID)).
+		ExecuteTo(&result)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhook subscription: %w", err)
+	}
+
+	if len(result) == 0 {
+		return nil, ErrWebhookNotFound
+	}
+
+	return &result[0], nil
+}
+
+func (db *Database) GetWebhookSecret(ctx context.Context, subscriptionID int) (string, error) {
+	var result []struct {
+		Secret string `json:"secret"`
+	}
+
+	_, err := db.client.From("webhook_subscriptions").Select("secret", "", false).
+		Eq("id", strconv.Itoa(subscriptionID)).
+		ExecuteTo(&result)
+	if err != nil {
+		return "", fmt.Errorf("failed to get webhook secret: %w", err)
+	}
+
+	if len(result) == 0 {
+		return "", ErrWebhookNotFound
+	}
+
+	return result[0].Secret, nil
+}
+
+func (db *Database) UpdateWebhookSubscription(ctx context.Context, subscriptionID int, req *models.WebhookSubscriptionRequest) (*models.WebhookSubscription, error) {
+	row := webhookRow(req)
+	if req.Secret != "" {
+		row["secret"] = req.Secret
+	}
+
+	var result []models.WebhookSubscription
+	_, err := db.client.From("webhook_subscriptions").Update(row, "", "").Eq("id", strconv.Itoa(subscriptionID)).ExecuteTo(&result)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update webhook subscription: %w", err)
+	}
+
+	if len(result) == 0 {
+		return nil, ErrWebhookNotFound
+	}
+
+	return &result[0], nil
+}
+
+func (db *Database) DeleteWebhookSubscription(ctx context.Context, subscriptionID int) error {
+	var result []models.WebhookSubscription
+	_, err := db.client.From("webhook_subscriptions").Delete("", "").Eq("id", strconv.Itoa(subscriptionID)).ExecuteTo(&result)
+	if err != nil {
+		return fmt.Errorf("failed to delete webhook subscription: %w", err)
+	}
+
+	if len(result) == 0 {
+		return ErrWebhookNotFound
+	}
+
+	return nil
+}
+
+func webhookRow(req *models.WebhookSubscriptionRequest) map[string]interface{} {
+	return map[string]interface{}{
+		"url":         req.URL,
+		"events":      req.Events,
+		"description": req.Description,
+		"active":      webhookActive(req),
+	}
+}
+
+// Webhook delivery methods
+func (db *Database) CreateWebhookDelivery(ctx context.Context, delivery *models.WebhookDelivery) (*models.WebhookDelivery, error) {
+	nextAttempt := delivery.NextAttemptAt
+	if nextAttempt.IsZero() {
+		nextAttempt = time.Now()
+	}
+
+	row := map[string]interface{}{
+		"subscription_id": delivery.SubscriptionID,
+		"event":           delivery.Event,
+		"payload":         delivery.Payload,
+		"next_attempt_at": nextAttempt.UTC(),
+	}
+
+	var result []models.WebhookDelivery
+	_, err := db.client.From("webhook_deliveries").Insert(row, false, "", "", "").ExecuteTo(&result)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create webhook delivery: %w", err)
+	}
+
+	if len(result) == 0 {
+		return nil, fmt.Errorf("no webhook delivery created")
+	}
+
+	return &result[0], nil
+}
+
+func (db *Database) GetWebhookDeliveries(ctx context.Context, filter WebhookDeliveryFilter, page Page) ([]models.WebhookDelivery, int, error) {
+	query := db.client.From("webhook_deliveries").Select("*", "exact", false)
+	if filter.SubscriptionID != nil {
+		query = query.Eq("subscription_id", strconv.Itoa(*filter.SubscriptionID))
+	}
+	if filter.Event != "" {
+		query = query.Eq("event", filter.Event)
+	}
+	if filter.Status != "" {
+		query = query.Eq("status", filter.Status)
+	}
+
+	var deliveries []models.WebhookDelivery
+	total, err := orderPage(query, page, WebhookDeliverySorts).ExecuteTo(&deliveries)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get webhook deliveries: %w", err)
+	}
+
+	return deliveries, int(total), nil
+}
+
+func (db *Database) GetWebhookDelivery(ctx context.Context, deliveryID int) (*models.WebhookDelivery, error) {
+	var result []models.WebhookDelivery
+	_, err := db.client.From("webhook_deliveries").Select("*", "", false).Eq("id", strconv.Itoa(deliveryID)).ExecuteTo(&result)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhook delivery: %w", err)
+	}
+
+	if len(result) == 0 {
+		return nil, ErrDeliveryNotFound
+	}
+
+	return &result[0], nil
+}
+
+// ClaimWebhookDeliveries claims each due delivery with an update conditioned
+// on its attempt count, so a delivery another instance claimed first is
+// skipped.
+func (db *Database) ClaimWebhookDeliveries(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]models.WebhookDelivery, error) {
+	var due []models.WebhookDelivery
+	_, err := db.client.From("webhook_deliveries").Select("*", "", false).
+		Eq("status", "pending").
+		Lte("next_attempt_at", now.UTC().Format(time.RFC3339Nano)).
+		Order("next_attempt_at", &postgrest.OrderOpts{Ascending: true}).
+		Limit(limit, "").
+		ExecuteTo(&due)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim webhook deliveries: %w", err)
+	}
+
+	claimed := []models.WebhookDelivery{}
+	for _, d := range due {
+		updateData := map[string]interface{}{
+			"attempts":        d.Attempts + 1,
+			"next_attempt_at": now.Add(lease).UTC(),
+		}
+
+		var result []models.WebhookDelivery
+		_, err := db.client.From("webhook_deliveries").Update(updateData, "", "").
+			Eq("id", strconv.Itoa(d.ID)).
+			Eq("attempts", strconv.Itoa(d.Attempts)).
+			ExecuteTo(&result)
+		if err != nil {
+			return nil, fmt.Errorf("failed to claim webhook deliveries: %w", err)
+		}
+		claimed = append(claimed, result...)
+	}
+
+	return claimed, nil
+}
+
+func (db *Database) UpdateWebhookDelivery(ctx context.Context, delivery *models.WebhookDelivery) (*models.WebhookDelivery, error) {
+	updateData := map[string]interface{}{
+		"status":          delivery.Status,
+		"attempts":        delivery.Attempts,
+		"last_error":      delivery.LastError,
+		"response_status": delivery.ResponseStatus,
+		"next_attempt_at": delivery.NextAttemptAt.UTC(),
+		"delivered_at":    delivery.DeliveredAt,
+	}
+
+	var result []models.WebhookDelivery
+	_, err := db.client.From("webhook_deliveries").Update(updateData, "", "").Eq("id", strconv.Itoa(delivery.ID)).ExecuteTo(&result)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update webhook delivery: %w", err)
+	}
+
+	if len(result) == 0 {
+		return nil, ErrDeliveryNotFound
+	}
+
+	return &result[0], nil
+}
+
+// Admin methods
+// adminSelect leaves the password hash out of admin rows.
+const adminSelect = "id,email,name,role,artist_id,two_factor_enabled,created_at,updated_at"
+
+func (db *Database) CreateAdmin(ctx context.Context, email, passwordHash, name, role string, artistID *int) (*models.AdminUser, error) {
+	admin := map[string]interface{}{
+		"email":         email,
+		"password_hash": passwordHash,
+		"name":          name,
+		"role":          role,
+		"artist_id":     artistID,
+	}
+
+	var result []models.AdminUser
+	_, err := db.client.From("admin_users").Insert(admin, false, "", "", "").ExecuteTo(&result)
+	if isPostgrestUniqueViolation(err) {
+		return nil, ErrAdminExists
+	}
+	if isPostgrestForeignKeyViolation(err) {
+		return nil, ErrArtistNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create admin: %w", err)
+	}
+
+	if len(result) == 0 {
+		return nil, fmt.Errorf("no admin created")
+	}
+
+	return &result[0], nil
+}
+
+func (db *Database) GetAdmins(ctx context.Context) ([]models.AdminUser, error) {
+	var admins []models.AdminUser
+	_, err := db.client.From("admin_users").Select(adminSelect, "", false).Order("created_at", &postgrest.OrderOpts{Ascending: true}).ExecuteTo(&admins)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get admins: %w", err)
+	}
+
+	return admins, nil
+}
+
+func (db *Database) GetAdmin(ctx context.Context, adminID string) (*models.AdminUser, error) {
+	var admins []models.AdminUser
+	_, err := db.client.From("admin_users").Select(adminSelect, "", false).Eq("id", adminID).ExecuteTo(&admins)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get admin: %w", err)
+	}
+
+	if len(admins) == 0 {
+		return nil, ErrAdminNotFound
+	}
+
+	return &admins[0], nil
+}
+
+// UpdateAdminRole calls the update_admin_role function, which counts the
+// owners and updates the admin in one transaction as PostgREST requests
+// can't.
+func (db *Database) UpdateAdminRole(ctx context.Context, adminID, role string, artistID *int) (*models.AdminUser, error) {
+	params := map[string]interface{}{
+		"target_id":     adminID,
+		"new_role":      role,
+		"new_artist_id": artistID,
+	}
+
+	err := db.rpc("update_admin_role", params, nil)
+	switch {
+	case isPostgrestNoDataFound(err):
+		return nil, ErrAdminNotFound
+	case isPostgrestRestrictViolation(err):
+		return nil, ErrLastOwner
+	case isPostgrestForeignKeyViolation(err):
+		return nil, ErrArtistNotFound
+	case err != nil:
+		return nil, fmt.Errorf("failed to update admin role: %w", err)
+	}
+
+	return db.GetAdmin(ctx, adminID)
+}
+
+func (db *Database) GetAdminByEmail(ctx context.Context, email string) (*models.AdminUser, error) {
+	var admins []models.AdminUser
+	_, err := db.client.From("admin_users").Select(adminSelect, "", false).Eq("email", email).ExecuteTo(&admins)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get admin by email: %w", err)
+	}
+
+	if len(admins) == 0 {
+		return nil, ErrAdminNotFound
+	}
+
+	return &admins[0], nil
+}
+
+func (db *Database) GetAdminPasswordHash(ctx context.Context, email string) (string, error) {
+	var result []struct {
+		PasswordHash string `json:"password_hash"`
+	}
+
+	_, err := db.client.From("admin_users").Select("password_hash", "", false).Eq("email", email).ExecuteTo(&result)
+	if err != nil {
+		return "", fmt.Errorf("failed to get admin password hash: %w", err)
+	}
+
+	if len(result) == 0 {
+		return "", ErrAdminNotFound
+	}
+
+	return result[0].PasswordHash, nil
+}
+
+func (db *Database) UpdateAdminPassword(ctx context.Context, adminID, passwordHash string) error {
+	update := map[string]interface{}{
+		"password_hash": passwordHash,
+	}
+
+	var admins []models.AdminUser
+	_, err := db.client.From("admin_users").Update(update, "", "").Eq("id", adminID).ExecuteTo(&admins)
+	if err != nil {
+		return fmt.Errorf("failed to update admin password: %w", err)
+	}
+
+	if len(admins) == 0 {
+		return ErrAdminNotFound
+	}
+
+	return nil
+}
+
+// Password reset methods
+func (db *Database) CreatePasswordReset(ctx context.Context, adminID, tokenHash string, expiresAt time.Time) (int, error) {
+	reset := map[string]interface{}{
+		"admin_id":   adminID,
+		"token_hash": tokenHash,
+		"expires_at": expiresAt.UTC().Format(time.RFC3339),
+	}
+
+	var result []struct {
+		ID int `json:"id"`
+	}
+	_, err := db.client.From("admin_password_resets").Insert(reset, false, "", "", "").ExecuteTo(&result)
+	if isPostgrestForeignKeyViolation(err) {
+		return 0, ErrAdminNotFound
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to create password reset: %w", err)
+	}
+
+	if len(result) == 0 {
+		return 0, fmt.Errorf("no password reset created")
+	}
+
+	return result[0].ID, nil
+}
+
+// ResetAdminPassword uses the reset with an update conditional on it being
+// unused, so the token works once, then sets the password and ends the
+// admin's sessions.
+func (db *Database) ResetAdminPassword(ctx context.Context, tokenHash, passwordHash string) (*models.AdminUser, error) {
+	now := time.Now().UTC().Format(time.RFC3339)
+	used := map[string]interface{}{
+		"used_at": now,
+	}
+
+	var resets []struct {
+		AdminID string `json:"admin_id"`
+	}
+	_, err := db.client.From("admin_password_resets").Update(used, "", "").
+		Eq("token_hash", tokenHash).
+		Is("used_at", "null").
+		Gt("expires_at", now).
+		ExecuteTo(&resets)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reset admin password: %w", err)
+	}
+	if len(resets) == 0 {
+		return nil, ErrResetNotFound
+	}
+	adminID := resets[0].AdminID
+
+	_, _, err = db.client.From("admin_password_resets").Update(used, "", "").
+		Eq("admin_id", adminID).
+		Is("used_at", "null").
+		Execute()
+	if err != nil {
+		return nil, fmt.Errorf("failed to reset admin password: %w", err)
+	}
+
+	update := map[string]interface{}{
+		"password_hash": passwordHash,
+	}
+	var admins []models.AdminUser
+	_, err = db.client.From("admin_users").Update(update, "", "").Eq("id", adminID).ExecuteTo(&admins)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reset admin password: %w", err)
+	}
+	if len(admins) == 0 {
+		return nil, ErrResetNotFound
+	}
+
+	if err := db.revokeAdminSessions(adminID, ""); err != nil {
+		return nil, fmt.Errorf("failed to reset admin password: %w", err)
+	}
+
+	return &admins[0], nil
+}
+
+// Admin invite methods
+// inviteSelect leaves the token hash out of invite rows.
+const inviteSelect = "id,email,role,artist_id,invited_by,status,expires_at,accepted_at,created_at,updated_at"
+
+func (db *Database) CreateAdminInvite(ctx context.Context, invite *models.AdminInvite, tokenHash string) (*models.AdminInvite, error) {
+	row := map[string]interface{}{
+		"email":      invite.Email,
+		"role":       invite.Role,
+		"artist_id":  invite.ArtistID,
+		"token_hash": tokenHash,
+		"invited_by": invite.InvitedBy,
+		"expires_at": invite.ExpiresAt.UTC().Format(time.RFC3339),
+	}
+
+	var result []models.AdminInvite
+	_, err := db.client.From("admin_invites").Insert(row, false, "", "", "").ExecuteTo(&result)
+	if isPostgrestForeignKeyViolation(err) {
+		return nil, ErrArtistNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create admin invite: %w", err)
+	}
+
+	if len(result) == 0 {
+		return nil, fmt.Errorf("no admin invite created")
+	}
+
+	return &result[0], nil
+}
+
+func (db *Database) GetAdminInvites(ctx context.Context) ([]models.AdminInvite, error) {
+	var invites []models.AdminInvite
+	_, err := db.client.From("admin_invites").Select(inviteSelect, "", false).Order("created_at", &postgrest.OrderOpts{Ascending: false}).ExecuteTo(&invites)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get admin invites: %w", err)
+	}
+	markExpired(invites, time.Now())
+
+	return invites, nil
+}
+
+func (db *Database) GetAdminInviteByToken(ctx context.Context, tokenHash string) (*models.AdminInvite, error) {
+	var invites []models.AdminInvite
+	_, err := db.client.From("admin_invites").Select(inviteSelect, "", false).
+		Eq("token_hash", tokenHash).
+		Eq("status", "pending").
+		Gt("expires_at", time.Now().UTC().Format(time.RFC3339)).
+		ExecuteTo(&invites)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get admin invite: %w", err)
+	}
+
+	if len(invites) == 0 {
+		return nil, ErrInviteNotFound
+	}
+
+	return &invites[0], nil
+}
+
+func (db *Database) RevokeAdminInvite(ctx context.Context, inviteID int) (*models.AdminInvite, error) {
+	update := map[string]interface{}{
+		"status": "revoked",
+	}
+
+	var invites []models.AdminInvite
+	_, err := db.client.From("admin_invites").Update(update, "", "").
+		Eq("id", strconv.Itoa(inviteID)).
+		Eq("status", "pending").
+		ExecuteTo(&invites)
+	if err != nil {
+		return nil, fmt.Errorf("failed to revoke admin invite: %w", err)
+	}
+
+	if len(invites) == 0 {
+		return nil, ErrInviteNotFound
+	}
+
+	return &invites[0], nil
+}
+
+// RedeemAdminInvite claims the invite with a conditional update, so only one
+// registration can win it, then creates the admin. PostgREST can't wrap both
+// in a transaction, so the claim is handed back if the admin can't be
+// created.
+func (db *Database) RedeemAdminInvite(ctx context.Context, tokenHash, passwordHash, name string) (*models.AdminUser, error) {
+	now := time.Now().UTC().Format(time.RFC3339)
+	claim := map[string]interface{}{
+		"status":      "accepted",
+		"accepted_at": now,
+	}
+
+	var invites []models.AdminInvite
+	_, err := db.client.From("admin_invites").Update(claim, "", "").
+		Eq("token_hash", tokenHash).
+		Eq("status", "pending").
+		Gt("expires_at", now).
+		ExecuteTo(&invites)
+	if err != nil {
+		return nil, fmt.Errorf("failed to redeem admin invite: %w", err)
+	}
+	if len(invites) == 0 {
+		return nil, ErrInviteNotFound
+	}
+	invite := invites[0]
+
+	admin, err := db.CreateAdmin(ctx, invite.Email, passwordHash, name, invite.Role, invite.ArtistID)
+	if err != nil {
+		release := map[string]interface{}{
+			"status":      "pending",
+			"accepted_at": nil,
+		}
+		_, _, releaseErr := db.client.From("admin_invites").Update(release, "", "").Eq("id", strconv.Itoa(invite.ID)).Execute()
+		if releaseErr != nil {
+			log.Printf("Error releasing admin invite %d: %v", invite.ID, releaseErr)
+		}
+		return nil, err
+	}
+
+	return admin, nil
+}
+
+// Admin session methods
+// sessionSelect leaves the refresh token hash and access token out of
+// session rows.
+const sessionSelect = "id,admin_id,user_agent,ip_address,expires_at,last_used_at,revoked_at,created_at,updated_at"
+
+func (db *Database) CreateAdminSession(ctx context.Context, session *models.AdminSession, refreshTokenHash string, access AccessToken) (*models.AdminSession, error) {
+	row := map[string]interface{}{
+		"admin_id":           session.AdminID,
+		"refresh_token_hash": refreshTokenHash,
+		"access_jti":         access.JTI,
+		"access_expires_at":  access.ExpiresAt.UTC().Format(time.RFC3339),
+		"user_agent":         session.UserAgent,
+		"ip_address":         session.IPAddress,
+		"expires_at":         session.ExpiresAt.UTC().Format(time.RFC3339),
+	}
+
+	var result []models.AdminSession
+	_, err := db.client.From("admin_sessions").Insert(row, false, "", "", "").ExecuteTo(&result)
+	if isPostgrestForeignKeyViolation(err) {
+		return nil, ErrAdminNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create admin session: %w", err)
+	}
+
+	if len(result) == 0 {
+		return nil, fmt.Errorf("no admin session created")
+	}
+
+	return &result[0], nil
+}
+
+func (db *Database) GetAdminSessions(ctx context.Context, adminID string) ([]models.AdminSession, error) {
+	var sessions []models.AdminSession
+	_, err := db.client.From("admin_sessions").Select(sessionSelect, "", false).
+		Eq("admin_id", adminID).
+		Is("revoked_at", "null").
+		Gt("expires_at", time.Now().UTC().Format(time.RFC3339)).
+		Order("last_used_at", &postgrest.OrderOpts{Ascending: false}).
+		ExecuteTo(&sessions)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get admin sessions: %w", err)
+	}
+
+	return sessions, nil
+}
+
+// sessionAccess is the access token a session row holds.
+type sessionAccess struct {
+	ID              string    `json:"id"`
+	AccessJTI       string    `json:"access_jti"`
+	AccessExpiresAt time.Time `json:"access_expires_at"`
+}
+
+// RotateAdminSession swaps the refresh token with an update conditional on
+// the old one, so only one refresh can use it, then revokes the replaced
+// access token and remembers the refresh token as used.
+func (db *Database) RotateAdminSession(ctx context.Context, refreshTokenHash, newRefreshTokenHash string, access AccessToken, expiresAt time.Time) (*models.AdminSession, error) {
+	now := time.Now().UTC().Format(time.RFC3339)
+
+	var current []sessionAccess
+	_, err := db.client.From("admin_sessions").Select("id,access_jti,access_expires_at", "", false).
+		Eq("refresh_token_hash", refreshTokenHash).
+		Is("revoked_at", "null").
+		Gt("expires_at", now).
+		ExecuteTo(&current)
+	if err != nil {
+		return nil, fmt.Errorf("failed to rotate admin session: %w", err)
+	}
+	if len(current) == 0 {
+		if err := db.endReusedSession(refreshTokenHash); err != nil {
+			return nil, fmt.Errorf("failed to rotate admin session: %w", err)
+		}
+		return nil, ErrSessionNotFound
+	}
+
+	update := map[string]interface{}{
+		"refresh_token_hash": newRefreshTokenHash,
+		"access_jti":         access.JTI,
+		"access_expires_at":  access.ExpiresAt.UTC().Format(time.RFC3339),
+		"expires_at":         expiresAt.UTC().Format(time.RFC3339),
+		"last_used_at":       now,
+	}
+
+	var sessions []models.AdminSession
+	_, err = db.client.From("admin_sessions").Update(update, "", "").
+		Eq("id", current[0].ID).
+		Eq("refresh_token_hash", refreshTokenHash).
+		ExecuteTo(&sessions)
+	if err != nil {
+		return nil, fmt.Errorf("failed to rotate admin session: %w", err)
+	}
+	if len(sessions) == 0 {
+		return nil, ErrSessionNotFound
+	}
+
+	previous := AccessToken{JTI: current[0].AccessJTI, ExpiresAt: current[0].AccessExpiresAt}
+	if err := db.revokeTokens(previous); err != nil {
+		return nil, fmt.Errorf("failed to rotate admin session: %w", err)
+	}
+
+	used := map[string]interface{}{
+		"token_hash": refreshTokenHash,
+		"session_id": current[0].ID,
+	}
+	if _, _, err := db.client.From("used_refresh_tokens").Insert(used, false, "", "", "").Execute(); err != nil {
+		return nil, fmt.Errorf("failed to rotate admin session: %w", err)
+	}
+
+	return &sessions[0], nil
+}
+
+func (db *Database) RevokeAdminSession(ctx context.Context, adminID, sessionID string) error {
+	update := map[string]interface{}{
+		"revoked_at": time.Now().UTC().Format(time.RFC3339),
+	}
+
+	var sessions []sessionAccess
+	_, err := db.client.From("admin_sessions").Update(update, "", "").
+		Eq("id", sessionID).
+		Eq("admin_id", adminID).
+		Is("revoked_at", "null").
+		Gt("expires_at", time.Now().UTC().Format(time.RFC3339)).
+		ExecuteTo(&sessions)
+	if err != nil {
+		return fmt.Errorf("failed to revoke admin session: %w", err)
+	}
+	if len(sessions) == 0 {
+		return ErrSessionNotFound
+	}
+
+	access := AccessToken{JTI: sessions[0].AccessJTI, ExpiresAt: sessions[0].AccessExpiresAt}
+	if err := db.revokeTokens(access); err != nil {
+		return fmt.Errorf("failed to revoke admin session: %w", err)
+	}
+
+	return nil
+}
+
+func (db *Database) RevokeAdminSessions(ctx context.Context, adminID, exceptSessionID string) error {
+	if err := db.revokeAdminSessions(adminID, exceptSessionID); err != nil {
+		return fmt.Errorf("failed to revoke admin sessions: %w", err)
+	}
+
+	return nil
+}
+
+func (db *Database) IsTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	var result []struct {
+		JTI string `json:"jti"`
+	}
+	_, err := db.client.From("revoked_tokens").Select("jti", "", false).Eq("jti", jti).ExecuteTo(&result)
+	if err != nil {
+		return false, fmt.Errorf("failed to check revoked token: %w", err)
+	}
+
+	return len(result) > 0, nil
+}
+
+// revokeTokens adds tokens to the revoked tokens, forgetting those that
+// have expired.
+func (db *Database) revokeTokens(tokens ...AccessToken) error {
+	_, _, err := db.client.From("revoked_tokens").Delete("", "").Lte("expires_at", time.Now().UTC().Format(time.RFC3339)).Execute()
+	if err != nil || len(tokens) == 0 {
+		return err
+	}
+
+	rows := make([]map[string]interface{}, len(tokens))
+	for i, access := range tokens {
+		rows[i] = map[string]interface{}{
+			"jti":        access.JTI,
+			"expires_at": access.ExpiresAt.UTC().Format(time.RFC3339),
+		}
+	}
+	_, _, err = db.client.From("revoked_tokens").Insert(rows, true, "jti", "", "").Execute()
+	return err
+}
+
+// revokeAdminSessions ends adminID's active sessions but exceptSessionID
+// and revokes their access tokens.
+// endReusedSession ends the active session that already swapped the
+// refresh token with refreshTokenHash, if there is one, and revokes its
+// access token.
+func (db *Database) endReusedSession(refreshTokenHash string) error {
+	var used []struct {
+		SessionID string `json:"session_id"`
+	}
+	_, err := db.client.From("used_refresh_tokens").Select("session_id", "", false).
+		Eq("token_hash", refreshTokenHash).
+		ExecuteTo(&used)
+	if err != nil || len(used) == 0 {
+		return err
+	}
+
+	update := map[string]interface{}{
+		"revoked_at": time.Now().UTC().Format(time.RFC3339),
+	}
+
+	var sessions []sessionAccess
+	_, err = db.client.From("admin_sessions").Update(update, "", "").
+		Eq("id", used[0].SessionID).
+		Is("revoked_at", "null").
+		Gt("expires_at", time.Now().UTC().Format(time.RFC3339)).
+		ExecuteTo(&sessions)
+	if err != nil || len(sessions) == 0 {
+		return err
+	}
+
+	return db.revokeTokens(AccessToken{JTI: sessions[0].AccessJTI, ExpiresAt: sessions[0].AccessExpiresAt})
+}
+
+func (db *Database) revokeAdminSessions(adminID, exceptSessionID string) error {
+	update := map[string]interface{}{
+		"revoked_at": time.Now().UTC().Format(time.RFC3339),
+	}
+
+	query := db.client.From("admin_sessions").Update(update, "", "").
+		Eq("admin_id", adminID).
+		Is("revoked_at", "null").
+		Gt("expires_at", time.Now().UTC().Format(time.RFC3339))
+	if exceptSessionID != "" {
+		query = query.Neq("id", exceptSessionID)
+	}
+
+	var sessions []sessionAccess
+	if _, err := query.ExecuteTo(&sessions); err != nil {
+		return err
+	}
+
+	tokens := make([]AccessToken, len(sessions))
+	for i, session := range sessions {
+		tokens[i] = AccessToken{JTI: session.AccessJTI, ExpiresAt: session.AccessExpiresAt}
+	}
+	return db.revokeTokens(tokens...)
+}
+
+// Two-factor methods
+func (db *Database) GetAdminTwoFactor(ctx context.Context, adminID string) (*TwoFactor, error) {
+	var result []struct {
+		Secret   *string `json:"totp_secret"`
+		Enabled  bool    `json:"two_factor_enabled"`
+		LastStep *int64  `json:"totp_last_step"`
+	}
+	_, err := db.client.From("admin_users").Select("totp_secret,two_factor_enabled,totp_last_step", "", false).Eq("id", adminID).ExecuteTo(&result)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get two-factor settings: %w", err)
+	}
+
+	if len(result) == 0 {
+		return nil, ErrAdminNotFound
+	}
+
+	twoFactor := TwoFactor{Enabled: result[0].Enabled}
+	if result[0].Secret != nil {
+		twoFactor.Secret = *result[0].Secret
+	}
+	if result[0].LastStep != nil {
+		twoFactor.LastStep = *result[0].LastStep
+	}
+	return &twoFactor, nil
+}
+
+func (db *Database) SetAdminTOTPSecret(ctx context.Context, adminID, secret string) error {
+	update := map[string]interface{}{
+		"totp_secret":        secret,
+		"two_factor_enabled": false,
+		"totp_last_step":     nil,
+	}
+
+	var admins []models.AdminUser
+	_, err := db.client.From("admin_users").Update(update, "", "").Eq("id", adminID).ExecuteTo(&admins)
+	if err != nil {
+		return fmt.Errorf("failed to save TOTP secret: %w", err)
+	}
+
+	if len(admins) == 0 {
+		return ErrAdminNotFound
+	}
+
+	return nil
+}
+
+func (db *Database) EnableAdminTwoFactor(ctx context.Context, adminID string, step int64, recoveryCodeHashes []string) error {
+	update := map[string]interface{}{
+		"two_factor_enabled": true,
+		"totp_last_step":     step,
+	}
+
+	var admins []models.AdminUser
+	_, err := db.client.From("admin_users").Update(update, "", "").Eq("id", adminID).Not("totp_secret", "is", "null").ExecuteTo(&admins)
+	if err != nil {
+		return fmt.Errorf("failed to enable two-factor authentication: %w", err)
+	}
+	if len(admins) == 0 {
+		return ErrAdminNotFound
+	}
+
+	if _, _, err := db.client.From("admin_recovery_codes").Delete("", "").Eq("admin_id", adminID).Execute(); err != nil {
+		return fmt.Errorf("failed to replace recovery codes: %w", err)
+	}
+	rows := make([]map[string]interface{}, len(recoveryCodeHashes))
+	for i, hash := range recoveryCodeHashes {
+		rows[i] = map[string]interface{}{
+			"admin_id":  adminID,
+			"code_hash": hash,
+		}
+	}
+	if len(rows) > 0 {
+		if _, _, err := db.client.From("admin_recovery_codes").Insert(rows, false, "", "", "").Execute(); err != nil {
+			return fmt.Errorf("failed to replace recovery codes: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func (db *Database) DisableAdminTwoFactor(ctx context.Context, adminID string) error {
+	update := map[string]interface{}{
+		"totp_secret":        nil,
+		"two_factor_enabled": false,
+		"totp_last_step":     nil,
+	}
+
+	var admins []models.AdminUser
+	_, err := db.client.From("admin_users").Update(update, "", "").Eq("id", adminID).ExecuteTo(&admins)
+	if err != nil {
+		return fmt.Errorf("failed to disable two-factor authentication: %w", err)
+	}
+	if len(admins) == 0 {
+		return ErrAdminNotFound
+	}
+
+	if _, _, err := db.client.From("admin_recovery_codes").Delete("", "").Eq("admin_id", adminID).Execute(); err != nil {
+		return fmt.Errorf("failed to delete recovery codes: %w", err)
+	}
+
+	return nil
+}
+
+func (db *Database) ClaimTOTPStep(ctx context.Context, adminID string, step int64) (bool, error) {
+	update := map[string]interface{}{
+		"totp_last_step": step,
+	}
+
+	// The filter on the last step makes the claim conditional, so of two
+	// requests with the same code only one updates the row.
+	var admins []models.AdminUser
+	_, err := db.client.From("admin_users").Update(update, "", "").
+		Eq("id", adminID).
+		Or(fmt.Sprintf("totp_last_step.is.null,totp_last_step.lt.%d", step), "").
+		ExecuteTo(&admins)
+	if err != nil {
+		return false, fmt.Errorf("failed to claim TOTP step: %w", err)
+	}
+
+	return len(admins) == 1, nil
+}
+
+func (db *Database) UseRecoveryCode(ctx context.Context, adminID, codeHash string) error {
+	update := map[string]interface{}{
+		"used_at": time.Now().UTC().Format(time.RFC3339),
+	}
+
+	var codes []struct {
+		ID int `json:"id"`
+	}
+	_, err := db.client.From("admin_recovery_codes").Update(update, "", "").
+		Eq("admin_id", adminID).
+		Eq("code_hash", codeHash).
+		Is("used_at", "null").
+		ExecuteTo(&codes)
+	if err != nil {
+		return fmt.Errorf("failed to use recovery code: %w", err)
+	}
+
+	if len(codes) == 0 {
+		return ErrRecoveryCodeNotFound
+	}
+
+	return nil
+}
+
+func (db *Database) GetSecuritySettings(ctx context.Context) (*models.SecuritySettings, error) {
+	var result []models.SecuritySettings
+	_, err := db.client.From("admin_security_settings").Select("require_two_factor,updated_at", "", false).ExecuteTo(&result)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get security settings: %w", err)
+	}
+
+	if len(result) == 0 {
+		return &models.SecuritySettings{}, nil
+	}
+
+	return &result[0], nil
+}
+
+func (db *Database) UpdateSecuritySettings(ctx context.Context, requireTwoFactor bool) (*models.SecuritySettings, error) {
+	settings := map[string]interface{}{
+		"id":                 true,
+		"require_two_factor": requireTwoFactor,
+	}
+
+	var result []models.SecuritySettings
+	_, err := db.client.From("admin_security_settings").Insert(settings, true, "id", "", "").ExecuteTo(&result)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update security settings: %w", err)
+	}
+
+	if len(result) == 0 {
+		return nil, fmt.Errorf("no security settings saved")
+	}
+
+	return &result[0], nil
+}
+
+// Login throttle methods
+func (db *Database) GetLoginThrottles(ctx context.Context, keys []string) ([]models.LoginThrottle, error) {
+	var throttles []models.LoginThrottle
+	_, err := db.client.From("login_throttles").Select("*", "", false).In("key", keys).ExecuteTo(&throttles)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get login throttles: %w", err)
+	}
+
+	return throttles, nil
+}
+
+// RecordLoginFailure reads the counts before writing them back, as PostgREST
+// can't increment a column; two failures at the same moment may count once.
+func (db *Database) RecordLoginFailure(ctx context.Context, keys []string, now, resetBefore time.Time) error {
+	throttles, err := db.GetLoginThrottles(ctx, keys)
+	if err != nil {
+		return err
+	}
+	failures := make(map[string]int, len(throttles))
+	for _, t := range throttles {
+		if !t.LastFailureAt.Before(resetBefore) {
+			failures[t.Key] = t.Failures
+		}
+	}
+
+	rows := make([]map[string]interface{}, len(keys))
+	for i, key := range keys {
+		rows[i] = map[string]interface{}{
+			"key":             key,
+			"failures":        failures[key] + 1,
+			"last_failure_at": now.UTC().Format(time.RFC3339),
+		}
+	}
+	if _, _, err := db.client.From("login_throttles").Insert(rows, true, "key", "", "").Execute(); err != nil {
+		return fmt.Errorf("failed to record login failure: %w", err)
+	}
+
+	return nil
+}
+
+func (db *Database) ClearLoginThrottle(ctx context.Context, key string) error {
+	if _, _, err := db.client.From("login_throttles").Delete("", "").Eq("key", key).Execute(); err != nil {
+		return fmt.Errorf("failed to clear login throttle: %w", err)
+	}
+
+	return nil
+}
+
+func (db *Database) CreateLoginAttempt(ctx context.Context, attempt *models.LoginAttempt) error {
+	row := map[string]interface{}{
+		"email":      attempt.Email,
+		"ip_address": attempt.IPAddress,
+		"user_agent": attempt.UserAgent,
+		"result":     attempt.Result,
+	}
+
+	if _, _, err := db.client.From("admin_login_attempts").Insert(row, false, "", "", "").Execute(); err != nil {
+		return fmt.Errorf("failed to record login attempt: %w", err)
+	}
+
+	return nil
+}
+
+func (db *Database) GetLoginAttempts(ctx context.Context, filter LoginAttemptFilter, page Page) ([]models.LoginAttempt, int, error) {
+	query := db.client.From("admin_login_attempts").Select("*", "exact", false)
+	if filter.Email != "" {
+		query = query.Ilike("email", escapeLike(filter.Email))
+	}
+	if filter.IPAddress != "" {
+		query = query.Eq("ip_address", filter.IPAddress)
+	}
+	if filter.Result != "" {
+		query = query.Eq("result", filter.Result)
+	}
+
+	var attempts []models.LoginAttempt
+	total, err := orderPage(query, page, LoginAttemptSorts).ExecuteTo(&attempts)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get login attempts: %w", err)
+	}
+
+	return attempts, int(total), nil
+}
+
+// Customer methods
+
+// matchCustomer returns the ID of the customer with email, creating one if
+// there is none.
+func (db *Database) matchCustomer(name, email, phone string) (int, error) {
+	email = customerEmail(email)
+
+	var result []models.Customer
+	_, err := db.client.From("customers").Select("id", "", false).Eq("email", email).ExecuteTo(&result)
+	if err != nil {
+		return 0, fmt.Errorf("failed to match customer: %w", err)
+	}
+	if len(result) > 0 {
+		return result[0].ID, nil
+	}
+
+	row := map[string]interface{}{"name": name, "email": email, "phone": phone}
+	_, err = db.client.From("customers").Insert(row, false, "", "", "").ExecuteTo(&result)
+	if isPostgrestUniqueViolation(err) {
+		// A customer was created with the email since the lookup
+		_, err = db.client.From("customers").Select("id", "", false).Eq("email", email).ExecuteTo(&result)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to create customer: %w", err)
+	}
+	if len(result) == 0 {
+		return 0, fmt.Errorf("no customer created")
+	}
+
+	return result[0].ID, nil
+}
+
+func (db *Database) GetCustomers(ctx context.Context, filter CustomerFilter, page Page) ([]models.Customer, int, error) {
+	query := db.client.From("customers").Select("*", "exact", false)
+	if filter.Search != "" {
+		query = query.Or("and("+searchGroup(filter.Search, "name", "email", "phone")+")", "")
+	}
+
+	var customers []models.Customer
+	total, err := orderPage(query, page, CustomerSorts).ExecuteTo(&customers)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get customers: %w", err)
+	}
+
+	return customers, int(total), nil
+}
+
+func (db *Database) GetCustomer(ctx context.Context, customerID int) (*models.Customer, error) {
+	var result []models.Customer
+	_, err := db.client.From("customers").Select("*", "", false).Eq("id", strconv.Itoa(customerID)).ExecuteTo(&result)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get customer: %w", err)
+	}
+
+	if len(result) == 0 {
+		return nil, ErrCustomerNotFound
+	}
+
+	return &result[0], nil
+}
+
+func (db *Database) UpdateCustomer(ctx context.Context, customerID int, req *models.CustomerRequest) (*models.Customer, error) {
+	update := map[string]interface{}{
+		"name":  req.Name,
+		"email": customerEmail(req.Email),
+		"phone": req.Phone,
+		"notes": req.Notes,
+	}
+
+	var result []models.Customer
+	_, err := db.client.From("customers").Update(update, "", "").Eq("id", strconv.Itoa(customerID)).ExecuteTo(&result)
+	if isPostgrestUniqueViolation(err) {
+		return nil, ErrCustomerExists
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to update customer: %w", err)
+	}
+
+	if len(result) == 0 {
+		return nil, ErrCustomerNotFound
+	}
+
+	return &result[0], nil
+}
+
+// MergeCustomers moves the bookings over one table at a time. PostgREST has
+// no transactions, so a booking made for the source meanwhile is left on it
+// and stops the delete, failing the merge; running it again finishes it.
+func (db *Database) MergeCustomers(ctx context.Context, targetID, sourceID int) (*models.Customer, error) {
+	target, err := db.GetCustomer(ctx, targetID)
+	if err != nil {
+		return nil, err
+	}
+	source, err := db.GetCustomer(ctx, sourceID)
+	if err != nil {
+		return nil, err
+	}
+
+	move := map[string]interface{}{"customer_id": targetID}
+	for _, table := range []string{"appointments", "classes"} {
+		_, _, err := db.client.From(table).Update(move, "", "").Eq("customer_id", strconv.Itoa(sourceID)).Execute()
+		if err != nil {
+			return nil, fmt.Errorf("failed to merge customers: %w", err)
+		}
+	}
+	if _, _, err := db.client.From("customers").Delete("", "").Eq("id", strconv.Itoa(sourceID)).Execute(); err != nil {
+		return nil, fmt.Errorf("failed to merge customers: %w", err)
+	}
+
+	update := map[string]interface{}{"notes": mergeNotes(target.Notes, source.Notes)}
+	if target.Phone == "" {
+		update["phone"] = source.Phone
+	}
+	var result []models.Customer
+	_, err = db.client.From("customers").Update(update, "", "").Eq("id", strconv.Itoa(targetID)).ExecuteTo(&result)
+	if err != nil {
+		return nil, fmt.Errorf("failed to merge customers: %w", err)
+	}
+
+	if len(result) == 0 {
+		return nil, ErrCustomerNotFound
+	}
+
+	return &result[0], nil
+}
+
+// Idempotency key methods
+
+// ClaimIdempotencyKey inserts the key, relying on the unique (scope, key)
+// constraint. On a conflict it reads the stored key, and if that has
+// expired deletes it and inserts once more.
+func (db *Database) ClaimIdempotencyKey(ctx context.Context, key *models.IdempotencyKey, now time.Time) (*models.IdempotencyKey, bool, error) {
+	claimed, err := db.insertIdempotencyKey(key, now)
+	if !isPostgrestUniqueViolation(err) {
+		return claimed, err == nil, err
+	}
+
+	existing, err := db.getIdempotencyKey(key.Scope, key.Key)
+	if err != nil || existing.ExpiresAt.After(now) {
+		return existing, false, err
+	}
+
+	_, _, err = db.client.From("idempotency_keys").Delete("", "").
+		Eq("id", strconv.Itoa(existing.ID)).
+		Lte("expires_at", now.UTC().Format(time.RFC3339)).
+		Execute()
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to replace expired idempotency key: %w", err)
+	}
+
+	claimed, err = db.insertIdempotencyKey(key, now)
+	if !isPostgrestUniqueViolation(err) {
+		return claimed, err == nil, err
+	}
+	existing, err = db.getIdempotencyKey(key.Scope, key.Key)
+	return existing, false, err
+}
+
+func (db *Database) insertIdempotencyKey(key *models.IdempotencyKey, now time.Time) (*models.IdempotencyKey, error) {
+	row := map[string]interface{}{
+		"scope":        key.Scope,
+		"key":          key.Key,
+		"request_hash": key.RequestHash,
+		"created_at":   now.UTC().Format(time.RFC3339),
+		"expires_at":   key.ExpiresAt.UTC().Format(time.RFC3339),
+	}
+
+	var result []models.IdempotencyKey
+	_, err := db.client.From("idempotency_keys").Insert(row, false, "", "", "").ExecuteTo(&result)
+	if isPostgrestUniqueViolation(err) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim idempotency key: %w", err)
+	}
+
+	if len(result) == 0 {
+		return nil, fmt.Errorf("no idempotency key claimed")
+	}
+
+	return &result[0], nil
+}
+
+func (db *Database) getIdempotencyKey(scope, key string) (*models.IdempotencyKey, error) {
+	var result []models.IdempotencyKey
+	_, err := db.client.From("idempotency_keys").Select("*", "", false).
+		Eq("scope", scope).
+		Eq("key", key).
+		ExecuteTo(&result)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get idempotency key: %w", err)
+	}
+
+	if len(result) == 0 {
+		return nil, fmt.Errorf("idempotency key was released while claiming it")
+	}
+
+	return &result[0], nil
+}
+
+func (db *Database) CompleteIdempotencyKey(ctx context.Context, keyID, statusCode int, responseBody string) error {
+	update := map[string]interface{}{
+		"status_code":   statusCode,
+		"response_body": responseBody,
+	}
+	_, _, err := db.client.From("idempotency_keys").Update(update, "", "").Eq("id", strconv.Itoa(keyID)).Execute()
+	if err != nil {
+		return fmt.Errorf("failed to complete idempotency key: %w", err)
+	}
+
+	return nil
+}
+
+func (db *Database) ReleaseIdempotencyKey(ctx context.Context, keyID int) error {
+	if _, _, err := db.client.From("idempotency_keys").Delete("", "").Eq("id", strconv.Itoa(keyID)).Execute(); err != nil {
+		return fmt.Errorf("failed to release idempotency key: %w", err)
+	}
+
+	return nil
+}
+
+func (db *Database) DeleteExpiredIdempotencyKeys(ctx context.Context, now time.Time) (int, error) {
+	var deleted []models.IdempotencyKey
+	_, err := db.client.From("idempotency_keys").Delete("", "").
+		Lte("expires_at", now.UTC().Format(time.RFC3339)).
+		ExecuteTo(&deleted)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired idempotency keys: %w", err)
+	}
+
+	return len(deleted), nil
+}
+
+// Update appointment status
+func (db *Database) UpdateAppointmentStatus(ctx context.Context, appointmentID int, change StatusChange) (*models.Appointment, error) {
+	if err := validateStatus(change.Status); err != nil {
+		return nil, err
+	}
+
+	var result []models.Appointment
+	err := db.changeStatus("appointments", "appointment", appointmentID, change, &result)
+	if err != nil {
+		return nil, statusChangeError(err, "failed to update appointment status")
+	}
+
+	if len(result) == 0 {
+		return nil, ErrAppointmentNotFound
+	}
+
+	return &result[0], nil
+}
+
+// Update class status
+// An enrollment coming off the waitlist takes a seat. Its cohort's seats are
+// counted before the change, so like CreateClass it can overfill a cohort
+// under contention.
+func (db *Database) UpdateClassStatus(ctx context.Context, classID int, change StatusChange) (*models.Class, error) {
+	if err := validateClassStatus(change.Status); err != nil {
+		return nil, err
+	}
+
+	current, err := db.GetClass(ctx, classID)
+	if err != nil {
+		return nil, err
+	}
+	seated := current.Status == "waitlisted" && isActiveStatus(change.Status)
+	if seated && current.CohortID != nil {
+		cohort, err := db.GetCohort(ctx, *current.CohortID)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := enrollmentStatus(cohort.Capacity, cohort.SeatsTaken, false); err != nil {
+			return nil, err
+		}
+	}
+
+	var result []models.Class
+	err = db.changeStatus("classes", "class", classID, change, &result)
+	if err != nil {
+		return nil, statusChangeError(err, "failed to update class status")
+	}
+
+	if len(result) == 0 {
+		return nil, ErrClassNotFound
+	}
+
+	if seated {
+		updateData := map[string]interface{}{
+			"status":          "promoted",
+			"hold_expires_at": nil,
+		}
+		_, _, err := db.client.From("waitlist_entries").Update(updateData, "", "").
+			Eq("class_id", strconv.Itoa(classID)).
+			Eq("status", "waiting").
+			Execute()
+		if err != nil {
+			return nil, fmt.Errorf("failed to promote waitlist entry: %w", err)
+		}
+	}
+
+	return &result[0], nil
+}
+
+// changeStatus moves a row of table to change.Status and records the change
+// in status_history, decoding the updated row into result. PostgREST has no
+// transactions, so the update only applies while the row still has the
+// status the lifecycle was checked against; a concurrent change makes it
+// fail the check instead. result is left empty when the row doesn't exist.
+func (db *Database) changeStatus(table, resourceType string, id int, change StatusChange, result interface{}) error {
+	var current []struct {
+		Status string `json:"status"`
+	}
+	_, err := db.client.From(table).Select("status", "", false).Eq("id", strconv.Itoa(id)).ExecuteTo(&current)
+	if err != nil {
+		return err
+	}
+	if len(current) == 0 {
+		return nil
+	}
+
+	from := current[0].Status
+	if err := lifecycle.Check(from, change.Status, change.Reason); err != nil {
+		return err
+	}
+
+	updateData := map[string]interface{}{
+		"status": change.Status,
+	}
+	if column := statusTimestampColumn(change.Status); column != "" {
+		updateData[column] = time.Now().UTC()
+	}
+	if change.Status == "cancelled" {
+		updateData["cancellation_reason"] = statusReason(change)
+	}
+
+	body, _, err := db.client.From(table).Update(updateData, "", "").
+		Eq("id", strconv.Itoa(id)).
+		Eq("status", from).
+		Execute()
+	if err != nil {
+		return err
+	}
+	var updated []json.RawMessage
+	if err := json.Unmarshal(body, &updated); err != nil {
+		return err
+	}
+	if len(updated) == 0 {
+		return &lifecycle.TransitionError{From: from, To: change.Status}
+	}
+
+	history := map[string]interface{}{
+		"resource_type": resourceType,
+		"resource_id":   id,
+		"from_status":   from,
+		"to_status":     change.Status,
+		"reason":        statusReason(change),
+		"admin_id":      change.AdminID,
+	}
+	_, _, err = db.client.From("status_history").Insert(history, false, "", "minimal", "").Execute()
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(body, result)
+}
+
+// Status history methods
+func (db *Database) GetStatusHistory(ctx context.Context, resourceType string, resourceID int) ([]models.StatusHistory, error) {
+	var table string
+	var notFound error
+	switch resourceType {
+	case "appointment":
+		table, notFound = "appointments", ErrAppointmentNotFound
+	case "class":
+		table, notFound = "classes", ErrClassNotFound
+	default:
+		return nil, fmt.Errorf("unknown resource type: %s", resourceType)
+	}
+
+	var exists []struct {
+		ID int `json:"id"`
+	}
+	_, err := db.client.From(table).Select("id", "", false).Eq("id", strconv.Itoa(resourceID)).ExecuteTo(&exists)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get status history: %w", err)
+	}
+	if len(exists) == 0 {
+		return nil, notFound
+	}
+
+	var history []models.StatusHistory
+	_, err = db.client.From("status_history").Select("*", "", false).
+		Eq("resource_type", resourceType).
+		Eq("resource_id", strconv.Itoa(resourceID)).
+		Order("created_at", &postgrest.OrderOpts{Ascending: true}).
+		Order("id", &postgrest.OrderOpts{Ascending: true}).
+		ExecuteTo(&history)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get status history: %w", err)
+	}
+
+	if history == nil {
+		history = []models.StatusHistory{}
+	}
+
+	return history, nil
+}
+
+// isPostgrestUniqueViolation reports whether a PostgREST error carries the
+// Postgres unique_violation code. postgrest-go formats errors as "(code) message".
+func isPostgrestUniqueViolation(err error) bool {
+	return err != nil && strings.HasPrefix(err.Error(), "(23505)")
+}
+
+// isPostgrestForeignKeyViolation reports whether a PostgREST error carries
+// the Postgres foreign_key_violation code.
+func isPostgrestForeignKeyViolation(err error) bool {
+	return err != nil && strings.HasPrefix(err.Error(), "(23503)")
+}
+
+// isPostgrestRestrictViolation reports whether a PostgREST error carries
+// the Postgres restrict_violation code.
+func isPostgrestRestrictViolation(err error) bool {
+	return err != nil && strings.HasPrefix(err.Error(), "(23001)")
+}
+
+// isPostgrestNoDataFound reports whether a PostgREST error carries the
+// Postgres no_data_found code.
+func isPostgrestNoDataFound(err error) bool {
+	return err != nil && strings.HasPrefix(err.Error(), "(P0002)")
+}
+
+// rpc calls the Postgres function name with params and decodes its result
+// into result, unless it is nil. Errors are formatted like postgrest-go's,
+// as "(code) message".
+func (db *Database) rpc(name string, params interface{}, result interface{}) error {
+	body := db.client.Rpc(name, "", params)
+	if body == "" {
+		return fmt.Errorf("no response from %s", name)
+	}
+
+	var failure struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	}
+	if json.Unmarshal([]byte(body), &failure) == nil && failure.Code != "" {
+		return fmt.Errorf("(%s) %s", failure.Code, failure.Message)
+	}
+	if result == nil {
+		return nil
+	}
+	return json.Unmarshal([]byte(body), result)
+}
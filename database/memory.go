@@ -0,0 +1,2838 @@
+package database
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"mumuni_backend/auth"
+	"mumuni_backend/lifecycle"
+	"mumuni_backend/models"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MemoryStore is a thread-safe, in-process Store. Data lives only as long as
+// the process, which makes it suitable for local development and tests.
+type MemoryStore struct {
+	mu sync.RWMutex
+
+	appointments      []models.Appointment
+	nextAppointmentID int
+	// appointmentTokens and classTokens map manage token hashes to IDs
+	appointmentTokens map[string]int
+
+	services      []models.Service
+	nextServiceID int
+
+	artists      []models.Artist
+	nextArtistID int
+
+	offerings      []models.ClassOffering
+	nextOfferingID int
+
+	cohorts      []models.Cohort
+	nextCohortID int
+
+	classes     []models.Class
+	nextClassID int
+	classTokens map[string]int
+
+	waitlist       []models.WaitlistEntry
+	nextWaitlistID int
+
+	statusHistory []models.StatusHistory
+	nextHistoryID int
+
+	notifications      []models.Notification
+	nextNotificationID int
+	preferences        map[string]models.NotificationPreference
+
+	jobs      []models.Job
+	nextJobID int
+
+	webhooks       []memoryWebhook
+	nextWebhookID  int
+	deliveries     []models.WebhookDelivery
+	nextDeliveryID int
+
+	admins        []memoryAdmin
+	adminsByEmail map[string]int
+
+	invites      []memoryInvite
+	nextInviteID int
+
+	sessions      []memorySession
+	revokedTokens map[string]time.Time
+
+	resets      []memoryReset
+	nextResetID int
+
+	security      models.SecuritySettings
+	throttles     map[string]models.LoginThrottle
+	loginAttempts []models.LoginAttempt
+	nextAttemptID int
+
+	customers      []models.Customer
+	nextCustomerID int
+
+	idempotencyKeys      map[int]models.IdempotencyKey
+	idempotencyKeyIDs    map[string]int
+	nextIdempotencyKeyID int
+}
+
+// memoryReset is a password reset token, by its hash.
+type memoryReset struct {
+	id        int
+	adminID   string
+	tokenHash string
+	expiresAt time.Time
+	usedAt    *time.Time
+}
+
+// memorySession keeps the refresh token hash and current access token next
+// to the session, which never returns them.
+type memorySession struct {
+	session          models.AdminSession
+	refreshTokenHash string
+	access           AccessToken
+	// usedRefreshTokenHashes are the refresh tokens the session has swapped.
+	usedRefreshTokenHashes []string
+}
+
+// memoryInvite keeps the token hash next to the invite, which never
+// returns it.
+type memoryInvite struct {
+	invite    models.AdminInvite
+	tokenHash string
+}
+
+// memoryWebhook keeps the signing secret next to the subscription, which
+// never returns it.
+type memoryWebhook struct {
+	subscription models.WebhookSubscription
+	secret       string
+}
+
+// memoryAdmin keeps the password hash and two-factor secrets next to the
+// admin record, mirroring the admin_users table where they are never
+// returned to API clients. recoveryCodes maps code hashes to whether they
+// were used.
+type memoryAdmin struct {
+	admin         models.AdminUser
+	passwordHash  string
+	totpSecret    string
+	lastStep      int64
+	recoveryCodes map[string]bool
+}
+
+func NewMemoryStore() *MemoryStore {
+	m := &MemoryStore{
+		nextAppointmentID:    1,
+		nextServiceID:        1,
+		nextArtistID:         1,
+		nextOfferingID:       1,
+		nextCohortID:         1,
+		nextClassID:          1,
+		nextWaitlistID:       1,
+		nextHistoryID:        1,
+		nextNotificationID:   1,
+		nextJobID:            1,
+		nextWebhookID:        1,
+		nextDeliveryID:       1,
+		nextInviteID:         1,
+		nextResetID:          1,
+		nextAttemptID:        1,
+		nextCustomerID:       1,
+		nextIdempotencyKeyID: 1,
+		appointmentTokens:    make(map[string]int),
+		classTokens:          make(map[string]int),
+		preferences:          make(map[string]models.NotificationPreference),
+		adminsByEmail:        make(map[string]int),
+		revokedTokens:        make(map[string]time.Time),
+		security:             models.SecuritySettings{UpdatedAt: time.Now().UTC()},
+		throttles:            make(map[string]models.LoginThrottle),
+		idempotencyKeys:      make(map[int]models.IdempotencyKey),
+		idempotencyKeyIDs:    make(map[string]int),
+	}
+
+	// Seed the same catalog as migration 0003_services
+	for i, s := range []struct {
+		name, description string
+		duration, buffer  int
+		price             float64
+	}{
+		{"Bridal Makeup", "Full bridal look for the big day", 180, 30, 50000},
+		{"Event Makeup", "Glam for parties, weddings and special events", 90, 15, 25000},
+		{"Photoshoot Makeup", "Camera-ready makeup for shoots", 90, 15, 30000},
+		{"Everyday Glam", "Polished everyday look", 60, 15, 15000},
+	} {
+		description := s.description
+		m.CreateService(context.Background(), &models.ServiceRequest{
+			Name:            s.name,
+			Description:     &description,
+			DurationMinutes: s.duration,
+			BufferMinutes:   s.buffer,
+			Price:           s.price,
+			DisplayOrder:    i + 1,
+		})
+	}
+
+	// Seed the same class catalog as migration 0004_class_cohorts
+	for i, o := range []struct {
+		name, description string
+		price             float64
+	}{
+		{"Beginner Basics", "Skin prep, base and everyday looks for beginners", 35000},
+		{"Advanced Techniques", "Sculpting, cut creases and editorial techniques", 75000},
+		{"Bridal Specialist", "Long-wear bridal looks and working with brides", 60000},
+		{"Business Training", "Pricing, marketing and running a makeup business", 45000},
+	} {
+		description := o.description
+		m.CreateClassOffering(context.Background(), &models.ClassOfferingRequest{
+			Name:         o.name,
+			Description:  &description,
+			Price:        o.price,
+			DisplayOrder: i + 1,
+		})
+	}
+
+	return m
+}
+
+// Appointment methods
+func (m *MemoryStore) CreateAppointment(ctx context.Context, req *models.AppointmentRequest, manageTokenHash string, check BookingCheck) (*models.Appointment, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if check != nil {
+		if err := check(m.activeAppointments(req.Date, req.Date)); err != nil {
+			return nil, err
+		}
+	}
+
+	now := time.Now().UTC()
+	customerID := m.matchCustomer(req.Name, req.Email, req.Phone, now)
+	appointment := models.Appointment{
+		ID:              m.nextAppointmentID,
+		Name:            req.Name,
+		Email:           req.Email,
+		Phone:           req.Phone,
+		AppointmentDate: req.Date,
+		AppointmentTime: req.Time,
+		StartsAt:        appointmentStart(req),
+		Service:         req.Service,
+		Message:         copyString(req.Message),
+		ArtistID:        copyInt(req.ArtistID),
+		CustomerID:      &customerID,
+		Channels:        bookingChannels(req.Channels),
+		Status:          "pending",
+		CreatedAt:       now,
+		UpdatedAt:       now,
+	}
+	m.nextAppointmentID++
+	m.appointments = append(m.appointments, appointment)
+	m.appointmentTokens[manageTokenHash] = appointment.ID
+
+	return &appointment, nil
+}
+
+func (m *MemoryStore) GetAppointment(ctx context.Context, appointmentID int) (*models.Appointment, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, a := range m.appointments {
+		if a.ID == appointmentID {
+			return &a, nil
+		}
+	}
+
+	return nil, ErrAppointmentNotFound
+}
+
+func (m *MemoryStore) GetAppointmentByManageToken(ctx context.Context, tokenHash string) (*models.Appointment, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if id, ok := m.appointmentTokens[tokenHash]; ok {
+		for _, a := range m.appointments {
+			if a.ID == id {
+				return &a, nil
+			}
+		}
+	}
+
+	return nil, ErrAppointmentNotFound
+}
+
+func (m *MemoryStore) RescheduleAppointment(ctx context.Context, appointmentID int, req *models.AppointmentRequest, check BookingCheck) (*models.Appointment, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i := range m.appointments {
+		a := &m.appointments[i]
+		if a.ID != appointmentID {
+			continue
+		}
+		if !isActiveStatus(a.Status) {
+			return nil, ErrBookingClosed
+		}
+
+		if check != nil {
+			existing := []models.Appointment{}
+			for _, other := range m.activeAppointments(req.Date, req.Date) {
+				if other.ID != appointmentID {
+					existing = append(existing, other)
+				}
+			}
+			if err := check(existing); err != nil {
+				return nil, err
+			}
+		}
+
+		a.AppointmentDate = req.Date
+		a.AppointmentTime = req.Time
+		a.StartsAt = appointmentStart(req)
+		a.ArtistID = copyInt(req.ArtistID)
+		a.UpdatedAt = time.Now().UTC()
+		appointment := *a
+		return &appointment, nil
+	}
+
+	return nil, ErrAppointmentNotFound
+}
+
+func (m *MemoryStore) UpdateAppointmentChannels(ctx context.Context, appointmentID int, channels []string) (*models.Appointment, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i := range m.appointments {
+		a := &m.appointments[i]
+		if a.ID != appointmentID {
+			continue
+		}
+		a.Channels = bookingChannels(channels)
+		a.UpdatedAt = time.Now().UTC()
+		appointment := *a
+		return &appointment, nil
+	}
+
+	return nil, ErrAppointmentNotFound
+}
+
+func (m *MemoryStore) GetAppointments(ctx context.Context, filter AppointmentFilter, page Page) ([]models.Appointment, int, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	appointments := []models.Appointment{}
+	for _, a := range m.appointments {
+		if filter.ArtistID != nil && (a.ArtistID == nil || *a.ArtistID != *filter.ArtistID) {
+			continue
+		}
+		if filter.CustomerID != nil && (a.CustomerID == nil || *a.CustomerID != *filter.CustomerID) {
+			continue
+		}
+		if filter.Date != "" && a.AppointmentDate != filter.Date {
+			continue
+		}
+		if filter.DateFrom != "" && a.AppointmentDate < filter.DateFrom {
+			continue
+		}
+		if filter.DateTo != "" && a.AppointmentDate > filter.DateTo {
+			continue
+		}
+		if filter.Status != "" && a.Status != filter.Status {
+			continue
+		}
+		if filter.Service != "" && a.Service != filter.Service {
+			continue
+		}
+		if filter.Email != "" && !strings.EqualFold(a.Email, filter.Email) {
+			continue
+		}
+		if filter.Phone != "" && a.Phone != filter.Phone {
+			continue
+		}
+		if filter.Search != "" && !containsFold(filter.Search, a.Name, a.Email, derefString(a.Message)) {
+			continue
+		}
+		appointments = append(appointments, a)
+	}
+	sort.SliceStable(appointments, func(i, j int) bool {
+		a, b := appointments[i], appointments[j]
+		var c int
+		switch page.Sort {
+		case "appointment_date":
+			c = strings.Compare(a.AppointmentDate, b.AppointmentDate)
+		case "name":
+			c = strings.Compare(a.Name, b.Name)
+		case "status":
+			c = strings.Compare(a.Status, b.Status)
+		case "service":
+			c = strings.Compare(a.Service, b.Service)
+		case "updated_at":
+			c = a.UpdatedAt.Compare(b.UpdatedAt)
+		default:
+			c = a.CreatedAt.Compare(b.CreatedAt)
+		}
+		return pageLess(c, a.ID, b.ID, page.Descending)
+	})
+
+	start, end := pageBounds(len(appointments), page)
+	return appointments[start:end], len(appointments), nil
+}
+
+func (m *MemoryStore) GetActiveAppointments(ctx context.Context, fromDate, toDate string) ([]models.Appointment, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.activeAppointments(fromDate, toDate), nil
+}
+
+// activeAppointments returns the pending and confirmed appointments between
+// two YYYY-MM-DD dates inclusive. Callers must hold m.mu.
+func (m *MemoryStore) activeAppointments(fromDate, toDate string) []models.Appointment {
+	appointments := []models.Appointment{}
+	for _, a := range m.appointments {
+		if isActiveStatus(a.Status) && a.AppointmentDate >= fromDate && a.AppointmentDate <= toDate {
+			appointments = append(appointments, a)
+		}
+	}
+	return appointments
+}
+
+func (m *MemoryStore) UpdateAppointmentStatus(ctx context.Context, appointmentID int, change StatusChange) (*models.Appointment, error) {
+	if err := validateStatus(change.Status); err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i := range m.appointments {
+		a := &m.appointments[i]
+		if a.ID != appointmentID {
+			continue
+		}
+		if err := lifecycle.Check(a.Status, change.Status, change.Reason); err != nil {
+			return nil, err
+		}
+
+		now := time.Now().UTC()
+		m.recordStatusChange("appointment", a.ID, a.Status, change, now)
+		a.Status = change.Status
+		switch change.Status {
+		case "confirmed":
+			a.ConfirmedAt = &now
+		case "cancelled":
+			a.CancelledAt = &now
+			a.CancellationReason = statusReason(change)
+		case "completed":
+			a.CompletedAt = &now
+		}
+		a.UpdatedAt = now
+		appointment := *a
+		return &appointment, nil
+	}
+
+	return nil, ErrAppointmentNotFound
+}
+
+// Service catalog methods
+func (m *MemoryStore) CreateService(ctx context.Context, req *models.ServiceRequest) (*models.Service, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.serviceNameTaken(req.Name, 0) {
+		return nil, ErrServiceExists
+	}
+
+	now := time.Now().UTC()
+	service := models.Service{ID: m.nextServiceID, CreatedAt: now}
+	applyServiceRequest(&service, req, now)
+	m.nextServiceID++
+	m.services = append(m.services, service)
+
+	return &service, nil
+}
+
+func (m *MemoryStore) GetServices(ctx context.Context, activeOnly bool) ([]models.Service, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	services := []models.Service{}
+	for _, s := range m.services {
+		if activeOnly && !s.Active {
+			continue
+		}
+		services = append(services, s)
+	}
+	sort.SliceStable(services, func(i, j int) bool {
+		if services[i].DisplayOrder != services[j].DisplayOrder {
+			return services[i].DisplayOrder < services[j].DisplayOrder
+		}
+		return services[i].Name < services[j].Name
+	})
+
+	return services, nil
+}
+
+func (m *MemoryStore) GetService(ctx context.Context, serviceID int) (*models.Service, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, s := range m.services {
+		if s.ID == serviceID {
+			return &s, nil
+		}
+	}
+
+	return nil, ErrServiceNotFound
+}
+
+func (m *MemoryStore) UpdateService(ctx context.Context, serviceID int, req *models.ServiceRequest) (*models.Service, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i := range m.services {
+		if m.services[i].ID == serviceID {
+			if m.serviceNameTaken(req.Name, serviceID) {
+				return nil, ErrServiceExists
+			}
+			applyServiceRequest(&m.services[i], req, time.Now().UTC())
+			service := m.services[i]
+			return &service, nil
+		}
+	}
+
+	return nil, ErrServiceNotFound
+}
+
+func (m *MemoryStore) DeleteService(ctx context.Context, serviceID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i := range m.services {
+		if m.services[i].ID == serviceID {
+			m.services = append(m.services[:i], m.services[i+1:]...)
+			return nil
+		}
+	}
+
+	return ErrServiceNotFound
+}
+
+// serviceNameTaken reports whether another service already uses name.
+// Callers must hold m.mu.
+func (m *MemoryStore) serviceNameTaken(name string, exceptID int) bool {
+	for _, s := range m.services {
+		if s.Name == name && s.ID != exceptID {
+			return true
+		}
+	}
+	return false
+}
+
+func applyServiceRequest(service *models.Service, req *models.ServiceRequest, now time.Time) {
+	service.Name = req.Name
+	service.Description = copyString(req.Description)
+	service.DurationMinutes = req.DurationMinutes
+	service.BufferMinutes = req.BufferMinutes
+	service.Price = req.Price
+	service.DepositAmount = req.DepositAmount
+	service.Active = serviceActive(req)
+	service.DisplayOrder = req.DisplayOrder
+	service.UpdatedAt = now
+}
+
+// Artist methods
+func (m *MemoryStore) CreateArtist(ctx context.Context, req *models.ArtistRequest) (*models.Artist, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now().UTC()
+	artist := models.Artist{ID: m.nextArtistID, CreatedAt: now}
+	applyArtistRequest(&artist, req, now)
+	m.nextArtistID++
+	m.artists = append(m.artists, artist)
+
+	return &artist, nil
+}
+
+func (m *MemoryStore) GetArtists(ctx context.Context, activeOnly bool) ([]models.Artist, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	artists := []models.Artist{}
+	for _, a := range m.artists {
+		if activeOnly && !a.Active {
+			continue
+		}
+		artists = append(artists, a)
+	}
+	sort.SliceStable(artists, func(i, j int) bool {
+		return artists[i].Name < artists[j].Name
+	})
+
+	return artists, nil
+}
+
+func (m *MemoryStore) GetArtist(ctx context.Context, artistID int) (*models.Artist, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, a := range m.artists {
+		if a.ID == artistID {
+			return &a, nil
+		}
+	}
+
+	return nil, ErrArtistNotFound
+}
+
+func (m *MemoryStore) UpdateArtist(ctx context.Context, artistID int, req *models.ArtistRequest) (*models.Artist, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i := range m.artists {
+		if m.artists[i].ID == artistID {
+			applyArtistRequest(&m.artists[i], req, time.Now().UTC())
+			artist := m.artists[i]
+			return &artist, nil
+		}
+	}
+
+	return nil, ErrArtistNotFound
+}
+
+func (m *MemoryStore) DeleteArtist(ctx context.Context, artistID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i := range m.artists {
+		if m.artists[i].ID == artistID {
+			m.artists = append(m.artists[:i], m.artists[i+1:]...)
+			// Mirror ON DELETE SET NULL on appointments.artist_id and
+			// waitlist_entries.artist_id
+			for j := range m.appointments {
+				if a := m.appointments[j].ArtistID; a != nil && *a == artistID {
+					m.appointments[j].ArtistID = nil
+				}
+			}
+			for j := range m.waitlist {
+				if a := m.waitlist[j].ArtistID; a != nil && *a == artistID {
+					m.waitlist[j].ArtistID = nil
+				}
+			}
+			return nil
+		}
+	}
+
+	return ErrArtistNotFound
+}
+
+func applyArtistRequest(artist *models.Artist, req *models.ArtistRequest, now time.Time) {
+	artist.Name = req.Name
+	artist.Email = copyString(req.Email)
+	artist.Phone = copyString(req.Phone)
+	artist.Skills = append([]string(nil), req.Skills...)
+	artist.WorkingHours = req.WorkingHours
+	artist.Active = artistActive(req)
+	artist.UpdatedAt = now
+}
+
+// Class catalog methods
+func (m *MemoryStore) CreateClassOffering(ctx context.Context, req *models.ClassOfferingRequest) (*models.ClassOffering, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.offeringNameTaken(req.Name, 0) {
+		return nil, ErrOfferingExists
+	}
+
+	now := time.Now().UTC()
+	offering := models.ClassOffering{ID: m.nextOfferingID, CreatedAt: now}
+	applyOfferingRequest(&offering, req, now)
+	m.nextOfferingID++
+	m.offerings = append(m.offerings, offering)
+
+	return &offering, nil
+}
+
+func (m *MemoryStore) GetClassOfferings(ctx context.Context, activeOnly bool) ([]models.ClassOffering, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	offerings := []models.ClassOffering{}
+	for _, o := range m.offerings {
+		if activeOnly && !o.Active {
+			continue
+		}
+		offerings = append(offerings, o)
+	}
+	sort.SliceStable(offerings, func(i, j int) bool {
+		if offerings[i].DisplayOrder != offerings[j].DisplayOrder {
+			return offerings[i].DisplayOrder < offerings[j].DisplayOrder
+		}
+		return offerings[i].Name < offerings[j].Name
+	})
+
+	return offerings, nil
+}
+
+func (m *MemoryStore) GetClassOffering(ctx context.Context, offeringID int) (*models.ClassOffering, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, o := range m.offerings {
+		if o.ID == offeringID {
+			return &o, nil
+		}
+	}
+
+	return nil, ErrOfferingNotFound
+}
+
+func (m *MemoryStore) UpdateClassOffering(ctx context.Context, offeringID int, req *models.ClassOfferingRequest) (*models.ClassOffering, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i := range m.offerings {
+		if m.offerings[i].ID == offeringID {
+			if m.offeringNameTaken(req.Name, offeringID) {
+				return nil, ErrOfferingExists
+			}
+			applyOfferingRequest(&m.offerings[i], req, time.Now().UTC())
+			offering := m.offerings[i]
+			return &offering, nil
+		}
+	}
+
+	return nil, ErrOfferingNotFound
+}
+
+func (m *MemoryStore) DeleteClassOffering(ctx context.Context, offeringID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i := range m.offerings {
+		if m.offerings[i].ID == offeringID {
+			m.offerings = append(m.offerings[:i], m.offerings[i+1:]...)
+			// Mirror ON DELETE CASCADE on class_cohorts.offering_id
+			cohorts := m.cohorts[:0]
+			for _, c := range m.cohorts {
+				if c.OfferingID == offeringID {
+					m.unlinkCohort(c.ID)
+					continue
+				}
+				cohorts = append(cohorts, c)
+			}
+			m.cohorts = cohorts
+			return nil
+		}
+	}
+
+	return ErrOfferingNotFound
+}
+
+// offeringNameTaken reports whether another class offering already uses
+// name. Callers must hold m.mu.
+func (m *MemoryStore) offeringNameTaken(name string, exceptID int) bool {
+	for _, o := range m.offerings {
+		if o.Name == name && o.ID != exceptID {
+			return true
+		}
+	}
+	return false
+}
+
+func applyOfferingRequest(offering *models.ClassOffering, req *models.ClassOfferingRequest, now time.Time) {
+	offering.Name = req.Name
+	offering.Description = copyString(req.Description)
+	offering.Price = req.Price
+	offering.Active = offeringActive(req)
+	offering.DisplayOrder = req.DisplayOrder
+	offering.UpdatedAt = now
+}
+
+// Cohort methods
+func (m *MemoryStore) CreateCohort(ctx context.Context, req *models.CohortRequest) (*models.Cohort, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !m.offeringExists(req.OfferingID) {
+		return nil, ErrOfferingNotFound
+	}
+
+	now := time.Now().UTC()
+	cohort := models.Cohort{ID: m.nextCohortID, CreatedAt: now}
+	applyCohortRequest(&cohort, req, now)
+	m.nextCohortID++
+	m.cohorts = append(m.cohorts, cohort)
+
+	return m.withSeats(cohort), nil
+}
+
+func (m *MemoryStore) GetCohorts(ctx context.Context, filter CohortFilter) ([]models.Cohort, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	cohorts := []models.Cohort{}
+	for _, c := range m.cohorts {
+		if filter.OfferingID != nil && c.OfferingID != *filter.OfferingID {
+			continue
+		}
+		if filter.ActiveOnly && !c.Active {
+			continue
+		}
+		if filter.StartsFrom != "" && c.StartDate < filter.StartsFrom {
+			continue
+		}
+		cohorts = append(cohorts, *m.withSeats(c))
+	}
+	sort.SliceStable(cohorts, func(i, j int) bool {
+		if cohorts[i].StartDate != cohorts[j].StartDate {
+			return cohorts[i].StartDate < cohorts[j].StartDate
+		}
+		return cohorts[i].ID < cohorts[j].ID
+	})
+
+	return cohorts, nil
+}
+
+func (m *MemoryStore) GetCohort(ctx context.Context, cohortID int) (*models.Cohort, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, c := range m.cohorts {
+		if c.ID == cohortID {
+			return m.withSeats(c), nil
+		}
+	}
+
+	return nil, ErrCohortNotFound
+}
+
+func (m *MemoryStore) UpdateCohort(ctx context.Context, cohortID int, req *models.CohortRequest) (*models.Cohort, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i := range m.cohorts {
+		if m.cohorts[i].ID == cohortID {
+			if !m.offeringExists(req.OfferingID) {
+				return nil, ErrOfferingNotFound
+			}
+			applyCohortRequest(&m.cohorts[i], req, time.Now().UTC())
+			return m.withSeats(m.cohorts[i]), nil
+		}
+	}
+
+	return nil, ErrCohortNotFound
+}
+
+func (m *MemoryStore) DeleteCohort(ctx context.Context, cohortID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i := range m.cohorts {
+		if m.cohorts[i].ID == cohortID {
+			m.cohorts = append(m.cohorts[:i], m.cohorts[i+1:]...)
+			m.unlinkCohort(cohortID)
+			return nil
+		}
+	}
+
+	return ErrCohortNotFound
+}
+
+// offeringExists reports whether a class offering exists. Callers must hold m.mu.
+func (m *MemoryStore) offeringExists(offeringID int) bool {
+	for _, o := range m.offerings {
+		if o.ID == offeringID {
+			return true
+		}
+	}
+	return false
+}
+
+// unlinkCohort mirrors ON DELETE SET NULL on classes.cohort_id and ON DELETE
+// CASCADE on waitlist_entries.cohort_id. Callers must hold m.mu.
+func (m *MemoryStore) unlinkCohort(cohortID int) {
+	for i := range m.classes {
+		if c := m.classes[i].CohortID; c != nil && *c == cohortID {
+			m.classes[i].CohortID = nil
+		}
+	}
+
+	waitlist := m.waitlist[:0]
+	for _, e := range m.waitlist {
+		if e.CohortID != nil && *e.CohortID == cohortID {
+			continue
+		}
+		waitlist = append(waitlist, e)
+	}
+	m.waitlist = waitlist
+}
+
+// seatsTaken counts the enrollments holding a seat in a cohort. Callers must
+// hold m.mu.
+func (m *MemoryStore) seatsTaken(cohortID int) int {
+	taken := 0
+	for _, c := range m.classes {
+		if c.CohortID != nil && *c.CohortID == cohortID && isActiveStatus(c.Status) {
+			taken++
+		}
+	}
+	return taken
+}
+
+// cohortEnrollmentStatus is the status a new enrollment in the cohort gets.
+// Callers must hold m.mu.
+func (m *MemoryStore) cohortEnrollmentStatus(cohortID int, waitlistWhenFull bool) (string, error) {
+	for _, cohort := range m.cohorts {
+		if cohort.ID == cohortID {
+			return enrollmentStatus(cohort.Capacity, m.seatsTaken(cohortID), waitlistWhenFull)
+		}
+	}
+	return "", ErrCohortNotFound
+}
+
+// withSeats returns a copy of cohort with its seat counts filled in. Callers
+// must hold m.mu.
+func (m *MemoryStore) withSeats(cohort models.Cohort) *models.Cohort {
+	cohort.EndDate = copyString(cohort.EndDate)
+	cohort.Instructor = copyString(cohort.Instructor)
+	cohort.SetSeatsTaken(m.seatsTaken(cohort.ID))
+	return &cohort
+}
+
+func applyCohortRequest(cohort *models.Cohort, req *models.CohortRequest, now time.Time) {
+	cohort.OfferingID = req.OfferingID
+	cohort.Name = req.Name
+	cohort.StartDate = req.StartDate
+	cohort.EndDate = copyString(req.EndDate)
+	cohort.MeetingTimes = req.MeetingTimes
+	cohort.Instructor = copyString(req.Instructor)
+	cohort.Capacity = req.Capacity
+	cohort.Active = cohortActive(req)
+	cohort.UpdatedAt = now
+}
+
+// Class methods
+func (m *MemoryStore) CreateClass(ctx context.Context, req *models.ClassRequest, manageTokenHash string, waitlistWhenFull bool) (*models.Class, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	status := "pending"
+	if req.CohortID != nil {
+		var cohort *models.Cohort
+		for i := range m.cohorts {
+			if m.cohorts[i].ID == *req.CohortID {
+				cohort = &m.cohorts[i]
+				break
+			}
+		}
+		if cohort == nil {
+			return nil, ErrCohortNotFound
+		}
+
+		var err error
+		status, err = enrollmentStatus(cohort.Capacity, m.seatsTaken(cohort.ID), waitlistWhenFull)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	now := time.Now().UTC()
+	customerID := m.matchCustomer(req.Name, req.Email, req.Phone, now)
+	class := models.Class{
+		ID:                m.nextClassID,
+		Name:              req.Name,
+		Email:             req.Email,
+		Phone:             req.Phone,
+		ClassType:         req.ClassType,
+		ExperienceLevel:   req.Experience,
+		Goals:             copyString(req.Goals),
+		PreferredSchedule: req.Schedule,
+		CohortID:          copyInt(req.CohortID),
+		CustomerID:        &customerID,
+		Channels:          bookingChannels(req.Channels),
+		Status:            status,
+		CreatedAt:         now,
+		UpdatedAt:         now,
+	}
+	m.nextClassID++
+	m.classes = append(m.classes, class)
+	m.classTokens[manageTokenHash] = class.ID
+
+	return &class, nil
+}
+
+func (m *MemoryStore) GetClass(ctx context.Context, classID int) (*models.Class, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, c := range m.classes {
+		if c.ID == classID {
+			return &c, nil
+		}
+	}
+
+	return nil, ErrClassNotFound
+}
+
+func (m *MemoryStore) GetClassByManageToken(ctx context.Context, tokenHash string) (*models.Class, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if id, ok := m.classTokens[tokenHash]; ok {
+		for _, c := range m.classes {
+			if c.ID == id {
+				return &c, nil
+			}
+		}
+	}
+
+	return nil, ErrClassNotFound
+}
+
+func (m *MemoryStore) ChangeClassCohort(ctx context.Context, classID, cohortID int) (*models.Class, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var cohort *models.Cohort
+	for i := range m.cohorts {
+		if m.cohorts[i].ID == cohortID {
+			cohort = &m.cohorts[i]
+			break
+		}
+	}
+	if cohort == nil {
+		return nil, ErrCohortNotFound
+	}
+
+	for i := range m.classes {
+		c := &m.classes[i]
+		if c.ID != classID {
+			continue
+		}
+		if !isActiveStatus(c.Status) {
+			return nil, ErrBookingClosed
+		}
+		if _, err := enrollmentStatus(cohort.Capacity, m.seatsTaken(cohortID), false); err != nil {
+			return nil, err
+		}
+
+		c.CohortID = &cohortID
+		c.UpdatedAt = time.Now().UTC()
+		class := *c
+		return &class, nil
+	}
+
+	return nil, ErrClassNotFound
+}
+
+func (m *MemoryStore) UpdateClassChannels(ctx context.Context, classID int, channels []string) (*models.Class, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i := range m.classes {
+		c := &m.classes[i]
+		if c.ID != classID {
+			continue
+		}
+		c.Channels = bookingChannels(channels)
+		c.UpdatedAt = time.Now().UTC()
+		class := *c
+		return &class, nil
+	}
+
+	return nil, ErrClassNotFound
+}
+
+func (m *MemoryStore) GetClasses(ctx context.Context, filter ClassFilter, page Page) ([]models.Class, int, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	classes := []models.Class{}
+	for _, c := range m.classes {
+		created := c.CreatedAt.UTC().Format("2006-01-02")
+		if filter.Status != "" && c.Status != filter.Status {
+			continue
+		}
+		if filter.ClassType != "" && c.ClassType != filter.ClassType {
+			continue
+		}
+		if filter.CohortID != nil && (c.CohortID == nil || *c.CohortID != *filter.CohortID) {
+			continue
+		}
+		if filter.CustomerID != nil && (c.CustomerID == nil || *c.CustomerID != *filter.CustomerID) {
+			continue
+		}
+		if filter.Email != "" && !strings.EqualFold(c.Email, filter.Email) {
+			continue
+		}
+		if filter.Phone != "" && c.Phone != filter.Phone {
+			continue
+		}
+		if filter.CreatedFrom != "" && created < filter.CreatedFrom {
+			continue
+		}
+		if filter.CreatedTo != "" && created > filter.CreatedTo {
+			continue
+		}
+		if filter.Search != "" && !containsFold(filter.Search, c.Name, c.Email, derefString(c.Goals)) {
+			continue
+		}
+		classes = append(classes, c)
+	}
+	sort.SliceStable(classes, func(i, j int) bool {
+		a, b := classes[i], classes[j]
+		var c int
+		switch page.Sort {
+		case "name":
+			c = strings.Compare(a.Name, b.Name)
+		case "status":
+			c = strings.Compare(a.Status, b.Status)
+		case "class_type":
+			c = strings.Compare(a.ClassType, b.ClassType)
+		case "updated_at":
+			c = a.UpdatedAt.Compare(b.UpdatedAt)
+		default:
+			c = a.CreatedAt.Compare(b.CreatedAt)
+		}
+		return pageLess(c, a.ID, b.ID, page.Descending)
+	})
+
+	start, end := pageBounds(len(classes), page)
+	return classes[start:end], len(classes), nil
+}
+
+func (m *MemoryStore) UpdateClassStatus(ctx context.Context, classID int, change StatusChange) (*models.Class, error) {
+	if err := validateClassStatus(change.Status); err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// Coming off the waitlist takes a seat
+	var seated bool
+	for _, c := range m.classes {
+		if c.ID != classID {
+			continue
+		}
+		seated = c.Status == "waitlisted" && isActiveStatus(change.Status)
+		if seated && c.CohortID != nil {
+			if _, err := m.cohortEnrollmentStatus(*c.CohortID, false); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	class, err := m.changeClassStatus(classID, change)
+	if err != nil {
+		return nil, err
+	}
+	if seated {
+		now := time.Now().UTC()
+		for i := range m.waitlist {
+			e := &m.waitlist[i]
+			if e.ClassID != nil && *e.ClassID == classID && e.Status == "waiting" {
+				e.Status = "promoted"
+				e.HoldExpiresAt = nil
+				e.UpdatedAt = now
+			}
+		}
+	}
+	return class, nil
+}
+
+// changeClassStatus moves an enrollment to change.Status. Callers must hold
+// m.mu.
+func (m *MemoryStore) changeClassStatus(classID int, change StatusChange) (*models.Class, error) {
+	for i := range m.classes {
+		c := &m.classes[i]
+		if c.ID != classID {
+			continue
+		}
+		if err := lifecycle.Check(c.Status, change.Status, change.Reason); err != nil {
+			return nil, err
+		}
+
+		now := time.Now().UTC()
+		m.recordStatusChange("class", c.ID, c.Status, change, now)
+		c.Status = change.Status
+		switch change.Status {
+		case "confirmed":
+			c.ConfirmedAt = &now
+		case "cancelled":
+			c.CancelledAt = &now
+			c.CancellationReason = statusReason(change)
+		case "completed":
+			c.CompletedAt = &now
+		}
+		c.UpdatedAt = now
+		class := *c
+		return &class, nil
+	}
+
+	return nil, ErrClassNotFound
+}
+
+// recordStatusChange appends to the status history. Callers hold m.mu.
+func (m *MemoryStore) recordStatusChange(resourceType string, resourceID int, from string, change StatusChange, now time.Time) {
+	m.statusHistory = append(m.statusHistory, models.StatusHistory{
+		ID:           m.nextHistoryID,
+		ResourceType: resourceType,
+		ResourceID:   resourceID,
+		FromStatus:   from,
+		ToStatus:     change.Status,
+		Reason:       statusReason(change),
+		AdminID:      change.AdminID,
+		CreatedAt:    now,
+	})
+	m.nextHistoryID++
+}
+
+// Status history methods
+func (m *MemoryStore) GetStatusHistory(ctx context.Context, resourceType string, resourceID int) ([]models.StatusHistory, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	found := false
+	switch resourceType {
+	case "appointment":
+		for _, a := range m.appointments {
+			found = found || a.ID == resourceID
+		}
+		if !found {
+			return nil, ErrAppointmentNotFound
+		}
+	case "class":
+		for _, c := range m.classes {
+			found = found || c.ID == resourceID
+		}
+		if !found {
+			return nil, ErrClassNotFound
+		}
+	default:
+		return nil, fmt.Errorf("unknown resource type: %s", resourceType)
+	}
+
+	history := []models.StatusHistory{}
+	for _, h := range m.statusHistory {
+		if h.ResourceType == resourceType && h.ResourceID == resourceID {
+			history = append(history, h)
+		}
+	}
+	return history, nil
+}
+
+// Waitlist methods
+func (m *MemoryStore) CreateWaitlistEntry(ctx context.Context, entry *models.WaitlistEntry) (*models.WaitlistEntry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now().UTC()
+	created := copyWaitlistEntry(*entry)
+	created.ID = m.nextWaitlistID
+	if created.Status == "" {
+		created.Status = "waiting"
+	}
+	created.CreatedAt = now
+	created.UpdatedAt = now
+	m.nextWaitlistID++
+	m.waitlist = append(m.waitlist, created)
+
+	result := copyWaitlistEntry(created)
+	return &result, nil
+}
+
+func (m *MemoryStore) GetWaitlistEntries(ctx context.Context, filter WaitlistFilter) ([]models.WaitlistEntry, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	entries := []models.WaitlistEntry{}
+	for _, e := range m.waitlist {
+		if filter.Kind != "" && e.Kind != filter.Kind {
+			continue
+		}
+		if filter.Status != "" && e.Status != filter.Status {
+			continue
+		}
+		if filter.CohortID != nil && (e.CohortID == nil || *e.CohortID != *filter.CohortID) {
+			continue
+		}
+		if filter.ClassID != nil && (e.ClassID == nil || *e.ClassID != *filter.ClassID) {
+			continue
+		}
+		if filter.AppointmentID != nil && (e.AppointmentID == nil || *e.AppointmentID != *filter.AppointmentID) {
+			continue
+		}
+		if filter.Date != "" && (e.AppointmentDate == nil || *e.AppointmentDate != filter.Date) {
+			continue
+		}
+		entries = append(entries, copyWaitlistEntry(e))
+	}
+
+	return entries, nil
+}
+
+func (m *MemoryStore) GetWaitlistEntry(ctx context.Context, entryID int) (*models.WaitlistEntry, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, e := range m.waitlist {
+		if e.ID == entryID {
+			entry := copyWaitlistEntry(e)
+			return &entry, nil
+		}
+	}
+
+	return nil, ErrWaitlistNotFound
+}
+
+func (m *MemoryStore) GetWaitlistEntryByToken(ctx context.Context, tokenHash string) (*models.WaitlistEntry, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, e := range m.waitlist {
+		if e.TokenHash == tokenHash {
+			entry := copyWaitlistEntry(e)
+			return &entry, nil
+		}
+	}
+
+	return nil, ErrWaitlistNotFound
+}
+
+func (m *MemoryStore) UpdateWaitlistEntry(ctx context.Context, entry *models.WaitlistEntry) (*models.WaitlistEntry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.updateWaitlistEntry(entry)
+}
+
+// updateWaitlistEntry saves entry's status, hold expiry and appointment.
+// Callers must hold m.mu.
+func (m *MemoryStore) updateWaitlistEntry(entry *models.WaitlistEntry) (*models.WaitlistEntry, error) {
+	for i := range m.waitlist {
+		if m.waitlist[i].ID == entry.ID {
+			m.waitlist[i].Status = entry.Status
+			m.waitlist[i].HoldExpiresAt = copyTime(entry.HoldExpiresAt)
+			m.waitlist[i].AppointmentID = copyInt(entry.AppointmentID)
+			m.waitlist[i].UpdatedAt = time.Now().UTC()
+			updated := copyWaitlistEntry(m.waitlist[i])
+			return &updated, nil
+		}
+	}
+
+	return nil, ErrWaitlistNotFound
+}
+
+func (m *MemoryStore) PromoteWaitlistedClass(ctx context.Context, entry *models.WaitlistEntry, change StatusChange, overfill bool) (*models.Class, *models.WaitlistEntry, error) {
+	if err := validateClassStatus(change.Status); err != nil {
+		return nil, nil, err
+	}
+	if entry.ClassID == nil {
+		return nil, nil, ErrClassNotFound
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	found := false
+	for _, e := range m.waitlist {
+		if e.ID == entry.ID {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return nil, nil, ErrWaitlistNotFound
+	}
+	if entry.CohortID != nil && !overfill {
+		if _, err := m.cohortEnrollmentStatus(*entry.CohortID, false); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	class, err := m.changeClassStatus(*entry.ClassID, change)
+	if err != nil {
+		return nil, nil, err
+	}
+	updated, err := m.updateWaitlistEntry(entry)
+	if err != nil {
+		return nil, nil, err
+	}
+	return class, updated, nil
+}
+
+func copyWaitlistEntry(e models.WaitlistEntry) models.WaitlistEntry {
+	e.CohortID = copyInt(e.CohortID)
+	e.ClassID = copyInt(e.ClassID)
+	e.Service = copyString(e.Service)
+	e.AppointmentDate = copyString(e.AppointmentDate)
+	e.PreferredTime = copyString(e.PreferredTime)
+	e.ArtistID = copyInt(e.ArtistID)
+	e.AppointmentID = copyInt(e.AppointmentID)
+	e.HoldExpiresAt = copyTime(e.HoldExpiresAt)
+	return e
+}
+
+// Notification outbox methods
+func (m *MemoryStore) CreateNotification(ctx context.Context, notification *models.Notification) (*models.Notification, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now().UTC()
+	created := copyNotification(*notification)
+	created.ID = m.nextNotificationID
+	if created.Channel == "" {
+		created.Channel = "email"
+	}
+	created.Status = "pending"
+	created.Attempts = 0
+	if created.NextAttemptAt.IsZero() {
+		created.NextAttemptAt = now
+	}
+	created.CreatedAt = now
+	created.UpdatedAt = now
+	m.nextNotificationID++
+	m.notifications = append(m.notifications, created)
+
+	result := copyNotification(created)
+	return &result, nil
+}
+
+func (m *MemoryStore) ClaimNotifications(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]models.Notification, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var due []int
+	for i, n := range m.notifications {
+		if n.Status == "pending" && !n.NextAttemptAt.After(now) {
+			due = append(due, i)
+		}
+	}
+	sort.SliceStable(due, func(a, b int) bool {
+		return m.notifications[due[a]].NextAttemptAt.Before(m.notifications[due[b]].NextAttemptAt)
+	})
+	if len(due) > limit {
+		due = due[:limit]
+	}
+
+	claimed := []models.Notification{}
+	for _, i := range due {
+		m.notifications[i].Attempts++
+		m.notifications[i].NextAttemptAt = now.Add(lease).UTC()
+		m.notifications[i].UpdatedAt = time.Now().UTC()
+		claimed = append(claimed, copyNotification(m.notifications[i]))
+	}
+
+	return claimed, nil
+}
+
+func (m *MemoryStore) GetNotifications(ctx context.Context, filter NotificationFilter) ([]models.Notification, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	notifications := []models.Notification{}
+	for _, n := range m.notifications {
+		if filter.Channel != "" && n.Channel != filter.Channel {
+			continue
+		}
+		if filter.Status != "" && n.Status != filter.Status {
+			continue
+		}
+		if filter.ResourceType != "" && n.ResourceType != filter.ResourceType {
+			continue
+		}
+		if filter.ResourceID != nil && n.ResourceID != *filter.ResourceID {
+			continue
+		}
+		if filter.ProviderMessageID != "" && (n.ProviderMessageID == nil || *n.ProviderMessageID != filter.ProviderMessageID) {
+			continue
+		}
+		notifications = append(notifications, copyNotification(n))
+	}
+
+	return notifications, nil
+}
+
+func (m *MemoryStore) UpdateNotification(ctx context.Context, notification *models.Notification) (*models.Notification, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i := range m.notifications {
+		if m.notifications[i].ID == notification.ID {
+			m.notifications[i].Status = notification.Status
+			m.notifications[i].LastError = copyString(notification.LastError)
+			m.notifications[i].NextAttemptAt = notification.NextAttemptAt
+			m.notifications[i].SentAt = copyTime(notification.SentAt)
+			m.notifications[i].ProviderMessageID = copyString(notification.ProviderMessageID)
+			m.notifications[i].DeliveredAt = copyTime(notification.DeliveredAt)
+			m.notifications[i].Secret = notification.Secret
+			m.notifications[i].UpdatedAt = time.Now().UTC()
+			updated := copyNotification(m.notifications[i])
+			return &updated, nil
+		}
+	}
+
+	return nil, ErrNotificationNotFound
+}
+
+func copyNotification(n models.Notification) models.Notification {
+	n.LastError = copyString(n.LastError)
+	n.SentAt = copyTime(n.SentAt)
+	n.ProviderMessageID = copyString(n.ProviderMessageID)
+	n.DeliveredAt = copyTime(n.DeliveredAt)
+	return n
+}
+
+// Notification preference methods
+func (m *MemoryStore) GetNotificationPreference(ctx context.Context, email string) (*models.NotificationPreference, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	preference, ok := m.preferences[preferenceKey(email)]
+	if !ok {
+		return nil, ErrPreferenceNotFound
+	}
+
+	result := copyPreference(preference)
+	return &result, nil
+}
+
+func (m *MemoryStore) SaveNotificationPreference(ctx context.Context, email string, channels []string) (*models.NotificationPreference, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now().UTC()
+	key := preferenceKey(email)
+	preference, ok := m.preferences[key]
+	if !ok {
+		preference = models.NotificationPreference{Email: key, CreatedAt: now}
+	}
+	preference.Channels = append([]string(nil), channels...)
+	preference.UpdatedAt = now
+	m.preferences[key] = preference
+
+	result := copyPreference(preference)
+	return &result, nil
+}
+
+func copyPreference(p models.NotificationPreference) models.NotificationPreference {
+	p.Channels = append([]string(nil), p.Channels...)
+	return p
+}
+
+// Job methods
+func (m *MemoryStore) CreateJob(ctx context.Context, job *models.Job) (*models.Job, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now().UTC()
+	created := copyJob(*job)
+	created.ID = m.nextJobID
+	created.Status = "pending"
+	created.Attempts = 0
+	created.CreatedAt = now
+	created.UpdatedAt = now
+	m.nextJobID++
+	m.jobs = append(m.jobs, created)
+
+	result := copyJob(created)
+	return &result, nil
+}
+
+func (m *MemoryStore) GetJobs(ctx context.Context, filter JobFilter) ([]models.Job, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	jobs := []models.Job{}
+	for _, j := range m.jobs {
+		if filter.Kind != "" && j.Kind != filter.Kind {
+			continue
+		}
+		if filter.Status != "" && j.Status != filter.Status {
+			continue
+		}
+		if filter.ResourceType != "" && j.ResourceType != filter.ResourceType {
+			continue
+		}
+		if filter.ResourceID != nil && j.ResourceID != *filter.ResourceID {
+			continue
+		}
+		jobs = append(jobs, copyJob(j))
+	}
+
+	sort.SliceStable(jobs, func(i, k int) bool { return jobs[i].RunAt.Before(jobs[k].RunAt) })
+	return jobs, nil
+}
+
+func (m *MemoryStore) ClaimJobs(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]models.Job, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var due []int
+	for i, j := range m.jobs {
+		if j.Status == "pending" && !j.RunAt.After(now) {
+			due = append(due, i)
+		}
+	}
+	sort.SliceStable(due, func(a, b int) bool {
+		return m.jobs[due[a]].RunAt.Before(m.jobs[due[b]].RunAt)
+	})
+	if len(due) > limit {
+		due = due[:limit]
+	}
+
+	claimed := []models.Job{}
+	for _, i := range due {
+		m.jobs[i].Attempts++
+		m.jobs[i].RunAt = now.Add(lease).UTC()
+		m.jobs[i].UpdatedAt = time.Now().UTC()
+		claimed = append(claimed, copyJob(m.jobs[i]))
+	}
+
+	return claimed, nil
+}
+
+func (m *MemoryStore) UpdateJob(ctx context.Context, job *models.Job) (*models.Job, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i := range m.jobs {
+		if m.jobs[i].ID == job.ID {
+			m.jobs[i].Status = job.Status
+			m.jobs[i].LastError = copyString(job.LastError)
+			m.jobs[i].RunAt = job.RunAt
+			m.jobs[i].UpdatedAt = time.Now().UTC()
+			updated := copyJob(m.jobs[i])
+			return &updated, nil
+		}
+	}
+
+	return nil, ErrJobNotFound
+}
+
+func (m *MemoryStore) CancelJobs(ctx context.Context, resourceType string, resourceID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now().UTC()
+	for i := range m.jobs {
+		j := &m.jobs[i]
+		if j.Status == "pending" && j.ResourceType == resourceType && j.ResourceID == resourceID {
+			j.Status = "cancelled"
+			j.UpdatedAt = now
+		}
+	}
+
+	return nil
+}
+
+func copyJob(j models.Job) models.Job {
+	j.Payload = append(json.RawMessage(nil), j.Payload...)
+	j.LastError = copyString(j.LastError)
+	return j
+}
+
+// Webhook subscription methods
+func (m *MemoryStore) CreateWebhookSubscription(ctx context.Context, req *models.WebhookSubscriptionRequest) (*models.WebhookSubscription, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now().UTC()
+	webhook := memoryWebhook{
+		subscription: models.WebhookSubscription{ID: m.nextWebhookID, CreatedAt: now},
+		secret:       req.Secret,
+	}
+	applyWebhookRequest(&webhook.subscription, req, now)
+	m.nextWebhookID++
+	m.webhooks = append(m.webhooks, webhook)
+
+	result := copyWebhook(webhook.subscription)
+	return &result, nil
+}
+
+func (m *MemoryStore) GetWebhookSubscriptions(ctx context.Context, activeOnly bool) ([]models.WebhookSubscription, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	subscriptions := []models.WebhookSubscription{}
+	for _, w := range m.webhooks {
+		if activeOnly && !w.subscription.Active {
+			continue
+		}
+		subscriptions = append(subscriptions, copyWebhook(w.subscription))
+	}
+
+	return subscriptions, nil
+}
+
+func (m *MemoryStore) GetWebhookSubscription(ctx context.Context, subscriptionID int) (*models.WebhookSubscription, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, w := range m.webhooks {
+		if w.subscription.ID == subscriptionID {
+			result := copyWebhook(w.subscription)
+			return &result, nil
+		}
+	}
+
+	return nil, ErrWebhookNotFound
+}
+
+func (m *MemoryStore) GetWebhookSecret(ctx context.Context, subscriptionID int) (string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, w := range m.webhooks {
+		if w.subscription.ID == subscriptionID {
+			return w.secret, nil
+		}
+	}
+
+	return "", ErrWebhookNotFound
+}
+
+func (m *MemoryStore) UpdateWebhookSubscription(ctx context.Context, subscriptionID int, req *models.WebhookSubscriptionRequest) (*models.WebhookSubscription, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i := range m.webhooks {
+		if m.webhooks[i].subscription.ID == subscriptionID {
+			applyWebhookRequest(&m.webhooks[i].subscription, req, time.Now().UTC())
+			if req.Secret != "" {
+				m.webhooks[i].secret = req.Secret
+			}
+			result := copyWebhook(m.webhooks[i].subscription)
+			return &result, nil
+		}
+	}
+
+	return nil, ErrWebhookNotFound
+}
+
+func (m *MemoryStore) DeleteWebhookSubscription(ctx context.Context, subscriptionID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i := range m.webhooks {
+		if m.webhooks[i].subscription.ID == subscriptionID {
+			m.webhooks = append(m.webhooks[:i], m.webhooks[i+1:]...)
+			// Mirror ON DELETE CASCADE on webhook_deliveries
+			deliveries := m.deliveries[:0]
+			for _, d := range m.deliveries {
+				if d.SubscriptionID != subscriptionID {
+					deliveries = append(deliveries, d)
+				}
+			}
+			m.deliveries = deliveries
+			return nil
+		}
+	}
+
+	return ErrWebhookNotFound
+}
+
+func applyWebhookRequest(subscription *models.WebhookSubscription, req *models.WebhookSubscriptionRequest, now time.Time) {
+	subscription.URL = req.URL
+	subscription.Events = append([]string(nil), req.Events...)
+	subscription.Description = copyString(req.Description)
+	subscription.Active = webhookActive(req)
+	subscription.UpdatedAt = now
+}
+
+func copyWebhook(w models.WebhookSubscription) models.WebhookSubscription {
+	w.Events = append([]string(nil), w.Events...)
+	w.Description = copyString(w.Description)
+	return w
+}
+
+// Webhook delivery methods
+func (m *MemoryStore) CreateWebhookDelivery(ctx context.Context, delivery *models.WebhookDelivery) (*models.WebhookDelivery, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now().UTC()
+	created := copyDelivery(*delivery)
+	created.ID = m.nextDeliveryID
+	created.Status = "pending"
+	created.Attempts = 0
+	if created.NextAttemptAt.IsZero() {
+		created.NextAttemptAt = now
+	}
+	created.CreatedAt = now
+	created.UpdatedAt = now
+	m.nextDeliveryID++
+	m.deliveries = append(m.deliveries, created)
+
+	result := copyDelivery(created)
+	return &result, nil
+}
+
+func (m *MemoryStore) GetWebhookDeliveries(ctx context.Context, filter WebhookDeliveryFilter, page Page) ([]models.WebhookDelivery, int, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	deliveries := []models.WebhookDelivery{}
+	for _, d := range m.deliveries {
+		if filter.SubscriptionID != nil && d.SubscriptionID != *filter.SubscriptionID {
+			continue
+		}
+		if filter.Event != "" && d.Event != filter.Event {
+			continue
+		}
+		if filter.Status != "" && d.Status != filter.Status {
+			continue
+		}
+		deliveries = append(deliveries, copyDelivery(d))
+	}
+	sort.SliceStable(deliveries, func(i, j int) bool {
+		a, b := deliveries[i], deliveries[j]
+		var c int
+		switch page.Sort {
+		case "updated_at":
+			c = a.UpdatedAt.Compare(b.UpdatedAt)
+		default:
+			c = a.CreatedAt.Compare(b.CreatedAt)
+		}
+		return pageLess(c, a.ID, b.ID, page.Descending)
+	})
+
+	start, end := pageBounds(len(deliveries), page)
+	return deliveries[start:end], len(deliveries), nil
+}
+
+func (m *MemoryStore) GetWebhookDelivery(ctx context.Context, deliveryID int) (*models.WebhookDelivery, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, d := range m.deliveries {
+		if d.ID == deliveryID {
+			result := copyDelivery(d)
+			return &result, nil
+		}
+	}
+
+	return nil, ErrDeliveryNotFound
+}
+
+func (m *MemoryStore) ClaimWebhookDeliveries(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]models.WebhookDelivery, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var due []int
+	for i, d := range m.deliveries {
+		if d.Status == "pending" && !d.NextAttemptAt.After(now) {
+			due = append(due, i)
+		}
+	}
+	sort.SliceStable(due, func(a, b int) bool {
+		return m.deliveries[due[a]].NextAttemptAt.Before(m.deliveries[due[b]].NextAttemptAt)
+	})
+	if len(due) > limit {
+		due = due[:limit]
+	}
+
+	claimed := []models.WebhookDelivery{}
+	for _, i := range due {
+		m.deliveries[i].Attempts++
+		m.deliveries[i].NextAttemptAt = now.Add(lease).UTC()
+		m.deliveries[i].UpdatedAt = time.Now().UTC()
+		claimed = append(claimed, copyDelivery(m.deliveries[i]))
+	}
+
+	return claimed, nil
+}
+
+func (m *MemoryStore) UpdateWebhookDelivery(ctx context.Context, delivery *models.WebhookDelivery) (*models.WebhookDelivery, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i := range m.deliveries {
+		if m.deliveries[i].ID == delivery.ID {
+			m.deliveries[i].Status = delivery.Status
+			m.deliveries[i].Attempts = delivery.Attempts
+			m.deliveries[i].LastError = copyString(delivery.LastError)
+			m.deliveries[i].ResponseStatus = copyInt(delivery.ResponseStatus)
+			m.deliveries[i].NextAttemptAt = delivery.NextAttemptAt
+			m.deliveries[i].DeliveredAt = copyTime(delivery.DeliveredAt)
+			m.deliveries[i].UpdatedAt = time.Now().UTC()
+			updated := copyDelivery(m.deliveries[i])
+			return &updated, nil
+		}
+	}
+
+	return nil, ErrDeliveryNotFound
+}
+
+func copyDelivery(d models.WebhookDelivery) models.WebhookDelivery {
+	d.Payload = append(json.RawMessage(nil), d.Payload...)
+	d.LastError = copyString(d.LastError)
+	d.ResponseStatus = copyInt(d.ResponseStatus)
+	d.DeliveredAt = copyTime(d.DeliveredAt)
+	return d
+}
+
+// Admin methods
+func (m *MemoryStore) CreateAdmin(ctx context.Context, email, passwordHash, name, role string, artistID *int) (*models.AdminUser, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.createAdmin(email, passwordHash, name, role, artistID)
+}
+
+// createAdmin adds an admin. Callers must hold m.mu for writing.
+func (m *MemoryStore) createAdmin(email, passwordHash, name, role string, artistID *int) (*models.AdminUser, error) {
+	key := strings.ToLower(email)
+	if _, exists := m.adminsByEmail[key]; exists {
+		return nil, ErrAdminExists
+	}
+
+	id, err := newUUID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create admin: %w", err)
+	}
+
+	now := time.Now().UTC()
+	admin := models.AdminUser{
+		ID:        id,
+		Email:     email,
+		Name:      name,
+		Role:      role,
+		ArtistID:  copyInt(artistID),
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	m.adminsByEmail[key] = len(m.admins)
+	m.admins = append(m.admins, memoryAdmin{admin: admin, passwordHash: passwordHash})
+
+	result := copyAdmin(admin)
+	return &result, nil
+}
+
+func (m *MemoryStore) GetAdmins(ctx context.Context) ([]models.AdminUser, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	admins := make([]models.AdminUser, len(m.admins))
+	for i, a := range m.admins {
+		admins[i] = copyAdmin(a.admin)
+	}
+	sort.SliceStable(admins, func(i, j int) bool {
+		return admins[i].CreatedAt.Before(admins[j].CreatedAt)
+	})
+
+	return admins, nil
+}
+
+func (m *MemoryStore) GetAdmin(ctx context.Context, adminID string) (*models.AdminUser, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, a := range m.admins {
+		if a.admin.ID == adminID {
+			admin := copyAdmin(a.admin)
+			return &admin, nil
+		}
+	}
+
+	return nil, ErrAdminNotFound
+}
+
+func (m *MemoryStore) UpdateAdminRole(ctx context.Context, adminID, role string, artistID *int) (*models.AdminUser, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	owners := 0
+	for _, a := range m.admins {
+		if a.admin.Role == auth.RoleOwner {
+			owners++
+		}
+	}
+
+	for i := range m.admins {
+		a := &m.admins[i].admin
+		if a.ID != adminID {
+			continue
+		}
+		if a.Role == auth.RoleOwner && role != auth.RoleOwner && owners <= 1 {
+			return nil, ErrLastOwner
+		}
+		a.Role = role
+		a.ArtistID = copyInt(artistID)
+		a.UpdatedAt = time.Now().UTC()
+		admin := copyAdmin(*a)
+		return &admin, nil
+	}
+
+	return nil, ErrAdminNotFound
+}
+
+func (m *MemoryStore) GetAdminByEmail(ctx context.Context, email string) (*models.AdminUser, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	idx, ok := m.adminsByEmail[strings.ToLower(email)]
+	if !ok {
+		return nil, ErrAdminNotFound
+	}
+
+	admin := copyAdmin(m.admins[idx].admin)
+	return &admin, nil
+}
+
+func (m *MemoryStore) GetAdminPasswordHash(ctx context.Context, email string) (string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	idx, ok := m.adminsByEmail[strings.ToLower(email)]
+	if !ok {
+		return "", ErrAdminNotFound
+	}
+
+	return m.admins[idx].passwordHash, nil
+}
+
+func (m *MemoryStore) UpdateAdminPassword(ctx context.Context, adminID, passwordHash string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i := range m.admins {
+		if m.admins[i].admin.ID == adminID {
+			m.admins[i].passwordHash = passwordHash
+			m.admins[i].admin.UpdatedAt = time.Now().UTC()
+			return nil
+		}
+	}
+
+	return ErrAdminNotFound
+}
+
+// Password reset methods
+func (m *MemoryStore) CreatePasswordReset(ctx context.Context, adminID, tokenHash string, expiresAt time.Time) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	id := m.nextResetID
+	m.nextResetID++
+	m.resets = append(m.resets, memoryReset{id: id, adminID: adminID, tokenHash: tokenHash, expiresAt: expiresAt})
+
+	return id, nil
+}
+
+func (m *MemoryStore) ResetAdminPassword(ctx context.Context, tokenHash, passwordHash string) (*models.AdminUser, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now().UTC()
+	var adminID string
+	for _, r := range m.resets {
+		if r.tokenHash == tokenHash && r.usedAt == nil && r.expiresAt.After(now) {
+			adminID = r.adminID
+		}
+	}
+	if adminID == "" {
+		return nil, ErrResetNotFound
+	}
+
+	for i := range m.admins {
+		a := &m.admins[i]
+		if a.admin.ID != adminID {
+			continue
+		}
+		a.passwordHash = passwordHash
+		a.admin.UpdatedAt = now
+		for j := range m.resets {
+			if m.resets[j].adminID == adminID && m.resets[j].usedAt == nil {
+				usedAt := now
+				m.resets[j].usedAt = &usedAt
+			}
+		}
+		m.revokeAdminSessions(adminID, "", now)
+
+		admin := copyAdmin(a.admin)
+		return &admin, nil
+	}
+
+	return nil, ErrResetNotFound
+}
+
+// Admin invite methods
+func (m *MemoryStore) CreateAdminInvite(ctx context.Context, invite *models.AdminInvite, tokenHash string) (*models.AdminInvite, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now().UTC()
+	created := copyInvite(*invite)
+	created.ID = m.nextInviteID
+	created.Status = "pending"
+	created.AcceptedAt = nil
+	created.CreatedAt = now
+	created.UpdatedAt = now
+	m.nextInviteID++
+	m.invites = append(m.invites, memoryInvite{invite: created, tokenHash: tokenHash})
+
+	result := copyInvite(created)
+	return &result, nil
+}
+
+func (m *MemoryStore) GetAdminInvites(ctx context.Context) ([]models.AdminInvite, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	invites := make([]models.AdminInvite, len(m.invites))
+	for i, inv := range m.invites {
+		invites[i] = copyInvite(inv.invite)
+	}
+	sort.SliceStable(invites, func(i, j int) bool {
+		return invites[i].CreatedAt.After(invites[j].CreatedAt)
+	})
+	markExpired(invites, time.Now())
+
+	return invites, nil
+}
+
+func (m *MemoryStore) GetAdminInviteByToken(ctx context.Context, tokenHash string) (*models.AdminInvite, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	inv := m.openInvite(tokenHash)
+	if inv == nil {
+		return nil, ErrInviteNotFound
+	}
+
+	result := copyInvite(inv.invite)
+	return &result, nil
+}
+
+func (m *MemoryStore) RevokeAdminInvite(ctx context.Context, inviteID int) (*models.AdminInvite, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i := range m.invites {
+		inv := &m.invites[i].invite
+		if inv.ID != inviteID || inv.Status != "pending" {
+			continue
+		}
+		inv.Status = "revoked"
+		inv.UpdatedAt = time.Now().UTC()
+
+		result := copyInvite(*inv)
+		return &result, nil
+	}
+
+	return nil, ErrInviteNotFound
+}
+
+func (m *MemoryStore) RedeemAdminInvite(ctx context.Context, tokenHash, passwordHash, name string) (*models.AdminUser, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	inv := m.openInvite(tokenHash)
+	if inv == nil {
+		return nil, ErrInviteNotFound
+	}
+
+	admin, err := m.createAdmin(inv.invite.Email, passwordHash, name, inv.invite.Role, inv.invite.ArtistID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	inv.invite.Status = "accepted"
+	inv.invite.AcceptedAt = &now
+	inv.invite.UpdatedAt = now
+
+	return admin, nil
+}
+
+// openInvite returns the pending, unexpired invite with tokenHash, or nil.
+// Callers must hold m.mu.
+func (m *MemoryStore) openInvite(tokenHash string) *memoryInvite {
+	now := time.Now()
+	for i := range m.invites {
+		inv := &m.invites[i]
+		if inv.tokenHash == tokenHash && inv.invite.Status == "pending" && inv.invite.ExpiresAt.After(now) {
+			return inv
+		}
+	}
+	return nil
+}
+
+// Admin session methods
+func (m *MemoryStore) CreateAdminSession(ctx context.Context, session *models.AdminSession, refreshTokenHash string, access AccessToken) (*models.AdminSession, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	id, err := newUUID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create admin session: %w", err)
+	}
+
+	now := time.Now().UTC()
+	created := *session
+	created.ID = id
+	created.LastUsedAt = now
+	created.RevokedAt = nil
+	created.CreatedAt = now
+	created.UpdatedAt = now
+	created.Current = false
+	m.sessions = append(m.sessions, memorySession{session: created, refreshTokenHash: refreshTokenHash, access: access})
+
+	result := copySession(created)
+	return &result, nil
+}
+
+func (m *MemoryStore) GetAdminSessions(ctx context.Context, adminID string) ([]models.AdminSession, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	now := time.Now()
+	sessions := []models.AdminSession{}
+	for _, s := range m.sessions {
+		if s.session.AdminID == adminID && activeSession(s.session, now) {
+			sessions = append(sessions, copySession(s.session))
+		}
+	}
+	sort.SliceStable(sessions, func(i, j int) bool {
+		return sessions[i].LastUsedAt.After(sessions[j].LastUsedAt)
+	})
+
+	return sessions, nil
+}
+
+func (m *MemoryStore) RotateAdminSession(ctx context.Context, refreshTokenHash, newRefreshTokenHash string, access AccessToken, expiresAt time.Time) (*models.AdminSession, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now().UTC()
+	for i := range m.sessions {
+		s := &m.sessions[i]
+		if s.refreshTokenHash != refreshTokenHash || !activeSession(s.session, now) {
+			continue
+		}
+		m.revokeToken(s.access, now)
+		s.usedRefreshTokenHashes = append(s.usedRefreshTokenHashes, s.refreshTokenHash)
+		s.refreshTokenHash = newRefreshTokenHash
+		s.access = access
+		s.session.ExpiresAt = expiresAt
+		s.session.LastUsedAt = now
+		s.session.UpdatedAt = now
+
+		result := copySession(s.session)
+		return &result, nil
+	}
+
+	// A refresh token used before ends its session
+	for i := range m.sessions {
+		s := &m.sessions[i]
+		if !activeSession(s.session, now) {
+			continue
+		}
+		for _, used := range s.usedRefreshTokenHashes {
+			if used == refreshTokenHash {
+				m.revokeToken(s.access, now)
+				s.session.RevokedAt = &now
+				s.session.UpdatedAt = now
+				return nil, ErrSessionNotFound
+			}
+		}
+	}
+
+	return nil, ErrSessionNotFound
+}
+
+func (m *MemoryStore) RevokeAdminSession(ctx context.Context, adminID, sessionID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now().UTC()
+	for i := range m.sessions {
+		s := &m.sessions[i]
+		if s.session.ID != sessionID || s.session.AdminID != adminID || !activeSession(s.session, now) {
+			continue
+		}
+		m.revokeToken(s.access, now)
+		s.session.RevokedAt = &now
+		s.session.UpdatedAt = now
+		return nil
+	}
+
+	return ErrSessionNotFound
+}
+
+func (m *MemoryStore) RevokeAdminSessions(ctx context.Context, adminID, exceptSessionID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.revokeAdminSessions(adminID, exceptSessionID, time.Now().UTC())
+	return nil
+}
+
+// revokeAdminSessions ends adminID's active sessions but exceptSessionID.
+// Callers must hold m.mu for writing.
+func (m *MemoryStore) revokeAdminSessions(adminID, exceptSessionID string, now time.Time) {
+	for i := range m.sessions {
+		s := &m.sessions[i]
+		if s.session.AdminID != adminID || s.session.ID == exceptSessionID || !activeSession(s.session, now) {
+			continue
+		}
+		m.revokeToken(s.access, now)
+		revokedAt := now
+		s.session.RevokedAt = &revokedAt
+		s.session.UpdatedAt = now
+	}
+}
+
+func (m *MemoryStore) IsTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	_, revoked := m.revokedTokens[jti]
+	return revoked, nil
+}
+
+// revokeToken adds access to the revoked tokens, forgetting those that have
+// expired. Callers must hold m.mu for writing.
+func (m *MemoryStore) revokeToken(access AccessToken, now time.Time) {
+	for jti, expiresAt := range m.revokedTokens {
+		if !expiresAt.After(now) {
+			delete(m.revokedTokens, jti)
+		}
+	}
+	if access.ExpiresAt.After(now) {
+		m.revokedTokens[access.JTI] = access.ExpiresAt
+	}
+}
+
+func activeSession(session models.AdminSession, now time.Time) bool {
+	return session.RevokedAt == nil && session.ExpiresAt.After(now)
+}
+
+func copySession(s models.AdminSession) models.AdminSession {
+	s.RevokedAt = copyTime(s.RevokedAt)
+	return s
+}
+
+// Two-factor methods
+func (m *MemoryStore) GetAdminTwoFactor(ctx context.Context, adminID string) (*TwoFactor, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	a := m.findAdmin(adminID)
+	if a == nil {
+		return nil, ErrAdminNotFound
+	}
+
+	return &TwoFactor{Secret: a.totpSecret, Enabled: a.admin.TwoFactorEnabled, LastStep: a.lastStep}, nil
+}
+
+func (m *MemoryStore) SetAdminTOTPSecret(ctx context.Context, adminID, secret string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	a := m.findAdmin(adminID)
+	if a == nil {
+		return ErrAdminNotFound
+	}
+	a.totpSecret = secret
+	a.admin.TwoFactorEnabled = false
+	a.lastStep = 0
+	a.admin.UpdatedAt = time.Now().UTC()
+
+	return nil
+}
+
+func (m *MemoryStore) EnableAdminTwoFactor(ctx context.Context, adminID string, step int64, recoveryCodeHashes []string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	a := m.findAdmin(adminID)
+	if a == nil {
+		return ErrAdminNotFound
+	}
+	a.admin.TwoFactorEnabled = true
+	a.lastStep = step
+	a.recoveryCodes = make(map[string]bool, len(recoveryCodeHashes))
+	for _, hash := range recoveryCodeHashes {
+		a.recoveryCodes[hash] = false
+	}
+	a.admin.UpdatedAt = time.Now().UTC()
+
+	return nil
+}
+
+func (m *MemoryStore) DisableAdminTwoFactor(ctx context.Context, adminID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	a := m.findAdmin(adminID)
+	if a == nil {
+		return ErrAdminNotFound
+	}
+	a.totpSecret = ""
+	a.admin.TwoFactorEnabled = false
+	a.lastStep = 0
+	a.recoveryCodes = nil
+	a.admin.UpdatedAt = time.Now().UTC()
+
+	return nil
+}
+
+func (m *MemoryStore) ClaimTOTPStep(ctx context.Context, adminID string, step int64) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	a := m.findAdmin(adminID)
+	if a == nil {
+		return false, ErrAdminNotFound
+	}
+	if a.lastStep >= step {
+		return false, nil
+	}
+	a.lastStep = step
+
+	return true, nil
+}
+
+func (m *MemoryStore) UseRecoveryCode(ctx context.Context, adminID, codeHash string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	a := m.findAdmin(adminID)
+	if a == nil {
+		return ErrAdminNotFound
+	}
+	used, ok := a.recoveryCodes[codeHash]
+	if !ok || used {
+		return ErrRecoveryCodeNotFound
+	}
+	a.recoveryCodes[codeHash] = true
+
+	return nil
+}
+
+// findAdmin returns the admin with adminID, or nil. Callers must hold m.mu.
+func (m *MemoryStore) findAdmin(adminID string) *memoryAdmin {
+	for i := range m.admins {
+		if m.admins[i].admin.ID == adminID {
+			return &m.admins[i]
+		}
+	}
+	return nil
+}
+
+func (m *MemoryStore) GetSecuritySettings(ctx context.Context) (*models.SecuritySettings, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	settings := m.security
+	return &settings, nil
+}
+
+func (m *MemoryStore) UpdateSecuritySettings(ctx context.Context, requireTwoFactor bool) (*models.SecuritySettings, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.security.RequireTwoFactor = requireTwoFactor
+	m.security.UpdatedAt = time.Now().UTC()
+
+	settings := m.security
+	return &settings, nil
+}
+
+// Login throttle methods
+func (m *MemoryStore) GetLoginThrottles(ctx context.Context, keys []string) ([]models.LoginThrottle, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	throttles := []models.LoginThrottle{}
+	for _, key := range keys {
+		if throttle, ok := m.throttles[key]; ok {
+			throttles = append(throttles, throttle)
+		}
+	}
+
+	return throttles, nil
+}
+
+func (m *MemoryStore) RecordLoginFailure(ctx context.Context, keys []string, now, resetBefore time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, key := range keys {
+		throttle, ok := m.throttles[key]
+		if !ok || throttle.LastFailureAt.Before(resetBefore) {
+			throttle = models.LoginThrottle{Key: key}
+		}
+		throttle.Failures++
+		throttle.LastFailureAt = now
+		m.throttles[key] = throttle
+	}
+
+	return nil
+}
+
+func (m *MemoryStore) ClearLoginThrottle(ctx context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.throttles, key)
+	return nil
+}
+
+func (m *MemoryStore) CreateLoginAttempt(ctx context.Context, attempt *models.LoginAttempt) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	created := *attempt
+	created.ID = m.nextAttemptID
+	created.CreatedAt = time.Now().UTC()
+	m.nextAttemptID++
+	m.loginAttempts = append(m.loginAttempts, created)
+
+	return nil
+}
+
+func (m *MemoryStore) GetLoginAttempts(ctx context.Context, filter LoginAttemptFilter, page Page) ([]models.LoginAttempt, int, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	attempts := []models.LoginAttempt{}
+	for _, a := range m.loginAttempts {
+		if filter.Email != "" && !strings.EqualFold(a.Email, filter.Email) {
+			continue
+		}
+		if filter.IPAddress != "" && a.IPAddress != filter.IPAddress {
+			continue
+		}
+		if filter.Result != "" && a.Result != filter.Result {
+			continue
+		}
+		attempts = append(attempts, a)
+	}
+	sort.SliceStable(attempts, func(i, j int) bool {
+		a, b := attempts[i], attempts[j]
+		return pageLess(a.CreatedAt.Compare(b.CreatedAt), a.ID, b.ID, page.Descending)
+	})
+
+	start, end := pageBounds(len(attempts), page)
+	return attempts[start:end], len(attempts), nil
+}
+
+func copyInvite(inv models.AdminInvite) models.AdminInvite {
+	inv.ArtistID = copyInt(inv.ArtistID)
+	inv.InvitedBy = copyString(inv.InvitedBy)
+	inv.AcceptedAt = copyTime(inv.AcceptedAt)
+	return inv
+}
+
+func copyAdmin(a models.AdminUser) models.AdminUser {
+	a.ArtistID = copyInt(a.ArtistID)
+	return a
+}
+
+// newUUID returns a random (version 4) UUID, matching the uuid_generate_v4()
+// default used for admin_users in Postgres.
+func newUUID() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16]), nil
+}
+
+// pageLess orders two rows that compare as c, falling back to their IDs
+// when they tie.
+func pageLess(c, aID, bID int, descending bool) bool {
+	if c == 0 {
+		c = aID - bID
+	}
+	if descending {
+		return c > 0
+	}
+	return c < 0
+}
+
+// pageBounds returns the slice bounds of page within total rows.
+func pageBounds(total int, page Page) (int, int) {
+	start := min(page.Offset, total)
+	end := total
+	if page.Limit > 0 {
+		end = min(start+page.Limit, total)
+	}
+	return start, end
+}
+
+// containsFold reports whether any of values contains search, ignoring case.
+func containsFold(search string, values ...string) bool {
+	search = strings.ToLower(search)
+	for _, value := range values {
+		if strings.Contains(strings.ToLower(value), search) {
+			return true
+		}
+	}
+	return false
+}
+
+func derefString(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+func copyInt(n *int) *int {
+	if n == nil {
+		return nil
+	}
+	v := *n
+	return &v
+}
+
+func copyTime(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	v := *t
+	return &v
+}
+
+func copyString(s *string) *string {
+	if s == nil {
+		return nil
+	}
+	v := *s
+	return &v
+}
+
+// Customer methods
+
+// matchCustomer returns the ID of the customer with email, creating one if
+// there is none. Callers must hold m.mu.
+func (m *MemoryStore) matchCustomer(name, email, phone string, now time.Time) int {
+	email = customerEmail(email)
+	for _, c := range m.customers {
+		if c.Email == email {
+			return c.ID
+		}
+	}
+
+	customer := models.Customer{
+		ID:        m.nextCustomerID,
+		Name:      name,
+		Email:     email,
+		Phone:     phone,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	m.nextCustomerID++
+	m.customers = append(m.customers, customer)
+	return customer.ID
+}
+
+func (m *MemoryStore) GetCustomers(ctx context.Context, filter CustomerFilter, page Page) ([]models.Customer, int, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	customers := []models.Customer{}
+	for _, c := range m.customers {
+		if filter.Search != "" && !containsFold(filter.Search, c.Name, c.Email, c.Phone) {
+			continue
+		}
+		customers = append(customers, copyCustomer(c))
+	}
+	sort.SliceStable(customers, func(i, j int) bool {
+		a, b := customers[i], customers[j]
+		var c int
+		switch page.Sort {
+		case "name":
+			c = strings.Compare(a.Name, b.Name)
+		case "email":
+			c = strings.Compare(a.Email, b.Email)
+		case "updated_at":
+			c = a.UpdatedAt.Compare(b.UpdatedAt)
+		default:
+			c = a.CreatedAt.Compare(b.CreatedAt)
+		}
+		return pageLess(c, a.ID, b.ID, page.Descending)
+	})
+
+	start, end := pageBounds(len(customers), page)
+	return customers[start:end], len(customers), nil
+}
+
+func (m *MemoryStore) GetCustomer(ctx context.Context, customerID int) (*models.Customer, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, c := range m.customers {
+		if c.ID == customerID {
+			customer := copyCustomer(c)
+			return &customer, nil
+		}
+	}
+
+	return nil, ErrCustomerNotFound
+}
+
+func (m *MemoryStore) UpdateCustomer(ctx context.Context, customerID int, req *models.CustomerRequest) (*models.Customer, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	email := customerEmail(req.Email)
+	index := -1
+	for i, c := range m.customers {
+		if c.ID == customerID {
+			index = i
+		} else if c.Email == email {
+			return nil, ErrCustomerExists
+		}
+	}
+	if index < 0 {
+		return nil, ErrCustomerNotFound
+	}
+
+	c := &m.customers[index]
+	c.Name = req.Name
+	c.Email = email
+	c.Phone = req.Phone
+	c.Notes = copyString(req.Notes)
+	c.UpdatedAt = time.Now().UTC()
+
+	customer := copyCustomer(*c)
+	return &customer, nil
+}
+
+func (m *MemoryStore) MergeCustomers(ctx context.Context, targetID, sourceID int) (*models.Customer, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	target, source := -1, -1
+	for i, c := range m.customers {
+		switch c.ID {
+		case targetID:
+			target = i
+		case sourceID:
+			source = i
+		}
+	}
+	if target < 0 || source < 0 {
+		return nil, ErrCustomerNotFound
+	}
+
+	now := time.Now().UTC()
+	for i := range m.appointments {
+		if a := &m.appointments[i]; a.CustomerID != nil && *a.CustomerID == sourceID {
+			a.CustomerID = &targetID
+			a.UpdatedAt = now
+		}
+	}
+	for i := range m.classes {
+		if c := &m.classes[i]; c.CustomerID != nil && *c.CustomerID == sourceID {
+			c.CustomerID = &targetID
+			c.UpdatedAt = now
+		}
+	}
+
+	t, src := &m.customers[target], m.customers[source]
+	if t.Phone == "" {
+		t.Phone = src.Phone
+	}
+	t.Notes = copyString(mergeNotes(t.Notes, src.Notes))
+	t.UpdatedAt = now
+	customer := copyCustomer(*t)
+
+	m.customers = append(m.customers[:source], m.customers[source+1:]...)
+	return &customer, nil
+}
+
+func copyCustomer(c models.Customer) models.Customer {
+	c.Notes = copyString(c.Notes)
+	return c
+}
+
+// Idempotency key methods
+func (m *MemoryStore) ClaimIdempotencyKey(ctx context.Context, key *models.IdempotencyKey, now time.Time) (*models.IdempotencyKey, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	lookup := key.Scope + "\x00" + key.Key
+	if id, ok := m.idempotencyKeyIDs[lookup]; ok {
+		existing := m.idempotencyKeys[id]
+		if existing.ExpiresAt.After(now) {
+			return &existing, false, nil
+		}
+		delete(m.idempotencyKeys, id)
+	}
+
+	claimed := *key
+	claimed.ID = m.nextIdempotencyKeyID
+	claimed.StatusCode = 0
+	claimed.ResponseBody = ""
+	claimed.CreatedAt = now
+	m.nextIdempotencyKeyID++
+	m.idempotencyKeys[claimed.ID] = claimed
+	m.idempotencyKeyIDs[lookup] = claimed.ID
+
+	return &claimed, true, nil
+}
+
+func (m *MemoryStore) CompleteIdempotencyKey(ctx context.Context, keyID, statusCode int, responseBody string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if key, ok := m.idempotencyKeys[keyID]; ok {
+		key.StatusCode = statusCode
+		key.ResponseBody = responseBody
+		m.idempotencyKeys[keyID] = key
+	}
+	return nil
+}
+
+func (m *MemoryStore) ReleaseIdempotencyKey(ctx context.Context, keyID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.deleteIdempotencyKey(keyID)
+	return nil
+}
+
+func (m *MemoryStore) DeleteExpiredIdempotencyKeys(ctx context.Context, now time.Time) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	deleted := 0
+	for id, key := range m.idempotencyKeys {
+		if !key.ExpiresAt.After(now) {
+			m.deleteIdempotencyKey(id)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+// deleteIdempotencyKey deletes a key and its lookup entry. Callers must
+// hold m.mu.
+func (m *MemoryStore) deleteIdempotencyKey(keyID int) {
+	key, ok := m.idempotencyKeys[keyID]
+	if !ok {
+		return
+	}
+	delete(m.idempotencyKeys, keyID)
+	lookup := key.Scope + "\x00" + key.Key
+	if m.idempotencyKeyIDs[lookup] == keyID {
+		delete(m.idempotencyKeyIDs, lookup)
+	}
+}
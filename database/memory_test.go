@@ -0,0 +1,326 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"mumuni_backend/models"
+)
+
+func TestMemoryStoreConcurrentAppointments(t *testing.T) {
+	store := NewMemoryStore()
+	ctx := context.Background()
+
+	// Every booking asks for the same slot; the check runs under the lock,
+	// so exactly one of them may take it
+	errTaken := errors.New("slot taken")
+	taken := func(existing []models.Appointment) error {
+		if len(existing) > 0 {
+			return errTaken
+		}
+		return nil
+	}
+
+	const bookings = 50
+	var wg sync.WaitGroup
+	errs := make(chan error, bookings)
+	for i := 0; i < bookings; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, err := store.CreateAppointment(ctx, &models.AppointmentRequest{
+				Name:    "Customer " + strconv.Itoa(i),
+				Email:   "customer" + strconv.Itoa(i) + "@example.com",
+				Phone:   "+23480311122" + strconv.Itoa(10+i),
+				Date:    "2030-01-07",
+				Time:    "10:00 AM",
+				Service: "Everyday Glam",
+			}, "hash-"+strconv.Itoa(i), taken)
+			errs <- err
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+
+	created := 0
+	for err := range errs {
+		switch {
+		case err == nil:
+			created++
+		case !errors.Is(err, errTaken):
+			t.Fatalf("CreateAppointment: %v", err)
+		}
+	}
+	if created != 1 {
+		t.Fatalf("%d bookings took the slot, want 1", created)
+	}
+
+	appointments, total, err := store.GetAppointments(ctx, AppointmentFilter{}, Page{})
+	if err != nil {
+		t.Fatalf("GetAppointments: %v", err)
+	}
+	if total != 1 || len(appointments) != 1 {
+		t.Fatalf("got %d appointments (total %d), want 1", len(appointments), total)
+	}
+}
+
+func TestMemoryStoreAdmins(t *testing.T) {
+	store := NewMemoryStore()
+	ctx := context.Background()
+
+	admin, err := store.CreateAdmin(ctx, "Owner@Example.com", "hash", "Owner", "owner", nil)
+	if err != nil {
+		t.Fatalf("CreateAdmin: %v", err)
+	}
+	if _, err := store.CreateAdmin(ctx, "owner@example.com", "hash", "Again", "owner", nil); !errors.Is(err, ErrAdminExists) {
+		t.Errorf("creating an admin with the same email: got %v, want ErrAdminExists", err)
+	}
+
+	found, err := store.GetAdminByEmail(ctx, "owner@example.com")
+	if err != nil || found.ID != admin.ID {
+		t.Errorf("GetAdminByEmail = %+v, %v; want admin %s", found, err, admin.ID)
+	}
+	if hash, err := store.GetAdminPasswordHash(ctx, "OWNER@example.com"); err != nil || hash != "hash" {
+		t.Errorf("GetAdminPasswordHash = %q, %v; want the stored hash", hash, err)
+	}
+	if _, err := store.GetAdminByEmail(ctx, "nobody@example.com"); !errors.Is(err, ErrAdminNotFound) {
+		t.Errorf("unknown email: got %v, want ErrAdminNotFound", err)
+	}
+}
+
+func TestMemoryStoreMatchesCustomersByEmailOnly(t *testing.T) {
+	store := NewMemoryStore()
+	ctx := context.Background()
+
+	book := func(email, phone, clock string) *models.Appointment {
+		t.Helper()
+		appointment, err := store.CreateAppointment(ctx, &models.AppointmentRequest{
+			Name:    "Customer",
+			Email:   email,
+			Phone:   phone,
+			Date:    "2030-01-07",
+			Time:    clock,
+			Service: "Everyday Glam",
+		}, "hash-"+clock, nil)
+		if err != nil {
+			t.Fatalf("CreateAppointment: %v", err)
+		}
+		return appointment
+	}
+
+	first := *book("ada@example.com", "+2348031112222", "10:00 AM").CustomerID
+	if again := *book(" Ada@Example.com", "+2348039998888", "12:00 PM").CustomerID; again != first {
+		t.Errorf("same email: customer %d, want %d", again, first)
+	}
+	// Someone else giving Ada's number gets a customer of their own
+	if other := *book("bola@example.com", "+2348031112222", "2:00 PM").CustomerID; other == first {
+		t.Errorf("same phone, different email: joined customer %d", first)
+	}
+
+	if _, total, _ := store.GetCustomers(ctx, CustomerFilter{}, Page{}); total != 2 {
+		t.Errorf("got %d customers, want 2", total)
+	}
+}
+
+func TestMemoryStorePromotesOneWaitlistedClassPerSeat(t *testing.T) {
+	store := NewMemoryStore()
+	ctx := context.Background()
+
+	offering, err := store.CreateClassOffering(ctx, &models.ClassOfferingRequest{Name: "Basics"})
+	if err != nil {
+		t.Fatalf("CreateClassOffering: %v", err)
+	}
+	cohort, err := store.CreateCohort(ctx, &models.CohortRequest{
+		OfferingID: offering.ID,
+		Name:       "January",
+		StartDate:  "2030-01-07",
+		Capacity:   1,
+	})
+	if err != nil {
+		t.Fatalf("CreateCohort: %v", err)
+	}
+
+	// The first enrollment takes the only seat and is then cancelled, so
+	// one seat is free for the rest, who are waitlisted
+	var entries []*models.WaitlistEntry
+	var first int
+	for i := 0; i < 11; i++ {
+		class, err := store.CreateClass(ctx, &models.ClassRequest{
+			Name:       "Student " + strconv.Itoa(i),
+			Email:      "student" + strconv.Itoa(i) + "@example.com",
+			Phone:      "+23480311122" + strconv.Itoa(10+i),
+			Experience: "beginner",
+			CohortID:   &cohort.ID,
+		}, "hash-"+strconv.Itoa(i), true)
+		if err != nil {
+			t.Fatalf("CreateClass: %v", err)
+		}
+		if i == 0 {
+			first = class.ID
+			continue
+		}
+		entry, err := store.CreateWaitlistEntry(ctx, &models.WaitlistEntry{
+			Kind:     "class",
+			Name:     class.Name,
+			Email:    class.Email,
+			Phone:    class.Phone,
+			CohortID: &cohort.ID,
+			ClassID:  &class.ID,
+		})
+		if err != nil {
+			t.Fatalf("CreateWaitlistEntry: %v", err)
+		}
+		entries = append(entries, entry)
+	}
+	if _, err := store.UpdateClassStatus(ctx, first, StatusChange{Status: "cancelled", Reason: "Moved away"}); err != nil {
+		t.Fatalf("UpdateClassStatus: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, len(entries))
+	for _, entry := range entries {
+		wg.Add(1)
+		go func(entry models.WaitlistEntry) {
+			defer wg.Done()
+			entry.Status = "promoted"
+			_, _, err := store.PromoteWaitlistedClass(ctx, &entry, StatusChange{Status: "pending"}, false)
+			errs <- err
+		}(*entry)
+	}
+	wg.Wait()
+	close(errs)
+
+	promoted := 0
+	for err := range errs {
+		switch {
+		case err == nil:
+			promoted++
+		case !errors.Is(err, ErrCohortFull):
+			t.Fatalf("PromoteWaitlistedClass: %v", err)
+		}
+	}
+	if promoted != 1 {
+		t.Fatalf("%d enrollments took the seat, want 1", promoted)
+	}
+
+	waiting, err := store.GetWaitlistEntries(ctx, WaitlistFilter{Status: "waiting"})
+	if err != nil {
+		t.Fatalf("GetWaitlistEntries: %v", err)
+	}
+	if len(waiting) != len(entries)-1 {
+		t.Errorf("%d entries still waiting, want %d", len(waiting), len(entries)-1)
+	}
+
+	// An admin can still seat someone in the full cohort
+	overfilled := waiting[0]
+	overfilled.Status = "promoted"
+	if _, _, err := store.PromoteWaitlistedClass(ctx, &overfilled, StatusChange{Status: "pending"}, true); err != nil {
+		t.Errorf("overfilling: %v", err)
+	}
+}
+
+func TestMemoryStoreRedeemsInviteOnce(t *testing.T) {
+	store := NewMemoryStore()
+	ctx := context.Background()
+
+	_, err := store.CreateAdminInvite(ctx, &models.AdminInvite{
+		Email:     "manager@example.com",
+		Role:      "manager",
+		ExpiresAt: time.Now().Add(time.Hour),
+	}, "invite-hash")
+	if err != nil {
+		t.Fatalf("CreateAdminInvite: %v", err)
+	}
+
+	const attempts = 20
+	var wg sync.WaitGroup
+	errs := make(chan error, attempts)
+	for i := 0; i < attempts; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, err := store.RedeemAdminInvite(ctx, "invite-hash", "hash", "Manager "+strconv.Itoa(i))
+			errs <- err
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+
+	redeemed := 0
+	for err := range errs {
+		switch {
+		case err == nil:
+			redeemed++
+		case !errors.Is(err, ErrInviteNotFound):
+			t.Fatalf("RedeemAdminInvite: %v", err)
+		}
+	}
+	if redeemed != 1 {
+		t.Fatalf("the invite was redeemed %d times, want 1", redeemed)
+	}
+	if admins, _ := store.GetAdmins(ctx); len(admins) != 1 || admins[0].Role != "manager" {
+		t.Errorf("admins %+v, want one manager", admins)
+	}
+}
+
+func TestMemoryStoreKeepsAnOwner(t *testing.T) {
+	store := NewMemoryStore()
+	ctx := context.Background()
+
+	// Every owner demotes themself at once; the count and the update happen
+	// under one lock, so exactly one of them must stay an owner
+	const owners = 10
+	ids := make([]string, owners)
+	for i := range ids {
+		admin, err := store.CreateAdmin(ctx, "owner"+strconv.Itoa(i)+"@example.com", "hash", "Owner", "owner", nil)
+		if err != nil {
+			t.Fatalf("CreateAdmin: %v", err)
+		}
+		ids[i] = admin.ID
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, owners)
+	for _, id := range ids {
+		wg.Add(1)
+		go func(id string) {
+			defer wg.Done()
+			_, err := store.UpdateAdminRole(ctx, id, "manager", nil)
+			errs <- err
+		}(id)
+	}
+	wg.Wait()
+	close(errs)
+
+	refused := 0
+	for err := range errs {
+		switch {
+		case errors.Is(err, ErrLastOwner):
+			refused++
+		case err != nil:
+			t.Fatalf("UpdateAdminRole: %v", err)
+		}
+	}
+	if refused != 1 {
+		t.Errorf("%d demotions were refused, want 1", refused)
+	}
+
+	admins, _ := store.GetAdmins(ctx)
+	remaining := 0
+	for _, admin := range admins {
+		if admin.Role == "owner" {
+			remaining++
+		}
+	}
+	if remaining != 1 {
+		t.Errorf("%d owners left, want 1", remaining)
+	}
+
+	if _, err := store.UpdateAdminRole(ctx, "no-such-admin", "manager", nil); !errors.Is(err, ErrAdminNotFound) {
+		t.Errorf("demoting an unknown admin: got %v, want ErrAdminNotFound", err)
+	}
+}
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS classes;
+DROP TABLE IF EXISTS appointments;
+DROP TABLE IF EXISTS admin_users;
+DROP FUNCTION IF EXISTS update_updated_at_column();
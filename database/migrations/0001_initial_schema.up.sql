@@ -1,10 +1,12 @@
--- Supabase Database Schema for Mumuni Backend
+-- Initial Mumuni schema: admin users, appointments and class enrollments.
+-- Written with IF NOT EXISTS so it can be applied to databases that were
+-- created by pasting the old schema.sql into the Supabase SQL editor.
 
 -- Enable necessary extensions
 CREATE EXTENSION IF NOT EXISTS "uuid-ossp";
 
 -- Admin users table
-CREATE TABLE admin_users (
+CREATE TABLE IF NOT EXISTS admin_users (
     id UUID DEFAULT uuid_generate_v4() PRIMARY KEY,
     email VARCHAR(255) UNIQUE NOT NULL,
     password_hash VARCHAR(255) NOT NULL,
@@ -14,7 +16,7 @@ CREATE TABLE admin_users (
 );
 
 -- Appointments table
-CREATE TABLE appointments (
+CREATE TABLE IF NOT EXISTS appointments (
     id SERIAL PRIMARY KEY,
     name VARCHAR(255) NOT NULL,
     email VARCHAR(255) NOT NULL,
@@ -29,7 +31,7 @@ CREATE TABLE appointments (
 );
 
 -- Classes table
-CREATE TABLE classes (
+CREATE TABLE IF NOT EXISTS classes (
     id SERIAL PRIMARY KEY,
     name VARCHAR(255) NOT NULL,
     email VARCHAR(255) NOT NULL,
@@ -44,15 +46,15 @@ CREATE TABLE classes (
 );
 
 -- Create indexes for better performance
-CREATE INDEX idx_appointments_email ON appointments(email);
-CREATE INDEX idx_appointments_date ON appointments(appointment_date);
-CREATE INDEX idx_appointments_status ON appointments(status);
+CREATE INDEX IF NOT EXISTS idx_appointments_email ON appointments(email);
+CREATE INDEX IF NOT EXISTS idx_appointments_date ON appointments(appointment_date);
+CREATE INDEX IF NOT EXISTS idx_appointments_status ON appointments(status);
 
-CREATE INDEX idx_classes_email ON classes(email);
-CREATE INDEX idx_classes_type ON classes(class_type);
-CREATE INDEX idx_classes_status ON classes(status);
+CREATE INDEX IF NOT EXISTS idx_classes_email ON classes(email);
+CREATE INDEX IF NOT EXISTS idx_classes_type ON classes(class_type);
+CREATE INDEX IF NOT EXISTS idx_classes_status ON classes(status);
 
-CREATE INDEX idx_admin_users_email ON admin_users(email);
+CREATE INDEX IF NOT EXISTS idx_admin_users_email ON admin_users(email);
 
 -- Create updated_at trigger function
 CREATE OR REPLACE FUNCTION update_updated_at_column()
@@ -64,11 +66,15 @@ END;
 $$ language 'plpgsql';
 
 -- Create triggers for updated_at
+DROP TRIGGER IF EXISTS update_admin_users_updated_at ON admin_users;
 CREATE TRIGGER update_admin_users_updated_at BEFORE UPDATE ON admin_users FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+DROP TRIGGER IF EXISTS update_appointments_updated_at ON appointments;
 CREATE TRIGGER update_appointments_updated_at BEFORE UPDATE ON appointments FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+DROP TRIGGER IF EXISTS update_classes_updated_at ON classes;
 CREATE TRIGGER update_classes_updated_at BEFORE UPDATE ON classes FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
 
 -- Insert a default admin user (password: admin123)
 -- Password hash for 'admin123' using bcrypt
-INSERT INTO admin_users (email, password_hash, name) VALUES 
-('admin@mumuni.com', '$2a$10$92IXUNpkjO0rOQ5byMi.Ye4oKoEa3Ro9llC/.og/at2.uheWG/igi', 'Admin User');
+INSERT INTO admin_users (email, password_hash, name) VALUES
+('admin@mumuni.com', '$2a$10$92IXUNpkjO0rOQ5byMi.Ye4oKoEa3Ro9llC/.og/at2.uheWG/igi', 'Admin User')
+ON CONFLICT (email) DO NOTHING;
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_appointments_artist_date;
+ALTER TABLE appointments DROP COLUMN IF EXISTS artist_id;
+DROP TABLE IF EXISTS artists;
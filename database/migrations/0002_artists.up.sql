@@ -0,0 +1,21 @@
+-- Makeup artists and their assignment to appointments.
+
+CREATE TABLE IF NOT EXISTS artists (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    email VARCHAR(255),
+    phone VARCHAR(50),
+    -- Services (by name) the artist performs
+    skills TEXT[] NOT NULL DEFAULT '{}',
+    -- Weekly hours in the BUSINESS_HOURS format; empty means studio hours
+    working_hours TEXT NOT NULL DEFAULT '',
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE TRIGGER update_artists_updated_at BEFORE UPDATE ON artists FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+ALTER TABLE appointments ADD COLUMN artist_id INTEGER REFERENCES artists(id) ON DELETE SET NULL;
+
+CREATE INDEX idx_appointments_artist_date ON appointments(artist_id, appointment_date);
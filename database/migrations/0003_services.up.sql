@@ -0,0 +1,28 @@
+-- Admin-managed service catalog. Appointments keep referring to services by
+-- name, so renaming a service does not rewrite past bookings.
+
+CREATE TABLE IF NOT EXISTS services (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(100) UNIQUE NOT NULL,
+    description TEXT,
+    duration_minutes INTEGER NOT NULL CHECK (duration_minutes > 0),
+    buffer_minutes INTEGER NOT NULL DEFAULT 0 CHECK (buffer_minutes >= 0),
+    price NUMERIC(12, 2) NOT NULL DEFAULT 0 CHECK (price >= 0),
+    deposit_amount NUMERIC(12, 2) NOT NULL DEFAULT 0 CHECK (deposit_amount >= 0),
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    display_order INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX idx_services_active_order ON services(active, display_order);
+
+CREATE TRIGGER update_services_updated_at BEFORE UPDATE ON services FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+-- The services previously hardcoded in BookAppointment
+INSERT INTO services (name, description, duration_minutes, buffer_minutes, price, display_order) VALUES
+('Bridal Makeup', 'Full bridal look for the big day', 180, 30, 50000, 1),
+('Event Makeup', 'Glam for parties, weddings and special events', 90, 15, 25000, 2),
+('Photoshoot Makeup', 'Camera-ready makeup for shoots', 90, 15, 30000, 3),
+('Everyday Glam', 'Polished everyday look', 60, 15, 15000, 4)
+ON CONFLICT (name) DO NOTHING;
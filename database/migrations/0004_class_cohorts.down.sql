@@ -0,0 +1,10 @@
+UPDATE classes SET status = 'pending' WHERE status = 'waitlisted';
+ALTER TABLE classes DROP CONSTRAINT IF EXISTS classes_status_check;
+ALTER TABLE classes ADD CONSTRAINT classes_status_check
+    CHECK (status IN ('pending', 'confirmed', 'cancelled', 'completed'));
+
+DROP INDEX IF EXISTS idx_classes_cohort_status;
+ALTER TABLE classes DROP COLUMN IF EXISTS cohort_id;
+
+DROP TABLE IF EXISTS class_cohorts;
+DROP TABLE IF EXISTS class_offerings;
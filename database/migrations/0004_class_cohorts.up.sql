@@ -0,0 +1,51 @@
+-- Class catalog and scheduled cohorts. Enrollments keep their class_type by
+-- name; cohort_id ties an enrollment to a concrete run of the class.
+
+CREATE TABLE IF NOT EXISTS class_offerings (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(100) UNIQUE NOT NULL,
+    description TEXT,
+    price NUMERIC(12, 2) NOT NULL DEFAULT 0 CHECK (price >= 0),
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    display_order INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE TRIGGER update_class_offerings_updated_at BEFORE UPDATE ON class_offerings FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+CREATE TABLE IF NOT EXISTS class_cohorts (
+    id SERIAL PRIMARY KEY,
+    offering_id INTEGER NOT NULL REFERENCES class_offerings(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    start_date DATE NOT NULL,
+    end_date DATE CHECK (end_date IS NULL OR end_date >= start_date),
+    -- Weekly meeting times in the BUSINESS_HOURS format
+    meeting_times TEXT NOT NULL DEFAULT '',
+    instructor VARCHAR(255),
+    capacity INTEGER NOT NULL CHECK (capacity > 0),
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX idx_class_cohorts_offering_start ON class_cohorts(offering_id, start_date);
+
+CREATE TRIGGER update_class_cohorts_updated_at BEFORE UPDATE ON class_cohorts FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+ALTER TABLE classes ADD COLUMN cohort_id INTEGER REFERENCES class_cohorts(id) ON DELETE SET NULL;
+
+CREATE INDEX idx_classes_cohort_status ON classes(cohort_id, status);
+
+-- Enrollments for a full cohort can be waitlisted
+ALTER TABLE classes DROP CONSTRAINT IF EXISTS classes_status_check;
+ALTER TABLE classes ADD CONSTRAINT classes_status_check
+    CHECK (status IN ('pending', 'confirmed', 'cancelled', 'completed', 'waitlisted'));
+
+-- The class types previously hardcoded in EnrollInClass
+INSERT INTO class_offerings (name, description, price, display_order) VALUES
+('Beginner Basics', 'Skin prep, base and everyday looks for beginners', 35000, 1),
+('Advanced Techniques', 'Sculpting, cut creases and editorial techniques', 75000, 2),
+('Bridal Specialist', 'Long-wear bridal looks and working with brides', 60000, 3),
+('Business Training', 'Pricing, marketing and running a makeup business', 45000, 4)
+ON CONFLICT (name) DO NOTHING;
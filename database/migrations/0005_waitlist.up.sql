@@ -0,0 +1,32 @@
+-- Waitlist for full class cohorts and booked-out appointment days.
+-- Class entries point at the enrollment stored with status 'waitlisted';
+-- appointment entries carry the request and get appointment_id once a slot
+-- is booked for them.
+
+CREATE TABLE IF NOT EXISTS waitlist_entries (
+    id SERIAL PRIMARY KEY,
+    kind VARCHAR(20) NOT NULL CHECK (kind IN ('class', 'appointment')),
+    name VARCHAR(255) NOT NULL,
+    email VARCHAR(255) NOT NULL,
+    phone VARCHAR(50) NOT NULL,
+    cohort_id INTEGER REFERENCES class_cohorts(id) ON DELETE CASCADE,
+    class_id INTEGER REFERENCES classes(id) ON DELETE CASCADE,
+    service VARCHAR(100),
+    appointment_date DATE,
+    preferred_time VARCHAR(20),
+    artist_id INTEGER REFERENCES artists(id) ON DELETE SET NULL,
+    appointment_id INTEGER REFERENCES appointments(id) ON DELETE SET NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'waiting' CHECK (status IN ('waiting', 'offered', 'promoted', 'expired', 'cancelled')),
+    hold_expires_at TIMESTAMP WITH TIME ZONE,
+    token_hash VARCHAR(64) UNIQUE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    CHECK (kind <> 'class' OR (cohort_id IS NOT NULL AND class_id IS NOT NULL)),
+    CHECK (kind <> 'appointment' OR (service IS NOT NULL AND appointment_date IS NOT NULL))
+);
+
+CREATE INDEX idx_waitlist_cohort_status ON waitlist_entries(cohort_id, status, created_at);
+CREATE INDEX idx_waitlist_date_status ON waitlist_entries(appointment_date, status, created_at);
+CREATE INDEX idx_waitlist_status_hold ON waitlist_entries(status, hold_expires_at);
+
+CREATE TRIGGER update_waitlist_entries_updated_at BEFORE UPDATE ON waitlist_entries FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
@@ -0,0 +1,13 @@
+DROP TABLE IF EXISTS status_history;
+
+ALTER TABLE classes
+    DROP COLUMN IF EXISTS confirmed_at,
+    DROP COLUMN IF EXISTS cancelled_at,
+    DROP COLUMN IF EXISTS completed_at,
+    DROP COLUMN IF EXISTS cancellation_reason;
+
+ALTER TABLE appointments
+    DROP COLUMN IF EXISTS confirmed_at,
+    DROP COLUMN IF EXISTS cancelled_at,
+    DROP COLUMN IF EXISTS completed_at,
+    DROP COLUMN IF EXISTS cancellation_reason;
@@ -0,0 +1,28 @@
+-- Booking lifecycle: when each milestone was reached, why a booking was
+-- cancelled, and an audit trail of every status change.
+
+ALTER TABLE appointments
+    ADD COLUMN confirmed_at TIMESTAMP WITH TIME ZONE,
+    ADD COLUMN cancelled_at TIMESTAMP WITH TIME ZONE,
+    ADD COLUMN completed_at TIMESTAMP WITH TIME ZONE,
+    ADD COLUMN cancellation_reason TEXT;
+
+ALTER TABLE classes
+    ADD COLUMN confirmed_at TIMESTAMP WITH TIME ZONE,
+    ADD COLUMN cancelled_at TIMESTAMP WITH TIME ZONE,
+    ADD COLUMN completed_at TIMESTAMP WITH TIME ZONE,
+    ADD COLUMN cancellation_reason TEXT;
+
+-- admin_id is NULL for changes the system made, such as waitlist promotion.
+CREATE TABLE IF NOT EXISTS status_history (
+    id SERIAL PRIMARY KEY,
+    resource_type VARCHAR(20) NOT NULL CHECK (resource_type IN ('appointment', 'class')),
+    resource_id INTEGER NOT NULL,
+    from_status VARCHAR(20) NOT NULL,
+    to_status VARCHAR(20) NOT NULL,
+    reason TEXT,
+    admin_id UUID REFERENCES admin_users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX idx_status_history_resource ON status_history(resource_type, resource_id, created_at);
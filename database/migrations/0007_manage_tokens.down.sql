@@ -0,0 +1,2 @@
+ALTER TABLE classes DROP COLUMN IF EXISTS manage_token_hash;
+ALTER TABLE appointments DROP COLUMN IF EXISTS manage_token_hash;
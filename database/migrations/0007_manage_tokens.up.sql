@@ -0,0 +1,6 @@
+-- Customers manage their own bookings through a link carrying a random
+-- token. Only its SHA-256 hash is stored. Bookings made before this
+-- migration have no token and can only be managed by admins.
+
+ALTER TABLE appointments ADD COLUMN manage_token_hash VARCHAR(64) UNIQUE;
+ALTER TABLE classes ADD COLUMN manage_token_hash VARCHAR(64) UNIQUE;
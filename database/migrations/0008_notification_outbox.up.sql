@@ -0,0 +1,27 @@
+-- Outbox of customer and studio notifications. Rows are written alongside
+-- the booking and delivered by a background worker, which retries failed
+-- sends with backoff until max attempts, so a mail outage never fails a
+-- booking request.
+
+CREATE TABLE IF NOT EXISTS notification_outbox (
+    id SERIAL PRIMARY KEY,
+    channel VARCHAR(20) NOT NULL DEFAULT 'email' CHECK (channel IN ('email')),
+    event VARCHAR(50) NOT NULL,
+    resource_type VARCHAR(20) NOT NULL CHECK (resource_type IN ('appointment', 'class')),
+    resource_id INTEGER NOT NULL,
+    recipient VARCHAR(255) NOT NULL,
+    subject VARCHAR(255) NOT NULL,
+    body TEXT NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'sent', 'failed')),
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT,
+    next_attempt_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    sent_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX idx_notification_outbox_due ON notification_outbox(status, next_attempt_at);
+CREATE INDEX idx_notification_outbox_resource ON notification_outbox(resource_type, resource_id, created_at);
+
+CREATE TRIGGER update_notification_outbox_updated_at BEFORE UPDATE ON notification_outbox FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
@@ -0,0 +1,22 @@
+-- Background jobs, such as booking reminders, persisted so they survive
+-- restarts. The runner claims due jobs by pushing run_at forward for the
+-- length of a lease, so several instances can share the table.
+
+CREATE TABLE IF NOT EXISTS scheduled_jobs (
+    id SERIAL PRIMARY KEY,
+    kind VARCHAR(50) NOT NULL,
+    resource_type VARCHAR(20) NOT NULL CHECK (resource_type IN ('appointment', 'class')),
+    resource_id INTEGER NOT NULL,
+    payload JSONB NOT NULL DEFAULT '{}',
+    run_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'done', 'cancelled', 'failed')),
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX idx_scheduled_jobs_due ON scheduled_jobs(status, run_at);
+CREATE INDEX idx_scheduled_jobs_resource ON scheduled_jobs(resource_type, resource_id, status);
+
+CREATE TRIGGER update_scheduled_jobs_updated_at BEFORE UPDATE ON scheduled_jobs FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
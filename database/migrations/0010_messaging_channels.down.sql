@@ -0,0 +1,16 @@
+DROP TABLE IF EXISTS notification_preferences;
+
+DELETE FROM notification_outbox WHERE channel <> 'email';
+UPDATE notification_outbox SET status = 'sent' WHERE status = 'delivered';
+
+DROP INDEX IF EXISTS idx_notification_outbox_provider_message;
+ALTER TABLE notification_outbox DROP COLUMN IF EXISTS delivered_at;
+ALTER TABLE notification_outbox DROP COLUMN IF EXISTS provider_message_id;
+
+ALTER TABLE notification_outbox DROP CONSTRAINT IF EXISTS notification_outbox_status_check;
+ALTER TABLE notification_outbox ADD CONSTRAINT notification_outbox_status_check
+    CHECK (status IN ('pending', 'sent', 'failed'));
+
+ALTER TABLE notification_outbox DROP CONSTRAINT IF EXISTS notification_outbox_channel_check;
+ALTER TABLE notification_outbox ADD CONSTRAINT notification_outbox_channel_check
+    CHECK (channel IN ('email'));
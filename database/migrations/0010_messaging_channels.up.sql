@@ -0,0 +1,27 @@
+-- Text message channels next to email. SMS and WhatsApp messages go through
+-- the same outbox; the provider's message ID is kept so its delivery status
+-- callbacks can be matched to the row. Customers choose their channels in
+-- notification_preferences, keyed by email; without a row they get email.
+
+ALTER TABLE notification_outbox DROP CONSTRAINT IF EXISTS notification_outbox_channel_check;
+ALTER TABLE notification_outbox ADD CONSTRAINT notification_outbox_channel_check
+    CHECK (channel IN ('email', 'sms', 'whatsapp'));
+
+ALTER TABLE notification_outbox DROP CONSTRAINT IF EXISTS notification_outbox_status_check;
+ALTER TABLE notification_outbox ADD CONSTRAINT notification_outbox_status_check
+    CHECK (status IN ('pending', 'sent', 'delivered', 'failed'));
+
+ALTER TABLE notification_outbox ADD COLUMN provider_message_id VARCHAR(100);
+ALTER TABLE notification_outbox ADD COLUMN delivered_at TIMESTAMP WITH TIME ZONE;
+
+CREATE UNIQUE INDEX idx_notification_outbox_provider_message ON notification_outbox(provider_message_id);
+
+CREATE TABLE IF NOT EXISTS notification_preferences (
+    email VARCHAR(255) PRIMARY KEY,
+    channels TEXT[] NOT NULL DEFAULT '{email}'
+        CHECK (channels <@ ARRAY['email', 'sms', 'whatsapp']::TEXT[]),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE TRIGGER update_notification_preferences_updated_at BEFORE UPDATE ON notification_preferences FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
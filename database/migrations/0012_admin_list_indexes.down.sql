@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_classes_lower_email;
+DROP INDEX IF EXISTS idx_classes_created_at;
+DROP INDEX IF EXISTS idx_appointments_lower_email;
+DROP INDEX IF EXISTS idx_appointments_created_at;
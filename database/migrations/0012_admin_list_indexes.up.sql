@@ -0,0 +1,6 @@
+-- Indexes for the admin appointment and class lists, which are paged in
+-- created_at order and filtered by email regardless of case.
+CREATE INDEX idx_appointments_created_at ON appointments(created_at);
+CREATE INDEX idx_appointments_lower_email ON appointments(lower(email));
+CREATE INDEX idx_classes_created_at ON classes(created_at);
+CREATE INDEX idx_classes_lower_email ON classes(lower(email));
@@ -0,0 +1,3 @@
+ALTER TABLE admin_users
+    DROP COLUMN IF EXISTS artist_id,
+    DROP COLUMN IF EXISTS role;
@@ -0,0 +1,9 @@
+-- Admin roles. Admins that exist already could do everything, so they
+-- become owners; accounts made from now on are given a role explicitly.
+-- Artists are linked to the artist whose appointments they see.
+ALTER TABLE admin_users
+    ADD COLUMN role TEXT NOT NULL DEFAULT 'owner'
+        CHECK (role IN ('owner', 'manager', 'artist', 'receptionist', 'instructor')),
+    ADD COLUMN artist_id INTEGER REFERENCES artists(id) ON DELETE SET NULL;
+
+ALTER TABLE admin_users ALTER COLUMN role DROP DEFAULT;
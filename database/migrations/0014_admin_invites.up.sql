@@ -0,0 +1,21 @@
+-- Invite-only admin onboarding. An owner invites an email with a role; the
+-- invitee registers with the single-use token, which is stored hashed and
+-- expires. Accepting or revoking an invite closes it.
+
+CREATE TABLE IF NOT EXISTS admin_invites (
+    id SERIAL PRIMARY KEY,
+    email VARCHAR(255) NOT NULL,
+    role TEXT NOT NULL CHECK (role IN ('owner', 'manager', 'artist', 'receptionist', 'instructor')),
+    artist_id INTEGER REFERENCES artists(id) ON DELETE SET NULL,
+    token_hash VARCHAR(64) NOT NULL UNIQUE,
+    invited_by UUID REFERENCES admin_users(id) ON DELETE SET NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'accepted', 'revoked')),
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    accepted_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX idx_admin_invites_status ON admin_invites(status, expires_at);
+
+CREATE TRIGGER update_admin_invites_updated_at BEFORE UPDATE ON admin_invites FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
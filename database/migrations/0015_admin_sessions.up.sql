@@ -0,0 +1,31 @@
+-- Admin sessions. Each login starts a session kept alive by a rotating
+-- refresh token, stored hashed. The session remembers the ID (jti) of its
+-- current access token so that ending the session revokes it too.
+-- revoked_tokens lists access tokens refused before they expire.
+
+CREATE TABLE IF NOT EXISTS admin_sessions (
+    id UUID DEFAULT uuid_generate_v4() PRIMARY KEY,
+    admin_id UUID NOT NULL REFERENCES admin_users(id) ON DELETE CASCADE,
+    refresh_token_hash VARCHAR(64) NOT NULL UNIQUE,
+    access_jti VARCHAR(64) NOT NULL,
+    access_expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    user_agent TEXT,
+    ip_address VARCHAR(64),
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    last_used_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    revoked_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX idx_admin_sessions_admin ON admin_sessions(admin_id, expires_at);
+
+CREATE TRIGGER update_admin_sessions_updated_at BEFORE UPDATE ON admin_sessions FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+CREATE TABLE IF NOT EXISTS revoked_tokens (
+    jti VARCHAR(64) PRIMARY KEY,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX idx_revoked_tokens_expires ON revoked_tokens(expires_at);
@@ -0,0 +1,6 @@
+DELETE FROM notification_outbox WHERE resource_type = 'password_reset';
+ALTER TABLE notification_outbox DROP CONSTRAINT IF EXISTS notification_outbox_resource_type_check;
+ALTER TABLE notification_outbox ADD CONSTRAINT notification_outbox_resource_type_check
+    CHECK (resource_type IN ('appointment', 'class'));
+
+DROP TABLE IF EXISTS admin_password_resets;
@@ -0,0 +1,18 @@
+-- Admin password resets. A reset token is emailed to the admin, stored
+-- hashed, and works once before it expires. The email goes through the
+-- notification outbox, recorded against the reset.
+
+CREATE TABLE IF NOT EXISTS admin_password_resets (
+    id SERIAL PRIMARY KEY,
+    admin_id UUID NOT NULL REFERENCES admin_users(id) ON DELETE CASCADE,
+    token_hash VARCHAR(64) NOT NULL UNIQUE,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    used_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX idx_admin_password_resets_admin ON admin_password_resets(admin_id);
+
+ALTER TABLE notification_outbox DROP CONSTRAINT IF EXISTS notification_outbox_resource_type_check;
+ALTER TABLE notification_outbox ADD CONSTRAINT notification_outbox_resource_type_check
+    CHECK (resource_type IN ('appointment', 'class', 'password_reset'));
@@ -0,0 +1,9 @@
+DROP TABLE IF EXISTS admin_login_attempts;
+DROP TABLE IF EXISTS login_throttles;
+DROP TABLE IF EXISTS admin_security_settings;
+DROP TABLE IF EXISTS admin_recovery_codes;
+
+ALTER TABLE admin_users
+    DROP COLUMN IF EXISTS totp_last_step,
+    DROP COLUMN IF EXISTS two_factor_enabled,
+    DROP COLUMN IF EXISTS totp_secret;
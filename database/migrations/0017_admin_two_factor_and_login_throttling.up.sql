@@ -0,0 +1,52 @@
+-- Two-factor authentication for admins. An admin enrolls by saving a TOTP
+-- secret and confirming a code from it, which turns two_factor_enabled on
+-- and gives them one-time recovery codes, stored hashed. totp_last_step is
+-- the last accepted time step, so a code can't be used twice. Owners can
+-- require two-factor authentication for every admin.
+ALTER TABLE admin_users
+    ADD COLUMN totp_secret TEXT,
+    ADD COLUMN two_factor_enabled BOOLEAN NOT NULL DEFAULT FALSE,
+    ADD COLUMN totp_last_step BIGINT;
+
+CREATE TABLE IF NOT EXISTS admin_recovery_codes (
+    id SERIAL PRIMARY KEY,
+    admin_id UUID NOT NULL REFERENCES admin_users(id) ON DELETE CASCADE,
+    code_hash VARCHAR(64) NOT NULL,
+    used_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX idx_admin_recovery_codes_admin ON admin_recovery_codes(admin_id, code_hash);
+
+-- Studio-wide admin security settings, kept in a single row.
+CREATE TABLE IF NOT EXISTS admin_security_settings (
+    id BOOLEAN PRIMARY KEY DEFAULT TRUE CHECK (id),
+    require_two_factor BOOLEAN NOT NULL DEFAULT FALSE,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+INSERT INTO admin_security_settings (id) VALUES (TRUE) ON CONFLICT DO NOTHING;
+
+CREATE TRIGGER update_admin_security_settings_updated_at BEFORE UPDATE ON admin_security_settings FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+-- Login throttling. Failed logins are counted per email and per IP address
+-- under keys like "email:owner@mumuni.com" and "ip:203.0.113.7"; the count
+-- decides how long the next attempt has to wait and when to lock out.
+CREATE TABLE IF NOT EXISTS login_throttles (
+    key VARCHAR(320) PRIMARY KEY,
+    failures INTEGER NOT NULL DEFAULT 0,
+    last_failure_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+-- Every admin login attempt and how it went.
+CREATE TABLE IF NOT EXISTS admin_login_attempts (
+    id SERIAL PRIMARY KEY,
+    email VARCHAR(255) NOT NULL,
+    ip_address VARCHAR(64) NOT NULL,
+    user_agent TEXT,
+    result VARCHAR(30) NOT NULL CHECK (result IN ('success', 'invalid_credentials', 'blocked', 'two_factor_required', 'invalid_two_factor')),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX idx_admin_login_attempts_email ON admin_login_attempts(lower(email), created_at);
+CREATE INDEX idx_admin_login_attempts_ip ON admin_login_attempts(ip_address, created_at);
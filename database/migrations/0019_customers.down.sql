@@ -0,0 +1,4 @@
+ALTER TABLE classes DROP COLUMN IF EXISTS customer_id;
+ALTER TABLE appointments DROP COLUMN IF EXISTS customer_id;
+
+DROP TABLE IF EXISTS customers;
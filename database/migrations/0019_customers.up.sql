@@ -0,0 +1,38 @@
+-- Customer profiles. Every appointment and class enrollment belongs to a
+-- customer, matched on booking by email (lowercased) and created when none
+-- matches.
+CREATE TABLE IF NOT EXISTS customers (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    email VARCHAR(255) UNIQUE NOT NULL,
+    phone VARCHAR(50) NOT NULL DEFAULT '',
+    notes TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX idx_customers_phone ON customers(phone) WHERE phone <> '';
+CREATE INDEX idx_customers_name ON customers(lower(name));
+
+CREATE TRIGGER update_customers_updated_at BEFORE UPDATE ON customers FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+ALTER TABLE appointments ADD COLUMN customer_id INTEGER REFERENCES customers(id);
+ALTER TABLE classes ADD COLUMN customer_id INTEGER REFERENCES customers(id);
+
+-- One customer per email among existing bookings, named as in their first
+-- booking
+INSERT INTO customers (name, email, phone, created_at)
+SELECT DISTINCT ON (lower(trim(email))) name, lower(trim(email)), regexp_replace(phone, '[^0-9+]', '', 'g'), created_at
+FROM (
+    SELECT name, email, phone, created_at FROM appointments
+    UNION ALL
+    SELECT name, email, phone, created_at FROM classes
+) bookings
+ORDER BY lower(trim(email)), created_at
+ON CONFLICT (email) DO NOTHING;
+
+UPDATE appointments a SET customer_id = c.id FROM customers c WHERE c.email = lower(trim(a.email));
+UPDATE classes cl SET customer_id = c.id FROM customers c WHERE c.email = lower(trim(cl.email));
+
+CREATE INDEX idx_appointments_customer ON appointments(customer_id);
+CREATE INDEX idx_classes_customer ON classes(customer_id);
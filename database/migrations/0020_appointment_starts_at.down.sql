@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_appointments_starts_at;
+ALTER TABLE appointments DROP COLUMN IF EXISTS starts_at;
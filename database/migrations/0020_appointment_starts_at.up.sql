@@ -0,0 +1,8 @@
+-- The moment an appointment starts, worked out from its date and clock time
+-- in the studio's time zone (STUDIO_TIME_ZONE) when it is booked. Existing
+-- appointments are filled in by "migrate up" after the migrations run, since
+-- only the server knows the configured zone.
+
+ALTER TABLE appointments ADD COLUMN starts_at TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX idx_appointments_starts_at ON appointments(starts_at);
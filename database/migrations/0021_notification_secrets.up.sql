@@ -0,0 +1,11 @@
+-- Secrets in messages, like the manage token in a manage link or a password
+-- reset token, are kept here sealed with a key derived from JWT_SECRET. The
+-- body holds a placeholder the secret is filled into as the message is sent,
+-- and the secret is cleared once it is sent or given up on.
+ALTER TABLE notification_outbox ADD COLUMN secret TEXT NOT NULL DEFAULT '';
+
+-- Messages sent before carried those tokens in the clear
+UPDATE notification_outbox SET body = ''
+WHERE status <> 'pending'
+  AND (resource_type = 'password_reset'
+       OR event IN ('appointment.received', 'appointment.promoted', 'enrollment.received', 'enrollment.waitlisted'));
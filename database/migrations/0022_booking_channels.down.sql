@@ -0,0 +1,2 @@
+ALTER TABLE classes DROP COLUMN IF EXISTS channels;
+ALTER TABLE appointments DROP COLUMN IF EXISTS channels;
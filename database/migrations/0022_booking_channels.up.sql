@@ -0,0 +1,5 @@
+-- Channels chosen on the booking form apply to that booking only, rather
+-- than replacing the customer's stored preference. Empty means the
+-- customer's preference, or email.
+ALTER TABLE appointments ADD COLUMN channels TEXT[] NOT NULL DEFAULT '{}';
+ALTER TABLE classes ADD COLUMN channels TEXT[] NOT NULL DEFAULT '{}';
@@ -0,0 +1,4 @@
+DELETE FROM admin_login_attempts WHERE result IN ('invalid_current_password', 'password_reset_requested');
+ALTER TABLE admin_login_attempts DROP CONSTRAINT IF EXISTS admin_login_attempts_result_check;
+ALTER TABLE admin_login_attempts ADD CONSTRAINT admin_login_attempts_result_check
+    CHECK (result IN ('success', 'invalid_credentials', 'blocked', 'two_factor_required', 'invalid_two_factor'));
@@ -0,0 +1,6 @@
+-- Wrong current passwords and password reset requests are logged with the
+-- login attempts they are throttled with.
+ALTER TABLE admin_login_attempts DROP CONSTRAINT IF EXISTS admin_login_attempts_result_check;
+ALTER TABLE admin_login_attempts ADD CONSTRAINT admin_login_attempts_result_check
+    CHECK (result IN ('success', 'invalid_credentials', 'blocked', 'two_factor_required', 'invalid_two_factor',
+                      'invalid_current_password', 'password_reset_requested'));
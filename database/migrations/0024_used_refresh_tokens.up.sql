@@ -0,0 +1,10 @@
+-- Refresh tokens a session has already swapped. One coming back means it
+-- was copied, so the session it belonged to is ended.
+
+CREATE TABLE IF NOT EXISTS used_refresh_tokens (
+    token_hash VARCHAR(64) PRIMARY KEY,
+    session_id UUID NOT NULL REFERENCES admin_sessions(id) ON DELETE CASCADE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX idx_used_refresh_tokens_session ON used_refresh_tokens(session_id);
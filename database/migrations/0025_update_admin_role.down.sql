@@ -0,0 +1 @@
+DROP FUNCTION IF EXISTS update_admin_role(UUID, VARCHAR, INTEGER);
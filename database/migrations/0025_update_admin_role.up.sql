@@ -0,0 +1,27 @@
+-- update_admin_role sets an admin's role and linked artist in one
+-- transaction, for the Supabase backend, whose PostgREST requests can't
+-- hold one. Every owner is locked while they are counted, so two owners
+-- demoting each other at once can't leave the studio without one.
+
+CREATE OR REPLACE FUNCTION update_admin_role(target_id UUID, new_role VARCHAR, new_artist_id INTEGER)
+RETURNS TEXT AS $$
+DECLARE
+    owners INTEGER;
+    old_role VARCHAR;
+BEGIN
+    SELECT COUNT(*) INTO owners FROM (
+        SELECT id FROM admin_users WHERE role = 'owner' ORDER BY id FOR UPDATE
+    ) locked;
+
+    SELECT role INTO old_role FROM admin_users WHERE id = target_id FOR UPDATE;
+    IF NOT FOUND THEN
+        RAISE EXCEPTION 'admin not found' USING ERRCODE = 'no_data_found';
+    END IF;
+    IF old_role = 'owner' AND new_role <> 'owner' AND owners <= 1 THEN
+        RAISE EXCEPTION 'the last owner can''t be given another role' USING ERRCODE = 'restrict_violation';
+    END IF;
+
+    UPDATE admin_users SET role = new_role, artist_id = new_artist_id WHERE id = target_id;
+    RETURN target_id::text;
+END;
+$$ LANGUAGE plpgsql;
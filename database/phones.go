@@ -0,0 +1,117 @@
+package database
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// phoneColumns are the columns holding phone numbers, all in tables keyed by
+// id. Text messages still waiting in the outbox are included so they reach
+// the normalized number.
+var phoneColumns = []struct {
+	table, column, where string
+}{
+	{table: "appointments", column: "phone"},
+	{table: "classes", column: "phone"},
+	{table: "waitlist_entries", column: "phone"},
+	{table: "artists", column: "phone"},
+	{table: "customers", column: "phone"},
+	{table: "notification_outbox", column: "recipient", where: "channel <> 'email' AND status = 'pending'"},
+}
+
+// PhoneBackfill reports what NormalizePhones did to one table.
+type PhoneBackfill struct {
+	Table   string
+	Checked int
+	Updated int
+	// Invalid are the IDs of rows whose number couldn't be normalized. They
+	// are left as they were.
+	Invalid []int
+}
+
+// NormalizePhones rewrites the phone numbers stored at databaseURL in the
+// form normalize returns, one table per transaction. With dryRun nothing is
+// written and the report says what would change.
+func NormalizePhones(ctx context.Context, databaseURL string, normalize func(string) (string, error), dryRun bool) ([]PhoneBackfill, error) {
+	if databaseURL == "" {
+		return nil, fmt.Errorf("DATABASE_URL is required to normalize phone numbers")
+	}
+
+	conn, err := pgx.Connect(ctx, databaseURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to postgres: %w", err)
+	}
+	defer conn.Close(ctx)
+
+	var reports []PhoneBackfill
+	for _, col := range phoneColumns {
+		report := PhoneBackfill{Table: col.table}
+		err := pgx.BeginFunc(ctx, conn, func(tx pgx.Tx) error {
+			where := col.column + " IS NOT NULL AND " + col.column + " <> ''"
+			if col.where != "" {
+				where += " AND " + col.where
+			}
+			rows, err := tx.Query(ctx, `SELECT id, `+col.column+` FROM `+col.table+` WHERE `+where+` ORDER BY id FOR UPDATE`)
+			if err != nil {
+				return err
+			}
+			var stored []phoneRow
+			for rows.Next() {
+				var row phoneRow
+				if err := rows.Scan(&row.id, &row.number); err != nil {
+					rows.Close()
+					return err
+				}
+				stored = append(stored, row)
+			}
+			rows.Close()
+			if err := rows.Err(); err != nil {
+				return err
+			}
+
+			changed := planPhoneBackfill(stored, normalize, &report)
+			if dryRun {
+				return nil
+			}
+			for id, normalized := range changed {
+				if _, err := tx.Exec(ctx, `UPDATE `+col.table+` SET `+col.column+` = $2 WHERE id = $1`, id, normalized); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return reports, fmt.Errorf("failed to normalize %s.%s: %w", col.table, col.column, err)
+		}
+		reports = append(reports, report)
+	}
+
+	return reports, nil
+}
+
+// phoneRow is a stored phone number and the id of its row.
+type phoneRow struct {
+	id     int
+	number string
+}
+
+// planPhoneBackfill returns the new numbers of the rows normalize changes,
+// by id, counting them in report along with the rows checked and those it
+// can't normalize.
+func planPhoneBackfill(rows []phoneRow, normalize func(string) (string, error), report *PhoneBackfill) map[int]string {
+	changed := map[int]string{}
+	for _, row := range rows {
+		report.Checked++
+		normalized, err := normalize(row.number)
+		switch {
+		case err != nil:
+			report.Invalid = append(report.Invalid, row.id)
+		case normalized != row.number:
+			changed[row.id] = normalized
+		}
+	}
+	report.Updated = len(changed)
+	return changed
+}
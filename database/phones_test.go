@@ -0,0 +1,39 @@
+package database
+
+import (
+	"reflect"
+	"testing"
+
+	"mumuni_backend/phone"
+)
+
+func TestPlanPhoneBackfill(t *testing.T) {
+	p, err := phone.NewParser("NG")
+	if err != nil {
+		t.Fatalf("NewParser: %v", err)
+	}
+	rows := []phoneRow{
+		{1, "0803 111 2222"},
+		{2, "+2348031112222"},
+		{3, "+44 20 7946 0958"},
+		{4, "call me"},
+		{5, "002348031113333"},
+		{6, "0803 111"},
+	}
+
+	report := PhoneBackfill{Table: "appointments"}
+	changed := planPhoneBackfill(rows, p.Normalize, &report)
+
+	// Numbers already in E.164 are left alone, as are invalid ones
+	want := map[int]string{
+		1: "+2348031112222",
+		3: "+442079460958",
+		5: "+2348031113333",
+	}
+	if !reflect.DeepEqual(changed, want) {
+		t.Errorf("changed %v, want %v", changed, want)
+	}
+	if report.Checked != 6 || report.Updated != 3 || !reflect.DeepEqual(report.Invalid, []int{4, 6}) {
+		t.Errorf("report %+v, want 6 checked, 3 updated and 4 and 6 invalid", report)
+	}
+}
@@ -0,0 +1,2845 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mumuni_backend/auth"
+	"mumuni_backend/config"
+	"mumuni_backend/lifecycle"
+	"mumuni_backend/models"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// PostgresStore is the Store that talks to Postgres directly over a pooled
+// pgx connection, using the DATABASE_URL (postgresql://) connection string.
+type PostgresStore struct {
+	pool *pgxpool.Pool
+}
+
+func NewPostgresStore(ctx context.Context, cfg *config.Config) (*PostgresStore, error) {
+	if cfg.DatabaseURL == "" {
+		return nil, fmt.Errorf("DATABASE_URL is required")
+	}
+
+	poolConfig, err := pgxpool.ParseConfig(cfg.DatabaseURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse DATABASE_URL: %w", err)
+	}
+	if cfg.DatabaseMaxConns > 0 {
+		poolConfig.MaxConns = int32(cfg.DatabaseMaxConns)
+	}
+
+	pool, err := pgxpool.NewWithConfig(ctx, poolConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create connection pool: %w", err)
+	}
+
+	if err := pool.Ping(ctx); err != nil {
+		pool.Close()
+		return nil, fmt.Errorf("failed to connect to postgres: %w", err)
+	}
+
+	fmt.Printf("Connected to Postgres at %s:%d (max %d connections)\n",
+		poolConfig.ConnConfig.Host, poolConfig.ConnConfig.Port, poolConfig.MaxConns)
+
+	return &PostgresStore{pool: pool}, nil
+}
+
+// Close releases every pooled connection.
+func (s *PostgresStore) Close() {
+	s.pool.Close()
+}
+
+// withTx runs fn inside a transaction, committing when it returns nil and
+// rolling back otherwise.
+func (s *PostgresStore) withTx(ctx context.Context, fn func(tx pgx.Tx) error) error {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+const appointmentColumns = `id, name, email, phone, appointment_date::text, appointment_time, starts_at,
+	service, message, artist_id, customer_id, channels, status, confirmed_at, cancelled_at, completed_at,
+	cancellation_reason, created_at, updated_at`
+
+func scanAppointment(row pgx.Row) (*models.Appointment, error) {
+	var a models.Appointment
+	err := row.Scan(&a.ID, &a.Name, &a.Email, &a.Phone, &a.AppointmentDate, &a.AppointmentTime, &a.StartsAt,
+		&a.Service, &a.Message, &a.ArtistID, &a.CustomerID, &a.Channels, &a.Status, &a.ConfirmedAt, &a.CancelledAt, &a.CompletedAt,
+		&a.CancellationReason, &a.CreatedAt, &a.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	// pgx reads timestamps in the server's local zone; starts_at is
+	// documented as UTC
+	if a.StartsAt != nil {
+		start := a.StartsAt.UTC()
+		a.StartsAt = &start
+	}
+	return &a, nil
+}
+
+const serviceColumns = `id, name, description, duration_minutes, buffer_minutes, price::float8,
+	deposit_amount::float8, active, display_order, created_at, updated_at`
+
+func scanService(row pgx.Row) (*models.Service, error) {
+	var s models.Service
+	err := row.Scan(&s.ID, &s.Name, &s.Description, &s.DurationMinutes, &s.BufferMinutes, &s.Price,
+		&s.DepositAmount, &s.Active, &s.DisplayOrder, &s.CreatedAt, &s.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+const artistColumns = `id, name, email, phone, skills, working_hours, active, created_at, updated_at`
+
+func scanArtist(row pgx.Row) (*models.Artist, error) {
+	var a models.Artist
+	err := row.Scan(&a.ID, &a.Name, &a.Email, &a.Phone, &a.Skills, &a.WorkingHours, &a.Active,
+		&a.CreatedAt, &a.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+const offeringColumns = `id, name, description, price::float8, active, display_order, created_at, updated_at`
+
+func scanOffering(row pgx.Row) (*models.ClassOffering, error) {
+	var o models.ClassOffering
+	err := row.Scan(&o.ID, &o.Name, &o.Description, &o.Price, &o.Active, &o.DisplayOrder,
+		&o.CreatedAt, &o.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &o, nil
+}
+
+// cohortColumns selects from a class_cohorts row aliased as c, counting the
+// pending and confirmed enrollments that hold a seat.
+const cohortColumns = `c.id, c.offering_id, c.name, c.start_date::text, c.end_date::text, c.meeting_times,
+	c.instructor, c.capacity,
+	(SELECT COUNT(*) FROM classes e WHERE e.cohort_id = c.id AND e.status IN ('pending', 'confirmed'))::int,
+	c.active, c.created_at, c.updated_at`
+
+func scanCohort(row pgx.Row) (*models.Cohort, error) {
+	var c models.Cohort
+	var taken int
+	err := row.Scan(&c.ID, &c.OfferingID, &c.Name, &c.StartDate, &c.EndDate, &c.MeetingTimes,
+		&c.Instructor, &c.Capacity, &taken, &c.Active, &c.CreatedAt, &c.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	c.SetSeatsTaken(taken)
+	return &c, nil
+}
+
+const classColumns = `id, name, email, phone, class_type, experience_level, goals,
+	preferred_schedule, cohort_id, customer_id, channels, status, confirmed_at, cancelled_at, completed_at,
+	cancellation_reason, created_at, updated_at`
+
+func scanClass(row pgx.Row) (*models.Class, error) {
+	var c models.Class
+	err := row.Scan(&c.ID, &c.Name, &c.Email, &c.Phone, &c.ClassType, &c.ExperienceLevel, &c.Goals,
+		&c.PreferredSchedule, &c.CohortID, &c.CustomerID, &c.Channels, &c.Status, &c.ConfirmedAt, &c.CancelledAt, &c.CompletedAt,
+		&c.CancellationReason, &c.CreatedAt, &c.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+const waitlistColumns = `id, kind, name, email, phone, cohort_id, class_id, service,
+	appointment_date::text, preferred_time, artist_id, appointment_id, status, hold_expires_at,
+	token_hash, created_at, updated_at`
+
+func scanWaitlistEntry(row pgx.Row) (*models.WaitlistEntry, error) {
+	var e models.WaitlistEntry
+	err := row.Scan(&e.ID, &e.Kind, &e.Name, &e.Email, &e.Phone, &e.CohortID, &e.ClassID, &e.Service,
+		&e.AppointmentDate, &e.PreferredTime, &e.ArtistID, &e.AppointmentID, &e.Status, &e.HoldExpiresAt,
+		&e.TokenHash, &e.CreatedAt, &e.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+const notificationColumns = `id, channel, event, resource_type, resource_id, recipient, subject, body, secret,
+	status, attempts, last_error, next_attempt_at, sent_at, provider_message_id, delivered_at,
+	created_at, updated_at`
+
+func scanNotification(row pgx.Row) (*models.Notification, error) {
+	var n models.Notification
+	err := row.Scan(&n.ID, &n.Channel, &n.Event, &n.ResourceType, &n.ResourceID, &n.Recipient, &n.Subject, &n.Body, &n.Secret,
+		&n.Status, &n.Attempts, &n.LastError, &n.NextAttemptAt, &n.SentAt, &n.ProviderMessageID, &n.DeliveredAt,
+		&n.CreatedAt, &n.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &n, nil
+}
+
+// queryNotifications runs a query returning notificationColumns.
+func queryNotifications(ctx context.Context, pool *pgxpool.Pool, sql string, args ...any) ([]models.Notification, error) {
+	rows, err := pool.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	notifications := []models.Notification{}
+	for rows.Next() {
+		notification, err := scanNotification(rows)
+		if err != nil {
+			return nil, err
+		}
+		notifications = append(notifications, *notification)
+	}
+	return notifications, rows.Err()
+}
+
+const preferenceColumns = `email, channels, created_at, updated_at`
+
+func scanPreference(row pgx.Row) (*models.NotificationPreference, error) {
+	var p models.NotificationPreference
+	if err := row.Scan(&p.Email, &p.Channels, &p.CreatedAt, &p.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+const jobColumns = `id, kind, resource_type, resource_id, payload, run_at, status, attempts, last_error,
+	created_at, updated_at`
+
+func scanJob(row pgx.Row) (*models.Job, error) {
+	var j models.Job
+	err := row.Scan(&j.ID, &j.Kind, &j.ResourceType, &j.ResourceID, &j.Payload, &j.RunAt, &j.Status, &j.Attempts, &j.LastError,
+		&j.CreatedAt, &j.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &j, nil
+}
+
+// queryJobs runs a query returning jobColumns.
+func queryJobs(ctx context.Context, pool *pgxpool.Pool, sql string, args ...any) ([]models.Job, error) {
+	rows, err := pool.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	jobs := []models.Job{}
+	for rows.Next() {
+		job, err := scanJob(rows)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, *job)
+	}
+	return jobs, rows.Err()
+}
+
+const webhookColumns = `id, url, events, description, active, created_at, updated_at`
+
+func scanWebhook(row pgx.Row) (*models.WebhookSubscription, error) {
+	var w models.WebhookSubscription
+	if err := row.Scan(&w.ID, &w.URL, &w.Events, &w.Description, &w.Active, &w.CreatedAt, &w.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return &w, nil
+}
+
+const deliveryColumns = `id, subscription_id, event, payload, status, attempts, last_error, response_status,
+	next_attempt_at, delivered_at, created_at, updated_at`
+
+func scanDelivery(row pgx.Row) (*models.WebhookDelivery, error) {
+	var d models.WebhookDelivery
+	err := row.Scan(&d.ID, &d.SubscriptionID, &d.Event, &d.Payload, &d.Status, &d.Attempts, &d.LastError, &d.ResponseStatus,
+		&d.NextAttemptAt, &d.DeliveredAt, &d.CreatedAt, &d.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+// queryDeliveries runs a query returning deliveryColumns.
+func queryDeliveries(ctx context.Context, pool *pgxpool.Pool, sql string, args ...any) ([]models.WebhookDelivery, error) {
+	rows, err := pool.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := []models.WebhookDelivery{}
+	for rows.Next() {
+		delivery, err := scanDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, *delivery)
+	}
+	return deliveries, rows.Err()
+}
+
+const adminColumns = `id::text, email, name, role, artist_id, two_factor_enabled, created_at, updated_at`
+
+func scanAdmin(row pgx.Row) (*models.AdminUser, error) {
+	var a models.AdminUser
+	if err := row.Scan(&a.ID, &a.Email, &a.Name, &a.Role, &a.ArtistID, &a.TwoFactorEnabled, &a.CreatedAt, &a.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+// Appointment methods
+func (s *PostgresStore) CreateAppointment(ctx context.Context, req *models.AppointmentRequest, manageTokenHash string, check BookingCheck) (*models.Appointment, error) {
+	var appointment *models.Appointment
+	err := s.withTx(ctx, func(tx pgx.Tx) error {
+		if check != nil {
+			if err := checkAppointmentDate(ctx, tx, req.Date, 0, check); err != nil {
+				return err
+			}
+		}
+
+		customerID, err := matchCustomer(ctx, tx, req.Name, req.Email, req.Phone)
+		if err != nil {
+			return err
+		}
+
+		appointment, err = scanAppointment(tx.QueryRow(ctx, `
+			INSERT INTO appointments (name, email, phone, appointment_date, appointment_time, starts_at, service, message, artist_id, customer_id, channels, status, manage_token_hash)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, 'pending', $12)
+			RETURNING `+appointmentColumns,
+			req.Name, req.Email, req.Phone, req.Date, req.Time, appointmentStart(req), req.Service, req.Message, req.ArtistID, customerID,
+			bookingChannels(req.Channels), manageTokenHash))
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create appointment: %w", err)
+	}
+
+	return appointment, nil
+}
+
+// checkAppointmentDate runs check against the active appointments on date,
+// other than excludeID, holding a lock that serializes bookings for the
+// same date until tx ends.
+func checkAppointmentDate(ctx context.Context, tx pgx.Tx, date string, excludeID int, check BookingCheck) error {
+	if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock(hashtext('appointments:' || $1))`, date); err != nil {
+		return err
+	}
+	existing, err := queryAppointments(ctx, tx, `
+		SELECT `+appointmentColumns+` FROM appointments
+		WHERE appointment_date = $1 AND status = ANY($2) AND id <> $3`, date, activeStatuses, excludeID)
+	if err != nil {
+		return err
+	}
+	return check(existing)
+}
+
+func (s *PostgresStore) GetAppointment(ctx context.Context, appointmentID int) (*models.Appointment, error) {
+	appointment, err := scanAppointment(s.pool.QueryRow(ctx,
+		`SELECT `+appointmentColumns+` FROM appointments WHERE id = $1`, appointmentID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrAppointmentNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get appointment: %w", err)
+	}
+
+	return appointment, nil
+}
+
+func (s *PostgresStore) GetAppointmentByManageToken(ctx context.Context, tokenHash string) (*models.Appointment, error) {
+	appointment, err := scanAppointment(s.pool.QueryRow(ctx,
+		`SELECT `+appointmentColumns+` FROM appointments WHERE manage_token_hash = $1`, tokenHash))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrAppointmentNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get appointment: %w", err)
+	}
+
+	return appointment, nil
+}
+
+func (s *PostgresStore) RescheduleAppointment(ctx context.Context, appointmentID int, req *models.AppointmentRequest, check BookingCheck) (*models.Appointment, error) {
+	var appointment *models.Appointment
+	err := s.withTx(ctx, func(tx pgx.Tx) error {
+		var status string
+		err := tx.QueryRow(ctx, `SELECT status FROM appointments WHERE id = $1 FOR UPDATE`, appointmentID).Scan(&status)
+		if err != nil {
+			return err
+		}
+		if !isActiveStatus(status) {
+			return ErrBookingClosed
+		}
+
+		if check != nil {
+			if err := checkAppointmentDate(ctx, tx, req.Date, appointmentID, check); err != nil {
+				return err
+			}
+		}
+
+		appointment, err = scanAppointment(tx.QueryRow(ctx, `
+			UPDATE appointments SET appointment_date = $2, appointment_time = $3, starts_at = $4, artist_id = $5
+			WHERE id = $1
+			RETURNING `+appointmentColumns,
+			appointmentID, req.Date, req.Time, appointmentStart(req), req.ArtistID))
+		return err
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrAppointmentNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to reschedule appointment: %w", err)
+	}
+
+	return appointment, nil
+}
+
+func (s *PostgresStore) UpdateAppointmentChannels(ctx context.Context, appointmentID int, channels []string) (*models.Appointment, error) {
+	appointment, err := scanAppointment(s.pool.QueryRow(ctx, `
+		UPDATE appointments SET channels = $2 WHERE id = $1
+		RETURNING `+appointmentColumns, appointmentID, bookingChannels(channels)))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrAppointmentNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to update appointment channels: %w", err)
+	}
+
+	return appointment, nil
+}
+
+func (s *PostgresStore) GetAppointments(ctx context.Context, filter AppointmentFilter, page Page) ([]models.Appointment, int, error) {
+	where := "TRUE"
+	var args []any
+	if filter.ArtistID != nil {
+		args = append(args, *filter.ArtistID)
+		where += fmt.Sprintf(" AND artist_id = $%d", len(args))
+	}
+	if filter.CustomerID != nil {
+		args = append(args, *filter.CustomerID)
+		where += fmt.Sprintf(" AND customer_id = $%d", len(args))
+	}
+	if filter.Date != "" {
+		args = append(args, filter.Date)
+		where += fmt.Sprintf(" AND appointment_date = $%d", len(args))
+	}
+	if filter.DateFrom != "" {
+		args = append(args, filter.DateFrom)
+		where += fmt.Sprintf(" AND appointment_date >= $%d", len(args))
+	}
+	if filter.DateTo != "" {
+		args = append(args, filter.DateTo)
+		where += fmt.Sprintf(" AND appointment_date <= $%d", len(args))
+	}
+	if filter.Status != "" {
+		args = append(args, filter.Status)
+		where += fmt.Sprintf(" AND status = $%d", len(args))
+	}
+	if filter.Service != "" {
+		args = append(args, filter.Service)
+		where += fmt.Sprintf(" AND service = $%d", len(args))
+	}
+	if filter.Email != "" {
+		args = append(args, filter.Email)
+		where += fmt.Sprintf(" AND lower(email) = lower($%d)", len(args))
+	}
+	if filter.Phone != "" {
+		args = append(args, filter.Phone)
+		where += fmt.Sprintf(" AND phone = $%d", len(args))
+	}
+	if filter.Search != "" {
+		args = append(args, "%"+escapeLike(filter.Search)+"%")
+		where += fmt.Sprintf(" AND (name ILIKE $%d OR email ILIKE $%[1]d OR message ILIKE $%[1]d)", len(args))
+	}
+
+	var total int
+	err := s.pool.QueryRow(ctx, `SELECT COUNT(*) FROM appointments WHERE `+where, args...).Scan(&total)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count appointments: %w", err)
+	}
+
+	appointments, err := queryAppointments(ctx, s.pool,
+		`SELECT `+appointmentColumns+` FROM appointments WHERE `+where+pageClause(page, AppointmentSorts, &args), args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get appointments: %w", err)
+	}
+
+	return appointments, total, nil
+}
+
+func (s *PostgresStore) GetActiveAppointments(ctx context.Context, fromDate, toDate string) ([]models.Appointment, error) {
+	appointments, err := queryAppointments(ctx, s.pool, `
+		SELECT `+appointmentColumns+` FROM appointments
+		WHERE appointment_date BETWEEN $1 AND $2 AND status = ANY($3)
+		ORDER BY appointment_date, appointment_time`, fromDate, toDate, activeStatuses)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get active appointments: %w", err)
+	}
+
+	return appointments, nil
+}
+
+// pageClause returns the ORDER BY, LIMIT and OFFSET for page, appending its
+// parameters to args.
+func pageClause(page Page, sorts []string, args *[]any) string {
+	direction := "ASC"
+	if page.Descending {
+		direction = "DESC"
+	}
+	clause := fmt.Sprintf(" ORDER BY %s %s, id %[2]s", sortColumn(page, sorts), direction)
+	if page.Limit > 0 {
+		*args = append(*args, page.Limit)
+		clause += fmt.Sprintf(" LIMIT $%d", len(*args))
+	}
+	*args = append(*args, page.Offset)
+	return clause + fmt.Sprintf(" OFFSET $%d", len(*args))
+}
+
+// querier is satisfied by both the pool and a transaction.
+type querier interface {
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+}
+
+func queryAppointments(ctx context.Context, q querier, sql string, args ...any) ([]models.Appointment, error) {
+	rows, err := q.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	appointments := []models.Appointment{}
+	for rows.Next() {
+		appointment, err := scanAppointment(rows)
+		if err != nil {
+			return nil, err
+		}
+		appointments = append(appointments, *appointment)
+	}
+
+	return appointments, rows.Err()
+}
+
+func (s *PostgresStore) UpdateAppointmentStatus(ctx context.Context, appointmentID int, change StatusChange) (*models.Appointment, error) {
+	if err := validateStatus(change.Status); err != nil {
+		return nil, err
+	}
+
+	var appointment *models.Appointment
+	err := s.withTx(ctx, func(tx pgx.Tx) error {
+		if err := changeStatus(ctx, tx, "appointments", "appointment", appointmentID, change); err != nil {
+			return err
+		}
+		var err error
+		appointment, err = scanAppointment(tx.QueryRow(ctx,
+			`SELECT `+appointmentColumns+` FROM appointments WHERE id = $1`, appointmentID))
+		return err
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrAppointmentNotFound
+	}
+	if err != nil {
+		return nil, statusChangeError(err, "failed to update appointment status")
+	}
+
+	return appointment, nil
+}
+
+// changeStatus moves a row of table to change.Status inside tx, checking the
+// lifecycle against the locked current status and recording the change in
+// status_history. It returns pgx.ErrNoRows when the row doesn't exist.
+func changeStatus(ctx context.Context, tx pgx.Tx, table, resourceType string, id int, change StatusChange) error {
+	var from string
+	err := tx.QueryRow(ctx, `SELECT status FROM `+table+` WHERE id = $1 FOR UPDATE`, id).Scan(&from)
+	if err != nil {
+		return err
+	}
+	if err := lifecycle.Check(from, change.Status, change.Reason); err != nil {
+		return err
+	}
+
+	// Reaching a milestone stamps its column; only cancelling keeps a reason
+	set := `status = $2,
+		cancellation_reason = CASE WHEN $2 = 'cancelled' THEN $3 ELSE cancellation_reason END`
+	if column := statusTimestampColumn(change.Status); column != "" {
+		set += `, ` + column + ` = NOW()`
+	}
+	reason := statusReason(change)
+	if _, err := tx.Exec(ctx, `UPDATE `+table+` SET `+set+` WHERE id = $1`, id, change.Status, reason); err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO status_history (resource_type, resource_id, from_status, to_status, reason, admin_id)
+		VALUES ($1, $2, $3, $4, $5, $6)`,
+		resourceType, id, from, change.Status, reason, change.AdminID)
+	return err
+}
+
+// statusChangeError passes lifecycle errors through unchanged so handlers can
+// tell them apart, and wraps anything else with message.
+func statusChangeError(err error, message string) error {
+	var transitionErr *lifecycle.TransitionError
+	if errors.As(err, &transitionErr) || errors.Is(err, lifecycle.ErrReasonRequired) {
+		return err
+	}
+	return fmt.Errorf("%s: %w", message, err)
+}
+
+// Service catalog methods
+func (s *PostgresStore) CreateService(ctx context.Context, req *models.ServiceRequest) (*models.Service, error) {
+	service, err := scanService(s.pool.QueryRow(ctx, `
+		INSERT INTO services (name, description, duration_minutes, buffer_minutes, price, deposit_amount, active, display_order)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING `+serviceColumns,
+		req.Name, req.Description, req.DurationMinutes, req.BufferMinutes, req.Price, req.DepositAmount,
+		serviceActive(req), req.DisplayOrder))
+	if isUniqueViolation(err) {
+		return nil, ErrServiceExists
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create service: %w", err)
+	}
+
+	return service, nil
+}
+
+func (s *PostgresStore) GetServices(ctx context.Context, activeOnly bool) ([]models.Service, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT `+serviceColumns+` FROM services
+		WHERE active OR NOT $1
+		ORDER BY display_order, name`, activeOnly)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get services: %w", err)
+	}
+	defer rows.Close()
+
+	services := []models.Service{}
+	for rows.Next() {
+		service, err := scanService(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get services: %w", err)
+		}
+		services = append(services, *service)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get services: %w", err)
+	}
+
+	return services, nil
+}
+
+func (s *PostgresStore) GetService(ctx context.Context, serviceID int) (*models.Service, error) {
+	service, err := scanService(s.pool.QueryRow(ctx, `SELECT `+serviceColumns+` FROM services WHERE id = $1`, serviceID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrServiceNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get service: %w", err)
+	}
+
+	return service, nil
+}
+
+func (s *PostgresStore) UpdateService(ctx context.Context, serviceID int, req *models.ServiceRequest) (*models.Service, error) {
+	service, err := scanService(s.pool.QueryRow(ctx, `
+		UPDATE services
+		SET name = $2, description = $3, duration_minutes = $4, buffer_minutes = $5, price = $6,
+			deposit_amount = $7, active = $8, display_order = $9
+		WHERE id = $1
+		RETURNING `+serviceColumns,
+		serviceID, req.Name, req.Description, req.DurationMinutes, req.BufferMinutes, req.Price,
+		req.DepositAmount, serviceActive(req), req.DisplayOrder))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrServiceNotFound
+	}
+	if isUniqueViolation(err) {
+		return nil, ErrServiceExists
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to update service: %w", err)
+	}
+
+	return service, nil
+}
+
+func (s *PostgresStore) DeleteService(ctx context.Context, serviceID int) error {
+	tag, err := s.pool.Exec(ctx, `DELETE FROM services WHERE id = $1`, serviceID)
+	if err != nil {
+		return fmt.Errorf("failed to delete service: %w", err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return ErrServiceNotFound
+	}
+
+	return nil
+}
+
+// Artist methods
+func (s *PostgresStore) CreateArtist(ctx context.Context, req *models.ArtistRequest) (*models.Artist, error) {
+	artist, err := scanArtist(s.pool.QueryRow(ctx, `
+		INSERT INTO artists (name, email, phone, skills, working_hours, active)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING `+artistColumns,
+		req.Name, req.Email, req.Phone, req.Skills, req.WorkingHours, artistActive(req)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create artist: %w", err)
+	}
+
+	return artist, nil
+}
+
+func (s *PostgresStore) GetArtists(ctx context.Context, activeOnly bool) ([]models.Artist, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT `+artistColumns+` FROM artists
+		WHERE active OR NOT $1
+		ORDER BY name`, activeOnly)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get artists: %w", err)
+	}
+	defer rows.Close()
+
+	artists := []models.Artist{}
+	for rows.Next() {
+		artist, err := scanArtist(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get artists: %w", err)
+		}
+		artists = append(artists, *artist)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get artists: %w", err)
+	}
+
+	return artists, nil
+}
+
+func (s *PostgresStore) GetArtist(ctx context.Context, artistID int) (*models.Artist, error) {
+	artist, err := scanArtist(s.pool.QueryRow(ctx, `SELECT `+artistColumns+` FROM artists WHERE id = $1`, artistID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrArtistNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get artist: %w", err)
+	}
+
+	return artist, nil
+}
+
+func (s *PostgresStore) UpdateArtist(ctx context.Context, artistID int, req *models.ArtistRequest) (*models.Artist, error) {
+	artist, err := scanArtist(s.pool.QueryRow(ctx, `
+		UPDATE artists
+		SET name = $2, email = $3, phone = $4, skills = $5, working_hours = $6, active = $7
+		WHERE id = $1
+		RETURNING `+artistColumns,
+		artistID, req.Name, req.Email, req.Phone, req.Skills, req.WorkingHours, artistActive(req)))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrArtistNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to update artist: %w", err)
+	}
+
+	return artist, nil
+}
+
+func (s *PostgresStore) DeleteArtist(ctx context.Context, artistID int) error {
+	tag, err := s.pool.Exec(ctx, `DELETE FROM artists WHERE id = $1`, artistID)
+	if err != nil {
+		return fmt.Errorf("failed to delete artist: %w", err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return ErrArtistNotFound
+	}
+
+	return nil
+}
+
+// Class catalog methods
+func (s *PostgresStore) CreateClassOffering(ctx context.Context, req *models.ClassOfferingRequest) (*models.ClassOffering, error) {
+	offering, err := scanOffering(s.pool.QueryRow(ctx, `
+		INSERT INTO class_offerings (name, description, price, active, display_order)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING `+offeringColumns,
+		req.Name, req.Description, req.Price, offeringActive(req), req.DisplayOrder))
+	if isUniqueViolation(err) {
+		return nil, ErrOfferingExists
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create class offering: %w", err)
+	}
+
+	return offering, nil
+}
+
+func (s *PostgresStore) GetClassOfferings(ctx context.Context, activeOnly bool) ([]models.ClassOffering, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT `+offeringColumns+` FROM class_offerings
+		WHERE active OR NOT $1
+		ORDER BY display_order, name`, activeOnly)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get class offerings: %w", err)
+	}
+	defer rows.Close()
+
+	offerings := []models.ClassOffering{}
+	for rows.Next() {
+		offering, err := scanOffering(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get class offerings: %w", err)
+		}
+		offerings = append(offerings, *offering)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get class offerings: %w", err)
+	}
+
+	return offerings, nil
+}
+
+func (s *PostgresStore) GetClassOffering(ctx context.Context, offeringID int) (*models.ClassOffering, error) {
+	offering, err := scanOffering(s.pool.QueryRow(ctx, `SELECT `+offeringColumns+` FROM class_offerings WHERE id = $1`, offeringID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrOfferingNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get class offering: %w", err)
+	}
+
+	return offering, nil
+}
+
+func (s *PostgresStore) UpdateClassOffering(ctx context.Context, offeringID int, req *models.ClassOfferingRequest) (*models.ClassOffering, error) {
+	offering, err := scanOffering(s.pool.QueryRow(ctx, `
+		UPDATE class_offerings
+		SET name = $2, description = $3, price = $4, active = $5, display_order = $6
+		WHERE id = $1
+		RETURNING `+offeringColumns,
+		offeringID, req.Name, req.Description, req.Price, offeringActive(req), req.DisplayOrder))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrOfferingNotFound
+	}
+	if isUniqueViolation(err) {
+		return nil, ErrOfferingExists
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to update class offering: %w", err)
+	}
+
+	return offering, nil
+}
+
+func (s *PostgresStore) DeleteClassOffering(ctx context.Context, offeringID int) error {
+	tag, err := s.pool.Exec(ctx, `DELETE FROM class_offerings WHERE id = $1`, offeringID)
+	if err != nil {
+		return fmt.Errorf("failed to delete class offering: %w", err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return ErrOfferingNotFound
+	}
+
+	return nil
+}
+
+// Cohort methods
+func (s *PostgresStore) CreateCohort(ctx context.Context, req *models.CohortRequest) (*models.Cohort, error) {
+	cohort, err := scanCohort(s.pool.QueryRow(ctx, `
+		WITH c AS (
+			INSERT INTO class_cohorts (offering_id, name, start_date, end_date, meeting_times, instructor, capacity, active)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+			RETURNING *
+		)
+		SELECT `+cohortColumns+` FROM c`,
+		req.OfferingID, req.Name, req.StartDate, req.EndDate, req.MeetingTimes, req.Instructor,
+		req.Capacity, cohortActive(req)))
+	if isForeignKeyViolation(err) {
+		return nil, ErrOfferingNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cohort: %w", err)
+	}
+
+	return cohort, nil
+}
+
+func (s *PostgresStore) GetCohorts(ctx context.Context, filter CohortFilter) ([]models.Cohort, error) {
+	where := "TRUE"
+	var args []any
+	if filter.OfferingID != nil {
+		args = append(args, *filter.OfferingID)
+		where += fmt.Sprintf(" AND c.offering_id = $%d", len(args))
+	}
+	if filter.ActiveOnly {
+		where += " AND c.active"
+	}
+	if filter.StartsFrom != "" {
+		args = append(args, filter.StartsFrom)
+		where += fmt.Sprintf(" AND c.start_date >= $%d", len(args))
+	}
+
+	rows, err := s.pool.Query(ctx, `
+		SELECT `+cohortColumns+` FROM class_cohorts c
+		WHERE `+where+`
+		ORDER BY c.start_date, c.id`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cohorts: %w", err)
+	}
+	defer rows.Close()
+
+	cohorts := []models.Cohort{}
+	for rows.Next() {
+		cohort, err := scanCohort(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get cohorts: %w", err)
+		}
+		cohorts = append(cohorts, *cohort)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get cohorts: %w", err)
+	}
+
+	return cohorts, nil
+}
+
+func (s *PostgresStore) GetCohort(ctx context.Context, cohortID int) (*models.Cohort, error) {
+	cohort, err := scanCohort(s.pool.QueryRow(ctx, `SELECT `+cohortColumns+` FROM class_cohorts c WHERE c.id = $1`, cohortID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrCohortNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cohort: %w", err)
+	}
+
+	return cohort, nil
+}
+
+func (s *PostgresStore) UpdateCohort(ctx context.Context, cohortID int, req *models.CohortRequest) (*models.Cohort, error) {
+	cohort, err := scanCohort(s.pool.QueryRow(ctx, `
+		WITH c AS (
+			UPDATE class_cohorts
+			SET offering_id = $2, name = $3, start_date = $4, end_date = $5, meeting_times = $6,
+				instructor = $7, capacity = $8, active = $9
+			WHERE id = $1
+			RETURNING *
+		)
+		SELECT `+cohortColumns+` FROM c`,
+		cohortID, req.OfferingID, req.Name, req.StartDate, req.EndDate, req.MeetingTimes, req.Instructor,
+		req.Capacity, cohortActive(req)))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrCohortNotFound
+	}
+	if isForeignKeyViolation(err) {
+		return nil, ErrOfferingNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to update cohort: %w", err)
+	}
+
+	return cohort, nil
+}
+
+func (s *PostgresStore) DeleteCohort(ctx context.Context, cohortID int) error {
+	tag, err := s.pool.Exec(ctx, `DELETE FROM class_cohorts WHERE id = $1`, cohortID)
+	if err != nil {
+		return fmt.Errorf("failed to delete cohort: %w", err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return ErrCohortNotFound
+	}
+
+	return nil
+}
+
+// Class methods
+func (s *PostgresStore) CreateClass(ctx context.Context, req *models.ClassRequest, manageTokenHash string, waitlistWhenFull bool) (*models.Class, error) {
+	var class *models.Class
+	err := s.withTx(ctx, func(tx pgx.Tx) error {
+		status := "pending"
+		if req.CohortID != nil {
+			var err error
+			if status, err = cohortEnrollmentStatus(ctx, tx, *req.CohortID, waitlistWhenFull); err != nil {
+				return err
+			}
+		}
+
+		customerID, err := matchCustomer(ctx, tx, req.Name, req.Email, req.Phone)
+		if err != nil {
+			return err
+		}
+
+		class, err = scanClass(tx.QueryRow(ctx, `
+			INSERT INTO classes (name, email, phone, class_type, experience_level, goals, preferred_schedule, cohort_id, customer_id, channels, status, manage_token_hash)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+			RETURNING `+classColumns,
+			req.Name, req.Email, req.Phone, req.ClassType, req.Experience, req.Goals, req.Schedule,
+			req.CohortID, customerID, bookingChannels(req.Channels), status, manageTokenHash))
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create class enrollment: %w", err)
+	}
+
+	return class, nil
+}
+
+// cohortEnrollmentStatus is the status a new enrollment in the cohort gets.
+// It locks the cohort row so concurrent enrollments count seats one at a time.
+func cohortEnrollmentStatus(ctx context.Context, tx pgx.Tx, cohortID int, waitlistWhenFull bool) (string, error) {
+	var capacity int
+	err := tx.QueryRow(ctx, `SELECT capacity FROM class_cohorts WHERE id = $1 FOR UPDATE`, cohortID).Scan(&capacity)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", ErrCohortNotFound
+	}
+	if err != nil {
+		return "", err
+	}
+
+	var taken int
+	err = tx.QueryRow(ctx, `
+		SELECT COUNT(*) FROM classes WHERE cohort_id = $1 AND status = ANY($2)`,
+		cohortID, activeStatuses).Scan(&taken)
+	if err != nil {
+		return "", err
+	}
+
+	return enrollmentStatus(capacity, taken, waitlistWhenFull)
+}
+
+func (s *PostgresStore) GetClass(ctx context.Context, classID int) (*models.Class, error) {
+	class, err := scanClass(s.pool.QueryRow(ctx,
+		`SELECT `+classColumns+` FROM classes WHERE id = $1`, classID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrClassNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get class enrollment: %w", err)
+	}
+
+	return class, nil
+}
+
+func (s *PostgresStore) GetClassByManageToken(ctx context.Context, tokenHash string) (*models.Class, error) {
+	class, err := scanClass(s.pool.QueryRow(ctx,
+		`SELECT `+classColumns+` FROM classes WHERE manage_token_hash = $1`, tokenHash))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrClassNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get class enrollment: %w", err)
+	}
+
+	return class, nil
+}
+
+func (s *PostgresStore) ChangeClassCohort(ctx context.Context, classID, cohortID int) (*models.Class, error) {
+	var class *models.Class
+	err := s.withTx(ctx, func(tx pgx.Tx) error {
+		var status string
+		err := tx.QueryRow(ctx, `SELECT status FROM classes WHERE id = $1 FOR UPDATE`, classID).Scan(&status)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrClassNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if !isActiveStatus(status) {
+			return ErrBookingClosed
+		}
+
+		if _, err := cohortEnrollmentStatus(ctx, tx, cohortID, false); err != nil {
+			return err
+		}
+
+		class, err = scanClass(tx.QueryRow(ctx, `
+			UPDATE classes SET cohort_id = $2 WHERE id = $1
+			RETURNING `+classColumns, classID, cohortID))
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to change cohort: %w", err)
+	}
+
+	return class, nil
+}
+
+func (s *PostgresStore) UpdateClassChannels(ctx context.Context, classID int, channels []string) (*models.Class, error) {
+	class, err := scanClass(s.pool.QueryRow(ctx, `
+		UPDATE classes SET channels = $2 WHERE id = $1
+		RETURNING `+classColumns, classID, bookingChannels(channels)))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrClassNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to update enrollment channels: %w", err)
+	}
+
+	return class, nil
+}
+
+func (s *PostgresStore) GetClasses(ctx context.Context, filter ClassFilter, page Page) ([]models.Class, int, error) {
+	where := "TRUE"
+	var args []any
+	if filter.Status != "" {
+		args = append(args, filter.Status)
+		where += fmt.Sprintf(" AND status = $%d", len(args))
+	}
+	if filter.ClassType != "" {
+		args = append(args, filter.ClassType)
+		where += fmt.Sprintf(" AND class_type = $%d", len(args))
+	}
+	if filter.CohortID != nil {
+		args = append(args, *filter.CohortID)
+		where += fmt.Sprintf(" AND cohort_id = $%d", len(args))
+	}
+	if filter.CustomerID != nil {
+		args = append(args, *filter.CustomerID)
+		where += fmt.Sprintf(" AND customer_id = $%d", len(args))
+	}
+	if filter.Email != "" {
+		args = append(args, filter.Email)
+		where += fmt.Sprintf(" AND lower(email) = lower($%d)", len(args))
+	}
+	if filter.Phone != "" {
+		args = append(args, filter.Phone)
+		where += fmt.Sprintf(" AND phone = $%d", len(args))
+	}
+	if filter.CreatedFrom != "" {
+		args = append(args, filter.CreatedFrom)
+		where += fmt.Sprintf(" AND created_at >= $%d::date", len(args))
+	}
+	if filter.CreatedTo != "" {
+		args = append(args, filter.CreatedTo)
+		where += fmt.Sprintf(" AND created_at < $%d::date + 1", len(args))
+	}
+	if filter.Search != "" {
+		args = append(args, "%"+escapeLike(filter.Search)+"%")
+		where += fmt.Sprintf(" AND (name ILIKE $%d OR email ILIKE $%[1]d OR goals ILIKE $%[1]d)", len(args))
+	}
+
+	var total int
+	err := s.pool.QueryRow(ctx, `SELECT COUNT(*) FROM classes WHERE `+where, args...).Scan(&total)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count classes: %w", err)
+	}
+
+	rows, err := s.pool.Query(ctx, `SELECT `+classColumns+` FROM classes WHERE `+where+pageClause(page, ClassSorts, &args), args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get classes: %w", err)
+	}
+	defer rows.Close()
+
+	classes := []models.Class{}
+	for rows.Next() {
+		class, err := scanClass(rows)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to get classes: %w", err)
+		}
+		classes = append(classes, *class)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("failed to get classes: %w", err)
+	}
+
+	return classes, total, nil
+}
+
+func (s *PostgresStore) UpdateClassStatus(ctx context.Context, classID int, change StatusChange) (*models.Class, error) {
+	if err := validateClassStatus(change.Status); err != nil {
+		return nil, err
+	}
+
+	var class *models.Class
+	err := s.withTx(ctx, func(tx pgx.Tx) error {
+		// Coming off the waitlist takes a seat. It is counted under the
+		// cohort's lock, taken before the enrollment's as CreateClass does
+		var from string
+		var cohortID *int
+		err := tx.QueryRow(ctx, `SELECT status, cohort_id FROM classes WHERE id = $1`, classID).Scan(&from, &cohortID)
+		if err != nil {
+			return err
+		}
+		seated := from == "waitlisted" && isActiveStatus(change.Status)
+		if seated && cohortID != nil {
+			if _, err := cohortEnrollmentStatus(ctx, tx, *cohortID, false); err != nil {
+				return err
+			}
+		}
+
+		if err := changeStatus(ctx, tx, "classes", "class", classID, change); err != nil {
+			return err
+		}
+		if seated {
+			_, err := tx.Exec(ctx, `
+				UPDATE waitlist_entries SET status = 'promoted', hold_expires_at = NULL
+				WHERE class_id = $1 AND status = 'waiting'`, classID)
+			if err != nil {
+				return err
+			}
+		}
+
+		class, err = scanClass(tx.QueryRow(ctx,
+			`SELECT `+classColumns+` FROM classes WHERE id = $1`, classID))
+		return err
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrClassNotFound
+	}
+	if err != nil {
+		return nil, statusChangeError(err, "failed to update class status")
+	}
+
+	return class, nil
+}
+
+// Status history methods
+func (s *PostgresStore) GetStatusHistory(ctx context.Context, resourceType string, resourceID int) ([]models.StatusHistory, error) {
+	var table string
+	var notFound error
+	switch resourceType {
+	case "appointment":
+		table, notFound = "appointments", ErrAppointmentNotFound
+	case "class":
+		table, notFound = "classes", ErrClassNotFound
+	default:
+		return nil, fmt.Errorf("unknown resource type: %s", resourceType)
+	}
+
+	var exists bool
+	err := s.pool.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM `+table+` WHERE id = $1)`, resourceID).Scan(&exists)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get status history: %w", err)
+	}
+	if !exists {
+		return nil, notFound
+	}
+
+	rows, err := s.pool.Query(ctx, `
+		SELECT id, resource_type, resource_id, from_status, to_status, reason, admin_id::text, created_at
+		FROM status_history
+		WHERE resource_type = $1 AND resource_id = $2
+		ORDER BY created_at, id`, resourceType, resourceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get status history: %w", err)
+	}
+	defer rows.Close()
+
+	history := []models.StatusHistory{}
+	for rows.Next() {
+		var h models.StatusHistory
+		err := rows.Scan(&h.ID, &h.ResourceType, &h.ResourceID, &h.FromStatus, &h.ToStatus,
+			&h.Reason, &h.AdminID, &h.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan status history: %w", err)
+		}
+		history = append(history, h)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get status history: %w", err)
+	}
+
+	return history, nil
+}
+
+// Waitlist methods
+func (s *PostgresStore) CreateWaitlistEntry(ctx context.Context, entry *models.WaitlistEntry) (*models.WaitlistEntry, error) {
+	status := entry.Status
+	if status == "" {
+		status = "waiting"
+	}
+
+	created, err := scanWaitlistEntry(s.pool.QueryRow(ctx, `
+		INSERT INTO waitlist_entries (kind, name, email, phone, cohort_id, class_id, service, appointment_date,
+			preferred_time, artist_id, status, token_hash)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		RETURNING `+waitlistColumns,
+		entry.Kind, entry.Name, entry.Email, entry.Phone, entry.CohortID, entry.ClassID, entry.Service,
+		entry.AppointmentDate, entry.PreferredTime, entry.ArtistID, status, entry.TokenHash))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create waitlist entry: %w", err)
+	}
+
+	return created, nil
+}
+
+func (s *PostgresStore) GetWaitlistEntries(ctx context.Context, filter WaitlistFilter) ([]models.WaitlistEntry, error) {
+	where := "TRUE"
+	var args []any
+	if filter.Kind != "" {
+		args = append(args, filter.Kind)
+		where += fmt.Sprintf(" AND kind = $%d", len(args))
+	}
+	if filter.Status != "" {
+		args = append(args, filter.Status)
+		where += fmt.Sprintf(" AND status = $%d", len(args))
+	}
+	if filter.CohortID != nil {
+		args = append(args, *filter.CohortID)
+		where += fmt.Sprintf(" AND cohort_id = $%d", len(args))
+	}
+	if filter.ClassID != nil {
+		args = append(args, *filter.ClassID)
+		where += fmt.Sprintf(" AND class_id = $%d", len(args))
+	}
+	if filter.AppointmentID != nil {
+		args = append(args, *filter.AppointmentID)
+		where += fmt.Sprintf(" AND appointment_id = $%d", len(args))
+	}
+	if filter.Date != "" {
+		args = append(args, filter.Date)
+		where += fmt.Sprintf(" AND appointment_date = $%d", len(args))
+	}
+
+	rows, err := s.pool.Query(ctx, `
+		SELECT `+waitlistColumns+` FROM waitlist_entries
+		WHERE `+where+`
+		ORDER BY created_at, id`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get waitlist entries: %w", err)
+	}
+	defer rows.Close()
+
+	entries := []models.WaitlistEntry{}
+	for rows.Next() {
+		entry, err := scanWaitlistEntry(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get waitlist entries: %w", err)
+		}
+		entries = append(entries, *entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get waitlist entries: %w", err)
+	}
+
+	return entries, nil
+}
+
+func (s *PostgresStore) GetWaitlistEntry(ctx context.Context, entryID int) (*models.WaitlistEntry, error) {
+	entry, err := scanWaitlistEntry(s.pool.QueryRow(ctx, `SELECT `+waitlistColumns+` FROM waitlist_entries WHERE id = $1`, entryID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrWaitlistNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get waitlist entry: %w", err)
+	}
+
+	return entry, nil
+}
+
+func (s *PostgresStore) GetWaitlistEntryByToken(ctx context.Context, tokenHash string) (*models.WaitlistEntry, error) {
+	entry, err := scanWaitlistEntry(s.pool.QueryRow(ctx, `SELECT `+waitlistColumns+` FROM waitlist_entries WHERE token_hash = $1`, tokenHash))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrWaitlistNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get waitlist entry: %w", err)
+	}
+
+	return entry, nil
+}
+
+func (s *PostgresStore) UpdateWaitlistEntry(ctx context.Context, entry *models.WaitlistEntry) (*models.WaitlistEntry, error) {
+	updated, err := scanWaitlistEntry(s.pool.QueryRow(ctx, `
+		UPDATE waitlist_entries SET status = $2, hold_expires_at = $3, appointment_id = $4
+		WHERE id = $1
+		RETURNING `+waitlistColumns,
+		entry.ID, entry.Status, entry.HoldExpiresAt, entry.AppointmentID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrWaitlistNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to update waitlist entry: %w", err)
+	}
+
+	return updated, nil
+}
+
+// PromoteWaitlistedClass counts the cohort's seats under the same lock as
+// CreateClass, so a seat can't be handed out twice.
+func (s *PostgresStore) PromoteWaitlistedClass(ctx context.Context, entry *models.WaitlistEntry, change StatusChange, overfill bool) (*models.Class, *models.WaitlistEntry, error) {
+	if err := validateClassStatus(change.Status); err != nil {
+		return nil, nil, err
+	}
+	if entry.ClassID == nil {
+		return nil, nil, ErrClassNotFound
+	}
+
+	var class *models.Class
+	var updated *models.WaitlistEntry
+	err := s.withTx(ctx, func(tx pgx.Tx) error {
+		if entry.CohortID != nil && !overfill {
+			if _, err := cohortEnrollmentStatus(ctx, tx, *entry.CohortID, false); err != nil {
+				return err
+			}
+		}
+
+		err := changeStatus(ctx, tx, "classes", "class", *entry.ClassID, change)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrClassNotFound
+		}
+		if err != nil {
+			return err
+		}
+		class, err = scanClass(tx.QueryRow(ctx,
+			`SELECT `+classColumns+` FROM classes WHERE id = $1`, *entry.ClassID))
+		if err != nil {
+			return err
+		}
+
+		updated, err = scanWaitlistEntry(tx.QueryRow(ctx, `
+			UPDATE waitlist_entries SET status = $2, hold_expires_at = $3
+			WHERE id = $1
+			RETURNING `+waitlistColumns,
+			entry.ID, entry.Status, entry.HoldExpiresAt))
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrWaitlistNotFound
+		}
+		return err
+	})
+	if err != nil {
+		return nil, nil, statusChangeError(err, "failed to promote waitlisted class")
+	}
+
+	return class, updated, nil
+}
+
+// Notification outbox methods
+func (s *PostgresStore) CreateNotification(ctx context.Context, notification *models.Notification) (*models.Notification, error) {
+	channel := notification.Channel
+	if channel == "" {
+		channel = "email"
+	}
+	nextAttempt := notification.NextAttemptAt
+	if nextAttempt.IsZero() {
+		nextAttempt = time.Now()
+	}
+
+	created, err := scanNotification(s.pool.QueryRow(ctx, `
+		INSERT INTO notification_outbox (channel, event, resource_type, resource_id, recipient, subject, body, secret, next_attempt_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING `+notificationColumns,
+		channel, notification.Event, notification.ResourceType, notification.ResourceID, notification.Recipient,
+		notification.Subject, notification.Body, notification.Secret, nextAttempt))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create notification: %w", err)
+	}
+
+	return created, nil
+}
+
+func (s *PostgresStore) ClaimNotifications(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]models.Notification, error) {
+	// SKIP LOCKED lets several instances claim disjoint batches at once
+	notifications, err := queryNotifications(ctx, s.pool, `
+		UPDATE notification_outbox SET attempts = attempts + 1, next_attempt_at = $2
+		WHERE id IN (
+			SELECT id FROM notification_outbox
+			WHERE status = 'pending' AND next_attempt_at <= $1
+			ORDER BY next_attempt_at, id
+			LIMIT $3
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING `+notificationColumns,
+		now, now.Add(lease), limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim notifications: %w", err)
+	}
+
+	return notifications, nil
+}
+
+func (s *PostgresStore) GetNotifications(ctx context.Context, filter NotificationFilter) ([]models.Notification, error) {
+	where := "TRUE"
+	var args []any
+	if filter.Channel != "" {
+		args = append(args, filter.Channel)
+		where += fmt.Sprintf(" AND channel = $%d", len(args))
+	}
+	if filter.Status != "" {
+		args = append(args, filter.Status)
+		where += fmt.Sprintf(" AND status = $%d", len(args))
+	}
+	if filter.ResourceType != "" {
+		args = append(args, filter.ResourceType)
+		where += fmt.Sprintf(" AND resource_type = $%d", len(args))
+	}
+	if filter.ResourceID != nil {
+		args = append(args, *filter.ResourceID)
+		where += fmt.Sprintf(" AND resource_id = $%d", len(args))
+	}
+	if filter.ProviderMessageID != "" {
+		args = append(args, filter.ProviderMessageID)
+		where += fmt.Sprintf(" AND provider_message_id = $%d", len(args))
+	}
+
+	notifications, err := queryNotifications(ctx, s.pool, `
+		SELECT `+notificationColumns+` FROM notification_outbox
+		WHERE `+where+`
+		ORDER BY created_at, id`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get notifications: %w", err)
+	}
+
+	return notifications, nil
+}
+
+func (s *PostgresStore) UpdateNotification(ctx context.Context, notification *models.Notification) (*models.Notification, error) {
+	updated, err := scanNotification(s.pool.QueryRow(ctx, `
+		UPDATE notification_outbox SET status = $2, last_error = $3, next_attempt_at = $4, sent_at = $5,
+			provider_message_id = $6, delivered_at = $7, secret = $8
+		WHERE id = $1
+		RETURNING `+notificationColumns,
+		notification.ID, notification.Status, notification.LastError, notification.NextAttemptAt, notification.SentAt,
+		notification.ProviderMessageID, notification.DeliveredAt, notification.Secret))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotificationNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to update notification: %w", err)
+	}
+
+	return updated, nil
+}
+
+// Notification preference methods
+func (s *PostgresStore) GetNotificationPreference(ctx context.Context, email string) (*models.NotificationPreference, error) {
+	preference, err := scanPreference(s.pool.QueryRow(ctx, `
+		SELECT `+preferenceColumns+` FROM notification_preferences WHERE email = $1`,
+		preferenceKey(email)))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrPreferenceNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get notification preference: %w", err)
+	}
+
+	return preference, nil
+}
+
+func (s *PostgresStore) SaveNotificationPreference(ctx context.Context, email string, channels []string) (*models.NotificationPreference, error) {
+	preference, err := scanPreference(s.pool.QueryRow(ctx, `
+		INSERT INTO notification_preferences (email, channels)
+		VALUES ($1, $2)
+		ON CONFLICT (email) DO UPDATE SET channels = EXCLUDED.channels
+		RETURNING `+preferenceColumns,
+		preferenceKey(email), channels))
+	if err != nil {
+		return nil, fmt.Errorf("failed to save notification preference: %w", err)
+	}
+
+	return preference, nil
+}
+
+// Job methods
+func (s *PostgresStore) CreateJob(ctx context.Context, job *models.Job) (*models.Job, error) {
+	payload := job.Payload
+	if len(payload) == 0 {
+		payload = json.RawMessage(`{}`)
+	}
+
+	created, err := scanJob(s.pool.QueryRow(ctx, `
+		INSERT INTO scheduled_jobs (kind, resource_type, resource_id, payload, run_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING `+jobColumns,
+		job.Kind, job.ResourceType, job.ResourceID, payload, job.RunAt))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create job: %w", err)
+	}
+
+	return created, nil
+}
+
+func (s *PostgresStore) GetJobs(ctx context.Context, filter JobFilter) ([]models.Job, error) {
+	where := "TRUE"
+	var args []any
+	if filter.Kind != "" {
+		args = append(args, filter.Kind)
+		where += fmt.Sprintf(" AND kind = $%d", len(args))
+	}
+	if filter.Status != "" {
+		args = append(args, filter.Status)
+		where += fmt.Sprintf(" AND status = $%d", len(args))
+	}
+	if filter.ResourceType != "" {
+		args = append(args, filter.ResourceType)
+		where += fmt.Sprintf(" AND resource_type = $%d", len(args))
+	}
+	if filter.ResourceID != nil {
+		args = append(args, *filter.ResourceID)
+		where += fmt.Sprintf(" AND resource_id = $%d", len(args))
+	}
+
+	jobs, err := queryJobs(ctx, s.pool, `
+		SELECT `+jobColumns+` FROM scheduled_jobs
+		WHERE `+where+`
+		ORDER BY run_at, id`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get jobs: %w", err)
+	}
+
+	return jobs, nil
+}
+
+func (s *PostgresStore) ClaimJobs(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]models.Job, error) {
+	jobs, err := queryJobs(ctx, s.pool, `
+		UPDATE scheduled_jobs SET attempts = attempts + 1, run_at = $2
+		WHERE id IN (
+			SELECT id FROM scheduled_jobs
+			WHERE status = 'pending' AND run_at <= $1
+			ORDER BY run_at, id
+			LIMIT $3
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING `+jobColumns,
+		now, now.Add(lease), limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim jobs: %w", err)
+	}
+
+	return jobs, nil
+}
+
+func (s *PostgresStore) UpdateJob(ctx context.Context, job *models.Job) (*models.Job, error) {
+	updated, err := scanJob(s.pool.QueryRow(ctx, `
+		UPDATE scheduled_jobs SET status = $2, last_error = $3, run_at = $4
+		WHERE id = $1
+		RETURNING `+jobColumns,
+		job.ID, job.Status, job.LastError, job.RunAt))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrJobNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to update job: %w", err)
+	}
+
+	return updated, nil
+}
+
+func (s *PostgresStore) CancelJobs(ctx context.Context, resourceType string, resourceID int) error {
+	_, err := s.pool.Exec(ctx, `
+		UPDATE scheduled_jobs SET status = 'cancelled'
+		WHERE resource_type = $1 AND resource_id = $2 AND status = 'pending'`,
+		resourceType, resourceID)
+	if err != nil {
+		return fmt.Errorf("failed to cancel jobs: %w", err)
+	}
+
+	return nil
+}
+
+// Webhook subscription methods
+func (s *PostgresStore) CreateWebhookSubscription(ctx context.Context, req *models.WebhookSubscriptionRequest) (*models.WebhookSubscription, error) {
+	subscription, err := scanWebhook(s.pool.QueryRow(ctx, `
+		INSERT INTO webhook_subscriptions (url, secret, events, description, active)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING `+webhookColumns,
+		req.URL, req.Secret, req.Events, req.Description, webhookActive(req)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create webhook subscription: %w", err)
+	}
+
+	return subscription, nil
+}
+
+func (s *PostgresStore) GetWebhookSubscriptions(ctx context.Context, activeOnly bool) ([]models.WebhookSubscription, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT `+webhookColumns+` FROM webhook_subscriptions
+		WHERE active OR NOT $1
+		ORDER BY id`, activeOnly)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhook subscriptions: %w", err)
+	}
+	defer rows.Close()
+
+	subscriptions := []models.WebhookSubscription{}
+	for rows.Next() {
+		subscription, err := scanWebhook(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get webhook subscriptions: %w", err)
+		}
+		subscriptions = append(subscriptions, *subscription)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get webhook subscriptions: %w", err)
+	}
+
+	return subscriptions, nil
+}
+
+func (s *PostgresStore) GetWebhookSubscription(ctx context.Context, subscriptionID int) (*models.WebhookSubscription, error) {
+	subscription, err := scanWebhook(s.pool.QueryRow(ctx, `
+		SELECT `+webhookColumns+` FROM webhook_subscriptions WHERE id = $1`, subscriptionID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrWebhookNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhook subscription: %w", err)
+	}
+
+	return subscription, nil
+}
+
+func (s *PostgresStore) GetWebhookSecret(ctx context.Context, subscriptionID int) (string, error) {
+	var secret string
+	err := s.pool.QueryRow(ctx, `SELECT secret FROM webhook_subscriptions WHERE id = $1`, subscriptionID).Scan(&secret)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", ErrWebhookNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get webhook secret: %w", err)
+	}
+
+	return secret, nil
+}
+
+func (s *PostgresStore) UpdateWebhookSubscription(ctx context.Context, subscriptionID int, req *models.WebhookSubscriptionRequest) (*models.WebhookSubscription, error) {
+	subscription, err := scanWebhook(s.pool.QueryRow(ctx, `
+		UPDATE webhook_subscriptions
+		SET url = $2, secret = COALESCE(NULLIF($3, ''), secret), events = $4, description = $5, active = $6
+		WHERE id = $1
+		RETURNING `+webhookColumns,
+		subscriptionID, req.URL, req.Secret, req.Events, req.Description, webhookActive(req)))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrWebhookNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to update webhook subscription: %w", err)
+	}
+
+	return subscription, nil
+}
+
+func (s *PostgresStore) DeleteWebhookSubscription(ctx context.Context, subscriptionID int) error {
+	tag, err := s.pool.Exec(ctx, `DELETE FROM webhook_subscriptions WHERE id = $1`, subscriptionID)
+	if err != nil {
+		return fmt.Errorf("failed to delete webhook subscription: %w", err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return ErrWebhookNotFound
+	}
+
+	return nil
+}
+
+// Webhook delivery methods
+func (s *PostgresStore) CreateWebhookDelivery(ctx context.Context, delivery *models.WebhookDelivery) (*models.WebhookDelivery, error) {
+	nextAttempt := delivery.NextAttemptAt
+	if nextAttempt.IsZero() {
+		nextAttempt = time.Now()
+	}
+
+	created, err := scanDelivery(s.pool.QueryRow(ctx, `
+		INSERT INTO webhook_deliveries (subscription_id, event, payload, next_attempt_at)
+		VALUES ($1, $2, $3, $4)
+		RETURNING `+deliveryColumns,
+		delivery.SubscriptionID, delivery.Event, delivery.Payload, nextAttempt))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create webhook delivery: %w", err)
+	}
+
+	return created, nil
+}
+
+func (s *PostgresStore) GetWebhookDeliveries(ctx context.Context, filter WebhookDeliveryFilter, page Page) ([]models.WebhookDelivery, int, error) {
+	where := "TRUE"
+	var args []any
+	if filter.SubscriptionID != nil {
+		args = append(args, *filter.SubscriptionID)
+		where += fmt.Sprintf(" AND subscription_id = $%d", len(args))
+	}
+	if filter.Event != "" {
+		args = append(args, filter.Event)
+		where += fmt.Sprintf(" AND event = $%d", len(args))
+	}
+	if filter.Status != "" {
+		args = append(args, filter.Status)
+		where += fmt.Sprintf(" AND status = $%d", len(args))
+	}
+
+	var total int
+	err := s.pool.QueryRow(ctx, `SELECT COUNT(*) FROM webhook_deliveries WHERE `+where, args...).Scan(&total)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count webhook deliveries: %w", err)
+	}
+
+	deliveries, err := queryDeliveries(ctx, s.pool, `
+		SELECT `+deliveryColumns+` FROM webhook_deliveries
+		WHERE `+where+pageClause(page, WebhookDeliverySorts, &args), args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get webhook deliveries: %w", err)
+	}
+
+	return deliveries, total, nil
+}
+
+func (s *PostgresStore) GetWebhookDelivery(ctx context.Context, deliveryID int) (*models.WebhookDelivery, error) {
+	delivery, err := scanDelivery(s.pool.QueryRow(ctx, `
+		SELECT `+deliveryColumns+` FROM webhook_deliveries WHERE id = $1`, deliveryID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrDeliveryNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhook delivery: %w", err)
+	}
+
+	return delivery, nil
+}
+
+func (s *PostgresStore) ClaimWebhookDeliveries(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]models.WebhookDelivery, error) {
+	deliveries, err := queryDeliveries(ctx, s.pool, `
+		UPDATE webhook_deliveries SET attempts = attempts + 1, next_attempt_at = $2
+		WHERE id IN (
+			SELECT id FROM webhook_deliveries
+			WHERE status = 'pending' AND next_attempt_at <= $1
+			ORDER BY next_attempt_at, id
+			LIMIT $3
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING `+deliveryColumns,
+		now, now.Add(lease), limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim webhook deliveries: %w", err)
+	}
+
+	return deliveries, nil
+}
+
+func (s *PostgresStore) UpdateWebhookDelivery(ctx context.Context, delivery *models.WebhookDelivery) (*models.WebhookDelivery, error) {
+	updated, err := scanDelivery(s.pool.QueryRow(ctx, `
+		UPDATE webhook_deliveries
+		SET status = $2, attempts = $3, last_error = $4, response_status = $5, next_attempt_at = $6, delivered_at = $7
+		WHERE id = $1
+		RETURNING `+deliveryColumns,
+		delivery.ID, delivery.Status, delivery.Attempts, delivery.LastError, delivery.ResponseStatus,
+		delivery.NextAttemptAt, delivery.DeliveredAt))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrDeliveryNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to update webhook delivery: %w", err)
+	}
+
+	return updated, nil
+}
+
+// Admin methods
+func (s *PostgresStore) CreateAdmin(ctx context.Context, email, passwordHash, name, role string, artistID *int) (*models.AdminUser, error) {
+	row := s.pool.QueryRow(ctx, `
+		INSERT INTO admin_users (email, password_hash, name, role, artist_id)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING `+adminColumns, email, passwordHash, name, role, artistID)
+
+	admin, err := scanAdmin(row)
+	if isUniqueViolation(err) {
+		return nil, ErrAdminExists
+	}
+	if isForeignKeyViolation(err) {
+		return nil, ErrArtistNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create admin: %w", err)
+	}
+
+	return admin, nil
+}
+
+func (s *PostgresStore) GetAdmins(ctx context.Context) ([]models.AdminUser, error) {
+	rows, err := s.pool.Query(ctx, `SELECT `+adminColumns+` FROM admin_users ORDER BY created_at`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get admins: %w", err)
+	}
+	defer rows.Close()
+
+	admins := []models.AdminUser{}
+	for rows.Next() {
+		admin, err := scanAdmin(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get admins: %w", err)
+		}
+		admins = append(admins, *admin)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get admins: %w", err)
+	}
+
+	return admins, nil
+}
+
+func (s *PostgresStore) GetAdmin(ctx context.Context, adminID string) (*models.AdminUser, error) {
+	admin, err := scanAdmin(s.pool.QueryRow(ctx, `SELECT `+adminColumns+` FROM admin_users WHERE id::text = $1`, adminID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrAdminNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get admin: %w", err)
+	}
+
+	return admin, nil
+}
+
+// UpdateAdminRole locks every owner, in ID order so concurrent calls can't
+// deadlock, before counting them and updating the admin.
+func (s *PostgresStore) UpdateAdminRole(ctx context.Context, adminID, role string, artistID *int) (*models.AdminUser, error) {
+	var admin *models.AdminUser
+	err := s.withTx(ctx, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, `SELECT id::text FROM admin_users WHERE role = $1 ORDER BY id FOR UPDATE`, auth.RoleOwner)
+		if err != nil {
+			return err
+		}
+		var owners []string
+		for rows.Next() {
+			var id string
+			if err := rows.Scan(&id); err != nil {
+				rows.Close()
+				return err
+			}
+			owners = append(owners, id)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		if role != auth.RoleOwner && len(owners) == 1 && owners[0] == adminID {
+			return ErrLastOwner
+		}
+
+		admin, err = scanAdmin(tx.QueryRow(ctx, `
+			UPDATE admin_users SET role = $2, artist_id = $3
+			WHERE id::text = $1
+			RETURNING `+adminColumns, adminID, role, artistID))
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrAdminNotFound
+		}
+		if isForeignKeyViolation(err) {
+			return ErrArtistNotFound
+		}
+		return err
+	})
+	if errors.Is(err, ErrLastOwner) || errors.Is(err, ErrAdminNotFound) || errors.Is(err, ErrArtistNotFound) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to update admin role: %w", err)
+	}
+
+	return admin, nil
+}
+
+func (s *PostgresStore) GetAdminByEmail(ctx context.Context, email string) (*models.AdminUser, error) {
+	admin, err := scanAdmin(s.pool.QueryRow(ctx, `SELECT `+adminColumns+` FROM admin_users WHERE email = $1`, email))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrAdminNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get admin by email: %w", err)
+	}
+
+	return admin, nil
+}
+
+func (s *PostgresStore) GetAdminPasswordHash(ctx context.Context, email string) (string, error) {
+	var passwordHash string
+	err := s.pool.QueryRow(ctx, `SELECT password_hash FROM admin_users WHERE email = $1`, email).Scan(&passwordHash)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", ErrAdminNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get admin password hash: %w", err)
+	}
+
+	return passwordHash, nil
+}
+
+func (s *PostgresStore) UpdateAdminPassword(ctx context.Context, adminID, passwordHash string) error {
+	tag, err := s.pool.Exec(ctx, `UPDATE admin_users SET password_hash = $2 WHERE id::text = $1`, adminID, passwordHash)
+	if err != nil {
+		return fmt.Errorf("failed to update admin password: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrAdminNotFound
+	}
+
+	return nil
+}
+
+// Password reset methods
+func (s *PostgresStore) CreatePasswordReset(ctx context.Context, adminID, tokenHash string, expiresAt time.Time) (int, error) {
+	var id int
+	err := s.pool.QueryRow(ctx, `
+		INSERT INTO admin_password_resets (admin_id, token_hash, expires_at)
+		VALUES ($1::uuid, $2, $3)
+		RETURNING id`, adminID, tokenHash, expiresAt).Scan(&id)
+	if isForeignKeyViolation(err) {
+		return 0, ErrAdminNotFound
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to create password reset: %w", err)
+	}
+
+	return id, nil
+}
+
+func (s *PostgresStore) ResetAdminPassword(ctx context.Context, tokenHash, passwordHash string) (*models.AdminUser, error) {
+	var admin *models.AdminUser
+	err := s.withTx(ctx, func(tx pgx.Tx) error {
+		var adminID string
+		err := tx.QueryRow(ctx, `
+			UPDATE admin_password_resets SET used_at = NOW()
+			WHERE token_hash = $1 AND used_at IS NULL AND expires_at > NOW()
+			RETURNING admin_id::text`, tokenHash).Scan(&adminID)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrResetNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(ctx, `
+			UPDATE admin_password_resets SET used_at = NOW()
+			WHERE admin_id::text = $1 AND used_at IS NULL`, adminID)
+		if err != nil {
+			return err
+		}
+		admin, err = scanAdmin(tx.QueryRow(ctx, `
+			UPDATE admin_users SET password_hash = $2
+			WHERE id::text = $1
+			RETURNING `+adminColumns, adminID, passwordHash))
+		if err != nil {
+			return err
+		}
+		return revokeAdminSessions(ctx, tx, adminID, "")
+	})
+	if errors.Is(err, ErrResetNotFound) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to reset admin password: %w", err)
+	}
+
+	return admin, nil
+}
+
+// Admin invite methods
+const inviteColumns = `id, email, role, artist_id, invited_by::text, status, expires_at, accepted_at, created_at, updated_at`
+
+func scanInvite(row pgx.Row) (*models.AdminInvite, error) {
+	var inv models.AdminInvite
+	err := row.Scan(&inv.ID, &inv.Email, &inv.Role, &inv.ArtistID, &inv.InvitedBy, &inv.Status,
+		&inv.ExpiresAt, &inv.AcceptedAt, &inv.CreatedAt, &inv.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &inv, nil
+}
+
+func (s *PostgresStore) CreateAdminInvite(ctx context.Context, invite *models.AdminInvite, tokenHash string) (*models.AdminInvite, error) {
+	created, err := scanInvite(s.pool.QueryRow(ctx, `
+		INSERT INTO admin_invites (email, role, artist_id, token_hash, invited_by, expires_at)
+		VALUES ($1, $2, $3, $4, $5::uuid, $6)
+		RETURNING `+inviteColumns,
+		invite.Email, invite.Role, invite.ArtistID, tokenHash, invite.InvitedBy, invite.ExpiresAt))
+	if isForeignKeyViolation(err) {
+		return nil, ErrArtistNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create admin invite: %w", err)
+	}
+
+	return created, nil
+}
+
+func (s *PostgresStore) GetAdminInvites(ctx context.Context) ([]models.AdminInvite, error) {
+	rows, err := s.pool.Query(ctx, `SELECT `+inviteColumns+` FROM admin_invites ORDER BY created_at DESC`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get admin invites: %w", err)
+	}
+	defer rows.Close()
+
+	invites := []models.AdminInvite{}
+	for rows.Next() {
+		invite, err := scanInvite(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get admin invites: %w", err)
+		}
+		invites = append(invites, *invite)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get admin invites: %w", err)
+	}
+	markExpired(invites, time.Now())
+
+	return invites, nil
+}
+
+func (s *PostgresStore) GetAdminInviteByToken(ctx context.Context, tokenHash string) (*models.AdminInvite, error) {
+	invite, err := scanInvite(s.pool.QueryRow(ctx, `
+		SELECT `+inviteColumns+` FROM admin_invites
+		WHERE token_hash = $1 AND status = 'pending' AND expires_at > NOW()`, tokenHash))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrInviteNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get admin invite: %w", err)
+	}
+
+	return invite, nil
+}
+
+func (s *PostgresStore) RevokeAdminInvite(ctx context.Context, inviteID int) (*models.AdminInvite, error) {
+	invite, err := scanInvite(s.pool.QueryRow(ctx, `
+		UPDATE admin_invites SET status = 'revoked'
+		WHERE id = $1 AND status = 'pending'
+		RETURNING `+inviteColumns, inviteID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrInviteNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to revoke admin invite: %w", err)
+	}
+
+	return invite, nil
+}
+
+func (s *PostgresStore) RedeemAdminInvite(ctx context.Context, tokenHash, passwordHash, name string) (*models.AdminUser, error) {
+	var admin *models.AdminUser
+	err := s.withTx(ctx, func(tx pgx.Tx) error {
+		invite, err := scanInvite(tx.QueryRow(ctx, `
+			UPDATE admin_invites SET status = 'accepted', accepted_at = NOW()
+			WHERE token_hash = $1 AND status = 'pending' AND expires_at > NOW()
+			RETURNING `+inviteColumns, tokenHash))
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrInviteNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		admin, err = scanAdmin(tx.QueryRow(ctx, `
+			INSERT INTO admin_users (email, password_hash, name, role, artist_id)
+			VALUES ($1, $2, $3, $4, $5)
+			RETURNING `+adminColumns, invite.Email, passwordHash, name, invite.Role, invite.ArtistID))
+		if isUniqueViolation(err) {
+			return ErrAdminExists
+		}
+		return err
+	})
+	if errors.Is(err, ErrInviteNotFound) || errors.Is(err, ErrAdminExists) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to redeem admin invite: %w", err)
+	}
+
+	return admin, nil
+}
+
+// Admin session methods
+const sessionColumns = `id::text, admin_id::text, COALESCE(user_agent, ''), COALESCE(ip_address, ''),
+	expires_at, last_used_at, revoked_at, created_at, updated_at`
+
+func scanSession(row pgx.Row) (*models.AdminSession, error) {
+	var s models.AdminSession
+	err := row.Scan(&s.ID, &s.AdminID, &s.UserAgent, &s.IPAddress,
+		&s.ExpiresAt, &s.LastUsedAt, &s.RevokedAt, &s.CreatedAt, &s.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+func (s *PostgresStore) CreateAdminSession(ctx context.Context, session *models.AdminSession, refreshTokenHash string, access AccessToken) (*models.AdminSession, error) {
+	created, err := scanSession(s.pool.QueryRow(ctx, `
+		INSERT INTO admin_sessions (admin_id, refresh_token_hash, access_jti, access_expires_at, user_agent, ip_address, expires_at)
+		VALUES ($1::uuid, $2, $3, $4, $5, $6, $7)
+		RETURNING `+sessionColumns,
+		session.AdminID, refreshTokenHash, access.JTI, access.ExpiresAt, session.UserAgent, session.IPAddress, session.ExpiresAt))
+	if isForeignKeyViolation(err) {
+		return nil, ErrAdminNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create admin session: %w", err)
+	}
+
+	return created, nil
+}
+
+func (s *PostgresStore) GetAdminSessions(ctx context.Context, adminID string) ([]models.AdminSession, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT `+sessionColumns+` FROM admin_sessions
+		WHERE admin_id::text = $1 AND revoked_at IS NULL AND expires_at > NOW()
+		ORDER BY last_used_at DESC`, adminID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get admin sessions: %w", err)
+	}
+	defer rows.Close()
+
+	sessions := []models.AdminSession{}
+	for rows.Next() {
+		session, err := scanSession(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get admin sessions: %w", err)
+		}
+		sessions = append(sessions, *session)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get admin sessions: %w", err)
+	}
+
+	return sessions, nil
+}
+
+func (s *PostgresStore) RotateAdminSession(ctx context.Context, refreshTokenHash, newRefreshTokenHash string, access AccessToken, expiresAt time.Time) (*models.AdminSession, error) {
+	var session *models.AdminSession
+	err := s.withTx(ctx, func(tx pgx.Tx) error {
+		var sessionID string
+		var previous AccessToken
+		err := tx.QueryRow(ctx, `
+			SELECT id::text, access_jti, access_expires_at FROM admin_sessions
+			WHERE refresh_token_hash = $1 AND revoked_at IS NULL AND expires_at > NOW()
+			FOR UPDATE`, refreshTokenHash).Scan(&sessionID, &previous.JTI, &previous.ExpiresAt)
+		if errors.Is(err, pgx.ErrNoRows) {
+			// A refresh token used before ends its session, which has to
+			// be committed before answering ErrSessionNotFound
+			return endReusedSession(ctx, tx, refreshTokenHash)
+		}
+		if err != nil {
+			return err
+		}
+		if err := revokeTokens(ctx, tx, previous); err != nil {
+			return err
+		}
+		_, err = tx.Exec(ctx, `
+			INSERT INTO used_refresh_tokens (token_hash, session_id) VALUES ($1, $2::uuid)`,
+			refreshTokenHash, sessionID)
+		if err != nil {
+			return err
+		}
+
+		session, err = scanSession(tx.QueryRow(ctx, `
+			UPDATE admin_sessions
+			SET refresh_token_hash = $2, access_jti = $3, access_expires_at = $4, expires_at = $5, last_used_at = NOW()
+			WHERE refresh_token_hash = $1
+			RETURNING `+sessionColumns,
+			refreshTokenHash, newRefreshTokenHash, access.JTI, access.ExpiresAt, expiresAt))
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to rotate admin session: %w", err)
+	}
+	if session == nil {
+		return nil, ErrSessionNotFound
+	}
+
+	return session, nil
+}
+
+func (s *PostgresStore) RevokeAdminSession(ctx context.Context, adminID, sessionID string) error {
+	err := s.withTx(ctx, func(tx pgx.Tx) error {
+		var access AccessToken
+		err := tx.QueryRow(ctx, `
+			UPDATE admin_sessions SET revoked_at = NOW()
+			WHERE id::text = $1 AND admin_id::text = $2 AND revoked_at IS NULL AND expires_at > NOW()
+			RETURNING access_jti, access_expires_at`, sessionID, adminID).Scan(&access.JTI, &access.ExpiresAt)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrSessionNotFound
+		}
+		if err != nil {
+			return err
+		}
+		return revokeTokens(ctx, tx, access)
+	})
+	if errors.Is(err, ErrSessionNotFound) {
+		return err
+	}
+	if err != nil {
+		return fmt.Errorf("failed to revoke admin session: %w", err)
+	}
+
+	return nil
+}
+
+func (s *PostgresStore) RevokeAdminSessions(ctx context.Context, adminID, exceptSessionID string) error {
+	err := s.withTx(ctx, func(tx pgx.Tx) error {
+		return revokeAdminSessions(ctx, tx, adminID, exceptSessionID)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to revoke admin sessions: %w", err)
+	}
+
+	return nil
+}
+
+func (s *PostgresStore) IsTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	var revoked bool
+	err := s.pool.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM revoked_tokens WHERE jti = $1)`, jti).Scan(&revoked)
+	if err != nil {
+		return false, fmt.Errorf("failed to check revoked token: %w", err)
+	}
+
+	return revoked, nil
+}
+
+// revokeTokens adds tokens to the revoked tokens, forgetting those that
+// have expired.
+func revokeTokens(ctx context.Context, tx pgx.Tx, tokens ...AccessToken) error {
+	if _, err := tx.Exec(ctx, `DELETE FROM revoked_tokens WHERE expires_at <= NOW()`); err != nil {
+		return err
+	}
+	for _, access := range tokens {
+		_, err := tx.Exec(ctx, `
+			INSERT INTO revoked_tokens (jti, expires_at) VALUES ($1, $2)
+			ON CONFLICT (jti) DO NOTHING`, access.JTI, access.ExpiresAt)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// endReusedSession ends the active session that already swapped the
+// refresh token with refreshTokenHash, if there is one, and revokes its
+// access token.
+func endReusedSession(ctx context.Context, tx pgx.Tx, refreshTokenHash string) error {
+	var access AccessToken
+	err := tx.QueryRow(ctx, `
+		UPDATE admin_sessions SET revoked_at = NOW()
+		WHERE id = (SELECT session_id FROM used_refresh_tokens WHERE token_hash = $1)
+			AND revoked_at IS NULL AND expires_at > NOW()
+		RETURNING access_jti, access_expires_at`, refreshTokenHash).Scan(&access.JTI, &access.ExpiresAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return revokeTokens(ctx, tx, access)
+}
+
+// revokeAdminSessions ends adminID's active sessions but exceptSessionID
+// and revokes their access tokens.
+func revokeAdminSessions(ctx context.Context, tx pgx.Tx, adminID, exceptSessionID string) error {
+	rows, err := tx.Query(ctx, `
+		UPDATE admin_sessions SET revoked_at = NOW()
+		WHERE admin_id::text = $1 AND id::text <> $2 AND revoked_at IS NULL AND expires_at > NOW()
+		RETURNING access_jti, access_expires_at`, adminID, exceptSessionID)
+	if err != nil {
+		return err
+	}
+	var tokens []AccessToken
+	for rows.Next() {
+		var access AccessToken
+		if err := rows.Scan(&access.JTI, &access.ExpiresAt); err != nil {
+			rows.Close()
+			return err
+		}
+		tokens = append(tokens, access)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	return revokeTokens(ctx, tx, tokens...)
+}
+
+// Two-factor methods
+func (s *PostgresStore) GetAdminTwoFactor(ctx context.Context, adminID string) (*TwoFactor, error) {
+	var secret *string
+	var lastStep *int64
+	var twoFactor TwoFactor
+	err := s.pool.QueryRow(ctx, `
+		SELECT totp_secret, two_factor_enabled, totp_last_step
+		FROM admin_users WHERE id::text = $1`, adminID).Scan(&secret, &twoFactor.Enabled, &lastStep)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrAdminNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get two-factor settings: %w", err)
+	}
+	if secret != nil {
+		twoFactor.Secret = *secret
+	}
+	if lastStep != nil {
+		twoFactor.LastStep = *lastStep
+	}
+
+	return &twoFactor, nil
+}
+
+func (s *PostgresStore) SetAdminTOTPSecret(ctx context.Context, adminID, secret string) error {
+	tag, err := s.pool.Exec(ctx, `
+		UPDATE admin_users SET totp_secret = $2, two_factor_enabled = FALSE, totp_last_step = NULL
+		WHERE id::text = $1`, adminID, secret)
+	if err != nil {
+		return fmt.Errorf("failed to save TOTP secret: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrAdminNotFound
+	}
+
+	return nil
+}
+
+func (s *PostgresStore) EnableAdminTwoFactor(ctx context.Context, adminID string, step int64, recoveryCodeHashes []string) error {
+	err := s.withTx(ctx, func(tx pgx.Tx) error {
+		tag, err := tx.Exec(ctx, `
+			UPDATE admin_users SET two_factor_enabled = TRUE, totp_last_step = $2
+			WHERE id::text = $1 AND totp_secret IS NOT NULL`, adminID, step)
+		if err != nil {
+			return err
+		}
+		if tag.RowsAffected() == 0 {
+			return ErrAdminNotFound
+		}
+		if _, err := tx.Exec(ctx, `DELETE FROM admin_recovery_codes WHERE admin_id::text = $1`, adminID); err != nil {
+			return err
+		}
+		for _, hash := range recoveryCodeHashes {
+			_, err := tx.Exec(ctx, `
+				INSERT INTO admin_recovery_codes (admin_id, code_hash) VALUES ($1::uuid, $2)`, adminID, hash)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if errors.Is(err, ErrAdminNotFound) {
+		return err
+	}
+	if err != nil {
+		return fmt.Errorf("failed to enable two-factor authentication: %w", err)
+	}
+
+	return nil
+}
+
+func (s *PostgresStore) DisableAdminTwoFactor(ctx context.Context, adminID string) error {
+	err := s.withTx(ctx, func(tx pgx.Tx) error {
+		tag, err := tx.Exec(ctx, `
+			UPDATE admin_users SET totp_secret = NULL, two_factor_enabled = FALSE, totp_last_step = NULL
+			WHERE id::text = $1`, adminID)
+		if err != nil {
+			return err
+		}
+		if tag.RowsAffected() == 0 {
+			return ErrAdminNotFound
+		}
+		_, err = tx.Exec(ctx, `DELETE FROM admin_recovery_codes WHERE admin_id::text = $1`, adminID)
+		return err
+	})
+	if errors.Is(err, ErrAdminNotFound) {
+		return err
+	}
+	if err != nil {
+		return fmt.Errorf("failed to disable two-factor authentication: %w", err)
+	}
+
+	return nil
+}
+
+func (s *PostgresStore) ClaimTOTPStep(ctx context.Context, adminID string, step int64) (bool, error) {
+	tag, err := s.pool.Exec(ctx, `
+		UPDATE admin_users SET totp_last_step = $2
+		WHERE id::text = $1 AND (totp_last_step IS NULL OR totp_last_step < $2)`, adminID, step)
+	if err != nil {
+		return false, fmt.Errorf("failed to claim TOTP step: %w", err)
+	}
+
+	return tag.RowsAffected() == 1, nil
+}
+
+func (s *PostgresStore) UseRecoveryCode(ctx context.Context, adminID, codeHash string) error {
+	tag, err := s.pool.Exec(ctx, `
+		UPDATE admin_recovery_codes SET used_at = NOW()
+		WHERE id = (
+			SELECT id FROM admin_recovery_codes
+			WHERE admin_id::text = $1 AND code_hash = $2 AND used_at IS NULL
+			LIMIT 1
+		) AND used_at IS NULL`, adminID, codeHash)
+	if err != nil {
+		return fmt.Errorf("failed to use recovery code: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrRecoveryCodeNotFound
+	}
+
+	return nil
+}
+
+func (s *PostgresStore) GetSecuritySettings(ctx context.Context) (*models.SecuritySettings, error) {
+	var settings models.SecuritySettings
+	err := s.pool.QueryRow(ctx, `
+		SELECT require_two_factor, updated_at FROM admin_security_settings`).Scan(&settings.RequireTwoFactor, &settings.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return &models.SecuritySettings{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get security settings: %w", err)
+	}
+
+	return &settings, nil
+}
+
+func (s *PostgresStore) UpdateSecuritySettings(ctx context.Context, requireTwoFactor bool) (*models.SecuritySettings, error) {
+	var settings models.SecuritySettings
+	err := s.pool.QueryRow(ctx, `
+		INSERT INTO admin_security_settings (id, require_two_factor) VALUES (TRUE, $1)
+		ON CONFLICT (id) DO UPDATE SET require_two_factor = EXCLUDED.require_two_factor
+		RETURNING require_two_factor, updated_at`, requireTwoFactor).Scan(&settings.RequireTwoFactor, &settings.UpdatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update security settings: %w", err)
+	}
+
+	return &settings, nil
+}
+
+// Login throttle methods
+func (s *PostgresStore) GetLoginThrottles(ctx context.Context, keys []string) ([]models.LoginThrottle, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT key, failures, last_failure_at FROM login_throttles WHERE key = ANY($1)`, keys)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get login throttles: %w", err)
+	}
+	defer rows.Close()
+
+	throttles := []models.LoginThrottle{}
+	for rows.Next() {
+		var t models.LoginThrottle
+		if err := rows.Scan(&t.Key, &t.Failures, &t.LastFailureAt); err != nil {
+			return nil, fmt.Errorf("failed to get login throttles: %w", err)
+		}
+		throttles = append(throttles, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get login throttles: %w", err)
+	}
+
+	return throttles, nil
+}
+
+func (s *PostgresStore) RecordLoginFailure(ctx context.Context, keys []string, now, resetBefore time.Time) error {
+	for _, key := range keys {
+		_, err := s.pool.Exec(ctx, `
+			INSERT INTO login_throttles (key, failures, last_failure_at) VALUES ($1, 1, $2)
+			ON CONFLICT (key) DO UPDATE SET
+				failures = CASE WHEN login_throttles.last_failure_at < $3 THEN 1 ELSE login_throttles.failures + 1 END,
+				last_failure_at = EXCLUDED.last_failure_at`, key, now, resetBefore)
+		if err != nil {
+			return fmt.Errorf("failed to record login failure: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func (s *PostgresStore) ClearLoginThrottle(ctx context.Context, key string) error {
+	if _, err := s.pool.Exec(ctx, `DELETE FROM login_throttles WHERE key = $1`, key); err != nil {
+		return fmt.Errorf("failed to clear login throttle: %w", err)
+	}
+
+	return nil
+}
+
+func (s *PostgresStore) CreateLoginAttempt(ctx context.Context, attempt *models.LoginAttempt) error {
+	_, err := s.pool.Exec(ctx, `
+		INSERT INTO admin_login_attempts (email, ip_address, user_agent, result)
+		VALUES ($1, $2, $3, $4)`, attempt.Email, attempt.IPAddress, attempt.UserAgent, attempt.Result)
+	if err != nil {
+		return fmt.Errorf("failed to record login attempt: %w", err)
+	}
+
+	return nil
+}
+
+func (s *PostgresStore) GetLoginAttempts(ctx context.Context, filter LoginAttemptFilter, page Page) ([]models.LoginAttempt, int, error) {
+	where := "TRUE"
+	var args []any
+	if filter.Email != "" {
+		args = append(args, filter.Email)
+		where += fmt.Sprintf(" AND lower(email) = lower($%d)", len(args))
+	}
+	if filter.IPAddress != "" {
+		args = append(args, filter.IPAddress)
+		where += fmt.Sprintf(" AND ip_address = $%d", len(args))
+	}
+	if filter.Result != "" {
+		args = append(args, filter.Result)
+		where += fmt.Sprintf(" AND result = $%d", len(args))
+	}
+
+	var total int
+	err := s.pool.QueryRow(ctx, `SELECT COUNT(*) FROM admin_login_attempts WHERE `+where, args...).Scan(&total)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count login attempts: %w", err)
+	}
+
+	rows, err := s.pool.Query(ctx, `
+		SELECT id, email, ip_address, COALESCE(user_agent, ''), result, created_at
+		FROM admin_login_attempts WHERE `+where+pageClause(page, LoginAttemptSorts, &args), args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get login attempts: %w", err)
+	}
+	defer rows.Close()
+
+	attempts := []models.LoginAttempt{}
+	for rows.Next() {
+		var a models.LoginAttempt
+		if err := rows.Scan(&a.ID, &a.Email, &a.IPAddress, &a.UserAgent, &a.Result, &a.CreatedAt); err != nil {
+			return nil, 0, fmt.Errorf("failed to get login attempts: %w", err)
+		}
+		attempts = append(attempts, a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("failed to get login attempts: %w", err)
+	}
+
+	return attempts, total, nil
+}
+
+const customerColumns = `id, name, email, phone, notes, created_at, updated_at`
+
+func scanCustomer(row pgx.Row) (*models.Customer, error) {
+	var c models.Customer
+	if err := row.Scan(&c.ID, &c.Name, &c.Email, &c.Phone, &c.Notes, &c.CreatedAt, &c.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// Customer methods
+
+// matchCustomer returns the ID of the customer with email, creating one if
+// there is none.
+func matchCustomer(ctx context.Context, q querier, name, email, phone string) (int, error) {
+	email = customerEmail(email)
+
+	var id int
+	err := q.QueryRow(ctx, `SELECT id FROM customers WHERE email = $1`, email).Scan(&id)
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return id, err
+	}
+
+	// A customer created with the email since the lookup is matched too
+	err = q.QueryRow(ctx, `
+		INSERT INTO customers (name, email, phone) VALUES ($1, $2, $3)
+		ON CONFLICT (email) DO UPDATE SET email = EXCLUDED.email
+		RETURNING id`, name, email, phone).Scan(&id)
+	return id, err
+}
+
+func (s *PostgresStore) GetCustomers(ctx context.Context, filter CustomerFilter, page Page) ([]models.Customer, int, error) {
+	where := "TRUE"
+	var args []any
+	if filter.Search != "" {
+		args = append(args, "%"+escapeLike(filter.Search)+"%")
+		where += fmt.Sprintf(" AND (name ILIKE $%[1]d OR email ILIKE $%[1]d OR phone ILIKE $%[1]d)", len(args))
+	}
+
+	var total int
+	if err := s.pool.QueryRow(ctx, `SELECT COUNT(*) FROM customers WHERE `+where, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count customers: %w", err)
+	}
+
+	rows, err := s.pool.Query(ctx, `SELECT `+customerColumns+` FROM customers WHERE `+where+pageClause(page, CustomerSorts, &args), args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get customers: %w", err)
+	}
+	defer rows.Close()
+
+	customers := []models.Customer{}
+	for rows.Next() {
+		c, err := scanCustomer(rows)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to get customers: %w", err)
+		}
+		customers = append(customers, *c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("failed to get customers: %w", err)
+	}
+
+	return customers, total, nil
+}
+
+func (s *PostgresStore) GetCustomer(ctx context.Context, customerID int) (*models.Customer, error) {
+	customer, err := scanCustomer(s.pool.QueryRow(ctx,
+		`SELECT `+customerColumns+` FROM customers WHERE id = $1`, customerID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrCustomerNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get customer: %w", err)
+	}
+
+	return customer, nil
+}
+
+func (s *PostgresStore) UpdateCustomer(ctx context.Context, customerID int, req *models.CustomerRequest) (*models.Customer, error) {
+	customer, err := scanCustomer(s.pool.QueryRow(ctx, `
+		UPDATE customers SET name = $2, email = $3, phone = $4, notes = $5
+		WHERE id = $1
+		RETURNING `+customerColumns,
+		customerID, req.Name, customerEmail(req.Email), req.Phone, req.Notes))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrCustomerNotFound
+	}
+	if isUniqueViolation(err) {
+		return nil, ErrCustomerExists
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to update customer: %w", err)
+	}
+
+	return customer, nil
+}
+
+func (s *PostgresStore) MergeCustomers(ctx context.Context, targetID, sourceID int) (*models.Customer, error) {
+	var customer *models.Customer
+	err := s.withTx(ctx, func(tx pgx.Tx) error {
+		target, err := scanCustomer(tx.QueryRow(ctx,
+			`SELECT `+customerColumns+` FROM customers WHERE id = $1 FOR UPDATE`, targetID))
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrCustomerNotFound
+		}
+		if err != nil {
+			return err
+		}
+		source, err := scanCustomer(tx.QueryRow(ctx,
+			`SELECT `+customerColumns+` FROM customers WHERE id = $1 FOR UPDATE`, sourceID))
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrCustomerNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		for _, table := range []string{"appointments", "classes"} {
+			_, err := tx.Exec(ctx, `UPDATE `+table+` SET customer_id = $1 WHERE customer_id = $2`, targetID, sourceID)
+			if err != nil {
+				return err
+			}
+		}
+		if _, err := tx.Exec(ctx, `DELETE FROM customers WHERE id = $1`, sourceID); err != nil {
+			return err
+		}
+
+		phone := target.Phone
+		if phone == "" {
+			phone = source.Phone
+		}
+		customer, err = scanCustomer(tx.QueryRow(ctx, `
+			UPDATE customers SET phone = $2, notes = $3 WHERE id = $1
+			RETURNING `+customerColumns, targetID, phone, mergeNotes(target.Notes, source.Notes)))
+		return err
+	})
+	if errors.Is(err, ErrCustomerNotFound) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to merge customers: %w", err)
+	}
+
+	return customer, nil
+}
+
+const idempotencyKeyColumns = `id, scope, key, request_hash, status_code, response_body, created_at, expires_at`
+
+func scanIdempotencyKey(row pgx.Row) (*models.IdempotencyKey, error) {
+	var k models.IdempotencyKey
+	err := row.Scan(&k.ID, &k.Scope, &k.Key, &k.RequestHash, &k.StatusCode, &k.ResponseBody, &k.CreatedAt, &k.ExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+	return &k, nil
+}
+
+// Idempotency key methods
+
+// ClaimIdempotencyKey inserts the key, taking over an expired one in the
+// same statement. When an unexpired key holds the spot nothing is returned,
+// and it is read instead.
+func (s *PostgresStore) ClaimIdempotencyKey(ctx context.Context, key *models.IdempotencyKey, now time.Time) (*models.IdempotencyKey, bool, error) {
+	claimed, err := scanIdempotencyKey(s.pool.QueryRow(ctx, `
+		INSERT INTO idempotency_keys (scope, key, request_hash, created_at, expires_at) VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (scope, key) DO UPDATE SET
+			request_hash = EXCLUDED.request_hash, status_code = 0, response_body = '',
+			created_at = EXCLUDED.created_at, expires_at = EXCLUDED.expires_at
+		WHERE idempotency_keys.expires_at <= $4
+		RETURNING `+idempotencyKeyColumns, key.Scope, key.Key, key.RequestHash, now, key.ExpiresAt))
+	if err == nil {
+		return claimed, true, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return nil, false, fmt.Errorf("failed to claim idempotency key: %w", err)
+	}
+
+	existing, err := scanIdempotencyKey(s.pool.QueryRow(ctx, `
+		SELECT `+idempotencyKeyColumns+` FROM idempotency_keys WHERE scope = $1 AND key = $2`, key.Scope, key.Key))
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get idempotency key: %w", err)
+	}
+
+	return existing, false, nil
+}
+
+func (s *PostgresStore) CompleteIdempotencyKey(ctx context.Context, keyID, statusCode int, responseBody string) error {
+	_, err := s.pool.Exec(ctx, `
+		UPDATE idempotency_keys SET status_code = $2, response_body = $3 WHERE id = $1`, keyID, statusCode, responseBody)
+	if err != nil {
+		return fmt.Errorf("failed to complete idempotency key: %w", err)
+	}
+
+	return nil
+}
+
+func (s *PostgresStore) ReleaseIdempotencyKey(ctx context.Context, keyID int) error {
+	if _, err := s.pool.Exec(ctx, `DELETE FROM idempotency_keys WHERE id = $1`, keyID); err != nil {
+		return fmt.Errorf("failed to release idempotency key: %w", err)
+	}
+
+	return nil
+}
+
+func (s *PostgresStore) DeleteExpiredIdempotencyKeys(ctx context.Context, now time.Time) (int, error) {
+	tag, err := s.pool.Exec(ctx, `DELETE FROM idempotency_keys WHERE expires_at <= $1`, now)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired idempotency keys: %w", err)
+	}
+
+	return int(tag.RowsAffected()), nil
+}
+
+// isUniqueViolation reports whether err is a Postgres unique_violation.
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
+
+// isForeignKeyViolation reports whether err is a Postgres foreign_key_violation.
+func isForeignKeyViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23503"
+}
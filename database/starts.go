@@ -0,0 +1,67 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// StartTimeBackfill reports what BackfillStartTimes did.
+type StartTimeBackfill struct {
+	Checked int
+	Updated int
+	// Invalid are the IDs of appointments whose time isn't a clock time.
+	// They are left without a start.
+	Invalid []int
+}
+
+// BackfillStartTimes fills in starts_at for appointments booked before it
+// was stored, with the moment startTime gives for their date and clock
+// time. startTime reads them in the studio's time zone, which SQL alone
+// doesn't know, so this runs after the migrations rather than in one.
+func (m *Migrator) BackfillStartTimes(ctx context.Context, startTime func(date, clock string) (time.Time, error)) (StartTimeBackfill, error) {
+	var report StartTimeBackfill
+	err := pgx.BeginFunc(ctx, m.conn, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, `
+			SELECT id, to_char(appointment_date, 'YYYY-MM-DD'), appointment_time
+			FROM appointments WHERE starts_at IS NULL ORDER BY id FOR UPDATE`)
+		if err != nil {
+			return err
+		}
+		starts := map[int]time.Time{}
+		for rows.Next() {
+			var id int
+			var date, clock string
+			if err := rows.Scan(&id, &date, &clock); err != nil {
+				rows.Close()
+				return err
+			}
+			report.Checked++
+
+			start, err := startTime(date, clock)
+			if err != nil {
+				report.Invalid = append(report.Invalid, id)
+				continue
+			}
+			starts[id] = start
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		for id, start := range starts {
+			if _, err := tx.Exec(ctx, `UPDATE appointments SET starts_at = $2 WHERE id = $1`, id, start); err != nil {
+				return err
+			}
+		}
+		report.Updated = len(starts)
+		return nil
+	})
+	if err != nil {
+		return StartTimeBackfill{}, fmt.Errorf("failed to backfill appointment start times: %w", err)
+	}
+	return report, nil
+}
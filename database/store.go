@@ -0,0 +1,658 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"mumuni_backend/config"
+	"mumuni_backend/models"
+	"strings"
+	"time"
+)
+
+// Errors shared by every Store implementation so handlers can map them to
+// HTTP responses without caring which backend produced them.
+var (
+	ErrAppointmentNotFound  = errors.New("appointment not found")
+	ErrClassNotFound        = errors.New("class not found")
+	ErrOfferingNotFound     = errors.New("class offering not found")
+	ErrOfferingExists       = errors.New("class offering already exists")
+	ErrCohortNotFound       = errors.New("cohort not found")
+	ErrCohortFull           = errors.New("cohort is full")
+	ErrWaitlistNotFound     = errors.New("waitlist entry not found")
+	ErrNotificationNotFound = errors.New("notification not found")
+	ErrPreferenceNotFound   = errors.New("notification preference not found")
+	ErrJobNotFound          = errors.New("job not found")
+	ErrWebhookNotFound      = errors.New("webhook subscription not found")
+	ErrDeliveryNotFound     = errors.New("webhook delivery not found")
+	ErrArtistNotFound       = errors.New("artist not found")
+	ErrServiceNotFound      = errors.New("service not found")
+	ErrServiceExists        = errors.New("service already exists")
+	ErrAdminNotFound        = errors.New("admin not found")
+	ErrAdminExists          = errors.New("admin already exists")
+	ErrLastOwner            = errors.New("the last owner can't be given another role")
+	ErrInviteNotFound       = errors.New("admin invite not found")
+	ErrSessionNotFound      = errors.New("admin session not found")
+	ErrResetNotFound        = errors.New("password reset not found")
+	ErrRecoveryCodeNotFound = errors.New("recovery code not found")
+	ErrCustomerNotFound     = errors.New("customer not found")
+	ErrCustomerExists       = errors.New("customer already exists")
+	ErrInvalidStatus        = errors.New("invalid status")
+	ErrBookingClosed        = errors.New("booking is no longer active")
+)
+
+// BookingCheck is called with the active (pending or confirmed) appointments
+// already on the requested date and returns an error to refuse the booking.
+// It may also fill in request fields that depend on those appointments, such
+// as ArtistID. Backends that support it hold a per-date lock between the
+// check and the insert so concurrent requests can't both take the same slot.
+type BookingCheck func(existing []models.Appointment) error
+
+// AppointmentFilter narrows GetAppointments. Zero values match everything.
+// Email matches regardless of case, and Search matches a substring of the
+// name, email or message regardless of case.
+type AppointmentFilter struct {
+	ArtistID   *int
+	CustomerID *int
+	Date       string
+	// DateFrom and DateTo keep appointments on or between these YYYY-MM-DD
+	// dates.
+	DateFrom string
+	DateTo   string
+	Status   string
+	Service  string
+	Email    string
+	Phone    string
+	Search   string
+}
+
+// ClassFilter narrows GetClasses. Zero values match everything. Email and
+// Search work as in AppointmentFilter, with Search looking at the name,
+// email and goals.
+type ClassFilter struct {
+	Status     string
+	ClassType  string
+	CohortID   *int
+	CustomerID *int
+	Email      string
+	Phone      string
+	// CreatedFrom and CreatedTo keep enrollments made on or between these
+	// YYYY-MM-DD dates (UTC).
+	CreatedFrom string
+	CreatedTo   string
+	Search      string
+}
+
+// CustomerFilter narrows GetCustomers. Search matches a substring of the
+// name, email or phone regardless of case.
+type CustomerFilter struct {
+	Search string
+}
+
+// Columns GetAppointments, GetClasses, GetCustomers, GetLoginAttempts and
+// GetWebhookDeliveries can sort by. The first is the default.
+var (
+	AppointmentSorts     = []string{"created_at", "updated_at", "appointment_date", "name", "status", "service"}
+	ClassSorts           = []string{"created_at", "updated_at", "name", "status", "class_type"}
+	CustomerSorts        = []string{"created_at", "updated_at", "name", "email"}
+	LoginAttemptSorts    = []string{"created_at"}
+	WebhookDeliverySorts = []string{"created_at", "updated_at"}
+)
+
+// sortColumn returns page.Sort if it is one of sorts, or else the default.
+func sortColumn(page Page, sorts []string) string {
+	for _, column := range sorts {
+		if column == page.Sort {
+			return column
+		}
+	}
+	return sorts[0]
+}
+
+// Page selects part of a sorted list. Sort is one of the list's sortable
+// columns; rows that tie are ordered by ID. A Limit of zero returns every row from Offset on.
+type Page struct {
+	Sort       string
+	Descending bool
+	Limit      int
+	Offset     int
+}
+
+// StatusChange moves an appointment or enrollment to Status, following the
+// rules in the lifecycle package. AdminID is the admin making the change, or
+// nil when the system makes it.
+type StatusChange struct {
+	Status  string
+	Reason  string
+	AdminID *string
+}
+
+// CohortFilter narrows GetCohorts. Zero values match everything.
+type CohortFilter struct {
+	OfferingID *int
+	ActiveOnly bool
+	// StartsFrom keeps cohorts starting on or after this YYYY-MM-DD date.
+	StartsFrom string
+}
+
+// WaitlistFilter narrows GetWaitlistEntries. Zero values match everything.
+type WaitlistFilter struct {
+	Kind          string
+	Status        string
+	CohortID      *int
+	ClassID       *int
+	AppointmentID *int
+	Date          string
+}
+
+// NotificationFilter narrows GetNotifications. Zero values match everything.
+type NotificationFilter struct {
+	Channel           string
+	Status            string
+	ResourceType      string
+	ResourceID        *int
+	ProviderMessageID string
+}
+
+// WebhookDeliveryFilter narrows GetWebhookDeliveries. Zero values match
+// everything.
+type WebhookDeliveryFilter struct {
+	SubscriptionID *int
+	Event          string
+	Status         string
+}
+
+// JobFilter narrows GetJobs. Zero values match everything.
+type JobFilter struct {
+	Kind         string
+	Status       string
+	ResourceType string
+	ResourceID   *int
+}
+
+// Store is the persistence layer used by the HTTP handlers.
+type Store interface {
+	// Appointment methods. manageTokenHash is the hash of the token the
+	// customer manages the booking with.
+	CreateAppointment(ctx context.Context, req *models.AppointmentRequest, manageTokenHash string, check BookingCheck) (*models.Appointment, error)
+	// GetAppointments returns one page of the matching appointments along
+	// with how many match in total.
+	GetAppointments(ctx context.Context, filter AppointmentFilter, page Page) ([]models.Appointment, int, error)
+	GetAppointment(ctx context.Context, appointmentID int) (*models.Appointment, error)
+	GetAppointmentByManageToken(ctx context.Context, tokenHash string) (*models.Appointment, error)
+	GetActiveAppointments(ctx context.Context, fromDate, toDate string) ([]models.Appointment, error)
+	UpdateAppointmentStatus(ctx context.Context, appointmentID int, change StatusChange) (*models.Appointment, error)
+	// RescheduleAppointment moves an active appointment to req.Date, req.Time
+	// and req.ArtistID. check sees the new date's active appointments other
+	// than this one, under the same per-date lock as CreateAppointment.
+	RescheduleAppointment(ctx context.Context, appointmentID int, req *models.AppointmentRequest, check BookingCheck) (*models.Appointment, error)
+	// UpdateAppointmentChannels replaces the notification channels chosen
+	// for one appointment.
+	UpdateAppointmentChannels(ctx context.Context, appointmentID int, channels []string) (*models.Appointment, error)
+
+	// Service catalog methods
+	CreateService(ctx context.Context, req *models.ServiceRequest) (*models.Service, error)
+	GetServices(ctx context.Context, activeOnly bool) ([]models.Service, error)
+	GetService(ctx context.Context, serviceID int) (*models.Service, error)
+	UpdateService(ctx context.Context, serviceID int, req *models.ServiceRequest) (*models.Service, error)
+	DeleteService(ctx context.Context, serviceID int) error
+
+	// Artist methods
+	CreateArtist(ctx context.Context, req *models.ArtistRequest) (*models.Artist, error)
+	GetArtists(ctx context.Context, activeOnly bool) ([]models.Artist, error)
+	GetArtist(ctx context.Context, artistID int) (*models.Artist, error)
+	UpdateArtist(ctx context.Context, artistID int, req *models.ArtistRequest) (*models.Artist, error)
+	DeleteArtist(ctx context.Context, artistID int) error
+
+	// Class catalog methods
+	CreateClassOffering(ctx context.Context, req *models.ClassOfferingRequest) (*models.ClassOffering, error)
+	GetClassOfferings(ctx context.Context, activeOnly bool) ([]models.ClassOffering, error)
+	GetClassOffering(ctx context.Context, offeringID int) (*models.ClassOffering, error)
+	UpdateClassOffering(ctx context.Context, offeringID int, req *models.ClassOfferingRequest) (*models.ClassOffering, error)
+	DeleteClassOffering(ctx context.Context, offeringID int) error
+
+	// Cohort methods
+	CreateCohort(ctx context.Context, req *models.CohortRequest) (*models.Cohort, error)
+	GetCohorts(ctx context.Context, filter CohortFilter) ([]models.Cohort, error)
+	GetCohort(ctx context.Context, cohortID int) (*models.Cohort, error)
+	UpdateCohort(ctx context.Context, cohortID int, req *models.CohortRequest) (*models.Cohort, error)
+	DeleteCohort(ctx context.Context, cohortID int) error
+
+	// Class methods
+	// CreateClass allocates a seat when req.CohortID is set. If the cohort
+	// is full the enrollment is stored as "waitlisted" when waitlistWhenFull
+	// is true, and ErrCohortFull is returned otherwise.
+	CreateClass(ctx context.Context, req *models.ClassRequest, manageTokenHash string, waitlistWhenFull bool) (*models.Class, error)
+	// GetClasses returns one page of the matching enrollments along with how
+	// many match in total.
+	GetClasses(ctx context.Context, filter ClassFilter, page Page) ([]models.Class, int, error)
+	GetClass(ctx context.Context, classID int) (*models.Class, error)
+	GetClassByManageToken(ctx context.Context, tokenHash string) (*models.Class, error)
+	// ChangeClassCohort moves an active enrollment to another cohort,
+	// returning ErrCohortFull when that cohort has no seat left.
+	ChangeClassCohort(ctx context.Context, classID, cohortID int) (*models.Class, error)
+	// UpdateClassStatus moves an enrollment to change.Status. Moving it off
+	// the waitlist takes a seat, returning ErrCohortFull when its cohort has
+	// none left, and promotes its waiting waitlist entry.
+	UpdateClassStatus(ctx context.Context, classID int, change StatusChange) (*models.Class, error)
+	// UpdateClassChannels replaces the notification channels chosen for one
+	// enrollment.
+	UpdateClassChannels(ctx context.Context, classID int, channels []string) (*models.Class, error)
+
+	// Status history methods. Entries are returned oldest first, and
+	// ErrAppointmentNotFound or ErrClassNotFound is returned when the
+	// resource doesn't exist.
+	GetStatusHistory(ctx context.Context, resourceType string, resourceID int) ([]models.StatusHistory, error)
+
+	// Waitlist methods. Entries are returned oldest first, which is the
+	// order seats and slots are handed out in.
+	CreateWaitlistEntry(ctx context.Context, entry *models.WaitlistEntry) (*models.WaitlistEntry, error)
+	GetWaitlistEntries(ctx context.Context, filter WaitlistFilter) ([]models.WaitlistEntry, error)
+	GetWaitlistEntry(ctx context.Context, entryID int) (*models.WaitlistEntry, error)
+	GetWaitlistEntryByToken(ctx context.Context, tokenHash string) (*models.WaitlistEntry, error)
+	// UpdateWaitlistEntry saves an entry's status, hold expiry and appointment.
+	UpdateWaitlistEntry(ctx context.Context, entry *models.WaitlistEntry) (*models.WaitlistEntry, error)
+	// PromoteWaitlistedClass moves a class entry's enrollment off the
+	// waitlist with change and saves the entry's status and hold expiry
+	// together. Unless overfill is true it returns ErrCohortFull, changing
+	// neither, when the enrollment's cohort has no seat left.
+	PromoteWaitlistedClass(ctx context.Context, entry *models.WaitlistEntry, change StatusChange, overfill bool) (*models.Class, *models.WaitlistEntry, error)
+
+	// Notification outbox methods
+	CreateNotification(ctx context.Context, notification *models.Notification) (*models.Notification, error)
+	// ClaimNotifications returns up to limit pending notifications due by
+	// now, counting an attempt on each and pushing its next attempt to
+	// now+lease so other instances skip it while it is being delivered.
+	ClaimNotifications(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]models.Notification, error)
+	// GetNotifications returns notifications oldest first.
+	GetNotifications(ctx context.Context, filter NotificationFilter) ([]models.Notification, error)
+	// UpdateNotification saves a notification's status, last error, next
+	// attempt, sent time, provider message ID and delivery time.
+	UpdateNotification(ctx context.Context, notification *models.Notification) (*models.Notification, error)
+
+	// Notification preference methods. Emails are matched case-insensitively.
+	GetNotificationPreference(ctx context.Context, email string) (*models.NotificationPreference, error)
+	// SaveNotificationPreference creates or replaces a customer's channels.
+	SaveNotificationPreference(ctx context.Context, email string, channels []string) (*models.NotificationPreference, error)
+
+	// Job methods. Jobs are returned in the order they are due.
+	CreateJob(ctx context.Context, job *models.Job) (*models.Job, error)
+	GetJobs(ctx context.Context, filter JobFilter) ([]models.Job, error)
+	// ClaimJobs returns up to limit pending jobs due by now, counting an
+	// attempt on each and pushing it back to now+lease so other instances
+	// skip it while it runs.
+	ClaimJobs(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]models.Job, error)
+	// UpdateJob saves a job's status, last error and run time.
+	UpdateJob(ctx context.Context, job *models.Job) (*models.Job, error)
+	// CancelJobs cancels the pending jobs of an appointment or class.
+	CancelJobs(ctx context.Context, resourceType string, resourceID int) error
+
+	// Webhook subscription methods. The secret is only read through
+	// GetWebhookSecret, and an update with an empty secret keeps the old one.
+	CreateWebhookSubscription(ctx context.Context, req *models.WebhookSubscriptionRequest) (*models.WebhookSubscription, error)
+	GetWebhookSubscriptions(ctx context.Context, activeOnly bool) ([]models.WebhookSubscription, error)
+	GetWebhookSubscription(ctx context.Context, subscriptionID int) (*models.WebhookSubscription, error)
+	GetWebhookSecret(ctx context.Context, subscriptionID int) (string, error)
+	UpdateWebhookSubscription(ctx context.Context, subscriptionID int, req *models.WebhookSubscriptionRequest) (*models.WebhookSubscription, error)
+	// DeleteWebhookSubscription deletes the subscription and its deliveries.
+	DeleteWebhookSubscription(ctx context.Context, subscriptionID int) error
+
+	// Webhook delivery methods
+	CreateWebhookDelivery(ctx context.Context, delivery *models.WebhookDelivery) (*models.WebhookDelivery, error)
+	GetWebhookDeliveries(ctx context.Context, filter WebhookDeliveryFilter, page Page) ([]models.WebhookDelivery, int, error)
+	GetWebhookDelivery(ctx context.Context, deliveryID int) (*models.WebhookDelivery, error)
+	// ClaimWebhookDeliveries works like ClaimNotifications.
+	ClaimWebhookDeliveries(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]models.WebhookDelivery, error)
+	// UpdateWebhookDelivery saves a delivery's status, attempts, last error,
+	// response status, next attempt and delivery time.
+	UpdateWebhookDelivery(ctx context.Context, delivery *models.WebhookDelivery) (*models.WebhookDelivery, error)
+
+	// Admin methods
+	CreateAdmin(ctx context.Context, email, passwordHash, name, role string, artistID *int) (*models.AdminUser, error)
+	GetAdmins(ctx context.Context) ([]models.AdminUser, error)
+	GetAdmin(ctx context.Context, adminID string) (*models.AdminUser, error)
+	GetAdminByEmail(ctx context.Context, email string) (*models.AdminUser, error)
+	// UpdateAdminRole sets an admin's role and linked artist. It gives
+	// ErrLastOwner rather than take the role from the only owner; the owners
+	// are locked while they are counted, so two owners can't demote each
+	// other at once.
+	UpdateAdminRole(ctx context.Context, adminID, role string, artistID *int) (*models.AdminUser, error)
+
+	// Admin invite methods. tokenHash is the hash of the invite token.
+	CreateAdminInvite(ctx context.Context, invite *models.AdminInvite, tokenHash string) (*models.AdminInvite, error)
+	GetAdminInvites(ctx context.Context) ([]models.AdminInvite, error)
+	// GetAdminInviteByToken returns a pending, unexpired invite, or
+	// ErrInviteNotFound.
+	GetAdminInviteByToken(ctx context.Context, tokenHash string) (*models.AdminInvite, error)
+	// RevokeAdminInvite closes a pending invite.
+	RevokeAdminInvite(ctx context.Context, inviteID int) (*models.AdminInvite, error)
+	// RedeemAdminInvite accepts a pending, unexpired invite and creates its
+	// admin, so each invite makes at most one admin.
+	RedeemAdminInvite(ctx context.Context, tokenHash, passwordHash, name string) (*models.AdminUser, error)
+	GetAdminPasswordHash(ctx context.Context, email string) (string, error)
+	UpdateAdminPassword(ctx context.Context, adminID, passwordHash string) error
+
+	// Password reset methods. tokenHash is the hash of the emailed token.
+	// CreatePasswordReset returns the ID of the new reset.
+	CreatePasswordReset(ctx context.Context, adminID, tokenHash string, expiresAt time.Time) (int, error)
+	// ResetAdminPassword uses an unused, unexpired reset to set its admin's
+	// password, closes the admin's other resets and ends all their
+	// sessions, or gives ErrResetNotFound.
+	ResetAdminPassword(ctx context.Context, tokenHash, passwordHash string) (*models.AdminUser, error)
+
+	// Admin session methods. refreshTokenHash is the hash of the session's
+	// refresh token and access its current access token.
+	CreateAdminSession(ctx context.Context, session *models.AdminSession, refreshTokenHash string, access AccessToken) (*models.AdminSession, error)
+	// GetAdminSessions returns adminID's sessions that are neither revoked
+	// nor expired, most recently used first.
+	GetAdminSessions(ctx context.Context, adminID string) ([]models.AdminSession, error)
+	// RotateAdminSession swaps an active session's refresh token for a new
+	// one that expires at expiresAt, and its access token for access,
+	// revoking the one it replaces. A refresh token works only once; an
+	// unknown, used, revoked or expired one gives ErrSessionNotFound. A used
+	// one must have been copied, so it also ends the session it belonged to.
+	RotateAdminSession(ctx context.Context, refreshTokenHash, newRefreshTokenHash string, access AccessToken, expiresAt time.Time) (*models.AdminSession, error)
+	// RevokeAdminSession ends one of adminID's active sessions and revokes
+	// its access token.
+	RevokeAdminSession(ctx context.Context, adminID, sessionID string) error
+	// RevokeAdminSessions ends all of adminID's active sessions except
+	// exceptSessionID, which may be empty, and revokes their access tokens.
+	RevokeAdminSessions(ctx context.Context, adminID, exceptSessionID string) error
+	// IsTokenRevoked reports whether the access token with ID jti was
+	// revoked. Revocations are forgotten once the token has expired.
+	IsTokenRevoked(ctx context.Context, jti string) (bool, error)
+
+	// Two-factor methods
+	GetAdminTwoFactor(ctx context.Context, adminID string) (*TwoFactor, error)
+	// SetAdminTOTPSecret saves a secret that is waiting to be confirmed,
+	// leaving two-factor authentication off until EnableAdminTwoFactor.
+	SetAdminTOTPSecret(ctx context.Context, adminID, secret string) error
+	// EnableAdminTwoFactor turns two-factor authentication on with the
+	// saved secret, records step as used and replaces the admin's recovery
+	// codes with recoveryCodeHashes.
+	EnableAdminTwoFactor(ctx context.Context, adminID string, step int64, recoveryCodeHashes []string) error
+	// DisableAdminTwoFactor turns two-factor authentication off, forgetting
+	// the secret and the recovery codes.
+	DisableAdminTwoFactor(ctx context.Context, adminID string) error
+	// ClaimTOTPStep records step as the admin's last used time step, and
+	// reports false if it or a later one was already used, so each code
+	// works once.
+	ClaimTOTPStep(ctx context.Context, adminID string, step int64) (bool, error)
+	// UseRecoveryCode marks one of the admin's unused recovery codes used,
+	// or gives ErrRecoveryCodeNotFound.
+	UseRecoveryCode(ctx context.Context, adminID, codeHash string) error
+	GetSecuritySettings(ctx context.Context) (*models.SecuritySettings, error)
+	UpdateSecuritySettings(ctx context.Context, requireTwoFactor bool) (*models.SecuritySettings, error)
+
+	// Login throttle methods. Keys name an email or IP address, as in
+	// "email:owner@mumuni.com" or "ip:203.0.113.7".
+	// GetLoginThrottles returns the throttles there are for keys.
+	GetLoginThrottles(ctx context.Context, keys []string) ([]models.LoginThrottle, error)
+	// RecordLoginFailure counts a failed login against each of keys at now.
+	// A count whose last failure was before resetBefore starts over.
+	RecordLoginFailure(ctx context.Context, keys []string, now, resetBefore time.Time) error
+	ClearLoginThrottle(ctx context.Context, key string) error
+	CreateLoginAttempt(ctx context.Context, attempt *models.LoginAttempt) error
+	// GetLoginAttempts returns a page of attempts sorted by when they were
+	// made, with the number of attempts matching filter.
+	GetLoginAttempts(ctx context.Context, filter LoginAttemptFilter, page Page) ([]models.LoginAttempt, int, error)
+
+	// Customer methods. CreateAppointment and CreateClass link each booking
+	// to the customer with its email, creating one if there is none. Phone
+	// numbers aren't matched on: anyone can type someone else's, and the
+	// booking would join that customer's history.
+	GetCustomers(ctx context.Context, filter CustomerFilter, page Page) ([]models.Customer, int, error)
+	GetCustomer(ctx context.Context, customerID int) (*models.Customer, error)
+	// UpdateCustomer gives ErrCustomerExists if another customer has the
+	// email.
+	UpdateCustomer(ctx context.Context, customerID int, req *models.CustomerRequest) (*models.Customer, error)
+	// MergeCustomers moves sourceID's appointments and enrollments to
+	// targetID and deletes sourceID. The target keeps its details, taking
+	// the source's phone if it has none, and the source's notes are added
+	// to its own.
+	MergeCustomers(ctx context.Context, targetID, sourceID int) (*models.Customer, error)
+
+	// Idempotency key methods.
+	// ClaimIdempotencyKey stores key, unfinished, unless a key that hasn't
+	// expired at now is already stored for its scope. It returns the stored
+	// key and whether it was claimed by this call; an expired key is
+	// replaced.
+	ClaimIdempotencyKey(ctx context.Context, key *models.IdempotencyKey, now time.Time) (*models.IdempotencyKey, bool, error)
+	// CompleteIdempotencyKey stores the response to the request that
+	// claimed a key.
+	CompleteIdempotencyKey(ctx context.Context, keyID, statusCode int, responseBody string) error
+	// ReleaseIdempotencyKey deletes a key, so it can be claimed again.
+	ReleaseIdempotencyKey(ctx context.Context, keyID int) error
+	// DeleteExpiredIdempotencyKeys deletes keys that expired by now and
+	// returns how many there were.
+	DeleteExpiredIdempotencyKeys(ctx context.Context, now time.Time) (int, error)
+}
+
+// TwoFactor is an admin's TOTP set-up. Secret is empty until one is set up,
+// and LastStep is the last time step a code was accepted for.
+type TwoFactor struct {
+	Secret   string
+	Enabled  bool
+	LastStep int64
+}
+
+// LoginAttemptFilter narrows GetLoginAttempts. Zero values match
+// everything. Email matches regardless of case.
+type LoginAttemptFilter struct {
+	Email     string
+	IPAddress string
+	Result    string
+}
+
+// AccessToken identifies an issued access token by its ID (jti) and expiry.
+type AccessToken struct {
+	JTI       string
+	ExpiresAt time.Time
+}
+
+var (
+	_ Store = (*Database)(nil)
+	_ Store = (*PostgresStore)(nil)
+	_ Store = (*MemoryStore)(nil)
+)
+
+// NewStore returns the Store selected by cfg.DatabaseDriver.
+func NewStore(cfg *config.Config) (Store, error) {
+	switch cfg.DatabaseDriver {
+	case "", "supabase":
+		return NewDatabase(cfg)
+	case "postgres":
+		return NewPostgresStore(context.Background(), cfg)
+	case "memory":
+		return NewMemoryStore(), nil
+	default:
+		return nil, fmt.Errorf("unknown DATABASE_DRIVER: %s", cfg.DatabaseDriver)
+	}
+}
+
+// validStatuses lists the statuses accepted for appointments.
+var validStatuses = map[string]bool{
+	"pending":   true,
+	"confirmed": true,
+	"cancelled": true,
+	"completed": true,
+}
+
+// classStatuses adds "waitlisted" for enrollments in a full cohort.
+var classStatuses = map[string]bool{
+	"pending":    true,
+	"confirmed":  true,
+	"cancelled":  true,
+	"completed":  true,
+	"waitlisted": true,
+}
+
+// activeStatuses are the appointment statuses that hold a calendar slot, and
+// the enrollment statuses that hold a cohort seat.
+var activeStatuses = []string{"pending", "confirmed"}
+
+func isActiveStatus(status string) bool {
+	for _, s := range activeStatuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+// appointmentStart is when req starts, or nil if it wasn't worked out.
+func appointmentStart(req *models.AppointmentRequest) *time.Time {
+	if req.StartsAt.IsZero() {
+		return nil
+	}
+	start := req.StartsAt
+	return &start
+}
+
+// bookingChannels is channels as stored on a booking, where none is an
+// empty list rather than NULL.
+func bookingChannels(channels []string) []string {
+	if channels == nil {
+		return []string{}
+	}
+	return append([]string(nil), channels...)
+}
+
+// preferenceKey is the form customer emails are stored in as notification
+// preference keys.
+func preferenceKey(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// datesBetween lists every YYYY-MM-DD date from fromDate to toDate inclusive.
+func datesBetween(fromDate, toDate string) ([]string, error) {
+	from, err := time.Parse("2006-01-02", fromDate)
+	if err != nil {
+		return nil, fmt.Errorf("invalid date: %s", fromDate)
+	}
+	to, err := time.Parse("2006-01-02", toDate)
+	if err != nil {
+		return nil, fmt.Errorf("invalid date: %s", toDate)
+	}
+
+	var dates []string
+	for d := from; !d.After(to); d = d.AddDate(0, 0, 1) {
+		dates = append(dates, d.Format("2006-01-02"))
+	}
+	return dates, nil
+}
+
+// dayAfter returns the YYYY-MM-DD date after date, which turns an inclusive
+// date bound into an exclusive timestamp bound.
+func dayAfter(date string) (string, error) {
+	d, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		return "", fmt.Errorf("invalid date: %s", date)
+	}
+	return d.AddDate(0, 0, 1).Format("2006-01-02"), nil
+}
+
+// escapeLike escapes the LIKE wildcards in s so that it matches literally.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// customerEmail is how customer emails are stored and matched.
+func customerEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// mergeNotes returns the notes of a customer merged with another's.
+func mergeNotes(target, source *string) *string {
+	switch {
+	case source == nil || strings.TrimSpace(*source) == "":
+		return target
+	case target == nil || strings.TrimSpace(*target) == "":
+		return source
+	}
+	merged := *target + "\n\n" + *source
+	return &merged
+}
+
+// markExpired reports pending invites past their expiry as "expired".
+func markExpired(invites []models.AdminInvite, now time.Time) {
+	for i := range invites {
+		if invites[i].Status == "pending" && !invites[i].ExpiresAt.After(now) {
+			invites[i].Status = "expired"
+		}
+	}
+}
+
+// serviceActive returns the active flag from a service request, defaulting to true.
+func serviceActive(req *models.ServiceRequest) bool {
+	return req.Active == nil || *req.Active
+}
+
+// offeringActive returns the active flag from a class offering request, defaulting to true.
+func offeringActive(req *models.ClassOfferingRequest) bool {
+	return req.Active == nil || *req.Active
+}
+
+// cohortActive returns the active flag from a cohort request, defaulting to true.
+func cohortActive(req *models.CohortRequest) bool {
+	return req.Active == nil || *req.Active
+}
+
+// enrollmentStatus is the status a new enrollment gets given the seats
+// already taken in its cohort.
+func enrollmentStatus(capacity, taken int, waitlistWhenFull bool) (string, error) {
+	if taken < capacity {
+		return "pending", nil
+	}
+	if waitlistWhenFull {
+		return "waitlisted", nil
+	}
+	return "", ErrCohortFull
+}
+
+// webhookActive returns the active flag from a webhook subscription request, defaulting to true.
+func webhookActive(req *models.WebhookSubscriptionRequest) bool {
+	return req.Active == nil || *req.Active
+}
+
+// artistActive returns the active flag from an artist request, defaulting to true.
+func artistActive(req *models.ArtistRequest) bool {
+	return req.Active == nil || *req.Active
+}
+
+func validateStatus(status string) error {
+	if !validStatuses[status] {
+		return fmt.Errorf("%w: %s", ErrInvalidStatus, status)
+	}
+	return nil
+}
+
+func validateClassStatus(status string) error {
+	if !classStatuses[status] {
+		return fmt.Errorf("%w: %s", ErrInvalidStatus, status)
+	}
+	return nil
+}
+
+// statusReason is the reason stored with a status change, nil when none was given.
+func statusReason(change StatusChange) *string {
+	reason := strings.TrimSpace(change.Reason)
+	if reason == "" {
+		return nil
+	}
+	return &reason
+}
+
+// statusTimestampColumn names the column recording when a record reached
+// status, or "" for statuses without one.
+func statusTimestampColumn(status string) string {
+	switch status {
+	case "confirmed":
+		return "confirmed_at"
+	case "cancelled":
+		return "cancelled_at"
+	case "completed":
+		return "completed_at"
+	}
+	return ""
+}
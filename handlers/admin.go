@@ -1,16 +1,24 @@
 package handlers
 
 import (
+	"crypto/subtle"
+	"errors"
 	"log"
 	"mumuni_backend/auth"
+	"mumuni_backend/database"
+	"mumuni_backend/lifecycle"
 	"mumuni_backend/models"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 )
 
 // AdminSignup handles POST /api/admin/signup
+// Signup only creates the studio's first owner, and only with the bootstrap
+// token from ADMIN_BOOTSTRAP_TOKEN. Every later admin joins through an
+// owner's invite.
 func (h *Handlers) AdminSignup(c *gin.Context) {
 	var req models.AdminSignupRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -20,14 +28,28 @@ func (h *Handlers) AdminSignup(c *gin.Context) {
 		return
 	}
 
-	// Check if admin already exists
-	_, err := h.db.GetAdminByEmail(c.Request.Context(), req.Email)
-	if err == nil {
-		c.JSON(http.StatusConflict, models.ErrorResponse{
-			Error: "Admin with this email already exists",
+	if !h.checkPassword(c, req.Password) {
+		return
+	}
+
+	bootstrapToken := h.cfg.AdminBootstrapToken
+	if bootstrapToken == "" || subtle.ConstantTimeCompare([]byte(req.BootstrapToken), []byte(bootstrapToken)) != 1 {
+		respondSignupClosed(c)
+		return
+	}
+
+	admins, err := h.db.GetAdmins(c.Request.Context())
+	if err != nil {
+		log.Printf("Error getting admins: %v", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error: "Failed to create admin account",
 		})
 		return
 	}
+	if len(admins) > 0 {
+		respondSignupClosed(c)
+		return
+	}
 
 	// Hash password
 	hashedPassword, err := auth.HashPassword(req.Password)
@@ -40,8 +62,14 @@ func (h *Handlers) AdminSignup(c *gin.Context) {
 	}
 
 	// Create admin
-	_, err = h.db.CreateAdmin(c.Request.Context(), req.Email, hashedPassword, req.Name)
+	_, err = h.db.CreateAdmin(c.Request.Context(), req.Email, hashedPassword, req.Name, auth.RoleOwner, nil)
 	if err != nil {
+		if errors.Is(err, database.ErrAdminExists) {
+			c.JSON(http.StatusConflict, models.ErrorResponse{
+				Error: "Admin with this email already exists",
+			})
+			return
+		}
 		log.Printf("Error creating admin: %v", err)
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
 			Error: "Failed to create admin account",
@@ -55,7 +83,18 @@ func (h *Handlers) AdminSignup(c *gin.Context) {
 	})
 }
 
+func respondSignupClosed(c *gin.Context) {
+	c.JSON(http.StatusForbidden, models.ErrorResponse{
+		Error: "Admin signup is closed. Ask an owner for an invite",
+	})
+}
+
 // AdminLogin handles POST /api/admin/login
+// It starts a session, answering with a short-lived access token and a
+// refresh token for the next one. Admins with two-factor authentication get
+// a challenge token instead, to trade for the session at
+// /api/admin/login/2fa with a code. Repeated failures for an email or IP
+// address slow down and then lock out further attempts.
 func (h *Handlers) AdminLogin(c *gin.Context) {
 	var req models.AdminLoginRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -65,53 +104,81 @@ func (h *Handlers) AdminLogin(c *gin.Context) {
 		return
 	}
 
-	// Get admin by email
-	admin, err := h.db.GetAdminByEmail(c.Request.Context(), req.Email)
-	if err != nil {
-		c.JSON(http.StatusUnauthorized, models.ErrorResponse{
-			Error: "Invalid email or password",
-		})
+	if !h.checkLoginThrottle(c, req.Email) {
 		return
 	}
 
-	// Get password hash
-	passwordHash, err := h.db.GetAdminPasswordHash(c.Request.Context(), req.Email)
-	if err != nil {
-		c.JSON(http.StatusUnauthorized, models.ErrorResponse{
-			Error: "Invalid email or password",
-		})
+	// Get admin and password hash by email
+	ctx := c.Request.Context()
+	admin, err := h.db.GetAdminByEmail(ctx, req.Email)
+	passwordHash := dummyPasswordHash
+	if err == nil {
+		passwordHash, err = h.db.GetAdminPasswordHash(ctx, req.Email)
+	}
+	if err != nil && !errors.Is(err, database.ErrAdminNotFound) {
+		respondAdminError(c, err, "Failed to log in")
 		return
 	}
 
-	// Check password
-	if !auth.CheckPasswordHash(req.Password, passwordHash) {
+	// Check password, even without an admin, so both take as long
+	if !auth.CheckPasswordHash(req.Password, passwordHash) || err != nil {
+		h.recordLoginFailure(c, req.Email, loginInvalidCredentials)
 		c.JSON(http.StatusUnauthorized, models.ErrorResponse{
 			Error: "Invalid email or password",
 		})
 		return
 	}
 
-	// Generate JWT token
-	token, err := auth.GenerateToken(admin.ID, admin.Email)
-	if err != nil {
-		log.Printf("Error generating token: %v", err)
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
-			Error: "Failed to generate authentication token",
-		})
+	if admin.TwoFactorEnabled {
+		h.challengeTwoFactor(c, admin, req.Email)
 		return
 	}
 
-	c.JSON(http.StatusOK, models.AdminLoginResponse{
-		Success: true,
-		Token:   token,
-		Admin:   *admin,
-		Message: "Login successful",
-	})
+	// Start a session with its first pair of tokens
+	h.completeLogin(c, admin, req.Email)
 }
 
-// GetAppointments handles GET /api/admin/appointments
+// GetAppointments handles GET /api/admin/appointments?status=&service=&artist_id=&customer_id=&date=&date_from=&date_to=&email=&phone=&q=&sort=&order=&limit=&offset=
+// Appointments are listed a page at a time, newest first unless sorted
+// otherwise. q searches the name, email and message. Artists only see their
+// own appointments.
 func (h *Handlers) GetAppointments(c *gin.Context) {
-	appointments, err := h.db.GetAppointments(c.Request.Context())
+	filter := database.AppointmentFilter{
+		Status:  c.Query("status"),
+		Service: c.Query("service"),
+		Email:   strings.TrimSpace(c.Query("email")),
+		Phone:   h.phoneFilter(c.Query("phone")),
+		Search:  strings.TrimSpace(c.Query("q")),
+	}
+	var ok bool
+	if filter.ArtistID, ok = optionalIDParam(c, "artist_id", "Invalid artist ID"); !ok {
+		return
+	}
+	if filter.CustomerID, ok = optionalIDParam(c, "customer_id", "Invalid customer ID"); !ok {
+		return
+	}
+	if filter.Date, ok = dateParam(c, "date"); !ok {
+		return
+	}
+	if filter.DateFrom, ok = dateParam(c, "date_from"); !ok {
+		return
+	}
+	if filter.DateTo, ok = dateParam(c, "date_to"); !ok {
+		return
+	}
+	scope, ok := artistScope(c)
+	if !ok {
+		return
+	}
+	if scope != nil {
+		filter.ArtistID = scope
+	}
+	page, ok := pageParams(c, database.AppointmentSorts)
+	if !ok {
+		return
+	}
+
+	appointments, total, err := h.db.GetAppointments(c.Request.Context(), filter, page)
 	if err != nil {
 		log.Printf("Error getting appointments: %v", err)
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
@@ -120,16 +187,39 @@ func (h *Handlers) GetAppointments(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"success":      true,
-		"appointments": appointments,
-		"count":        len(appointments),
-	})
+	respondPage(c, "appointments", appointments, len(appointments), total, page)
 }
 
-// GetClasses handles GET /api/admin/classes
+// GetClasses handles GET /api/admin/classes?status=&class_type=&cohort_id=&customer_id=&created_from=&created_to=&email=&phone=&q=&sort=&order=&limit=&offset=
+// Enrollments are listed a page at a time, newest first unless sorted
+// otherwise. q searches the name, email and goals.
 func (h *Handlers) GetClasses(c *gin.Context) {
-	classes, err := h.db.GetClasses(c.Request.Context())
+	filter := database.ClassFilter{
+		Status:    c.Query("status"),
+		ClassType: c.Query("class_type"),
+		Email:     strings.TrimSpace(c.Query("email")),
+		Phone:     h.phoneFilter(c.Query("phone")),
+		Search:    strings.TrimSpace(c.Query("q")),
+	}
+	var ok bool
+	if filter.CohortID, ok = optionalIDParam(c, "cohort_id", "Invalid cohort ID"); !ok {
+		return
+	}
+	if filter.CustomerID, ok = optionalIDParam(c, "customer_id", "Invalid customer ID"); !ok {
+		return
+	}
+	if filter.CreatedFrom, ok = dateParam(c, "created_from"); !ok {
+		return
+	}
+	if filter.CreatedTo, ok = dateParam(c, "created_to"); !ok {
+		return
+	}
+	page, ok := pageParams(c, database.ClassSorts)
+	if !ok {
+		return
+	}
+
+	classes, total, err := h.db.GetClasses(c.Request.Context(), filter, page)
 	if err != nil {
 		log.Printf("Error getting classes: %v", err)
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
@@ -138,11 +228,7 @@ func (h *Handlers) GetClasses(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"success": true,
-		"classes": classes,
-		"count":   len(classes),
-	})
+	respondPage(c, "classes", classes, len(classes), total, page)
 }
 
 // UpdateAppointmentStatus handles PUT /api/admin/appointments/:id/status
@@ -156,6 +242,9 @@ func (h *Handlers) UpdateAppointmentStatus(c *gin.Context) {
 		})
 		return
 	}
+	if !h.checkAppointmentScope(c, appointmentID) {
+		return
+	}
 
 	// Parse request body
 	var req models.StatusUpdateRequest
@@ -167,27 +256,36 @@ func (h *Handlers) UpdateAppointmentStatus(c *gin.Context) {
 	}
 
 	// Update appointment status
-	appointment, err := h.db.UpdateAppointmentStatus(c.Request.Context(), appointmentID, req.Status)
+	appointment, err := h.db.UpdateAppointmentStatus(c.Request.Context(), appointmentID, database.StatusChange{
+		Status:  req.Status,
+		Reason:  req.Reason,
+		AdminID: adminID(c),
+	})
 	if err != nil {
 		log.Printf("Error updating appointment status: %v", err)
-		if err.Error() == "appointment not found" {
+		if errors.Is(err, database.ErrAppointmentNotFound) {
 			c.JSON(http.StatusNotFound, models.ErrorResponse{
 				Error: "Appointment not found",
 			})
 			return
 		}
-		if err.Error() == "invalid status: "+req.Status {
+		if errors.Is(err, database.ErrInvalidStatus) {
 			c.JSON(http.StatusBadRequest, models.ErrorResponse{
 				Error: "Invalid status. Must be one of: pending, confirmed, cancelled, completed",
 			})
 			return
 		}
+		if respondLifecycleError(c, err) {
+			return
+		}
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
 			Error: "Failed to update appointment status",
 		})
 		return
 	}
 
+	h.appointmentStatusChanged(c.Request.Context(), appointment)
+
 	c.JSON(http.StatusOK, models.StatusUpdateResponse{
 		Success: true,
 		Message: "Appointment status updated successfully",
@@ -196,6 +294,8 @@ func (h *Handlers) UpdateAppointmentStatus(c *gin.Context) {
 }
 
 // UpdateClassStatus handles PUT /api/admin/classes/:id/status
+// Moving an enrollment off the waitlist takes a seat in its cohort and
+// promotes its waitlist entry; a full cohort answers 409 Conflict.
 func (h *Handlers) UpdateClassStatus(c *gin.Context) {
 	// Get class ID from URL parameter
 	classIDStr := c.Param("id")
@@ -217,30 +317,128 @@ func (h *Handlers) UpdateClassStatus(c *gin.Context) {
 	}
 
 	// Update class status
-	class, err := h.db.UpdateClassStatus(c.Request.Context(), classID, req.Status)
+	class, err := h.db.UpdateClassStatus(c.Request.Context(), classID, database.StatusChange{
+		Status:  req.Status,
+		Reason:  req.Reason,
+		AdminID: adminID(c),
+	})
 	if err != nil {
 		log.Printf("Error updating class status: %v", err)
-		if err.Error() == "class not found" {
+		if errors.Is(err, database.ErrClassNotFound) {
 			c.JSON(http.StatusNotFound, models.ErrorResponse{
 				Error: "Class not found",
 			})
 			return
 		}
-		if err.Error() == "invalid status: "+req.Status {
+		if errors.Is(err, database.ErrInvalidStatus) {
 			c.JSON(http.StatusBadRequest, models.ErrorResponse{
-				Error: "Invalid status. Must be one of: pending, confirmed, cancelled, completed",
+				Error: "Invalid status. Must be one of: pending, confirmed, cancelled, completed, waitlisted",
 			})
 			return
 		}
+		if errors.Is(err, database.ErrCohortFull) {
+			c.JSON(http.StatusConflict, models.ErrorResponse{
+				Error: "This cohort is full",
+			})
+			return
+		}
+		if respondLifecycleError(c, err) {
+			return
+		}
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
 			Error: "Failed to update class status",
 		})
 		return
 	}
 
+	h.classStatusChanged(c.Request.Context(), class)
+
 	c.JSON(http.StatusOK, models.StatusUpdateResponse{
 		Success: true,
 		Message: "Class status updated successfully",
 		Data:    class,
 	})
 }
+
+// GetAppointmentHistory handles GET /api/admin/appointments/:id/history
+func (h *Handlers) GetAppointmentHistory(c *gin.Context) {
+	h.respondStatusHistory(c, "appointment")
+}
+
+// GetClassHistory handles GET /api/admin/classes/:id/history
+func (h *Handlers) GetClassHistory(c *gin.Context) {
+	h.respondStatusHistory(c, "class")
+}
+
+func (h *Handlers) respondStatusHistory(c *gin.Context, resourceType string) {
+	resourceID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: "Invalid " + resourceType + " ID",
+		})
+		return
+	}
+	if resourceType == "appointment" && !h.checkAppointmentScope(c, resourceID) {
+		return
+	}
+
+	history, err := h.db.GetStatusHistory(c.Request.Context(), resourceType, resourceID)
+	if err != nil {
+		switch {
+		case errors.Is(err, database.ErrAppointmentNotFound):
+			c.JSON(http.StatusNotFound, models.ErrorResponse{
+				Error: "Appointment not found",
+			})
+		case errors.Is(err, database.ErrClassNotFound):
+			c.JSON(http.StatusNotFound, models.ErrorResponse{
+				Error: "Class not found",
+			})
+		default:
+			log.Printf("Error getting status history: %v", err)
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+				Error: "Failed to fetch status history",
+			})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"history": history,
+		"count":   len(history),
+	})
+}
+
+// respondLifecycleError writes the response for a status change the
+// lifecycle refused and reports whether err was one.
+func respondLifecycleError(c *gin.Context, err error) bool {
+	var transitionErr *lifecycle.TransitionError
+	switch {
+	case errors.As(err, &transitionErr):
+		allowed := "none, it is final"
+		if next := lifecycle.Next(transitionErr.From); len(next) > 0 {
+			allowed = strings.Join(next, ", ")
+		}
+		c.JSON(http.StatusConflict, models.ErrorResponse{
+			Error: "Cannot change status from " + transitionErr.From + " to " + transitionErr.To +
+				". Allowed: " + allowed,
+		})
+	case errors.Is(err, lifecycle.ErrReasonRequired):
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: "A reason is required to cancel",
+		})
+	default:
+		return false
+	}
+	return true
+}
+
+// adminID returns the ID AuthMiddleware stored for the signed-in admin, or
+// nil on public routes.
+func adminID(c *gin.Context) *string {
+	id := c.GetString("admin_id")
+	if id == "" {
+		return nil
+	}
+	return &id
+}
@@ -0,0 +1,169 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"mumuni_backend/auth"
+	"mumuni_backend/database"
+	"mumuni_backend/models"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetCurrentAdmin handles GET /api/admin/me
+// It returns the signed-in admin with what their role lets them do.
+func (h *Handlers) GetCurrentAdmin(c *gin.Context) {
+	admin, err := h.db.GetAdmin(c.Request.Context(), c.GetString("admin_id"))
+	if err != nil {
+		respondAdminError(c, err, "Failed to fetch admin")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":     true,
+		"admin":       admin,
+		"permissions": auth.Permissions(admin.Role),
+	})
+}
+
+// GetAdmins handles GET /api/admin/admins
+func (h *Handlers) GetAdmins(c *gin.Context) {
+	admins, err := h.db.GetAdmins(c.Request.Context())
+	if err != nil {
+		log.Printf("Error getting admins: %v", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error: "Failed to fetch admins",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"admins":  admins,
+		"count":   len(admins),
+	})
+}
+
+// UpdateAdminRole handles PUT /api/admin/admins/:id/role
+// Artists must be linked to an artist. The last owner can't be demoted, so
+// the studio always has someone who can manage admins. The admin's sessions
+// are ended, since their tokens carry the old role, so the new one applies
+// from when they next sign in.
+func (h *Handlers) UpdateAdminRole(c *gin.Context) {
+	var req models.AdminRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: "Invalid request data: " + err.Error(),
+		})
+		return
+	}
+	if req.Role != auth.RoleArtist {
+		req.ArtistID = nil
+	} else if req.ArtistID == nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: "An artist admin must be linked to an artist with artist_id",
+		})
+		return
+	}
+
+	ctx := c.Request.Context()
+	admin, err := h.db.GetAdmin(ctx, c.Param("id"))
+	if err != nil {
+		respondAdminError(c, err, "Failed to update admin role")
+		return
+	}
+	if req.ArtistID != nil {
+		if _, err := h.db.GetArtist(ctx, *req.ArtistID); err != nil {
+			respondAdminError(c, err, "Failed to update admin role")
+			return
+		}
+	}
+
+	admin, err = h.db.UpdateAdminRole(ctx, admin.ID, req.Role, req.ArtistID)
+	if errors.Is(err, database.ErrLastOwner) {
+		c.JSON(http.StatusConflict, models.ErrorResponse{
+			Error: "The last owner can't be given another role",
+		})
+		return
+	}
+	if err != nil {
+		respondAdminError(c, err, "Failed to update admin role")
+		return
+	}
+	if err := h.db.RevokeAdminSessions(ctx, admin.ID, ""); err != nil {
+		respondAdminError(c, err, "Failed to end the admin's sessions")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Admin role updated successfully",
+		"admin":   admin,
+	})
+}
+
+// artistScope returns the artist whose appointments the signed-in admin is
+// limited to, or nil if they see every appointment. Artists who aren't
+// linked to an artist are refused, and ok is false.
+func artistScope(c *gin.Context) (artistID *int, ok bool) {
+	if c.GetString("admin_role") != auth.RoleArtist {
+		return nil, true
+	}
+
+	id, linked := c.Get("admin_artist_id")
+	if !linked {
+		c.JSON(http.StatusForbidden, models.ErrorResponse{
+			Error: "Your account is not linked to an artist",
+		})
+		return nil, false
+	}
+	artist := id.(int)
+	return &artist, true
+}
+
+// checkAppointmentScope refuses an artist access to an appointment that
+// isn't theirs, answering as if it didn't exist.
+func (h *Handlers) checkAppointmentScope(c *gin.Context, appointmentID int) bool {
+	artistID, ok := artistScope(c)
+	if !ok {
+		return false
+	}
+	if artistID == nil {
+		return true
+	}
+
+	appointment, err := h.db.GetAppointment(c.Request.Context(), appointmentID)
+	if err != nil && !errors.Is(err, database.ErrAppointmentNotFound) {
+		log.Printf("Error getting appointment: %v", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error: "Failed to fetch appointment",
+		})
+		return false
+	}
+	if err != nil || appointment.ArtistID == nil || *appointment.ArtistID != *artistID {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Error: "Appointment not found",
+		})
+		return false
+	}
+	return true
+}
+
+func respondAdminError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, database.ErrAdminNotFound):
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Error: "Admin not found",
+		})
+	case errors.Is(err, database.ErrArtistNotFound):
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: "Artist not found",
+		})
+	default:
+		log.Printf("%s: %v", message, err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error: message,
+		})
+	}
+}
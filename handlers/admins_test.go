@@ -0,0 +1,164 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"mumuni_backend/auth"
+	"mumuni_backend/database"
+	"mumuni_backend/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestUpdateAdminRoleEndsSessions(t *testing.T) {
+	h, store := newTestHandlers(t)
+	ctx := context.Background()
+
+	if _, err := store.CreateAdmin(ctx, "owner@example.com", "hash", "Owner", auth.RoleOwner, nil); err != nil {
+		t.Fatalf("CreateAdmin: %v", err)
+	}
+	manager, err := store.CreateAdmin(ctx, "manager@example.com", "hash", "Manager", auth.RoleManager, nil)
+	if err != nil {
+		t.Fatalf("CreateAdmin: %v", err)
+	}
+	access := database.AccessToken{JTI: "manager-token", ExpiresAt: time.Now().Add(time.Hour)}
+	if _, err := store.CreateAdminSession(ctx, &models.AdminSession{
+		AdminID:   manager.ID,
+		ExpiresAt: time.Now().Add(24 * time.Hour),
+	}, "manager-refresh", access); err != nil {
+		t.Fatalf("CreateAdminSession: %v", err)
+	}
+
+	r := gin.New()
+	r.PUT("/api/admin/admins/:id/role", asAdmin(auth.RoleOwner, nil), h.UpdateAdminRole)
+	w := serve(r, http.MethodPut, "/api/admin/admins/"+manager.ID+"/role", map[string]string{"role": auth.RoleReceptionist})
+	if w.Code != http.StatusOK {
+		t.Fatalf("got %d %s, want 200", w.Code, w.Body)
+	}
+
+	// The manager's token still says manager, so it must stop working
+	if revoked, _ := store.IsTokenRevoked(ctx, access.JTI); !revoked {
+		t.Error("the demoted admin's access token still works")
+	}
+	if sessions, _ := store.GetAdminSessions(ctx, manager.ID); len(sessions) != 0 {
+		t.Errorf("the demoted admin has %d sessions, want none", len(sessions))
+	}
+}
+
+func TestArtistsOnlyReachTheirOwnAppointments(t *testing.T) {
+	h, store := newTestHandlers(t)
+	ctx := context.Background()
+
+	var artists []int
+	for _, name := range []string{"Amaka", "Bisi"} {
+		artist, err := store.CreateArtist(ctx, &models.ArtistRequest{Name: name, Skills: []string{"Everyday Glam"}})
+		if err != nil {
+			t.Fatalf("CreateArtist: %v", err)
+		}
+		artists = append(artists, artist.ID)
+	}
+	mine, theirs := artists[0], artists[1]
+
+	book := gin.New()
+	book.POST("/api/appointments", h.BookAppointment)
+	booked := map[int]int{}
+	for artistID, clock := range map[int]string{mine: "10:00 AM", theirs: "2:00 PM"} {
+		req := appointmentRequest("customer"+strconv.Itoa(artistID)+"@example.com", clock)
+		req["artist_id"] = artistID
+		w := serve(book, http.MethodPost, "/api/appointments", req)
+		if w.Code != http.StatusCreated {
+			t.Fatalf("booking with artist %d: got %d %s, want 201", artistID, w.Code, w.Body)
+		}
+		var resp models.AppointmentResponse
+		decode(t, w, &resp)
+		booked[artistID] = resp.Appointment.ID
+	}
+
+	r := gin.New()
+	admin := r.Group("/api/admin", asAdmin(auth.RoleArtist, &mine))
+	admin.GET("/appointments", h.GetAppointments)
+	admin.PUT("/appointments/:id/status", h.UpdateAppointmentStatus)
+	admin.GET("/appointments/:id/history", h.GetAppointmentHistory)
+
+	var list struct {
+		Appointments []models.Appointment `json:"appointments"`
+	}
+	decode(t, serve(r, http.MethodGet, "/api/admin/appointments", nil), &list)
+	if len(list.Appointments) != 1 || list.Appointments[0].ID != booked[mine] {
+		t.Errorf("the artist's appointments = %+v, want only appointment %d", list.Appointments, booked[mine])
+	}
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   any
+		want   int
+	}{
+		{"confirm own", http.MethodPut, "/api/admin/appointments/" + strconv.Itoa(booked[mine]) + "/status", map[string]string{"status": "confirmed"}, http.StatusOK},
+		{"own history", http.MethodGet, "/api/admin/appointments/" + strconv.Itoa(booked[mine]) + "/history", nil, http.StatusOK},
+		// Another artist's appointments answer as if they didn't exist
+		{"confirm another's", http.MethodPut, "/api/admin/appointments/" + strconv.Itoa(booked[theirs]) + "/status", map[string]string{"status": "confirmed"}, http.StatusNotFound},
+		{"another's history", http.MethodGet, "/api/admin/appointments/" + strconv.Itoa(booked[theirs]) + "/history", nil, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		if w := serve(r, tt.method, tt.path, tt.body); w.Code != tt.want {
+			t.Errorf("%s: got %d %s, want %d", tt.name, w.Code, w.Body, tt.want)
+		}
+	}
+	if stored, _ := store.GetAppointment(ctx, booked[theirs]); stored.Status != "pending" {
+		t.Errorf("another artist's appointment is %s, want it left pending", stored.Status)
+	}
+}
+
+func TestUnlinkedArtistIsRefused(t *testing.T) {
+	h, _ := newTestHandlers(t)
+	r := gin.New()
+	r.GET("/api/admin/appointments", asAdmin(auth.RoleArtist, nil), h.GetAppointments)
+	r.PUT("/api/admin/appointments/:id/status", asAdmin(auth.RoleArtist, nil), h.UpdateAppointmentStatus)
+
+	if w := serve(r, http.MethodGet, "/api/admin/appointments", nil); w.Code != http.StatusForbidden {
+		t.Errorf("listing: got %d, want 403", w.Code)
+	}
+	if w := serve(r, http.MethodPut, "/api/admin/appointments/1/status", map[string]string{"status": "confirmed"}); w.Code != http.StatusForbidden {
+		t.Errorf("updating: got %d, want 403", w.Code)
+	}
+}
+
+func TestUpdateAdminRoleKeepsAnOwner(t *testing.T) {
+	h, store := newTestHandlers(t)
+	ctx := context.Background()
+
+	owner, err := store.CreateAdmin(ctx, "owner@example.com", "hash", "Owner", auth.RoleOwner, nil)
+	if err != nil {
+		t.Fatalf("CreateAdmin: %v", err)
+	}
+	r := gin.New()
+	r.PUT("/api/admin/admins/:id/role", asAdmin(auth.RoleOwner, nil), h.UpdateAdminRole)
+	demote := func(id string) int {
+		return serve(r, http.MethodPut, "/api/admin/admins/"+id+"/role", map[string]string{"role": auth.RoleManager}).Code
+	}
+
+	if code := demote(owner.ID); code != http.StatusConflict {
+		t.Fatalf("demoting the only owner: got %d, want 409", code)
+	}
+
+	// With a second owner either may step down, but not both
+	second, err := store.CreateAdmin(ctx, "second@example.com", "hash", "Second", auth.RoleOwner, nil)
+	if err != nil {
+		t.Fatalf("CreateAdmin: %v", err)
+	}
+	if code := demote(owner.ID); code != http.StatusOK {
+		t.Fatalf("demoting one of two owners: got %d, want 200", code)
+	}
+	if code := demote(second.ID); code != http.StatusConflict {
+		t.Errorf("demoting the remaining owner: got %d, want 409", code)
+	}
+	if admin, _ := store.GetAdmin(ctx, second.ID); admin.Role != auth.RoleOwner {
+		t.Errorf("the remaining owner is now %s", admin.Role)
+	}
+}
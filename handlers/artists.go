@@ -0,0 +1,205 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"mumuni_backend/database"
+	"mumuni_backend/models"
+	"mumuni_backend/scheduling"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListArtists handles GET /api/artists
+func (h *Handlers) ListArtists(c *gin.Context) {
+	artists, err := h.db.GetArtists(c.Request.Context(), true)
+	if err != nil {
+		log.Printf("Error getting artists: %v", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error: "Failed to fetch artists",
+		})
+		return
+	}
+
+	public := make([]models.PublicArtist, 0, len(artists))
+	for _, a := range artists {
+		public = append(public, models.PublicArtist{ID: a.ID, Name: a.Name, Skills: a.Skills})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"artists": public,
+		"count":   len(public),
+	})
+}
+
+// GetArtists handles GET /api/admin/artists
+func (h *Handlers) GetArtists(c *gin.Context) {
+	artists, err := h.db.GetArtists(c.Request.Context(), false)
+	if err != nil {
+		log.Printf("Error getting artists: %v", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error: "Failed to fetch artists",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"artists": artists,
+		"count":   len(artists),
+	})
+}
+
+// GetArtist handles GET /api/admin/artists/:id
+func (h *Handlers) GetArtist(c *gin.Context) {
+	artistID, ok := artistIDParam(c)
+	if !ok {
+		return
+	}
+
+	artist, err := h.db.GetArtist(c.Request.Context(), artistID)
+	if err != nil {
+		respondArtistError(c, err, "Failed to fetch artist")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"artist":  artist,
+	})
+}
+
+// CreateArtist handles POST /api/admin/artists
+func (h *Handlers) CreateArtist(c *gin.Context) {
+	var req models.ArtistRequest
+	if !h.bindArtistRequest(c, &req) {
+		return
+	}
+
+	artist, err := h.db.CreateArtist(c.Request.Context(), &req)
+	if err != nil {
+		log.Printf("Error creating artist: %v", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error: "Failed to create artist",
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"message": "Artist created successfully",
+		"artist":  artist,
+	})
+}
+
+// UpdateArtist handles PUT /api/admin/artists/:id
+func (h *Handlers) UpdateArtist(c *gin.Context) {
+	artistID, ok := artistIDParam(c)
+	if !ok {
+		return
+	}
+
+	var req models.ArtistRequest
+	if !h.bindArtistRequest(c, &req) {
+		return
+	}
+
+	artist, err := h.db.UpdateArtist(c.Request.Context(), artistID, &req)
+	if err != nil {
+		respondArtistError(c, err, "Failed to update artist")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Artist updated successfully",
+		"artist":  artist,
+	})
+}
+
+// DeleteArtist handles DELETE /api/admin/artists/:id
+func (h *Handlers) DeleteArtist(c *gin.Context) {
+	artistID, ok := artistIDParam(c)
+	if !ok {
+		return
+	}
+
+	if err := h.db.DeleteArtist(c.Request.Context(), artistID); err != nil {
+		respondArtistError(c, err, "Failed to delete artist")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Success: true,
+		Message: "Artist deleted successfully",
+	})
+}
+
+// bindArtistRequest parses and validates an artist payload, writing the error
+// response itself when it fails.
+func (h *Handlers) bindArtistRequest(c *gin.Context, req *models.ArtistRequest) bool {
+	if err := c.ShouldBindJSON(req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: "Invalid request data: " + err.Error(),
+		})
+		return false
+	}
+	if req.Phone != nil && strings.TrimSpace(*req.Phone) != "" && !h.normalizePhone(c, req.Phone) {
+		return false
+	}
+
+	services, err := h.db.GetServices(c.Request.Context(), false)
+	if err != nil {
+		log.Printf("Error getting services: %v", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error: "Failed to validate artist skills",
+		})
+		return false
+	}
+
+	for _, skill := range req.Skills {
+		if findService(services, skill) == nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error: "Invalid skill " + strconv.Quote(skill) + ". Skills must be service names",
+			})
+			return false
+		}
+	}
+
+	if err := scheduling.ValidateWorkingHours(req.WorkingHours); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: "Invalid working hours: " + err.Error(),
+		})
+		return false
+	}
+
+	return true
+}
+
+func artistIDParam(c *gin.Context) (int, bool) {
+	artistID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: "Invalid artist ID",
+		})
+		return 0, false
+	}
+	return artistID, true
+}
+
+func respondArtistError(c *gin.Context, err error, message string) {
+	if errors.Is(err, database.ErrArtistNotFound) {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Error: "Artist not found",
+		})
+		return
+	}
+	log.Printf("%s: %v", message, err)
+	c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+		Error: message,
+	})
+}
@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"mumuni_backend/models"
+	"mumuni_backend/scheduling"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetAvailability handles GET /api/availability?service=&from=&to=&artist_id=
+func (h *Handlers) GetAvailability(c *gin.Context) {
+	service := c.Query("service")
+	if service == "" {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: "service query parameter is required",
+		})
+		return
+	}
+
+	today, _ := time.Parse(scheduling.DateLayout, h.scheduler.Today(time.Now()))
+	from, err := parseDateParam(c.Query("from"), today)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: "Invalid from date. Use YYYY-MM-DD",
+		})
+		return
+	}
+	to, err := parseDateParam(c.Query("to"), from.AddDate(0, 0, 6))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: "Invalid to date. Use YYYY-MM-DD",
+		})
+		return
+	}
+
+	if to.Before(from) {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: "to date must not be before from date",
+		})
+		return
+	}
+	if maxDays := h.cfg.AvailabilityMaxDays; int(to.Sub(from).Hours()/24)+1 > maxDays {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: fmt.Sprintf("Date range cannot exceed %d days", maxDays),
+		})
+		return
+	}
+
+	services, err := h.db.GetServices(c.Request.Context(), false)
+	if err != nil {
+		log.Printf("Error getting services: %v", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error: "Failed to fetch availability",
+		})
+		return
+	}
+	if s := findService(services, service); s == nil || !s.Active {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Error: "Service not found",
+		})
+		return
+	}
+
+	var artistID *int
+	if value := c.Query("artist_id"); value != "" {
+		id, err := strconv.Atoi(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error: "Invalid artist ID",
+			})
+			return
+		}
+		artistID = &id
+	}
+
+	artists, err := h.db.GetArtists(c.Request.Context(), true)
+	if err != nil {
+		log.Printf("Error getting artists: %v", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error: "Failed to fetch availability",
+		})
+		return
+	}
+
+	existing, err := h.db.GetActiveAppointments(c.Request.Context(),
+		from.Format(scheduling.DateLayout), to.Format(scheduling.DateLayout))
+	if err != nil {
+		log.Printf("Error getting active appointments: %v", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error: "Failed to fetch availability",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":      true,
+		"service":      service,
+		"availability": h.scheduler.WithServices(services).Availability(service, from, to, artists, artistID, existing, time.Now()),
+	})
+}
+
+func parseDateParam(value string, defaultValue time.Time) (time.Time, error) {
+	if value == "" {
+		return defaultValue, nil
+	}
+	return time.Parse(scheduling.DateLayout, value)
+}
+
+// respondSlotError maps scheduling errors to API responses.
+func (h *Handlers) respondSlotError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, scheduling.ErrInvalidDate):
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: "Invalid date. Use YYYY-MM-DD",
+		})
+	case errors.Is(err, scheduling.ErrInvalidTime):
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: "Invalid time. Use a time such as 14:00 or 2:00 PM",
+		})
+	case errors.Is(err, scheduling.ErrSlotUnavailable):
+		c.JSON(http.StatusConflict, models.ErrorResponse{
+			Error: "The requested time is no longer available. Please choose another slot",
+		})
+	case errors.Is(err, scheduling.ErrPastTime):
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: "The requested time has already passed",
+		})
+	case errors.Is(err, scheduling.ErrTooSoon):
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: fmt.Sprintf("Appointments must be booked at least %s ahead", formatCutoff(h.scheduler.MinLeadTime)),
+		})
+	case errors.Is(err, scheduling.ErrTooFarAhead):
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: fmt.Sprintf("Appointments can be booked at most %d days ahead", h.scheduler.MaxAdvanceDays),
+		})
+	case errors.Is(err, scheduling.ErrNoQualifiedArtist):
+		c.JSON(http.StatusConflict, models.ErrorResponse{
+			Error: "No artist is currently available for this service",
+		})
+	default:
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: "Requested time is not available: " + err.Error(),
+		})
+	}
+}
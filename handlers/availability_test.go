@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"mumuni_backend/config"
+	"mumuni_backend/scheduling"
+
+	"github.com/gin-gonic/gin"
+)
+
+// rfc3339UTC parses s as an RFC 3339 timestamp in UTC, failing the test
+// otherwise.
+func rfc3339UTC(t *testing.T, field, s string) time.Time {
+	t.Helper()
+	ts, err := time.Parse(time.RFC3339, s)
+	if err != nil || ts.Location() != time.UTC {
+		t.Fatalf("%s = %q, want an RFC 3339 UTC timestamp", field, s)
+	}
+	return ts
+}
+
+func TestTimesAreRFC3339(t *testing.T) {
+	h, _ := newTestHandlers(t, func(cfg *config.Config) {
+		cfg.StudioTimeZone = "America/New_York"
+	})
+	studio, _ := time.LoadLocation("America/New_York")
+	r := gin.New()
+	r.POST("/api/appointments", h.BookAppointment)
+	r.GET("/api/availability", h.GetAvailability)
+
+	date := bookingDate(14)
+	w := serve(r, http.MethodPost, "/api/appointments", appointmentRequest("ada@example.com", "10:00 AM"))
+	if w.Code != http.StatusCreated {
+		t.Fatalf("booking: got %d %s, want 201", w.Code, w.Body)
+	}
+	var booked struct {
+		Appointment map[string]any `json:"appointment"`
+	}
+	decode(t, w, &booked)
+	startsAt, _ := booked.Appointment["starts_at"].(string)
+	want, _ := time.ParseInLocation(scheduling.DateLayout+" 3:04 PM", date+" 10:00 AM", studio)
+	if start := rfc3339UTC(t, "starts_at", startsAt); !start.Equal(want) {
+		t.Errorf("starts_at = %s, want 10:00 AM in New York, %s", start, want.UTC())
+	}
+
+	var availability struct {
+		Availability []struct {
+			Date  string           `json:"date"`
+			Slots []map[string]any `json:"slots"`
+		} `json:"availability"`
+	}
+	decode(t, serve(r, http.MethodGet, "/api/availability?service=Everyday%20Glam&from="+date+"&to="+date, nil), &availability)
+	if len(availability.Availability) != 1 || len(availability.Availability[0].Slots) == 0 {
+		t.Fatalf("availability = %+v, want slots on %s", availability, date)
+	}
+	for _, slot := range availability.Availability[0].Slots {
+		start := rfc3339UTC(t, "start", slot["start"].(string))
+		end := rfc3339UTC(t, "end", slot["end"].(string))
+		// The deprecated clock times name the same moments in the studio
+		if clock := start.In(studio).Format("3:04 PM"); clock != slot["time"] {
+			t.Errorf("slot start %s is %s in the studio, but time is %v", start, clock, slot["time"])
+		}
+		if clock := end.In(studio).Format("3:04 PM"); clock != slot["ends_at"] {
+			t.Errorf("slot end %s is %s in the studio, but ends_at is %v", end, clock, slot["ends_at"])
+		}
+	}
+}
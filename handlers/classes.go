@@ -0,0 +1,405 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"mumuni_backend/database"
+	"mumuni_backend/models"
+	"mumuni_backend/scheduling"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListClassOfferings handles GET /api/class-offerings
+func (h *Handlers) ListClassOfferings(c *gin.Context) {
+	offerings, err := h.db.GetClassOfferings(c.Request.Context(), true)
+	if err != nil {
+		log.Printf("Error getting class offerings: %v", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error: "Failed to fetch class offerings",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":   true,
+		"offerings": offerings,
+		"count":     len(offerings),
+	})
+}
+
+// ListCohorts handles GET /api/cohorts?offering_id=
+// Only active cohorts that haven't started yet are listed.
+func (h *Handlers) ListCohorts(c *gin.Context) {
+	filter := database.CohortFilter{
+		ActiveOnly: true,
+		StartsFrom: h.scheduler.Today(time.Now()),
+	}
+	if !parseOfferingFilter(c, &filter) {
+		return
+	}
+
+	h.respondCohorts(c, filter)
+}
+
+// GetClassOfferings handles GET /api/admin/class-offerings
+func (h *Handlers) GetClassOfferings(c *gin.Context) {
+	offerings, err := h.db.GetClassOfferings(c.Request.Context(), false)
+	if err != nil {
+		log.Printf("Error getting class offerings: %v", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error: "Failed to fetch class offerings",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":   true,
+		"offerings": offerings,
+		"count":     len(offerings),
+	})
+}
+
+// GetClassOffering handles GET /api/admin/class-offerings/:id
+func (h *Handlers) GetClassOffering(c *gin.Context) {
+	offeringID, ok := offeringIDParam(c)
+	if !ok {
+		return
+	}
+
+	offering, err := h.db.GetClassOffering(c.Request.Context(), offeringID)
+	if err != nil {
+		respondClassCatalogError(c, err, "Failed to fetch class offering")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":  true,
+		"offering": offering,
+	})
+}
+
+// CreateClassOffering handles POST /api/admin/class-offerings
+func (h *Handlers) CreateClassOffering(c *gin.Context) {
+	var req models.ClassOfferingRequest
+	if !bindOfferingRequest(c, &req) {
+		return
+	}
+
+	offering, err := h.db.CreateClassOffering(c.Request.Context(), &req)
+	if err != nil {
+		respondClassCatalogError(c, err, "Failed to create class offering")
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success":  true,
+		"message":  "Class offering created successfully",
+		"offering": offering,
+	})
+}
+
+// UpdateClassOffering handles PUT /api/admin/class-offerings/:id
+func (h *Handlers) UpdateClassOffering(c *gin.Context) {
+	offeringID, ok := offeringIDParam(c)
+	if !ok {
+		return
+	}
+
+	var req models.ClassOfferingRequest
+	if !bindOfferingRequest(c, &req) {
+		return
+	}
+
+	offering, err := h.db.UpdateClassOffering(c.Request.Context(), offeringID, &req)
+	if err != nil {
+		respondClassCatalogError(c, err, "Failed to update class offering")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":  true,
+		"message":  "Class offering updated successfully",
+		"offering": offering,
+	})
+}
+
+// DeleteClassOffering handles DELETE /api/admin/class-offerings/:id
+// Its cohorts are deleted with it; their enrollments are kept.
+func (h *Handlers) DeleteClassOffering(c *gin.Context) {
+	offeringID, ok := offeringIDParam(c)
+	if !ok {
+		return
+	}
+
+	if err := h.db.DeleteClassOffering(c.Request.Context(), offeringID); err != nil {
+		respondClassCatalogError(c, err, "Failed to delete class offering")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Success: true,
+		Message: "Class offering deleted successfully",
+	})
+}
+
+// GetCohorts handles GET /api/admin/cohorts?offering_id=
+func (h *Handlers) GetCohorts(c *gin.Context) {
+	var filter database.CohortFilter
+	if !parseOfferingFilter(c, &filter) {
+		return
+	}
+
+	h.respondCohorts(c, filter)
+}
+
+// GetCohort handles GET /api/admin/cohorts/:id
+func (h *Handlers) GetCohort(c *gin.Context) {
+	cohortID, ok := cohortIDParam(c)
+	if !ok {
+		return
+	}
+
+	cohort, err := h.db.GetCohort(c.Request.Context(), cohortID)
+	if err != nil {
+		respondClassCatalogError(c, err, "Failed to fetch cohort")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"cohort":  cohort,
+	})
+}
+
+// CreateCohort handles POST /api/admin/cohorts
+func (h *Handlers) CreateCohort(c *gin.Context) {
+	var req models.CohortRequest
+	if !bindCohortRequest(c, &req) {
+		return
+	}
+
+	cohort, err := h.db.CreateCohort(c.Request.Context(), &req)
+	if err != nil {
+		respondClassCatalogError(c, err, "Failed to create cohort")
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"message": "Cohort created successfully",
+		"cohort":  cohort,
+	})
+}
+
+// UpdateCohort handles PUT /api/admin/cohorts/:id
+func (h *Handlers) UpdateCohort(c *gin.Context) {
+	cohortID, ok := cohortIDParam(c)
+	if !ok {
+		return
+	}
+
+	var req models.CohortRequest
+	if !bindCohortRequest(c, &req) {
+		return
+	}
+
+	cohort, err := h.db.UpdateCohort(c.Request.Context(), cohortID, &req)
+	if err != nil {
+		respondClassCatalogError(c, err, "Failed to update cohort")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Cohort updated successfully",
+		"cohort":  cohort,
+	})
+}
+
+// DeleteCohort handles DELETE /api/admin/cohorts/:id
+// Enrollments in the cohort are kept and fall back to preference-only.
+func (h *Handlers) DeleteCohort(c *gin.Context) {
+	cohortID, ok := cohortIDParam(c)
+	if !ok {
+		return
+	}
+
+	if err := h.db.DeleteCohort(c.Request.Context(), cohortID); err != nil {
+		respondClassCatalogError(c, err, "Failed to delete cohort")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Success: true,
+		Message: "Cohort deleted successfully",
+	})
+}
+
+func (h *Handlers) respondCohorts(c *gin.Context, filter database.CohortFilter) {
+	cohorts, err := h.db.GetCohorts(c.Request.Context(), filter)
+	if err != nil {
+		log.Printf("Error getting cohorts: %v", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error: "Failed to fetch cohorts",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"cohorts": cohorts,
+		"count":   len(cohorts),
+	})
+}
+
+func parseOfferingFilter(c *gin.Context, filter *database.CohortFilter) bool {
+	value := c.Query("offering_id")
+	if value == "" {
+		return true
+	}
+
+	offeringID, err := strconv.Atoi(value)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: "Invalid class offering ID",
+		})
+		return false
+	}
+	filter.OfferingID = &offeringID
+	return true
+}
+
+func bindOfferingRequest(c *gin.Context, req *models.ClassOfferingRequest) bool {
+	if err := c.ShouldBindJSON(req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: "Invalid request data: " + err.Error(),
+		})
+		return false
+	}
+
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: "Class offering name is required",
+		})
+		return false
+	}
+
+	return true
+}
+
+func bindCohortRequest(c *gin.Context, req *models.CohortRequest) bool {
+	if err := c.ShouldBindJSON(req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: "Invalid request data: " + err.Error(),
+		})
+		return false
+	}
+
+	start, err := time.Parse(scheduling.DateLayout, req.StartDate)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: "Invalid start_date. Use YYYY-MM-DD",
+		})
+		return false
+	}
+
+	if req.EndDate != nil {
+		end, err := time.Parse(scheduling.DateLayout, *req.EndDate)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error: "Invalid end_date. Use YYYY-MM-DD",
+			})
+			return false
+		}
+		if end.Before(start) {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error: "end_date must not be before start_date",
+			})
+			return false
+		}
+	}
+
+	if _, err := scheduling.ParseWeeklyHours(req.MeetingTimes); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: "Invalid meeting_times: " + err.Error(),
+		})
+		return false
+	}
+
+	return true
+}
+
+func offeringIDParam(c *gin.Context) (int, bool) {
+	offeringID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: "Invalid class offering ID",
+		})
+		return 0, false
+	}
+	return offeringID, true
+}
+
+func cohortIDParam(c *gin.Context) (int, bool) {
+	cohortID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: "Invalid cohort ID",
+		})
+		return 0, false
+	}
+	return cohortID, true
+}
+
+func respondClassCatalogError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, database.ErrOfferingNotFound):
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Error: "Class offering not found",
+		})
+	case errors.Is(err, database.ErrOfferingExists):
+		c.JSON(http.StatusConflict, models.ErrorResponse{
+			Error: "A class offering with this name already exists",
+		})
+	case errors.Is(err, database.ErrCohortNotFound):
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Error: "Cohort not found",
+		})
+	case errors.Is(err, database.ErrCohortFull):
+		c.JSON(http.StatusConflict, models.ErrorResponse{
+			Error: "This cohort is full",
+		})
+	default:
+		log.Printf("%s: %v", message, err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error: message,
+		})
+	}
+}
+
+// findOffering returns the class offering with the given name, or nil.
+func findOffering(offerings []models.ClassOffering, name string) *models.ClassOffering {
+	for i := range offerings {
+		if offerings[i].Name == name {
+			return &offerings[i]
+		}
+	}
+	return nil
+}
+
+// activeOfferingNames lists the open class offerings for error messages.
+func activeOfferingNames(offerings []models.ClassOffering) string {
+	var names []string
+	for _, o := range offerings {
+		if o.Active {
+			names = append(names, o.Name)
+		}
+	}
+	return strings.Join(names, ", ")
+}
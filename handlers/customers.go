@@ -0,0 +1,210 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"mumuni_backend/database"
+	"mumuni_backend/models"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetCustomers handles GET /api/admin/customers?q=&sort=&order=&limit=&offset=
+// Customers are listed a page at a time, newest first unless sorted
+// otherwise. q searches the name, email and phone.
+func (h *Handlers) GetCustomers(c *gin.Context) {
+	filter := database.CustomerFilter{
+		Search: strings.TrimSpace(c.Query("q")),
+	}
+	page, ok := pageParams(c, database.CustomerSorts)
+	if !ok {
+		return
+	}
+
+	customers, total, err := h.db.GetCustomers(c.Request.Context(), filter, page)
+	if err != nil {
+		log.Printf("Error getting customers: %v", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error: "Failed to fetch customers",
+		})
+		return
+	}
+
+	respondPage(c, "customers", customers, len(customers), total, page)
+}
+
+// GetCustomer handles GET /api/admin/customers/:id
+// The customer comes with every appointment and enrollment they have made,
+// newest first, and their lifetime value.
+func (h *Handlers) GetCustomer(c *gin.Context) {
+	customerID, ok := customerIDParam(c)
+	if !ok {
+		return
+	}
+
+	profile, err := h.customerProfile(c.Request.Context(), customerID)
+	if err != nil {
+		respondCustomerError(c, err, "Failed to fetch customer")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":  true,
+		"customer": profile,
+	})
+}
+
+// UpdateCustomer handles PUT /api/admin/customers/:id
+// It changes the customer's details and notes. Their past bookings keep the
+// details they were made with.
+func (h *Handlers) UpdateCustomer(c *gin.Context) {
+	customerID, ok := customerIDParam(c)
+	if !ok {
+		return
+	}
+
+	var req models.CustomerRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: "Invalid request data: " + err.Error(),
+		})
+		return
+	}
+	if strings.TrimSpace(req.Phone) != "" && !h.normalizePhone(c, &req.Phone) {
+		return
+	}
+
+	customer, err := h.db.UpdateCustomer(c.Request.Context(), customerID, &req)
+	if err != nil {
+		respondCustomerError(c, err, "Failed to update customer")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":  true,
+		"message":  "Customer updated successfully",
+		"customer": customer,
+	})
+}
+
+// MergeCustomers handles POST /api/admin/customers/:id/merge
+// It merges the duplicate named in the body into the customer in the path:
+// the duplicate's bookings move over and the duplicate is deleted.
+func (h *Handlers) MergeCustomers(c *gin.Context) {
+	customerID, ok := customerIDParam(c)
+	if !ok {
+		return
+	}
+
+	var req models.MergeCustomerRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: "Invalid request data: " + err.Error(),
+		})
+		return
+	}
+	if req.CustomerID == customerID {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: "A customer cannot be merged into itself",
+		})
+		return
+	}
+
+	ctx := c.Request.Context()
+	if _, err := h.db.MergeCustomers(ctx, customerID, req.CustomerID); err != nil {
+		respondCustomerError(c, err, "Failed to merge customers")
+		return
+	}
+
+	profile, err := h.customerProfile(ctx, customerID)
+	if err != nil {
+		respondCustomerError(c, err, "Failed to fetch customer")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":  true,
+		"message":  "Customers merged successfully",
+		"customer": profile,
+	})
+}
+
+// customerProfile returns a customer with their bookings and lifetime
+// value, which prices their completed appointments and classes at the
+// current catalog prices.
+func (h *Handlers) customerProfile(ctx context.Context, customerID int) (*models.CustomerProfile, error) {
+	customer, err := h.db.GetCustomer(ctx, customerID)
+	if err != nil {
+		return nil, err
+	}
+
+	newestFirst := database.Page{Descending: true}
+	appointments, _, err := h.db.GetAppointments(ctx, database.AppointmentFilter{CustomerID: &customerID}, newestFirst)
+	if err != nil {
+		return nil, err
+	}
+	classes, _, err := h.db.GetClasses(ctx, database.ClassFilter{CustomerID: &customerID}, newestFirst)
+	if err != nil {
+		return nil, err
+	}
+
+	services, err := h.db.GetServices(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+	offerings, err := h.db.GetClassOfferings(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+
+	profile := &models.CustomerProfile{
+		Customer:     *customer,
+		Appointments: appointments,
+		Classes:      classes,
+	}
+	for _, a := range appointments {
+		if service := findService(services, a.Service); a.Status == "completed" && service != nil {
+			profile.LifetimeValue += service.Price
+		}
+	}
+	for _, cl := range classes {
+		if offering := findOffering(offerings, cl.ClassType); cl.Status == "completed" && offering != nil {
+			profile.LifetimeValue += offering.Price
+		}
+	}
+
+	return profile, nil
+}
+
+func customerIDParam(c *gin.Context) (int, bool) {
+	customerID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: "Invalid customer ID",
+		})
+		return 0, false
+	}
+	return customerID, true
+}
+
+func respondCustomerError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, database.ErrCustomerNotFound):
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Error: "Customer not found",
+		})
+	case errors.Is(err, database.ErrCustomerExists):
+		c.JSON(http.StatusConflict, models.ErrorResponse{
+			Error: "Another customer has this email",
+		})
+	default:
+		log.Printf("%s: %v", message, err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error: message,
+		})
+	}
+}
@@ -1,23 +1,42 @@
 package handlers
 
 import (
+	"context"
+	"errors"
 	"log"
+	"mumuni_backend/auth"
+	"mumuni_backend/config"
 	"mumuni_backend/database"
 	"mumuni_backend/models"
+	"mumuni_backend/notify"
+	"mumuni_backend/phone"
+	"mumuni_backend/scheduling"
+	"mumuni_backend/spam"
+	"mumuni_backend/webhooks"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
 type Handlers struct {
-	db *database.Database
+	cfg       *config.Config
+	db        database.Store
+	scheduler *scheduling.Engine
+	notifier  *notify.Notifier
+	publisher *webhooks.Publisher
+	guard     *spam.Guard
+	phones    *phone.Parser
 }
 
-func NewHandlers(db *database.Database) *Handlers {
-	return &Handlers{db: db}
+func NewHandlers(cfg *config.Config, db database.Store, scheduler *scheduling.Engine, notifier *notify.Notifier, publisher *webhooks.Publisher, guard *spam.Guard, phones *phone.Parser) *Handlers {
+	return &Handlers{cfg: cfg, db: db, scheduler: scheduler, notifier: notifier, publisher: publisher, guard: guard, phones: phones}
 }
 
 // BookAppointment handles POST /api/appointments
+// Bookings are screened for spam once the phone number is normalized,
+// before anything else is checked.
 func (h *Handlers) BookAppointment(c *gin.Context) {
 	var req models.AppointmentRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -26,39 +45,153 @@ func (h *Handlers) BookAppointment(c *gin.Context) {
 		})
 		return
 	}
-
-	// Validate service type
-	validServices := map[string]bool{
-		"Bridal Makeup":     true,
-		"Event Makeup":      true,
-		"Photoshoot Makeup": true,
-		"Everyday Glam":     true,
+	if !h.normalizePhone(c, &req.Phone) {
+		return
 	}
+	req.Channels = uniqueChannels(req.Channels)
 
-	if !validServices[req.Service] {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse{
-			Error: "Invalid service type. Must be one of: Bridal Makeup, Event Makeup, Photoshoot Makeup, Everyday Glam",
-		})
+	submission := spam.Submission{
+		Kind:     "appointment",
+		IP:       c.ClientIP(),
+		Email:    req.Email,
+		Phone:    req.Phone,
+		Honeypot: req.Website,
+		Token:    c.GetHeader(verificationHeader),
+		Payload:  req,
+	}
+	decoy := func(manageToken string) any {
+		return models.AppointmentResponse{
+			Success:     true,
+			Appointment: h.decoyAppointment(&req),
+			Message:     "Appointment booked successfully",
+			ManageToken: manageToken,
+		}
+	}
+	if !h.screenBooking(c, submission, decoy, "Failed to book appointment") {
 		return
 	}
+	defer h.releaseFailedBooking(c, submission)
 
-	appointment, err := h.db.CreateAppointment(c.Request.Context(), &req)
+	appointment, manageToken, err := h.bookAppointment(c.Request.Context(), &req)
 	if err != nil {
-		log.Printf("Error creating appointment: %v", err)
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
-			Error: "Failed to book appointment",
-		})
+		var serviceErr *invalidServiceError
+		switch {
+		case errors.As(err, &serviceErr):
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error: "Invalid service type. Must be one of: " + serviceErr.active,
+			})
+		case scheduling.IsSlotError(err):
+			h.respondSlotError(c, err)
+		default:
+			log.Printf("Error creating appointment: %v", err)
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+				Error: "Failed to book appointment",
+			})
+		}
 		return
 	}
 
+	h.notifier.Appointment(c.Request.Context(), notify.AppointmentReceived, appointment, notify.Details{ManageToken: manageToken})
+
 	c.JSON(http.StatusCreated, models.AppointmentResponse{
 		Success:     true,
 		Appointment: *appointment,
 		Message:     "Appointment booked successfully",
+		ManageToken: manageToken,
 	})
 }
 
+// invalidServiceError is returned by bookAppointment when the requested
+// service is missing from the catalog or inactive.
+type invalidServiceError struct {
+	active string
+}
+
+func (e *invalidServiceError) Error() string {
+	return "invalid service type, must be one of: " + e.active
+}
+
+// bookAppointment checks req against the service catalog, the studio
+// calendar and each artist's calendar, then stores it with the assigned
+// artist. It returns the appointment and the token the customer manages it
+// with, schedules its reminders and publishes the appointment.created
+// webhook. req.Time is normalized to the stored
+// format. Slot problems are returned as scheduling errors.
+func (h *Handlers) bookAppointment(ctx context.Context, req *models.AppointmentRequest) (*models.Appointment, string, error) {
+	check, err := h.slotCheck(ctx, req)
+	if err != nil {
+		return nil, "", err
+	}
+
+	manageToken, manageTokenHash, err := auth.NewOpaqueToken()
+	if err != nil {
+		return nil, "", err
+	}
+
+	appointment, err := h.db.CreateAppointment(ctx, req, manageTokenHash, check)
+	if err != nil {
+		return nil, "", err
+	}
+
+	h.scheduleAppointmentReminders(ctx, appointment)
+	h.publisher.Publish(ctx, webhooks.AppointmentCreated, appointment)
+	return appointment, manageToken, nil
+}
+
+// slotCheck validates req.Date, req.Time and req.Service against the
+// catalog, calendars and booking window, normalizes req.Time, sets
+// req.StartsAt, and returns the BookingCheck that rejects overlaps and fills
+// in req.ArtistID once the date is locked.
+func (h *Handlers) slotCheck(ctx context.Context, req *models.AppointmentRequest) (database.BookingCheck, error) {
+	services, err := h.db.GetServices(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+
+	if service := findService(services, req.Service); service == nil || !service.Active {
+		return nil, &invalidServiceError{active: activeServiceNames(services)}
+	}
+	scheduler := h.scheduler.WithServices(services)
+
+	artists, err := h.db.GetArtists(ctx, true)
+	if err != nil {
+		return nil, err
+	}
+
+	// Only book between the minimum lead time and the furthest date ahead
+	startsAt, err := scheduler.StartTime(req.Date, req.Time)
+	if err != nil {
+		return nil, err
+	}
+	if err := scheduler.CheckBookingWindow(startsAt, time.Now()); err != nil {
+		return nil, err
+	}
+	req.StartsAt = startsAt.UTC()
+
+	// Validate the requested slot against working hours and blackout dates
+	if _, err := scheduler.AssignArtist(req.Date, req.Time, req.Service, artists, req.ArtistID, nil); err != nil {
+		return nil, err
+	}
+
+	// Store the time in one canonical format so bookings can be compared
+	start, _ := scheduling.ParseTimeOfDay(req.Time)
+	req.Time = scheduling.FormatTimeOfDay(start)
+
+	// Reject overlaps with pending or confirmed appointments and pick the artist
+	requested := req.ArtistID
+	return func(existing []models.Appointment) error {
+		artistID, err := scheduler.AssignArtist(req.Date, req.Time, req.Service, artists, requested, existing)
+		if err != nil {
+			return err
+		}
+		req.ArtistID = artistID
+		return nil
+	}, nil
+}
+
 // EnrollInClass handles POST /api/classes
+// Enrollments are screened for spam once the phone number is normalized,
+// before anything else is checked.
 func (h *Handlers) EnrollInClass(c *gin.Context) {
 	var req models.ClassRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -67,21 +200,32 @@ func (h *Handlers) EnrollInClass(c *gin.Context) {
 		})
 		return
 	}
-
-	// Validate class type
-	validClassTypes := map[string]bool{
-		"Beginner Basics":     true,
-		"Advanced Techniques": true,
-		"Bridal Specialist":   true,
-		"Business Training":   true,
+	if !h.normalizePhone(c, &req.Phone) {
+		return
 	}
+	req.Channels = uniqueChannels(req.Channels)
 
-	if !validClassTypes[req.ClassType] {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse{
-			Error: "Invalid class type. Must be one of: Beginner Basics, Advanced Techniques, Bridal Specialist, Business Training",
-		})
+	submission := spam.Submission{
+		Kind:     "class",
+		IP:       c.ClientIP(),
+		Email:    req.Email,
+		Phone:    req.Phone,
+		Honeypot: req.Website,
+		Token:    c.GetHeader(verificationHeader),
+		Payload:  req,
+	}
+	decoy := func(manageToken string) any {
+		return models.ClassResponse{
+			Success:     true,
+			Enrollment:  decoyEnrollment(&req),
+			Message:     "Class enrollment successful",
+			ManageToken: manageToken,
+		}
+	}
+	if !h.screenBooking(c, submission, decoy, "Failed to enroll in class") {
 		return
 	}
+	defer h.releaseFailedBooking(c, submission)
 
 	// Validate experience level
 	validExperienceLevels := map[string]bool{
@@ -98,7 +242,8 @@ func (h *Handlers) EnrollInClass(c *gin.Context) {
 		return
 	}
 
-	// Validate schedule
+	// Validate schedule. It is only required without a cohort, which has
+	// its own meeting times.
 	validSchedules := map[string]bool{
 		"Weekdays":        true,
 		"Weekends":        true,
@@ -106,25 +251,143 @@ func (h *Handlers) EnrollInClass(c *gin.Context) {
 		"Flexible":        true,
 	}
 
-	if !validSchedules[req.Schedule] {
+	if (req.CohortID == nil || req.Schedule != "") && !validSchedules[req.Schedule] {
 		c.JSON(http.StatusBadRequest, models.ErrorResponse{
 			Error: "Invalid schedule preference. Must be one of: Weekdays, Weekends, Evening Classes, Flexible",
 		})
 		return
 	}
 
-	class, err := h.db.CreateClass(c.Request.Context(), &req)
+	// Validate class type against the catalog, and the cohort if one was chosen
+	offerings, err := h.db.GetClassOfferings(c.Request.Context(), false)
 	if err != nil {
-		log.Printf("Error creating class enrollment: %v", err)
+		log.Printf("Error getting class offerings: %v", err)
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
 			Error: "Failed to enroll in class",
 		})
 		return
 	}
 
-	c.JSON(http.StatusCreated, models.ClassResponse{
-		Success:    true,
-		Enrollment: *class,
-		Message:    "Class enrollment successful",
-	})
+	if req.CohortID != nil {
+		if !h.validateCohortEnrollment(c, &req, offerings) {
+			return
+		}
+	} else if offering := findOffering(offerings, req.ClassType); offering == nil || !offering.Active {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: "Invalid class type. Must be one of: " + activeOfferingNames(offerings),
+		})
+		return
+	}
+
+	manageToken, manageTokenHash, err := auth.NewOpaqueToken()
+	if err != nil {
+		log.Printf("Error generating manage token: %v", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error: "Failed to enroll in class",
+		})
+		return
+	}
+
+	class, err := h.db.CreateClass(c.Request.Context(), &req, manageTokenHash, h.cfg.CohortFullPolicy == "waitlist")
+	if err != nil {
+		respondClassCatalogError(c, err, "Failed to enroll in class")
+		return
+	}
+
+	response := models.ClassResponse{
+		Success:     true,
+		Enrollment:  *class,
+		Message:     "Class enrollment successful",
+		ManageToken: manageToken,
+	}
+
+	if class.Status == "waitlisted" {
+		token, err := h.joinCohortWaitlist(c.Request.Context(), class)
+		if err != nil {
+			log.Printf("Error adding class %d to the waitlist: %v", class.ID, err)
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+				Error: "Failed to enroll in class",
+			})
+			return
+		}
+		response.Message = "This cohort is full. You have been added to the waitlist"
+		response.WaitlistToken = token
+	}
+
+	h.publisher.Publish(c.Request.Context(), webhooks.ClassCreated, class)
+	event := notify.EnrollmentReceived
+	if class.Status == "waitlisted" {
+		event = notify.EnrollmentWaitlisted
+	}
+	h.notifier.Enrollment(c.Request.Context(), event, class, notify.Details{ManageToken: manageToken})
+	if class.Status == "pending" {
+		h.scheduleClassReminders(c.Request.Context(), class)
+	}
+
+	c.JSON(http.StatusCreated, response)
+}
+
+// validateCohortEnrollment checks that the chosen cohort is open and belongs
+// to the requested class type, filling in the class type when it was left out.
+func (h *Handlers) validateCohortEnrollment(c *gin.Context, req *models.ClassRequest, offerings []models.ClassOffering) bool {
+	cohort, err := h.db.GetCohort(c.Request.Context(), *req.CohortID)
+	if err != nil {
+		respondClassCatalogError(c, err, "Failed to enroll in class")
+		return false
+	}
+
+	if !cohort.Active || cohort.StartDate < h.scheduler.Today(time.Now()) {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: "This cohort is not open for enrollment",
+		})
+		return false
+	}
+
+	var offering *models.ClassOffering
+	for i := range offerings {
+		if offerings[i].ID == cohort.OfferingID {
+			offering = &offerings[i]
+		}
+	}
+	if offering == nil || !offering.Active {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: "This cohort is not open for enrollment",
+		})
+		return false
+	}
+
+	if req.ClassType == "" {
+		req.ClassType = offering.Name
+	} else if req.ClassType != offering.Name {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: "Cohort does not belong to class type " + req.ClassType,
+		})
+		return false
+	}
+
+	return true
+}
+
+// normalizePhone rewrites *number in E.164, answering 400 Bad Request if it
+// isn't a valid phone number.
+func (h *Handlers) normalizePhone(c *gin.Context, number *string) bool {
+	normalized, err := h.phones.Normalize(*number)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: "Invalid phone number: " + err.Error(),
+		})
+		return false
+	}
+	*number = normalized
+	return true
+}
+
+// phoneFilter normalizes a phone number to filter by, so it matches however
+// it was written. A number that can't be normalized is used as given.
+func (h *Handlers) phoneFilter(number string) string {
+	number = strings.TrimSpace(number)
+	if normalized, err := h.phones.Normalize(number); err == nil {
+		return normalized
+	}
+	return number
 }
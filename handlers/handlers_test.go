@@ -0,0 +1,225 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"mumuni_backend/config"
+	"mumuni_backend/database"
+	"mumuni_backend/models"
+	"mumuni_backend/notify"
+	"mumuni_backend/phone"
+	"mumuni_backend/ratelimit"
+	"mumuni_backend/scheduling"
+	"mumuni_backend/spam"
+	"mumuni_backend/webhooks"
+
+	"github.com/gin-gonic/gin"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// newTestHandlers returns handlers over a fresh memory store, configured
+// with the defaults and then by configure.
+func newTestHandlers(t *testing.T, configure ...func(*config.Config)) (*Handlers, *database.MemoryStore) {
+	t.Helper()
+
+	cfg := config.LoadConfig()
+	cfg.DatabaseDriver = "memory"
+	for _, f := range configure {
+		f(cfg)
+	}
+
+	store := database.NewMemoryStore()
+	scheduler, err := scheduling.NewEngine(cfg)
+	if err != nil {
+		t.Fatalf("NewEngine: %v", err)
+	}
+	notifier, err := notify.NewNotifier(cfg, store)
+	if err != nil {
+		t.Fatalf("NewNotifier: %v", err)
+	}
+	guard, err := spam.NewGuard(cfg, ratelimit.NewMemoryStore())
+	if err != nil {
+		t.Fatalf("NewGuard: %v", err)
+	}
+	phones, err := phone.NewParser(cfg.DefaultPhoneCountry)
+	if err != nil {
+		t.Fatalf("NewParser: %v", err)
+	}
+
+	return NewHandlers(cfg, store, scheduler, notifier, webhooks.NewPublisher(store), guard, phones), store
+}
+
+// asAdmin stands in for AuthMiddleware, signing the request in as an admin
+// with role, linked to artistID if it isn't nil.
+func asAdmin(role string, artistID *int) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set("admin_id", "admin-1")
+		c.Set("admin_role", role)
+		if artistID != nil {
+			c.Set("admin_artist_id", *artistID)
+		}
+		c.Next()
+	}
+}
+
+// serve sends a request with body encoded as JSON to r and returns the
+// recorded response.
+func serve(r http.Handler, method, path string, body any) *httptest.ResponseRecorder {
+	var payload bytes.Buffer
+	if body != nil {
+		json.NewEncoder(&payload).Encode(body)
+	}
+	req := httptest.NewRequest(method, path, &payload)
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+// decode decodes the JSON response in w into v.
+func decode(t *testing.T, w *httptest.ResponseRecorder, v any) {
+	t.Helper()
+	if err := json.Unmarshal(w.Body.Bytes(), v); err != nil {
+		t.Fatalf("invalid JSON response %q: %v", w.Body.String(), err)
+	}
+}
+
+// bookingDate returns a weekday at least days from today.
+func bookingDate(days int) string {
+	d := time.Now().AddDate(0, 0, days)
+	for d.Weekday() == time.Saturday || d.Weekday() == time.Sunday {
+		d = d.AddDate(0, 0, 1)
+	}
+	return d.Format(scheduling.DateLayout)
+}
+
+// appointmentRequest returns a valid booking for email at clock on a
+// weekday two weeks out.
+func appointmentRequest(email, clock string) map[string]any {
+	return map[string]any{
+		"name":    "Test Customer",
+		"email":   email,
+		"phone":   "0803 111 2222",
+		"date":    bookingDate(14),
+		"time":    clock,
+		"service": "Everyday Glam",
+	}
+}
+
+func TestBookAppointment(t *testing.T) {
+	h, store := newTestHandlers(t)
+	r := gin.New()
+	r.POST("/api/appointments", h.BookAppointment)
+
+	w := serve(r, http.MethodPost, "/api/appointments", appointmentRequest("ada@example.com", "10:00"))
+	if w.Code != http.StatusCreated {
+		t.Fatalf("booking: got %d %s, want 201", w.Code, w.Body)
+	}
+	var resp models.AppointmentResponse
+	decode(t, w, &resp)
+	if resp.ManageToken == "" {
+		t.Error("booking response has no manage token")
+	}
+	if got := resp.Appointment; got.Status != "pending" || got.AppointmentTime != "10:00 AM" || got.Phone != "+2348031112222" {
+		t.Errorf("booked appointment = %+v, want a pending 10:00 AM appointment for +2348031112222", got)
+	}
+
+	stored, err := store.GetAppointment(context.Background(), resp.Appointment.ID)
+	if err != nil {
+		t.Fatalf("GetAppointment: %v", err)
+	}
+	if stored.Email != "ada@example.com" || stored.StartsAt == nil {
+		t.Errorf("stored appointment = %+v, want ada@example.com's with a start time", stored)
+	}
+}
+
+func TestBookAppointmentRejects(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(map[string]any)
+		want   int
+	}{
+		{"missing email", func(req map[string]any) { delete(req, "email") }, http.StatusBadRequest},
+		{"unknown service", func(req map[string]any) { req["service"] = "Face Painting" }, http.StatusBadRequest},
+		{"closed day", func(req map[string]any) {
+			d, _ := time.Parse(scheduling.DateLayout, bookingDate(14))
+			for d.Weekday() != time.Sunday {
+				d = d.AddDate(0, 0, 1)
+			}
+			req["date"] = d.Format(scheduling.DateLayout)
+		}, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, store := newTestHandlers(t)
+			r := gin.New()
+			r.POST("/api/appointments", h.BookAppointment)
+
+			req := appointmentRequest("ada@example.com", "10:00 AM")
+			tt.change(req)
+			if w := serve(r, http.MethodPost, "/api/appointments", req); w.Code != tt.want {
+				t.Fatalf("got %d %s, want %d", w.Code, w.Body, tt.want)
+			}
+			if _, total, _ := store.GetAppointments(context.Background(), database.AppointmentFilter{}, database.Page{}); total != 0 {
+				t.Errorf("%d appointments stored, want none", total)
+			}
+		})
+	}
+}
+
+func TestBookAppointmentRejectsOverlap(t *testing.T) {
+	h, _ := newTestHandlers(t)
+	r := gin.New()
+	r.POST("/api/appointments", h.BookAppointment)
+
+	if w := serve(r, http.MethodPost, "/api/appointments", appointmentRequest("ada@example.com", "10:00 AM")); w.Code != http.StatusCreated {
+		t.Fatalf("first booking: got %d %s, want 201", w.Code, w.Body)
+	}
+	if w := serve(r, http.MethodPost, "/api/appointments", appointmentRequest("bola@example.com", "10:30 AM")); w.Code != http.StatusConflict {
+		t.Fatalf("overlapping booking: got %d %s, want 409", w.Code, w.Body)
+	}
+}
+
+func TestUpdateAppointmentStatus(t *testing.T) {
+	h, store := newTestHandlers(t)
+	r := gin.New()
+	r.POST("/api/appointments", h.BookAppointment)
+	r.PUT("/api/admin/appointments/:id/status", asAdmin("owner", nil), h.UpdateAppointmentStatus)
+	r.GET("/api/admin/appointments", asAdmin("owner", nil), h.GetAppointments)
+
+	var booked models.AppointmentResponse
+	decode(t, serve(r, http.MethodPost, "/api/appointments", appointmentRequest("ada@example.com", "10:00 AM")), &booked)
+	path := "/api/admin/appointments/" + strconv.Itoa(booked.Appointment.ID) + "/status"
+
+	if w := serve(r, http.MethodPut, path, map[string]string{"status": "confirmed"}); w.Code != http.StatusOK {
+		t.Fatalf("confirming: got %d %s, want 200", w.Code, w.Body)
+	}
+	stored, _ := store.GetAppointment(context.Background(), booked.Appointment.ID)
+	if stored.Status != "confirmed" {
+		t.Errorf("stored status = %q, want confirmed", stored.Status)
+	}
+	if w := serve(r, http.MethodPut, path, map[string]string{"status": "pending"}); w.Code == http.StatusOK {
+		t.Errorf("moving a confirmed appointment back to pending: got 200, want it refused")
+	}
+	if w := serve(r, http.MethodPut, "/api/admin/appointments/999/status", map[string]string{"status": "confirmed"}); w.Code != http.StatusNotFound {
+		t.Errorf("unknown appointment: got %d, want 404", w.Code)
+	}
+
+	var list struct {
+		Appointments []models.Appointment `json:"appointments"`
+	}
+	decode(t, serve(r, http.MethodGet, "/api/admin/appointments?status=confirmed", nil), &list)
+	if len(list.Appointments) != 1 || list.Appointments[0].ID != booked.Appointment.ID {
+		t.Errorf("confirmed appointments = %+v, want the one booked", list.Appointments)
+	}
+}
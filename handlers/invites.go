@@ -0,0 +1,195 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"mumuni_backend/auth"
+	"mumuni_backend/database"
+	"mumuni_backend/models"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetAdminInvites handles GET /api/admin/invites
+func (h *Handlers) GetAdminInvites(c *gin.Context) {
+	invites, err := h.db.GetAdminInvites(c.Request.Context())
+	if err != nil {
+		log.Printf("Error getting admin invites: %v", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error: "Failed to fetch invites",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"invites": invites,
+		"count":   len(invites),
+	})
+}
+
+// CreateAdminInvite handles POST /api/admin/invites
+// The invite token is only returned here, once; hand the link to the person
+// invited. It can be used a single time before ADMIN_INVITE_TTL runs out.
+func (h *Handlers) CreateAdminInvite(c *gin.Context) {
+	var req models.AdminInviteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: "Invalid request data: " + err.Error(),
+		})
+		return
+	}
+	if req.Role != auth.RoleArtist {
+		req.ArtistID = nil
+	} else if req.ArtistID == nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: "An artist admin must be linked to an artist with artist_id",
+		})
+		return
+	}
+
+	ctx := c.Request.Context()
+	_, err := h.db.GetAdminByEmail(ctx, req.Email)
+	if err == nil {
+		c.JSON(http.StatusConflict, models.ErrorResponse{
+			Error: "Admin with this email already exists",
+		})
+		return
+	}
+	if !errors.Is(err, database.ErrAdminNotFound) {
+		respondInviteError(c, err, "Failed to create invite")
+		return
+	}
+	if req.ArtistID != nil {
+		if _, err := h.db.GetArtist(ctx, *req.ArtistID); err != nil {
+			respondInviteError(c, err, "Failed to create invite")
+			return
+		}
+	}
+
+	token, tokenHash, err := auth.NewOpaqueToken()
+	if err != nil {
+		respondInviteError(c, err, "Failed to create invite")
+		return
+	}
+	invitedBy := c.GetString("admin_id")
+	invite, err := h.db.CreateAdminInvite(ctx, &models.AdminInvite{
+		Email:     req.Email,
+		Role:      req.Role,
+		ArtistID:  req.ArtistID,
+		InvitedBy: &invitedBy,
+		ExpiresAt: time.Now().UTC().Add(h.cfg.AdminInviteTTL),
+	}, tokenHash)
+	if err != nil {
+		respondInviteError(c, err, "Failed to create invite")
+		return
+	}
+
+	response := gin.H{
+		"success": true,
+		"message": "Invite created successfully",
+		"invite":  invite,
+		"token":   token,
+	}
+	if h.cfg.AdminInviteURL != "" {
+		response["link"] = strings.ReplaceAll(h.cfg.AdminInviteURL, "{token}", token)
+	}
+	c.JSON(http.StatusCreated, response)
+}
+
+// RevokeAdminInvite handles DELETE /api/admin/invites/:id
+// Only pending invites can be revoked.
+func (h *Handlers) RevokeAdminInvite(c *gin.Context) {
+	inviteID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: "Invalid invite ID",
+		})
+		return
+	}
+
+	invite, err := h.db.RevokeAdminInvite(c.Request.Context(), inviteID)
+	if err != nil {
+		respondInviteError(c, err, "Failed to revoke invite")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Invite revoked successfully",
+		"invite":  invite,
+	})
+}
+
+// GetInvite handles GET /api/admin/register/:token
+// It shows who an invite is for, so the registration page can greet them.
+func (h *Handlers) GetInvite(c *gin.Context) {
+	invite, err := h.db.GetAdminInviteByToken(c.Request.Context(), auth.HashOpaqueToken(c.Param("token")))
+	if err != nil {
+		respondInviteError(c, err, "Failed to fetch invite")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":    true,
+		"email":      invite.Email,
+		"role":       invite.Role,
+		"expires_at": invite.ExpiresAt,
+	})
+}
+
+// RegisterAdmin handles POST /api/admin/register/:token
+// The new admin gets the email and role the invite was made for, and the
+// invite can't be used again.
+func (h *Handlers) RegisterAdmin(c *gin.Context) {
+	var req models.AdminRegisterRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: "Invalid request data: " + err.Error(),
+		})
+		return
+	}
+	if !h.checkPassword(c, req.Password) {
+		return
+	}
+
+	hashedPassword, err := auth.HashPassword(req.Password)
+	if err != nil {
+		log.Printf("Error hashing password: %v", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error: "Failed to process password",
+		})
+		return
+	}
+
+	admin, err := h.db.RedeemAdminInvite(c.Request.Context(), auth.HashOpaqueToken(c.Param("token")), hashedPassword, req.Name)
+	if err != nil {
+		respondInviteError(c, err, "Failed to create admin account")
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"message": "Admin account created successfully",
+		"admin":   admin,
+	})
+}
+
+func respondInviteError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, database.ErrInviteNotFound):
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Error: "Invite not found, already used or expired",
+		})
+	case errors.Is(err, database.ErrAdminExists):
+		c.JSON(http.StatusConflict, models.ErrorResponse{
+			Error: "Admin with this email already exists",
+		})
+	default:
+		respondAdminError(c, err, message)
+	}
+}
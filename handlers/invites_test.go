@@ -0,0 +1,193 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"mumuni_backend/auth"
+	"mumuni_backend/config"
+	"mumuni_backend/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// inviteRouter serves the invite routes, with those for owners signed in
+// as one.
+func inviteRouter(h *Handlers) *gin.Engine {
+	r := gin.New()
+	r.GET("/api/admin/invites", asAdmin(auth.RoleOwner, nil), h.GetAdminInvites)
+	r.POST("/api/admin/invites", asAdmin(auth.RoleOwner, nil), h.CreateAdminInvite)
+	r.DELETE("/api/admin/invites/:id", asAdmin(auth.RoleOwner, nil), h.RevokeAdminInvite)
+	r.GET("/api/admin/register/:token", h.GetInvite)
+	r.POST("/api/admin/register/:token", h.RegisterAdmin)
+	return r
+}
+
+// invite invites email as role and returns the invite with its token.
+func invite(t *testing.T, r *gin.Engine, email, role string, artistID *int) (models.AdminInvite, string) {
+	t.Helper()
+	w := serve(r, http.MethodPost, "/api/admin/invites", map[string]any{
+		"email":     email,
+		"role":      role,
+		"artist_id": artistID,
+	})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("inviting %s: got %d %s, want 201", email, w.Code, w.Body)
+	}
+	var resp struct {
+		Invite models.AdminInvite `json:"invite"`
+		Token  string             `json:"token"`
+	}
+	decode(t, w, &resp)
+	return resp.Invite, resp.Token
+}
+
+func register(r *gin.Engine, token string) *httptest.ResponseRecorder {
+	return serve(r, http.MethodPost, "/api/admin/register/"+token, map[string]string{
+		"name":     "New Admin",
+		"password": "Brand-new-password-2",
+	})
+}
+
+// inviteStatus returns the status the invite list gives inviteID.
+func inviteStatus(t *testing.T, r *gin.Engine, inviteID int) string {
+	t.Helper()
+	var resp struct {
+		Invites []models.AdminInvite `json:"invites"`
+	}
+	decode(t, serve(r, http.MethodGet, "/api/admin/invites", nil), &resp)
+	for _, invite := range resp.Invites {
+		if invite.ID == inviteID {
+			return invite.Status
+		}
+	}
+	t.Fatalf("invite %d not listed", inviteID)
+	return ""
+}
+
+func TestRegisterGivesTheInvitedRole(t *testing.T) {
+	h, store := newTestHandlers(t)
+	ctx := context.Background()
+	r := inviteRouter(h)
+
+	artist, err := store.CreateArtist(ctx, &models.ArtistRequest{Name: "Amaka", Skills: []string{"Everyday Glam"}})
+	if err != nil {
+		t.Fatalf("CreateArtist: %v", err)
+	}
+	tests := []struct {
+		email    string
+		role     string
+		artistID *int
+	}{
+		{"manager@example.com", auth.RoleManager, nil},
+		{"artist@example.com", auth.RoleArtist, &artist.ID},
+		// Only artists are linked to an artist
+		{"instructor@example.com", auth.RoleInstructor, &artist.ID},
+	}
+	for _, tt := range tests {
+		invited, token := invite(t, r, tt.email, tt.role, tt.artistID)
+
+		var show struct {
+			Email string `json:"email"`
+			Role  string `json:"role"`
+		}
+		decode(t, serve(r, http.MethodGet, "/api/admin/register/"+token, nil), &show)
+		if show.Email != tt.email || show.Role != tt.role {
+			t.Errorf("invite shows %s as %s, want %s as %s", show.Email, show.Role, tt.email, tt.role)
+		}
+
+		w := register(r, token)
+		if w.Code != http.StatusCreated {
+			t.Fatalf("registering %s: got %d %s, want 201", tt.email, w.Code, w.Body)
+		}
+		admin, err := store.GetAdminByEmail(ctx, tt.email)
+		if err != nil {
+			t.Fatalf("GetAdminByEmail(%s): %v", tt.email, err)
+		}
+		if admin.Role != tt.role || admin.Name != "New Admin" {
+			t.Errorf("%s registered as %s named %q, want %s", tt.email, admin.Role, admin.Name, tt.role)
+		}
+		if tt.role == auth.RoleArtist {
+			if admin.ArtistID == nil || *admin.ArtistID != artist.ID {
+				t.Errorf("artist admin linked to %v, want %d", admin.ArtistID, artist.ID)
+			}
+		} else if admin.ArtistID != nil {
+			t.Errorf("%s admin linked to artist %d, want none", tt.role, *admin.ArtistID)
+		}
+		if hash, _ := store.GetAdminPasswordHash(ctx, tt.email); !auth.CheckPasswordHash("Brand-new-password-2", hash) {
+			t.Errorf("%s can't sign in with the password they registered", tt.email)
+		}
+		if status := inviteStatus(t, r, invited.ID); status != "accepted" {
+			t.Errorf("%s's invite is %q, want accepted", tt.email, status)
+		}
+	}
+}
+
+func TestInviteWorksOnce(t *testing.T) {
+	h, store := newTestHandlers(t)
+	r := inviteRouter(h)
+
+	_, token := invite(t, r, "manager@example.com", auth.RoleManager, nil)
+	if w := register(r, token); w.Code != http.StatusCreated {
+		t.Fatalf("first use: got %d %s, want 201", w.Code, w.Body)
+	}
+	if w := register(r, token); w.Code != http.StatusNotFound {
+		t.Errorf("second use: got %d %s, want 404", w.Code, w.Body)
+	}
+	if w := serve(r, http.MethodGet, "/api/admin/register/"+token, nil); w.Code != http.StatusNotFound {
+		t.Errorf("viewing a used invite: got %d, want 404", w.Code)
+	}
+	if admins, _ := store.GetAdmins(context.Background()); len(admins) != 1 {
+		t.Errorf("%d admins, want the one registered", len(admins))
+	}
+
+	// Nor can the same email be invited again
+	w := serve(r, http.MethodPost, "/api/admin/invites", map[string]string{
+		"email": "manager@example.com",
+		"role":  auth.RoleManager,
+	})
+	if w.Code != http.StatusConflict {
+		t.Errorf("inviting an admin: got %d, want 409", w.Code)
+	}
+}
+
+func TestExpiredInviteIsRefused(t *testing.T) {
+	h, store := newTestHandlers(t, func(cfg *config.Config) {
+		cfg.AdminInviteTTL = -time.Minute
+	})
+	r := inviteRouter(h)
+
+	invited, token := invite(t, r, "manager@example.com", auth.RoleManager, nil)
+	if w := serve(r, http.MethodGet, "/api/admin/register/"+token, nil); w.Code != http.StatusNotFound {
+		t.Errorf("viewing an expired invite: got %d, want 404", w.Code)
+	}
+	if w := register(r, token); w.Code != http.StatusNotFound {
+		t.Errorf("registering with an expired invite: got %d %s, want 404", w.Code, w.Body)
+	}
+	if _, err := store.GetAdminByEmail(context.Background(), "manager@example.com"); err == nil {
+		t.Error("an expired invite created an admin")
+	}
+	if status := inviteStatus(t, r, invited.ID); status != "expired" {
+		t.Errorf("invite is %q, want expired", status)
+	}
+}
+
+func TestRevokedInviteIsRefused(t *testing.T) {
+	h, _ := newTestHandlers(t)
+	r := inviteRouter(h)
+
+	invited, token := invite(t, r, "manager@example.com", auth.RoleManager, nil)
+	if w := serve(r, http.MethodDelete, "/api/admin/invites/"+strconv.Itoa(invited.ID), nil); w.Code != http.StatusOK {
+		t.Fatalf("revoking: got %d %s, want 200", w.Code, w.Body)
+	}
+	if w := register(r, token); w.Code != http.StatusNotFound {
+		t.Errorf("registering with a revoked invite: got %d %s, want 404", w.Code, w.Body)
+	}
+	if w := serve(r, http.MethodDelete, "/api/admin/invites/"+strconv.Itoa(invited.ID), nil); w.Code != http.StatusNotFound {
+		t.Errorf("revoking again: got %d, want 404", w.Code)
+	}
+}
@@ -0,0 +1,217 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"mumuni_backend/database"
+	"mumuni_backend/jobs"
+	"mumuni_backend/models"
+	"mumuni_backend/notify"
+	"mumuni_backend/scheduling"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// jobStatuses are the values accepted by GetJobs' status filter.
+var jobStatuses = map[string]bool{
+	"pending":   true,
+	"done":      true,
+	"cancelled": true,
+	"failed":    true,
+	"all":       true,
+}
+
+// GetJobs handles GET /api/admin/jobs?status=&kind=&resource_type=&resource_id=
+// It lists upcoming (pending) jobs unless another status, or "all", is asked for.
+func (h *Handlers) GetJobs(c *gin.Context) {
+	filter := database.JobFilter{
+		Kind:         c.Query("kind"),
+		Status:       c.DefaultQuery("status", "pending"),
+		ResourceType: c.Query("resource_type"),
+	}
+	if !jobStatuses[filter.Status] {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: "Invalid status. Must be one of: pending, done, cancelled, failed, all",
+		})
+		return
+	}
+	if filter.Status == "all" {
+		filter.Status = ""
+	}
+	if value := c.Query("resource_id"); value != "" {
+		resourceID, err := strconv.Atoi(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error: "Invalid resource ID",
+			})
+			return
+		}
+		filter.ResourceID = &resourceID
+	}
+
+	jobs, err := h.db.GetJobs(c.Request.Context(), filter)
+	if err != nil {
+		log.Printf("Error getting jobs: %v", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error: "Failed to fetch jobs",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"jobs":    jobs,
+		"count":   len(jobs),
+	})
+}
+
+// reminderPayload is the payload of a reminder job: when the appointment or
+// class session it is for starts, and how long before that it is sent.
+type reminderPayload struct {
+	StartsAt time.Time `json:"starts_at"`
+	Offset   string    `json:"offset"`
+}
+
+// RunAppointmentReminder is the jobs.Handler for appointment reminders. The
+// reminder is dropped if the appointment has since been cancelled, completed
+// or moved.
+func (h *Handlers) RunAppointmentReminder(ctx context.Context, job *models.Job) error {
+	var payload reminderPayload
+	if err := json.Unmarshal(job.Payload, &payload); err != nil {
+		return err
+	}
+
+	appointment, err := h.db.GetAppointment(ctx, job.ResourceID)
+	if errors.Is(err, database.ErrAppointmentNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	start, err := h.scheduler.StartTime(appointment.AppointmentDate, appointment.AppointmentTime)
+	if err != nil {
+		return err
+	}
+	if !scheduling.BlocksCalendar(appointment.Status) || !start.Equal(payload.StartsAt) || !start.After(time.Now()) {
+		return nil
+	}
+
+	h.notifier.Appointment(ctx, notify.AppointmentReminder, appointment, notify.Details{})
+	return nil
+}
+
+// RunClassReminder is the jobs.Handler for class session reminders. The
+// reminder is dropped if the enrollment is no longer active or the session
+// is no longer part of its cohort.
+func (h *Handlers) RunClassReminder(ctx context.Context, job *models.Job) error {
+	var payload reminderPayload
+	if err := json.Unmarshal(job.Payload, &payload); err != nil {
+		return err
+	}
+
+	class, err := h.db.GetClass(ctx, job.ResourceID)
+	if errors.Is(err, database.ErrClassNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if (class.Status != "pending" && class.Status != "confirmed") || class.CohortID == nil || !payload.StartsAt.After(time.Now()) {
+		return nil
+	}
+
+	cohort, err := h.db.GetCohort(ctx, *class.CohortID)
+	if errors.Is(err, database.ErrCohortNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	sessions, err := h.scheduler.CohortSessions(cohort)
+	if err != nil {
+		return err
+	}
+
+	for _, session := range sessions {
+		if session.Equal(payload.StartsAt) {
+			h.notifier.Enrollment(ctx, notify.EnrollmentReminder, class, notify.Details{SessionStart: &session})
+			return nil
+		}
+	}
+	return nil
+}
+
+// scheduleAppointmentReminders queues the reminders for an appointment.
+func (h *Handlers) scheduleAppointmentReminders(ctx context.Context, appointment *models.Appointment) {
+	start, err := h.scheduler.StartTime(appointment.AppointmentDate, appointment.AppointmentTime)
+	if err != nil {
+		log.Printf("Error scheduling reminders for appointment %d: %v", appointment.ID, err)
+		return
+	}
+
+	h.scheduleReminders(ctx, jobs.AppointmentReminder, "appointment", appointment.ID, []time.Time{start})
+}
+
+// scheduleClassReminders queues the reminders for every session of an
+// enrollment's cohort. Enrollments without a cohort have no sessions.
+func (h *Handlers) scheduleClassReminders(ctx context.Context, class *models.Class) {
+	if class.CohortID == nil {
+		return
+	}
+
+	cohort, err := h.db.GetCohort(ctx, *class.CohortID)
+	if err != nil {
+		log.Printf("Error scheduling reminders for class %d: %v", class.ID, err)
+		return
+	}
+	sessions, err := h.scheduler.CohortSessions(cohort)
+	if err != nil {
+		log.Printf("Error scheduling reminders for class %d: %v", class.ID, err)
+		return
+	}
+
+	h.scheduleReminders(ctx, jobs.ClassReminder, "class", class.ID, sessions)
+}
+
+// scheduleReminders queues a reminder job at each configured offset before
+// each start, skipping those already in the past. Failures are logged so
+// they never fail the booking.
+func (h *Handlers) scheduleReminders(ctx context.Context, kind, resourceType string, resourceID int, starts []time.Time) {
+	now := time.Now()
+	for _, start := range starts {
+		for _, offset := range h.cfg.ReminderOffsets {
+			runAt := start.Add(-offset)
+			if !runAt.After(now) {
+				continue
+			}
+
+			payload, err := json.Marshal(reminderPayload{StartsAt: start, Offset: offset.String()})
+			if err == nil {
+				_, err = h.db.CreateJob(ctx, &models.Job{
+					Kind:         kind,
+					ResourceType: resourceType,
+					ResourceID:   resourceID,
+					Payload:      payload,
+					RunAt:        runAt,
+				})
+			}
+			if err != nil {
+				log.Printf("Error scheduling reminder for %s %d: %v", resourceType, resourceID, err)
+				return
+			}
+		}
+	}
+}
+
+// cancelReminders cancels the pending reminders of an appointment or class.
+func (h *Handlers) cancelReminders(ctx context.Context, resourceType string, resourceID int) {
+	if err := h.db.CancelJobs(ctx, resourceType, resourceID); err != nil {
+		log.Printf("Error cancelling reminders for %s %d: %v", resourceType, resourceID, err)
+	}
+}
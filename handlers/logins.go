@@ -0,0 +1,177 @@
+package handlers
+
+import (
+	"log"
+	"math"
+	"mumuni_backend/auth"
+	"mumuni_backend/database"
+	"mumuni_backend/models"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Login attempt results
+const (
+	loginSuccess            = "success"
+	loginInvalidCredentials = "invalid_credentials"
+	loginBlocked            = "blocked"
+	loginTwoFactorRequired  = "two_factor_required"
+	loginInvalidTwoFactor   = "invalid_two_factor"
+	// Wrong current passwords go through the login throttle too. Reset
+	// requests are only recorded; they have their own rate limit
+	loginInvalidCurrentPassword = "invalid_current_password"
+	loginPasswordResetRequested = "password_reset_requested"
+)
+
+// loginResults are the values accepted by GetLoginAttempts' result filter.
+var loginResults = map[string]bool{
+	loginSuccess:                true,
+	loginInvalidCredentials:     true,
+	loginBlocked:                true,
+	loginTwoFactorRequired:      true,
+	loginInvalidTwoFactor:       true,
+	loginInvalidCurrentPassword: true,
+	loginPasswordResetRequested: true,
+}
+
+// dummyPasswordHash is checked against when no admin has the email, so the
+// answer takes as long as for a wrong password.
+var dummyPasswordHash, _ = auth.HashPassword("no admin has this password")
+
+// GetLoginAttempts handles GET /api/admin/login-attempts?email=&ip_address=&result=&order=&limit=&offset=
+// Login attempts are listed a page at a time, newest first.
+func (h *Handlers) GetLoginAttempts(c *gin.Context) {
+	filter := database.LoginAttemptFilter{
+		Email:     strings.TrimSpace(c.Query("email")),
+		IPAddress: strings.TrimSpace(c.Query("ip_address")),
+		Result:    c.Query("result"),
+	}
+	if filter.Result != "" && !loginResults[filter.Result] {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: "Invalid result. Must be one of: success, invalid_credentials, blocked, two_factor_required, invalid_two_factor, invalid_current_password, password_reset_requested",
+		})
+		return
+	}
+	page, ok := pageParams(c, database.LoginAttemptSorts)
+	if !ok {
+		return
+	}
+
+	attempts, total, err := h.db.GetLoginAttempts(c.Request.Context(), filter, page)
+	if err != nil {
+		log.Printf("Error getting login attempts: %v", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error: "Failed to fetch login attempts",
+		})
+		return
+	}
+
+	respondPage(c, "login_attempts", attempts, len(attempts), total, page)
+}
+
+// UnlockAdmin handles POST /api/admin/admins/:id/unlock
+// It forgets the admin's failed logins, lifting a lockout or delay on their
+// email. Lockouts of an IP address run their course.
+func (h *Handlers) UnlockAdmin(c *gin.Context) {
+	ctx := c.Request.Context()
+	admin, err := h.db.GetAdmin(ctx, c.Param("id"))
+	if err != nil {
+		respondAdminError(c, err, "Failed to unlock admin")
+		return
+	}
+	if err := h.db.ClearLoginThrottle(ctx, emailThrottleKey(admin.Email)); err != nil {
+		respondAdminError(c, err, "Failed to unlock admin")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Success: true,
+		Message: "Admin unlocked successfully",
+	})
+}
+
+// checkLoginThrottle refuses a login for email with 429 Too Many Requests
+// while it or the client's IP address is locked out or waiting out the delay
+// after a failure, recording the attempt as blocked.
+func (h *Handlers) checkLoginThrottle(c *gin.Context, email string) bool {
+	emailKey, ipKey := emailThrottleKey(email), ipThrottleKey(c)
+	throttles, err := h.db.GetLoginThrottles(c.Request.Context(), []string{emailKey, ipKey})
+	if err != nil {
+		log.Printf("Error getting login throttles: %v", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error: "Failed to log in",
+		})
+		return false
+	}
+
+	var blockedUntil time.Time
+	for _, throttle := range throttles {
+		policy := auth.ThrottlePolicy{MaxFailures: h.cfg.LoginMaxFailures, Lockout: h.cfg.LoginLockout, Delay: h.cfg.LoginDelay}
+		if throttle.Key == ipKey {
+			policy = auth.ThrottlePolicy{MaxFailures: h.cfg.LoginIPMaxFailures, Lockout: h.cfg.LoginLockout}
+		}
+		if until := policy.BlockedUntil(throttle.Failures, throttle.LastFailureAt); until.After(blockedUntil) {
+			blockedUntil = until
+		}
+	}
+
+	wait := time.Until(blockedUntil)
+	if wait <= 0 {
+		return true
+	}
+
+	h.recordLoginAttempt(c, email, loginBlocked)
+	c.Header("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+	c.JSON(http.StatusTooManyRequests, models.ErrorResponse{
+		Error: "Too many failed login attempts. Try again in " + wait.Round(time.Second).String(),
+	})
+	return false
+}
+
+// recordLoginFailure counts a failed login against email and the client's
+// IP address, and records the attempt with result.
+func (h *Handlers) recordLoginFailure(c *gin.Context, email, result string) {
+	now := time.Now().UTC()
+	err := h.db.RecordLoginFailure(c.Request.Context(), []string{emailThrottleKey(email), ipThrottleKey(c)},
+		now, now.Add(-h.cfg.LoginLockout))
+	if err != nil {
+		log.Printf("Error recording login failure: %v", err)
+	}
+	h.recordLoginAttempt(c, email, result)
+}
+
+// completeLogin forgets email's failed logins and starts a session for
+// admin, who has proven who they are.
+func (h *Handlers) completeLogin(c *gin.Context, admin *models.AdminUser, email string) {
+	if err := h.db.ClearLoginThrottle(c.Request.Context(), emailThrottleKey(email)); err != nil {
+		log.Printf("Error clearing login throttle: %v", err)
+	}
+	h.recordLoginAttempt(c, email, loginSuccess)
+	h.startSession(c, admin)
+}
+
+// recordLoginAttempt records a login attempt for email. Failures are
+// logged, not answered.
+func (h *Handlers) recordLoginAttempt(c *gin.Context, email, result string) {
+	err := h.db.CreateLoginAttempt(c.Request.Context(), &models.LoginAttempt{
+		Email:     email,
+		IPAddress: c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+		Result:    result,
+	})
+	if err != nil {
+		log.Printf("Error recording login attempt: %v", err)
+	}
+}
+
+func emailThrottleKey(email string) string {
+	return "email:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+func ipThrottleKey(c *gin.Context) string {
+	return "ip:" + c.ClientIP()
+}
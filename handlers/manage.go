@@ -0,0 +1,391 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"mumuni_backend/auth"
+	"mumuni_backend/database"
+	"mumuni_backend/lifecycle"
+	"mumuni_backend/models"
+	"mumuni_backend/scheduling"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// customerCancellationReason is recorded when a customer cancels without
+// giving a reason.
+const customerCancellationReason = "Cancelled by customer"
+
+// GetManagedAppointment handles GET /api/manage/appointments/:token
+func (h *Handlers) GetManagedAppointment(c *gin.Context) {
+	appointment, ok := h.appointmentFromManageToken(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":     true,
+		"appointment": appointment,
+	})
+}
+
+// RescheduleManagedAppointment handles PUT /api/manage/appointments/:token
+func (h *Handlers) RescheduleManagedAppointment(c *gin.Context) {
+	appointment, ok := h.appointmentFromManageToken(c)
+	if !ok {
+		return
+	}
+
+	var req models.RescheduleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: "Invalid request data: " + err.Error(),
+		})
+		return
+	}
+
+	if !scheduling.BlocksCalendar(appointment.Status) {
+		respondBookingClosed(c)
+		return
+	}
+	if !h.beforeCutoff(c, appointment.AppointmentDate, appointment.AppointmentTime, h.cfg.RescheduleCutoff, "rescheduled") {
+		return
+	}
+
+	// Keep the artist unless the customer picked another one
+	artistID := appointment.ArtistID
+	if req.ArtistID != nil {
+		artistID = req.ArtistID
+	}
+	booking := models.AppointmentRequest{
+		Date:     req.Date,
+		Time:     req.Time,
+		Service:  appointment.Service,
+		ArtistID: artistID,
+	}
+
+	ctx := c.Request.Context()
+	var rescheduled *models.Appointment
+	check, err := h.slotCheck(ctx, &booking)
+	if err == nil {
+		rescheduled, err = h.db.RescheduleAppointment(ctx, appointment.ID, &booking, check)
+	}
+	if err != nil {
+		var serviceErr *invalidServiceError
+		switch {
+		case errors.As(err, &serviceErr):
+			c.JSON(http.StatusConflict, models.ErrorResponse{
+				Error: "This service can no longer be booked. Please contact the studio",
+			})
+		case scheduling.IsSlotError(err):
+			h.respondSlotError(c, err)
+		case errors.Is(err, database.ErrBookingClosed):
+			respondBookingClosed(c)
+		default:
+			log.Printf("Error rescheduling appointment %d: %v", appointment.ID, err)
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+				Error: "Failed to reschedule appointment",
+			})
+		}
+		return
+	}
+
+	// Remind the customer about the new time instead of the old one
+	h.cancelReminders(ctx, "appointment", appointment.ID)
+	h.scheduleAppointmentReminders(ctx, rescheduled)
+
+	// The old slot is free for the waitlist
+	h.fillDay(ctx, appointment.AppointmentDate, appointment.AppointmentTime)
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":     true,
+		"message":     "Appointment rescheduled successfully",
+		"appointment": rescheduled,
+	})
+}
+
+// CancelManagedAppointment handles POST /api/manage/appointments/:token/cancel
+func (h *Handlers) CancelManagedAppointment(c *gin.Context) {
+	appointment, ok := h.appointmentFromManageToken(c)
+	if !ok {
+		return
+	}
+
+	reason, ok := bindCancellationReason(c)
+	if !ok {
+		return
+	}
+
+	if len(lifecycle.Next(appointment.Status)) == 0 {
+		respondBookingClosed(c)
+		return
+	}
+	if !h.beforeCutoff(c, appointment.AppointmentDate, appointment.AppointmentTime, h.cfg.CancellationCutoff, "cancelled") {
+		return
+	}
+
+	appointment, err := h.db.UpdateAppointmentStatus(c.Request.Context(), appointment.ID, database.StatusChange{
+		Status: "cancelled",
+		Reason: reason,
+	})
+	if err != nil {
+		if respondLifecycleError(c, err) {
+			return
+		}
+		log.Printf("Error cancelling appointment: %v", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error: "Failed to cancel appointment",
+		})
+		return
+	}
+
+	h.appointmentStatusChanged(c.Request.Context(), appointment)
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":     true,
+		"message":     "Appointment cancelled",
+		"appointment": appointment,
+	})
+}
+
+// GetManagedClass handles GET /api/manage/classes/:token
+func (h *Handlers) GetManagedClass(c *gin.Context) {
+	class, ok := h.classFromManageToken(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":    true,
+		"enrollment": class,
+	})
+}
+
+// ChangeManagedClassCohort handles PUT /api/manage/classes/:token
+// The enrollment moves to another open cohort of the same class with a seat
+// left.
+func (h *Handlers) ChangeManagedClassCohort(c *gin.Context) {
+	class, ok := h.classFromManageToken(c)
+	if !ok {
+		return
+	}
+
+	var req models.CohortChangeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: "Invalid request data: " + err.Error(),
+		})
+		return
+	}
+
+	if class.Status != "pending" && class.Status != "confirmed" {
+		respondBookingClosed(c)
+		return
+	}
+	if class.CohortID != nil && *class.CohortID == req.CohortID {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: "You are already enrolled in this cohort",
+		})
+		return
+	}
+	if !h.beforeCohortCutoff(c, class, h.cfg.RescheduleCutoff, "moved to another cohort") {
+		return
+	}
+
+	ctx := c.Request.Context()
+	offerings, err := h.db.GetClassOfferings(ctx, false)
+	if err != nil {
+		log.Printf("Error getting class offerings: %v", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error: "Failed to change cohort",
+		})
+		return
+	}
+
+	enrollment := models.ClassRequest{ClassType: class.ClassType, CohortID: &req.CohortID}
+	if !h.validateCohortEnrollment(c, &enrollment, offerings) {
+		return
+	}
+
+	moved, err := h.db.ChangeClassCohort(ctx, class.ID, req.CohortID)
+	if err != nil {
+		if errors.Is(err, database.ErrBookingClosed) {
+			respondBookingClosed(c)
+			return
+		}
+		respondClassCatalogError(c, err, "Failed to change cohort")
+		return
+	}
+
+	// Remind the customer about the new cohort's sessions instead
+	h.cancelReminders(ctx, "class", class.ID)
+	h.scheduleClassReminders(ctx, moved)
+
+	// The old seat is free for the waitlist
+	if class.CohortID != nil {
+		h.fillCohort(ctx, *class.CohortID)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":    true,
+		"message":    "Cohort changed successfully",
+		"enrollment": moved,
+	})
+}
+
+// CancelManagedClass handles POST /api/manage/classes/:token/cancel
+func (h *Handlers) CancelManagedClass(c *gin.Context) {
+	class, ok := h.classFromManageToken(c)
+	if !ok {
+		return
+	}
+
+	reason, ok := bindCancellationReason(c)
+	if !ok {
+		return
+	}
+
+	if len(lifecycle.Next(class.Status)) == 0 {
+		respondBookingClosed(c)
+		return
+	}
+	if !h.beforeCohortCutoff(c, class, h.cfg.CancellationCutoff, "cancelled") {
+		return
+	}
+
+	class, err := h.db.UpdateClassStatus(c.Request.Context(), class.ID, database.StatusChange{
+		Status: "cancelled",
+		Reason: reason,
+	})
+	if err != nil {
+		if respondLifecycleError(c, err) {
+			return
+		}
+		log.Printf("Error cancelling class enrollment: %v", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error: "Failed to cancel enrollment",
+		})
+		return
+	}
+
+	h.classStatusChanged(c.Request.Context(), class)
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":    true,
+		"message":    "Enrollment cancelled",
+		"enrollment": class,
+	})
+}
+
+// beforeCutoff reports whether a booking starting at date and clock is far
+// enough away to still be changed, responding with 409 Conflict when not.
+func (h *Handlers) beforeCutoff(c *gin.Context, date, clock string, cutoff time.Duration, action string) bool {
+	start, err := h.scheduler.StartTime(date, clock)
+	if err != nil {
+		log.Printf("Error reading booking start %s %s: %v", date, clock, err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error: "Failed to update booking",
+		})
+		return false
+	}
+
+	if time.Until(start) < cutoff {
+		c.JSON(http.StatusConflict, models.ErrorResponse{
+			Error: fmt.Sprintf("Bookings can't be %s less than %s before they start. Please contact the studio", action, formatCutoff(cutoff)),
+		})
+		return false
+	}
+	return true
+}
+
+// beforeCohortCutoff applies beforeCutoff to the start of the enrollment's
+// cohort. Enrollments without a cohort have no start and can always change.
+func (h *Handlers) beforeCohortCutoff(c *gin.Context, class *models.Class, cutoff time.Duration, action string) bool {
+	if class.CohortID == nil {
+		return true
+	}
+
+	cohort, err := h.db.GetCohort(c.Request.Context(), *class.CohortID)
+	if err != nil {
+		respondClassCatalogError(c, err, "Failed to update booking")
+		return false
+	}
+	return h.beforeCutoff(c, cohort.StartDate, "00:00", cutoff, action)
+}
+
+// formatCutoff renders a cut-off the way customers read it, e.g. "24 hours".
+func formatCutoff(d time.Duration) string {
+	switch {
+	case d == time.Hour:
+		return "1 hour"
+	case d%time.Hour == 0:
+		return fmt.Sprintf("%d hours", d/time.Hour)
+	case d%time.Minute == 0:
+		return fmt.Sprintf("%d minutes", d/time.Minute)
+	}
+	return d.String()
+}
+
+// bindCancellationReason reads the optional cancellation body.
+func bindCancellationReason(c *gin.Context) (string, bool) {
+	var req models.CancelBookingRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error: "Invalid request data: " + err.Error(),
+			})
+			return "", false
+		}
+	}
+
+	if strings.TrimSpace(req.Reason) == "" {
+		return customerCancellationReason, true
+	}
+	return req.Reason, true
+}
+
+func respondBookingClosed(c *gin.Context) {
+	c.JSON(http.StatusConflict, models.ErrorResponse{
+		Error: "This booking has been cancelled or completed and can no longer be changed",
+	})
+}
+
+func (h *Handlers) appointmentFromManageToken(c *gin.Context) (*models.Appointment, bool) {
+	appointment, err := h.db.GetAppointmentByManageToken(c.Request.Context(), auth.HashOpaqueToken(c.Param("token")))
+	if err != nil {
+		if errors.Is(err, database.ErrAppointmentNotFound) {
+			c.JSON(http.StatusNotFound, models.ErrorResponse{
+				Error: "Appointment not found",
+			})
+			return nil, false
+		}
+		log.Printf("Error getting appointment by manage token: %v", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error: "Failed to fetch appointment",
+		})
+		return nil, false
+	}
+	return appointment, true
+}
+
+func (h *Handlers) classFromManageToken(c *gin.Context) (*models.Class, bool) {
+	class, err := h.db.GetClassByManageToken(c.Request.Context(), auth.HashOpaqueToken(c.Param("token")))
+	if err != nil {
+		if errors.Is(err, database.ErrClassNotFound) {
+			c.JSON(http.StatusNotFound, models.ErrorResponse{
+				Error: "Enrollment not found",
+			})
+			return nil, false
+		}
+		log.Printf("Error getting class by manage token: %v", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error: "Failed to fetch enrollment",
+		})
+		return nil, false
+	}
+	return class, true
+}
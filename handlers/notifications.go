@@ -0,0 +1,265 @@
+package handlers
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"log"
+	"mumuni_backend/database"
+	"mumuni_backend/models"
+	"mumuni_backend/notify"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// notificationStatuses are the values accepted by GetNotifications' status
+// filter.
+var notificationStatuses = map[string]bool{
+	"pending":   true,
+	"sent":      true,
+	"delivered": true,
+	"failed":    true,
+}
+
+// GetNotifications handles GET /api/admin/notifications?channel=&status=&resource_type=&resource_id=
+// It shows what customers were sent and whether it arrived.
+func (h *Handlers) GetNotifications(c *gin.Context) {
+	filter := database.NotificationFilter{
+		Channel:      c.Query("channel"),
+		Status:       c.Query("status"),
+		ResourceType: c.Query("resource_type"),
+	}
+	if filter.Status != "" && !notificationStatuses[filter.Status] {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: "Invalid status. Must be one of: pending, sent, delivered, failed",
+		})
+		return
+	}
+	if value := c.Query("resource_id"); value != "" {
+		resourceID, err := strconv.Atoi(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error: "Invalid resource ID",
+			})
+			return
+		}
+		filter.ResourceID = &resourceID
+	}
+
+	notifications, err := h.db.GetNotifications(c.Request.Context(), filter)
+	if err != nil {
+		log.Printf("Error getting notifications: %v", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error: "Failed to fetch notifications",
+		})
+		return
+	}
+	for i := range notifications {
+		notify.Redact(&notifications[i])
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":       true,
+		"notifications": notifications,
+		"count":         len(notifications),
+	})
+}
+
+// GetNotificationPreference handles GET /api/admin/notification-preferences/:email
+func (h *Handlers) GetNotificationPreference(c *gin.Context) {
+	h.respondNotificationPreference(c, c.Param("email"), nil)
+}
+
+// UpdateNotificationPreference handles PUT /api/admin/notification-preferences/:email
+func (h *Handlers) UpdateNotificationPreference(c *gin.Context) {
+	h.saveNotificationPreference(c, c.Param("email"))
+}
+
+// GetManagedAppointmentNotifications handles GET /api/manage/appointments/:token/notifications
+func (h *Handlers) GetManagedAppointmentNotifications(c *gin.Context) {
+	appointment, ok := h.appointmentFromManageToken(c)
+	if !ok {
+		return
+	}
+	h.respondNotificationPreference(c, appointment.Email, appointment.Channels)
+}
+
+// UpdateManagedAppointmentNotifications handles PUT /api/manage/appointments/:token/notifications
+// Only the appointment's own channels change. Anyone can book with someone
+// else's email and get a manage link, so the link can't change how that
+// customer hears about their other bookings.
+func (h *Handlers) UpdateManagedAppointmentNotifications(c *gin.Context) {
+	appointment, ok := h.appointmentFromManageToken(c)
+	if !ok {
+		return
+	}
+	h.saveBookingChannels(c, appointment.Email, func(ctx context.Context, channels []string) error {
+		_, err := h.db.UpdateAppointmentChannels(ctx, appointment.ID, channels)
+		return err
+	})
+}
+
+// GetManagedClassNotifications handles GET /api/manage/classes/:token/notifications
+func (h *Handlers) GetManagedClassNotifications(c *gin.Context) {
+	class, ok := h.classFromManageToken(c)
+	if !ok {
+		return
+	}
+	h.respondNotificationPreference(c, class.Email, class.Channels)
+}
+
+// UpdateManagedClassNotifications handles PUT /api/manage/classes/:token/notifications
+// Like UpdateManagedAppointmentNotifications, it changes the enrollment's
+// own channels only.
+func (h *Handlers) UpdateManagedClassNotifications(c *gin.Context) {
+	class, ok := h.classFromManageToken(c)
+	if !ok {
+		return
+	}
+	h.saveBookingChannels(c, class.Email, func(ctx context.Context, channels []string) error {
+		_, err := h.db.UpdateClassChannels(ctx, class.ID, channels)
+		return err
+	})
+}
+
+// RecordDeliveryStatus handles POST /api/notifications/status, where the
+// messaging provider reports whether a text message arrived. The provider
+// authenticates with MESSAGING_WEBHOOK_SECRET in the X-Webhook-Secret
+// header; reports are refused while no secret is configured.
+func (h *Handlers) RecordDeliveryStatus(c *gin.Context) {
+	secret := c.GetHeader("X-Webhook-Secret")
+	if h.cfg.MessagingWebhookSecret == "" || subtle.ConstantTimeCompare([]byte(secret), []byte(h.cfg.MessagingWebhookSecret)) != 1 {
+		c.JSON(http.StatusUnauthorized, models.ErrorResponse{
+			Error: "Invalid webhook secret",
+		})
+		return
+	}
+
+	var req models.DeliveryStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: "Invalid request data: " + err.Error(),
+		})
+		return
+	}
+
+	notification, err := notify.RecordDelivery(c.Request.Context(), h.db, &req)
+	if err != nil {
+		switch {
+		case errors.Is(err, database.ErrNotificationNotFound):
+			c.JSON(http.StatusNotFound, models.ErrorResponse{
+				Error: "Message not found",
+			})
+		case errors.Is(err, notify.ErrUnknownDeliveryStatus):
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error: "Invalid status. Must be one of: queued, sent, delivered, failed, undelivered",
+			})
+		default:
+			log.Printf("Error recording delivery status for message %s: %v", req.ID, err)
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+				Error: "Failed to record delivery status",
+			})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":      true,
+		"notification": notification,
+	})
+}
+
+// respondNotificationPreference writes the channels the customer with email
+// is notified on: bookingChannels, the channels chosen for one booking, when
+// there are any, or else the customer's own. Customers who haven't chosen
+// are emailed.
+func (h *Handlers) respondNotificationPreference(c *gin.Context, email string, bookingChannels []string) {
+	preference, err := h.db.GetNotificationPreference(c.Request.Context(), email)
+	if errors.Is(err, database.ErrPreferenceNotFound) {
+		preference = &models.NotificationPreference{Email: email, Channels: []string{"email"}}
+	} else if err != nil {
+		log.Printf("Error getting notification preference: %v", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error: "Failed to fetch notification preferences",
+		})
+		return
+	}
+	if len(bookingChannels) > 0 {
+		preference.Channels = bookingChannels
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":    true,
+		"preference": preference,
+	})
+}
+
+// saveNotificationPreference replaces the channels of the customer with
+// email from the request body.
+func (h *Handlers) saveNotificationPreference(c *gin.Context, email string) {
+	var req models.NotificationPreferenceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: "Invalid request data: " + err.Error(),
+		})
+		return
+	}
+
+	preference, err := h.db.SaveNotificationPreference(c.Request.Context(), email, uniqueChannels(req.Channels))
+	if err != nil {
+		log.Printf("Error saving notification preference: %v", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error: "Failed to update notification preferences",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":    true,
+		"message":    "Notification preferences updated successfully",
+		"preference": preference,
+	})
+}
+
+// saveBookingChannels sets the channels of one booking of the customer with
+// email from the request body through update, leaving the customer's
+// preference alone.
+func (h *Handlers) saveBookingChannels(c *gin.Context, email string, update func(ctx context.Context, channels []string) error) {
+	var req models.NotificationPreferenceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: "Invalid request data: " + err.Error(),
+		})
+		return
+	}
+
+	channels := uniqueChannels(req.Channels)
+	if err := update(c.Request.Context(), channels); err != nil {
+		log.Printf("Error saving booking notification channels: %v", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error: "Failed to update notification preferences",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":    true,
+		"message":    "Notification preferences updated successfully",
+		"preference": models.NotificationPreference{Email: email, Channels: channels},
+	})
+}
+
+// uniqueChannels drops repeated channels, keeping the first of each.
+func uniqueChannels(channels []string) []string {
+	seen := make(map[string]bool)
+	var unique []string
+	for _, channel := range channels {
+		if !seen[channel] {
+			seen[channel] = true
+			unique = append(unique, channel)
+		}
+	}
+	return unique
+}
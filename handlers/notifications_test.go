@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"mumuni_backend/database"
+	"mumuni_backend/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// queuedChannels returns the channels the customer was sent messages on
+// about the appointment with id.
+func queuedChannels(t *testing.T, store database.Store, id int) []string {
+	t.Helper()
+	notifications, err := store.GetNotifications(context.Background(), database.NotificationFilter{ResourceType: "appointment", ResourceID: &id})
+	if err != nil {
+		t.Fatalf("GetNotifications: %v", err)
+	}
+	var channels []string
+	for _, n := range notifications {
+		if n.Event == "appointment.received" {
+			channels = append(channels, n.Channel)
+		}
+	}
+	return channels
+}
+
+func TestBookingChannelsOnlyApplyToTheBooking(t *testing.T) {
+	h, store := newTestHandlers(t)
+	r := gin.New()
+	r.POST("/api/appointments", h.BookAppointment)
+	ctx := context.Background()
+
+	if _, err := store.SaveNotificationPreference(ctx, "ada@example.com", []string{"whatsapp"}); err != nil {
+		t.Fatalf("SaveNotificationPreference: %v", err)
+	}
+
+	// Anyone can book with someone else's email; their choice of channels
+	// must not change how that customer hears about their other bookings
+	req := appointmentRequest("ada@example.com", "10:00 AM")
+	req["channels"] = []string{"email", "sms", "email"}
+	w := serve(r, http.MethodPost, "/api/appointments", req)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("booking: got %d %s, want 201", w.Code, w.Body)
+	}
+	var booked models.AppointmentResponse
+	decode(t, w, &booked)
+
+	if got := queuedChannels(t, store, booked.Appointment.ID); !reflect.DeepEqual(got, []string{"email", "sms"}) {
+		t.Errorf("booking messages went out on %v, want the booking's email and sms", got)
+	}
+	preference, err := store.GetNotificationPreference(ctx, "ada@example.com")
+	if err != nil || !reflect.DeepEqual(preference.Channels, []string{"whatsapp"}) {
+		t.Errorf("stored preference = %+v, %v; want whatsapp left alone", preference, err)
+	}
+
+	// A booking without channels follows the stored preference
+	w = serve(r, http.MethodPost, "/api/appointments", appointmentRequest("ada@example.com", "2:00 PM"))
+	var other models.AppointmentResponse
+	decode(t, w, &other)
+	if got := queuedChannels(t, store, other.Appointment.ID); !reflect.DeepEqual(got, []string{"whatsapp"}) {
+		t.Errorf("second booking's messages went out on %v, want whatsapp", got)
+	}
+}
+
+func TestManagedNotificationsOnlyChangeTheBooking(t *testing.T) {
+	h, store := newTestHandlers(t)
+	r := gin.New()
+	r.POST("/api/appointments", h.BookAppointment)
+	r.GET("/api/manage/appointments/:token/notifications", h.GetManagedAppointmentNotifications)
+	r.PUT("/api/manage/appointments/:token/notifications", h.UpdateManagedAppointmentNotifications)
+	ctx := context.Background()
+
+	if _, err := store.SaveNotificationPreference(ctx, "ada@example.com", []string{"email"}); err != nil {
+		t.Fatalf("SaveNotificationPreference: %v", err)
+	}
+	var mine models.AppointmentResponse
+	decode(t, serve(r, http.MethodPost, "/api/appointments", appointmentRequest("ada@example.com", "2:00 PM")), &mine)
+
+	// Someone else books with Ada's email and uses the manage link they get
+	req := appointmentRequest("ada@example.com", "10:00 AM")
+	req["channels"] = []string{"sms"}
+	var booked models.AppointmentResponse
+	decode(t, serve(r, http.MethodPost, "/api/appointments", req), &booked)
+	path := "/api/manage/appointments/" + booked.ManageToken + "/notifications"
+
+	var got struct {
+		Preference models.NotificationPreference `json:"preference"`
+	}
+	decode(t, serve(r, http.MethodGet, path, nil), &got)
+	if !reflect.DeepEqual(got.Preference.Channels, []string{"sms"}) {
+		t.Errorf("channels for the booking = %v, want the sms it was booked with", got.Preference.Channels)
+	}
+
+	if w := serve(r, http.MethodPut, path, map[string][]string{"channels": {"whatsapp", "whatsapp"}}); w.Code != http.StatusOK {
+		t.Fatalf("updating: got %d %s, want 200", w.Code, w.Body)
+	}
+	appointment, _ := store.GetAppointment(ctx, booked.Appointment.ID)
+	if !reflect.DeepEqual(appointment.Channels, []string{"whatsapp"}) {
+		t.Errorf("booking channels = %v, want whatsapp", appointment.Channels)
+	}
+	decode(t, serve(r, http.MethodGet, path, nil), &got)
+	if !reflect.DeepEqual(got.Preference.Channels, []string{"whatsapp"}) {
+		t.Errorf("channels for the booking = %v, want whatsapp", got.Preference.Channels)
+	}
+
+	preference, err := store.GetNotificationPreference(ctx, "ada@example.com")
+	if err != nil || !reflect.DeepEqual(preference.Channels, []string{"email"}) {
+		t.Errorf("stored preference = %+v, %v; want Ada's email left alone", preference, err)
+	}
+	if other, _ := store.GetAppointment(ctx, mine.Appointment.ID); len(other.Channels) != 0 {
+		t.Errorf("Ada's own booking channels = %v, want them untouched", other.Channels)
+	}
+
+	if w := serve(r, http.MethodPut, path, map[string][]string{"channels": {}}); w.Code != http.StatusBadRequest {
+		t.Errorf("no channels: got %d, want 400", w.Code)
+	}
+}
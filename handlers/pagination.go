@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"mumuni_backend/database"
+	"mumuni_backend/models"
+	"mumuni_backend/scheduling"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// defaultPageLimit is how many rows a list returns when no limit is
+	// given, and maxPageLimit the most it returns at once.
+	defaultPageLimit = 50
+	maxPageLimit     = 200
+)
+
+// pageParams reads ?limit=&offset=&sort=&order= into a page of a list that
+// can be sorted by sorts. Lists are sorted by their first sortable column,
+// newest first, unless asked otherwise.
+func pageParams(c *gin.Context, sorts []string) (database.Page, bool) {
+	page := database.Page{
+		Sort:       c.DefaultQuery("sort", sorts[0]),
+		Descending: true,
+		Limit:      defaultPageLimit,
+	}
+
+	if value := c.Query("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > maxPageLimit {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error: "Invalid limit. Must be between 1 and " + strconv.Itoa(maxPageLimit),
+			})
+			return page, false
+		}
+		page.Limit = limit
+	}
+	if value := c.Query("offset"); value != "" {
+		offset, err := strconv.Atoi(value)
+		if err != nil || offset < 0 {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error: "Invalid offset",
+			})
+			return page, false
+		}
+		page.Offset = offset
+	}
+
+	sortable := false
+	for _, column := range sorts {
+		if column == page.Sort {
+			sortable = true
+		}
+	}
+	if !sortable {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: "Invalid sort. Must be one of: " + strings.Join(sorts, ", "),
+		})
+		return page, false
+	}
+
+	switch c.DefaultQuery("order", "desc") {
+	case "asc":
+		page.Descending = false
+	case "desc":
+	default:
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: "Invalid order. Must be asc or desc",
+		})
+		return page, false
+	}
+
+	return page, true
+}
+
+// dateParam reads an optional YYYY-MM-DD query parameter.
+func dateParam(c *gin.Context, name string) (string, bool) {
+	value := c.Query(name)
+	if value == "" {
+		return "", true
+	}
+	if _, err := time.Parse(scheduling.DateLayout, value); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: "Invalid " + name + ". Use YYYY-MM-DD",
+		})
+		return "", false
+	}
+	return value, true
+}
+
+// optionalIDParam reads an optional numeric ID query parameter, answering
+// with message if it isn't a number.
+func optionalIDParam(c *gin.Context, name, message string) (*int, bool) {
+	value := c.Query(name)
+	if value == "" {
+		return nil, true
+	}
+	id, err := strconv.Atoi(value)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: message,
+		})
+		return nil, false
+	}
+	return &id, true
+}
+
+// respondPage writes one page of a list under key, with the total number of
+// matching rows and a link to the next page, which is null on the last one.
+func respondPage(c *gin.Context, key string, items any, count, total int, page database.Page) {
+	var next *string
+	if page.Offset+count < total {
+		query := c.Request.URL.Query()
+		query.Set("offset", strconv.Itoa(page.Offset+count))
+		query.Set("limit", strconv.Itoa(page.Limit))
+		link := c.Request.URL.Path + "?" + query.Encode()
+		next = &link
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		key:       items,
+		"count":   count,
+		"total":   total,
+		"limit":   page.Limit,
+		"offset":  page.Offset,
+		"next":    next,
+	})
+}
@@ -0,0 +1,210 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"math"
+	"mumuni_backend/auth"
+	"mumuni_backend/database"
+	"mumuni_backend/models"
+	"mumuni_backend/spam"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ChangePassword handles PUT /api/admin/password
+// The signed-in admin's other sessions are ended; the current one stays
+// signed in. A wrong current password counts as a failed login for the
+// admin's email and the client's IP address, so it can't be guessed at any
+// faster than by logging in.
+func (h *Handlers) ChangePassword(c *gin.Context) {
+	var req models.AdminChangePasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: "Invalid request data: " + err.Error(),
+		})
+		return
+	}
+	if !h.checkPassword(c, req.NewPassword) {
+		return
+	}
+
+	ctx := c.Request.Context()
+	adminID := c.GetString("admin_id")
+	admin, err := h.db.GetAdmin(ctx, adminID)
+	if err != nil {
+		respondAdminError(c, err, "Failed to change password")
+		return
+	}
+	if !h.checkLoginThrottle(c, admin.Email) {
+		return
+	}
+	passwordHash, err := h.db.GetAdminPasswordHash(ctx, admin.Email)
+	if err != nil {
+		respondAdminError(c, err, "Failed to change password")
+		return
+	}
+	if !auth.CheckPasswordHash(req.CurrentPassword, passwordHash) {
+		h.recordLoginFailure(c, admin.Email, loginInvalidCurrentPassword)
+		c.JSON(http.StatusUnauthorized, models.ErrorResponse{
+			Error: "Current password is incorrect",
+		})
+		return
+	}
+
+	hashedPassword, err := auth.HashPassword(req.NewPassword)
+	if err != nil {
+		log.Printf("Error hashing password: %v", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error: "Failed to process password",
+		})
+		return
+	}
+	if err := h.db.UpdateAdminPassword(ctx, adminID, hashedPassword); err != nil {
+		respondAdminError(c, err, "Failed to change password")
+		return
+	}
+	if err := h.db.RevokeAdminSessions(ctx, adminID, c.GetString("admin_session_id")); err != nil {
+		respondAdminError(c, err, "Failed to end other sessions")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Success: true,
+		Message: "Password changed successfully",
+	})
+}
+
+// ForgotPassword handles POST /api/admin/password/forgot
+// An admin with the email is sent a reset link. The answer is the same
+// whether or not there is one, so it can't be used to find admin emails.
+// Requests are rate limited per email and per client IP address apart from
+// the login throttle, so they bound how many reset emails anyone can
+// trigger without locking the admin out of logging in.
+func (h *Handlers) ForgotPassword(c *gin.Context) {
+	var req models.AdminForgotPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: "Invalid request data: " + err.Error(),
+		})
+		return
+	}
+
+	ctx := c.Request.Context()
+	err := h.guard.CheckPasswordReset(ctx, c.ClientIP(), req.Email)
+	var limitErr *spam.RateLimitError
+	switch {
+	case errors.As(err, &limitErr):
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(limitErr.RetryAfter.Seconds()))))
+		c.JSON(http.StatusTooManyRequests, models.ErrorResponse{
+			Error: "Too many password reset requests. Try again in " + limitErr.RetryAfter.Round(time.Second).String(),
+		})
+		return
+	case err != nil:
+		log.Printf("Error checking password reset limit: %v", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error: "Failed to request password reset",
+		})
+		return
+	}
+	h.recordLoginAttempt(c, req.Email, loginPasswordResetRequested)
+
+	admin, err := h.db.GetAdminByEmail(ctx, req.Email)
+	switch {
+	case errors.Is(err, database.ErrAdminNotFound):
+	case err != nil:
+		log.Printf("Error getting admin for password reset: %v", err)
+	default:
+		h.sendPasswordReset(c, admin)
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Success: true,
+		Message: "If an admin account has that email, a password reset link is on its way",
+	})
+}
+
+// ResetPassword handles POST /api/admin/password/reset
+// The reset token works once. Every session of the admin is ended, so they
+// sign in again with the new password, and a lockout of their email is
+// lifted.
+func (h *Handlers) ResetPassword(c *gin.Context) {
+	var req models.AdminResetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: "Invalid request data: " + err.Error(),
+		})
+		return
+	}
+	if !h.checkPassword(c, req.NewPassword) {
+		return
+	}
+
+	hashedPassword, err := auth.HashPassword(req.NewPassword)
+	if err != nil {
+		log.Printf("Error hashing password: %v", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error: "Failed to process password",
+		})
+		return
+	}
+
+	ctx := c.Request.Context()
+	admin, err := h.db.ResetAdminPassword(ctx, auth.HashOpaqueToken(req.Token), hashedPassword)
+	if errors.Is(err, database.ErrResetNotFound) {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: "Reset link is invalid, already used or expired",
+		})
+		return
+	}
+	if err != nil {
+		respondAdminError(c, err, "Failed to reset password")
+		return
+	}
+	if err := h.db.ClearLoginThrottle(ctx, emailThrottleKey(admin.Email)); err != nil {
+		log.Printf("Error clearing login throttle: %v", err)
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Success: true,
+		Message: "Password reset successfully. Please log in again",
+	})
+}
+
+// sendPasswordReset makes a reset token for admin and queues the email
+// with it. Failures are logged, not answered.
+func (h *Handlers) sendPasswordReset(c *gin.Context, admin *models.AdminUser) {
+	token, tokenHash, err := auth.NewOpaqueToken()
+	if err != nil {
+		log.Printf("Error generating password reset token: %v", err)
+		return
+	}
+
+	expiresAt := time.Now().UTC().Add(h.cfg.PasswordResetTTL)
+	resetID, err := h.db.CreatePasswordReset(c.Request.Context(), admin.ID, tokenHash, expiresAt)
+	if err != nil {
+		log.Printf("Error creating password reset: %v", err)
+		return
+	}
+
+	h.notifier.PasswordReset(c.Request.Context(), admin, resetID, token, expiresAt)
+}
+
+// checkPassword refuses a new password that doesn't meet the password
+// policy.
+func (h *Handlers) checkPassword(c *gin.Context, password string) bool {
+	policy := auth.PasswordPolicy{
+		MinLength:  h.cfg.PasswordMinLength,
+		MinClasses: h.cfg.PasswordMinClasses,
+	}
+	if err := policy.Check(password); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: "Weak password: " + err.Error(),
+		})
+		return false
+	}
+	return true
+}
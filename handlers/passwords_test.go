@@ -0,0 +1,152 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"mumuni_backend/auth"
+	"mumuni_backend/config"
+	"mumuni_backend/database"
+	"mumuni_backend/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// throttleAfter makes the login throttle lock an email or IP address out
+// after failures, without delays before then.
+func throttleAfter(failures int) func(*config.Config) {
+	return func(cfg *config.Config) {
+		cfg.LoginMaxFailures = failures
+		cfg.LoginIPMaxFailures = 100
+		cfg.LoginDelay = 0
+	}
+}
+
+func TestChangePasswordIsThrottled(t *testing.T) {
+	h, store := newTestHandlers(t, throttleAfter(3))
+	ctx := context.Background()
+
+	hash, _ := auth.HashPassword("Current-password-1")
+	admin, err := store.CreateAdmin(ctx, "owner@example.com", hash, "Owner", "owner", nil)
+	if err != nil {
+		t.Fatalf("CreateAdmin: %v", err)
+	}
+	r := gin.New()
+	r.PUT("/api/admin/password", func(c *gin.Context) {
+		c.Set("admin_id", admin.ID)
+		c.Next()
+	}, h.ChangePassword)
+
+	change := func(current string) int {
+		return serve(r, http.MethodPut, "/api/admin/password", map[string]string{
+			"current_password": current,
+			"new_password":     "Brand-new-password-2",
+		}).Code
+	}
+	for i := 0; i < 3; i++ {
+		if code := change("a wrong guess"); code != http.StatusUnauthorized {
+			t.Fatalf("guess %d: got %d, want 401", i+1, code)
+		}
+	}
+	if code := change("Current-password-1"); code != http.StatusTooManyRequests {
+		t.Fatalf("after 3 wrong guesses: got %d, want 429 even for the right password", code)
+	}
+
+	attempts, _, err := store.GetLoginAttempts(ctx, database.LoginAttemptFilter{Result: loginInvalidCurrentPassword}, database.Page{})
+	if err != nil || len(attempts) != 3 {
+		t.Errorf("logged %d wrong current passwords (%v), want 3", len(attempts), err)
+	}
+	if hash, _ := store.GetAdminPasswordHash(ctx, admin.Email); !auth.CheckPasswordHash("Current-password-1", hash) {
+		t.Error("the password was changed while throttled")
+	}
+}
+
+func TestForgotPasswordIsRateLimited(t *testing.T) {
+	h, store := newTestHandlers(t, throttleAfter(3), func(cfg *config.Config) {
+		cfg.PasswordResetEmailLimit = "3/1h"
+		cfg.PasswordResetIPLimit = "100/1h"
+	})
+	ctx := context.Background()
+
+	hash, _ := auth.HashPassword("Current-password-1")
+	if _, err := store.CreateAdmin(ctx, "owner@example.com", hash, "Owner", "owner", nil); err != nil {
+		t.Fatalf("CreateAdmin: %v", err)
+	}
+	r := gin.New()
+	r.POST("/api/admin/login", h.AdminLogin)
+	r.POST("/api/admin/password/forgot", h.ForgotPassword)
+
+	forgot := func(email string) int {
+		return serve(r, http.MethodPost, "/api/admin/password/forgot", map[string]string{"email": email}).Code
+	}
+	for i := 0; i < 3; i++ {
+		if code := forgot("owner@example.com"); code != http.StatusOK {
+			t.Fatalf("request %d: got %d, want 200", i+1, code)
+		}
+	}
+	w := serve(r, http.MethodPost, "/api/admin/password/forgot", map[string]string{"email": "owner@example.com"})
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") == "" {
+		t.Fatalf("request 4: got %d, Retry-After %q; want 429 with Retry-After", w.Code, w.Header().Get("Retry-After"))
+	}
+	// Emails without an admin are limited alike, so the answer gives
+	// nothing away
+	for i := 0; i < 3; i++ {
+		forgot("nobody@example.com")
+	}
+	if code := forgot("nobody@example.com"); code != http.StatusTooManyRequests {
+		t.Errorf("request 4 for an unknown email: got %d, want 429", code)
+	}
+
+	resets, err := store.GetNotifications(ctx, database.NotificationFilter{ResourceType: "password_reset"})
+	if err != nil {
+		t.Fatalf("GetNotifications: %v", err)
+	}
+	if len(resets) != 3 {
+		t.Errorf("queued %d reset emails, want 3", len(resets))
+	}
+
+	// Reset requests are no login failures, so they can't lock the admin out
+	login := map[string]string{"email": "owner@example.com", "password": "Current-password-1"}
+	if w := serve(r, http.MethodPost, "/api/admin/login", login); w.Code != http.StatusOK {
+		t.Errorf("login after reset requests: got %d %s, want 200", w.Code, w.Body)
+	}
+}
+
+func TestForgotPasswordIsRateLimitedPerIP(t *testing.T) {
+	h, _ := newTestHandlers(t, func(cfg *config.Config) {
+		cfg.PasswordResetEmailLimit = "0"
+		cfg.PasswordResetIPLimit = "2/1h"
+	})
+	r := gin.New()
+	r.POST("/api/admin/password/forgot", h.ForgotPassword)
+
+	for i, email := range []string{"a@example.com", "b@example.com", "c@example.com"} {
+		want := http.StatusOK
+		if i == 2 {
+			want = http.StatusTooManyRequests
+		}
+		if w := serve(r, http.MethodPost, "/api/admin/password/forgot", map[string]string{"email": email}); w.Code != want {
+			t.Errorf("request %d: got %d, want %d", i+1, w.Code, want)
+		}
+	}
+}
+
+func TestLoginAttemptsAcceptsPasswordResults(t *testing.T) {
+	h, _ := newTestHandlers(t)
+	r := gin.New()
+	r.GET("/api/admin/login-attempts", h.GetLoginAttempts)
+
+	for _, result := range []string{loginInvalidCurrentPassword, loginPasswordResetRequested} {
+		w := serve(r, http.MethodGet, "/api/admin/login-attempts?result="+result, nil)
+		if w.Code != http.StatusOK {
+			t.Errorf("filtering by %s: got %d %s, want 200", result, w.Code, w.Body)
+		}
+	}
+	var resp models.ErrorResponse
+	w := serve(r, http.MethodGet, "/api/admin/login-attempts?result=bogus", nil)
+	decode(t, w, &resp)
+	if w.Code != http.StatusBadRequest || resp.Error == "" {
+		t.Errorf("unknown result: got %d %+v, want 400", w.Code, resp)
+	}
+}
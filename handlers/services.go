@@ -0,0 +1,200 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"mumuni_backend/database"
+	"mumuni_backend/models"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListServices handles GET /api/services
+func (h *Handlers) ListServices(c *gin.Context) {
+	services, err := h.db.GetServices(c.Request.Context(), true)
+	if err != nil {
+		log.Printf("Error getting services: %v", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error: "Failed to fetch services",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":  true,
+		"services": services,
+		"count":    len(services),
+	})
+}
+
+// GetServices handles GET /api/admin/services
+func (h *Handlers) GetServices(c *gin.Context) {
+	services, err := h.db.GetServices(c.Request.Context(), false)
+	if err != nil {
+		log.Printf("Error getting services: %v", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error: "Failed to fetch services",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":  true,
+		"services": services,
+		"count":    len(services),
+	})
+}
+
+// GetService handles GET /api/admin/services/:id
+func (h *Handlers) GetService(c *gin.Context) {
+	serviceID, ok := serviceIDParam(c)
+	if !ok {
+		return
+	}
+
+	service, err := h.db.GetService(c.Request.Context(), serviceID)
+	if err != nil {
+		respondServiceError(c, err, "Failed to fetch service")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"service": service,
+	})
+}
+
+// CreateService handles POST /api/admin/services
+func (h *Handlers) CreateService(c *gin.Context) {
+	var req models.ServiceRequest
+	if !bindServiceRequest(c, &req) {
+		return
+	}
+
+	service, err := h.db.CreateService(c.Request.Context(), &req)
+	if err != nil {
+		respondServiceError(c, err, "Failed to create service")
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"message": "Service created successfully",
+		"service": service,
+	})
+}
+
+// UpdateService handles PUT /api/admin/services/:id
+func (h *Handlers) UpdateService(c *gin.Context) {
+	serviceID, ok := serviceIDParam(c)
+	if !ok {
+		return
+	}
+
+	var req models.ServiceRequest
+	if !bindServiceRequest(c, &req) {
+		return
+	}
+
+	service, err := h.db.UpdateService(c.Request.Context(), serviceID, &req)
+	if err != nil {
+		respondServiceError(c, err, "Failed to update service")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Service updated successfully",
+		"service": service,
+	})
+}
+
+// DeleteService handles DELETE /api/admin/services/:id
+func (h *Handlers) DeleteService(c *gin.Context) {
+	serviceID, ok := serviceIDParam(c)
+	if !ok {
+		return
+	}
+
+	if err := h.db.DeleteService(c.Request.Context(), serviceID); err != nil {
+		respondServiceError(c, err, "Failed to delete service")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Success: true,
+		Message: "Service deleted successfully",
+	})
+}
+
+func bindServiceRequest(c *gin.Context, req *models.ServiceRequest) bool {
+	if err := c.ShouldBindJSON(req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: "Invalid request data: " + err.Error(),
+		})
+		return false
+	}
+
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: "Service name is required",
+		})
+		return false
+	}
+
+	return true
+}
+
+func serviceIDParam(c *gin.Context) (int, bool) {
+	serviceID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: "Invalid service ID",
+		})
+		return 0, false
+	}
+	return serviceID, true
+}
+
+func respondServiceError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, database.ErrServiceNotFound):
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Error: "Service not found",
+		})
+	case errors.Is(err, database.ErrServiceExists):
+		c.JSON(http.StatusConflict, models.ErrorResponse{
+			Error: "A service with this name already exists",
+		})
+	default:
+		log.Printf("%s: %v", message, err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error: message,
+		})
+	}
+}
+
+// findService returns the catalog service with the given name, or nil.
+func findService(services []models.Service, name string) *models.Service {
+	for i := range services {
+		if services[i].Name == name {
+			return &services[i]
+		}
+	}
+	return nil
+}
+
+// activeServiceNames lists the bookable services for error messages.
+func activeServiceNames(services []models.Service) string {
+	var names []string
+	for _, s := range services {
+		if s.Active {
+			names = append(names, s.Name)
+		}
+	}
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,186 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"mumuni_backend/auth"
+	"mumuni_backend/database"
+	"mumuni_backend/models"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RefreshToken handles POST /api/admin/refresh
+// The refresh token is swapped for a new pair of tokens and can't be used
+// again. The new access token carries the admin's current role.
+func (h *Handlers) RefreshToken(c *gin.Context) {
+	var req models.AdminRefreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: "Invalid request data: " + err.Error(),
+		})
+		return
+	}
+
+	refreshToken, refreshTokenHash, access, ok := h.newSessionTokens(c)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	session, err := h.db.RotateAdminSession(ctx, auth.HashOpaqueToken(req.RefreshToken), refreshTokenHash,
+		access, time.Now().UTC().Add(h.cfg.RefreshTokenTTL))
+	if err != nil {
+		respondSessionError(c, err, "Failed to refresh token")
+		return
+	}
+
+	admin, err := h.db.GetAdmin(ctx, session.AdminID)
+	if err != nil {
+		respondSessionError(c, err, "Failed to refresh token")
+		return
+	}
+
+	h.respondSessionTokens(c, admin, session, refreshToken, access, "Token refreshed successfully")
+}
+
+// AdminLogout handles POST /api/admin/logout
+// It ends the session the request was made with, revoking its tokens.
+func (h *Handlers) AdminLogout(c *gin.Context) {
+	err := h.db.RevokeAdminSession(c.Request.Context(), c.GetString("admin_id"), c.GetString("admin_session_id"))
+	if err != nil && !errors.Is(err, database.ErrSessionNotFound) {
+		respondSessionError(c, err, "Failed to log out")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Success: true,
+		Message: "Logged out successfully",
+	})
+}
+
+// GetSessions handles GET /api/admin/sessions
+// It lists the signed-in admin's active sessions, marking the current one.
+func (h *Handlers) GetSessions(c *gin.Context) {
+	sessions, err := h.db.GetAdminSessions(c.Request.Context(), c.GetString("admin_id"))
+	if err != nil {
+		respondSessionError(c, err, "Failed to fetch sessions")
+		return
+	}
+
+	current := c.GetString("admin_session_id")
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID == current
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":  true,
+		"sessions": sessions,
+		"count":    len(sessions),
+	})
+}
+
+// RevokeSession handles DELETE /api/admin/sessions/:id
+// It ends one of the signed-in admin's sessions, such as one on a lost
+// device; its access token stops working at once.
+func (h *Handlers) RevokeSession(c *gin.Context) {
+	err := h.db.RevokeAdminSession(c.Request.Context(), c.GetString("admin_id"), c.Param("id"))
+	if errors.Is(err, database.ErrSessionNotFound) {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Error: "Session not found",
+		})
+		return
+	}
+	if err != nil {
+		respondSessionError(c, err, "Failed to end session")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Success: true,
+		Message: "Session ended successfully",
+	})
+}
+
+// startSession starts a session for admin, who has just signed in, and
+// answers with its tokens.
+func (h *Handlers) startSession(c *gin.Context, admin *models.AdminUser) {
+	refreshToken, refreshTokenHash, access, ok := h.newSessionTokens(c)
+	if !ok {
+		return
+	}
+
+	session, err := h.db.CreateAdminSession(c.Request.Context(), &models.AdminSession{
+		AdminID:   admin.ID,
+		UserAgent: c.Request.UserAgent(),
+		IPAddress: c.ClientIP(),
+		ExpiresAt: time.Now().UTC().Add(h.cfg.RefreshTokenTTL),
+	}, refreshTokenHash, access)
+	if err != nil {
+		respondSessionError(c, err, "Failed to start session")
+		return
+	}
+
+	h.respondSessionTokens(c, admin, session, refreshToken, access, "Login successful")
+}
+
+// newSessionTokens makes a refresh token, with the hash to store, and the
+// ID and expiry of the access token to go with it.
+func (h *Handlers) newSessionTokens(c *gin.Context) (string, string, database.AccessToken, bool) {
+	refreshToken, refreshTokenHash, err := auth.NewOpaqueToken()
+	if err != nil {
+		respondSessionError(c, err, "Failed to generate authentication token")
+		return "", "", database.AccessToken{}, false
+	}
+	tokenID, err := auth.NewTokenID()
+	if err != nil {
+		respondSessionError(c, err, "Failed to generate authentication token")
+		return "", "", database.AccessToken{}, false
+	}
+	return refreshToken, refreshTokenHash, database.AccessToken{JTI: tokenID, ExpiresAt: time.Now().UTC().Add(h.cfg.AccessTokenTTL)}, true
+}
+
+// respondSessionTokens answers with the tokens of session. Its access token
+// only reaches two-factor setup while the security settings require
+// two-factor authentication and admin hasn't turned it on.
+func (h *Handlers) respondSessionTokens(c *gin.Context, admin *models.AdminUser, session *models.AdminSession, refreshToken string, access database.AccessToken, message string) {
+	settings, err := h.db.GetSecuritySettings(c.Request.Context())
+	if err != nil {
+		respondSessionError(c, err, "Failed to fetch security settings")
+		return
+	}
+	setupOnly := settings.RequireTwoFactor && !admin.TwoFactorEnabled
+
+	token, err := auth.GenerateToken(admin.ID, admin.Email, admin.Role, admin.ArtistID, session.ID, access.JTI, setupOnly, access.ExpiresAt)
+	if err != nil {
+		log.Printf("Error generating token: %v", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error: "Failed to generate authentication token",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.AdminLoginResponse{
+		Success:                true,
+		Token:                  token,
+		ExpiresAt:              access.ExpiresAt,
+		RefreshToken:           refreshToken,
+		SessionID:              session.ID,
+		TwoFactorSetupRequired: setupOnly,
+		Admin:                  *admin,
+		Message:                message,
+	})
+}
+
+func respondSessionError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, database.ErrSessionNotFound):
+		c.JSON(http.StatusUnauthorized, models.ErrorResponse{
+			Error: "Session not found, expired or ended",
+		})
+	default:
+		respondAdminError(c, err, message)
+	}
+}
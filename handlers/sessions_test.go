@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"mumuni_backend/auth"
+	"mumuni_backend/middleware"
+	"mumuni_backend/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestReusedRefreshTokenEndsSession(t *testing.T) {
+	h, store := newTestHandlers(t)
+	ctx := context.Background()
+
+	hash, _ := auth.HashPassword("Current-password-1")
+	admin, err := store.CreateAdmin(ctx, "owner@example.com", hash, "Owner", auth.RoleOwner, nil)
+	if err != nil {
+		t.Fatalf("CreateAdmin: %v", err)
+	}
+	r := gin.New()
+	r.POST("/api/admin/login", h.AdminLogin)
+	r.POST("/api/admin/refresh", h.RefreshToken)
+	r.GET("/api/admin/me", middleware.AuthMiddleware(store), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	me := func(token string) int {
+		req := httptest.NewRequest(http.MethodGet, "/api/admin/me", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+	refresh := func(refreshToken string) *httptest.ResponseRecorder {
+		return serve(r, http.MethodPost, "/api/admin/refresh", map[string]string{"refresh_token": refreshToken})
+	}
+
+	var login models.AdminLoginResponse
+	w := serve(r, http.MethodPost, "/api/admin/login", map[string]string{
+		"email":    admin.Email,
+		"password": "Current-password-1",
+	})
+	if w.Code != http.StatusOK {
+		t.Fatalf("login: got %d %s, want 200", w.Code, w.Body)
+	}
+	decode(t, w, &login)
+
+	var first, second models.AdminLoginResponse
+	w = refresh(login.RefreshToken)
+	if w.Code != http.StatusOK {
+		t.Fatalf("first refresh: got %d %s, want 200", w.Code, w.Body)
+	}
+	decode(t, w, &first)
+	w = refresh(first.RefreshToken)
+	if w.Code != http.StatusOK {
+		t.Fatalf("second refresh: got %d %s, want 200", w.Code, w.Body)
+	}
+	decode(t, w, &second)
+	if code := me(login.Token); code != http.StatusUnauthorized {
+		t.Errorf("replaced access token: got %d, want 401", code)
+	}
+	if code := me(second.Token); code != http.StatusOK {
+		t.Fatalf("current access token: got %d, want 200", code)
+	}
+
+	// The login's refresh token coming back two rotations later ends the
+	// session, so the tokens it was swapped for stop working too
+	if w := refresh(login.RefreshToken); w.Code != http.StatusUnauthorized {
+		t.Fatalf("reused refresh token: got %d %s, want 401", w.Code, w.Body)
+	}
+	if code := me(second.Token); code != http.StatusUnauthorized {
+		t.Errorf("access token after the reuse: got %d, want 401", code)
+	}
+	if w := refresh(second.RefreshToken); w.Code != http.StatusUnauthorized {
+		t.Errorf("current refresh token after the reuse: got %d, want 401", w.Code)
+	}
+	if sessions, _ := store.GetAdminSessions(ctx, admin.ID); len(sessions) != 0 {
+		t.Errorf("%d sessions still active, want none", len(sessions))
+	}
+}
+
+func TestReusedRefreshTokenLeavesOtherSessions(t *testing.T) {
+	h, store := newTestHandlers(t)
+	ctx := context.Background()
+
+	hash, _ := auth.HashPassword("Current-password-1")
+	admin, err := store.CreateAdmin(ctx, "owner@example.com", hash, "Owner", auth.RoleOwner, nil)
+	if err != nil {
+		t.Fatalf("CreateAdmin: %v", err)
+	}
+	r := gin.New()
+	r.POST("/api/admin/login", h.AdminLogin)
+	r.POST("/api/admin/refresh", h.RefreshToken)
+
+	var laptop, phone models.AdminLoginResponse
+	for _, login := range []*models.AdminLoginResponse{&laptop, &phone} {
+		w := serve(r, http.MethodPost, "/api/admin/login", map[string]string{
+			"email":    admin.Email,
+			"password": "Current-password-1",
+		})
+		if w.Code != http.StatusOK {
+			t.Fatalf("login: got %d %s, want 200", w.Code, w.Body)
+		}
+		decode(t, w, login)
+	}
+
+	refresh := map[string]string{"refresh_token": laptop.RefreshToken}
+	serve(r, http.MethodPost, "/api/admin/refresh", refresh)
+	serve(r, http.MethodPost, "/api/admin/refresh", refresh)
+
+	sessions, err := store.GetAdminSessions(ctx, admin.ID)
+	if err != nil || len(sessions) != 1 || sessions[0].ID != phone.SessionID {
+		t.Errorf("active sessions %+v (%v), want only the phone's", sessions, err)
+	}
+}
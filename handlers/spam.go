@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"math"
+	"mumuni_backend/auth"
+	"mumuni_backend/models"
+	"mumuni_backend/spam"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// verificationHeader carries the CAPTCHA response or proof of work for a
+// public booking.
+const verificationHeader = "X-Verification-Token"
+
+// screenBooking runs a public booking through the spam guard and answers
+// it if it must not go ahead. A booking with the honeypot filled in is
+// answered as if it succeeded, with the response decoy builds around a
+// manage token that opens nothing, so bots don't learn to leave it empty.
+func (h *Handlers) screenBooking(c *gin.Context, s spam.Submission, decoy func(manageToken string) any, failed string) bool {
+	err := h.guard.Check(c.Request.Context(), s)
+	if err == nil {
+		return true
+	}
+
+	var limitErr *spam.RateLimitError
+	switch {
+	case errors.Is(err, spam.ErrHoneypot):
+		log.Printf("Discarding %s booking from %s with the honeypot filled in", s.Kind, s.IP)
+		manageToken, _, err := auth.NewOpaqueToken()
+		if err != nil {
+			log.Printf("Error generating manage token: %v", err)
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+				Error: failed,
+			})
+			return false
+		}
+		c.JSON(http.StatusCreated, decoy(manageToken))
+	case errors.As(err, &limitErr):
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(limitErr.RetryAfter.Seconds()))))
+		c.JSON(http.StatusTooManyRequests, models.ErrorResponse{
+			Error: "Too many bookings. Try again in " + limitErr.RetryAfter.Round(time.Second).String(),
+		})
+	case errors.Is(err, spam.ErrVerificationFailed):
+		c.JSON(http.StatusForbidden, models.ErrorResponse{
+			Error: "Verification failed. Please try again",
+		})
+	case errors.Is(err, spam.ErrDuplicate):
+		c.JSON(http.StatusConflict, models.ErrorResponse{
+			Error: "This booking was already submitted",
+		})
+	default:
+		log.Printf("Error screening %s booking: %v", s.Kind, err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error: failed,
+		})
+	}
+	return false
+}
+
+// releaseFailedBooking lets a screened booking be sent again if it was
+// refused, so fixing a mistake or retrying after an error isn't taken for
+// a duplicate. It is deferred once screenBooking lets the booking through.
+func (h *Handlers) releaseFailedBooking(c *gin.Context, s spam.Submission) {
+	if c.Writer.Status() < 300 {
+		return
+	}
+	if err := h.guard.Release(c.Request.Context(), s); err != nil {
+		log.Printf("Error releasing %s booking: %v", s.Kind, err)
+	}
+}
+
+// decoyAppointment is the pending appointment a discarded booking is told it
+// made. It is never stored.
+func (h *Handlers) decoyAppointment(req *models.AppointmentRequest) models.Appointment {
+	now := time.Now().UTC()
+	appointment := models.Appointment{
+		Name:            req.Name,
+		Email:           req.Email,
+		Phone:           req.Phone,
+		AppointmentDate: req.Date,
+		AppointmentTime: req.Time,
+		Service:         req.Service,
+		Message:         req.Message,
+		ArtistID:        req.ArtistID,
+		Channels:        req.Channels,
+		Status:          "pending",
+		CreatedAt:       now,
+		UpdatedAt:       now,
+	}
+	if startsAt, err := h.scheduler.StartTime(req.Date, req.Time); err == nil {
+		startsAt = startsAt.UTC()
+		appointment.StartsAt = &startsAt
+	}
+	return appointment
+}
+
+// decoyEnrollment is the enrollment a discarded class booking is told it
+// made. It is never stored.
+func decoyEnrollment(req *models.ClassRequest) models.Class {
+	now := time.Now().UTC()
+	return models.Class{
+		Name:              req.Name,
+		Email:             req.Email,
+		Phone:             req.Phone,
+		ClassType:         req.ClassType,
+		ExperienceLevel:   req.Experience,
+		Goals:             req.Goals,
+		PreferredSchedule: req.Schedule,
+		CohortID:          req.CohortID,
+		Channels:          req.Channels,
+		Status:            "pending",
+		CreatedAt:         now,
+		UpdatedAt:         now,
+	}
+}
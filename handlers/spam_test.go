@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"mumuni_backend/database"
+	"mumuni_backend/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestHoneypotLooksLikeABooking(t *testing.T) {
+	h, store := newTestHandlers(t)
+	r := gin.New()
+	r.POST("/api/appointments", h.BookAppointment)
+	r.GET("/api/manage/appointments/:token", h.GetManagedAppointment)
+
+	booked := serve(r, http.MethodPost, "/api/appointments", appointmentRequest("ada@example.com", "10:00 AM"))
+	if booked.Code != http.StatusCreated {
+		t.Fatalf("real booking: got %d %s, want 201", booked.Code, booked.Body)
+	}
+	req := appointmentRequest("bot@example.com", "2:00 PM")
+	req["website"] = "http://spam.example"
+	bot := serve(r, http.MethodPost, "/api/appointments", req)
+	if bot.Code != http.StatusCreated {
+		t.Fatalf("honeypot booking: got %d %s, want 201", bot.Code, bot.Body)
+	}
+
+	// The answers carry the same fields, so a bot can't tell them apart
+	var bookedFields, botFields map[string]any
+	json.Unmarshal(booked.Body.Bytes(), &bookedFields)
+	json.Unmarshal(bot.Body.Bytes(), &botFields)
+	for field := range bookedFields {
+		if _, ok := botFields[field]; !ok {
+			t.Errorf("the honeypot answer has no %q", field)
+		}
+	}
+	bookedAppointment, _ := bookedFields["appointment"].(map[string]any)
+	botAppointment, _ := botFields["appointment"].(map[string]any)
+	for field := range bookedAppointment {
+		if _, ok := botAppointment[field]; !ok {
+			t.Errorf("the honeypot appointment has no %q", field)
+		}
+	}
+
+	var resp models.AppointmentResponse
+	decode(t, bot, &resp)
+	if resp.ManageToken == "" || resp.Appointment.Email != "bot@example.com" || resp.Appointment.Status != "pending" || resp.Appointment.StartsAt == nil {
+		t.Errorf("honeypot answer %+v, want a pending appointment for the bot with a manage token", resp)
+	}
+
+	// Nothing was stored, and the manage token opens nothing
+	_, total, err := store.GetAppointments(context.Background(), database.AppointmentFilter{Email: "bot@example.com"}, database.Page{})
+	if err != nil || total != 0 {
+		t.Errorf("stored %d honeypot appointments (%v), want none", total, err)
+	}
+	if w := serve(r, http.MethodGet, "/api/manage/appointments/"+resp.ManageToken, nil); w.Code != http.StatusNotFound {
+		t.Errorf("the honeypot manage token: got %d, want 404", w.Code)
+	}
+}
@@ -0,0 +1,394 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"mumuni_backend/auth"
+	"mumuni_backend/database"
+	"mumuni_backend/models"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// challengeTTL is how long an admin has to give their code after their
+	// password.
+	challengeTTL = 5 * time.Minute
+	// recoveryCodeCount is how many recovery codes an admin gets.
+	recoveryCodeCount = 10
+)
+
+// LoginTwoFactor handles POST /api/admin/login/2fa
+// It finishes the login of an admin with two-factor authentication, trading
+// the challenge token from /api/admin/login and a code from their
+// authenticator app, or one of their recovery codes, for a session. Each
+// code works once, and wrong codes count as failed logins.
+func (h *Handlers) LoginTwoFactor(c *gin.Context) {
+	var req models.TwoFactorLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: "Invalid request data: " + err.Error(),
+		})
+		return
+	}
+	if (req.Code == "") == (req.RecoveryCode == "") {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: "Give either code or recovery_code",
+		})
+		return
+	}
+
+	claims, err := auth.ValidateChallengeToken(req.ChallengeToken)
+	if err != nil {
+		respondChallengeExpired(c)
+		return
+	}
+	if !h.checkLoginThrottle(c, claims.Email) {
+		return
+	}
+
+	ctx := c.Request.Context()
+	admin, err := h.db.GetAdmin(ctx, claims.AdminID)
+	if errors.Is(err, database.ErrAdminNotFound) {
+		respondChallengeExpired(c)
+		return
+	}
+	if err != nil {
+		respondAdminError(c, err, "Failed to log in")
+		return
+	}
+	twoFactor, err := h.db.GetAdminTwoFactor(ctx, admin.ID)
+	if err != nil {
+		respondAdminError(c, err, "Failed to log in")
+		return
+	}
+	if !twoFactor.Enabled {
+		respondChallengeExpired(c)
+		return
+	}
+
+	var valid bool
+	if req.Code != "" {
+		valid, err = h.claimTOTPCode(c, admin.ID, twoFactor, req.Code)
+	} else {
+		err = h.db.UseRecoveryCode(ctx, admin.ID, auth.HashRecoveryCode(req.RecoveryCode))
+		valid = err == nil
+		if errors.Is(err, database.ErrRecoveryCodeNotFound) {
+			err = nil
+		}
+	}
+	if err != nil {
+		respondAdminError(c, err, "Failed to log in")
+		return
+	}
+	if !valid {
+		h.recordLoginFailure(c, claims.Email, loginInvalidTwoFactor)
+		respondInvalidCode(c)
+		return
+	}
+
+	h.completeLogin(c, admin, claims.Email)
+}
+
+// SetupTwoFactor handles POST /api/admin/2fa/setup
+// It makes a new TOTP secret for the signed-in admin, with the otpauth://
+// URI to show as a QR code for their authenticator app. Two-factor
+// authentication stays off until a code from the app is confirmed.
+func (h *Handlers) SetupTwoFactor(c *gin.Context) {
+	ctx := c.Request.Context()
+	admin, err := h.db.GetAdmin(ctx, c.GetString("admin_id"))
+	if err != nil {
+		respondAdminError(c, err, "Failed to set up two-factor authentication")
+		return
+	}
+	if admin.TwoFactorEnabled {
+		c.JSON(http.StatusConflict, models.ErrorResponse{
+			Error: "Two-factor authentication is already on. Turn it off to set it up again",
+		})
+		return
+	}
+
+	secret, err := auth.NewTOTPSecret()
+	if err != nil {
+		respondAdminError(c, err, "Failed to set up two-factor authentication")
+		return
+	}
+	if err := h.db.SetAdminTOTPSecret(ctx, admin.ID, secret); err != nil {
+		respondAdminError(c, err, "Failed to set up two-factor authentication")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":     true,
+		"message":     "Add the secret to your authenticator app, then confirm a code from it",
+		"secret":      secret,
+		"otpauth_url": auth.TOTPURI(h.cfg.StudioName, admin.Email, secret),
+	})
+}
+
+// ConfirmTwoFactor handles POST /api/admin/2fa/confirm
+// A code from the authenticator app turns two-factor authentication on. The
+// answer has the admin's recovery codes, which are only shown here, once.
+// Refresh the token afterwards if it was limited to two-factor setup.
+func (h *Handlers) ConfirmTwoFactor(c *gin.Context) {
+	var req models.TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: "Invalid request data: " + err.Error(),
+		})
+		return
+	}
+
+	ctx := c.Request.Context()
+	adminID := c.GetString("admin_id")
+	twoFactor, err := h.db.GetAdminTwoFactor(ctx, adminID)
+	if err != nil {
+		respondAdminError(c, err, "Failed to turn on two-factor authentication")
+		return
+	}
+	if twoFactor.Enabled {
+		c.JSON(http.StatusConflict, models.ErrorResponse{
+			Error: "Two-factor authentication is already on",
+		})
+		return
+	}
+	if twoFactor.Secret == "" {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: "Start two-factor setup first",
+		})
+		return
+	}
+
+	step, ok := auth.ValidateTOTP(twoFactor.Secret, req.Code, time.Now())
+	if !ok {
+		respondInvalidCode(c)
+		return
+	}
+	codes, hashes, err := auth.NewRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		respondAdminError(c, err, "Failed to turn on two-factor authentication")
+		return
+	}
+	if err := h.db.EnableAdminTwoFactor(ctx, adminID, step, hashes); err != nil {
+		respondAdminError(c, err, "Failed to turn on two-factor authentication")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":        true,
+		"message":        "Two-factor authentication is on. Keep the recovery codes somewhere safe; each works once",
+		"recovery_codes": codes,
+	})
+}
+
+// DisableTwoFactor handles POST /api/admin/2fa/disable
+// It turns off the signed-in admin's two-factor authentication, given their
+// password and a current code. It can't while the security settings
+// require two-factor authentication. A wrong password or code counts as a
+// failed login, as in ChangePassword, so neither can be guessed here any
+// faster than by logging in.
+func (h *Handlers) DisableTwoFactor(c *gin.Context) {
+	var req models.TwoFactorDisableRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: "Invalid request data: " + err.Error(),
+		})
+		return
+	}
+
+	ctx := c.Request.Context()
+	settings, err := h.db.GetSecuritySettings(ctx)
+	if err != nil {
+		respondAdminError(c, err, "Failed to turn off two-factor authentication")
+		return
+	}
+	if settings.RequireTwoFactor {
+		c.JSON(http.StatusConflict, models.ErrorResponse{
+			Error: "Two-factor authentication is required for every admin",
+		})
+		return
+	}
+
+	admin, err := h.db.GetAdmin(ctx, c.GetString("admin_id"))
+	if err != nil {
+		respondAdminError(c, err, "Failed to turn off two-factor authentication")
+		return
+	}
+	if !h.checkLoginThrottle(c, admin.Email) {
+		return
+	}
+	passwordHash, err := h.db.GetAdminPasswordHash(ctx, admin.Email)
+	if err != nil {
+		respondAdminError(c, err, "Failed to turn off two-factor authentication")
+		return
+	}
+	if !auth.CheckPasswordHash(req.Password, passwordHash) {
+		h.recordLoginFailure(c, admin.Email, loginInvalidCurrentPassword)
+		c.JSON(http.StatusUnauthorized, models.ErrorResponse{
+			Error: "Password is incorrect",
+		})
+		return
+	}
+
+	twoFactor, err := h.db.GetAdminTwoFactor(ctx, admin.ID)
+	if err != nil {
+		respondAdminError(c, err, "Failed to turn off two-factor authentication")
+		return
+	}
+	if !twoFactor.Enabled {
+		c.JSON(http.StatusConflict, models.ErrorResponse{
+			Error: "Two-factor authentication is not on",
+		})
+		return
+	}
+	valid, err := h.claimTOTPCode(c, admin.ID, twoFactor, req.Code)
+	if err != nil {
+		respondAdminError(c, err, "Failed to turn off two-factor authentication")
+		return
+	}
+	if !valid {
+		h.recordLoginFailure(c, admin.Email, loginInvalidTwoFactor)
+		respondInvalidCode(c)
+		return
+	}
+
+	if err := h.db.DisableAdminTwoFactor(ctx, admin.ID); err != nil {
+		respondAdminError(c, err, "Failed to turn off two-factor authentication")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Success: true,
+		Message: "Two-factor authentication turned off",
+	})
+}
+
+// ResetAdminTwoFactor handles DELETE /api/admin/admins/:id/2fa
+// It turns off an admin's two-factor authentication, for one who lost their
+// authenticator app and recovery codes. If it is required they set it up
+// again at their next login. Their sessions are ended, as whoever holds them
+// may be the reason for the reset.
+func (h *Handlers) ResetAdminTwoFactor(c *gin.Context) {
+	ctx := c.Request.Context()
+	admin, err := h.db.GetAdmin(ctx, c.Param("id"))
+	if err != nil {
+		respondAdminError(c, err, "Failed to reset two-factor authentication")
+		return
+	}
+	if err := h.db.DisableAdminTwoFactor(ctx, admin.ID); err != nil {
+		respondAdminError(c, err, "Failed to reset two-factor authentication")
+		return
+	}
+	if err := h.db.RevokeAdminSessions(ctx, admin.ID, ""); err != nil {
+		respondAdminError(c, err, "Failed to end the admin's sessions")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Success: true,
+		Message: "Two-factor authentication reset successfully",
+	})
+}
+
+// GetSecuritySettings handles GET /api/admin/security
+func (h *Handlers) GetSecuritySettings(c *gin.Context) {
+	settings, err := h.db.GetSecuritySettings(c.Request.Context())
+	if err != nil {
+		respondAdminError(c, err, "Failed to fetch security settings")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":  true,
+		"settings": settings,
+	})
+}
+
+// UpdateSecuritySettings handles PUT /api/admin/security
+// Requiring two-factor authentication limits admins without it to setting
+// it up, from their next login or token refresh. The owner turning it on
+// must have it on themselves, so they don't lock themselves out.
+func (h *Handlers) UpdateSecuritySettings(c *gin.Context) {
+	var req models.SecuritySettingsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: "Invalid request data: " + err.Error(),
+		})
+		return
+	}
+
+	ctx := c.Request.Context()
+	if *req.RequireTwoFactor {
+		admin, err := h.db.GetAdmin(ctx, c.GetString("admin_id"))
+		if err != nil {
+			respondAdminError(c, err, "Failed to update security settings")
+			return
+		}
+		if !admin.TwoFactorEnabled {
+			c.JSON(http.StatusConflict, models.ErrorResponse{
+				Error: "Turn on two-factor authentication for your own account first",
+			})
+			return
+		}
+	}
+
+	settings, err := h.db.UpdateSecuritySettings(ctx, *req.RequireTwoFactor)
+	if err != nil {
+		respondAdminError(c, err, "Failed to update security settings")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":  true,
+		"message":  "Security settings updated successfully",
+		"settings": settings,
+	})
+}
+
+// challengeTwoFactor answers a login with the right password for admin, who
+// has two-factor authentication, with a challenge token for the code.
+func (h *Handlers) challengeTwoFactor(c *gin.Context, admin *models.AdminUser, email string) {
+	expiresAt := time.Now().UTC().Add(challengeTTL)
+	token, err := auth.GenerateChallengeToken(admin.ID, email, expiresAt)
+	if err != nil {
+		log.Printf("Error generating challenge token: %v", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error: "Failed to generate authentication token",
+		})
+		return
+	}
+
+	h.recordLoginAttempt(c, email, loginTwoFactorRequired)
+	c.JSON(http.StatusOK, gin.H{
+		"success":             true,
+		"message":             "Enter the code from your authenticator app",
+		"two_factor_required": true,
+		"challenge_token":     token,
+		"expires_at":          expiresAt,
+	})
+}
+
+// claimTOTPCode reports whether code is a current code for twoFactor that
+// hasn't been used yet, and records it as used.
+func (h *Handlers) claimTOTPCode(c *gin.Context, adminID string, twoFactor *database.TwoFactor, code string) (bool, error) {
+	step, ok := auth.ValidateTOTP(twoFactor.Secret, strings.TrimSpace(code), time.Now())
+	if !ok || step <= twoFactor.LastStep {
+		return false, nil
+	}
+	return h.db.ClaimTOTPStep(c.Request.Context(), adminID, step)
+}
+
+func respondChallengeExpired(c *gin.Context) {
+	c.JSON(http.StatusUnauthorized, models.ErrorResponse{
+		Error: "Login challenge is invalid or expired. Please log in again",
+	})
+}
+
+func respondInvalidCode(c *gin.Context) {
+	c.JSON(http.StatusUnauthorized, models.ErrorResponse{
+		Error: "Invalid two-factor code",
+	})
+}
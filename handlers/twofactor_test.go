@@ -0,0 +1,183 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"mumuni_backend/auth"
+	"mumuni_backend/database"
+	"mumuni_backend/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// withTwoFactor creates an owner with two-factor authentication on and
+// returns them with their recovery codes.
+func withTwoFactor(t *testing.T, store *database.MemoryStore, password string) (*models.AdminUser, []string) {
+	t.Helper()
+	ctx := context.Background()
+
+	hash, _ := auth.HashPassword(password)
+	admin, err := store.CreateAdmin(ctx, "owner@example.com", hash, "Owner", auth.RoleOwner, nil)
+	if err != nil {
+		t.Fatalf("CreateAdmin: %v", err)
+	}
+	secret, err := auth.NewTOTPSecret()
+	if err != nil {
+		t.Fatalf("NewTOTPSecret: %v", err)
+	}
+	if err := store.SetAdminTOTPSecret(ctx, admin.ID, secret); err != nil {
+		t.Fatalf("SetAdminTOTPSecret: %v", err)
+	}
+	codes, hashes, err := auth.NewRecoveryCodes(2)
+	if err != nil {
+		t.Fatalf("NewRecoveryCodes: %v", err)
+	}
+	if err := store.EnableAdminTwoFactor(ctx, admin.ID, 0, hashes); err != nil {
+		t.Fatalf("EnableAdminTwoFactor: %v", err)
+	}
+	return admin, codes
+}
+
+func TestRecoveryCodesWorkOnce(t *testing.T) {
+	h, store := newTestHandlers(t, throttleAfter(10))
+	admin, codes := withTwoFactor(t, store, "Current-password-1")
+
+	r := gin.New()
+	r.POST("/api/admin/login/2fa", h.LoginTwoFactor)
+	login := func(code string) int {
+		challenge, err := auth.GenerateChallengeToken(admin.ID, admin.Email, time.Now().Add(time.Minute))
+		if err != nil {
+			t.Fatalf("GenerateChallengeToken: %v", err)
+		}
+		return serve(r, http.MethodPost, "/api/admin/login/2fa", map[string]string{
+			"challenge_token": challenge,
+			"recovery_code":   code,
+		}).Code
+	}
+
+	if code := login(codes[0]); code != http.StatusOK {
+		t.Fatalf("first use: got %d, want 200", code)
+	}
+	if code := login(codes[0]); code != http.StatusUnauthorized {
+		t.Errorf("second use: got %d, want 401", code)
+	}
+	if code := login(codes[1]); code != http.StatusOK {
+		t.Errorf("the other code: got %d, want 200", code)
+	}
+}
+
+func TestLoginThrottleLocksOutAndResets(t *testing.T) {
+	h, store := newTestHandlers(t, throttleAfter(3))
+	ctx := context.Background()
+
+	hash, _ := auth.HashPassword("Current-password-1")
+	if _, err := store.CreateAdmin(ctx, "owner@example.com", hash, "Owner", auth.RoleOwner, nil); err != nil {
+		t.Fatalf("CreateAdmin: %v", err)
+	}
+	r := gin.New()
+	r.POST("/api/admin/login", h.AdminLogin)
+	login := func(password string) int {
+		return serve(r, http.MethodPost, "/api/admin/login", map[string]string{
+			"email":    "owner@example.com",
+			"password": password,
+		}).Code
+	}
+
+	// A successful login forgets the failures before it
+	for round := 0; round < 2; round++ {
+		for i := 0; i < 2; i++ {
+			if code := login("a wrong guess"); code != http.StatusUnauthorized {
+				t.Fatalf("round %d, guess %d: got %d, want 401", round+1, i+1, code)
+			}
+		}
+		if code := login("Current-password-1"); code != http.StatusOK {
+			t.Fatalf("round %d, right password: got %d, want 200", round+1, code)
+		}
+	}
+
+	for i := 0; i < 3; i++ {
+		login("a wrong guess")
+	}
+	if code := login("Current-password-1"); code != http.StatusTooManyRequests {
+		t.Fatalf("after 3 wrong guesses: got %d, want 429 even for the right password", code)
+	}
+
+	// So does a password reset, which lifts the lockout
+	if err := store.ClearLoginThrottle(ctx, emailThrottleKey("owner@example.com")); err != nil {
+		t.Fatalf("ClearLoginThrottle: %v", err)
+	}
+	if code := login("Current-password-1"); code != http.StatusOK {
+		t.Errorf("after the lockout was lifted: got %d, want 200", code)
+	}
+}
+
+func TestDisableTwoFactorIsThrottled(t *testing.T) {
+	h, store := newTestHandlers(t, throttleAfter(3))
+	ctx := context.Background()
+	admin, _ := withTwoFactor(t, store, "Current-password-1")
+
+	r := gin.New()
+	r.POST("/api/admin/2fa/disable", func(c *gin.Context) {
+		c.Set("admin_id", admin.ID)
+		c.Next()
+	}, h.DisableTwoFactor)
+	disable := func(password, code string) int {
+		return serve(r, http.MethodPost, "/api/admin/2fa/disable", map[string]string{
+			"password": password,
+			"code":     code,
+		}).Code
+	}
+
+	if code := disable("a wrong guess", "000000"); code != http.StatusUnauthorized {
+		t.Fatalf("wrong password: got %d, want 401", code)
+	}
+	for i := 0; i < 2; i++ {
+		if code := disable("Current-password-1", "000000"); code != http.StatusUnauthorized {
+			t.Fatalf("wrong code %d: got %d, want 401", i+1, code)
+		}
+	}
+	if code := disable("Current-password-1", "000000"); code != http.StatusTooManyRequests {
+		t.Fatalf("after 3 wrong guesses: got %d, want 429", code)
+	}
+
+	attempts, _, err := store.GetLoginAttempts(ctx, database.LoginAttemptFilter{Email: admin.Email}, database.Page{})
+	if err != nil || len(attempts) != 4 {
+		t.Errorf("logged %d attempts (%v), want 3 failures and 1 blocked", len(attempts), err)
+	}
+	if twoFactor, _ := store.GetAdminTwoFactor(ctx, admin.ID); !twoFactor.Enabled {
+		t.Error("two-factor authentication was turned off")
+	}
+}
+
+func TestResetAdminTwoFactorEndsSessions(t *testing.T) {
+	h, store := newTestHandlers(t)
+	ctx := context.Background()
+	admin, _ := withTwoFactor(t, store, "Current-password-1")
+
+	access := database.AccessToken{JTI: "owner-token", ExpiresAt: time.Now().Add(time.Hour)}
+	if _, err := store.CreateAdminSession(ctx, &models.AdminSession{
+		AdminID:   admin.ID,
+		ExpiresAt: time.Now().Add(24 * time.Hour),
+	}, "owner-refresh", access); err != nil {
+		t.Fatalf("CreateAdminSession: %v", err)
+	}
+
+	r := gin.New()
+	r.DELETE("/api/admin/admins/:id/2fa", asAdmin(auth.RoleOwner, nil), h.ResetAdminTwoFactor)
+	if w := serve(r, http.MethodDelete, "/api/admin/admins/"+admin.ID+"/2fa", nil); w.Code != http.StatusOK {
+		t.Fatalf("got %d %s, want 200", w.Code, w.Body)
+	}
+
+	if twoFactor, _ := store.GetAdminTwoFactor(ctx, admin.ID); twoFactor.Enabled {
+		t.Error("two-factor authentication is still on")
+	}
+	if revoked, _ := store.IsTokenRevoked(ctx, access.JTI); !revoked {
+		t.Error("the admin's access token still works")
+	}
+	if sessions, _ := store.GetAdminSessions(ctx, admin.ID); len(sessions) != 0 {
+		t.Errorf("the admin has %d sessions, want none", len(sessions))
+	}
+}
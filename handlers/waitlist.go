@@ -0,0 +1,676 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"mumuni_backend/auth"
+	"mumuni_backend/database"
+	"mumuni_backend/models"
+	"mumuni_backend/notify"
+	"mumuni_backend/scheduling"
+	"mumuni_backend/webhooks"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// JoinWaitlist handles POST /api/waitlist
+// Customers who can't find a slot on a date wait for one to be freed.
+func (h *Handlers) JoinWaitlist(c *gin.Context) {
+	var req models.WaitlistRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: "Invalid request data: " + err.Error(),
+		})
+		return
+	}
+	if !h.normalizePhone(c, &req.Phone) {
+		return
+	}
+
+	if req.Date < h.scheduler.Today(time.Now()) {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: "Cannot join the waitlist for a past date",
+		})
+		return
+	}
+	if _, err := time.Parse(scheduling.DateLayout, req.Date); err != nil {
+		h.respondSlotError(c, scheduling.ErrInvalidDate)
+		return
+	}
+	if req.Time != nil {
+		start, err := scheduling.ParseTimeOfDay(*req.Time)
+		if err != nil {
+			h.respondSlotError(c, scheduling.ErrInvalidTime)
+			return
+		}
+		preferred := scheduling.FormatTimeOfDay(start)
+		req.Time = &preferred
+	}
+
+	services, err := h.db.GetServices(c.Request.Context(), false)
+	if err != nil {
+		log.Printf("Error getting services: %v", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error: "Failed to join waitlist",
+		})
+		return
+	}
+	if service := findService(services, req.Service); service == nil || !service.Active {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: "Invalid service type. Must be one of: " + activeServiceNames(services),
+		})
+		return
+	}
+
+	token, tokenHash, err := auth.NewOpaqueToken()
+	if err != nil {
+		log.Printf("Error generating waitlist token: %v", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error: "Failed to join waitlist",
+		})
+		return
+	}
+
+	entry, err := h.db.CreateWaitlistEntry(c.Request.Context(), &models.WaitlistEntry{
+		Kind:            "appointment",
+		Name:            req.Name,
+		Email:           req.Email,
+		Phone:           req.Phone,
+		Service:         &req.Service,
+		AppointmentDate: &req.Date,
+		PreferredTime:   req.Time,
+		ArtistID:        req.ArtistID,
+		TokenHash:       tokenHash,
+	})
+	if err != nil {
+		log.Printf("Error creating waitlist entry: %v", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error: "Failed to join waitlist",
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"message": "You have been added to the waitlist",
+		"entry":   entry,
+		"token":   token,
+	})
+}
+
+// joinCohortWaitlist queues a waitlisted enrollment for the next free seat
+// in its cohort and returns the token the customer manages it with.
+func (h *Handlers) joinCohortWaitlist(ctx context.Context, class *models.Class) (string, error) {
+	token, tokenHash, err := auth.NewOpaqueToken()
+	if err != nil {
+		return "", err
+	}
+
+	_, err = h.db.CreateWaitlistEntry(ctx, &models.WaitlistEntry{
+		Kind:      "class",
+		Name:      class.Name,
+		Email:     class.Email,
+		Phone:     class.Phone,
+		CohortID:  class.CohortID,
+		ClassID:   &class.ID,
+		TokenHash: tokenHash,
+	})
+	if err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// GetWaitlistEntryByToken handles GET /api/waitlist/:token
+func (h *Handlers) GetWaitlistEntryByToken(c *gin.Context) {
+	entry, ok := h.waitlistEntryFromToken(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"entry":   entry,
+	})
+}
+
+// AcceptWaitlistOffer handles POST /api/waitlist/:token/accept
+func (h *Handlers) AcceptWaitlistOffer(c *gin.Context) {
+	entry, ok := h.waitlistEntryFromToken(c)
+	if !ok {
+		return
+	}
+
+	if entry.Status != "offered" {
+		c.JSON(http.StatusConflict, models.ErrorResponse{
+			Error: "There is no open offer for this waitlist entry",
+		})
+		return
+	}
+
+	ctx := c.Request.Context()
+	if entry.HoldExpiresAt != nil && time.Now().After(*entry.HoldExpiresAt) {
+		if err := h.releaseWaitlistEntry(ctx, entry, "expired", nil); err != nil {
+			log.Printf("Error expiring waitlist offer %d: %v", entry.ID, err)
+		}
+		c.JSON(http.StatusGone, models.ErrorResponse{
+			Error: "This offer has expired",
+		})
+		return
+	}
+
+	entry.Status = "promoted"
+	entry.HoldExpiresAt = nil
+	entry, err := h.db.UpdateWaitlistEntry(ctx, entry)
+	if err != nil {
+		log.Printf("Error accepting waitlist offer: %v", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error: "Failed to accept offer",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Offer accepted",
+		"entry":   entry,
+	})
+}
+
+// LeaveWaitlist handles POST /api/waitlist/:token/leave
+// Leaving gives up any held seat or slot.
+func (h *Handlers) LeaveWaitlist(c *gin.Context) {
+	entry, ok := h.waitlistEntryFromToken(c)
+	if !ok {
+		return
+	}
+
+	h.cancelWaitlistEntry(c, entry)
+}
+
+// GetWaitlist handles GET /api/admin/waitlist?kind=&status=&cohort_id=&date=
+func (h *Handlers) GetWaitlist(c *gin.Context) {
+	filter := database.WaitlistFilter{
+		Kind:   c.Query("kind"),
+		Status: c.Query("status"),
+		Date:   c.Query("date"),
+	}
+	if value := c.Query("cohort_id"); value != "" {
+		cohortID, err := strconv.Atoi(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error: "Invalid cohort ID",
+			})
+			return
+		}
+		filter.CohortID = &cohortID
+	}
+
+	entries, err := h.db.GetWaitlistEntries(c.Request.Context(), filter)
+	if err != nil {
+		log.Printf("Error getting waitlist: %v", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error: "Failed to fetch waitlist",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"entries": entries,
+		"count":   len(entries),
+	})
+}
+
+// GetWaitlistEntry handles GET /api/admin/waitlist/:id
+func (h *Handlers) GetWaitlistEntry(c *gin.Context) {
+	entry, ok := h.waitlistEntryFromID(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"entry":   entry,
+	})
+}
+
+// PromoteWaitlistEntry handles POST /api/admin/waitlist/:id/promote
+// It moves an entry ahead of the queue: a class entry gets a seat even if
+// the cohort is full, an appointment entry is booked at its preferred time
+// or at the "time" given in the body.
+func (h *Handlers) PromoteWaitlistEntry(c *gin.Context) {
+	entry, ok := h.waitlistEntryFromID(c)
+	if !ok {
+		return
+	}
+
+	if entry.Status != "waiting" {
+		c.JSON(http.StatusConflict, models.ErrorResponse{
+			Error: "Only waiting entries can be promoted",
+		})
+		return
+	}
+
+	var body struct {
+		Time string `json:"time"`
+	}
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&body); err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error: "Invalid request data: " + err.Error(),
+			})
+			return
+		}
+	}
+
+	ctx := c.Request.Context()
+	var err error
+	if entry.Kind == "class" {
+		err = h.promoteWaitlistedClass(ctx, entry, adminID(c), true)
+	} else {
+		clock := body.Time
+		if clock == "" && entry.PreferredTime != nil {
+			clock = *entry.PreferredTime
+		}
+		if clock == "" {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error: "time is required for entries without a preferred time",
+			})
+			return
+		}
+		err = h.bookWaitlistSlot(ctx, entry, clock)
+	}
+	if err != nil {
+		if scheduling.IsSlotError(err) {
+			h.respondSlotError(c, err)
+			return
+		}
+		if respondLifecycleError(c, err) {
+			return
+		}
+		log.Printf("Error promoting waitlist entry %d: %v", entry.ID, err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error: "Failed to promote waitlist entry",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Waitlist entry promoted",
+		"entry":   entry,
+	})
+}
+
+// CancelWaitlistEntry handles POST /api/admin/waitlist/:id/cancel
+func (h *Handlers) CancelWaitlistEntry(c *gin.Context) {
+	entry, ok := h.waitlistEntryFromID(c)
+	if !ok {
+		return
+	}
+
+	h.cancelWaitlistEntry(c, entry)
+}
+
+// ExpireWaitlistOffers releases every offered seat or slot whose hold has
+// run out and passes it to the next person. main runs it periodically.
+func (h *Handlers) ExpireWaitlistOffers(ctx context.Context) {
+	entries, err := h.db.GetWaitlistEntries(ctx, database.WaitlistFilter{Status: "offered"})
+	if err != nil {
+		log.Printf("Error getting waitlist offers: %v", err)
+		return
+	}
+
+	now := time.Now()
+	for i := range entries {
+		entry := &entries[i]
+		if entry.HoldExpiresAt == nil || now.Before(*entry.HoldExpiresAt) {
+			continue
+		}
+		if err := h.releaseWaitlistEntry(ctx, entry, "expired", nil); err != nil {
+			log.Printf("Error expiring waitlist offer %d: %v", entry.ID, err)
+		}
+	}
+}
+
+// appointmentStatusChanged tells the customer and webhook subscribers about
+// a status change and keeps the waitlist in step with it: confirming an
+// offered appointment counts as accepting the offer, and cancelling any
+// appointment frees its slot for the waitlist. Reminders stop once the
+// appointment is closed.
+func (h *Handlers) appointmentStatusChanged(ctx context.Context, appointment *models.Appointment) {
+	h.notifier.Appointment(ctx, notify.AppointmentEvent(appointment.Status), appointment, notify.Details{})
+	h.publisher.Publish(ctx, webhooks.AppointmentStatusChanged, appointment)
+	if !scheduling.BlocksCalendar(appointment.Status) {
+		h.cancelReminders(ctx, "appointment", appointment.ID)
+	}
+
+	entries, err := h.db.GetWaitlistEntries(ctx, database.WaitlistFilter{
+		AppointmentID: &appointment.ID,
+		Status:        "offered",
+	})
+	if err != nil {
+		log.Printf("Error getting waitlist entries for appointment %d: %v", appointment.ID, err)
+		return
+	}
+
+	for i := range entries {
+		entry := &entries[i]
+		switch appointment.Status {
+		case "confirmed", "completed":
+			entry.Status = "promoted"
+		case "cancelled":
+			entry.Status = "cancelled"
+		default:
+			continue
+		}
+		entry.HoldExpiresAt = nil
+		if _, err := h.db.UpdateWaitlistEntry(ctx, entry); err != nil {
+			log.Printf("Error updating waitlist entry %d: %v", entry.ID, err)
+		}
+	}
+
+	if appointment.Status == "cancelled" {
+		h.fillDay(ctx, appointment.AppointmentDate, appointment.AppointmentTime)
+	}
+}
+
+// classStatusChanged tells the customer and webhook subscribers about a
+// status change on an enrollment, keeps the waitlist in step with it, and
+// hands a cancelled enrollment's seat to the waitlist. Reminders start when
+// the enrollment comes off the waitlist and stop once it is closed.
+func (h *Handlers) classStatusChanged(ctx context.Context, class *models.Class) {
+	h.notifier.Enrollment(ctx, notify.EnrollmentEvent(class.Status), class, notify.Details{})
+	h.publisher.Publish(ctx, webhooks.ClassStatusChanged, class)
+	switch class.Status {
+	case "pending":
+		h.scheduleClassReminders(ctx, class)
+	case "cancelled", "completed":
+		h.cancelReminders(ctx, "class", class.ID)
+	}
+
+	entries, err := h.db.GetWaitlistEntries(ctx, database.WaitlistFilter{ClassID: &class.ID})
+	if err != nil {
+		log.Printf("Error getting waitlist entries for class %d: %v", class.ID, err)
+		return
+	}
+
+	for i := range entries {
+		entry := &entries[i]
+		if entry.Status != "waiting" && entry.Status != "offered" {
+			continue
+		}
+		// An enrollment moved off the waitlist by hand has its waiting entry
+		// promoted by the store, which takes the seat
+		switch class.Status {
+		case "confirmed", "completed":
+			entry.Status = "promoted"
+		case "cancelled":
+			entry.Status = "cancelled"
+		default:
+			continue
+		}
+		entry.HoldExpiresAt = nil
+		if _, err := h.db.UpdateWaitlistEntry(ctx, entry); err != nil {
+			log.Printf("Error updating waitlist entry %d: %v", entry.ID, err)
+		}
+	}
+
+	if class.Status == "cancelled" && class.CohortID != nil {
+		h.fillCohort(ctx, *class.CohortID)
+	}
+}
+
+// fillCohort gives the cohort's free seats to waitlisted enrollments in the
+// order they joined. Each seat is counted as it is handed out, so an
+// enrollment made at the same moment can't overfill the cohort.
+func (h *Handlers) fillCohort(ctx context.Context, cohortID int) {
+	entries, err := h.db.GetWaitlistEntries(ctx, database.WaitlistFilter{
+		Kind:     "class",
+		Status:   "waiting",
+		CohortID: &cohortID,
+	})
+	if err != nil {
+		log.Printf("Error getting waitlist for cohort %d: %v", cohortID, err)
+		return
+	}
+
+	for i := range entries {
+		entry := &entries[i]
+		err := h.promoteWaitlistedClass(ctx, entry, nil, false)
+		if errors.Is(err, database.ErrCohortFull) {
+			return
+		}
+		if err != nil {
+			log.Printf("Error promoting class %d from the waitlist: %v", *entry.ClassID, err)
+			return
+		}
+	}
+}
+
+// fillDay books freed time on date for the people waiting on it, in the
+// order they joined. Each is tried at their preferred time, or at freedTime
+// when they didn't give one; those who don't fit keep waiting.
+func (h *Handlers) fillDay(ctx context.Context, date, freedTime string) {
+	entries, err := h.db.GetWaitlistEntries(ctx, database.WaitlistFilter{
+		Kind:   "appointment",
+		Status: "waiting",
+		Date:   date,
+	})
+	if err != nil {
+		log.Printf("Error getting waitlist for %s: %v", date, err)
+		return
+	}
+
+	for i := range entries {
+		entry := &entries[i]
+		clock := freedTime
+		if entry.PreferredTime != nil {
+			clock = *entry.PreferredTime
+		}
+
+		err := h.bookWaitlistSlot(ctx, entry, clock)
+		var serviceErr *invalidServiceError
+		if scheduling.IsSlotError(err) || errors.As(err, &serviceErr) {
+			continue
+		}
+		if err != nil {
+			log.Printf("Error booking waitlist entry %d: %v", entry.ID, err)
+			return
+		}
+	}
+}
+
+// bookWaitlistSlot books an appointment for an appointment waitlist entry,
+// records it on the entry as promoted and lets the customer know.
+func (h *Handlers) bookWaitlistSlot(ctx context.Context, entry *models.WaitlistEntry, clock string) error {
+	req := models.AppointmentRequest{
+		Name:     entry.Name,
+		Email:    entry.Email,
+		Phone:    entry.Phone,
+		Date:     *entry.AppointmentDate,
+		Time:     clock,
+		Service:  *entry.Service,
+		ArtistID: entry.ArtistID,
+	}
+
+	appointment, manageToken, err := h.bookAppointment(ctx, &req)
+	if err != nil {
+		return err
+	}
+
+	entry.AppointmentID = &appointment.ID
+	if err := h.markWaitlistPromoted(ctx, entry); err != nil {
+		return err
+	}
+
+	h.notifier.Appointment(ctx, notify.AppointmentPromoted, appointment, notify.Details{
+		ManageToken:   manageToken,
+		HoldExpiresAt: entry.HoldExpiresAt,
+	})
+	return nil
+}
+
+// promoteWaitlistedClass takes a class waitlist entry's enrollment off the
+// waitlist and lets the customer know. It returns database.ErrCohortFull
+// when the cohort has no seat left, unless overfill is true. adminID is the
+// admin promoting it, if any.
+func (h *Handlers) promoteWaitlistedClass(ctx context.Context, entry *models.WaitlistEntry, adminID *string, overfill bool) error {
+	promoted := *entry
+	h.setWaitlistPromoted(&promoted)
+	class, updated, err := h.db.PromoteWaitlistedClass(ctx, &promoted, database.StatusChange{
+		Status:  "pending",
+		AdminID: adminID,
+	}, overfill)
+	if err != nil {
+		return err
+	}
+	*entry = *updated
+
+	h.notifier.Enrollment(ctx, notify.EnrollmentPromoted, class, notify.Details{HoldExpiresAt: entry.HoldExpiresAt})
+	h.publisher.Publish(ctx, webhooks.ClassStatusChanged, class)
+	h.scheduleClassReminders(ctx, class)
+	return nil
+}
+
+// markWaitlistPromoted records that an entry's seat or slot has been
+// reserved.
+func (h *Handlers) markWaitlistPromoted(ctx context.Context, entry *models.WaitlistEntry) error {
+	h.setWaitlistPromoted(entry)
+	updated, err := h.db.UpdateWaitlistEntry(ctx, entry)
+	if err != nil {
+		return err
+	}
+	*entry = *updated
+	return nil
+}
+
+// setWaitlistPromoted sets the status of an entry whose seat or slot is
+// reserved. In offer mode the reservation is only held until the offer
+// expires.
+func (h *Handlers) setWaitlistPromoted(entry *models.WaitlistEntry) {
+	entry.Status = "promoted"
+	entry.HoldExpiresAt = nil
+	if h.cfg.WaitlistMode == "offer" {
+		expires := time.Now().Add(h.cfg.WaitlistHold).UTC()
+		entry.Status = "offered"
+		entry.HoldExpiresAt = &expires
+	}
+}
+
+// releaseWaitlistEntry takes an entry off the waitlist with the given final
+// status, cancelling the enrollment or appointment it was waiting with or
+// holding. A released hold goes to the next person in line. adminID is the
+// admin releasing it, if any.
+func (h *Handlers) releaseWaitlistEntry(ctx context.Context, entry *models.WaitlistEntry, status string, adminID *string) error {
+	held := entry.Status == "offered"
+	cancel := database.StatusChange{
+		Status:  "cancelled",
+		Reason:  "Removed from the waitlist",
+		AdminID: adminID,
+	}
+	if status == "expired" {
+		cancel.Reason = "Waitlist offer expired"
+	}
+
+	entry.Status = status
+	entry.HoldExpiresAt = nil
+	updated, err := h.db.UpdateWaitlistEntry(ctx, entry)
+	if err != nil {
+		return err
+	}
+	*entry = *updated
+
+	switch {
+	case entry.Kind == "class" && entry.ClassID != nil:
+		class, err := h.db.UpdateClassStatus(ctx, *entry.ClassID, cancel)
+		if err != nil {
+			return err
+		}
+		h.notifier.Enrollment(ctx, notify.EnrollmentCancelled, class, notify.Details{})
+		h.publisher.Publish(ctx, webhooks.ClassStatusChanged, class)
+		h.cancelReminders(ctx, "class", class.ID)
+		if held {
+			h.fillCohort(ctx, *entry.CohortID)
+		}
+	case entry.Kind == "appointment" && held && entry.AppointmentID != nil:
+		appointment, err := h.db.UpdateAppointmentStatus(ctx, *entry.AppointmentID, cancel)
+		if err != nil {
+			return err
+		}
+		h.notifier.Appointment(ctx, notify.AppointmentCancelled, appointment, notify.Details{})
+		h.publisher.Publish(ctx, webhooks.AppointmentStatusChanged, appointment)
+		h.cancelReminders(ctx, "appointment", appointment.ID)
+		h.fillDay(ctx, appointment.AppointmentDate, appointment.AppointmentTime)
+	}
+
+	return nil
+}
+
+func (h *Handlers) cancelWaitlistEntry(c *gin.Context, entry *models.WaitlistEntry) {
+	if entry.Status != "waiting" && entry.Status != "offered" {
+		c.JSON(http.StatusConflict, models.ErrorResponse{
+			Error: "This waitlist entry is no longer open",
+		})
+		return
+	}
+
+	if err := h.releaseWaitlistEntry(c.Request.Context(), entry, "cancelled", adminID(c)); err != nil {
+		log.Printf("Error cancelling waitlist entry %d: %v", entry.ID, err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error: "Failed to cancel waitlist entry",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Removed from the waitlist",
+		"entry":   entry,
+	})
+}
+
+func (h *Handlers) waitlistEntryFromToken(c *gin.Context) (*models.WaitlistEntry, bool) {
+	entry, err := h.db.GetWaitlistEntryByToken(c.Request.Context(), auth.HashOpaqueToken(c.Param("token")))
+	if err != nil {
+		respondWaitlistError(c, err)
+		return nil, false
+	}
+	return entry, true
+}
+
+func (h *Handlers) waitlistEntryFromID(c *gin.Context) (*models.WaitlistEntry, bool) {
+	entryID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: "Invalid waitlist entry ID",
+		})
+		return nil, false
+	}
+
+	entry, err := h.db.GetWaitlistEntry(c.Request.Context(), entryID)
+	if err != nil {
+		respondWaitlistError(c, err)
+		return nil, false
+	}
+	return entry, true
+}
+
+func respondWaitlistError(c *gin.Context, err error) {
+	if errors.Is(err, database.ErrWaitlistNotFound) {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Error: "Waitlist entry not found",
+		})
+		return
+	}
+
+	log.Printf("Error getting waitlist entry: %v", err)
+	c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+		Error: "Failed to fetch waitlist entry",
+	})
+}
@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"testing"
+
+	"mumuni_backend/auth"
+	"mumuni_backend/database"
+	"mumuni_backend/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestMovingOffTheWaitlistTakesASeat(t *testing.T) {
+	h, store := newTestHandlers(t)
+	ctx := context.Background()
+
+	offering, err := store.CreateClassOffering(ctx, &models.ClassOfferingRequest{Name: "Basics"})
+	if err != nil {
+		t.Fatalf("CreateClassOffering: %v", err)
+	}
+	cohortReq := &models.CohortRequest{
+		OfferingID: offering.ID,
+		Name:       "January",
+		StartDate:  bookingDate(30),
+		Capacity:   1,
+	}
+	cohort, err := store.CreateCohort(ctx, cohortReq)
+	if err != nil {
+		t.Fatalf("CreateCohort: %v", err)
+	}
+
+	var classes []*models.Class
+	for i := 0; i < 2; i++ {
+		class, err := store.CreateClass(ctx, &models.ClassRequest{
+			Name:       "Student " + strconv.Itoa(i),
+			Email:      "student" + strconv.Itoa(i) + "@example.com",
+			Phone:      "+2348031112233",
+			Experience: "beginner",
+			CohortID:   &cohort.ID,
+		}, "hash-"+strconv.Itoa(i), true)
+		if err != nil {
+			t.Fatalf("CreateClass: %v", err)
+		}
+		classes = append(classes, class)
+	}
+	waitlisted := classes[1]
+	entry, err := store.CreateWaitlistEntry(ctx, &models.WaitlistEntry{
+		Kind:     "class",
+		Name:     waitlisted.Name,
+		Email:    waitlisted.Email,
+		Phone:    waitlisted.Phone,
+		CohortID: &cohort.ID,
+		ClassID:  &waitlisted.ID,
+	})
+	if err != nil {
+		t.Fatalf("CreateWaitlistEntry: %v", err)
+	}
+
+	r := gin.New()
+	r.PUT("/api/admin/classes/:id/status", asAdmin(auth.RoleOwner, nil), h.UpdateClassStatus)
+	path := "/api/admin/classes/" + strconv.Itoa(waitlisted.ID) + "/status"
+	pending := map[string]string{"status": "pending"}
+
+	if w := serve(r, http.MethodPut, path, pending); w.Code != http.StatusConflict {
+		t.Fatalf("moving off the waitlist of a full cohort: got %d %s, want 409", w.Code, w.Body)
+	}
+	if stored, _ := store.GetClass(ctx, waitlisted.ID); stored.Status != "waitlisted" {
+		t.Errorf("the enrollment is %s, want it left waitlisted", stored.Status)
+	}
+
+	cohortReq.Capacity = 2
+	if _, err := store.UpdateCohort(ctx, cohort.ID, cohortReq); err != nil {
+		t.Fatalf("UpdateCohort: %v", err)
+	}
+	if w := serve(r, http.MethodPut, path, pending); w.Code != http.StatusOK {
+		t.Fatalf("moving off the waitlist with a seat free: got %d %s, want 200", w.Code, w.Body)
+	}
+	if stored, _ := store.GetWaitlistEntry(ctx, entry.ID); stored.Status != "promoted" {
+		t.Errorf("the waitlist entry is %s, want promoted", stored.Status)
+	}
+	if stored, _ := store.GetCohort(ctx, cohort.ID); stored.SeatsLeft != 0 {
+		t.Errorf("the cohort has %d seats left, want 0", stored.SeatsLeft)
+	}
+}
+
+func TestFillCohortStopsWhenFull(t *testing.T) {
+	h, store := newTestHandlers(t)
+	ctx := context.Background()
+
+	offering, err := store.CreateClassOffering(ctx, &models.ClassOfferingRequest{Name: "Basics"})
+	if err != nil {
+		t.Fatalf("CreateClassOffering: %v", err)
+	}
+	cohort, err := store.CreateCohort(ctx, &models.CohortRequest{
+		OfferingID: offering.ID,
+		Name:       "January",
+		StartDate:  bookingDate(30),
+		Capacity:   1,
+	})
+	if err != nil {
+		t.Fatalf("CreateCohort: %v", err)
+	}
+	// The first student takes the only seat, so the others are waitlisted
+	var first int
+	for i := 0; i < 3; i++ {
+		class, err := store.CreateClass(ctx, &models.ClassRequest{
+			Name:       "Student " + strconv.Itoa(i),
+			Email:      "student" + strconv.Itoa(i) + "@example.com",
+			Phone:      "+2348031112233",
+			Experience: "beginner",
+			CohortID:   &cohort.ID,
+		}, "hash-"+strconv.Itoa(i), true)
+		if err != nil {
+			t.Fatalf("CreateClass: %v", err)
+		}
+		if i == 0 {
+			first = class.ID
+			continue
+		}
+		if _, err := store.CreateWaitlistEntry(ctx, &models.WaitlistEntry{
+			Kind:     "class",
+			Name:     class.Name,
+			Email:    class.Email,
+			Phone:    class.Phone,
+			CohortID: &cohort.ID,
+			ClassID:  &class.ID,
+		}); err != nil {
+			t.Fatalf("CreateWaitlistEntry: %v", err)
+		}
+	}
+
+	if _, err := store.UpdateClassStatus(ctx, first, database.StatusChange{Status: "cancelled", Reason: "Moved away"}); err != nil {
+		t.Fatalf("UpdateClassStatus: %v", err)
+	}
+
+	h.fillCohort(ctx, cohort.ID)
+
+	waiting, err := store.GetWaitlistEntries(ctx, database.WaitlistFilter{Status: "waiting"})
+	if err != nil {
+		t.Fatalf("GetWaitlistEntries: %v", err)
+	}
+	if len(waiting) != 1 {
+		t.Errorf("%d entries still waiting, want 1", len(waiting))
+	}
+	if stored, _ := store.GetCohort(ctx, cohort.ID); stored.SeatsLeft != 0 || stored.SeatsTaken != 1 {
+		t.Errorf("the cohort has %d seats taken and %d left, want 1 and 0", stored.SeatsTaken, stored.SeatsLeft)
+	}
+}
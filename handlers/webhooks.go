@@ -0,0 +1,293 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"mumuni_backend/database"
+	"mumuni_backend/models"
+	"mumuni_backend/webhooks"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetWebhooks handles GET /api/admin/webhooks
+func (h *Handlers) GetWebhooks(c *gin.Context) {
+	subscriptions, err := h.db.GetWebhookSubscriptions(c.Request.Context(), false)
+	if err != nil {
+		log.Printf("Error getting webhook subscriptions: %v", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error: "Failed to fetch webhooks",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":  true,
+		"webhooks": subscriptions,
+		"count":    len(subscriptions),
+	})
+}
+
+// GetWebhook handles GET /api/admin/webhooks/:id
+func (h *Handlers) GetWebhook(c *gin.Context) {
+	subscriptionID, ok := webhookIDParam(c)
+	if !ok {
+		return
+	}
+
+	subscription, err := h.db.GetWebhookSubscription(c.Request.Context(), subscriptionID)
+	if err != nil {
+		respondWebhookError(c, err, "Failed to fetch webhook")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"webhook": subscription,
+	})
+}
+
+// CreateWebhook handles POST /api/admin/webhooks
+func (h *Handlers) CreateWebhook(c *gin.Context) {
+	var req models.WebhookSubscriptionRequest
+	if !h.bindWebhookRequest(c, &req) {
+		return
+	}
+	if req.Secret == "" {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: "A secret of at least 16 characters is required",
+		})
+		return
+	}
+
+	subscription, err := h.db.CreateWebhookSubscription(c.Request.Context(), &req)
+	if err != nil {
+		respondWebhookError(c, err, "Failed to create webhook")
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"message": "Webhook created successfully",
+		"webhook": subscription,
+	})
+}
+
+// UpdateWebhook handles PUT /api/admin/webhooks/:id
+// The secret is kept when left out.
+func (h *Handlers) UpdateWebhook(c *gin.Context) {
+	subscriptionID, ok := webhookIDParam(c)
+	if !ok {
+		return
+	}
+
+	var req models.WebhookSubscriptionRequest
+	if !h.bindWebhookRequest(c, &req) {
+		return
+	}
+
+	subscription, err := h.db.UpdateWebhookSubscription(c.Request.Context(), subscriptionID, &req)
+	if err != nil {
+		respondWebhookError(c, err, "Failed to update webhook")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Webhook updated successfully",
+		"webhook": subscription,
+	})
+}
+
+// DeleteWebhook handles DELETE /api/admin/webhooks/:id
+func (h *Handlers) DeleteWebhook(c *gin.Context) {
+	subscriptionID, ok := webhookIDParam(c)
+	if !ok {
+		return
+	}
+
+	if err := h.db.DeleteWebhookSubscription(c.Request.Context(), subscriptionID); err != nil {
+		respondWebhookError(c, err, "Failed to delete webhook")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Success: true,
+		Message: "Webhook deleted successfully",
+	})
+}
+
+// GetWebhookDeliveries handles GET /api/admin/webhook-deliveries?subscription_id=&event=&status=&sort=&order=&limit=&offset=
+// Deliveries are listed a page at a time, newest first unless sorted
+// otherwise.
+func (h *Handlers) GetWebhookDeliveries(c *gin.Context) {
+	filter := database.WebhookDeliveryFilter{
+		Event:  c.Query("event"),
+		Status: c.Query("status"),
+	}
+	if value := c.Query("subscription_id"); value != "" {
+		subscriptionID, err := strconv.Atoi(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error: "Invalid webhook ID",
+			})
+			return
+		}
+		filter.SubscriptionID = &subscriptionID
+	}
+	h.respondWebhookDeliveries(c, filter)
+}
+
+// GetWebhookDeadLetters handles GET /api/admin/webhook-deliveries/failed?sort=&order=&limit=&offset=
+// These are the deliveries that failed every attempt, waiting for an admin
+// to retry them.
+func (h *Handlers) GetWebhookDeadLetters(c *gin.Context) {
+	h.respondWebhookDeliveries(c, database.WebhookDeliveryFilter{Status: "failed"})
+}
+
+// GetWebhookDelivery handles GET /api/admin/webhook-deliveries/:id
+func (h *Handlers) GetWebhookDelivery(c *gin.Context) {
+	deliveryID, ok := deliveryIDParam(c)
+	if !ok {
+		return
+	}
+
+	delivery, err := h.db.GetWebhookDelivery(c.Request.Context(), deliveryID)
+	if err != nil {
+		respondWebhookError(c, err, "Failed to fetch webhook delivery")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":  true,
+		"delivery": delivery,
+	})
+}
+
+// RetryWebhookDelivery handles POST /api/admin/webhook-deliveries/:id/retry
+// A failed delivery gets a fresh set of attempts, starting right away.
+func (h *Handlers) RetryWebhookDelivery(c *gin.Context) {
+	deliveryID, ok := deliveryIDParam(c)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	delivery, err := h.db.GetWebhookDelivery(ctx, deliveryID)
+	if err != nil {
+		respondWebhookError(c, err, "Failed to retry webhook delivery")
+		return
+	}
+	if delivery.Status != "failed" {
+		c.JSON(http.StatusConflict, models.ErrorResponse{
+			Error: "Only failed deliveries can be retried",
+		})
+		return
+	}
+
+	delivery.Status = "pending"
+	delivery.Attempts = 0
+	delivery.NextAttemptAt = time.Now().UTC()
+	delivery, err = h.db.UpdateWebhookDelivery(ctx, delivery)
+	if err != nil {
+		respondWebhookError(c, err, "Failed to retry webhook delivery")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":  true,
+		"message":  "Webhook delivery queued for retry",
+		"delivery": delivery,
+	})
+}
+
+func (h *Handlers) respondWebhookDeliveries(c *gin.Context, filter database.WebhookDeliveryFilter) {
+	page, ok := pageParams(c, database.WebhookDeliverySorts)
+	if !ok {
+		return
+	}
+
+	deliveries, total, err := h.db.GetWebhookDeliveries(c.Request.Context(), filter, page)
+	if err != nil {
+		log.Printf("Error getting webhook deliveries: %v", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error: "Failed to fetch webhook deliveries",
+		})
+		return
+	}
+
+	respondPage(c, "deliveries", deliveries, len(deliveries), total, page)
+}
+
+// bindWebhookRequest binds and checks a subscription. Its URL must be https
+// and resolve to public addresses, unless WEBHOOK_ALLOW_LOCAL_URLS is set.
+func (h *Handlers) bindWebhookRequest(c *gin.Context, req *models.WebhookSubscriptionRequest) bool {
+	if err := c.ShouldBindJSON(req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: "Invalid request data: " + err.Error(),
+		})
+		return false
+	}
+
+	if err := webhooks.CheckURL(c.Request.Context(), req.URL, h.cfg.WebhookAllowLocalURLs); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: "Invalid webhook URL: " + err.Error(),
+		})
+		return false
+	}
+	for _, event := range req.Events {
+		if !webhooks.ValidEvent(event) {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error: "Invalid event " + event + ". Must be one of: " + strings.Join(webhooks.Events, ", "),
+			})
+			return false
+		}
+	}
+	req.Events = uniqueChannels(req.Events)
+
+	return true
+}
+
+func webhookIDParam(c *gin.Context) (int, bool) {
+	subscriptionID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: "Invalid webhook ID",
+		})
+		return 0, false
+	}
+	return subscriptionID, true
+}
+
+func deliveryIDParam(c *gin.Context) (int, bool) {
+	deliveryID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error: "Invalid delivery ID",
+		})
+		return 0, false
+	}
+	return deliveryID, true
+}
+
+func respondWebhookError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, database.ErrWebhookNotFound):
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Error: "Webhook not found",
+		})
+	case errors.Is(err, database.ErrDeliveryNotFound):
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Error: "Webhook delivery not found",
+		})
+	default:
+		log.Printf("%s: %v", message, err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error: message,
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"mumuni_backend/config"
+	"mumuni_backend/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestCreateWebhookRejectsLocalURLs(t *testing.T) {
+	h, store := newTestHandlers(t)
+	r := gin.New()
+	r.POST("/api/admin/webhooks", h.CreateWebhook)
+
+	subscribe := func(url string) int {
+		return serve(r, http.MethodPost, "/api/admin/webhooks", map[string]any{
+			"url":    url,
+			"secret": "a-webhook-secret-0123456789",
+			"events": []string{"appointment.created"},
+		}).Code
+	}
+	for _, url := range []string{
+		"http://93.184.215.14/hook",
+		"https://127.0.0.1/hook",
+		"https://localhost/hook",
+		"https://169.254.169.254/latest/meta-data",
+		"https://[::1]/hook",
+		"https://10.0.0.5/hook",
+	} {
+		if code := subscribe(url); code != http.StatusBadRequest {
+			t.Errorf("subscribing %s: got %d, want 400", url, code)
+		}
+	}
+	if subscriptions, _ := store.GetWebhookSubscriptions(context.Background(), false); len(subscriptions) != 0 {
+		t.Errorf("stored %d subscriptions, want none", len(subscriptions))
+	}
+	if code := subscribe("https://93.184.215.14/hook"); code != http.StatusCreated {
+		t.Errorf("subscribing a public https URL: got %d, want 201", code)
+	}
+}
+
+func TestCreateWebhookAllowsLocalURLsInDevelopment(t *testing.T) {
+	h, _ := newTestHandlers(t, func(cfg *config.Config) {
+		cfg.WebhookAllowLocalURLs = true
+	})
+	r := gin.New()
+	r.POST("/api/admin/webhooks", h.CreateWebhook)
+
+	w := serve(r, http.MethodPost, "/api/admin/webhooks", map[string]any{
+		"url":    "http://localhost:9000/hook",
+		"secret": "a-webhook-secret-0123456789",
+		"events": []string{"appointment.created"},
+	})
+	if w.Code != http.StatusCreated {
+		t.Errorf("got %d %s, want 201", w.Code, w.Body)
+	}
+}
+
+func TestGetWebhookDeliveriesPages(t *testing.T) {
+	h, store := newTestHandlers(t)
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		_, err := store.CreateWebhookDelivery(ctx, &models.WebhookDelivery{
+			SubscriptionID: 1,
+			Event:          "appointment.created",
+			Payload:        json.RawMessage(`{}`),
+		})
+		if err != nil {
+			t.Fatalf("CreateWebhookDelivery: %v", err)
+		}
+	}
+	r := gin.New()
+	r.GET("/api/admin/webhook-deliveries", h.GetWebhookDeliveries)
+
+	var first struct {
+		Deliveries []models.WebhookDelivery `json:"deliveries"`
+		Total      int                      `json:"total"`
+		Next       *string                  `json:"next"`
+	}
+	decode(t, serve(r, http.MethodGet, "/api/admin/webhook-deliveries?limit=2", nil), &first)
+	if len(first.Deliveries) != 2 || first.Total != 3 || first.Next == nil {
+		t.Fatalf("first page: %d deliveries of %d, next %v; want 2 of 3 and a next page", len(first.Deliveries), first.Total, first.Next)
+	}
+	if first.Deliveries[0].ID != 3 || first.Deliveries[1].ID != 2 {
+		t.Errorf("first page has deliveries %d and %d, want the newest, 3 and 2", first.Deliveries[0].ID, first.Deliveries[1].ID)
+	}
+
+	var last struct {
+		Deliveries []models.WebhookDelivery `json:"deliveries"`
+		Next       *string                  `json:"next"`
+	}
+	decode(t, serve(r, http.MethodGet, *first.Next, nil), &last)
+	if len(last.Deliveries) != 1 || last.Deliveries[0].ID != 1 || last.Next != nil {
+		t.Errorf("last page: %+v, next %v; want delivery 1 and no next page", last.Deliveries, last.Next)
+	}
+
+	for _, query := range []string{"limit=0", "limit=201", "offset=-1", "sort=event"} {
+		if w := serve(r, http.MethodGet, "/api/admin/webhook-deliveries?"+query, nil); w.Code != http.StatusBadRequest {
+			t.Errorf("?%s: got %d, want 400", query, w.Code)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+// Package jobs runs background work persisted in the scheduled_jobs table,
+// so jobs scheduled before a restart still run after it.
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"mumuni_backend/database"
+	"mumuni_backend/models"
+	"time"
+)
+
+// Job kinds
+const (
+	AppointmentReminder = "appointment_reminder"
+	ClassReminder       = "class_reminder"
+)
+
+const (
+	// maxAttempts is how many times a job is tried before it is marked
+	// "failed".
+	maxAttempts = 5
+	// claimLease keeps a claimed job from being picked up by another
+	// instance while it runs.
+	claimLease = 5 * time.Minute
+	// batchSize is how many jobs one pass claims.
+	batchSize = 20
+)
+
+// Handler runs one job. An error schedules a retry.
+type Handler func(ctx context.Context, job *models.Job) error
+
+// Runner runs due jobs with the handler registered for their kind.
+type Runner struct {
+	store    database.Store
+	handlers map[string]Handler
+}
+
+func NewRunner(store database.Store) *Runner {
+	return &Runner{store: store, handlers: make(map[string]Handler)}
+}
+
+// Handle registers the handler for jobs of kind.
+func (r *Runner) Handle(kind string, handler Handler) {
+	r.handlers[kind] = handler
+}
+
+// Run runs due jobs every interval until ctx is cancelled.
+func (r *Runner) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		r.RunDue(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunDue runs every job that is due, batch by batch. Failed jobs are retried
+// with exponential backoff, starting at a minute and capped at an hour.
+func (r *Runner) RunDue(ctx context.Context) {
+	for {
+		jobs, err := r.store.ClaimJobs(ctx, time.Now(), claimLease, batchSize)
+		if err != nil {
+			log.Printf("Error claiming jobs: %v", err)
+			return
+		}
+
+		for i := range jobs {
+			r.run(ctx, &jobs[i])
+		}
+		if len(jobs) < batchSize {
+			return
+		}
+	}
+}
+
+func (r *Runner) run(ctx context.Context, job *models.Job) {
+	var err error
+	if handler, ok := r.handlers[job.Kind]; ok {
+		err = handler(ctx, job)
+	} else {
+		err = fmt.Errorf("no handler for job kind %q", job.Kind)
+	}
+
+	if err == nil {
+		job.Status = "done"
+		job.LastError = nil
+	} else {
+		message := err.Error()
+		job.LastError = &message
+		if job.Attempts >= maxAttempts {
+			job.Status = "failed"
+			log.Printf("Giving up on %s job %d after %d attempts: %v", job.Kind, job.ID, job.Attempts, err)
+		} else {
+			job.RunAt = time.Now().UTC().Add(retryDelay(job.Attempts))
+			log.Printf("Error running %s job %d, retrying at %s: %v", job.Kind, job.ID, job.RunAt.Format(time.RFC3339), err)
+		}
+	}
+
+	if _, err := r.store.UpdateJob(ctx, job); err != nil {
+		log.Printf("Error updating job %d: %v", job.ID, err)
+	}
+}
+
+// retryDelay is how long to wait after the given number of failed attempts.
+func retryDelay(attempts int) time.Duration {
+	delay := time.Minute << (attempts - 1)
+	if delay > time.Hour || delay <= 0 {
+		return time.Hour
+	}
+	return delay
+}
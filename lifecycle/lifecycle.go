@@ -0,0 +1,55 @@
+// Package lifecycle holds the status rules shared by appointments and class
+// enrollments: which status may follow which, and what a change must carry.
+package lifecycle
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrReasonRequired is returned when a cancellation has no reason.
+var ErrReasonRequired = errors.New("a reason is required to cancel")
+
+// TransitionError is returned for a status change the lifecycle doesn't allow.
+type TransitionError struct {
+	From string
+	To   string
+}
+
+func (e *TransitionError) Error() string {
+	return fmt.Sprintf("cannot change status from %s to %s", e.From, e.To)
+}
+
+// transitions lists the statuses each status may move to. Completed and
+// cancelled are final. Only enrollments are ever waitlisted.
+var transitions = map[string][]string{
+	"waitlisted": {"pending", "cancelled"},
+	"pending":    {"confirmed", "cancelled"},
+	"confirmed":  {"completed", "cancelled"},
+}
+
+// Next returns the statuses that may follow from.
+func Next(from string) []string {
+	return transitions[from]
+}
+
+// Check reports whether a record in status from may move to status to,
+// given the reason supplied with the change.
+func Check(from, to, reason string) error {
+	allowed := false
+	for _, next := range transitions[from] {
+		if next == to {
+			allowed = true
+		}
+	}
+	if !allowed {
+		return &TransitionError{From: from, To: to}
+	}
+
+	if to == "cancelled" && strings.TrimSpace(reason) == "" {
+		return ErrReasonRequired
+	}
+
+	return nil
+}
@@ -0,0 +1,76 @@
+package lifecycle
+
+import (
+	"errors"
+	"testing"
+)
+
+var statuses = []string{"waitlisted", "pending", "confirmed", "completed", "cancelled"}
+
+func TestCheck(t *testing.T) {
+	// Every change allowed; every other pair of statuses is refused
+	allowed := map[[2]string]bool{
+		{"waitlisted", "pending"}:   true,
+		{"waitlisted", "cancelled"}: true,
+		{"pending", "confirmed"}:    true,
+		{"pending", "cancelled"}:    true,
+		{"confirmed", "completed"}:  true,
+		{"confirmed", "cancelled"}:  true,
+	}
+	for _, from := range statuses {
+		for _, to := range statuses {
+			err := Check(from, to, "Customer asked")
+			if allowed[[2]string{from, to}] {
+				if err != nil {
+					t.Errorf("Check(%s, %s) = %v, want nil", from, to, err)
+				}
+				continue
+			}
+
+			var transitionErr *TransitionError
+			if !errors.As(err, &transitionErr) || transitionErr.From != from || transitionErr.To != to {
+				t.Errorf("Check(%s, %s) = %v, want a TransitionError", from, to, err)
+			}
+		}
+	}
+}
+
+func TestFinalStatuses(t *testing.T) {
+	for _, status := range []string{"completed", "cancelled"} {
+		if next := Next(status); len(next) != 0 {
+			t.Errorf("Next(%s) = %v, want none", status, next)
+		}
+	}
+	for _, status := range []string{"waitlisted", "pending", "confirmed"} {
+		if next := Next(status); len(next) == 0 {
+			t.Errorf("Next(%s) is empty, want the statuses it can move to", status)
+		}
+	}
+}
+
+func TestCancellingNeedsAReason(t *testing.T) {
+	tests := []struct {
+		from   string
+		reason string
+		want   error
+	}{
+		{"pending", "", ErrReasonRequired},
+		{"confirmed", "   ", ErrReasonRequired},
+		{"waitlisted", "\n", ErrReasonRequired},
+		{"pending", "Double booked", nil},
+	}
+	for _, tt := range tests {
+		if err := Check(tt.from, "cancelled", tt.reason); !errors.Is(err, tt.want) {
+			t.Errorf("Check(%s, cancelled, %q) = %v, want %v", tt.from, tt.reason, err, tt.want)
+		}
+	}
+	// A change the lifecycle refuses is refused whatever the reason
+	var transitionErr *TransitionError
+	if err := Check("completed", "cancelled", ""); !errors.As(err, &transitionErr) {
+		t.Errorf("Check(completed, cancelled, \"\") = %v, want a TransitionError", err)
+	}
+	// Only cancelling needs a reason
+	if err := Check("pending", "confirmed", ""); err != nil {
+		t.Errorf("Check(pending, confirmed, \"\") = %v, want nil", err)
+	}
+}
@@ -1,12 +1,25 @@
 package main
 
 import (
+	"context"
+	"fmt"
 	"log"
 	"mumuni_backend/auth"
 	"mumuni_backend/config"
 	"mumuni_backend/database"
 	"mumuni_backend/handlers"
+	"mumuni_backend/jobs"
 	"mumuni_backend/middleware"
+	"mumuni_backend/notify"
+	"mumuni_backend/phone"
+	"mumuni_backend/ratelimit"
+	"mumuni_backend/scheduling"
+	"mumuni_backend/spam"
+	"mumuni_backend/webhooks"
+	"os"
+	"strconv"
+	"time"
+	_ "time/tzdata"
 
 	"github.com/gin-gonic/gin"
 )
@@ -15,22 +28,141 @@ func main() {
 	// Load configuration
 	cfg := config.LoadConfig()
 
+	// Subcommands
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "migrate":
+			if err := runMigrate(cfg, os.Args[2:]); err != nil {
+				log.Fatalf("Migration failed: %v", err)
+			}
+			return
+		case "normalize-phones":
+			if err := runNormalizePhones(cfg, os.Args[2:]); err != nil {
+				log.Fatalf("Normalizing phone numbers failed: %v", err)
+			}
+			return
+		default:
+			log.Fatalf("Unknown command %q. Available commands: migrate, normalize-phones", os.Args[1])
+		}
+	}
+
 	// Validate required configuration
-	if cfg.SupabaseURL == "" || cfg.SupabaseAnonKey == "" {
+	if cfg.DatabaseDriver == "supabase" && (cfg.SupabaseURL == "" || cfg.SupabaseAnonKey == "") {
 		log.Fatal("Missing required Supabase configuration. Please set SUPABASE_URL and SUPABASE_ANON_KEY")
 	}
+	if cfg.CohortFullPolicy != "reject" && cfg.CohortFullPolicy != "waitlist" {
+		log.Fatalf("Invalid COHORT_FULL_POLICY %q. Must be reject or waitlist", cfg.CohortFullPolicy)
+	}
+	if cfg.WaitlistMode != "auto" && cfg.WaitlistMode != "offer" {
+		log.Fatalf("Invalid WAITLIST_MODE %q. Must be auto or offer", cfg.WaitlistMode)
+	}
+	if cfg.IdempotencyKeyTTL <= 0 {
+		log.Fatal("IDEMPOTENCY_KEY_TTL must be positive")
+	}
+	if cfg.AvailabilityMaxDays < 1 {
+		log.Fatalf("Invalid AVAILABILITY_MAX_DAYS %d. Must be at least 1", cfg.AvailabilityMaxDays)
+	}
 
 	// Set JWT secret
 	auth.SetJWTSecret(cfg.JWTSecret)
 
 	// Initialize database
-	db, err := database.NewDatabase(cfg)
+	db, err := database.NewStore(cfg)
 	if err != nil {
 		log.Fatalf("Failed to initialize database: %v", err)
 	}
+	log.Printf("Using %s database driver", cfg.DatabaseDriver)
+
+	// Initialize the availability engine
+	scheduler, err := scheduling.NewEngine(cfg)
+	if err != nil {
+		log.Fatalf("Invalid scheduling configuration: %v", err)
+	}
+	log.Printf("Booking appointments in the %s time zone", scheduler.Location)
+
+	// Initialize notifications and deliver the outbox in the background
+	notifier, err := notify.NewNotifier(cfg, db)
+	if err != nil {
+		log.Fatalf("Failed to initialize notifications: %v", err)
+	}
+	var mailer notify.Mailer = notify.LogMailer{}
+	if cfg.SMTPHost != "" {
+		mailer, err = notify.NewSMTPMailer(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUsername, cfg.SMTPPassword, cfg.SMTPFrom)
+		if err != nil {
+			log.Fatalf("Invalid SMTP configuration: %v", err)
+		}
+		log.Printf("Sending email through %s:%d", cfg.SMTPHost, cfg.SMTPPort)
+	} else {
+		log.Println("SMTP_HOST not set, emails will be logged instead of sent")
+	}
+	var messenger notify.Messenger
+	switch cfg.MessagingProvider {
+	case "http":
+		if cfg.MessagingAPIURL == "" {
+			log.Fatal("MESSAGING_API_URL is required when MESSAGING_PROVIDER=http")
+		}
+		messenger = notify.NewHTTPMessenger(cfg.MessagingAPIURL, cfg.MessagingAPIKey, cfg.MessagingSMSFrom,
+			cfg.MessagingWhatsAppFrom, cfg.MessagingStatusURL)
+		log.Printf("Sending SMS and WhatsApp messages through %s", cfg.MessagingAPIURL)
+	case "fake":
+		messenger = &notify.LogMessenger{}
+		log.Println("MESSAGING_PROVIDER is fake, SMS and WhatsApp messages will be logged instead of sent")
+	case "":
+		log.Println("MESSAGING_PROVIDER not set, SMS and WhatsApp messages will be marked failed instead of sent")
+	default:
+		log.Fatalf("Unknown MESSAGING_PROVIDER: %s", cfg.MessagingProvider)
+	}
+	go notify.NewWorker(db, mailer, messenger, cfg.JWTSecret).Run(context.Background(), 30*time.Second)
+
+	// Send webhook deliveries in the background
+	publisher := webhooks.NewPublisher(db)
+	go webhooks.NewWorker(db, cfg.WebhookAllowLocalURLs).Run(context.Background(), 30*time.Second)
+
+	// Screen public bookings for spam, keeping rate limits in memory
+	guard, err := spam.NewGuard(cfg, ratelimit.NewMemoryStore())
+	if err != nil {
+		log.Fatalf("Invalid spam protection configuration: %v", err)
+	}
+	if cfg.BookingVerifier != "" {
+		log.Printf("Public bookings must pass the %s verifier", cfg.BookingVerifier)
+	}
+
+	// Normalize phone numbers to E.164
+	phones, err := phone.NewParser(cfg.DefaultPhoneCountry)
+	if err != nil {
+		log.Fatalf("Invalid DEFAULT_PHONE_COUNTRY: %v", err)
+	}
 
 	// Initialize handlers
-	h := handlers.NewHandlers(db)
+	h := handlers.NewHandlers(cfg, db, scheduler, notifier, publisher, guard, phones)
+
+	// Run scheduled jobs such as reminders in the background
+	runner := jobs.NewRunner(db)
+	runner.Handle(jobs.AppointmentReminder, h.RunAppointmentReminder)
+	runner.Handle(jobs.ClassReminder, h.RunClassReminder)
+	go runner.Run(context.Background(), 30*time.Second)
+
+	// Hand expired waitlist offers to the next person in line
+	if cfg.WaitlistMode == "offer" {
+		go func() {
+			ticker := time.NewTicker(time.Minute)
+			defer ticker.Stop()
+			for range ticker.C {
+				h.ExpireWaitlistOffers(context.Background())
+			}
+		}()
+	}
+
+	// Forget idempotency keys once they expire
+	go func() {
+		ticker := time.NewTicker(time.Hour)
+		defer ticker.Stop()
+		for range ticker.C {
+			if _, err := db.DeleteExpiredIdempotencyKeys(context.Background(), time.Now()); err != nil {
+				log.Printf("Error deleting expired idempotency keys: %v", err)
+			}
+		}
+	}()
 
 	// Set Gin mode
 	if gin.Mode() == gin.DebugMode {
@@ -38,8 +170,80 @@ func main() {
 	}
 
 	// Create Gin router
+	r, err := newRouter(cfg, db, h)
+	if err != nil {
+		log.Fatalf("Invalid TRUSTED_PROXIES: %v", err)
+	}
+
+	// Start server
+	port := cfg.Port
+	if port == "" {
+		port = "8080"
+	}
+
+	log.Printf("Server starting on port %s", port)
+	log.Printf("Health check: http://localhost:%s/health", port)
+	log.Printf("API endpoints:")
+	log.Printf("  POST /api/appointments - Book appointment")
+	log.Printf("  GET /api/availability - Open appointment slots")
+	log.Printf("  GET /api/artists - List artists")
+	log.Printf("  GET /api/services - List services")
+	log.Printf("  POST /api/classes - Enroll in class")
+	log.Printf("  GET /api/class-offerings - List class offerings")
+	log.Printf("  GET /api/cohorts - List upcoming cohorts")
+	log.Printf("  POST /api/waitlist - Join the waitlist for a booked-out day")
+	log.Printf("  GET /api/waitlist/:token, POST /api/waitlist/:token/accept|leave - Manage a waitlist entry")
+	log.Printf("  GET|PUT /api/manage/appointments/:token, POST /api/manage/appointments/:token/cancel - Manage an appointment")
+	log.Printf("  GET|PUT /api/manage/classes/:token, POST /api/manage/classes/:token/cancel - Manage a class enrollment")
+	log.Printf("  GET|PUT /api/manage/appointments/:token/notifications, GET|PUT /api/manage/classes/:token/notifications - Notification channels")
+	log.Printf("  POST /api/notifications/status - Messaging provider delivery reports")
+	log.Printf("  POST /api/admin/signup - Create the first owner (requires ADMIN_BOOTSTRAP_TOKEN)")
+	log.Printf("  POST /api/admin/login - Admin login")
+	log.Printf("  POST /api/admin/login/2fa - Finish a login with a two-factor code")
+	log.Printf("  POST /api/admin/refresh - Swap a refresh token for new tokens")
+	log.Printf("  POST /api/admin/password/forgot, POST /api/admin/password/reset - Reset a forgotten password by email")
+	log.Printf("  GET/POST /api/admin/register/:token - View an admin invite or register with it")
+	log.Printf("  GET /api/admin/me - The signed-in admin and their permissions (requires auth)")
+	log.Printf("  POST /api/admin/logout - End the current session (requires auth)")
+	log.Printf("  PUT /api/admin/password - Change your password (requires auth)")
+	log.Printf("  GET /api/admin/sessions, DELETE /api/admin/sessions/:id - List or end your sessions (requires auth)")
+	log.Printf("  POST /api/admin/2fa/setup|confirm|disable - Set up or turn off two-factor authentication (requires auth)")
+	log.Printf("  GET /api/admin/appointments - Get appointments (requires auth)")
+	log.Printf("  GET /api/admin/classes - Get classes (requires auth)")
+	log.Printf("  PUT /api/admin/appointments/:id/status - Update appointment status (requires auth)")
+	log.Printf("  PUT /api/admin/classes/:id/status - Update class status (requires auth)")
+	log.Printf("  GET /api/admin/appointments/:id/history, GET /api/admin/classes/:id/history - Status history (requires auth)")
+	log.Printf("  GET /api/admin/customers, GET|PUT /api/admin/customers/:id - Customer profiles with booking history (requires auth)")
+	log.Printf("  POST /api/admin/customers/:id/merge - Merge a duplicate customer (requires auth)")
+	log.Printf("  GET|POST /api/admin/services, GET|PUT|DELETE /api/admin/services/:id - Manage services (requires auth)")
+	log.Printf("  GET|POST /api/admin/class-offerings, GET|PUT|DELETE /api/admin/class-offerings/:id - Manage class offerings (requires auth)")
+	log.Printf("  GET|POST /api/admin/cohorts, GET|PUT|DELETE /api/admin/cohorts/:id - Manage cohorts (requires auth)")
+	log.Printf("  GET /api/admin/waitlist, GET /api/admin/waitlist/:id, POST /api/admin/waitlist/:id/promote|cancel - Manage the waitlist (requires auth)")
+	log.Printf("  GET|POST /api/admin/artists, GET|PUT|DELETE /api/admin/artists/:id - Manage artists (requires auth)")
+	log.Printf("  GET /api/admin/jobs - Scheduled jobs such as reminders (requires auth)")
+	log.Printf("  GET /api/admin/notifications, GET|PUT /api/admin/notification-preferences/:email - Notifications and channels (requires auth)")
+	log.Printf("  GET|POST /api/admin/webhooks, GET|PUT|DELETE /api/admin/webhooks/:id - Manage webhooks (requires auth)")
+	log.Printf("  GET /api/admin/webhook-deliveries, GET /api/admin/webhook-deliveries/failed, POST /api/admin/webhook-deliveries/:id/retry - Webhook deliveries (requires auth)")
+	log.Printf("  GET /api/admin/admins, PUT /api/admin/admins/:id/role - Manage admin roles (requires auth)")
+	log.Printf("  GET/POST /api/admin/invites, DELETE /api/admin/invites/:id - Manage admin invites (requires auth)")
+	log.Printf("  POST /api/admin/admins/:id/unlock, DELETE /api/admin/admins/:id/2fa - Unlock an admin or reset their two-factor authentication (requires auth)")
+	log.Printf("  GET|PUT /api/admin/security, GET /api/admin/login-attempts - Security settings and login attempts (requires auth)")
+
+	if err := r.Run(":" + port); err != nil {
+		log.Fatalf("Failed to start server: %v", err)
+	}
+}
+
+// newRouter returns the router serving the API with h.
+func newRouter(cfg *config.Config, db database.Store, h *handlers.Handlers) (*gin.Engine, error) {
 	r := gin.Default()
 
+	// Believe X-Forwarded-For only from our own proxies, so clients can't
+	// choose the IP address their logins and bookings are counted against
+	if err := r.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		return nil, err
+	}
+
 	// Add CORS middleware
 	r.Use(middleware.CORSMiddleware())
 
@@ -51,14 +255,44 @@ func main() {
 		})
 	})
 
+	// Retries of these requests with the same Idempotency-Key get the first
+	// response back instead of running again
+	idempotent := middleware.Idempotency(db, cfg.IdempotencyKeyTTL)
+
 	// Public API routes
 	api := r.Group("/api")
 	{
 		// Appointment booking
-		api.POST("/appointments", h.BookAppointment)
+		api.POST("/appointments", idempotent, h.BookAppointment)
+		api.GET("/availability", h.GetAvailability)
+		api.GET("/artists", h.ListArtists)
+		api.GET("/services", h.ListServices)
 
 		// Class enrollment
-		api.POST("/classes", h.EnrollInClass)
+		api.POST("/classes", idempotent, h.EnrollInClass)
+		api.GET("/class-offerings", h.ListClassOfferings)
+		api.GET("/cohorts", h.ListCohorts)
+
+		// Waitlist
+		api.POST("/waitlist", h.JoinWaitlist)
+		api.GET("/waitlist/:token", h.GetWaitlistEntryByToken)
+		api.POST("/waitlist/:token/accept", h.AcceptWaitlistOffer)
+		api.POST("/waitlist/:token/leave", h.LeaveWaitlist)
+
+		// Customer self-service through the manage link
+		api.GET("/manage/appointments/:token", h.GetManagedAppointment)
+		api.PUT("/manage/appointments/:token", h.RescheduleManagedAppointment)
+		api.POST("/manage/appointments/:token/cancel", h.CancelManagedAppointment)
+		api.GET("/manage/classes/:token", h.GetManagedClass)
+		api.PUT("/manage/classes/:token", h.ChangeManagedClassCohort)
+		api.POST("/manage/classes/:token/cancel", h.CancelManagedClass)
+		api.GET("/manage/appointments/:token/notifications", h.GetManagedAppointmentNotifications)
+		api.PUT("/manage/appointments/:token/notifications", h.UpdateManagedAppointmentNotifications)
+		api.GET("/manage/classes/:token/notifications", h.GetManagedClassNotifications)
+		api.PUT("/manage/classes/:token/notifications", h.UpdateManagedClassNotifications)
+
+		// Delivery status reports from the messaging provider
+		api.POST("/notifications/status", h.RecordDeliveryStatus)
 	}
 
 	// Admin routes
@@ -67,37 +301,232 @@ func main() {
 		// Admin authentication (no auth required)
 		admin.POST("/signup", h.AdminSignup)
 		admin.POST("/login", h.AdminLogin)
+		admin.POST("/login/2fa", h.LoginTwoFactor)
+		admin.POST("/refresh", h.RefreshToken)
+		admin.POST("/password/forgot", h.ForgotPassword)
+		admin.POST("/password/reset", h.ResetPassword)
+		admin.GET("/register/:token", h.GetInvite)
+		admin.POST("/register/:token", h.RegisterAdmin)
 
-		// Protected admin routes
-		adminProtected := admin.Group("/")
-		adminProtected.Use(middleware.AuthMiddleware())
+		// The signed-in admin's own account, open even to an admin who still
+		// has to set up required two-factor authentication
+		adminAccount := admin.Group("/")
+		adminAccount.Use(middleware.AuthMiddleware(db))
 		{
-			adminProtected.GET("/appointments", h.GetAppointments)
-			adminProtected.GET("/classes", h.GetClasses)
-			adminProtected.PUT("/appointments/:id/status", h.UpdateAppointmentStatus)
-			adminProtected.PUT("/classes/:id/status", h.UpdateClassStatus)
+			adminAccount.GET("/me", h.GetCurrentAdmin)
+			adminAccount.POST("/logout", h.AdminLogout)
+			adminAccount.PUT("/password", h.ChangePassword)
+			adminAccount.GET("/sessions", h.GetSessions)
+			adminAccount.DELETE("/sessions/:id", h.RevokeSession)
+			adminAccount.POST("/2fa/setup", h.SetupTwoFactor)
+			adminAccount.POST("/2fa/confirm", h.ConfirmTwoFactor)
+			adminAccount.POST("/2fa/disable", h.DisableTwoFactor)
+		}
+
+		// Protected admin routes. Each route needs a permission of the
+		// signed-in admin's role; see auth.Permissions.
+		adminProtected := adminAccount.Group("/")
+		adminProtected.Use(middleware.RequireTwoFactorSetup())
+		{
+			viewAppointments := middleware.RequirePermission(auth.PermViewAppointments)
+			manageAppointments := middleware.RequirePermission(auth.PermManageAppointments)
+			viewClasses := middleware.RequirePermission(auth.PermViewClasses)
+			manageClasses := middleware.RequirePermission(auth.PermManageClasses)
+			viewCustomers := middleware.RequirePermission(auth.PermViewCustomers)
+			manageCustomers := middleware.RequirePermission(auth.PermManageCustomers)
+			manageCatalog := middleware.RequirePermission(auth.PermManageCatalog)
+			manageCohorts := middleware.RequirePermission(auth.PermManageCohorts)
+			manageWaitlist := middleware.RequirePermission(auth.PermManageWaitlist)
+			notifications := middleware.RequirePermission(auth.PermNotifications)
+			manageWebhooks := middleware.RequirePermission(auth.PermManageWebhooks)
+			manageAdmins := middleware.RequirePermission(auth.PermManageAdmins)
+
+			adminProtected.GET("/appointments", viewAppointments, h.GetAppointments)
+			adminProtected.GET("/classes", viewClasses, h.GetClasses)
+			adminProtected.PUT("/appointments/:id/status", manageAppointments, idempotent, h.UpdateAppointmentStatus)
+			adminProtected.PUT("/classes/:id/status", manageClasses, idempotent, h.UpdateClassStatus)
+			adminProtected.GET("/appointments/:id/history", viewAppointments, h.GetAppointmentHistory)
+			adminProtected.GET("/classes/:id/history", viewClasses, h.GetClassHistory)
+
+			// Customers
+			adminProtected.GET("/customers", viewCustomers, h.GetCustomers)
+			adminProtected.GET("/customers/:id", viewCustomers, h.GetCustomer)
+			adminProtected.PUT("/customers/:id", manageCustomers, h.UpdateCustomer)
+			adminProtected.POST("/customers/:id/merge", manageCustomers, h.MergeCustomers)
+
+			// Service catalog
+			adminProtected.GET("/services", manageCatalog, h.GetServices)
+			adminProtected.POST("/services", manageCatalog, h.CreateService)
+			adminProtected.GET("/services/:id", manageCatalog, h.GetService)
+			adminProtected.PUT("/services/:id", manageCatalog, h.UpdateService)
+			adminProtected.DELETE("/services/:id", manageCatalog, h.DeleteService)
+
+			// Class catalog
+			adminProtected.GET("/class-offerings", manageCatalog, h.GetClassOfferings)
+			adminProtected.POST("/class-offerings", manageCatalog, h.CreateClassOffering)
+			adminProtected.GET("/class-offerings/:id", manageCatalog, h.GetClassOffering)
+			adminProtected.PUT("/class-offerings/:id", manageCatalog, h.UpdateClassOffering)
+			adminProtected.DELETE("/class-offerings/:id", manageCatalog, h.DeleteClassOffering)
+			adminProtected.GET("/cohorts", manageCohorts, h.GetCohorts)
+			adminProtected.POST("/cohorts", manageCohorts, h.CreateCohort)
+			adminProtected.GET("/cohorts/:id", manageCohorts, h.GetCohort)
+			adminProtected.PUT("/cohorts/:id", manageCohorts, h.UpdateCohort)
+			adminProtected.DELETE("/cohorts/:id", manageCohorts, h.DeleteCohort)
+
+			// Waitlist
+			adminProtected.GET("/waitlist", manageWaitlist, h.GetWaitlist)
+			adminProtected.GET("/waitlist/:id", manageWaitlist, h.GetWaitlistEntry)
+			adminProtected.POST("/waitlist/:id/promote", manageWaitlist, h.PromoteWaitlistEntry)
+			adminProtected.POST("/waitlist/:id/cancel", manageWaitlist, h.CancelWaitlistEntry)
+
+			// Artists
+			adminProtected.GET("/artists", manageCatalog, h.GetArtists)
+			adminProtected.POST("/artists", manageCatalog, h.CreateArtist)
+			adminProtected.GET("/artists/:id", manageCatalog, h.GetArtist)
+			adminProtected.PUT("/artists/:id", manageCatalog, h.UpdateArtist)
+			adminProtected.DELETE("/artists/:id", manageCatalog, h.DeleteArtist)
+
+			// Scheduled jobs
+			adminProtected.GET("/jobs", notifications, h.GetJobs)
+
+			// Notifications
+			adminProtected.GET("/notifications", notifications, h.GetNotifications)
+			adminProtected.GET("/notification-preferences/:email", notifications, h.GetNotificationPreference)
+			adminProtected.PUT("/notification-preferences/:email", notifications, h.UpdateNotificationPreference)
+
+			// Webhooks
+			adminProtected.GET("/webhooks", manageWebhooks, h.GetWebhooks)
+			adminProtected.POST("/webhooks", manageWebhooks, h.CreateWebhook)
+			adminProtected.GET("/webhooks/:id", manageWebhooks, h.GetWebhook)
+			adminProtected.PUT("/webhooks/:id", manageWebhooks, h.UpdateWebhook)
+			adminProtected.DELETE("/webhooks/:id", manageWebhooks, h.DeleteWebhook)
+			adminProtected.GET("/webhook-deliveries", manageWebhooks, h.GetWebhookDeliveries)
+			adminProtected.GET("/webhook-deliveries/failed", manageWebhooks, h.GetWebhookDeadLetters)
+			adminProtected.GET("/webhook-deliveries/:id", manageWebhooks, h.GetWebhookDelivery)
+			adminProtected.POST("/webhook-deliveries/:id/retry", manageWebhooks, h.RetryWebhookDelivery)
+
+			// Admin accounts
+			adminProtected.GET("/admins", manageAdmins, h.GetAdmins)
+			adminProtected.PUT("/admins/:id/role", manageAdmins, h.UpdateAdminRole)
+			adminProtected.POST("/admins/:id/unlock", manageAdmins, h.UnlockAdmin)
+			adminProtected.DELETE("/admins/:id/2fa", manageAdmins, h.ResetAdminTwoFactor)
+			adminProtected.GET("/security", manageAdmins, h.GetSecuritySettings)
+			adminProtected.PUT("/security", manageAdmins, h.UpdateSecuritySettings)
+			adminProtected.GET("/login-attempts", manageAdmins, h.GetLoginAttempts)
+			adminProtected.GET("/invites", manageAdmins, h.GetAdminInvites)
+			adminProtected.POST("/invites", manageAdmins, h.CreateAdminInvite)
+			adminProtected.DELETE("/invites/:id", manageAdmins, h.RevokeAdminInvite)
 		}
 	}
 
-	// Start server
-	port := cfg.Port
-	if port == "" {
-		port = "8080"
+	return r, nil
+}
+
+// runMigrate implements "migrate up|down [n]|status" against DATABASE_URL.
+func runMigrate(cfg *config.Config, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: migrate up|down [n]|status")
 	}
 
-	log.Printf("Server starting on port %s", port)
-	log.Printf("Health check: http://localhost:%s/health", port)
-	log.Printf("API endpoints:")
-	log.Printf("  POST /api/appointments - Book appointment")
-	log.Printf("  POST /api/classes - Enroll in class")
-	log.Printf("  POST /api/admin/signup - Admin signup")
-	log.Printf("  POST /api/admin/login - Admin login")
-	log.Printf("  GET /api/admin/appointments - Get appointments (requires auth)")
-	log.Printf("  GET /api/admin/classes - Get classes (requires auth)")
-	log.Printf("  PUT /api/admin/appointments/:id/status - Update appointment status (requires auth)")
-	log.Printf("  PUT /api/admin/classes/:id/status - Update class status (requires auth)")
+	ctx := context.Background()
+	migrator, err := database.NewMigrator(ctx, cfg.DatabaseURL)
+	if err != nil {
+		return err
+	}
+	defer migrator.Close(ctx)
 
-	if err := r.Run(":" + port); err != nil {
-		log.Fatalf("Failed to start server: %v", err)
+	switch args[0] {
+	case "up":
+		scheduler, err := scheduling.NewEngine(cfg)
+		if err != nil {
+			return fmt.Errorf("invalid scheduling configuration: %w", err)
+		}
+		applied, err := migrator.Up(ctx)
+		for _, m := range applied {
+			log.Printf("Applied %04d_%s", m.Version, m.Name)
+		}
+		if err != nil {
+			return err
+		}
+		if len(applied) == 0 {
+			log.Println("Database is up to date")
+		}
+
+		// Appointments booked before starts_at was stored get one in the
+		// studio's time zone
+		backfill, err := migrator.BackfillStartTimes(ctx, scheduler.StartTime)
+		if err != nil {
+			return err
+		}
+		if backfill.Updated > 0 {
+			log.Printf("Set the start time of %d appointments in %s", backfill.Updated, scheduler.Location)
+		}
+		if len(backfill.Invalid) > 0 {
+			log.Printf("%d appointments have no clock time and were left without a start time, ids %v", len(backfill.Invalid), backfill.Invalid)
+		}
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps < 1 {
+				return fmt.Errorf("invalid number of steps: %s", args[1])
+			}
+		}
+		reverted, err := migrator.Down(ctx, steps)
+		for _, m := range reverted {
+			log.Printf("Reverted %04d_%s", m.Version, m.Name)
+		}
+		if err != nil {
+			return err
+		}
+		if len(reverted) == 0 {
+			log.Println("No migrations to revert")
+		}
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		for _, s := range statuses {
+			applied := "pending"
+			if s.AppliedAt != nil {
+				applied = "applied " + s.AppliedAt.Format("2006-01-02 15:04:05 MST")
+			}
+			fmt.Printf("%04d_%-40s %s\n", s.Version, s.Name, applied)
+		}
+	default:
+		return fmt.Errorf("unknown migrate command %q: expected up, down or status", args[0])
+	}
+
+	return nil
+}
+
+// runNormalizePhones implements "normalize-phones [--dry-run]", rewriting the
+// phone numbers stored at DATABASE_URL in E.164.
+func runNormalizePhones(cfg *config.Config, args []string) error {
+	dryRun := false
+	for _, arg := range args {
+		if arg != "--dry-run" {
+			return fmt.Errorf("usage: normalize-phones [--dry-run]")
+		}
+		dryRun = true
+	}
+
+	phones, err := phone.NewParser(cfg.DefaultPhoneCountry)
+	if err != nil {
+		return fmt.Errorf("invalid DEFAULT_PHONE_COUNTRY: %w", err)
+	}
+
+	reports, err := database.NormalizePhones(context.Background(), cfg.DatabaseURL, phones.Normalize, dryRun)
+	for _, r := range reports {
+		verb := "updated"
+		if dryRun {
+			verb = "would update"
+		}
+		log.Printf("%s: checked %d, %s %d", r.Table, r.Checked, verb, r.Updated)
+		if len(r.Invalid) > 0 {
+			log.Printf("%s: %d invalid numbers left as they were, ids %v", r.Table, len(r.Invalid), r.Invalid)
+		}
 	}
+	return err
 }
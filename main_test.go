@@ -0,0 +1,239 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"mumuni_backend/auth"
+	"mumuni_backend/config"
+	"mumuni_backend/database"
+	"mumuni_backend/handlers"
+	"mumuni_backend/models"
+	"mumuni_backend/notify"
+	"mumuni_backend/phone"
+	"mumuni_backend/ratelimit"
+	"mumuni_backend/scheduling"
+	"mumuni_backend/spam"
+	"mumuni_backend/webhooks"
+
+	"github.com/gin-gonic/gin"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// newTestRouter returns the API router over a fresh memory store,
+// configured with the defaults and then by configure.
+func newTestRouter(t *testing.T, configure func(*config.Config)) (*gin.Engine, *database.MemoryStore) {
+	t.Helper()
+
+	cfg := config.LoadConfig()
+	cfg.DatabaseDriver = "memory"
+	configure(cfg)
+
+	store := database.NewMemoryStore()
+	scheduler, err := scheduling.NewEngine(cfg)
+	if err != nil {
+		t.Fatalf("NewEngine: %v", err)
+	}
+	notifier, err := notify.NewNotifier(cfg, store)
+	if err != nil {
+		t.Fatalf("NewNotifier: %v", err)
+	}
+	guard, err := spam.NewGuard(cfg, ratelimit.NewMemoryStore())
+	if err != nil {
+		t.Fatalf("NewGuard: %v", err)
+	}
+	phones, err := phone.NewParser(cfg.DefaultPhoneCountry)
+	if err != nil {
+		t.Fatalf("NewParser: %v", err)
+	}
+	h := handlers.NewHandlers(cfg, store, scheduler, notifier, webhooks.NewPublisher(store), guard, phones)
+
+	r, err := newRouter(cfg, store, h)
+	if err != nil {
+		t.Fatalf("newRouter: %v", err)
+	}
+	return r, store
+}
+
+// post sends body to path from remoteAddr, claiming to be forwarded for
+// forwardedFor if it is set.
+func post(r http.Handler, path, body, remoteAddr, forwardedFor string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.RemoteAddr = remoteAddr
+	if forwardedFor != "" {
+		req.Header.Set("X-Forwarded-For", forwardedFor)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func failedLogin(i int) string {
+	return `{"email": "nobody` + strconv.Itoa(i) + `@example.com", "password": "wrong password"}`
+}
+
+func TestForgedForwardedForDoesNotResetIPLockout(t *testing.T) {
+	r, store := newTestRouter(t, func(cfg *config.Config) {
+		cfg.LoginIPMaxFailures = 3
+	})
+
+	// Each attempt names a fresh email and claims a fresh address, but they
+	// all come from the same client
+	for i := 0; i < 3; i++ {
+		forged := "198.51.100." + strconv.Itoa(i+1)
+		if w := post(r, "/api/admin/login", failedLogin(i), "203.0.113.7:4321", forged); w.Code != http.StatusUnauthorized {
+			t.Fatalf("failed login %d: got %d %s, want 401", i+1, w.Code, w.Body)
+		}
+	}
+	if w := post(r, "/api/admin/login", failedLogin(3), "203.0.113.7:4321", "198.51.100.99"); w.Code != http.StatusTooManyRequests {
+		t.Fatalf("login after the IP's failures: got %d %s, want 429", w.Code, w.Body)
+	}
+
+	attempts, _, err := store.GetLoginAttempts(context.Background(), database.LoginAttemptFilter{}, database.Page{})
+	if err != nil {
+		t.Fatalf("GetLoginAttempts: %v", err)
+	}
+	for _, attempt := range attempts {
+		if attempt.IPAddress != "203.0.113.7" {
+			t.Errorf("attempt for %s logged from %q, want the connecting address", attempt.Email, attempt.IPAddress)
+		}
+	}
+}
+
+func TestTrustedProxyForwardsClientAddress(t *testing.T) {
+	r, store := newTestRouter(t, func(cfg *config.Config) {
+		cfg.TrustedProxies = []string{"10.0.0.0/8"}
+		cfg.LoginIPMaxFailures = 3
+	})
+
+	// Clients behind the proxy are counted separately
+	for i := 0; i < 3; i++ {
+		client := "198.51.100." + strconv.Itoa(i+1)
+		if w := post(r, "/api/admin/login", failedLogin(i), "10.0.0.2:4321", client); w.Code != http.StatusUnauthorized {
+			t.Fatalf("failed login %d: got %d %s, want 401", i+1, w.Code, w.Body)
+		}
+	}
+
+	attempts, _, err := store.GetLoginAttempts(context.Background(), database.LoginAttemptFilter{IPAddress: "198.51.100.2"}, database.Page{})
+	if err != nil {
+		t.Fatalf("GetLoginAttempts: %v", err)
+	}
+	if len(attempts) != 1 {
+		t.Errorf("got %d attempts from 198.51.100.2, want the one forwarded for it", len(attempts))
+	}
+}
+
+func TestNewRouterRejectsInvalidTrustedProxies(t *testing.T) {
+	cfg := config.LoadConfig()
+	cfg.TrustedProxies = []string{"not-an-address"}
+	if _, err := newRouter(cfg, database.NewMemoryStore(), nil); err == nil {
+		t.Error("newRouter accepted an invalid trusted proxy")
+	}
+}
+
+func TestForgedForwardedForDoesNotBypassBookingLimit(t *testing.T) {
+	r, _ := newTestRouter(t, func(cfg *config.Config) {
+		cfg.BookingIPLimit = "2/1h"
+		cfg.BookingContactLimit = "0"
+	})
+
+	date := time.Now().AddDate(0, 0, 14)
+	for date.Weekday() == time.Saturday || date.Weekday() == time.Sunday {
+		date = date.AddDate(0, 0, 1)
+	}
+	booking := func(i int, clock string) string {
+		return `{"name": "Customer", "email": "customer` + strconv.Itoa(i) + `@example.com", "phone": "0803 111 222` + strconv.Itoa(i) +
+			`", "date": "` + date.Format(scheduling.DateLayout) + `", "time": "` + clock + `", "service": "Everyday Glam"}`
+	}
+
+	for i, clock := range []string{"10:00 AM", "12:00 PM"} {
+		forged := "198.51.100." + strconv.Itoa(i+1)
+		if w := post(r, "/api/appointments", booking(i, clock), "203.0.113.7:4321", forged); w.Code != http.StatusCreated {
+			t.Fatalf("booking %d: got %d %s, want 201", i+1, w.Code, w.Body)
+		}
+	}
+	if w := post(r, "/api/appointments", booking(2, "2:00 PM"), "203.0.113.7:4321", "198.51.100.99"); w.Code != http.StatusTooManyRequests {
+		t.Fatalf("booking over the IP limit: got %d %s, want 429", w.Code, w.Body)
+	}
+}
+
+// signIn starts a session in store for an admin with role, linked to
+// artistID if it isn't nil, and returns its access token.
+func signIn(t *testing.T, store database.Store, role string, artistID *int) string {
+	t.Helper()
+	access := database.AccessToken{JTI: "token-" + role, ExpiresAt: time.Now().Add(time.Hour)}
+	session, err := store.CreateAdminSession(context.Background(), &models.AdminSession{
+		AdminID:   "admin-" + role,
+		ExpiresAt: time.Now().Add(24 * time.Hour),
+	}, "refresh-"+role, access)
+	if err != nil {
+		t.Fatalf("CreateAdminSession: %v", err)
+	}
+	token, err := auth.GenerateToken(session.AdminID, role+"@example.com", role, artistID, session.ID, access.JTI, false, access.ExpiresAt)
+	if err != nil {
+		t.Fatalf("GenerateToken: %v", err)
+	}
+	return token
+}
+
+func TestAdminRoutesPerRole(t *testing.T) {
+	r, store := newTestRouter(t, func(cfg *config.Config) {})
+	auth.SetJWTSecret("main-test-secret")
+
+	artistID := 1
+	tokens := map[string]string{}
+	for _, role := range auth.Roles {
+		var artist *int
+		if role == auth.RoleArtist {
+			artist = &artistID
+		}
+		tokens[role] = signIn(t, store, role, artist)
+	}
+
+	// The roles allowed each route; every other role gets 403 Forbidden
+	frontDesk := []string{auth.RoleOwner, auth.RoleManager, auth.RoleReceptionist}
+	tests := []struct {
+		path    string
+		allowed []string
+	}{
+		{"/api/admin/appointments", []string{auth.RoleOwner, auth.RoleManager, auth.RoleReceptionist, auth.RoleArtist}},
+		{"/api/admin/classes", []string{auth.RoleOwner, auth.RoleManager, auth.RoleReceptionist, auth.RoleInstructor}},
+		{"/api/admin/customers", frontDesk},
+		{"/api/admin/waitlist", frontDesk},
+		{"/api/admin/notifications", frontDesk},
+		{"/api/admin/services", []string{auth.RoleOwner, auth.RoleManager}},
+		{"/api/admin/artists", []string{auth.RoleOwner, auth.RoleManager}},
+		{"/api/admin/cohorts", []string{auth.RoleOwner, auth.RoleManager, auth.RoleInstructor}},
+		{"/api/admin/webhooks", []string{auth.RoleOwner}},
+		{"/api/admin/admins", []string{auth.RoleOwner}},
+		{"/api/admin/login-attempts", []string{auth.RoleOwner}},
+	}
+	for _, tt := range tests {
+		allowed := map[string]bool{}
+		for _, role := range tt.allowed {
+			allowed[role] = true
+		}
+		for _, role := range auth.Roles {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			req.Header.Set("Authorization", "Bearer "+tokens[role])
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			switch {
+			case allowed[role] && w.Code != http.StatusOK:
+				t.Errorf("%s as %s: got %d %s, want 200", tt.path, role, w.Code, w.Body)
+			case !allowed[role] && w.Code != http.StatusForbidden:
+				t.Errorf("%s as %s: got %d, want 403", tt.path, role, w.Code)
+			}
+		}
+	}
+}
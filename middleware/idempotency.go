@@ -0,0 +1,157 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"mumuni_backend/database"
+	"mumuni_backend/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxIdempotencyKeyLength is the longest Idempotency-Key accepted.
+const maxIdempotencyKeyLength = 255
+
+// secretResponseFields are response fields holding tokens that are only
+// ever stored hashed. They are left out of kept responses, so replays don't
+// have them.
+var secretResponseFields = []string{"manage_token", "waitlist_token"}
+
+// Idempotency makes retries of a request with the same Idempotency-Key
+// header safe. The first request with a key runs as usual and its response
+// is kept in store for ttl, answering retries without running them again.
+// A retry with a different payload, or one that arrives while the first is
+// still running, is refused with 409 Conflict. Keys are scoped to the route
+// and, on admin routes, to the signed-in admin. Server errors and 429 Too
+// Many Requests aren't kept, so the request can be tried again. Tokens in
+// secretResponseFields aren't kept either.
+func Idempotency(store database.Store, ttl time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader("Idempotency-Key")
+		if key == "" {
+			c.Next()
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error: "Idempotency-Key must be at most 255 characters",
+			})
+			c.Abort()
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error: "Failed to read request body",
+			})
+			c.Abort()
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		sum := sha256.Sum256([]byte(c.Request.Method + " " + c.Request.URL.Path + "\n" + string(body)))
+		hash := hex.EncodeToString(sum[:])
+		scope := c.Request.Method + " " + c.FullPath()
+		if adminID := c.GetString("admin_id"); adminID != "" {
+			scope += " " + adminID
+		}
+		now := time.Now().UTC()
+		record, claimed, err := store.ClaimIdempotencyKey(c.Request.Context(), &models.IdempotencyKey{
+			Scope:       scope,
+			Key:         key,
+			RequestHash: hash,
+			ExpiresAt:   now.Add(ttl),
+		}, now)
+		if err != nil {
+			log.Printf("Error claiming idempotency key: %v", err)
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+				Error: "Failed to process request",
+			})
+			c.Abort()
+			return
+		}
+
+		if !claimed {
+			switch {
+			case record.RequestHash != hash:
+				c.JSON(http.StatusConflict, models.ErrorResponse{
+					Error: "Idempotency-Key was already used for a different request",
+				})
+			case record.StatusCode == 0:
+				c.JSON(http.StatusConflict, models.ErrorResponse{
+					Error: "A request with this Idempotency-Key is still in progress",
+				})
+			default:
+				c.Header("Idempotent-Replayed", "true")
+				c.Data(record.StatusCode, "application/json; charset=utf-8", []byte(record.ResponseBody))
+			}
+			c.Abort()
+			return
+		}
+
+		writer := &recordingWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+		c.Next()
+
+		// Finish with a context of our own, so a client that hung up doesn't
+		// leave the key claimed
+		ctx := context.WithoutCancel(c.Request.Context())
+		if status := writer.Status(); status >= http.StatusInternalServerError || status == http.StatusTooManyRequests {
+			err = store.ReleaseIdempotencyKey(ctx, record.ID)
+		} else {
+			err = store.CompleteIdempotencyKey(ctx, record.ID, status, withoutSecrets(writer.body.Bytes()))
+		}
+		if err != nil {
+			log.Printf("Error saving idempotency key %d: %v", record.ID, err)
+		}
+	}
+}
+
+// withoutSecrets returns the JSON response body with secretResponseFields
+// removed.
+func withoutSecrets(body []byte) string {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return string(body)
+	}
+	removed := false
+	for _, field := range secretResponseFields {
+		if _, ok := fields[field]; ok {
+			delete(fields, field)
+			removed = true
+		}
+	}
+	if !removed {
+		return string(body)
+	}
+	stripped, err := json.Marshal(fields)
+	if err != nil {
+		return string(body)
+	}
+	return string(stripped)
+}
+
+// recordingWriter keeps a copy of the response body it writes.
+type recordingWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *recordingWriter) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *recordingWriter) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
@@ -0,0 +1,74 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"mumuni_backend/database"
+	"mumuni_backend/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestIdempotencyDoesNotKeepTokens(t *testing.T) {
+	store := database.NewMemoryStore()
+	r := gin.New()
+	bookings := 0
+	r.POST("/api/appointments", Idempotency(store, time.Hour), func(c *gin.Context) {
+		bookings++
+		c.JSON(http.StatusCreated, gin.H{
+			"success":        true,
+			"appointment":    gin.H{"id": 12},
+			"manage_token":   "secret-manage-token",
+			"waitlist_token": "secret-waitlist-token",
+		})
+	})
+	book := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/appointments", strings.NewReader(`{"name": "Ada"}`))
+		req.Header.Set("Idempotency-Key", "booking-1")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := book(); w.Code != http.StatusCreated || !strings.Contains(w.Body.String(), "secret-manage-token") {
+		t.Fatalf("first request: got %d %s, want 201 with the manage token", w.Code, w.Body)
+	}
+
+	// The stored row is handed back when the key is claimed again
+	now := time.Now().UTC()
+	record, claimed, err := store.ClaimIdempotencyKey(context.Background(), &models.IdempotencyKey{
+		Scope:     "POST /api/appointments",
+		Key:       "booking-1",
+		ExpiresAt: now.Add(time.Hour),
+	}, now)
+	if err != nil || claimed {
+		t.Fatalf("ClaimIdempotencyKey: claimed %t, %v; want the stored key", claimed, err)
+	}
+	if strings.Contains(record.ResponseBody, "secret-") || strings.Contains(record.ResponseBody, "_token") {
+		t.Errorf("stored response %s holds a token", record.ResponseBody)
+	}
+	if !strings.Contains(record.ResponseBody, `"appointment":{"id":12}`) {
+		t.Errorf("stored response %s, want the rest of the body kept", record.ResponseBody)
+	}
+
+	w := book()
+	if w.Code != http.StatusCreated || w.Header().Get("Idempotent-Replayed") != "true" || bookings != 1 {
+		t.Fatalf("retry: got %d after %d bookings, want the stored 201 replayed", w.Code, bookings)
+	}
+	if strings.Contains(w.Body.String(), "secret-") {
+		t.Errorf("replay %s holds a token", w.Body)
+	}
+}
+
+func TestWithoutSecretsKeepsOtherBodies(t *testing.T) {
+	for _, body := range []string{`{"success":true,"message":"ok"}`, `not json`, `[1,2]`} {
+		if got := withoutSecrets([]byte(body)); got != body {
+			t.Errorf("withoutSecrets(%s) = %s, want it unchanged", body, got)
+		}
+	}
+}
@@ -1,17 +1,20 @@
 package middleware
 
 import (
+	"log"
 	"net/http"
 	"strings"
 
 	"mumuni_backend/auth"
+	"mumuni_backend/database"
 	"mumuni_backend/models"
 
 	"github.com/gin-gonic/gin"
 )
 
-// AuthMiddleware validates JWT tokens for admin routes
-func AuthMiddleware() gin.HandlerFunc {
+// AuthMiddleware validates JWT tokens for admin routes, refusing tokens
+// revoked in store by logout or an ended session.
+func AuthMiddleware(store database.Store) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
@@ -36,7 +39,7 @@ func AuthMiddleware() gin.HandlerFunc {
 
 		// Validate the token
 		claims, err := auth.ValidateToken(token)
-		if err != nil {
+		if err != nil || claims.ID == "" || claims.SessionID == "" {
 			c.JSON(http.StatusUnauthorized, models.ErrorResponse{
 				Error: "Invalid or expired token",
 			})
@@ -44,9 +47,65 @@ func AuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
+		revoked, err := store.IsTokenRevoked(c.Request.Context(), claims.ID)
+		if err != nil {
+			log.Printf("Error checking revoked token: %v", err)
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+				Error: "Failed to check authentication token",
+			})
+			c.Abort()
+			return
+		}
+		if revoked {
+			c.JSON(http.StatusUnauthorized, models.ErrorResponse{
+				Error: "Token has been revoked",
+			})
+			c.Abort()
+			return
+		}
+
 		// Set admin info in context
 		c.Set("admin_id", claims.AdminID)
 		c.Set("admin_email", claims.Email)
+		c.Set("admin_role", claims.Role)
+		c.Set("admin_session_id", claims.SessionID)
+		c.Set("admin_setup_only", claims.SetupOnly)
+		if claims.ArtistID != nil {
+			c.Set("admin_artist_id", *claims.ArtistID)
+		}
+
+		c.Next()
+	}
+}
+
+// RequirePermission lets through admins whose role has perm and refuses the
+// rest with 403 Forbidden. It must run after AuthMiddleware.
+func RequirePermission(perm auth.Permission) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !auth.HasPermission(c.GetString("admin_role"), perm) {
+			c.JSON(http.StatusForbidden, models.ErrorResponse{
+				Error: "You don't have permission to do this",
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// RequireTwoFactorSetup refuses admins who still have to set up two-factor
+// authentication, as the security settings require, with 403 Forbidden. It
+// must run after AuthMiddleware.
+func RequireTwoFactorSetup() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetBool("admin_setup_only") {
+			c.JSON(http.StatusForbidden, models.ErrorResponse{
+				Error: "Set up two-factor authentication to continue",
+			})
+			c.Abort()
+			return
+		}
 
 		c.Next()
 	}
@@ -57,7 +116,7 @@ func CORSMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		c.Header("Access-Control-Allow-Origin", "*")
 		c.Header("Access-Control-Allow-Credentials", "true")
-		c.Header("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With")
+		c.Header("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With, X-Verification-Token, Idempotency-Key")
 		c.Header("Access-Control-Allow-Methods", "POST, OPTIONS, GET, PUT, DELETE")
 
 		if c.Request.Method == "OPTIONS" {
@@ -0,0 +1,184 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"mumuni_backend/auth"
+	"mumuni_backend/database"
+	"mumuni_backend/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+	auth.SetJWTSecret("middleware-test-secret")
+}
+
+// signIn starts a session in store for an admin with role and returns its
+// access token.
+func signIn(t *testing.T, store database.Store, role string, artistID *int, setupOnly bool) string {
+	t.Helper()
+	access := database.AccessToken{JTI: "token-" + role, ExpiresAt: time.Now().Add(time.Hour)}
+	session, err := store.CreateAdminSession(context.Background(), &models.AdminSession{
+		AdminID:   "admin-" + role,
+		ExpiresAt: time.Now().Add(24 * time.Hour),
+	}, "refresh-"+role, access)
+	if err != nil {
+		t.Fatalf("CreateAdminSession: %v", err)
+	}
+	token, err := auth.GenerateToken(session.AdminID, role+"@example.com", role, artistID, session.ID, access.JTI, setupOnly, access.ExpiresAt)
+	if err != nil {
+		t.Fatalf("GenerateToken: %v", err)
+	}
+	return token
+}
+
+func get(r http.Handler, path, token string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestRequirePermissionPerRole(t *testing.T) {
+	store := database.NewMemoryStore()
+	r := gin.New()
+	r.Use(AuthMiddleware(store))
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	r.GET("/appointments", RequirePermission(auth.PermViewAppointments), ok)
+	r.GET("/classes", RequirePermission(auth.PermManageClasses), ok)
+	r.GET("/customers", RequirePermission(auth.PermViewCustomers), ok)
+	r.GET("/catalog", RequirePermission(auth.PermManageCatalog), ok)
+	r.GET("/cohorts", RequirePermission(auth.PermManageCohorts), ok)
+	r.GET("/notifications", RequirePermission(auth.PermNotifications), ok)
+	r.GET("/webhooks", RequirePermission(auth.PermManageWebhooks), ok)
+	r.GET("/admins", RequirePermission(auth.PermManageAdmins), ok)
+
+	artistID := 2
+	tokens := map[string]string{}
+	for _, role := range auth.Roles {
+		var artist *int
+		if role == auth.RoleArtist {
+			artist = &artistID
+		}
+		tokens[role] = signIn(t, store, role, artist, false)
+	}
+
+	const allowed, forbidden = http.StatusOK, http.StatusForbidden
+	tests := []struct {
+		path                                             string
+		owner, manager, receptionist, artist, instructor int
+	}{
+		{"/appointments", allowed, allowed, allowed, allowed, forbidden},
+		{"/classes", allowed, allowed, allowed, forbidden, allowed},
+		{"/customers", allowed, allowed, allowed, forbidden, forbidden},
+		{"/catalog", allowed, allowed, forbidden, forbidden, forbidden},
+		{"/cohorts", allowed, allowed, forbidden, forbidden, allowed},
+		{"/notifications", allowed, allowed, allowed, forbidden, forbidden},
+		{"/webhooks", allowed, forbidden, forbidden, forbidden, forbidden},
+		{"/admins", allowed, forbidden, forbidden, forbidden, forbidden},
+	}
+	for _, tt := range tests {
+		want := map[string]int{
+			auth.RoleOwner:        tt.owner,
+			auth.RoleManager:      tt.manager,
+			auth.RoleReceptionist: tt.receptionist,
+			auth.RoleArtist:       tt.artist,
+			auth.RoleInstructor:   tt.instructor,
+		}
+		for role, code := range want {
+			if w := get(r, tt.path, tokens[role]); w.Code != code {
+				t.Errorf("%s as %s: got %d, want %d", tt.path, role, w.Code, code)
+			}
+		}
+	}
+}
+
+func TestAuthMiddlewareSetsClaims(t *testing.T) {
+	store := database.NewMemoryStore()
+	artistID := 7
+	token := signIn(t, store, auth.RoleArtist, &artistID, false)
+
+	var role string
+	var artist any
+	r := gin.New()
+	r.GET("/me", AuthMiddleware(store), func(c *gin.Context) {
+		role = c.GetString("admin_role")
+		artist, _ = c.Get("admin_artist_id")
+	})
+	if w := get(r, "/me", token); w.Code != http.StatusOK {
+		t.Fatalf("got %d %s, want 200", w.Code, w.Body)
+	}
+	if role != auth.RoleArtist || artist != artistID {
+		t.Errorf("role %q, artist %v; want artist linked to %d", role, artist, artistID)
+	}
+}
+
+func TestAuthMiddlewareRefusesTokens(t *testing.T) {
+	store := database.NewMemoryStore()
+	r := gin.New()
+	r.GET("/me", AuthMiddleware(store), RequireTwoFactorSetup(), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	revoked := signIn(t, store, auth.RoleOwner, nil, false)
+	if err := store.RevokeAdminSessions(context.Background(), "admin-"+auth.RoleOwner, ""); err != nil {
+		t.Fatalf("RevokeAdminSessions: %v", err)
+	}
+	expired, err := auth.GenerateToken("admin-1", "owner@example.com", auth.RoleOwner, nil, "session-1", "token-1", false, time.Now().Add(-time.Minute))
+	if err != nil {
+		t.Fatalf("GenerateToken: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		token string
+		want  int
+	}{
+		{"no token", "", http.StatusUnauthorized},
+		{"malformed", "not-a-token", http.StatusUnauthorized},
+		{"expired", expired, http.StatusUnauthorized},
+		{"revoked", revoked, http.StatusUnauthorized},
+		{"two-factor setup only", signIn(t, store, auth.RoleManager, nil, true), http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		if w := get(r, "/me", tt.token); w.Code != tt.want {
+			t.Errorf("%s: got %d, want %d", tt.name, w.Code, tt.want)
+		}
+	}
+}
+
+func TestAuthMiddlewareRefusesReplacedToken(t *testing.T) {
+	store := database.NewMemoryStore()
+	r := gin.New()
+	r.GET("/me", AuthMiddleware(store), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	// Refreshing keeps the session but revokes the old token's jti
+	old := signIn(t, store, auth.RoleOwner, nil, false)
+	access := database.AccessToken{JTI: "token-refreshed", ExpiresAt: time.Now().Add(time.Hour)}
+	session, err := store.RotateAdminSession(context.Background(), "refresh-"+auth.RoleOwner, "refresh-next", access, time.Now().Add(24*time.Hour))
+	if err != nil {
+		t.Fatalf("RotateAdminSession: %v", err)
+	}
+	refreshed, err := auth.GenerateToken(session.AdminID, "owner@example.com", auth.RoleOwner, nil, session.ID, access.JTI, false, access.ExpiresAt)
+	if err != nil {
+		t.Fatalf("GenerateToken: %v", err)
+	}
+
+	if w := get(r, "/me", old); w.Code != http.StatusUnauthorized {
+		t.Errorf("replaced token: got %d, want 401", w.Code)
+	}
+	if w := get(r, "/me", refreshed); w.Code != http.StatusOK {
+		t.Errorf("new token: got %d %s, want 200", w.Code, w.Body)
+	}
+}
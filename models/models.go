@@ -1,55 +1,90 @@
 package models
 
 import (
+	"encoding/json"
 	"time"
 )
 
-// Appointment represents a makeup appointment booking
+// Appointment represents a makeup appointment booking. StartsAt is when it
+// starts, as an RFC 3339 timestamp, and is what API clients should read;
+// AppointmentDate and AppointmentTime are the studio-local date and clock
+// time it was booked for, kept in responses for display but deprecated for
+// anything else. Channels are the notification channels chosen for it; when
+// empty the customer's preference is used.
 type Appointment struct {
-	ID              int       `json:"id" db:"id"`
-	Name            string    `json:"name" db:"name"`
-	Email           string    `json:"email" db:"email"`
-	Phone           string    `json:"phone" db:"phone"`
-	AppointmentDate string    `json:"appointment_date" db:"appointment_date"`
-	AppointmentTime string    `json:"appointment_time" db:"appointment_time"`
-	Service         string    `json:"service" db:"service"`
-	Message         *string   `json:"message" db:"message"`
-	Status          string    `json:"status" db:"status"`
-	CreatedAt       time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt       time.Time `json:"updated_at" db:"updated_at"`
+	ID                 int        `json:"id" db:"id"`
+	Name               string     `json:"name" db:"name"`
+	Email              string     `json:"email" db:"email"`
+	Phone              string     `json:"phone" db:"phone"`
+	AppointmentDate    string     `json:"appointment_date" db:"appointment_date"`
+	AppointmentTime    string     `json:"appointment_time" db:"appointment_time"`
+	StartsAt           *time.Time `json:"starts_at" db:"starts_at"`
+	Service            string     `json:"service" db:"service"`
+	Message            *string    `json:"message" db:"message"`
+	ArtistID           *int       `json:"artist_id" db:"artist_id"`
+	CustomerID         *int       `json:"customer_id" db:"customer_id"`
+	Channels           []string   `json:"channels" db:"channels"`
+	Status             string     `json:"status" db:"status"`
+	ConfirmedAt        *time.Time `json:"confirmed_at" db:"confirmed_at"`
+	CancelledAt        *time.Time `json:"cancelled_at" db:"cancelled_at"`
+	CompletedAt        *time.Time `json:"completed_at" db:"completed_at"`
+	CancellationReason *string    `json:"cancellation_reason" db:"cancellation_reason"`
+	CreatedAt          time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt          time.Time  `json:"updated_at" db:"updated_at"`
 }
 
 // AppointmentRequest represents the request payload for booking an appointment
 type AppointmentRequest struct {
-	Name    string  `json:"name" binding:"required"`
-	Email   string  `json:"email" binding:"required,email"`
-	Phone   string  `json:"phone" binding:"required"`
-	Date    string  `json:"date" binding:"required"`
-	Time    string  `json:"time" binding:"required"`
-	Service string  `json:"service" binding:"required"`
-	Message *string `json:"message"`
+	Name     string  `json:"name" binding:"required"`
+	Email    string  `json:"email" binding:"required,email"`
+	Phone    string  `json:"phone" binding:"required"`
+	Date     string  `json:"date" binding:"required"`
+	Time     string  `json:"time" binding:"required"`
+	Service  string  `json:"service" binding:"required"`
+	Message  *string `json:"message"`
+	ArtistID *int    `json:"artist_id"`
+	// StartsAt is when Date and Time start in the studio's time zone, set
+	// once they have been checked.
+	StartsAt time.Time `json:"-"`
+	// Channels, when set, are the notification channels for this booking
+	// only; the customer's stored preference is left alone.
+	Channels []string `json:"channels" binding:"omitempty,dive,oneof=email sms whatsapp"`
+	// Website is a honeypot: the booking form hides it, so only bots fill
+	// it in.
+	Website string `json:"website,omitempty"`
 }
 
 // AppointmentResponse represents the response for appointment booking
+// ManageToken is the customer's key to the /api/manage/appointments/:token
+// endpoints. It is only ever returned here and in the booking email.
 type AppointmentResponse struct {
 	Success     bool        `json:"success"`
 	Appointment Appointment `json:"appointment"`
 	Message     string      `json:"message"`
+	ManageToken string      `json:"manage_token,omitempty"`
 }
 
-// Class represents a makeup class enrollment
+// Class represents a makeup class enrollment. Channels work as in
+// Appointment.
 type Class struct {
-	ID                int       `json:"id" db:"id"`
-	Name              string    `json:"name" db:"name"`
-	Email             string    `json:"email" db:"email"`
-	Phone             string    `json:"phone" db:"phone"`
-	ClassType         string    `json:"class_type" db:"class_type"`
-	ExperienceLevel   string    `json:"experience_level" db:"experience_level"`
-	Goals             *string   `json:"goals" db:"goals"`
-	PreferredSchedule string    `json:"preferred_schedule" db:"preferred_schedule"`
-	Status            string    `json:"status" db:"status"`
-	CreatedAt         time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt         time.Time `json:"updated_at" db:"updated_at"`
+	ID                 int        `json:"id" db:"id"`
+	Name               string     `json:"name" db:"name"`
+	Email              string     `json:"email" db:"email"`
+	Phone              string     `json:"phone" db:"phone"`
+	ClassType          string     `json:"class_type" db:"class_type"`
+	ExperienceLevel    string     `json:"experience_level" db:"experience_level"`
+	Goals              *string    `json:"goals" db:"goals"`
+	PreferredSchedule  string     `json:"preferred_schedule" db:"preferred_schedule"`
+	CohortID           *int       `json:"cohort_id" db:"cohort_id"`
+	CustomerID         *int       `json:"customer_id" db:"customer_id"`
+	Channels           []string   `json:"channels" db:"channels"`
+	Status             string     `json:"status" db:"status"`
+	ConfirmedAt        *time.Time `json:"confirmed_at" db:"confirmed_at"`
+	CancelledAt        *time.Time `json:"cancelled_at" db:"cancelled_at"`
+	CompletedAt        *time.Time `json:"completed_at" db:"completed_at"`
+	CancellationReason *string    `json:"cancellation_reason" db:"cancellation_reason"`
+	CreatedAt          time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt          time.Time  `json:"updated_at" db:"updated_at"`
 }
 
 // ClassRequest represents the request payload for class enrollment
@@ -57,33 +92,395 @@ type ClassRequest struct {
 	Name       string  `json:"name" binding:"required"`
 	Email      string  `json:"email" binding:"required,email"`
 	Phone      string  `json:"phone" binding:"required"`
-	ClassType  string  `json:"classType" binding:"required"`
+	ClassType  string  `json:"classType"`
 	Experience string  `json:"experience" binding:"required"`
 	Goals      *string `json:"goals"`
-	Schedule   string  `json:"schedule" binding:"required"`
+	Schedule   string  `json:"schedule"`
+	CohortID   *int    `json:"cohortId"`
+	// Channels, when set, are the notification channels for this booking
+	// only; the customer's stored preference is left alone.
+	Channels []string `json:"channels" binding:"omitempty,dive,oneof=email sms whatsapp"`
+	// Website is a honeypot: the enrollment form hides it, so only bots
+	// fill it in.
+	Website string `json:"website,omitempty"`
 }
 
 // ClassResponse represents the response for class enrollment
+// ManageToken is used with the /api/manage/classes/:token endpoints.
+// WaitlistToken is set when the enrollment was waitlisted and is used with
+// the /api/waitlist/:token endpoints.
 type ClassResponse struct {
-	Success    bool   `json:"success"`
-	Enrollment Class  `json:"enrollment"`
-	Message    string `json:"message"`
+	Success       bool   `json:"success"`
+	Enrollment    Class  `json:"enrollment"`
+	Message       string `json:"message"`
+	ManageToken   string `json:"manage_token,omitempty"`
+	WaitlistToken string `json:"waitlist_token,omitempty"`
+}
+
+// RescheduleRequest moves an appointment to another slot.
+type RescheduleRequest struct {
+	Date     string `json:"date" binding:"required"`
+	Time     string `json:"time" binding:"required"`
+	ArtistID *int   `json:"artist_id"`
+}
+
+// CohortChangeRequest moves a class enrollment to another cohort.
+type CohortChangeRequest struct {
+	CohortID int `json:"cohortId" binding:"required"`
 }
 
-// AdminUser represents an admin user
+// CancelBookingRequest is the optional body of a customer cancellation.
+type CancelBookingRequest struct {
+	Reason string `json:"reason"`
+}
+
+// ClassOffering represents a course in the studio's class catalog
+type ClassOffering struct {
+	ID           int       `json:"id" db:"id"`
+	Name         string    `json:"name" db:"name"`
+	Description  *string   `json:"description" db:"description"`
+	Price        float64   `json:"price" db:"price"`
+	Active       bool      `json:"active" db:"active"`
+	DisplayOrder int       `json:"display_order" db:"display_order"`
+	CreatedAt    time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// ClassOfferingRequest represents the request payload for creating or updating a class offering
+type ClassOfferingRequest struct {
+	Name         string  `json:"name" binding:"required"`
+	Description  *string `json:"description"`
+	Price        float64 `json:"price" binding:"min=0"`
+	Active       *bool   `json:"active"`
+	DisplayOrder int     `json:"display_order"`
+}
+
+// Cohort represents a scheduled run of a class offering with a fixed number of seats.
+// SeatsTaken counts the pending and confirmed enrollments in the cohort.
+type Cohort struct {
+	ID           int       `json:"id" db:"id"`
+	OfferingID   int       `json:"offering_id" db:"offering_id"`
+	Name         string    `json:"name" db:"name"`
+	StartDate    string    `json:"start_date" db:"start_date"`
+	EndDate      *string   `json:"end_date" db:"end_date"`
+	MeetingTimes string    `json:"meeting_times" db:"meeting_times"`
+	Instructor   *string   `json:"instructor" db:"instructor"`
+	Capacity     int       `json:"capacity" db:"capacity"`
+	SeatsTaken   int       `json:"seats_taken" db:"-"`
+	SeatsLeft    int       `json:"seats_left" db:"-"`
+	Active       bool      `json:"active" db:"active"`
+	CreatedAt    time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// SetSeatsTaken records how many seats are taken and how many remain.
+func (c *Cohort) SetSeatsTaken(taken int) {
+	c.SeatsTaken = taken
+	c.SeatsLeft = c.Capacity - taken
+	if c.SeatsLeft < 0 {
+		c.SeatsLeft = 0
+	}
+}
+
+// CohortRequest represents the request payload for creating or updating a cohort
+type CohortRequest struct {
+	OfferingID   int     `json:"offering_id" binding:"required"`
+	Name         string  `json:"name" binding:"required"`
+	StartDate    string  `json:"start_date" binding:"required"`
+	EndDate      *string `json:"end_date"`
+	MeetingTimes string  `json:"meeting_times"`
+	Instructor   *string `json:"instructor"`
+	Capacity     int     `json:"capacity" binding:"required,min=1"`
+	Active       *bool   `json:"active"`
+}
+
+// WaitlistEntry represents a customer waiting for a seat in a full cohort
+// (kind "class") or for a slot on a booked-out day (kind "appointment")
+type WaitlistEntry struct {
+	ID              int        `json:"id" db:"id"`
+	Kind            string     `json:"kind" db:"kind"`
+	Name            string     `json:"name" db:"name"`
+	Email           string     `json:"email" db:"email"`
+	Phone           string     `json:"phone" db:"phone"`
+	CohortID        *int       `json:"cohort_id" db:"cohort_id"`
+	ClassID         *int       `json:"class_id" db:"class_id"`
+	Service         *string    `json:"service" db:"service"`
+	AppointmentDate *string    `json:"appointment_date" db:"appointment_date"`
+	PreferredTime   *string    `json:"preferred_time" db:"preferred_time"`
+	ArtistID        *int       `json:"artist_id" db:"artist_id"`
+	AppointmentID   *int       `json:"appointment_id" db:"appointment_id"`
+	Status          string     `json:"status" db:"status"`
+	HoldExpiresAt   *time.Time `json:"hold_expires_at" db:"hold_expires_at"`
+	TokenHash       string     `json:"-" db:"token_hash"`
+	CreatedAt       time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// WaitlistRequest represents the request payload for joining the waitlist for an appointment date
+type WaitlistRequest struct {
+	Name     string  `json:"name" binding:"required"`
+	Email    string  `json:"email" binding:"required,email"`
+	Phone    string  `json:"phone" binding:"required"`
+	Date     string  `json:"date" binding:"required"`
+	Time     *string `json:"time"`
+	Service  string  `json:"service" binding:"required"`
+	ArtistID *int    `json:"artist_id"`
+}
+
+// Service represents a bookable service in the studio's catalog
+type Service struct {
+	ID              int       `json:"id" db:"id"`
+	Name            string    `json:"name" db:"name"`
+	Description     *string   `json:"description" db:"description"`
+	DurationMinutes int       `json:"duration_minutes" db:"duration_minutes"`
+	BufferMinutes   int       `json:"buffer_minutes" db:"buffer_minutes"`
+	Price           float64   `json:"price" db:"price"`
+	DepositAmount   float64   `json:"deposit_amount" db:"deposit_amount"`
+	Active          bool      `json:"active" db:"active"`
+	DisplayOrder    int       `json:"display_order" db:"display_order"`
+	CreatedAt       time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// ServiceRequest represents the request payload for creating or updating a service
+type ServiceRequest struct {
+	Name            string  `json:"name" binding:"required"`
+	Description     *string `json:"description"`
+	DurationMinutes int     `json:"duration_minutes" binding:"required,min=1"`
+	BufferMinutes   int     `json:"buffer_minutes" binding:"min=0"`
+	Price           float64 `json:"price" binding:"min=0"`
+	DepositAmount   float64 `json:"deposit_amount" binding:"min=0,ltefield=Price"`
+	Active          *bool   `json:"active"`
+	DisplayOrder    int     `json:"display_order"`
+}
+
+// Artist represents a makeup artist on the studio's staff
+type Artist struct {
+	ID           int       `json:"id" db:"id"`
+	Name         string    `json:"name" db:"name"`
+	Email        *string   `json:"email" db:"email"`
+	Phone        *string   `json:"phone" db:"phone"`
+	Skills       []string  `json:"skills" db:"skills"`
+	WorkingHours string    `json:"working_hours" db:"working_hours"`
+	Active       bool      `json:"active" db:"active"`
+	CreatedAt    time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// HasSkill reports whether the artist performs the given service
+func (a *Artist) HasSkill(service string) bool {
+	for _, skill := range a.Skills {
+		if skill == service {
+			return true
+		}
+	}
+	return false
+}
+
+// ArtistRequest represents the request payload for creating or updating an artist.
+// WorkingHours uses the BUSINESS_HOURS format; empty means the studio's hours.
+type ArtistRequest struct {
+	Name         string   `json:"name" binding:"required"`
+	Email        *string  `json:"email" binding:"omitempty,email"`
+	Phone        *string  `json:"phone"`
+	Skills       []string `json:"skills" binding:"required,min=1"`
+	WorkingHours string   `json:"working_hours"`
+	Active       *bool    `json:"active"`
+}
+
+// PublicArtist is the subset of an artist shown to customers
+type PublicArtist struct {
+	ID     int      `json:"id"`
+	Name   string   `json:"name"`
+	Skills []string `json:"skills"`
+}
+
+// AdminUser represents an admin user. ArtistID links an admin with the
+// artist role to the artist whose appointments they see.
 type AdminUser struct {
-	ID        string    `json:"id" db:"id"`
+	ID               string    `json:"id" db:"id"`
+	Email            string    `json:"email" db:"email"`
+	Name             string    `json:"name" db:"name"`
+	Role             string    `json:"role" db:"role"`
+	ArtistID         *int      `json:"artist_id" db:"artist_id"`
+	TwoFactorEnabled bool      `json:"two_factor_enabled" db:"two_factor_enabled"`
+	CreatedAt        time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// AdminRoleRequest changes an admin's role. ArtistID is required for the
+// artist role and ignored for the others.
+type AdminRoleRequest struct {
+	Role     string `json:"role" binding:"required,oneof=owner manager artist receptionist instructor"`
+	ArtistID *int   `json:"artist_id"`
+}
+
+// AdminSignupRequest represents the request payload for admin signup, which
+// only creates the first owner. BootstrapToken must match
+// ADMIN_BOOTSTRAP_TOKEN. Passwords are checked against the password policy.
+type AdminSignupRequest struct {
+	Email          string `json:"email" binding:"required,email"`
+	Password       string `json:"password" binding:"required"`
+	Name           string `json:"name" binding:"required"`
+	BootstrapToken string `json:"bootstrap_token" binding:"required"`
+}
+
+// AdminInvite invites an email to become an admin with a role. Status is
+// "pending", "accepted", "revoked" or, for pending invites past ExpiresAt,
+// "expired".
+type AdminInvite struct {
+	ID         int        `json:"id" db:"id"`
+	Email      string     `json:"email" db:"email"`
+	Role       string     `json:"role" db:"role"`
+	ArtistID   *int       `json:"artist_id" db:"artist_id"`
+	InvitedBy  *string    `json:"invited_by" db:"invited_by"`
+	Status     string     `json:"status" db:"status"`
+	ExpiresAt  time.Time  `json:"expires_at" db:"expires_at"`
+	AcceptedAt *time.Time `json:"accepted_at" db:"accepted_at"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// AdminInviteRequest represents the request payload for inviting an admin.
+// ArtistID is required for the artist role and ignored for the others.
+type AdminInviteRequest struct {
+	Email    string `json:"email" binding:"required,email"`
+	Role     string `json:"role" binding:"required,oneof=owner manager artist receptionist instructor"`
+	ArtistID *int   `json:"artist_id"`
+}
+
+// AdminRegisterRequest represents the request payload for registering with
+// an invite. The email is the one the invite was sent to.
+type AdminRegisterRequest struct {
+	Password string `json:"password" binding:"required"`
+	Name     string `json:"name" binding:"required"`
+}
+
+// AdminChangePasswordRequest represents the request payload for changing
+// the signed-in admin's password
+type AdminChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	NewPassword     string `json:"new_password" binding:"required"`
+}
+
+// AdminForgotPasswordRequest represents the request payload for emailing an
+// admin a password reset link
+type AdminForgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// AdminResetPasswordRequest represents the request payload for setting a new
+// password with an emailed reset token
+type AdminResetPasswordRequest struct {
+	Token       string `json:"token" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required"`
+}
+
+// TwoFactorCodeRequest represents the request payload for confirming a TOTP
+// code
+type TwoFactorCodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// TwoFactorDisableRequest represents the request payload for turning off
+// two-factor authentication, which takes the password and a current code
+type TwoFactorDisableRequest struct {
+	Password string `json:"password" binding:"required"`
+	Code     string `json:"code" binding:"required"`
+}
+
+// TwoFactorLoginRequest represents the request payload for the second step
+// of a login. Either Code, from the authenticator app, or one of the
+// admin's RecoveryCodes is required.
+type TwoFactorLoginRequest struct {
+	ChallengeToken string `json:"challenge_token" binding:"required"`
+	Code           string `json:"code"`
+	RecoveryCode   string `json:"recovery_code"`
+}
+
+// SecuritySettings are the studio-wide admin security settings.
+// RequireTwoFactor makes every admin set up two-factor authentication
+// before they can do anything else.
+type SecuritySettings struct {
+	RequireTwoFactor bool      `json:"require_two_factor" db:"require_two_factor"`
+	UpdatedAt        time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// SecuritySettingsRequest represents the request payload for changing the
+// security settings
+type SecuritySettingsRequest struct {
+	RequireTwoFactor *bool `json:"require_two_factor" binding:"required"`
+}
+
+// LoginAttempt records one admin login attempt. Result is "success",
+// "invalid_credentials", "blocked" (refused while throttled or locked out),
+// "two_factor_required" (right password, waiting for the code),
+// "invalid_two_factor", "invalid_current_password" (a failed password
+// change) or "password_reset_requested".
+type LoginAttempt struct {
+	ID        int       `json:"id" db:"id"`
 	Email     string    `json:"email" db:"email"`
+	IPAddress string    `json:"ip_address" db:"ip_address"`
+	UserAgent string    `json:"user_agent" db:"user_agent"`
+	Result    string    `json:"result" db:"result"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+// LoginThrottle counts the recent failed logins for an email or IP address,
+// under Key.
+type LoginThrottle struct {
+	Key           string    `json:"key" db:"key"`
+	Failures      int       `json:"failures" db:"failures"`
+	LastFailureAt time.Time `json:"last_failure_at" db:"last_failure_at"`
+}
+
+// Customer is a person who has booked appointments or enrolled in classes,
+// matched across bookings by email or phone number. Email is stored
+// lowercased and Phone in E.164, like every phone number.
+type Customer struct {
+	ID        int       `json:"id" db:"id"`
 	Name      string    `json:"name" db:"name"`
+	Email     string    `json:"email" db:"email"`
+	Phone     string    `json:"phone" db:"phone"`
+	Notes     *string   `json:"notes" db:"notes"`
 	CreatedAt time.Time `json:"created_at" db:"created_at"`
 	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
 }
 
-// AdminSignupRequest represents the request payload for admin signup
-type AdminSignupRequest struct {
-	Email    string `json:"email" binding:"required,email"`
-	Password string `json:"password" binding:"required,min=6"`
-	Name     string `json:"name" binding:"required"`
+// CustomerRequest represents the request payload for updating a customer
+type CustomerRequest struct {
+	Name  string  `json:"name" binding:"required"`
+	Email string  `json:"email" binding:"required,email"`
+	Phone string  `json:"phone"`
+	Notes *string `json:"notes"`
+}
+
+// MergeCustomerRequest names the duplicate customer to merge into another.
+type MergeCustomerRequest struct {
+	CustomerID int `json:"customer_id" binding:"required"`
+}
+
+// CustomerProfile is a customer with their booking history. LifetimeValue
+// is the catalog price of their completed appointments and classes.
+type CustomerProfile struct {
+	Customer
+	Appointments  []Appointment `json:"appointments"`
+	Classes       []Class       `json:"classes"`
+	LifetimeValue float64       `json:"lifetime_value"`
+}
+
+// IdempotencyKey is a client's Idempotency-Key for one endpoint, Scope,
+// and the response the first request with it got. StatusCode is zero while
+// that request is still running.
+type IdempotencyKey struct {
+	ID           int       `json:"id" db:"id"`
+	Scope        string    `json:"scope" db:"scope"`
+	Key          string    `json:"key" db:"key"`
+	RequestHash  string    `json:"request_hash" db:"request_hash"`
+	StatusCode   int       `json:"status_code" db:"status_code"`
+	ResponseBody string    `json:"response_body" db:"response_body"`
+	CreatedAt    time.Time `json:"created_at" db:"created_at"`
+	ExpiresAt    time.Time `json:"expires_at" db:"expires_at"`
 }
 
 // AdminLoginRequest represents the request payload for admin login
@@ -92,12 +489,43 @@ type AdminLoginRequest struct {
 	Password string `json:"password" binding:"required"`
 }
 
-// AdminLoginResponse represents the response for admin login
+// AdminLoginResponse represents the response for admin login and token
+// refresh. Token is a short-lived access token expiring at ExpiresAt;
+// RefreshToken gets the next pair from /api/admin/refresh, once.
+// TwoFactorSetupRequired is set when two-factor authentication is required
+// and the admin hasn't set it up; until they do, the token only reaches
+// their own account and two-factor setup.
 type AdminLoginResponse struct {
-	Success bool      `json:"success"`
-	Token   string    `json:"token"`
-	Admin   AdminUser `json:"admin"`
-	Message string    `json:"message"`
+	Success                bool      `json:"success"`
+	Token                  string    `json:"token"`
+	ExpiresAt              time.Time `json:"expires_at"`
+	RefreshToken           string    `json:"refresh_token"`
+	SessionID              string    `json:"session_id"`
+	TwoFactorSetupRequired bool      `json:"two_factor_setup_required,omitempty"`
+	Admin                  AdminUser `json:"admin"`
+	Message                string    `json:"message"`
+}
+
+// AdminRefreshRequest represents the request payload for refreshing an
+// access token
+type AdminRefreshRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+// AdminSession is a signed-in device of an admin, kept alive by its refresh
+// token until ExpiresAt. Current marks the session of the request listing
+// them.
+type AdminSession struct {
+	ID         string     `json:"id" db:"id"`
+	AdminID    string     `json:"admin_id" db:"admin_id"`
+	UserAgent  string     `json:"user_agent" db:"user_agent"`
+	IPAddress  string     `json:"ip_address" db:"ip_address"`
+	ExpiresAt  time.Time  `json:"expires_at" db:"expires_at"`
+	LastUsedAt time.Time  `json:"last_used_at" db:"last_used_at"`
+	RevokedAt  *time.Time `json:"revoked_at" db:"revoked_at"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at" db:"updated_at"`
+	Current    bool       `json:"current" db:"-"`
 }
 
 // ErrorResponse represents an error response
@@ -111,9 +539,136 @@ type SuccessResponse struct {
 	Message string `json:"message"`
 }
 
-// StatusUpdateRequest represents the request payload for updating status
+// StatusUpdateRequest represents the request payload for updating status.
+// Reason is required when cancelling.
 type StatusUpdateRequest struct {
 	Status string `json:"status" binding:"required"`
+	Reason string `json:"reason"`
+}
+
+// StatusHistory records one status change of an appointment or class
+// enrollment. AdminID is nil for changes the system made itself.
+type StatusHistory struct {
+	ID           int       `json:"id" db:"id"`
+	ResourceType string    `json:"resource_type" db:"resource_type"`
+	ResourceID   int       `json:"resource_id" db:"resource_id"`
+	FromStatus   string    `json:"from_status" db:"from_status"`
+	ToStatus     string    `json:"to_status" db:"to_status"`
+	Reason       *string   `json:"reason" db:"reason"`
+	AdminID      *string   `json:"admin_id" db:"admin_id"`
+	CreatedAt    time.Time `json:"created_at" db:"created_at"`
+}
+
+// Notification is one message in the outbox about an appointment or class
+// enrollment, sent by email, SMS or WhatsApp. It stays "pending" until handed
+// to the mail server or messaging provider ("sent") or until every attempt
+// has failed ("failed"). Text messages move on to "delivered", or "failed",
+// when the provider reports back on ProviderMessageID.
+type Notification struct {
+	ID           int    `json:"id" db:"id"`
+	Channel      string `json:"channel" db:"channel"`
+	Event        string `json:"event" db:"event"`
+	ResourceType string `json:"resource_type" db:"resource_type"`
+	ResourceID   int    `json:"resource_id" db:"resource_id"`
+	Recipient    string `json:"recipient" db:"recipient"`
+	Subject      string `json:"subject" db:"subject"`
+	Body         string `json:"body" db:"body"`
+	// Secret is the sealed secret, like a manage token, that fills in the
+	// placeholder in Body when the message is sent. It is cleared once the
+	// message is sent or given up on, and never returned.
+	Secret        string     `json:"-" db:"secret"`
+	Status        string     `json:"status" db:"status"`
+	Attempts      int        `json:"attempts" db:"attempts"`
+	LastError     *string    `json:"last_error" db:"last_error"`
+	NextAttemptAt time.Time  `json:"next_attempt_at" db:"next_attempt_at"`
+	SentAt        *time.Time `json:"sent_at" db:"sent_at"`
+	// ProviderMessageID is the messaging provider's ID for a text message.
+	ProviderMessageID *string    `json:"provider_message_id" db:"provider_message_id"`
+	DeliveredAt       *time.Time `json:"delivered_at" db:"delivered_at"`
+	CreatedAt         time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// NotificationPreference is how a customer, identified by email, wants to
+// hear about their bookings: any of "email", "sms" and "whatsapp". Customers
+// without one are emailed.
+type NotificationPreference struct {
+	Email     string    `json:"email" db:"email"`
+	Channels  []string  `json:"channels" db:"channels"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// NotificationPreferenceRequest replaces a customer's notification channels.
+type NotificationPreferenceRequest struct {
+	Channels []string `json:"channels" binding:"required,min=1,dive,oneof=email sms whatsapp"`
+}
+
+// DeliveryStatusRequest is a messaging provider's delivery report for a
+// message it accepted. Status is "delivered", or "failed" or "undelivered"
+// with Error saying why.
+type DeliveryStatusRequest struct {
+	ID     string  `json:"id" binding:"required"`
+	Status string  `json:"status" binding:"required"`
+	Error  *string `json:"error"`
+}
+
+// WebhookSubscription sends the booking events in Events to URL. The secret
+// payloads are signed with is never returned.
+type WebhookSubscription struct {
+	ID          int       `json:"id" db:"id"`
+	URL         string    `json:"url" db:"url"`
+	Events      []string  `json:"events" db:"events"`
+	Description *string   `json:"description" db:"description"`
+	Active      bool      `json:"active" db:"active"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// WebhookSubscriptionRequest creates or updates a webhook subscription.
+// Secret is required when creating and kept when left out of an update.
+type WebhookSubscriptionRequest struct {
+	URL         string   `json:"url" binding:"required,url"`
+	Secret      string   `json:"secret" binding:"omitempty,min=16"`
+	Events      []string `json:"events" binding:"required,min=1"`
+	Description *string  `json:"description"`
+	Active      *bool    `json:"active"`
+}
+
+// WebhookDelivery is one event sent, or to be sent, to a subscription. It
+// stays "pending" until the endpoint answers with a 2xx ("delivered") or
+// every attempt has failed ("failed").
+type WebhookDelivery struct {
+	ID             int             `json:"id" db:"id"`
+	SubscriptionID int             `json:"subscription_id" db:"subscription_id"`
+	Event          string          `json:"event" db:"event"`
+	Payload        json.RawMessage `json:"payload" db:"payload"`
+	Status         string          `json:"status" db:"status"`
+	Attempts       int             `json:"attempts" db:"attempts"`
+	LastError      *string         `json:"last_error" db:"last_error"`
+	ResponseStatus *int            `json:"response_status" db:"response_status"`
+	NextAttemptAt  time.Time       `json:"next_attempt_at" db:"next_attempt_at"`
+	DeliveredAt    *time.Time      `json:"delivered_at" db:"delivered_at"`
+	CreatedAt      time.Time       `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time       `json:"updated_at" db:"updated_at"`
+}
+
+// Job is a piece of background work due at RunAt, such as a booking
+// reminder. Payload holds what the job's kind needs. Pending jobs become
+// "done" once run, "cancelled" when their booking goes away, or "failed"
+// after every attempt has failed.
+type Job struct {
+	ID           int             `json:"id" db:"id"`
+	Kind         string          `json:"kind" db:"kind"`
+	ResourceType string          `json:"resource_type" db:"resource_type"`
+	ResourceID   int             `json:"resource_id" db:"resource_id"`
+	Payload      json.RawMessage `json:"payload" db:"payload"`
+	RunAt        time.Time       `json:"run_at" db:"run_at"`
+	Status       string          `json:"status" db:"status"`
+	Attempts     int             `json:"attempts" db:"attempts"`
+	LastError    *string         `json:"last_error" db:"last_error"`
+	CreatedAt    time.Time       `json:"created_at" db:"created_at"`
+	UpdatedAt    time.Time       `json:"updated_at" db:"updated_at"`
 }
 
 // StatusUpdateResponse represents the response for status updates
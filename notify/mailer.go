@@ -0,0 +1,134 @@
+// Package notify tells customers and the studio about their bookings. Emails
+// and SMS or WhatsApp messages are rendered from templates into the
+// notification outbox when something happens, and a Worker delivers them in
+// the background with retries.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"log"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+	"time"
+)
+
+// Message is one email ready to send.
+type Message struct {
+	To      string
+	Subject string
+	HTML    string
+}
+
+// Mailer delivers emails.
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// smtpTimeout bounds a whole SMTP conversation when ctx has no deadline.
+const smtpTimeout = 30 * time.Second
+
+// SMTPMailer sends through an SMTP server, upgrading to TLS with STARTTLS
+// when the server offers it. Any SMTP server works for local testing,
+// e.g. MailHog or Mailpit on port 1025.
+type SMTPMailer struct {
+	host     string
+	port     int
+	username string
+	password string
+	from     string
+}
+
+// NewSMTPMailer returns a mailer for host:port. Authentication is skipped
+// when username is empty.
+func NewSMTPMailer(host string, port int, username, password, from string) (*SMTPMailer, error) {
+	if _, err := mail.ParseAddress(from); err != nil {
+		return nil, fmt.Errorf("invalid from address %q: %w", from, err)
+	}
+	return &SMTPMailer{host: host, port: port, username: username, password: password, from: from}, nil
+}
+
+func (m *SMTPMailer) Send(ctx context.Context, msg Message) error {
+	from, _ := mail.ParseAddress(m.from)
+
+	ctx, cancel := context.WithTimeout(ctx, smtpTimeout)
+	defer cancel()
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(m.host, strconv.Itoa(m.port)))
+	if err != nil {
+		return err
+	}
+	deadline, _ := ctx.Deadline()
+	conn.SetDeadline(deadline)
+
+	client, err := smtp.NewClient(conn, m.host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: m.host}); err != nil {
+			return err
+		}
+	}
+	if m.username != "" {
+		if err := client.Auth(smtp.PlainAuth("", m.username, m.password, m.host)); err != nil {
+			return err
+		}
+	}
+
+	if err := client.Mail(from.Address); err != nil {
+		return err
+	}
+	if err := client.Rcpt(msg.To); err != nil {
+		return err
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(buildMessage(m.from, msg)); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// buildMessage renders msg as a quoted-printable HTML email.
+func buildMessage(from string, msg Message) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s\r\n", from)
+	fmt.Fprintf(&b, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/html; charset=UTF-8\r\n")
+	b.WriteString("Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+
+	qp := quotedprintable.NewWriter(&b)
+	qp.Write([]byte(msg.HTML))
+	qp.Close()
+	b.WriteString("\r\n")
+
+	return b.Bytes()
+}
+
+// LogMailer writes emails to the log instead of sending them. It is used
+// when no SMTP server is configured.
+type LogMailer struct{}
+
+func (LogMailer) Send(ctx context.Context, msg Message) error {
+	log.Printf("Email to %s: %s", msg.To, msg.Subject)
+	return nil
+}
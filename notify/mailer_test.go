@@ -0,0 +1,146 @@
+package notify
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"strings"
+	"testing"
+)
+
+// smtpServer is a fake SMTP server on a local port. It accepts every
+// message and hands each one to the messages channel.
+type smtpServer struct {
+	listener net.Listener
+	messages chan receivedMail
+}
+
+type receivedMail struct {
+	from, to string
+	data     string
+}
+
+func newSMTPServer(t *testing.T) *smtpServer {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	s := &smtpServer{listener: listener, messages: make(chan receivedMail, 10)}
+	t.Cleanup(func() { listener.Close() })
+	go s.serve()
+	return s
+}
+
+func (s *smtpServer) addr() (string, int) {
+	a := s.listener.Addr().(*net.TCPAddr)
+	return a.IP.String(), a.Port
+}
+
+func (s *smtpServer) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *smtpServer) handle(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	reply := func(line string) { io.WriteString(conn, line+"\r\n") }
+
+	var msg receivedMail
+	reply("220 localhost fake SMTP")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		command := strings.ToUpper(strings.TrimSpace(line))
+		switch {
+		case strings.HasPrefix(command, "EHLO"), strings.HasPrefix(command, "HELO"):
+			reply("250 localhost")
+		case strings.HasPrefix(command, "MAIL FROM:"):
+			msg.from = strings.Trim(strings.TrimSpace(line)[len("MAIL FROM:"):], "<>")
+			reply("250 OK")
+		case strings.HasPrefix(command, "RCPT TO:"):
+			msg.to = strings.Trim(strings.TrimSpace(line)[len("RCPT TO:"):], "<>")
+			reply("250 OK")
+		case command == "DATA":
+			reply("354 End data with <CR><LF>.<CR><LF>")
+			var data strings.Builder
+			for {
+				line, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if line == ".\r\n" {
+					break
+				}
+				data.WriteString(strings.TrimPrefix(line, "."))
+			}
+			msg.data = data.String()
+			s.messages <- msg
+			reply("250 OK")
+		case command == "QUIT":
+			reply("221 Bye")
+			return
+		default:
+			reply("250 OK")
+		}
+	}
+}
+
+func TestSMTPMailerSend(t *testing.T) {
+	server := newSMTPServer(t)
+	host, port := server.addr()
+	mailer, err := NewSMTPMailer(host, port, "", "", "Mumuni <no-reply@mumuni.com>")
+	if err != nil {
+		t.Fatalf("NewSMTPMailer: %v", err)
+	}
+
+	err = mailer.Send(context.Background(), Message{
+		To:      "ada@example.com",
+		Subject: "Your booking is confirmed – see you soon",
+		HTML:    `<p>Hi Ada, your <strong>Bridal Makeup</strong> appointment is confirmed.</p>`,
+	})
+	if err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	got := <-server.messages
+	if got.from != "no-reply@mumuni.com" || got.to != "ada@example.com" {
+		t.Errorf("envelope from %q to %q, want no-reply@mumuni.com to ada@example.com", got.from, got.to)
+	}
+	parsed, err := mail.ReadMessage(strings.NewReader(got.data))
+	if err != nil {
+		t.Fatalf("invalid message: %v", err)
+	}
+	subject, err := new(mime.WordDecoder).DecodeHeader(parsed.Header.Get("Subject"))
+	if err != nil || subject != "Your booking is confirmed – see you soon" {
+		t.Errorf("subject = %q, %v", subject, err)
+	}
+	if ct := parsed.Header.Get("Content-Type"); !strings.HasPrefix(ct, "text/html") {
+		t.Errorf("content type = %q, want text/html", ct)
+	}
+	body, err := io.ReadAll(quotedprintable.NewReader(parsed.Body))
+	if err != nil {
+		t.Fatalf("reading body: %v", err)
+	}
+	if !strings.Contains(string(body), "<strong>Bridal Makeup</strong>") {
+		t.Errorf("body = %q, want the HTML sent", body)
+	}
+}
+
+func TestSMTPMailerRejectsBadFrom(t *testing.T) {
+	if _, err := NewSMTPMailer("localhost", 25, "", "", "not an address"); err == nil {
+		t.Error("NewSMTPMailer accepted an invalid from address")
+	}
+}
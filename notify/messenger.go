@@ -0,0 +1,164 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Text message channels
+const (
+	ChannelSMS      = "sms"
+	ChannelWhatsApp = "whatsapp"
+)
+
+// TextMessage is one SMS or WhatsApp message ready to send.
+type TextMessage struct {
+	Channel string
+	To      string
+	Body    string
+}
+
+// Messenger delivers text messages. Send returns the provider's ID for the
+// message, which its delivery status reports refer to.
+type Messenger interface {
+	Send(ctx context.Context, msg TextMessage) (string, error)
+}
+
+// messengerTimeout bounds a request to the messaging provider.
+const messengerTimeout = 30 * time.Second
+
+// HTTPMessenger sends through a messaging provider's HTTP API. Each message
+// is POSTed as JSON:
+//
+//	{"channel": "sms", "from": "...", "to": "...", "body": "...", "status_callback": "..."}
+//
+// with the API key as a bearer token, and the provider answers with the
+// message's ID as {"id": "..."}. Providers with a different API can be put
+// behind a small adapter speaking this format.
+type HTTPMessenger struct {
+	client       *http.Client
+	apiURL       string
+	apiKey       string
+	smsFrom      string
+	whatsAppFrom string
+	statusURL    string
+}
+
+// NewHTTPMessenger returns a messenger posting to apiURL. smsFrom and
+// whatsAppFrom are the sender for each channel, and statusURL, if set, is
+// where the provider should report delivery status.
+func NewHTTPMessenger(apiURL, apiKey, smsFrom, whatsAppFrom, statusURL string) *HTTPMessenger {
+	return &HTTPMessenger{
+		client:       &http.Client{Timeout: messengerTimeout},
+		apiURL:       apiURL,
+		apiKey:       apiKey,
+		smsFrom:      smsFrom,
+		whatsAppFrom: whatsAppFrom,
+		statusURL:    statusURL,
+	}
+}
+
+func (m *HTTPMessenger) Send(ctx context.Context, msg TextMessage) (string, error) {
+	from := m.smsFrom
+	if msg.Channel == ChannelWhatsApp {
+		from = m.whatsAppFrom
+	}
+
+	payload, err := json.Marshal(map[string]string{
+		"channel":         msg.Channel,
+		"from":            from,
+		"to":              msg.To,
+		"body":            msg.Body,
+		"status_callback": m.statusURL,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, m.apiURL, bytes.NewReader(payload))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if m.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+m.apiKey)
+	}
+
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return "", fmt.Errorf("messaging provider returned %s: %s", resp.Status, truncate(string(body), 200))
+	}
+
+	var result struct {
+		ID string `json:"id"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return "", fmt.Errorf("invalid messaging provider response: %w", err)
+	}
+	if result.ID == "" {
+		return "", fmt.Errorf("messaging provider response has no message ID")
+	}
+
+	return result.ID, nil
+}
+
+// LogMessenger stands in for a messaging provider in local development. It
+// logs who each message is for, but not what it says, which can hold a
+// manage link, and returns IDs "fake-1", "fake-2" and so on, which can be
+// used to post delivery status reports by hand.
+type LogMessenger struct {
+	sent atomic.Int64
+}
+
+func (m *LogMessenger) Send(ctx context.Context, msg TextMessage) (string, error) {
+	id := "fake-" + strconv.FormatInt(m.sent.Add(1), 10)
+	log.Printf("%s message %s to %s (not sent)", msg.Channel, id, msg.To)
+	return id, nil
+}
+
+// FakeMessenger stands in for a messaging provider in tests. It keeps each
+// message for Messages and returns IDs like LogMessenger.
+type FakeMessenger struct {
+	mu       sync.Mutex
+	messages []TextMessage
+}
+
+func (m *FakeMessenger) Send(ctx context.Context, msg TextMessage) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.messages = append(m.messages, msg)
+	return "fake-" + strconv.Itoa(len(m.messages)), nil
+}
+
+// Messages returns every message sent so far.
+func (m *FakeMessenger) Messages() []TextMessage {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]TextMessage(nil), m.messages...)
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "..."
+}
@@ -0,0 +1,140 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"mumuni_backend/database"
+	"mumuni_backend/models"
+)
+
+// queueSMS queues an appointment confirmation for a customer who has chosen
+// SMS and returns the store holding it.
+func queueSMS(t *testing.T) *database.MemoryStore {
+	t.Helper()
+	store := database.NewMemoryStore()
+	appointment := testAppointment()
+	if _, err := store.SaveNotificationPreference(context.Background(), appointment.Email, []string{ChannelSMS}); err != nil {
+		t.Fatalf("SaveNotificationPreference: %v", err)
+	}
+	notifier, err := NewNotifier(testConfig(), store)
+	if err != nil {
+		t.Fatalf("NewNotifier: %v", err)
+	}
+	notifier.Appointment(context.Background(), AppointmentConfirmed, appointment, Details{})
+	return store
+}
+
+func TestWorkerSendsTextMessages(t *testing.T) {
+	store := queueSMS(t)
+	messenger := &FakeMessenger{}
+	NewWorker(store, &recordingMailer{}, messenger, "test-secret").Deliver(context.Background())
+
+	messages := messenger.Messages()
+	if len(messages) != 1 {
+		t.Fatalf("sent %d text messages, want 1", len(messages))
+	}
+	if msg := messages[0]; msg.Channel != ChannelSMS || msg.To != "+2348031112222" || !strings.Contains(msg.Body, "Bridal Makeup") {
+		t.Errorf("sent %+v, want the confirmation by SMS to +2348031112222", msg)
+	}
+
+	var sms models.Notification
+	for _, n := range outbox(t, store) {
+		if n.Channel == ChannelSMS {
+			sms = n
+		}
+	}
+	if sms.Status != "sent" || sms.ProviderMessageID == nil || *sms.ProviderMessageID != "fake-1" {
+		t.Fatalf("after sending: %+v, want sent as fake-1", sms)
+	}
+
+	// The provider's delivery report then moves the message along
+	if _, err := RecordDelivery(context.Background(), store, &models.DeliveryStatusRequest{ID: "fake-1", Status: "delivered"}); err != nil {
+		t.Fatalf("RecordDelivery: %v", err)
+	}
+	if n := outbox(t, store)[0]; n.Status != "delivered" || n.DeliveredAt == nil {
+		t.Errorf("after the delivery report: status %q, delivered at %v; want delivered", n.Status, n.DeliveredAt)
+	}
+	if _, err := RecordDelivery(context.Background(), store, &models.DeliveryStatusRequest{ID: "fake-9", Status: "delivered"}); !errors.Is(err, database.ErrNotificationNotFound) {
+		t.Errorf("report for an unknown message: got %v, want ErrNotificationNotFound", err)
+	}
+	if _, err := RecordDelivery(context.Background(), store, &models.DeliveryStatusRequest{ID: "fake-1", Status: "exploded"}); !errors.Is(err, ErrUnknownDeliveryStatus) {
+		t.Errorf("unknown status: got %v, want ErrUnknownDeliveryStatus", err)
+	}
+}
+
+func TestWorkerWithoutMessengerFailsTextMessages(t *testing.T) {
+	store := queueSMS(t)
+	NewWorker(store, &recordingMailer{}, nil, "test-secret").Deliver(context.Background())
+
+	n := outbox(t, store)[0]
+	if n.Status != "failed" || n.Attempts != 1 || n.LastError == nil || *n.LastError != ErrNoMessenger.Error() {
+		t.Errorf("without a messenger: %+v, want failed after one attempt with %q", n, ErrNoMessenger)
+	}
+}
+
+func TestHTTPMessengerSend(t *testing.T) {
+	var got map[string]string
+	var auth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		json.NewDecoder(r.Body).Decode(&got)
+		w.Write([]byte(`{"id": "msg-42"}`))
+	}))
+	defer server.Close()
+
+	messenger := NewHTTPMessenger(server.URL, "api-key", "Mumuni", "+2348000000000", "https://api.mumuni.com/status")
+	id, err := messenger.Send(context.Background(), TextMessage{Channel: ChannelWhatsApp, To: "+2348031112222", Body: "See you soon"})
+	if err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if id != "msg-42" {
+		t.Errorf("message ID = %q, want msg-42", id)
+	}
+	if auth != "Bearer api-key" {
+		t.Errorf("Authorization = %q, want the API key", auth)
+	}
+	want := map[string]string{
+		"channel":         ChannelWhatsApp,
+		"from":            "+2348000000000",
+		"to":              "+2348031112222",
+		"body":            "See you soon",
+		"status_callback": "https://api.mumuni.com/status",
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("posted %s = %q, want %q", k, got[k], v)
+		}
+	}
+}
+
+func TestHTTPMessengerErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+	}{
+		{"error status", http.StatusBadRequest, `{"error": "invalid number"}`},
+		{"no ID", http.StatusOK, `{}`},
+		{"not JSON", http.StatusOK, `OK`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			messenger := NewHTTPMessenger(server.URL, "", "Mumuni", "", "")
+			if id, err := messenger.Send(context.Background(), TextMessage{Channel: ChannelSMS, To: "+2348031112222", Body: "Hi"}); err == nil {
+				t.Errorf("Send returned ID %q, want an error", id)
+			}
+		})
+	}
+}
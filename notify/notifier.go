@@ -0,0 +1,336 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html/template"
+	"log"
+	"mumuni_backend/config"
+	"mumuni_backend/database"
+	"mumuni_backend/models"
+	"strings"
+	texttemplate "text/template"
+	"time"
+)
+
+// Events customers are notified about. Each has an email template, and a
+// text template for SMS and WhatsApp, of the same name with the dot replaced
+// by an underscore. The studio's email copies are recorded as "studio."
+// followed by the event.
+const (
+	AppointmentReceived  = "appointment.received"
+	AppointmentPromoted  = "appointment.promoted"
+	AppointmentConfirmed = "appointment.confirmed"
+	AppointmentCancelled = "appointment.cancelled"
+	AppointmentCompleted = "appointment.completed"
+	AppointmentReminder  = "appointment.reminder"
+
+	EnrollmentReceived   = "enrollment.received"
+	EnrollmentWaitlisted = "enrollment.waitlisted"
+	EnrollmentPromoted   = "enrollment.promoted"
+	EnrollmentConfirmed  = "enrollment.confirmed"
+	EnrollmentCancelled  = "enrollment.cancelled"
+	EnrollmentCompleted  = "enrollment.completed"
+	EnrollmentReminder   = "enrollment.reminder"
+
+	// AdminPasswordReset is emailed to an admin who forgot their password.
+	AdminPasswordReset = "admin.password_reset"
+)
+
+// studioEvents are the events the studio gets a copy of.
+var studioEvents = map[string]bool{
+	AppointmentReceived:  true,
+	AppointmentPromoted:  true,
+	AppointmentCancelled: true,
+	EnrollmentReceived:   true,
+	EnrollmentWaitlisted: true,
+	EnrollmentCancelled:  true,
+}
+
+// manageLinkEvents are the events whose customer messages link to the
+// booking's manage page.
+var manageLinkEvents = map[string]bool{
+	AppointmentReceived:  true,
+	AppointmentPromoted:  true,
+	EnrollmentReceived:   true,
+	EnrollmentWaitlisted: true,
+}
+
+// Redact blanks the body of a password reset email or a message with a
+// manage link, which would let whoever reads it act as the recipient. Their
+// tokens are no longer stored with the message, but older messages still
+// hold them.
+func Redact(notification *models.Notification) {
+	if notification.ResourceType == "password_reset" || manageLinkEvents[notification.Event] {
+		notification.Body = ""
+	}
+}
+
+// AppointmentEvent returns the event for an appointment whose status has
+// just changed to status.
+func AppointmentEvent(status string) string {
+	return "appointment." + status
+}
+
+// EnrollmentEvent returns the event for an enrollment whose status has just
+// changed to status. Only waitlisted enrollments change to "pending", so
+// that is EnrollmentPromoted.
+func EnrollmentEvent(status string) string {
+	if status == "pending" {
+		return EnrollmentPromoted
+	}
+	return "enrollment." + status
+}
+
+// Details carries what an email needs beyond the booking itself. ManageToken
+// is only known when the booking was just made; the manage link is left out
+// without it. The token is sealed apart from the message and only filled
+// into the link when it is sent. HoldExpiresAt is set for a promoted waitlist seat or slot that
+// has to be accepted in time, and SessionStart for a class session reminder.
+type Details struct {
+	ManageToken   string
+	HoldExpiresAt *time.Time
+	SessionStart  *time.Time
+}
+
+// Notifier renders messages for booking events into the notification
+// outbox, on each channel the customer has chosen. Nothing is sent here; a
+// Worker delivers the outbox in the background, so booking requests never
+// wait on or fail because of the mail server or messaging provider.
+type Notifier struct {
+	store         database.Store
+	secrets       *secretBox
+	templates     map[string]*template.Template
+	textTemplates map[string]*texttemplate.Template
+	studioName    string
+	studioEmail   string
+	manageURL     string
+	resetURL      string
+}
+
+// NewNotifier parses the message templates and returns a Notifier writing
+// to store's outbox. Times in messages are shown in the studio's time zone,
+// and secrets in them are sealed with a key derived from JWT_SECRET.
+func NewNotifier(cfg *config.Config, store database.Store) (*Notifier, error) {
+	location, err := time.LoadLocation(cfg.StudioTimeZone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid STUDIO_TIME_ZONE: %w", err)
+	}
+	templates, err := parseTemplates(location)
+	if err != nil {
+		return nil, err
+	}
+	textTemplates, err := parseTextTemplates(location)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Notifier{
+		store:         store,
+		secrets:       newSecretBox(cfg.JWTSecret),
+		templates:     templates,
+		textTemplates: textTemplates,
+		studioName:    cfg.StudioName,
+		studioEmail:   cfg.StudioEmail,
+		manageURL:     cfg.ManageURL,
+		resetURL:      cfg.PasswordResetURL,
+	}, nil
+}
+
+// Appointment queues the messages for event to the customer, and an email
+// to the studio for new bookings and cancellations. Failures are logged
+// rather than returned.
+func (n *Notifier) Appointment(ctx context.Context, event string, appointment *models.Appointment, details Details) {
+	data := messageData{
+		Studio:        n.studioName,
+		Name:          appointment.Name,
+		Appointment:   appointment,
+		Reason:        stringValue(appointment.CancellationReason),
+		HoldExpiresAt: details.HoldExpiresAt,
+		ManageURL:     n.manageLink("appointments", details.ManageToken),
+	}
+
+	n.queueCustomer(ctx, event, "appointment", appointment.ID, appointment.Email, appointment.Phone, appointment.Channels, data, details.ManageToken)
+	if n.studioEmail != "" && studioEvents[event] {
+		data.ManageURL = ""
+		n.queueEmail(ctx, "studio."+event, "studio_appointment", "appointment", appointment.ID, n.studioEmail, data, "")
+	}
+}
+
+// Enrollment queues the messages for event about a class enrollment, like
+// Appointment.
+func (n *Notifier) Enrollment(ctx context.Context, event string, class *models.Class, details Details) {
+	data := messageData{
+		Studio:        n.studioName,
+		Name:          class.Name,
+		Class:         class,
+		Reason:        stringValue(class.CancellationReason),
+		HoldExpiresAt: details.HoldExpiresAt,
+		SessionStart:  details.SessionStart,
+		ManageURL:     n.manageLink("classes", details.ManageToken),
+	}
+	if class.CohortID != nil {
+		cohort, err := n.store.GetCohort(ctx, *class.CohortID)
+		if err != nil {
+			log.Printf("Error getting cohort %d for %s email: %v", *class.CohortID, event, err)
+		} else {
+			data.Cohort = cohort
+		}
+	}
+
+	n.queueCustomer(ctx, event, "class", class.ID, class.Email, class.Phone, class.Channels, data, details.ManageToken)
+	if n.studioEmail != "" && studioEvents[event] {
+		data.ManageURL = ""
+		n.queueEmail(ctx, "studio."+event, "studio_enrollment", "class", class.ID, n.studioEmail, data, "")
+	}
+}
+
+// PasswordReset queues the email with an admin's password reset token, made
+// by the reset with ID resetID. Failures are logged rather than returned.
+func (n *Notifier) PasswordReset(ctx context.Context, admin *models.AdminUser, resetID int, token string, expiresAt time.Time) {
+	data := messageData{
+		Studio:         n.studioName,
+		Name:           admin.Name,
+		ResetExpiresAt: &expiresAt,
+	}
+	if n.resetURL != "" {
+		data.ResetURL = strings.ReplaceAll(n.resetURL, "{token}", secretPlaceholder)
+	} else {
+		data.ResetToken = secretPlaceholder
+	}
+
+	n.queueEmail(ctx, AdminPasswordReset, templateName(AdminPasswordReset), "password_reset", resetID, admin.Email, data, token)
+}
+
+// queueCustomer queues event for the customer on each of the booking's
+// channels, or their own when the booking has none. Text messages go to
+// phone, and are skipped when there is none.
+func (n *Notifier) queueCustomer(ctx context.Context, event, resourceType string, resourceID int, email, phone string, channels []string, data messageData, secret string) {
+	if len(channels) == 0 {
+		channels = n.customerChannels(ctx, email)
+	}
+	for _, channel := range channels {
+		switch channel {
+		case "email":
+			n.queueEmail(ctx, event, templateName(event), resourceType, resourceID, email, data, secret)
+		case ChannelSMS, ChannelWhatsApp:
+			if strings.TrimSpace(phone) != "" {
+				n.queueText(ctx, channel, event, resourceType, resourceID, phone, data, secret)
+			}
+		}
+	}
+}
+
+// customerChannels returns the channels the customer with email has chosen,
+// falling back to email when they haven't chosen or the lookup fails.
+func (n *Notifier) customerChannels(ctx context.Context, email string) []string {
+	preference, err := n.store.GetNotificationPreference(ctx, email)
+	if err != nil {
+		if !errors.Is(err, database.ErrPreferenceNotFound) {
+			log.Printf("Error getting notification preference for %s: %v", email, err)
+		}
+		return []string{"email"}
+	}
+	return preference.Channels
+}
+
+// queueText renders event's text template into an outbox row for recipient
+// on channel, with secret sealed for the Worker to fill in. Events without a
+// text template are skipped.
+func (n *Notifier) queueText(ctx context.Context, channel, event, resourceType string, resourceID int, recipient string, data messageData, secret string) {
+	t, ok := n.textTemplates[templateName(event)]
+	if !ok {
+		return
+	}
+
+	body, err := renderText(t, data)
+	if err != nil {
+		log.Printf("Error rendering %s %s message for %s %d: %v", event, channel, resourceType, resourceID, err)
+		return
+	}
+	sealed, err := n.sealSecret(body, secret)
+	if err != nil {
+		log.Printf("Error sealing %s %s message for %s %d: %v", event, channel, resourceType, resourceID, err)
+		return
+	}
+
+	_, err = n.store.CreateNotification(ctx, &models.Notification{
+		Channel:      channel,
+		Event:        event,
+		ResourceType: resourceType,
+		ResourceID:   resourceID,
+		Recipient:    recipient,
+		Body:         body,
+		Secret:       sealed,
+	})
+	if err != nil {
+		log.Printf("Error queueing %s %s message for %s %d: %v", event, channel, resourceType, resourceID, err)
+	}
+}
+
+// queueEmail renders the named template into an outbox row for recipient,
+// like queueText. Events without a template are skipped.
+func (n *Notifier) queueEmail(ctx context.Context, event, name, resourceType string, resourceID int, recipient string, data messageData, secret string) {
+	t, ok := n.templates[name]
+	if !ok {
+		return
+	}
+
+	subject, body, err := render(t, data)
+	if err != nil {
+		log.Printf("Error rendering %s email for %s %d: %v", event, resourceType, resourceID, err)
+		return
+	}
+	sealed, err := n.sealSecret(body, secret)
+	if err != nil {
+		log.Printf("Error sealing %s email for %s %d: %v", event, resourceType, resourceID, err)
+		return
+	}
+
+	_, err = n.store.CreateNotification(ctx, &models.Notification{
+		Channel:      "email",
+		Event:        event,
+		ResourceType: resourceType,
+		ResourceID:   resourceID,
+		Recipient:    recipient,
+		Subject:      subject,
+		Body:         body,
+		Secret:       sealed,
+	})
+	if err != nil {
+		log.Printf("Error queueing %s email for %s %d: %v", event, resourceType, resourceID, err)
+	}
+}
+
+// templateName is the template an event's customer messages are rendered
+// from.
+func templateName(event string) string {
+	return strings.Replace(event, ".", "_", 1)
+}
+
+// manageLink fills in ManageURL for a booking, with the placeholder in
+// place of its token, or returns "" when either is missing.
+func (n *Notifier) manageLink(kind, token string) string {
+	if n.manageURL == "" || token == "" {
+		return ""
+	}
+	link := strings.ReplaceAll(n.manageURL, "{kind}", kind)
+	return strings.ReplaceAll(link, "{token}", secretPlaceholder)
+}
+
+// sealSecret returns secret sealed for a message with body, or "" if the
+// body has no placeholder to fill it into.
+func (n *Notifier) sealSecret(body, secret string) (string, error) {
+	if secret == "" || !strings.Contains(body, secretPlaceholder) {
+		return "", nil
+	}
+	return n.secrets.seal(secret)
+}
+
+func stringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
@@ -0,0 +1,210 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"mumuni_backend/config"
+	"mumuni_backend/database"
+	"mumuni_backend/models"
+)
+
+// recordingMailer keeps the emails it is asked to send, failing with err
+// if it is set.
+type recordingMailer struct {
+	mu   sync.Mutex
+	sent []Message
+	err  error
+}
+
+func (m *recordingMailer) Send(ctx context.Context, msg Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.err != nil {
+		return m.err
+	}
+	m.sent = append(m.sent, msg)
+	return nil
+}
+
+func testConfig() *config.Config {
+	return &config.Config{
+		JWTSecret:      "test-secret",
+		StudioTimeZone: "Africa/Lagos",
+		StudioName:     "Mumuni",
+		StudioEmail:    "studio@mumuni.com",
+		ManageURL:      "https://mumuni.com/manage/{kind}/{token}",
+	}
+}
+
+func testAppointment() *models.Appointment {
+	return &models.Appointment{
+		ID:              12,
+		Name:            "Ada",
+		Email:           "ada@example.com",
+		Phone:           "+2348031112222",
+		AppointmentDate: "2030-01-07",
+		AppointmentTime: "10:00 AM",
+		Service:         "Bridal Makeup",
+		Status:          "pending",
+	}
+}
+
+func outbox(t *testing.T, store database.Store) []models.Notification {
+	t.Helper()
+	notifications, err := store.GetNotifications(context.Background(), database.NotificationFilter{})
+	if err != nil {
+		t.Fatalf("GetNotifications: %v", err)
+	}
+	return notifications
+}
+
+func TestNotifierQueuesCustomerAndStudioEmails(t *testing.T) {
+	store := database.NewMemoryStore()
+	notifier, err := NewNotifier(testConfig(), store)
+	if err != nil {
+		t.Fatalf("NewNotifier: %v", err)
+	}
+
+	notifier.Appointment(context.Background(), AppointmentReceived, testAppointment(), Details{})
+
+	notifications := outbox(t, store)
+	if len(notifications) != 2 {
+		t.Fatalf("queued %d notifications, want the customer's and the studio's", len(notifications))
+	}
+	customer, studio := notifications[0], notifications[1]
+	if customer.Recipient != "ada@example.com" || customer.Event != AppointmentReceived || customer.Status != "pending" {
+		t.Errorf("customer notification = %+v", customer)
+	}
+	if !strings.Contains(customer.Body, "Monday, 7 January 2030") || !strings.Contains(customer.Subject, "Bridal Makeup") {
+		t.Errorf("customer email subject %q, body %q, want the service and date", customer.Subject, customer.Body)
+	}
+	if studio.Recipient != "studio@mumuni.com" || studio.Event != "studio."+AppointmentReceived {
+		t.Errorf("studio notification = %+v", studio)
+	}
+}
+
+func TestManageTokenOnlyFilledInWhenSent(t *testing.T) {
+	store := database.NewMemoryStore()
+	cfg := testConfig()
+	cfg.StudioEmail = ""
+	notifier, err := NewNotifier(cfg, store)
+	if err != nil {
+		t.Fatalf("NewNotifier: %v", err)
+	}
+	const token = "manage-token-abc123"
+	notifier.Appointment(context.Background(), AppointmentReceived, testAppointment(), Details{ManageToken: token})
+
+	queued := outbox(t, store)
+	if len(queued) != 1 {
+		t.Fatalf("queued %d notifications, want 1", len(queued))
+	}
+	if strings.Contains(queued[0].Body, token) || strings.Contains(queued[0].Secret, token) {
+		t.Fatalf("the outbox holds the manage token readable: body %q, secret %q", queued[0].Body, queued[0].Secret)
+	}
+	if queued[0].Secret == "" {
+		t.Fatal("the manage token wasn't kept for sending")
+	}
+
+	mailer := &recordingMailer{}
+	NewWorker(store, mailer, &FakeMessenger{}, cfg.JWTSecret).Deliver(context.Background())
+
+	if len(mailer.sent) != 1 {
+		t.Fatalf("sent %d emails, want 1", len(mailer.sent))
+	}
+	if want := "https://mumuni.com/manage/appointments/" + token; !strings.Contains(mailer.sent[0].HTML, want) {
+		t.Errorf("sent email %q doesn't link to %s", mailer.sent[0].HTML, want)
+	}
+	sent := outbox(t, store)[0]
+	if sent.Status != "sent" || sent.Secret != "" || strings.Contains(sent.Body, token) {
+		t.Errorf("after sending: status %q, secret %q, body %q; want sent with the secret cleared", sent.Status, sent.Secret, sent.Body)
+	}
+}
+
+func TestManageTokenNeedsTheSameKey(t *testing.T) {
+	store := database.NewMemoryStore()
+	notifier, _ := NewNotifier(testConfig(), store)
+	notifier.Appointment(context.Background(), AppointmentReceived, testAppointment(), Details{ManageToken: "tok"})
+
+	mailer := &recordingMailer{}
+	NewWorker(store, mailer, &FakeMessenger{}, "another-secret").Deliver(context.Background())
+
+	for _, n := range outbox(t, store) {
+		if n.Recipient == "ada@example.com" && (n.Status != "pending" || n.LastError == nil) {
+			t.Errorf("message with an unreadable secret: status %q, last error %v; want it retried", n.Status, n.LastError)
+		}
+	}
+	for _, msg := range mailer.sent {
+		if msg.To == "ada@example.com" {
+			t.Errorf("sent %q without its manage link", msg.HTML)
+		}
+	}
+}
+
+func TestWorkerRetriesFailedEmails(t *testing.T) {
+	store := database.NewMemoryStore()
+	notifier, _ := NewNotifier(testConfig(), store)
+	notifier.Appointment(context.Background(), AppointmentConfirmed, testAppointment(), Details{})
+
+	mailer := &recordingMailer{err: errors.New("connection refused")}
+	start := time.Now()
+	NewWorker(store, mailer, &FakeMessenger{}, "test-secret").Deliver(context.Background())
+
+	n := outbox(t, store)[0]
+	if n.Status != "pending" || n.Attempts != 1 || n.LastError == nil || *n.LastError != "connection refused" {
+		t.Fatalf("after a failed send: %+v, want pending with the error", n)
+	}
+	if !n.NextAttemptAt.After(start.Add(30 * time.Second)) {
+		t.Errorf("next attempt at %s, want it backed off", n.NextAttemptAt)
+	}
+
+	// Once the mail server is back the message goes out on its next attempt
+	n.NextAttemptAt = time.Now()
+	store.UpdateNotification(context.Background(), &n)
+	mailer.err = nil
+	NewWorker(store, mailer, &FakeMessenger{}, "test-secret").Deliver(context.Background())
+	if n := outbox(t, store)[0]; n.Status != "sent" || n.Attempts != 2 {
+		t.Errorf("after retrying: status %q after %d attempts, want sent after 2", n.Status, n.Attempts)
+	}
+}
+
+func TestPasswordResetTokenOnlyFilledInWhenSent(t *testing.T) {
+	store := database.NewMemoryStore()
+	notifier, _ := NewNotifier(testConfig(), store)
+	const token = "reset-token-xyz"
+	notifier.PasswordReset(context.Background(), &models.AdminUser{Name: "Owner", Email: "owner@mumuni.com"}, 1, token, time.Now().Add(time.Hour))
+
+	if queued := outbox(t, store)[0]; strings.Contains(queued.Body, token) {
+		t.Fatalf("the outbox holds the reset token readable: %q", queued.Body)
+	}
+	mailer := &recordingMailer{}
+	NewWorker(store, mailer, &FakeMessenger{}, "test-secret").Deliver(context.Background())
+	if len(mailer.sent) != 1 || !strings.Contains(mailer.sent[0].HTML, token) {
+		t.Errorf("sent %+v, want the reset email with its token", mailer.sent)
+	}
+}
+
+func TestRedact(t *testing.T) {
+	tests := []struct {
+		notification models.Notification
+		hidden       bool
+	}{
+		{models.Notification{ResourceType: "password_reset", Event: AdminPasswordReset}, true},
+		{models.Notification{ResourceType: "appointment", Event: AppointmentReceived}, true},
+		{models.Notification{ResourceType: "class", Event: EnrollmentWaitlisted}, true},
+		{models.Notification{ResourceType: "appointment", Event: AppointmentConfirmed}, false},
+		{models.Notification{ResourceType: "appointment", Event: "studio." + AppointmentReceived}, false},
+	}
+	for _, tt := range tests {
+		n := tt.notification
+		n.Body = "body"
+		Redact(&n)
+		if hidden := n.Body == ""; hidden != tt.hidden {
+			t.Errorf("Redact(%s %s) hid the body: %v, want %v", n.ResourceType, n.Event, hidden, tt.hidden)
+		}
+	}
+}
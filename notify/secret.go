@@ -0,0 +1,52 @@
+package notify
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+)
+
+// secretPlaceholder stands in a stored message for its secret, such as the
+// manage token in a manage link. The Worker fills the secret in as it sends
+// the message, so the outbox never holds it readable.
+const secretPlaceholder = "__secret__"
+
+// secretBox seals message secrets with a key derived from the server's
+// secret, so they are unreadable to anyone with only the database.
+type secretBox struct {
+	aead cipher.AEAD
+}
+
+func newSecretBox(key string) *secretBox {
+	sum := sha256.Sum256([]byte("mumuni notification secret\x00" + key))
+	// AES-256 and GCM can't fail with a 32-byte key
+	block, _ := aes.NewCipher(sum[:])
+	aead, _ := cipher.NewGCM(block)
+	return &secretBox{aead: aead}
+}
+
+// seal returns secret encrypted and encoded for storage.
+func (b *secretBox) seal(secret string) (string, error) {
+	nonce := make([]byte, b.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := b.aead.Seal(nonce, nonce, []byte(secret), nil)
+	return base64.RawStdEncoding.EncodeToString(sealed), nil
+}
+
+// open returns the secret sealed by seal.
+func (b *secretBox) open(sealed string) (string, error) {
+	data, err := base64.RawStdEncoding.DecodeString(sealed)
+	if err != nil || len(data) < b.aead.NonceSize() {
+		return "", errors.New("malformed message secret")
+	}
+	secret, err := b.aead.Open(nil, data[:b.aead.NonceSize()], data[b.aead.NonceSize():], nil)
+	if err != nil {
+		return "", errors.New("message secret can't be opened; was JWT_SECRET changed?")
+	}
+	return string(secret), nil
+}
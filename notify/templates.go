@@ -0,0 +1,127 @@
+package notify
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"html"
+	"html/template"
+	"io/fs"
+	"mumuni_backend/models"
+	"path"
+	"strings"
+	texttemplate "text/template"
+	"time"
+)
+
+//go:embed templates/*.html templates/text/*.txt
+var templateFiles embed.FS
+
+// messageData is what the email and text templates are rendered with.
+// Appointment or Class is set depending on what the message is about, if
+// it is about a booking.
+type messageData struct {
+	Studio        string
+	Name          string
+	Appointment   *models.Appointment
+	Class         *models.Class
+	Cohort        *models.Cohort
+	Reason        string
+	HoldExpiresAt *time.Time
+	SessionStart  *time.Time
+	ManageURL     string
+	// ResetURL, or ResetToken when there is no reset page to link to, lets
+	// an admin set a new password before ResetExpiresAt.
+	ResetURL       string
+	ResetToken     string
+	ResetExpiresAt *time.Time
+}
+
+// templateFuncs returns the template helpers, showing times in location.
+func templateFuncs(location *time.Location) template.FuncMap {
+	return template.FuncMap{
+		// longDate renders a YYYY-MM-DD date as e.g. "Sunday, 18 October 2026"
+		"longDate": func(date string) string {
+			d, err := time.Parse("2006-01-02", date)
+			if err != nil {
+				return date
+			}
+			return d.Format("Monday, 2 January 2006")
+		},
+		"longTime": func(t *time.Time) string {
+			return t.In(location).Format("Monday, 2 January 2006 at 3:04 PM")
+		},
+	}
+}
+
+// parseTemplates parses every email in templates/ together with the shared
+// layout, keyed by file name without the extension. Each email defines a
+// "subject" and a "content" template. Times are shown in location.
+func parseTemplates(location *time.Location) (map[string]*template.Template, error) {
+	pages, err := fs.Glob(templateFiles, "templates/*.html")
+	if err != nil {
+		return nil, err
+	}
+
+	templates := make(map[string]*template.Template)
+	for _, page := range pages {
+		name := strings.TrimSuffix(path.Base(page), ".html")
+		if name == "layout" {
+			continue
+		}
+
+		t, err := template.New("layout.html").Funcs(templateFuncs(location)).ParseFS(templateFiles, "templates/layout.html", page)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse email template %s: %w", name, err)
+		}
+		templates[name] = t
+	}
+
+	return templates, nil
+}
+
+// parseTextTemplates parses the plain text messages in templates/text/ sent
+// by SMS and WhatsApp, keyed like parseTemplates.
+func parseTextTemplates(location *time.Location) (map[string]*texttemplate.Template, error) {
+	pages, err := fs.Glob(templateFiles, "templates/text/*.txt")
+	if err != nil {
+		return nil, err
+	}
+
+	templates := make(map[string]*texttemplate.Template)
+	for _, page := range pages {
+		name := strings.TrimSuffix(path.Base(page), ".txt")
+		t, err := texttemplate.New(path.Base(page)).Funcs(texttemplate.FuncMap(templateFuncs(location))).ParseFS(templateFiles, page)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse text template %s: %w", name, err)
+		}
+		templates[name] = t
+	}
+
+	return templates, nil
+}
+
+// renderText executes t with data and returns the message text.
+func renderText(t *texttemplate.Template, data messageData) (string, error) {
+	var text bytes.Buffer
+	if err := t.Execute(&text, data); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(text.String()), nil
+}
+
+// render executes t with data and returns the subject line and HTML body.
+func render(t *template.Template, data messageData) (string, string, error) {
+	var subject bytes.Buffer
+	if err := t.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return "", "", err
+	}
+
+	var body bytes.Buffer
+	if err := t.Execute(&body, data); err != nil {
+		return "", "", err
+	}
+
+	// The subject goes in a mail header, not HTML
+	return html.UnescapeString(strings.TrimSpace(subject.String())), body.String(), nil
+}
@@ -0,0 +1,194 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"mumuni_backend/database"
+	"mumuni_backend/models"
+	"strings"
+	"time"
+)
+
+const (
+	// maxAttempts is how many times a notification is tried before it is
+	// marked "failed".
+	maxAttempts = 8
+	// claimLease keeps a claimed notification from being picked up by
+	// another instance while it is being delivered.
+	claimLease = 5 * time.Minute
+	// batchSize is how many notifications one pass claims.
+	batchSize = 20
+)
+
+// Worker delivers the notification outbox, emails through mailer and text
+// messages through messenger. With a nil messenger text messages are marked
+// failed without being sent. Message secrets are opened with the key the
+// Notifier sealed them with, derived from JWT_SECRET.
+type Worker struct {
+	store     database.Store
+	mailer    Mailer
+	messenger Messenger
+	secrets   *secretBox
+}
+
+func NewWorker(store database.Store, mailer Mailer, messenger Messenger, secretKey string) *Worker {
+	return &Worker{store: store, mailer: mailer, messenger: messenger, secrets: newSecretBox(secretKey)}
+}
+
+// Run delivers due notifications every interval until ctx is cancelled.
+func (w *Worker) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		w.Deliver(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Deliver sends every notification that is due, batch by batch. Failed sends
+// are retried with exponential backoff, starting at a minute and capped at
+// an hour.
+func (w *Worker) Deliver(ctx context.Context) {
+	for {
+		notifications, err := w.store.ClaimNotifications(ctx, time.Now(), claimLease, batchSize)
+		if err != nil {
+			log.Printf("Error claiming notifications: %v", err)
+			return
+		}
+
+		for i := range notifications {
+			w.deliver(ctx, &notifications[i])
+		}
+		if len(notifications) < batchSize {
+			return
+		}
+	}
+}
+
+func (w *Worker) deliver(ctx context.Context, notification *models.Notification) {
+	providerMessageID, err := w.send(ctx, notification)
+
+	now := time.Now().UTC()
+	if err == nil {
+		notification.Status = "sent"
+		notification.SentAt = &now
+		notification.LastError = nil
+		notification.Secret = ""
+		if providerMessageID != "" {
+			notification.ProviderMessageID = &providerMessageID
+		}
+	} else {
+		message := err.Error()
+		notification.LastError = &message
+		switch {
+		case errors.Is(err, ErrNoMessenger):
+			notification.Status = "failed"
+			notification.Secret = ""
+		case notification.Attempts >= maxAttempts:
+			notification.Status = "failed"
+			notification.Secret = ""
+			log.Printf("Giving up on notification %d to %s after %d attempts: %v", notification.ID, notification.Recipient, notification.Attempts, err)
+		default:
+			notification.NextAttemptAt = now.Add(retryDelay(notification.Attempts))
+			log.Printf("Error sending notification %d to %s, retrying at %s: %v", notification.ID, notification.Recipient,
+				notification.NextAttemptAt.Format(time.RFC3339), err)
+		}
+	}
+
+	if _, err := w.store.UpdateNotification(ctx, notification); err != nil {
+		log.Printf("Error updating notification %d: %v", notification.ID, err)
+	}
+}
+
+// send delivers notification on its channel, with its secret filled in, and
+// returns the messaging provider's message ID, if any.
+func (w *Worker) send(ctx context.Context, notification *models.Notification) (string, error) {
+	body := notification.Body
+	if notification.Secret != "" {
+		secret, err := w.secrets.open(notification.Secret)
+		if err != nil {
+			return "", err
+		}
+		body = strings.ReplaceAll(body, secretPlaceholder, secret)
+	}
+
+	switch notification.Channel {
+	case "email":
+		return "", w.mailer.Send(ctx, Message{
+			To:      notification.Recipient,
+			Subject: notification.Subject,
+			HTML:    body,
+		})
+	case ChannelSMS, ChannelWhatsApp:
+		if w.messenger == nil {
+			return "", ErrNoMessenger
+		}
+		return w.messenger.Send(ctx, TextMessage{
+			Channel: notification.Channel,
+			To:      notification.Recipient,
+			Body:    body,
+		})
+	default:
+		return "", fmt.Errorf("unsupported channel %q", notification.Channel)
+	}
+}
+
+// ErrNoMessenger is the error text messages are failed with when no
+// messaging provider is configured.
+var ErrNoMessenger = errors.New("no messaging provider configured")
+
+// retryDelay is how long to wait after the given number of failed attempts.
+func retryDelay(attempts int) time.Duration {
+	delay := time.Minute << (attempts - 1)
+	if delay > time.Hour || delay <= 0 {
+		return time.Hour
+	}
+	return delay
+}
+
+// ErrUnknownDeliveryStatus is returned by RecordDelivery for a status it
+// doesn't understand.
+var ErrUnknownDeliveryStatus = errors.New("unknown delivery status")
+
+// RecordDelivery applies a messaging provider's delivery report to the
+// message it is about: "delivered" marks it delivered, and "failed" or
+// "undelivered" marks it failed with the reported error, and "queued" or
+// "sent" change nothing. database.ErrNotificationNotFound is returned for
+// unknown IDs.
+func RecordDelivery(ctx context.Context, store database.Store, report *models.DeliveryStatusRequest) (*models.Notification, error) {
+	notifications, err := store.GetNotifications(ctx, database.NotificationFilter{ProviderMessageID: report.ID})
+	if err != nil {
+		return nil, err
+	}
+	if len(notifications) == 0 {
+		return nil, database.ErrNotificationNotFound
+	}
+	notification := &notifications[0]
+
+	switch report.Status {
+	case "queued", "sent":
+		return notification, nil
+	case "delivered":
+		now := time.Now().UTC()
+		notification.Status = "delivered"
+		notification.DeliveredAt = &now
+		notification.LastError = nil
+	case "failed", "undelivered":
+		message := "undelivered"
+		if report.Error != nil && *report.Error != "" {
+			message = *report.Error
+		}
+		notification.Status = "failed"
+		notification.LastError = &message
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownDeliveryStatus, report.Status)
+	}
+
+	return store.UpdateNotification(ctx, notification)
+}
@@ -0,0 +1,171 @@
+package phone
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// E.164 numbers have at most 15 digits, country code included. Numbers in
+// countries missing from the table below need at least minDigits.
+const (
+	maxDigits = 15
+	minDigits = 8
+)
+
+// country describes how phone numbers are written in one country.
+type country struct {
+	// callingCode is the international country code, like "234".
+	callingCode string
+	// trunkPrefix is dialled before a national number from inside the
+	// country, like the 0 of 0803 in Nigeria. It isn't part of the number.
+	trunkPrefix string
+	// minLength and maxLength bound the number of digits after the calling
+	// code and without the trunk prefix.
+	minLength, maxLength int
+}
+
+// countries are the countries whose numbers are checked for length, keyed by
+// ISO 3166 code. Numbers elsewhere only need a plausible length overall.
+var countries = map[string]country{
+	"NG": {callingCode: "234", trunkPrefix: "0", minLength: 8, maxLength: 10},
+	"GH": {callingCode: "233", trunkPrefix: "0", minLength: 9, maxLength: 9},
+	"KE": {callingCode: "254", trunkPrefix: "0", minLength: 9, maxLength: 9},
+	"ZA": {callingCode: "27", trunkPrefix: "0", minLength: 9, maxLength: 9},
+	"CM": {callingCode: "237", minLength: 9, maxLength: 9},
+	"SN": {callingCode: "221", minLength: 9, maxLength: 9},
+	"CI": {callingCode: "225", minLength: 10, maxLength: 10},
+	"AE": {callingCode: "971", trunkPrefix: "0", minLength: 8, maxLength: 9},
+	"GB": {callingCode: "44", trunkPrefix: "0", minLength: 9, maxLength: 10},
+	"IE": {callingCode: "353", trunkPrefix: "0", minLength: 7, maxLength: 9},
+	"FR": {callingCode: "33", trunkPrefix: "0", minLength: 9, maxLength: 9},
+	"DE": {callingCode: "49", trunkPrefix: "0", minLength: 6, maxLength: 13},
+	"NL": {callingCode: "31", trunkPrefix: "0", minLength: 9, maxLength: 9},
+	"ES": {callingCode: "34", minLength: 9, maxLength: 9},
+	"US": {callingCode: "1", trunkPrefix: "1", minLength: 10, maxLength: 10},
+	"CA": {callingCode: "1", trunkPrefix: "1", minLength: 10, maxLength: 10},
+	"IN": {callingCode: "91", trunkPrefix: "0", minLength: 10, maxLength: 10},
+	"AU": {callingCode: "61", trunkPrefix: "0", minLength: 9, maxLength: 9},
+}
+
+// Countries lists the ISO 3166 codes a Parser can default to.
+func Countries() []string {
+	codes := make([]string, 0, len(countries))
+	for code := range countries {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	return codes
+}
+
+// Parser normalizes phone numbers to E.164, like +2348031112222. Numbers
+// written without a country code are read as numbers in its default
+// country.
+type Parser struct {
+	country country
+}
+
+// NewParser returns a parser defaulting to the country with the ISO 3166
+// code defaultCountry, like "NG".
+func NewParser(defaultCountry string) (*Parser, error) {
+	c, ok := countries[strings.ToUpper(strings.TrimSpace(defaultCountry))]
+	if !ok {
+		return nil, fmt.Errorf("unknown country %q. Must be one of: %s", defaultCountry, strings.Join(Countries(), ", "))
+	}
+	return &Parser{country: c}, nil
+}
+
+// Normalize returns raw in E.164 form. Spaces, dashes, dots and parentheses
+// are ignored, and a leading + or 00 marks an international number. Any
+// error explains what is wrong with the number.
+func (p *Parser) Normalize(raw string) (string, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return "", fmt.Errorf("phone number is empty")
+	}
+
+	international := strings.HasPrefix(raw, "+")
+	var digits strings.Builder
+	for i, r := range raw {
+		switch {
+		case r >= '0' && r <= '9':
+			digits.WriteRune(r)
+		case r == '+' && i == 0:
+		case r == ' ' || r == '-' || r == '.' || r == '(' || r == ')':
+		default:
+			return "", fmt.Errorf("phone number may only contain digits, spaces, dashes, dots, parentheses and a leading +")
+		}
+	}
+	number := digits.String()
+	if !international && strings.HasPrefix(number, "00") {
+		international = true
+		number = number[2:]
+	}
+
+	if international {
+		return normalizeInternational(number)
+	}
+	return p.normalizeNational(number)
+}
+
+// normalizeInternational checks digits that start with a calling code. A
+// trunk prefix after the calling code, as in +234 (0)803, is dropped.
+func normalizeInternational(digits string) (string, error) {
+	if c, national, ok := findCallingCode(digits); ok {
+		if c.trunkPrefix != "" {
+			national = strings.TrimPrefix(national, c.trunkPrefix)
+		}
+		if err := c.checkLength(national); err != nil {
+			return "", err
+		}
+		return "+" + c.callingCode + national, nil
+	}
+	if digits == "" || digits[0] == '0' {
+		return "", fmt.Errorf("phone number has no country code after the +")
+	}
+	if len(digits) < minDigits || len(digits) > maxDigits {
+		return "", fmt.Errorf("international phone numbers have %d to %d digits", minDigits, maxDigits)
+	}
+	return "+" + digits, nil
+}
+
+// normalizeNational checks digits written without a calling code, as they
+// would be dialled in the parser's country. Numbers that start with the
+// country's calling code but left out the + are taken as international.
+func (p *Parser) normalizeNational(digits string) (string, error) {
+	c := p.country
+	national := digits
+	if c.trunkPrefix != "" {
+		national = strings.TrimPrefix(national, c.trunkPrefix)
+	}
+	if err := c.checkLength(national); err != nil {
+		if rest, ok := strings.CutPrefix(digits, c.callingCode); ok && c.checkLength(rest) == nil {
+			return "+" + digits, nil
+		}
+		return "", err
+	}
+	return "+" + c.callingCode + national, nil
+}
+
+// findCallingCode finds the table country whose calling code digits start
+// with, returning the digits after it.
+func findCallingCode(digits string) (country, string, bool) {
+	for n := 1; n <= 3 && n <= len(digits); n++ {
+		for _, c := range countries {
+			if c.callingCode == digits[:n] {
+				return c, digits[n:], true
+			}
+		}
+	}
+	return country{}, "", false
+}
+
+func (c country) checkLength(national string) error {
+	if len(national) >= c.minLength && len(national) <= c.maxLength {
+		return nil
+	}
+	if c.minLength == c.maxLength {
+		return fmt.Errorf("+%s phone numbers have %d digits after the country code", c.callingCode, c.minLength)
+	}
+	return fmt.Errorf("+%s phone numbers have %d to %d digits after the country code", c.callingCode, c.minLength, c.maxLength)
+}
@@ -0,0 +1,90 @@
+package phone
+
+import "testing"
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		country string
+		raw     string
+		want    string // empty when raw is refused
+	}{
+		// Written as dialled in the default country
+		{"NG", "0803 111 2222", "+2348031112222"},
+		{"NG", "803-111-2222", "+2348031112222"},
+		{"NG", "(0803) 111.2222", "+2348031112222"},
+		{"GB", "020 7946 0958", "+442079460958"},
+		{"GB", "07700 900123", "+447700900123"},
+		{"US", "(202) 555-0100", "+12025550100"},
+		{"US", "1 202 555 0100", "+12025550100"},
+		{"GH", "024 123 4567", "+233241234567"},
+		// The default country's calling code without the +
+		{"NG", "234 803 111 2222", "+2348031112222"},
+		// A leading + or 00 is international, whatever the default
+		{"NG", "+234 803 111 2222", "+2348031112222"},
+		{"NG", "002348031112222", "+2348031112222"},
+		{"NG", " +44 20 7946 0958 ", "+442079460958"},
+		{"NG", "0044 20 7946 0958", "+442079460958"},
+		{"GB", "+1 (202) 555-0100", "+12025550100"},
+		// The trunk prefix some write after the calling code is dropped
+		{"NG", "+234 (0)803 111 2222", "+2348031112222"},
+		{"GB", "+44 (0)20 7946 0958", "+442079460958"},
+		// Countries missing from the table only need a plausible length
+		{"NG", "+380 44 123 4567", "+380441234567"},
+		// Extensions have no place in E.164
+		{"NG", "0803 111 2222 ext. 12", ""},
+		{"NG", "0803 111 2222 x12", ""},
+		{"NG", "+234 803 111 2222;ext=12", ""},
+		{"US", "(202) 555-0100 #4", ""},
+		// Invalid input
+		{"NG", "", ""},
+		{"NG", "   ", ""},
+		{"NG", "0803-CALL-NOW", ""},
+		{"NG", "0803+1112222", ""},
+		{"NG", "0803 111", ""},
+		{"NG", "0803 111 2222 333", ""},
+		{"NG", "+234 803 111", ""},
+		{"NG", "+0803 111 2222", ""},
+		{"NG", "+", ""},
+		{"NG", "+999 1234", ""},
+		{"NG", "+1234567890123456", ""},
+		{"GH", "024 123 45", ""},
+	}
+	for _, tt := range tests {
+		p, err := NewParser(tt.country)
+		if err != nil {
+			t.Fatalf("NewParser(%s): %v", tt.country, err)
+		}
+		got, err := p.Normalize(tt.raw)
+		if tt.want == "" {
+			if err == nil {
+				t.Errorf("%s: Normalize(%q) = %q, want an error", tt.country, tt.raw, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("%s: Normalize(%q) = %q, %v; want %q", tt.country, tt.raw, got, err, tt.want)
+		}
+	}
+}
+
+func TestNormalizeIsIdempotent(t *testing.T) {
+	p, _ := NewParser("NG")
+	for _, number := range []string{"+2348031112222", "+442079460958", "+12025550100", "+380441234567"} {
+		if got, err := p.Normalize(number); err != nil || got != number {
+			t.Errorf("Normalize(%q) = %q, %v; want it unchanged", number, got, err)
+		}
+	}
+}
+
+func TestNewParser(t *testing.T) {
+	for _, code := range []string{"NG", "ng", " GB "} {
+		if _, err := NewParser(code); err != nil {
+			t.Errorf("NewParser(%q): %v", code, err)
+		}
+	}
+	for _, code := range []string{"", "XX", "Nigeria", "234"} {
+		if _, err := NewParser(code); err == nil {
+			t.Errorf("NewParser(%q) succeeded, want an error", code)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Limit is a token bucket holding up to Burst tokens, refilled at Burst
+// tokens every Per. Each request takes a token. The zero Limit allows
+// everything.
+type Limit struct {
+	Burst int
+	Per   time.Duration
+}
+
+// ParseLimit parses a limit written as "<burst>/<duration>", like "10/1h"
+// for ten requests an hour. An empty string or "0" disables the limit.
+func ParseLimit(s string) (Limit, error) {
+	s = strings.TrimSpace(s)
+	if s == "" || s == "0" {
+		return Limit{}, nil
+	}
+
+	burst, per, ok := strings.Cut(s, "/")
+	if !ok {
+		return Limit{}, fmt.Errorf("limit %q must look like 10/1h", s)
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(burst))
+	if err != nil || n <= 0 {
+		return Limit{}, fmt.Errorf("limit %q must start with a positive number of requests", s)
+	}
+	d, err := time.ParseDuration(strings.TrimSpace(per))
+	if err != nil || d <= 0 {
+		return Limit{}, fmt.Errorf("limit %q must end with a positive duration", s)
+	}
+	return Limit{Burst: n, Per: d}, nil
+}
+
+// Enabled reports whether the limit refuses anything.
+func (l Limit) Enabled() bool {
+	return l.Burst > 0 && l.Per > 0
+}
+
+// Bucket is the state of one key's token bucket. The zero Bucket is full.
+type Bucket struct {
+	Tokens  float64
+	Updated time.Time
+}
+
+// Take takes a token from b at now. It returns the bucket afterwards and,
+// if b had no token to give, how long until it will; the bucket is then
+// returned unchanged.
+func (l Limit) Take(b Bucket, now time.Time) (Bucket, time.Duration) {
+	if !l.Enabled() {
+		return b, 0
+	}
+
+	rate := float64(l.Burst) / l.Per.Seconds()
+	tokens := float64(l.Burst)
+	if !b.Updated.IsZero() {
+		tokens = math.Min(tokens, b.Tokens+now.Sub(b.Updated).Seconds()*rate)
+	}
+	if tokens < 1 {
+		wait := time.Duration(math.Ceil((1 - tokens) / rate * float64(time.Second)))
+		return b, wait
+	}
+	return Bucket{Tokens: tokens - 1, Updated: now}, 0
+}
+
+// Store keeps token buckets. It is in memory by default; a shared store
+// such as Redis lets several servers enforce one limit.
+type Store interface {
+	// Take takes a token from key's bucket under limit at now. It returns
+	// zero if the request is allowed, otherwise how long until it would be.
+	Take(ctx context.Context, key string, limit Limit, now time.Time) (time.Duration, error)
+	// Reset refills key's bucket.
+	Reset(ctx context.Context, key string) error
+}
+
+// sweepInterval is how often MemoryStore drops buckets that have refilled.
+const sweepInterval = time.Minute
+
+// MemoryStore is a Store for a single server.
+type MemoryStore struct {
+	mu        sync.Mutex
+	buckets   map[string]memoryBucket
+	lastSweep time.Time
+}
+
+// memoryBucket is a bucket and when it will be full again, after which it
+// can be forgotten.
+type memoryBucket struct {
+	Bucket
+	full time.Time
+}
+
+// NewMemoryStore returns an empty in-memory store.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{buckets: map[string]memoryBucket{}}
+}
+
+func (s *MemoryStore) Take(ctx context.Context, key string, limit Limit, now time.Time) (time.Duration, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if now.Sub(s.lastSweep) >= sweepInterval {
+		for k, b := range s.buckets {
+			if !now.Before(b.full) {
+				delete(s.buckets, k)
+			}
+		}
+		s.lastSweep = now
+	}
+
+	bucket, wait := limit.Take(s.buckets[key].Bucket, now)
+	if wait > 0 {
+		return wait, nil
+	}
+	s.buckets[key] = memoryBucket{Bucket: bucket, full: now.Add(limit.Per)}
+	return 0, nil
+}
+
+func (s *MemoryStore) Reset(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.buckets, key)
+	return nil
+}
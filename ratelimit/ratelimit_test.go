@@ -0,0 +1,110 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestParseLimit(t *testing.T) {
+	tests := []struct {
+		s    string
+		want Limit
+		ok   bool
+	}{
+		{"10/1h", Limit{Burst: 10, Per: time.Hour}, true},
+		{" 5 / 30m ", Limit{Burst: 5, Per: 30 * time.Minute}, true},
+		{"", Limit{}, true},
+		{"0", Limit{}, true},
+		{"10", Limit{}, false},
+		{"0/1h", Limit{}, false},
+		{"-1/1h", Limit{}, false},
+		{"ten/1h", Limit{}, false},
+		{"10/0s", Limit{}, false},
+		{"10/hour", Limit{}, false},
+	}
+	for _, tt := range tests {
+		got, err := ParseLimit(tt.s)
+		if (err == nil) != tt.ok || got != tt.want {
+			t.Errorf("ParseLimit(%q) = %+v, %v; want %+v, ok %t", tt.s, got, err, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestLimitTake(t *testing.T) {
+	limit := Limit{Burst: 3, Per: time.Minute}
+	start := time.Date(2030, 1, 7, 10, 0, 0, 0, time.UTC)
+
+	// A full bucket gives its burst at once
+	var bucket Bucket
+	var wait time.Duration
+	for i := 0; i < 3; i++ {
+		if bucket, wait = limit.Take(bucket, start); wait != 0 {
+			t.Fatalf("take %d: wait %v, want none", i+1, wait)
+		}
+	}
+
+	// Then it refills a token every 20 seconds
+	empty := bucket
+	if bucket, wait = limit.Take(bucket, start); wait != 20*time.Second {
+		t.Errorf("empty bucket: wait %v, want 20s", wait)
+	}
+	if bucket != empty {
+		t.Errorf("a refused take changed the bucket from %+v to %+v", empty, bucket)
+	}
+	if _, wait = limit.Take(bucket, start.Add(5*time.Second)); wait != 15*time.Second {
+		t.Errorf("5s later: wait %v, want 15s", wait)
+	}
+	if bucket, wait = limit.Take(bucket, start.Add(20*time.Second)); wait != 0 {
+		t.Errorf("20s later: wait %v, want none", wait)
+	}
+
+	// A long pause refills it to the burst, not beyond
+	later := start.Add(time.Hour)
+	for i := 0; i < 3; i++ {
+		if bucket, wait = limit.Take(bucket, later); wait != 0 {
+			t.Fatalf("after a pause, take %d: wait %v, want none", i+1, wait)
+		}
+	}
+	if _, wait = limit.Take(bucket, later); wait == 0 {
+		t.Error("after a pause the bucket held more than its burst")
+	}
+
+	// The zero limit allows everything
+	if _, wait = (Limit{}).Take(Bucket{}, start); wait != 0 {
+		t.Errorf("zero limit: wait %v, want none", wait)
+	}
+}
+
+func TestMemoryStore(t *testing.T) {
+	store := NewMemoryStore()
+	ctx := context.Background()
+	limit := Limit{Burst: 1, Per: time.Hour}
+	now := time.Now()
+
+	take := func(key string) time.Duration {
+		wait, err := store.Take(ctx, key, limit, now)
+		if err != nil {
+			t.Fatalf("Take(%s): %v", key, err)
+		}
+		return wait
+	}
+
+	if take("a") != 0 {
+		t.Fatal("first take from a was refused")
+	}
+	if take("a") == 0 {
+		t.Error("second take from a was allowed")
+	}
+	// Keys have their own buckets
+	if take("b") != 0 {
+		t.Error("first take from b was refused")
+	}
+
+	if err := store.Reset(ctx, "a"); err != nil {
+		t.Fatalf("Reset: %v", err)
+	}
+	if take("a") != 0 {
+		t.Error("take from a after a reset was refused")
+	}
+}
@@ -0,0 +1,455 @@
+package scheduling
+
+import (
+	"errors"
+	"fmt"
+	"mumuni_backend/config"
+	"mumuni_backend/models"
+	"sort"
+	"time"
+)
+
+// Errors returned when a requested appointment slot cannot be booked.
+var (
+	ErrInvalidDate     = errors.New("invalid date")
+	ErrInvalidTime     = errors.New("invalid time")
+	ErrBlackoutDate    = errors.New("the studio is closed on this date")
+	ErrOutsideHours    = errors.New("the requested time is outside business hours")
+	ErrSlotUnavailable = errors.New("the requested time is already booked")
+	ErrPastTime        = errors.New("the requested time has already passed")
+	ErrTooSoon         = errors.New("the requested time is too soon to book")
+	ErrTooFarAhead     = errors.New("the requested date is too far ahead to book")
+
+	ErrArtistNotFound     = errors.New("the requested artist does not exist")
+	ErrArtistNotQualified = errors.New("the requested artist does not offer this service")
+	ErrNoQualifiedArtist  = errors.New("no artist offers this service")
+)
+
+// IsSlotError reports whether err is one of the errors above, meaning the
+// request can't be booked as asked rather than that something failed.
+func IsSlotError(err error) bool {
+	for _, target := range []error{
+		ErrInvalidDate, ErrInvalidTime, ErrBlackoutDate, ErrOutsideHours, ErrSlotUnavailable,
+		ErrPastTime, ErrTooSoon, ErrTooFarAhead,
+		ErrArtistNotFound, ErrArtistNotQualified, ErrNoQualifiedArtist,
+	} {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// Engine decides which appointment slots are open, given the studio's weekly
+// business hours, per-service durations and buffers, and blackout dates.
+// Service rules come from the catalog; see WithServices. Dates and clock
+// times are in the studio's Location, and bookings must start between
+// MinLeadTime from now and MaxAdvanceDays days from today.
+type Engine struct {
+	Hours          WeeklyHours
+	Services       map[string]ServiceRule
+	DefaultRule    ServiceRule
+	Blackouts      map[string]bool
+	SlotInterval   time.Duration
+	Location       *time.Location
+	MinLeadTime    time.Duration
+	MaxAdvanceDays int
+}
+
+// NewEngine builds an Engine from the scheduling settings in cfg.
+func NewEngine(cfg *config.Config) (*Engine, error) {
+	hours, err := ParseWeeklyHours(cfg.BusinessHours)
+	if err != nil {
+		return nil, fmt.Errorf("invalid BUSINESS_HOURS: %w", err)
+	}
+
+	blackouts, err := ParseDates(cfg.BlackoutDates)
+	if err != nil {
+		return nil, fmt.Errorf("invalid BLACKOUT_DATES: %w", err)
+	}
+
+	if cfg.SlotInterval <= 0 {
+		return nil, fmt.Errorf("SLOT_INTERVAL must be positive")
+	}
+
+	location, err := time.LoadLocation(cfg.StudioTimeZone)
+	if err != nil || cfg.StudioTimeZone == "" {
+		return nil, fmt.Errorf("invalid STUDIO_TIME_ZONE %q: expected an IANA name such as Africa/Lagos", cfg.StudioTimeZone)
+	}
+	if cfg.BookingMinLeadTime < 0 {
+		return nil, fmt.Errorf("BOOKING_MIN_LEAD_TIME must not be negative")
+	}
+	if cfg.BookingMaxAdvanceDays < 0 {
+		return nil, fmt.Errorf("BOOKING_MAX_ADVANCE_DAYS must not be negative")
+	}
+
+	return &Engine{
+		Hours:          hours,
+		Services:       map[string]ServiceRule{},
+		DefaultRule:    ServiceRule{Duration: cfg.DefaultServiceDuration, Buffer: cfg.DefaultServiceBuffer},
+		Blackouts:      blackouts,
+		SlotInterval:   cfg.SlotInterval,
+		Location:       location,
+		MinLeadTime:    cfg.BookingMinLeadTime,
+		MaxAdvanceDays: cfg.BookingMaxAdvanceDays,
+	}, nil
+}
+
+// Today returns the studio's date at now, as YYYY-MM-DD.
+func (e *Engine) Today(now time.Time) string {
+	return now.In(e.Location).Format(DateLayout)
+}
+
+// StartTime returns the moment a booking on date at clock starts in the
+// studio's time zone.
+func (e *Engine) StartTime(date, clock string) (time.Time, error) {
+	day, err := time.ParseInLocation(DateLayout, date, e.Location)
+	if err != nil {
+		return time.Time{}, ErrInvalidDate
+	}
+	start, err := ParseTimeOfDay(clock)
+	if err != nil {
+		return time.Time{}, ErrInvalidTime
+	}
+	return at(day, start), nil
+}
+
+// CheckBookingWindow reports whether a booking starting at start can be
+// made at now: it must be at least MinLeadTime away, and on a date at most
+// MaxAdvanceDays after today. Zero turns off either rule.
+func (e *Engine) CheckBookingWindow(start, now time.Time) error {
+	if !start.After(now) {
+		return ErrPastTime
+	}
+	if start.Before(now.Add(e.MinLeadTime)) {
+		return ErrTooSoon
+	}
+	if e.MaxAdvanceDays > 0 {
+		today, _ := time.ParseInLocation(DateLayout, e.Today(now), e.Location)
+		if !start.Before(today.AddDate(0, 0, e.MaxAdvanceDays+1)) {
+			return ErrTooFarAhead
+		}
+	}
+	return nil
+}
+
+// at returns the moment minutes after the midnight starting day. Days with a
+// daylight saving change are counted by the clock, not elapsed time.
+func at(day time.Time, minutes int) time.Time {
+	return time.Date(day.Year(), day.Month(), day.Day(), minutes/60, minutes%60, 0, 0, day.Location())
+}
+
+// WithServices returns a copy of the engine that uses the durations and
+// buffers of the given catalog services. Include inactive services so
+// existing bookings for them keep their real length.
+func (e *Engine) WithServices(services []models.Service) *Engine {
+	rules := make(map[string]ServiceRule, len(services))
+	for _, s := range services {
+		rules[s.Name] = ServiceRule{
+			Duration: time.Duration(s.DurationMinutes) * time.Minute,
+			Buffer:   time.Duration(s.BufferMinutes) * time.Minute,
+		}
+	}
+
+	engine := *e
+	engine.Services = rules
+	return &engine
+}
+
+// Rule returns the duration and buffer for a service, falling back to the
+// default rule for services missing from the catalog.
+func (e *Engine) Rule(service string) ServiceRule {
+	if rule, ok := e.Services[service]; ok {
+		return rule
+	}
+	return e.DefaultRule
+}
+
+// Slot is a bookable start time on a given day. Start and End are the
+// moments it starts and ends, in UTC, and are what API clients should read;
+// Time and EndsAt are the same as studio clock times, deprecated except for
+// display. ArtistIDs lists the artists free at that time when the studio has
+// artists on staff.
+type Slot struct {
+	Time      string    `json:"time"`
+	EndsAt    string    `json:"ends_at"`
+	Start     time.Time `json:"start"`
+	End       time.Time `json:"end"`
+	ArtistIDs []int     `json:"artist_ids,omitempty"`
+}
+
+// DayAvailability lists the open slots on one date.
+type DayAvailability struct {
+	Date  string `json:"date"`
+	Slots []Slot `json:"slots"`
+}
+
+// busyPeriod is the time an existing appointment occupies, buffer included.
+type busyPeriod struct {
+	start, end int
+}
+
+// CheckSlot reports whether service can be booked at date and time given the
+// active appointments already on that date, treating the studio as a single
+// calendar. It returns one of the package errors when it cannot.
+func (e *Engine) CheckSlot(date, clock, service string, existing []models.Appointment) error {
+	_, err := e.AssignArtist(date, clock, service, nil, nil, existing)
+	return err
+}
+
+// AssignArtist checks a booking against each artist's calendar and returns
+// the artist who should take it: the requested one if given, otherwise the
+// qualified artist with the fewest bookings that day. When there are no
+// artists the studio is treated as a single calendar and nil is returned.
+func (e *Engine) AssignArtist(date, clock, service string, artists []models.Artist, requested *int, existing []models.Appointment) (*int, error) {
+	day, err := time.Parse(DateLayout, date)
+	if err != nil {
+		return nil, ErrInvalidDate
+	}
+	start, err := ParseTimeOfDay(clock)
+	if err != nil {
+		return nil, ErrInvalidTime
+	}
+
+	if e.Blackouts[date] {
+		return nil, ErrBlackoutDate
+	}
+
+	rule := e.Rule(service)
+	end := start + minutes(rule.Duration)
+
+	if len(artists) == 0 {
+		if requested != nil {
+			return nil, ErrArtistNotFound
+		}
+		if !withinHours(e.Hours, day.Weekday(), start, end) {
+			return nil, ErrOutsideHours
+		}
+		if overlaps(start, end+minutes(rule.Buffer), e.busyPeriods(date, existing, nil)) {
+			return nil, ErrSlotUnavailable
+		}
+		return nil, nil
+	}
+
+	candidates, err := qualifiedArtists(service, artists, requested)
+	if err != nil {
+		return nil, err
+	}
+
+	// Report "outside hours" only if no candidate works at that time at all.
+	var best *models.Artist
+	bestLoad, working := 0, false
+	for i := range candidates {
+		artist := &candidates[i]
+		if !withinHours(e.artistHours(artist), day.Weekday(), start, end) {
+			continue
+		}
+		working = true
+
+		busy := e.busyPeriods(date, existing, &artist.ID)
+		if overlaps(start, end+minutes(rule.Buffer), busy) {
+			continue
+		}
+		if best == nil || len(busy) < bestLoad {
+			best, bestLoad = artist, len(busy)
+		}
+	}
+
+	if best == nil {
+		if !working {
+			return nil, ErrOutsideHours
+		}
+		return nil, ErrSlotUnavailable
+	}
+
+	artistID := best.ID
+	return &artistID, nil
+}
+
+// Availability returns the open slots for service on every date from from to
+// to inclusive, given the active artists (possibly none) and the active
+// appointments in that range. If requested is set only that artist's
+// calendar is considered. Slots outside the booking window at now are left
+// out.
+func (e *Engine) Availability(service string, from, to time.Time, artists []models.Artist, requested *int, existing []models.Appointment, now time.Time) []DayAvailability {
+	rule := e.Rule(service)
+	duration := minutes(rule.Duration)
+	buffer := minutes(rule.Buffer)
+	step := minutes(e.SlotInterval)
+
+	candidates, err := qualifiedArtists(service, artists, requested)
+	if len(artists) > 0 && err != nil {
+		candidates = nil
+	}
+
+	var days []DayAvailability
+	for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+		date := day.Format(DateLayout)
+		availability := DayAvailability{Date: date, Slots: []Slot{}}
+		if e.Blackouts[date] {
+			days = append(days, availability)
+			continue
+		}
+
+		midnight := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, e.Location)
+		bookable := func(start int) bool {
+			return e.CheckBookingWindow(at(midnight, start), now) == nil
+		}
+		slot := func(start int, artistIDs []int) Slot {
+			return Slot{
+				Time:      FormatTimeOfDay(start),
+				EndsAt:    FormatTimeOfDay(start + duration),
+				Start:     at(midnight, start).UTC(),
+				End:       at(midnight, start+duration).UTC(),
+				ArtistIDs: artistIDs,
+			}
+		}
+
+		if len(artists) == 0 {
+			busy := e.busyPeriods(date, existing, nil)
+			for _, start := range slotStarts(e.Hours[day.Weekday()], duration, step) {
+				if bookable(start) && !overlaps(start, start+duration+buffer, busy) {
+					availability.Slots = append(availability.Slots, slot(start, nil))
+				}
+			}
+			days = append(days, availability)
+			continue
+		}
+
+		// Merge the free start times of every qualified artist.
+		free := make(map[int][]int)
+		for i := range candidates {
+			artist := &candidates[i]
+			busy := e.busyPeriods(date, existing, &artist.ID)
+			for _, start := range slotStarts(e.artistHours(artist)[day.Weekday()], duration, step) {
+				if bookable(start) && !overlaps(start, start+duration+buffer, busy) {
+					free[start] = append(free[start], artist.ID)
+				}
+			}
+		}
+		starts := make([]int, 0, len(free))
+		for start := range free {
+			starts = append(starts, start)
+		}
+		sort.Ints(starts)
+		for _, start := range starts {
+			availability.Slots = append(availability.Slots, slot(start, free[start]))
+		}
+
+		days = append(days, availability)
+	}
+
+	return days
+}
+
+// ValidateWorkingHours checks an artist's working hours spec.
+func ValidateWorkingHours(spec string) error {
+	_, err := ParseWeeklyHours(spec)
+	return err
+}
+
+// artistHours returns the artist's own weekly hours, or the studio's when the
+// artist has none. Specs are validated when artists are saved.
+func (e *Engine) artistHours(artist *models.Artist) WeeklyHours {
+	if artist.WorkingHours == "" {
+		return e.Hours
+	}
+	hours, err := ParseWeeklyHours(artist.WorkingHours)
+	if err != nil || len(hours) == 0 {
+		return e.Hours
+	}
+	return hours
+}
+
+// qualifiedArtists returns the active artists who perform service, narrowed
+// to the requested artist when one is given.
+func qualifiedArtists(service string, artists []models.Artist, requested *int) ([]models.Artist, error) {
+	var qualified []models.Artist
+	for _, artist := range artists {
+		if requested != nil && artist.ID != *requested {
+			continue
+		}
+		if artist.Active && artist.HasSkill(service) {
+			qualified = append(qualified, artist)
+		}
+	}
+
+	if len(qualified) == 0 {
+		if requested == nil {
+			return nil, ErrNoQualifiedArtist
+		}
+		for _, artist := range artists {
+			if artist.ID == *requested && artist.Active {
+				return nil, ErrArtistNotQualified
+			}
+		}
+		return nil, ErrArtistNotFound
+	}
+
+	return qualified, nil
+}
+
+// slotStarts lists the start times, step minutes apart, at which an
+// appointment of duration minutes fits inside the given periods.
+func slotStarts(periods []TimeRange, duration, step int) []int {
+	var starts []int
+	for _, period := range periods {
+		for start := period.Start; start+duration <= period.End; start += step {
+			starts = append(starts, start)
+		}
+	}
+	return starts
+}
+
+func withinHours(hours WeeklyHours, day time.Weekday, start, end int) bool {
+	for _, period := range hours[day] {
+		if period.contains(start, end) {
+			return true
+		}
+	}
+	return false
+}
+
+// busyPeriods converts the appointments on date into occupied periods. With
+// an artistID only that artist's appointments count, along with those no
+// artist was assigned, such as bookings made before there were artists,
+// since any of them may end up taking those; otherwise every appointment
+// does. Appointments whose stored time can't be parsed are skipped.
+func (e *Engine) busyPeriods(date string, appointments []models.Appointment, artistID *int) []busyPeriod {
+	var busy []busyPeriod
+	for _, a := range appointments {
+		if a.AppointmentDate != date || !BlocksCalendar(a.Status) {
+			continue
+		}
+		if artistID != nil && a.ArtistID != nil && *a.ArtistID != *artistID {
+			continue
+		}
+		start, err := ParseTimeOfDay(a.AppointmentTime)
+		if err != nil {
+			continue
+		}
+		rule := e.Rule(a.Service)
+		busy = append(busy, busyPeriod{start: start, end: start + minutes(rule.Duration+rule.Buffer)})
+	}
+	return busy
+}
+
+// BlocksCalendar reports whether an appointment in this status holds its slot.
+func BlocksCalendar(status string) bool {
+	return status == "pending" || status == "confirmed"
+}
+
+// overlaps reports whether [start, end) intersects any busy period. The
+// candidate's end already includes its own buffer.
+func overlaps(start, end int, busy []busyPeriod) bool {
+	for _, b := range busy {
+		if start < b.end && b.start < end {
+			return true
+		}
+	}
+	return false
+}
+
+func minutes(d time.Duration) int {
+	return int(d / time.Minute)
+}
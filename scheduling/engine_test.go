@@ -0,0 +1,116 @@
+package scheduling
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"mumuni_backend/config"
+	"mumuni_backend/models"
+)
+
+func TestStartTimeUsesStudioTimeZone(t *testing.T) {
+	tests := []struct {
+		zone  string
+		clock string
+		want  string
+	}{
+		{"Africa/Lagos", "2:00 PM", "2026-01-15T13:00:00Z"},
+		{"Africa/Lagos", "14:00", "2026-01-15T13:00:00Z"},
+		{"Europe/London", "2:00 PM", "2026-01-15T14:00:00Z"},
+		{"America/New_York", "9:30 AM", "2026-01-15T14:30:00Z"},
+		{"Asia/Kolkata", "10:00", "2026-01-15T04:30:00Z"},
+	}
+	for _, tt := range tests {
+		cfg := config.LoadConfig()
+		cfg.StudioTimeZone = tt.zone
+		engine, err := NewEngine(cfg)
+		if err != nil {
+			t.Fatalf("NewEngine: %v", err)
+		}
+		start, err := engine.StartTime("2026-01-15", tt.clock)
+		if err != nil {
+			t.Fatalf("StartTime(%s in %s): %v", tt.clock, tt.zone, err)
+		}
+		if got := start.UTC().Format(time.RFC3339); got != tt.want {
+			t.Errorf("StartTime(%s in %s) = %s, want %s", tt.clock, tt.zone, got, tt.want)
+		}
+	}
+}
+
+func TestStartTimeRejects(t *testing.T) {
+	engine, err := NewEngine(config.LoadConfig())
+	if err != nil {
+		t.Fatalf("NewEngine: %v", err)
+	}
+	if _, err := engine.StartTime("2026-01-15", "lunchtime"); !errors.Is(err, ErrInvalidTime) {
+		t.Errorf("lunchtime: got %v, want ErrInvalidTime", err)
+	}
+	if _, err := engine.StartTime("15/01/2026", "2:00 PM"); !errors.Is(err, ErrInvalidDate) {
+		t.Errorf("15/01/2026: got %v, want ErrInvalidDate", err)
+	}
+}
+
+func TestUnassignedAppointmentsBlockEveryArtist(t *testing.T) {
+	engine, err := NewEngine(config.LoadConfig())
+	if err != nil {
+		t.Fatalf("NewEngine: %v", err)
+	}
+	artists := []models.Artist{
+		{ID: 1, Name: "Amaka", Skills: []string{"Everyday Glam"}, Active: true},
+		{ID: 2, Name: "Bisi", Skills: []string{"Everyday Glam"}, Active: true},
+	}
+	one := 1
+	existing := []models.Appointment{
+		// Booked before there were any artists
+		{AppointmentDate: "2030-01-07", AppointmentTime: "10:00 AM", Service: "Everyday Glam", Status: "confirmed"},
+		{AppointmentDate: "2030-01-07", AppointmentTime: "2:00 PM", Service: "Everyday Glam", Status: "pending", ArtistID: &one},
+		{AppointmentDate: "2030-01-07", AppointmentTime: "4:00 PM", Service: "Everyday Glam", Status: "cancelled"},
+	}
+
+	// Artist 1 has the 2:00 PM booking, so artist 2 is the less busy
+	tests := []struct {
+		clock string
+		want  int // 0 when the slot is refused
+		err   error
+	}{
+		{"10:00 AM", 0, ErrSlotUnavailable},
+		{"10:30 AM", 0, ErrSlotUnavailable},
+		{"9:00 AM", 0, ErrSlotUnavailable},
+		{"11:30 AM", 2, nil},
+		{"2:00 PM", 2, nil},
+		{"4:00 PM", 2, nil},
+	}
+	for _, tt := range tests {
+		got, err := engine.AssignArtist("2030-01-07", tt.clock, "Everyday Glam", artists, nil, existing)
+		artistID := 0
+		if got != nil {
+			artistID = *got
+		}
+		if !errors.Is(err, tt.err) || artistID != tt.want {
+			t.Errorf("AssignArtist at %s = artist %d, %v; want artist %d, %v", tt.clock, artistID, err, tt.want, tt.err)
+		}
+	}
+	two := 2
+	if _, err := engine.AssignArtist("2030-01-07", "10:00 AM", "Everyday Glam", artists, &two, existing); !errors.Is(err, ErrSlotUnavailable) {
+		t.Errorf("AssignArtist at 10:00 AM with artist 2 requested: got %v, want ErrSlotUnavailable", err)
+	}
+
+	day, _ := time.ParseInLocation(DateLayout, "2030-01-07", engine.Location)
+	days := engine.Availability("Everyday Glam", day, day, artists, nil, existing, day.AddDate(0, 0, -1))
+	free := map[string][]int{}
+	for _, slot := range days[0].Slots {
+		free[slot.Time] = slot.ArtistIDs
+	}
+	for _, clock := range []string{"9:00 AM", "9:30 AM", "10:00 AM", "10:30 AM", "11:00 AM"} {
+		if artistIDs, ok := free[clock]; ok {
+			t.Errorf("%s is offered with artists %v, want it taken by the unassigned booking", clock, artistIDs)
+		}
+	}
+	if artistIDs := free["11:30 AM"]; len(artistIDs) != 2 {
+		t.Errorf("11:30 AM is offered with artists %v, want both", artistIDs)
+	}
+	if artistIDs := free["2:00 PM"]; len(artistIDs) != 1 || artistIDs[0] != 2 {
+		t.Errorf("2:00 PM is offered with artists %v, want only 2", artistIDs)
+	}
+}
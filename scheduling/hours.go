@@ -0,0 +1,205 @@
+package scheduling
+
+import (
+	"fmt"
+	"mumuni_backend/models"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DateLayout is the format of appointment dates (the Postgres DATE format).
+const DateLayout = "2006-01-02"
+
+// timeLayouts are the accepted spellings of a time of day, e.g. "14:00",
+// "2:00 PM" or "2pm".
+var timeLayouts = []string{"15:04", "3:04 PM", "3:04PM", "3 PM", "3PM"}
+
+// ParseTimeOfDay parses a clock time and returns it as minutes after midnight.
+func ParseTimeOfDay(s string) (int, error) {
+	value := strings.ToUpper(strings.TrimSpace(s))
+	for _, layout := range timeLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t.Hour()*60 + t.Minute(), nil
+		}
+	}
+	return 0, fmt.Errorf("invalid time of day: %q", s)
+}
+
+// FormatTimeOfDay renders minutes after midnight the way appointment times
+// are stored, e.g. "2:00 PM".
+func FormatTimeOfDay(minutes int) string {
+	return time.Date(0, 1, 1, minutes/60, minutes%60, 0, 0, time.UTC).Format("3:04 PM")
+}
+
+// CohortSessions returns when each session of a cohort starts: every period
+// of its meeting times from the start date to the end date, in the studio's
+// time zone. Without an end date only the first week is known, and without
+// meeting times the cohort is taken to start at midnight on its start date.
+func (e *Engine) CohortSessions(cohort *models.Cohort) ([]time.Time, error) {
+	first, err := e.StartTime(cohort.StartDate, "00:00")
+	if err != nil {
+		return nil, err
+	}
+
+	hours, err := ParseWeeklyHours(cohort.MeetingTimes)
+	if err != nil {
+		return nil, err
+	}
+	if len(hours) == 0 {
+		return []time.Time{first}, nil
+	}
+
+	last := first.AddDate(0, 0, 6)
+	if cohort.EndDate != nil {
+		if last, err = e.StartTime(*cohort.EndDate, "00:00"); err != nil {
+			return nil, err
+		}
+	}
+
+	var sessions []time.Time
+	for day := first; !day.After(last); day = day.AddDate(0, 0, 1) {
+		for _, period := range hours[day.Weekday()] {
+			sessions = append(sessions, at(day, period.Start))
+		}
+	}
+	return sessions, nil
+}
+
+// TimeRange is a half-open interval [Start, End) in minutes after midnight.
+type TimeRange struct {
+	Start int
+	End   int
+}
+
+func (r TimeRange) contains(start, end int) bool {
+	return start >= r.Start && end <= r.End
+}
+
+func (r TimeRange) String() string {
+	return fmt.Sprintf("%02d:%02d-%02d:%02d", r.Start/60, r.Start%60, r.End/60, r.End%60)
+}
+
+// WeeklyHours holds the open periods for each day of the week. Days without
+// an entry are closed.
+type WeeklyHours map[time.Weekday][]TimeRange
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// ParseWeeklyHours parses a spec such as
+// "mon-fri=09:00-18:00;sat=10:00-13:00,14:00-16:00". Days are separated by
+// semicolons and may be ranges; a day can have several comma-separated
+// periods.
+func ParseWeeklyHours(spec string) (WeeklyHours, error) {
+	hours := make(WeeklyHours)
+	for _, entry := range strings.Split(spec, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		days, periods, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid business hours entry %q: expected day=HH:MM-HH:MM", entry)
+		}
+
+		dayList, err := parseDays(days)
+		if err != nil {
+			return nil, err
+		}
+
+		var ranges []TimeRange
+		for _, period := range strings.Split(periods, ",") {
+			r, err := parseTimeRange(period)
+			if err != nil {
+				return nil, fmt.Errorf("invalid business hours entry %q: %w", entry, err)
+			}
+			ranges = append(ranges, r)
+		}
+
+		for _, day := range dayList {
+			hours[day] = append(hours[day], ranges...)
+			sort.Slice(hours[day], func(i, j int) bool { return hours[day][i].Start < hours[day][j].Start })
+		}
+	}
+	return hours, nil
+}
+
+func parseDays(s string) ([]time.Weekday, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	first, last, isRange := strings.Cut(s, "-")
+
+	start, ok := weekdays[strings.TrimSpace(first)]
+	if !ok {
+		return nil, fmt.Errorf("invalid day %q", first)
+	}
+	if !isRange {
+		return []time.Weekday{start}, nil
+	}
+
+	end, ok := weekdays[strings.TrimSpace(last)]
+	if !ok {
+		return nil, fmt.Errorf("invalid day %q", last)
+	}
+
+	var days []time.Weekday
+	for d := start; ; d = (d + 1) % 7 {
+		days = append(days, d)
+		if d == end {
+			break
+		}
+	}
+	return days, nil
+}
+
+func parseTimeRange(s string) (TimeRange, error) {
+	from, to, ok := strings.Cut(strings.TrimSpace(s), "-")
+	if !ok {
+		return TimeRange{}, fmt.Errorf("invalid period %q", s)
+	}
+
+	start, err := ParseTimeOfDay(from)
+	if err != nil {
+		return TimeRange{}, err
+	}
+	end, err := ParseTimeOfDay(to)
+	if err != nil {
+		return TimeRange{}, err
+	}
+	if end <= start {
+		return TimeRange{}, fmt.Errorf("period %q ends before it starts", s)
+	}
+
+	return TimeRange{Start: start, End: end}, nil
+}
+
+// ServiceRule is how long a service takes and how much cleanup time must
+// follow it before the next appointment can start.
+type ServiceRule struct {
+	Duration time.Duration
+	Buffer   time.Duration
+}
+
+// ParseDates parses a comma-separated list of YYYY-MM-DD dates.
+func ParseDates(spec string) (map[string]bool, error) {
+	dates := make(map[string]bool)
+	for _, value := range strings.Split(spec, ",") {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+		if _, err := time.Parse(DateLayout, value); err != nil {
+			return nil, fmt.Errorf("invalid date %q: expected YYYY-MM-DD", value)
+		}
+		dates[value] = true
+	}
+	return dates, nil
+}
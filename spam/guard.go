@@ -0,0 +1,231 @@
+package spam
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mumuni_backend/config"
+	"mumuni_backend/ratelimit"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrHoneypot is returned for a submission with the honeypot field
+	// filled in. Only bots fill it in, so it should look accepted.
+	ErrHoneypot = errors.New("honeypot field filled in")
+	// ErrDuplicate is returned for a submission identical to one made
+	// within the duplicate window.
+	ErrDuplicate = errors.New("duplicate submission")
+)
+
+// RateLimitError is returned when a submission's IP address, email or
+// phone number has made too many submissions.
+type RateLimitError struct {
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	return "rate limited, retry after " + e.RetryAfter.String()
+}
+
+// Submission is a public form submission to screen.
+type Submission struct {
+	// Kind tells apart forms with similar payloads, like "appointment".
+	Kind string
+	// IP is the client's address, from X-Forwarded-For only when the
+	// request came through one of TRUSTED_PROXIES.
+	IP    string
+	Email string
+	// Phone is in E.164, as phone.Parser normalizes it.
+	Phone    string
+	Honeypot string
+	// Token is the CAPTCHA response or proof of work, if a Verifier is set.
+	Token string
+	// Payload is the submitted data, compared to catch duplicates.
+	Payload any
+}
+
+// Guard screens public form submissions for spam.
+type Guard struct {
+	store           ratelimit.Store
+	ipLimit         ratelimit.Limit
+	contactLimit    ratelimit.Limit
+	duplicateWindow time.Duration
+	verifier        Verifier
+	resetEmailLimit ratelimit.Limit
+	resetIPLimit    ratelimit.Limit
+}
+
+// NewGuard returns a guard configured from cfg, keeping its rate limits in
+// store.
+func NewGuard(cfg *config.Config, store ratelimit.Store) (*Guard, error) {
+	ipLimit, err := ratelimit.ParseLimit(cfg.BookingIPLimit)
+	if err != nil {
+		return nil, fmt.Errorf("invalid BOOKING_IP_LIMIT: %w", err)
+	}
+	contactLimit, err := ratelimit.ParseLimit(cfg.BookingContactLimit)
+	if err != nil {
+		return nil, fmt.Errorf("invalid BOOKING_CONTACT_LIMIT: %w", err)
+	}
+	resetEmailLimit, err := ratelimit.ParseLimit(cfg.PasswordResetEmailLimit)
+	if err != nil {
+		return nil, fmt.Errorf("invalid PASSWORD_RESET_EMAIL_LIMIT: %w", err)
+	}
+	resetIPLimit, err := ratelimit.ParseLimit(cfg.PasswordResetIPLimit)
+	if err != nil {
+		return nil, fmt.Errorf("invalid PASSWORD_RESET_IP_LIMIT: %w", err)
+	}
+	if cfg.DuplicateWindow < 0 {
+		return nil, fmt.Errorf("DUPLICATE_WINDOW must not be negative")
+	}
+
+	var verifier Verifier
+	switch cfg.BookingVerifier {
+	case "":
+	case "captcha":
+		if cfg.CaptchaVerifyURL == "" || cfg.CaptchaSecret == "" {
+			return nil, fmt.Errorf("CAPTCHA_VERIFY_URL and CAPTCHA_SECRET are required when BOOKING_VERIFIER=captcha")
+		}
+		verifier = NewSiteVerifier(cfg.CaptchaVerifyURL, cfg.CaptchaSecret)
+	case "pow":
+		if cfg.PowDifficulty <= 0 || cfg.PowDifficulty > 32 {
+			return nil, fmt.Errorf("POW_DIFFICULTY must be between 1 and 32")
+		}
+		verifier = NewProofOfWork(cfg.PowDifficulty)
+	default:
+		return nil, fmt.Errorf("unknown BOOKING_VERIFIER %q. Must be captcha or pow", cfg.BookingVerifier)
+	}
+
+	return &Guard{
+		store:           store,
+		ipLimit:         ipLimit,
+		contactLimit:    contactLimit,
+		duplicateWindow: cfg.DuplicateWindow,
+		verifier:        verifier,
+		resetEmailLimit: resetEmailLimit,
+		resetIPLimit:    resetIPLimit,
+	}, nil
+}
+
+// Check screens s. It checks, in order, the IP address's rate limit, the
+// honeypot, the verifier's token, the email's and phone number's rate limit
+// and whether s duplicates a recent submission. Every check passed takes a
+// token from the rate limits, whether or not the submission goes on to
+// succeed.
+func (g *Guard) Check(ctx context.Context, s Submission) error {
+	now := time.Now()
+
+	if err := g.take(ctx, g.ipLimit, now, "ip:"+s.IP); err != nil {
+		return err
+	}
+
+	if strings.TrimSpace(s.Honeypot) != "" {
+		return ErrHoneypot
+	}
+
+	if g.verifier != nil {
+		if err := g.verifier.Verify(ctx, s.Token, s.IP); err != nil {
+			return err
+		}
+	}
+
+	if err := g.take(ctx, g.contactLimit, now, emailKey(s.Email), phoneKey(s.Phone)); err != nil {
+		return err
+	}
+
+	if g.duplicateWindow > 0 {
+		key, err := duplicateKey(s)
+		if err != nil {
+			return err
+		}
+		wait, err := g.store.Take(ctx, key, ratelimit.Limit{Burst: 1, Per: g.duplicateWindow}, now)
+		if err != nil {
+			return err
+		}
+		if wait > 0 {
+			return ErrDuplicate
+		}
+	}
+	return nil
+}
+
+// Release forgets that s was submitted, so a submission that failed can be
+// sent again without counting as a duplicate.
+func (g *Guard) Release(ctx context.Context, s Submission) error {
+	if g.duplicateWindow <= 0 {
+		return nil
+	}
+	key, err := duplicateKey(s)
+	if err != nil {
+		return err
+	}
+	return g.store.Reset(ctx, key)
+}
+
+// CheckPasswordReset takes a token from the password reset buckets of the
+// client's IP address and of email, returning a RateLimitError if either is
+// empty. They are kept apart from the booking limits.
+func (g *Guard) CheckPasswordReset(ctx context.Context, ip, email string) error {
+	now := time.Now()
+	if err := g.take(ctx, g.resetIPLimit, now, "reset:ip:"+ip); err != nil {
+		return err
+	}
+	if key := emailKey(email); key != "" {
+		return g.take(ctx, g.resetEmailLimit, now, "reset:"+key)
+	}
+	return nil
+}
+
+// take takes a token from each key's bucket under limit, returning a
+// RateLimitError with the longest wait if any bucket is empty.
+func (g *Guard) take(ctx context.Context, limit ratelimit.Limit, now time.Time, keys ...string) error {
+	if !limit.Enabled() {
+		return nil
+	}
+
+	var longest time.Duration
+	for _, key := range keys {
+		if key == "" {
+			continue
+		}
+		wait, err := g.store.Take(ctx, key, limit, now)
+		if err != nil {
+			return err
+		}
+		longest = max(longest, wait)
+	}
+	if longest > 0 {
+		return &RateLimitError{RetryAfter: longest}
+	}
+	return nil
+}
+
+func emailKey(email string) string {
+	email = strings.ToLower(strings.TrimSpace(email))
+	if email == "" {
+		return ""
+	}
+	return "email:" + email
+}
+
+// phoneKey expects phone in E.164, so differently formatted copies of one
+// number share a limit.
+func phoneKey(phone string) string {
+	if phone == "" {
+		return ""
+	}
+	return "phone:" + phone
+}
+
+func duplicateKey(s Submission) (string, error) {
+	payload, err := json.Marshal(s.Payload)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(append([]byte(s.Kind+"\x00"), payload...))
+	return "duplicate:" + hex.EncodeToString(sum[:]), nil
+}
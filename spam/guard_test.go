@@ -0,0 +1,179 @@
+package spam
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"mumuni_backend/config"
+	"mumuni_backend/ratelimit"
+)
+
+func newGuard(t *testing.T, configure func(*config.Config)) *Guard {
+	t.Helper()
+	cfg := &config.Config{
+		BookingIPLimit:      "0",
+		BookingContactLimit: "0",
+	}
+	configure(cfg)
+	guard, err := NewGuard(cfg, ratelimit.NewMemoryStore())
+	if err != nil {
+		t.Fatalf("NewGuard: %v", err)
+	}
+	return guard
+}
+
+// submission is a booking from ip for email and phone, told apart from
+// others by n so it isn't a duplicate.
+func submission(ip, email, phone string, n int) Submission {
+	return Submission{
+		Kind:    "appointment",
+		IP:      ip,
+		Email:   email,
+		Phone:   phone,
+		Payload: map[string]any{"email": email, "n": n},
+	}
+}
+
+func TestGuardHoneypot(t *testing.T) {
+	guard := newGuard(t, func(cfg *config.Config) {})
+	s := submission("203.0.113.7", "bot@example.com", "", 0)
+
+	s.Honeypot = "  "
+	if err := guard.Check(context.Background(), s); err != nil {
+		t.Errorf("blank honeypot: got %v, want nil", err)
+	}
+	s.Honeypot = "http://spam.example"
+	if err := guard.Check(context.Background(), s); !errors.Is(err, ErrHoneypot) {
+		t.Errorf("filled honeypot: got %v, want ErrHoneypot", err)
+	}
+}
+
+func TestGuardRateLimits(t *testing.T) {
+	guard := newGuard(t, func(cfg *config.Config) {
+		cfg.BookingIPLimit = "3/1h"
+		cfg.BookingContactLimit = "2/1h"
+	})
+	ctx := context.Background()
+
+	tests := []struct {
+		name    string
+		s       Submission
+		limited bool
+	}{
+		{"first from the email", submission("203.0.113.1", "ada@example.com", "+2348031112233", 1), false},
+		{"second from the email", submission("203.0.113.2", "ADA@example.com ", "+2348031112244", 2), false},
+		// The email's bucket is empty, however it is written
+		{"third from the email", submission("203.0.113.3", "ada@example.com", "+2348031112255", 3), true},
+		// So is the phone number's, from another email
+		{"the phone number again", submission("203.0.113.4", "bola@example.com", "+2348031112233", 4), false},
+		{"the phone number a third time", submission("203.0.113.5", "chi@example.com", "+2348031112233", 5), true},
+		{"first from the IP address", submission("198.51.100.9", "a@example.com", "", 6), false},
+		{"second from the IP address", submission("198.51.100.9", "b@example.com", "", 7), false},
+		{"third from the IP address", submission("198.51.100.9", "c@example.com", "", 8), false},
+		{"fourth from the IP address", submission("198.51.100.9", "d@example.com", "", 9), true},
+	}
+	for _, tt := range tests {
+		err := guard.Check(ctx, tt.s)
+		var limitErr *RateLimitError
+		if limited := errors.As(err, &limitErr); limited != tt.limited || (!limited && err != nil) {
+			t.Errorf("%s: got %v, want limited %t", tt.name, err, tt.limited)
+			continue
+		}
+		if tt.limited && limitErr.RetryAfter <= 0 {
+			t.Errorf("%s: retry after %v, want a wait", tt.name, limitErr.RetryAfter)
+		}
+	}
+}
+
+func TestGuardDuplicates(t *testing.T) {
+	guard := newGuard(t, func(cfg *config.Config) {
+		cfg.DuplicateWindow = time.Minute
+	})
+	ctx := context.Background()
+	s := submission("203.0.113.7", "ada@example.com", "+2348031112233", 1)
+
+	if err := guard.Check(ctx, s); err != nil {
+		t.Fatalf("first submission: %v", err)
+	}
+	if err := guard.Check(ctx, s); !errors.Is(err, ErrDuplicate) {
+		t.Errorf("the same again: got %v, want ErrDuplicate", err)
+	}
+
+	// The same payload for another form isn't a duplicate
+	class := s
+	class.Kind = "class"
+	if err := guard.Check(ctx, class); err != nil {
+		t.Errorf("the same payload as a class: got %v, want nil", err)
+	}
+
+	// Nor is a submission released after it failed
+	if err := guard.Release(ctx, s); err != nil {
+		t.Fatalf("Release: %v", err)
+	}
+	if err := guard.Check(ctx, s); err != nil {
+		t.Errorf("after a release: got %v, want nil", err)
+	}
+}
+
+func TestGuardPasswordResetLimits(t *testing.T) {
+	guard := newGuard(t, func(cfg *config.Config) {
+		cfg.BookingIPLimit = "1/1h"
+		cfg.BookingContactLimit = "1/1h"
+		cfg.PasswordResetEmailLimit = "2/1h"
+		cfg.PasswordResetIPLimit = "2/1h"
+	})
+	ctx := context.Background()
+
+	// Reset requests don't share buckets with bookings
+	if err := guard.Check(ctx, submission("203.0.113.7", "owner@example.com", "", 1)); err != nil {
+		t.Fatalf("booking: %v", err)
+	}
+
+	tests := []struct {
+		ip, email string
+		limited   bool
+	}{
+		{"203.0.113.7", "owner@example.com", false},
+		{"203.0.113.8", "Owner@example.com", false},
+		{"203.0.113.9", "owner@example.com", true},
+		{"203.0.113.7", "manager@example.com", false},
+		{"203.0.113.7", "artist@example.com", true},
+	}
+	for _, tt := range tests {
+		err := guard.CheckPasswordReset(ctx, tt.ip, tt.email)
+		var limitErr *RateLimitError
+		if limited := errors.As(err, &limitErr); limited != tt.limited || (!limited && err != nil) {
+			t.Errorf("reset for %s from %s: got %v, want limited %t", tt.email, tt.ip, err, tt.limited)
+		}
+	}
+}
+
+func TestNewGuardRefusesBadConfig(t *testing.T) {
+	tests := []struct {
+		name      string
+		configure func(*config.Config)
+	}{
+		{"bad IP limit", func(cfg *config.Config) { cfg.BookingIPLimit = "lots" }},
+		{"bad contact limit", func(cfg *config.Config) { cfg.BookingContactLimit = "5" }},
+		{"bad reset limit", func(cfg *config.Config) { cfg.PasswordResetEmailLimit = "3/forever" }},
+		{"negative duplicate window", func(cfg *config.Config) { cfg.DuplicateWindow = -time.Minute }},
+		{"unknown verifier", func(cfg *config.Config) { cfg.BookingVerifier = "riddle" }},
+		{"captcha without a secret", func(cfg *config.Config) {
+			cfg.BookingVerifier = "captcha"
+			cfg.CaptchaVerifyURL = "https://hcaptcha.com/siteverify"
+		}},
+		{"pow too hard", func(cfg *config.Config) {
+			cfg.BookingVerifier = "pow"
+			cfg.PowDifficulty = 33
+		}},
+	}
+	for _, tt := range tests {
+		cfg := &config.Config{}
+		tt.configure(cfg)
+		if _, err := NewGuard(cfg, ratelimit.NewMemoryStore()); err == nil {
+			t.Errorf("%s: NewGuard succeeded, want an error", tt.name)
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package spam
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/bits"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrVerificationFailed is returned by a Verifier when the token doesn't
+// prove the request came from a person.
+var ErrVerificationFailed = errors.New("verification failed")
+
+// Verifier checks the token a booking form sends to prove a person filled
+// it in, such as a CAPTCHA response or a proof of work.
+type Verifier interface {
+	Verify(ctx context.Context, token, remoteIP string) error
+}
+
+// verifyTimeout bounds a request to the CAPTCHA provider.
+const verifyTimeout = 10 * time.Second
+
+// SiteVerifier checks CAPTCHA responses with a provider's siteverify
+// endpoint. reCAPTCHA, hCaptcha and Turnstile all take the secret, the
+// response and the client's IP address as a form POST and answer with
+// {"success": true|false, "error-codes": [...]}.
+type SiteVerifier struct {
+	client    *http.Client
+	verifyURL string
+	secret    string
+}
+
+// NewSiteVerifier returns a verifier posting to verifyURL with secret.
+func NewSiteVerifier(verifyURL, secret string) *SiteVerifier {
+	return &SiteVerifier{
+		client:    &http.Client{Timeout: verifyTimeout},
+		verifyURL: verifyURL,
+		secret:    secret,
+	}
+}
+
+func (v *SiteVerifier) Verify(ctx context.Context, token, remoteIP string) error {
+	if token == "" {
+		return ErrVerificationFailed
+	}
+
+	form := url.Values{}
+	form.Set("secret", v.secret)
+	form.Set("response", token)
+	if remoteIP != "" {
+		form.Set("remoteip", remoteIP)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, v.verifyURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("CAPTCHA provider returned %s", resp.Status)
+	}
+
+	var result struct {
+		Success    bool     `json:"success"`
+		ErrorCodes []string `json:"error-codes"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return fmt.Errorf("invalid CAPTCHA provider response: %w", err)
+	}
+	if !result.Success {
+		return fmt.Errorf("%w: %s", ErrVerificationFailed, strings.Join(result.ErrorCodes, ", "))
+	}
+	return nil
+}
+
+// Proof of work tokens are accepted for powMaxAge after the time they name,
+// and up to powSkew before it, for clocks that run ahead.
+const (
+	powMaxAge = 10 * time.Minute
+	powSkew   = time.Minute
+)
+
+// ProofOfWork checks hashcash-style tokens, which cost the sender CPU time
+// instead of a puzzle. A token is "<unix time>:<nonce>", with the nonce
+// chosen so the SHA-256 hash of the whole token starts with at least the
+// configured number of zero bits. Each token is accepted once.
+type ProofOfWork struct {
+	difficulty int
+
+	mu   sync.Mutex
+	used map[string]time.Time
+}
+
+// NewProofOfWork returns a verifier requiring difficulty leading zero bits.
+// Every extra bit doubles the work.
+func NewProofOfWork(difficulty int) *ProofOfWork {
+	return &ProofOfWork{difficulty: difficulty, used: map[string]time.Time{}}
+}
+
+func (p *ProofOfWork) Verify(ctx context.Context, token, remoteIP string) error {
+	stamp, nonce, ok := strings.Cut(token, ":")
+	if !ok || nonce == "" {
+		return ErrVerificationFailed
+	}
+	unix, err := strconv.ParseInt(stamp, 10, 64)
+	if err != nil {
+		return ErrVerificationFailed
+	}
+
+	now := time.Now()
+	issued := time.Unix(unix, 0)
+	if issued.Before(now.Add(-powMaxAge)) || issued.After(now.Add(powSkew)) {
+		return ErrVerificationFailed
+	}
+	if leadingZeroBits(sha256.Sum256([]byte(token))) < p.difficulty {
+		return ErrVerificationFailed
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for t, expires := range p.used {
+		if now.After(expires) {
+			delete(p.used, t)
+		}
+	}
+	if _, seen := p.used[token]; seen {
+		return ErrVerificationFailed
+	}
+	p.used[token] = issued.Add(powMaxAge)
+	return nil
+}
+
+func leadingZeroBits(sum [sha256.Size]byte) int {
+	n := 0
+	for _, b := range sum {
+		if b != 0 {
+			return n + bits.LeadingZeros8(b)
+		}
+		n += 8
+	}
+	return n
+}
@@ -0,0 +1,116 @@
+package spam
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// solve finds a proof of work token for issued with at least difficulty
+// leading zero bits.
+func solve(issued time.Time, difficulty int) string {
+	stamp := strconv.FormatInt(issued.Unix(), 10)
+	for nonce := 0; ; nonce++ {
+		token := stamp + ":" + strconv.Itoa(nonce)
+		if leadingZeroBits(sha256.Sum256([]byte(token))) >= difficulty {
+			return token
+		}
+	}
+}
+
+func TestProofOfWork(t *testing.T) {
+	const difficulty = 8
+	pow := NewProofOfWork(difficulty)
+	ctx := context.Background()
+	now := time.Now()
+
+	token := solve(now, difficulty)
+	if err := pow.Verify(ctx, token, ""); err != nil {
+		t.Fatalf("fresh token: %v", err)
+	}
+	if err := pow.Verify(ctx, token, ""); !errors.Is(err, ErrVerificationFailed) {
+		t.Errorf("the same token again: got %v, want ErrVerificationFailed", err)
+	}
+
+	// A token short of the difficulty
+	weak := ""
+	stamp := strconv.FormatInt(now.Unix(), 10)
+	for nonce := 0; weak == ""; nonce++ {
+		candidate := stamp + ":" + strconv.Itoa(nonce)
+		if leadingZeroBits(sha256.Sum256([]byte(candidate))) < difficulty {
+			weak = candidate
+		}
+	}
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"not enough work", weak},
+		{"too old", solve(now.Add(-powMaxAge-time.Minute), difficulty)},
+		{"from the future", solve(now.Add(powSkew+time.Minute), difficulty)},
+		{"empty", ""},
+		{"no nonce", stamp + ":"},
+		{"no time", ":123"},
+		{"time isn't a number", "soon:123"},
+	}
+	for _, tt := range tests {
+		if err := pow.Verify(ctx, tt.token, ""); !errors.Is(err, ErrVerificationFailed) {
+			t.Errorf("%s: got %v, want ErrVerificationFailed", tt.name, err)
+		}
+	}
+}
+
+func TestSiteVerifier(t *testing.T) {
+	var form map[string]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		form = map[string]string{
+			"secret":   r.PostForm.Get("secret"),
+			"response": r.PostForm.Get("response"),
+			"remoteip": r.PostForm.Get("remoteip"),
+		}
+		switch r.PostForm.Get("response") {
+		case "good":
+			w.Write([]byte(`{"success": true}`))
+		case "broken":
+			w.WriteHeader(http.StatusBadGateway)
+		case "garbled":
+			w.Write([]byte(`<html>`))
+		default:
+			w.Write([]byte(`{"success": false, "error-codes": ["invalid-input-response"]}`))
+		}
+	}))
+	defer server.Close()
+
+	verifier := NewSiteVerifier(server.URL, "captcha-secret")
+	ctx := context.Background()
+
+	if err := verifier.Verify(ctx, "good", "203.0.113.7"); err != nil {
+		t.Fatalf("good response: %v", err)
+	}
+	if form["secret"] != "captcha-secret" || form["response"] != "good" || form["remoteip"] != "203.0.113.7" {
+		t.Errorf("the provider got %v, want the secret, response and IP address", form)
+	}
+
+	if err := verifier.Verify(ctx, "bad", ""); !errors.Is(err, ErrVerificationFailed) {
+		t.Errorf("bad response: got %v, want ErrVerificationFailed", err)
+	}
+	form = nil
+	if err := verifier.Verify(ctx, "", ""); !errors.Is(err, ErrVerificationFailed) || form != nil {
+		t.Errorf("no response: got %v, asked the provider %t; want ErrVerificationFailed without asking", err, form != nil)
+	}
+
+	// The provider failing isn't the customer's fault, so it isn't
+	// reported as a failed verification
+	for _, token := range []string{"broken", "garbled"} {
+		if err := verifier.Verify(ctx, token, ""); err == nil || errors.Is(err, ErrVerificationFailed) {
+			t.Errorf("%s provider answer: got %v, want another error", token, err)
+		}
+	}
+}
@@ -2,8 +2,67 @@
 
 # Mumuni Backend API Test Script
 # Run this after starting the server with: go run main.go
+# against an empty database, with ADMIN_BOOTSTRAP_TOKEN set to the server's.
 
 BASE_URL="http://localhost:8080"
+BOOTSTRAP_TOKEN="$ADMIN_BOOTSTRAP_TOKEN"
+
+# future_weekday prints the first Monday to Friday at least $1 days from
+# today, as YYYY-MM-DD, with GNU or BSD date.
+future_weekday() {
+  local days=$1 day
+  while true; do
+    day=$(date -d "+$days days" +%Y-%m-%d 2>/dev/null || date -v+"$days"d +%Y-%m-%d)
+    case $(date -d "$day" +%u 2>/dev/null || date -j -f %Y-%m-%d "$day" +%u) in
+      [1-5]) echo "$day"; return ;;
+    esac
+    days=$((days + 1))
+  done
+}
+BOOKING_DATE=$(future_weekday 14)
+
+# check_status prints a pass or fail line depending on whether a request
+# answers with the expected HTTP status.
+# Usage: check_status "description" expected_status method path token [json_body]
+check_status() {
+  local description=$1 expected=$2 method=$3 path=$4 token=$5 body=$6
+  local status
+  if [ -n "$body" ]; then
+    status=$(curl -s -o /dev/null -w "%{http_code}" -X "$method" "$BASE_URL$path" \
+      -H "Authorization: Bearer $token" -H "Content-Type: application/json" -d "$body")
+  else
+    status=$(curl -s -o /dev/null -w "%{http_code}" -X "$method" "$BASE_URL$path" \
+      -H "Authorization: Bearer $token")
+  fi
+
+  if [ "$status" = "$expected" ]; then
+    echo "✅ $description ($status)"
+  else
+    echo "❌ $description: expected $expected, got $status"
+  fi
+}
+
+# login prints the token for an admin.
+# Usage: login email password
+login() {
+  curl -s -X POST "$BASE_URL/api/admin/login" \
+    -H "Content-Type: application/json" \
+    -d "{\"email\":\"$1\",\"password\":\"$2\"}" | grep -o '"token":"[^"]*"' | cut -d'"' -f4
+}
+
+# totp prints the TOTP code for a base32 secret, for the time step
+# offset_steps from now (default 0). Needs python3.
+# Usage: totp secret [offset_steps]
+totp() {
+  python3 - "$1" "${2:-0}" <<'PY'
+import base64, hashlib, hmac, struct, sys, time
+secret, offset = sys.argv[1], int(sys.argv[2])
+key = base64.b32decode(secret + "=" * (-len(secret) % 8))
+digest = hmac.new(key, struct.pack(">Q", int(time.time()) // 30 + offset), hashlib.sha1).digest()
+start = digest[-1] & 0x0f
+print("%06d" % ((struct.unpack(">I", digest[start:start + 4])[0] & 0x7fffffff) % 1000000))
+PY
+}
 
 echo "🧪 Testing Mumuni Backend API..."
 echo "=============================="
@@ -14,9 +73,11 @@ curl -s -o /dev/null -w "%{http_code}" "$BASE_URL/health" | grep -q "200" && ech
 
 # Test Admin Signup
 echo -e "\n2. Testing Admin Signup..."
+check_status "Signup without the bootstrap token is refused" 403 POST /api/admin/signup "" \
+  '{"email":"intruder@mumuni.com","password":"intruder123","name":"Intruder","bootstrap_token":"guess"}'
 ADMIN_SIGNUP_RESPONSE=$(curl -s -X POST "$BASE_URL/api/admin/signup" \
   -H "Content-Type: application/json" \
-  -d '{"email":"admin@mumuni.com","password":"admin123456","name":"Admin User"}')
+  -d "{\"email\":\"admin@mumuni.com\",\"password\":\"admin123456\",\"name\":\"Admin User\",\"bootstrap_token\":\"$BOOTSTRAP_TOKEN\"}")
 
 if echo "$ADMIN_SIGNUP_RESPONSE" | grep -q "success"; then
   echo "✅ Admin signup successful"
@@ -42,7 +103,7 @@ fi
 echo -e "\n4. Testing Appointment Booking..."
 APPOINTMENT_RESPONSE=$(curl -s -X POST "$BASE_URL/api/appointments" \
   -H "Content-Type: application/json" \
-  -d '{"name":"Sarah Johnson","email":"sarah@email.com","phone":"+234-123-456-7890","date":"2024-02-15","time":"2:00 PM","service":"Bridal Makeup","message":"Wedding on March 1st"}')
+  -d '{"name":"Sarah Johnson","email":"sarah@email.com","phone":"+234-123-456-7890","date":"'"$BOOKING_DATE"'","time":"2:00 PM","service":"Bridal Makeup","message":"Wedding on March 1st"}')
 
 if echo "$APPOINTMENT_RESPONSE" | grep -q "success"; then
   echo "✅ Appointment booking successful"
@@ -87,5 +148,468 @@ if [ -n "$TOKEN" ]; then
   fi
 fi
 
+# Test Role-Based Access Control (if token available)
+# The first admin is the owner; staff join through the owner's invites.
+if [ -n "$TOKEN" ]; then
+  echo -e "\n7. Testing Role-Based Access Control..."
+
+  ME_RESPONSE=$(curl -s "$BASE_URL/api/admin/me" -H "Authorization: Bearer $TOKEN")
+  OWNER_ID=$(echo "$ME_RESPONSE" | grep -o '"id":"[^"]*"' | head -1 | cut -d'"' -f4)
+  if echo "$ME_RESPONSE" | grep -q '"role":"owner"'; then
+    echo "✅ First admin is the owner"
+  else
+    echo "❌ First admin is not the owner: $ME_RESPONSE"
+  fi
+
+  ARTIST_ID=$(curl -s -X POST "$BASE_URL/api/admin/artists" \
+    -H "Authorization: Bearer $TOKEN" -H "Content-Type: application/json" \
+    -d '{"name":"Test Artist","skills":["Bridal Makeup"]}' | grep -o '"id":[0-9]*' | head -1 | cut -d: -f2)
+
+  check_status "Signup is closed once there is an owner" 403 POST /api/admin/signup "" \
+    "{\"email\":\"staff@mumuni.com\",\"password\":\"staff123456\",\"name\":\"Staff User\",\"bootstrap_token\":\"$BOOTSTRAP_TOKEN\"}"
+  INVITE_TOKEN=$(curl -s -X POST "$BASE_URL/api/admin/invites" \
+    -H "Authorization: Bearer $TOKEN" -H "Content-Type: application/json" \
+    -d '{"email":"staff@mumuni.com","role":"receptionist"}' | grep -o '"token":"[^"]*"' | cut -d'"' -f4)
+  curl -s -X POST "$BASE_URL/api/admin/register/$INVITE_TOKEN" \
+    -H "Content-Type: application/json" \
+    -d '{"password":"staff123456","name":"Staff User"}' > /dev/null
+  STAFF_TOKEN=$(login staff@mumuni.com staff123456)
+  STAFF_ID=$(curl -s "$BASE_URL/api/admin/me" -H "Authorization: Bearer $STAFF_TOKEN" | grep -o '"id":"[^"]*"' | head -1 | cut -d'"' -f4)
+
+  # Receptionist
+  check_status "Receptionist can list appointments" 200 GET /api/admin/appointments "$STAFF_TOKEN"
+  check_status "Receptionist can list classes" 200 GET /api/admin/classes "$STAFF_TOKEN"
+  check_status "Receptionist cannot list admins" 403 GET /api/admin/admins "$STAFF_TOKEN"
+  check_status "Receptionist cannot change roles" 403 PUT "/api/admin/admins/$STAFF_ID/role" "$STAFF_TOKEN" '{"role":"owner"}'
+  check_status "Receptionist cannot manage services" 403 POST /api/admin/services "$STAFF_TOKEN" '{"name":"X"}'
+  check_status "Receptionist cannot manage webhooks" 403 GET /api/admin/webhooks "$STAFF_TOKEN"
+
+  # Artist
+  check_status "Artist role needs an artist" 400 PUT "/api/admin/admins/$STAFF_ID/role" "$TOKEN" '{"role":"artist"}'
+  check_status "Owner makes staff an artist" 200 PUT "/api/admin/admins/$STAFF_ID/role" "$TOKEN" "{\"role\":\"artist\",\"artist_id\":$ARTIST_ID}"
+  STAFF_TOKEN=$(login staff@mumuni.com staff123456)
+  check_status "Artist cannot list classes" 403 GET /api/admin/classes "$STAFF_TOKEN"
+  check_status "Artist cannot see waitlist" 403 GET /api/admin/waitlist "$STAFF_TOKEN"
+  check_status "Artist cannot see unassigned appointment history" 404 GET /api/admin/appointments/1/history "$STAFF_TOKEN"
+  ARTIST_APPOINTMENTS=$(curl -s "$BASE_URL/api/admin/appointments" -H "Authorization: Bearer $STAFF_TOKEN")
+  if echo "$ARTIST_APPOINTMENTS" | grep -q '"artist_id":null'; then
+    echo "❌ Artist sees appointments that aren't theirs: $ARTIST_APPOINTMENTS"
+  else
+    echo "✅ Artist only sees their own appointments"
+  fi
+
+  # Instructor
+  check_status "Owner makes staff an instructor" 200 PUT "/api/admin/admins/$STAFF_ID/role" "$TOKEN" '{"role":"instructor"}'
+  STAFF_TOKEN=$(login staff@mumuni.com staff123456)
+  check_status "Instructor can list classes" 200 GET /api/admin/classes "$STAFF_TOKEN"
+  check_status "Instructor can list cohorts" 200 GET /api/admin/cohorts "$STAFF_TOKEN"
+  check_status "Instructor cannot list appointments" 403 GET /api/admin/appointments "$STAFF_TOKEN"
+
+  # Owner
+  check_status "Owner can list admins" 200 GET /api/admin/admins "$TOKEN"
+  check_status "Last owner cannot step down" 409 PUT "/api/admin/admins/$OWNER_ID/role" "$TOKEN" '{"role":"manager"}'
+  check_status "Unknown roles are refused" 400 PUT "/api/admin/admins/$STAFF_ID/role" "$TOKEN" '{"role":"superuser"}'
+  check_status "Requests without a token are refused" 401 GET /api/admin/appointments ""
+fi
+
+# Test Admin Invites (if token available)
+if [ -n "$TOKEN" ]; then
+  echo -e "\n8. Testing Admin Invites..."
+
+  INVITE_RESPONSE=$(curl -s -X POST "$BASE_URL/api/admin/invites" \
+    -H "Authorization: Bearer $TOKEN" -H "Content-Type: application/json" \
+    -d '{"email":"manager@mumuni.com","role":"manager"}')
+  INVITE_TOKEN=$(echo "$INVITE_RESPONSE" | grep -o '"token":"[^"]*"' | cut -d'"' -f4)
+  if [ -n "$INVITE_TOKEN" ]; then
+    echo "✅ Owner created an invite"
+  else
+    echo "❌ Owner could not create an invite: $INVITE_RESPONSE"
+  fi
+
+  check_status "Staff cannot create invites" 403 POST /api/admin/invites "$STAFF_TOKEN" '{"email":"x@mumuni.com","role":"owner"}'
+  check_status "Existing admins cannot be invited" 409 POST /api/admin/invites "$TOKEN" '{"email":"staff@mumuni.com","role":"manager"}'
+  check_status "Artist invites need an artist" 400 POST /api/admin/invites "$TOKEN" '{"email":"artist@mumuni.com","role":"artist"}'
+  check_status "Invite can be previewed" 200 GET "/api/admin/register/$INVITE_TOKEN" ""
+  check_status "Unknown invites are refused" 404 POST /api/admin/register/not-a-token "" '{"password":"manager123","name":"Manager"}'
+  check_status "Invite registers a manager" 201 POST "/api/admin/register/$INVITE_TOKEN" "" '{"password":"manager123","name":"Manager"}'
+  check_status "Invite cannot be used twice" 404 POST "/api/admin/register/$INVITE_TOKEN" "" '{"password":"manager123","name":"Manager"}'
+  if login manager@mumuni.com manager123 | grep -q .; then
+    echo "✅ Invited manager can log in"
+  else
+    echo "❌ Invited manager cannot log in"
+  fi
+
+  REVOKED_RESPONSE=$(curl -s -X POST "$BASE_URL/api/admin/invites" \
+    -H "Authorization: Bearer $TOKEN" -H "Content-Type: application/json" \
+    -d '{"email":"revoked@mumuni.com","role":"receptionist"}')
+  REVOKED_ID=$(echo "$REVOKED_RESPONSE" | grep -o '"id":[0-9]*' | head -1 | cut -d: -f2)
+  REVOKED_TOKEN=$(echo "$REVOKED_RESPONSE" | grep -o '"token":"[^"]*"' | cut -d'"' -f4)
+  check_status "Owner revokes an invite" 200 DELETE "/api/admin/invites/$REVOKED_ID" "$TOKEN"
+  check_status "Revoked invites cannot be used" 404 POST "/api/admin/register/$REVOKED_TOKEN" "" '{"password":"revoked123","name":"Revoked"}'
+  check_status "Owner can list invites" 200 GET /api/admin/invites "$TOKEN"
+fi
+
+# Test Sessions (if token available)
+if [ -n "$TOKEN" ]; then
+  echo -e "\n9. Testing Sessions..."
+
+  SESSION_RESPONSE=$(curl -s -X POST "$BASE_URL/api/admin/login" \
+    -H "Content-Type: application/json" \
+    -d '{"email":"manager@mumuni.com","password":"manager123"}')
+  SESSION_TOKEN=$(echo "$SESSION_RESPONSE" | grep -o '"token":"[^"]*"' | cut -d'"' -f4)
+  REFRESH_TOKEN=$(echo "$SESSION_RESPONSE" | grep -o '"refresh_token":"[^"]*"' | cut -d'"' -f4)
+  check_status "Access token works" 200 GET /api/admin/me "$SESSION_TOKEN"
+
+  REFRESH_RESPONSE=$(curl -s -X POST "$BASE_URL/api/admin/refresh" \
+    -H "Content-Type: application/json" \
+    -d "{\"refresh_token\":\"$REFRESH_TOKEN\"}")
+  NEW_TOKEN=$(echo "$REFRESH_RESPONSE" | grep -o '"token":"[^"]*"' | cut -d'"' -f4)
+  NEW_REFRESH_TOKEN=$(echo "$REFRESH_RESPONSE" | grep -o '"refresh_token":"[^"]*"' | cut -d'"' -f4)
+  OTHER_SESSION_ID=$(echo "$REFRESH_RESPONSE" | grep -o '"session_id":"[^"]*"' | cut -d'"' -f4)
+  if [ -n "$NEW_TOKEN" ] && [ "$NEW_REFRESH_TOKEN" != "$REFRESH_TOKEN" ]; then
+    echo "✅ Refresh token swapped for new tokens"
+  else
+    echo "❌ Refresh failed: $REFRESH_RESPONSE"
+  fi
+  check_status "Replaced access token is revoked" 401 GET /api/admin/me "$SESSION_TOKEN"
+  check_status "New access token works" 200 GET /api/admin/me "$NEW_TOKEN"
+  check_status "Refresh token cannot be used twice" 401 POST /api/admin/refresh "" "{\"refresh_token\":\"$REFRESH_TOKEN\"}"
+  check_status "Reused refresh token ends its session" 401 GET /api/admin/me "$NEW_TOKEN"
+  check_status "Reused refresh token's session cannot be refreshed" 401 POST /api/admin/refresh "" "{\"refresh_token\":\"$NEW_REFRESH_TOKEN\"}"
+
+  SESSION_RESPONSE=$(curl -s -X POST "$BASE_URL/api/admin/login" \
+    -H "Content-Type: application/json" \
+    -d '{"email":"manager@mumuni.com","password":"manager123"}')
+  NEW_TOKEN=$(echo "$SESSION_RESPONSE" | grep -o '"token":"[^"]*"' | cut -d'"' -f4)
+  NEW_REFRESH_TOKEN=$(echo "$SESSION_RESPONSE" | grep -o '"refresh_token":"[^"]*"' | cut -d'"' -f4)
+  OTHER_SESSION_ID=$(echo "$SESSION_RESPONSE" | grep -o '"session_id":"[^"]*"' | cut -d'"' -f4)
+  OTHER_TOKEN=$(login manager@mumuni.com manager123)
+  SESSIONS_RESPONSE=$(curl -s "$BASE_URL/api/admin/sessions" -H "Authorization: Bearer $OTHER_TOKEN")
+  if echo "$SESSIONS_RESPONSE" | grep -q "\"id\":\"$OTHER_SESSION_ID\"" && echo "$SESSIONS_RESPONSE" | grep -q '"current":true'; then
+    echo "✅ Sessions are listed"
+  else
+    echo "❌ Sessions not listed: $SESSIONS_RESPONSE"
+  fi
+  check_status "Sessions of other admins cannot be ended" 404 DELETE "/api/admin/sessions/$OTHER_SESSION_ID" "$TOKEN"
+  check_status "Admin ends their other session" 200 DELETE "/api/admin/sessions/$OTHER_SESSION_ID" "$OTHER_TOKEN"
+  check_status "Ended session's token is revoked" 401 GET /api/admin/me "$NEW_TOKEN"
+  check_status "Ended session cannot be refreshed" 401 POST /api/admin/refresh "" "{\"refresh_token\":\"$NEW_REFRESH_TOKEN\"}"
+
+  check_status "Admin logs out" 200 POST /api/admin/logout "$OTHER_TOKEN"
+  check_status "Token is revoked after logout" 401 GET /api/admin/me "$OTHER_TOKEN"
+fi
+
+# Test Passwords (if token available)
+if [ -n "$TOKEN" ]; then
+  echo -e "\n10. Testing Passwords..."
+
+  MANAGER_TOKEN=$(login manager@mumuni.com manager123)
+  SECOND_TOKEN=$(login manager@mumuni.com manager123)
+  check_status "Wrong current password is refused" 401 PUT /api/admin/password "$MANAGER_TOKEN" \
+    '{"current_password":"wrong-password","new_password":"Manager-2024"}'
+  check_status "Weak new password is refused" 400 PUT /api/admin/password "$MANAGER_TOKEN" \
+    '{"current_password":"manager123","new_password":"short"}'
+  check_status "Single-class new password is refused" 400 PUT /api/admin/password "$MANAGER_TOKEN" \
+    '{"current_password":"manager123","new_password":"onlylowercaseletters"}'
+  check_status "Admin changes their password" 200 PUT /api/admin/password "$MANAGER_TOKEN" \
+    '{"current_password":"manager123","new_password":"Manager-2024"}'
+  check_status "Current session stays signed in" 200 GET /api/admin/me "$MANAGER_TOKEN"
+  check_status "Other sessions are ended" 401 GET /api/admin/me "$SECOND_TOKEN"
+  if login manager@mumuni.com Manager-2024 | grep -q .; then
+    echo "✅ New password works"
+  else
+    echo "❌ New password does not work"
+  fi
+
+  check_status "Forgot password answers for admins" 200 POST /api/admin/password/forgot "" '{"email":"manager@mumuni.com"}'
+  check_status "Forgot password answers the same for strangers" 200 POST /api/admin/password/forgot "" '{"email":"nobody@mumuni.com"}'
+  check_status "Unknown reset tokens are refused" 400 POST /api/admin/password/reset "" \
+    '{"token":"not-a-token","new_password":"Manager-2025"}'
+fi
+
+# Test Two-Factor Authentication (if token available)
+if [ -n "$TOKEN" ]; then
+  echo -e "\n11. Testing Two-Factor Authentication..."
+
+  MANAGER_TOKEN=$(login manager@mumuni.com Manager-2024)
+  SECRET=$(curl -s -X POST "$BASE_URL/api/admin/2fa/setup" -H "Authorization: Bearer $MANAGER_TOKEN" \
+    | grep -o '"secret":"[^"]*"' | cut -d'"' -f4)
+  check_status "Wrong code does not turn on two-factor" 401 POST /api/admin/2fa/confirm "$MANAGER_TOKEN" '{"code":"abcdef"}'
+  USED_CODE=$(totp "$SECRET")
+  CONFIRM_RESPONSE=$(curl -s -X POST "$BASE_URL/api/admin/2fa/confirm" \
+    -H "Authorization: Bearer $MANAGER_TOKEN" -H "Content-Type: application/json" \
+    -d "{\"code\":\"$USED_CODE\"}")
+  RECOVERY_CODE=$(echo "$CONFIRM_RESPONSE" | grep -o '"recovery_codes":\["[^"]*"' | cut -d'"' -f4)
+  if [ -n "$RECOVERY_CODE" ]; then
+    echo "✅ Code turns on two-factor and gives recovery codes"
+  else
+    echo "❌ Two-factor not turned on: $CONFIRM_RESPONSE"
+  fi
+
+  # challenge prints a challenge token for the manager's password.
+  challenge() {
+    curl -s -X POST "$BASE_URL/api/admin/login" \
+      -H "Content-Type: application/json" \
+      -d '{"email":"manager@mumuni.com","password":"Manager-2024"}' | grep -o '"challenge_token":"[^"]*"' | cut -d'"' -f4
+  }
+  CHALLENGE_TOKEN=$(challenge)
+  if [ -n "$CHALLENGE_TOKEN" ] && [ -z "$(login manager@mumuni.com Manager-2024)" ]; then
+    echo "✅ Password alone gives a challenge, not a token"
+  else
+    echo "❌ Password alone did not give a challenge"
+  fi
+  check_status "Challenge token is not an access token" 401 GET /api/admin/me "$CHALLENGE_TOKEN"
+  check_status "Used code cannot log in" 401 POST /api/admin/login/2fa "" \
+    "{\"challenge_token\":\"$CHALLENGE_TOKEN\",\"code\":\"$USED_CODE\"}"
+  check_status "Next code logs in" 200 POST /api/admin/login/2fa "" \
+    "{\"challenge_token\":\"$CHALLENGE_TOKEN\",\"code\":\"$(totp "$SECRET" 1)\"}"
+  check_status "Recovery code logs in" 200 POST /api/admin/login/2fa "" \
+    "{\"challenge_token\":\"$(challenge)\",\"recovery_code\":\"$RECOVERY_CODE\"}"
+  check_status "Recovery code works once" 401 POST /api/admin/login/2fa "" \
+    "{\"challenge_token\":\"$(challenge)\",\"recovery_code\":\"$RECOVERY_CODE\"}"
+  check_status "Owner resets the manager's two-factor" 200 DELETE "/api/admin/admins/$(curl -s "$BASE_URL/api/admin/me" \
+    -H "Authorization: Bearer $MANAGER_TOKEN" | grep -o '"id":"[^"]*"' | head -1 | cut -d'"' -f4)/2fa" "$TOKEN"
+  if login manager@mumuni.com Manager-2024 | grep -q .; then
+    echo "✅ Password alone logs in after a reset"
+  else
+    echo "❌ Password alone does not log in after a reset"
+  fi
+
+  check_status "Staff cannot see security settings" 403 GET /api/admin/security "$STAFF_TOKEN"
+  check_status "Owner without two-factor cannot require it" 409 PUT /api/admin/security "$TOKEN" '{"require_two_factor":true}'
+  OWNER_SECRET=$(curl -s -X POST "$BASE_URL/api/admin/2fa/setup" -H "Authorization: Bearer $TOKEN" \
+    | grep -o '"secret":"[^"]*"' | cut -d'"' -f4)
+  check_status "Owner turns on two-factor" 200 POST /api/admin/2fa/confirm "$TOKEN" "{\"code\":\"$(totp "$OWNER_SECRET")\"}"
+  check_status "Owner requires two-factor" 200 PUT /api/admin/security "$TOKEN" '{"require_two_factor":true}'
+  SETUP_RESPONSE=$(curl -s -X POST "$BASE_URL/api/admin/login" \
+    -H "Content-Type: application/json" \
+    -d '{"email":"staff@mumuni.com","password":"staff123456"}')
+  SETUP_TOKEN=$(echo "$SETUP_RESPONSE" | grep -o '"token":"[^"]*"' | cut -d'"' -f4)
+  if echo "$SETUP_RESPONSE" | grep -q '"two_factor_setup_required":true'; then
+    echo "✅ Admin without two-factor is told to set it up"
+  else
+    echo "❌ Admin without two-factor not told to set it up: $SETUP_RESPONSE"
+  fi
+  check_status "Setup-only token reaches the account" 200 GET /api/admin/me "$SETUP_TOKEN"
+  check_status "Setup-only token reaches nothing else" 403 GET /api/admin/classes "$SETUP_TOKEN"
+  check_status "Two-factor cannot be turned off while required" 409 POST /api/admin/2fa/disable "$TOKEN" \
+    "{\"password\":\"admin123456\",\"code\":\"$(totp "$OWNER_SECRET" 1)\"}"
+  check_status "Owner stops requiring two-factor" 200 PUT /api/admin/security "$TOKEN" '{"require_two_factor":false}'
+  check_status "Owner turns off two-factor" 200 POST /api/admin/2fa/disable "$TOKEN" \
+    "{\"password\":\"admin123456\",\"code\":\"$(totp "$OWNER_SECRET" 1)\"}"
+fi
+
+# Test Login Lockout (if token available)
+if [ -n "$TOKEN" ]; then
+  echo -e "\n12. Testing Login Lockout..."
+
+  WRONG_LOGIN='{"email":"staff@mumuni.com","password":"wrong-password"}'
+  check_status "First wrong password is refused" 401 POST /api/admin/login "" "$WRONG_LOGIN"
+  check_status "Second wrong password is refused" 401 POST /api/admin/login "" "$WRONG_LOGIN"
+  BLOCKED_HEADERS=$(curl -s -o /dev/null -D - -X POST "$BASE_URL/api/admin/login" \
+    -H "Content-Type: application/json" -d '{"email":"staff@mumuni.com","password":"staff123456"}')
+  if echo "$BLOCKED_HEADERS" | grep -q "429" && echo "$BLOCKED_HEADERS" | grep -qi "Retry-After"; then
+    echo "✅ Repeated failures hold off the next attempt"
+  else
+    echo "❌ Repeated failures did not hold off the next attempt: $BLOCKED_HEADERS"
+  fi
+  check_status "Staff cannot unlock admins" 403 POST "/api/admin/admins/$STAFF_ID/unlock" "$STAFF_TOKEN"
+  check_status "Owner unlocks the admin" 200 POST "/api/admin/admins/$STAFF_ID/unlock" "$TOKEN"
+  if login staff@mumuni.com staff123456 | grep -q .; then
+    echo "✅ Unlocked admin can log in"
+  else
+    echo "❌ Unlocked admin cannot log in"
+  fi
+
+  ATTEMPTS_RESPONSE=$(curl -s "$BASE_URL/api/admin/login-attempts?email=staff@mumuni.com&result=blocked" \
+    -H "Authorization: Bearer $TOKEN")
+  if echo "$ATTEMPTS_RESPONSE" | grep -q '"result":"blocked"'; then
+    echo "✅ Blocked attempts are recorded"
+  else
+    echo "❌ Blocked attempts not recorded: $ATTEMPTS_RESPONSE"
+  fi
+  check_status "Unknown attempt results are refused" 400 GET "/api/admin/login-attempts?result=maybe" "$TOKEN"
+fi
+
+# Test Spam Protection
+# Expects the default BOOKING_CONTACT_LIMIT of 5/1h and no BOOKING_VERIFIER.
+echo -e "\n13. Testing Spam Protection..."
+check_status "Bookings with the honeypot filled in look accepted" 201 POST /api/classes "" \
+  '{"name":"Spam Bot","email":"bot@spam.example","phone":"+1-202-555-0100","classType":"Beginner Basics","experience":"Complete Beginner","schedule":"Weekends","website":"http://spam.example"}'
+if [ -n "$TOKEN" ]; then
+  BOT_CLASSES=$(curl -s "$BASE_URL/api/admin/classes" -H "Authorization: Bearer $TOKEN")
+  if echo "$BOT_CLASSES" | grep -q "bot@spam.example"; then
+    echo "❌ Honeypot booking was stored"
+  else
+    echo "✅ Honeypot booking was not stored"
+  fi
+fi
+check_status "Identical enrollment is refused as a duplicate" 409 POST /api/classes "" \
+  '{"name":"Maria Garcia","email":"maria@email.com","phone":"+234-987-654-3210","classType":"Beginner Basics","experience":"Complete Beginner","goals":"Learn basic makeup","schedule":"Weekends"}'
+for i in 1 2 3 4 5; do
+  check_status "Refused enrollment $i still counts against the email" 400 POST /api/classes "" \
+    "{\"name\":\"Flood\",\"email\":\"flood@email.com\",\"phone\":\"+1-202-555-010$i\",\"classType\":\"Beginner Basics\",\"experience\":\"Expert\",\"schedule\":\"Weekends\"}"
+done
+FLOOD_HEADERS=$(curl -s -o /dev/null -D - -X POST "$BASE_URL/api/classes" -H "Content-Type: application/json" \
+  -d '{"name":"Flood","email":"flood@email.com","phone":"+1-202-555-0109","classType":"Beginner Basics","experience":"Expert","schedule":"Weekends"}')
+if echo "$FLOOD_HEADERS" | grep -q "429" && echo "$FLOOD_HEADERS" | grep -qi "Retry-After"; then
+  echo "✅ Too many bookings for one email are rate limited"
+else
+  echo "❌ Too many bookings for one email were not rate limited: $FLOOD_HEADERS"
+fi
+
+# Test Idempotency Keys
+echo -e "\n14. Testing Idempotency Keys..."
+IDEMPOTENT_BODY='{"name":"Retry Ruth","email":"ruth@email.com","phone":"+234-555-000-1111","classType":"Beginner Basics","experience":"Some Experience","schedule":"Flexible"}'
+FIRST_RESPONSE=$(curl -s -X POST "$BASE_URL/api/classes" -H "Content-Type: application/json" \
+  -H "Idempotency-Key: enroll-ruth-1" -d "$IDEMPOTENT_BODY")
+RETRY_HEADERS=$(curl -s -D /tmp/mumuni_retry_headers -X POST "$BASE_URL/api/classes" -H "Content-Type: application/json" \
+  -H "Idempotency-Key: enroll-ruth-1" -d "$IDEMPOTENT_BODY")
+FIRST_ID=$(echo "$FIRST_RESPONSE" | grep -o '"id":[0-9]*' | head -1)
+RETRY_ID=$(echo "$RETRY_HEADERS" | grep -o '"id":[0-9]*' | head -1)
+if [ -n "$FIRST_ID" ] && [ "$RETRY_ID" = "$FIRST_ID" ] && grep -qi "Idempotent-Replayed: true" /tmp/mumuni_retry_headers; then
+  echo "✅ Retry with the same key replays the first response"
+else
+  echo "❌ Retry with the same key was not replayed: $RETRY_HEADERS"
+fi
+if echo "$FIRST_RESPONSE" | grep -q '"manage_token"' && ! echo "$RETRY_HEADERS" | grep -q '"manage_token"'; then
+  echo "✅ Replays leave out the manage token, which isn't stored"
+else
+  echo "❌ Replay handling of the manage token is wrong: $RETRY_HEADERS"
+fi
+rm -f /tmp/mumuni_retry_headers
+RUTH_CLASSES=$(curl -s "$BASE_URL/api/admin/classes?email=ruth@email.com" -H "Authorization: Bearer $TOKEN")
+if echo "$RUTH_CLASSES" | grep -q '"count":1'; then
+  echo "✅ Retry did not enroll twice"
+else
+  echo "❌ Retry enrolled twice: $RUTH_CLASSES"
+fi
+RETRY_STATUS=$(curl -s -o /dev/null -w "%{http_code}" -X POST "$BASE_URL/api/classes" -H "Content-Type: application/json" \
+  -H "Idempotency-Key: enroll-ruth-1" -d '{"name":"Someone Else","email":"else@email.com","phone":"+234-555-000-2222","classType":"Beginner Basics","experience":"Some Experience","schedule":"Flexible"}')
+if [ "$RETRY_STATUS" = "409" ]; then
+  echo "✅ Same key with a different payload is refused (409)"
+else
+  echo "❌ Same key with a different payload: expected 409, got $RETRY_STATUS"
+fi
+
+if [ -n "$TOKEN" ]; then
+  RUTH_ID=$(echo "$FIRST_RESPONSE" | grep -o '"id":[0-9]*' | head -1 | cut -d: -f2)
+  for attempt in first retried; do
+    STATUS_CODE=$(curl -s -o /dev/null -w "%{http_code}" -X PUT "$BASE_URL/api/admin/classes/$RUTH_ID/status" \
+      -H "Authorization: Bearer $TOKEN" -H "Content-Type: application/json" -H "Idempotency-Key: confirm-ruth-1" \
+      -d '{"status":"confirmed"}')
+    if [ "$STATUS_CODE" = "200" ]; then
+      echo "✅ The $attempt status update succeeds (200)"
+    else
+      echo "❌ The $attempt status update: expected 200, got $STATUS_CODE"
+    fi
+  done
+fi
+
+# Test Customers
+# Bookings are linked to a customer by email, so Sarah's enrollment joins
+# the customer made by her appointment in step 4.
+echo -e "\n15. Testing Customers..."
+SARAH_CLASS=$(curl -s -X POST "$BASE_URL/api/classes" -H "Content-Type: application/json" \
+  -d '{"name":"Sarah Johnson","email":"Sarah@Email.com","phone":"+234-123-456-7890","classType":"Advanced Techniques","experience":"Intermediate","schedule":"Weekdays"}')
+DUPLICATE_CLASS=$(curl -s -X POST "$BASE_URL/api/classes" -H "Content-Type: application/json" \
+  -d '{"name":"Sarah J.","email":"sarah.j@email.com","phone":"+234-555-000-4444","classType":"Bridal Specialist","experience":"Intermediate","schedule":"Weekdays"}')
+
+if [ -n "$TOKEN" ]; then
+  SARAH_ID=$(echo "$SARAH_CLASS" | grep -o '"customer_id":[0-9]*' | cut -d: -f2)
+  DUPLICATE_ID=$(echo "$DUPLICATE_CLASS" | grep -o '"customer_id":[0-9]*' | cut -d: -f2)
+  CUSTOMERS=$(curl -s "$BASE_URL/api/admin/customers?q=sarah@email.com" -H "Authorization: Bearer $TOKEN")
+  if [ -n "$SARAH_ID" ] && echo "$CUSTOMERS" | grep -q "\"customers\":\[{\"id\":$SARAH_ID,"; then
+    echo "✅ Customer search found Sarah"
+  else
+    echo "❌ Customer search failed: $CUSTOMERS"
+  fi
+
+  PROFILE=$(curl -s "$BASE_URL/api/admin/customers/$SARAH_ID" -H "Authorization: Bearer $TOKEN")
+  if echo "$PROFILE" | grep -q '"appointments":\[{' && echo "$PROFILE" | grep -q '"classes":\[{' \
+    && echo "$PROFILE" | grep -q '"lifetime_value"'; then
+    echo "✅ Customer profile has appointments, classes and lifetime value"
+  else
+    echo "❌ Customer profile is incomplete: $PROFILE"
+  fi
+
+  NOTES_RESPONSE=$(curl -s -X PUT "$BASE_URL/api/admin/customers/$SARAH_ID" -H "Authorization: Bearer $TOKEN" \
+    -H "Content-Type: application/json" \
+    -d '{"name":"Sarah Johnson","email":"sarah@email.com","phone":"+2341234567890","notes":"Allergic to latex"}')
+  if echo "$NOTES_RESPONSE" | grep -q '"notes":"Allergic to latex"'; then
+    echo "✅ Customer notes updated"
+  else
+    echo "❌ Customer notes update failed: $NOTES_RESPONSE"
+  fi
+
+  MERGE_RESPONSE=$(curl -s -X POST "$BASE_URL/api/admin/customers/$SARAH_ID/merge" -H "Authorization: Bearer $TOKEN" \
+    -H "Content-Type: application/json" -d "{\"customer_id\":$DUPLICATE_ID}")
+  if echo "$MERGE_RESPONSE" | grep -q '"class_type":"Bridal Specialist"'; then
+    echo "✅ Duplicate customer merged with their bookings"
+  else
+    echo "❌ Customer merge failed: $MERGE_RESPONSE"
+  fi
+  MERGED_STATUS=$(curl -s -o /dev/null -w "%{http_code}" "$BASE_URL/api/admin/customers/$DUPLICATE_ID" \
+    -H "Authorization: Bearer $TOKEN")
+  if [ "$MERGED_STATUS" = "404" ]; then
+    echo "✅ Merged customer is gone (404)"
+  else
+    echo "❌ Merged customer: expected 404, got $MERGED_STATUS"
+  fi
+  SELF_MERGE_STATUS=$(curl -s -o /dev/null -w "%{http_code}" -X POST "$BASE_URL/api/admin/customers/$SARAH_ID/merge" \
+    -H "Authorization: Bearer $TOKEN" -H "Content-Type: application/json" -d "{\"customer_id\":$SARAH_ID}")
+  if [ "$SELF_MERGE_STATUS" = "400" ]; then
+    echo "✅ Merging a customer into itself is refused (400)"
+  else
+    echo "❌ Self merge: expected 400, got $SELF_MERGE_STATUS"
+  fi
+fi
+
+# Test Phone Numbers
+# Expects the default DEFAULT_PHONE_COUNTRY of NG.
+echo -e "\n16. Testing Phone Numbers..."
+NATIONAL_RESPONSE=$(curl -s -X POST "$BASE_URL/api/classes" -H "Content-Type: application/json" \
+  -d '{"name":"Ngozi Local","email":"ngozi@email.com","phone":"0803 123 4567","classType":"Beginner Basics","experience":"Complete Beginner","schedule":"Weekends"}')
+if echo "$NATIONAL_RESPONSE" | grep -q '"phone":"+2348031234567"'; then
+  echo "✅ National phone number stored in E.164"
+else
+  echo "❌ National phone number was not normalized: $NATIONAL_RESPONSE"
+fi
+INVALID_PHONE_RESPONSE=$(curl -s -w "\n%{http_code}" -X POST "$BASE_URL/api/classes" -H "Content-Type: application/json" \
+  -d '{"name":"Bad Number","email":"bad.number@email.com","phone":"0803-12","classType":"Beginner Basics","experience":"Complete Beginner","schedule":"Weekends"}')
+if [ "$(echo "$INVALID_PHONE_RESPONSE" | tail -1)" = "400" ] && echo "$INVALID_PHONE_RESPONSE" | grep -q "Invalid phone number"; then
+  echo "✅ Invalid phone number rejected (400)"
+else
+  echo "❌ Invalid phone number: expected 400, got $INVALID_PHONE_RESPONSE"
+fi
+
+if [ -n "$TOKEN" ]; then
+  PHONE_FILTER=$(curl -s "$BASE_URL/api/admin/classes?phone=%2B234%20803%20123%204567" -H "Authorization: Bearer $TOKEN")
+  if echo "$PHONE_FILTER" | grep -q '"email":"ngozi@email.com"'; then
+    echo "✅ Phone filter matches however the number is written"
+  else
+    echo "❌ Phone filter failed: $PHONE_FILTER"
+  fi
+fi
+
+# Test Booking Dates and Times
+# Expects the default BOOKING_MAX_ADVANCE_DAYS of 180.
+echo -e "\n17. Testing Booking Dates and Times..."
+if echo "$APPOINTMENT_RESPONSE" | grep -q "\"starts_at\":\"${BOOKING_DATE}T[0-9:]*Z\""; then
+  echo "✅ Appointment has an RFC 3339 starts_at"
+else
+  echo "❌ Appointment starts_at missing: $APPOINTMENT_RESPONSE"
+fi
+for case in "past date|2024-02-15|2:00 PM" "unreadable time|$BOOKING_DATE|lunchtime" "date too far ahead|$(future_weekday 400)|2:00 PM"; do
+  IFS='|' read -r description date clock <<< "$case"
+  STATUS_CODE=$(curl -s -o /dev/null -w "%{http_code}" -X POST "$BASE_URL/api/appointments" -H "Content-Type: application/json" \
+    -d '{"name":"Tina Time","email":"tina@email.com","phone":"+234-555-000-5555","date":"'"$date"'","time":"'"$clock"'","service":"Everyday Glam"}')
+  if [ "$STATUS_CODE" = "400" ]; then
+    echo "✅ Booking with a $description is rejected (400)"
+  else
+    echo "❌ Booking with a $description: expected 400, got $STATUS_CODE"
+  fi
+done
+
 echo -e "\n=============================="
 echo "✅ API testing completed!"
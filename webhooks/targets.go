@@ -0,0 +1,108 @@
+package webhooks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"syscall"
+)
+
+// ErrLocalAddress is returned for webhook URLs that reach the server's own
+// network: loopback, private, link-local and other addresses that aren't
+// on the public internet.
+var ErrLocalAddress = errors.New("webhook URL must not reach a local or private address")
+
+// nonPublicRanges are ranges that publicAddress's netip.Addr checks miss
+// but that aren't on the public internet either.
+var nonPublicRanges = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),     // "this" network
+	netip.MustParsePrefix("100.64.0.0/10"), // carrier-grade NAT
+	netip.MustParsePrefix("192.0.0.0/24"),  // IETF protocol assignments
+	netip.MustParsePrefix("198.18.0.0/15"), // benchmarking
+	netip.MustParsePrefix("240.0.0.0/4"),   // reserved, and broadcast
+	netip.MustParsePrefix("64:ff9b::/96"),  // NAT64, which can reach any IPv4 address
+	netip.MustParsePrefix("2002::/16"),     // 6to4, likewise
+}
+
+// publicAddress reports whether ip is an address on the public internet.
+func publicAddress(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	if !ip.IsValid() || ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() || ip.IsMulticast() {
+		return false
+	}
+	for _, prefix := range nonPublicRanges {
+		if prefix.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// CheckURL checks that rawURL can be subscribed to: an https URL whose host
+// resolves only to public addresses. With allowLocal, for development, http
+// URLs and local addresses are allowed too.
+func CheckURL(ctx context.Context, rawURL string, allowLocal bool) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return errors.New("webhook URL must be an absolute URL")
+	}
+	switch {
+	case u.Scheme == "https":
+	case u.Scheme == "http" && allowLocal:
+	default:
+		return errors.New("webhook URL must be https")
+	}
+	if u.User != nil {
+		return errors.New("webhook URL must not contain credentials")
+	}
+	if allowLocal {
+		return nil
+	}
+
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", u.Hostname())
+	if err != nil {
+		return fmt.Errorf("webhook URL host %s can't be resolved", u.Hostname())
+	}
+	for _, addr := range addrs {
+		if !publicAddress(addr) {
+			return ErrLocalAddress
+		}
+	}
+	return nil
+}
+
+// newClient returns the HTTP client deliveries are sent with. Unless
+// allowLocal is set it refuses to connect to anything but a public address,
+// checked as each connection is made so that a host which resolved to a
+// public address when it was subscribed can't be pointed elsewhere later.
+// Redirects aren't followed.
+func newClient(allowLocal bool) *http.Client {
+	dialer := &net.Dialer{Timeout: requestTimeout}
+	if !allowLocal {
+		dialer.Control = func(network, address string, _ syscall.RawConn) error {
+			addrPort, err := netip.ParseAddrPort(address)
+			if err != nil || !publicAddress(addrPort.Addr()) {
+				return ErrLocalAddress
+			}
+			return nil
+		}
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// A proxy would be dialed instead of the subscriber, escaping the check
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	return &http.Client{
+		Timeout:   requestTimeout,
+		Transport: transport,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
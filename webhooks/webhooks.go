@@ -0,0 +1,112 @@
+// Package webhooks tells other systems, such as the website, a CRM or an
+// automation tool, about booking events. Publish records a delivery for every
+// subscription to the event, and a Worker POSTs them in the background,
+// signed with the subscription's secret and retried with backoff.
+package webhooks
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"mumuni_backend/database"
+	"mumuni_backend/models"
+	"strconv"
+	"time"
+)
+
+// Events subscriptions can choose from
+const (
+	AppointmentCreated       = "appointment.created"
+	AppointmentStatusChanged = "appointment.status_changed"
+	ClassCreated             = "class.created"
+	ClassStatusChanged       = "class.status_changed"
+)
+
+// Events lists every event, in the order they are documented.
+var Events = []string{AppointmentCreated, AppointmentStatusChanged, ClassCreated, ClassStatusChanged}
+
+// ValidEvent reports whether event is one of Events.
+func ValidEvent(event string) bool {
+	for _, e := range Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// Payload is the JSON body of every webhook. Data is the appointment or
+// class enrollment as the admin API returns it.
+type Payload struct {
+	Event      string    `json:"event"`
+	OccurredAt time.Time `json:"occurred_at"`
+	Data       any       `json:"data"`
+}
+
+// Publisher records webhook deliveries for booking events.
+type Publisher struct {
+	store database.Store
+}
+
+func NewPublisher(store database.Store) *Publisher {
+	return &Publisher{store: store}
+}
+
+// Publish queues event with data for every active subscription to it.
+// Failures are logged rather than returned, so a webhook problem never
+// fails a booking.
+func (p *Publisher) Publish(ctx context.Context, event string, data any) {
+	subscriptions, err := p.store.GetWebhookSubscriptions(ctx, true)
+	if err != nil {
+		log.Printf("Error getting webhook subscriptions for %s: %v", event, err)
+		return
+	}
+
+	var payload []byte
+	for _, subscription := range subscriptions {
+		if !subscribed(subscription, event) {
+			continue
+		}
+
+		if payload == nil {
+			payload, err = json.Marshal(Payload{Event: event, OccurredAt: time.Now().UTC(), Data: data})
+			if err != nil {
+				log.Printf("Error encoding %s webhook: %v", event, err)
+				return
+			}
+		}
+
+		_, err := p.store.CreateWebhookDelivery(ctx, &models.WebhookDelivery{
+			SubscriptionID: subscription.ID,
+			Event:          event,
+			Payload:        payload,
+		})
+		if err != nil {
+			log.Printf("Error queueing %s webhook for subscription %d: %v", event, subscription.ID, err)
+		}
+	}
+}
+
+func subscribed(subscription models.WebhookSubscription, event string) bool {
+	for _, e := range subscription.Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// Sign returns the X-Mumuni-Signature value for a webhook body sent at
+// timestamp (Unix seconds): "sha256=" followed by the hex HMAC-SHA256 of
+// "<timestamp>.<body>" keyed with the subscription's secret. Receivers
+// should recompute it, compare in constant time, and reject old timestamps.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,25 @@
+package webhooks
+
+import "testing"
+
+func TestSign(t *testing.T) {
+	body := []byte(`{"event":"appointment.created","data":{"id":12}}`)
+	tests := []struct {
+		secret    string
+		timestamp int64
+		body      []byte
+		want      string
+	}{
+		// HMAC-SHA256 of "1760779800.<body>", computed independently
+		{"a-webhook-secret-0123456789", 1760779800, body, "sha256=f774e8511aa8765a719ab3dfdf3ee94f0b6cb837869865b8fb5713718e097373"},
+		// The timestamp is signed too, so a replayed body can't be re-dated
+		{"a-webhook-secret-0123456789", 1760779801, body, "sha256=f4a17f2cb3eead90297023c0197cddfc36aa0143b2e35ef6feb7c8014098272f"},
+		// HMAC-SHA256 of "0."
+		{"key", 0, nil, "sha256=85841b4efc3cd7776c3c8f9b7cca9e281c550e5d19889d78e9e669c6337f000d"},
+	}
+	for _, tt := range tests {
+		if got := Sign(tt.secret, tt.timestamp, tt.body); got != tt.want {
+			t.Errorf("Sign(%q, %d, %q) = %s, want %s", tt.secret, tt.timestamp, tt.body, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mumuni_backend/database"
+	"mumuni_backend/models"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	// maxAttempts is how many times a delivery is tried before it is marked
+	// "failed" and left for an admin to retry.
+	maxAttempts = 10
+	// claimLease keeps a claimed delivery from being picked up by another
+	// instance while it is being sent.
+	claimLease = 5 * time.Minute
+	// batchSize is how many deliveries one pass claims.
+	batchSize = 20
+	// requestTimeout bounds one POST to a subscriber.
+	requestTimeout = 15 * time.Second
+)
+
+// Worker sends pending webhook deliveries, only to public addresses unless
+// allowLocal is set.
+type Worker struct {
+	store  database.Store
+	client *http.Client
+}
+
+func NewWorker(store database.Store, allowLocal bool) *Worker {
+	return &Worker{store: store, client: newClient(allowLocal)}
+}
+
+// Run sends due deliveries every interval until ctx is cancelled.
+func (w *Worker) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		w.Deliver(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Deliver sends every delivery that is due, batch by batch. Anything but a
+// 2xx response is retried with exponential backoff, starting at a minute and
+// capped at an hour.
+func (w *Worker) Deliver(ctx context.Context) {
+	for {
+		deliveries, err := w.store.ClaimWebhookDeliveries(ctx, time.Now(), claimLease, batchSize)
+		if err != nil {
+			log.Printf("Error claiming webhook deliveries: %v", err)
+			return
+		}
+
+		for i := range deliveries {
+			w.deliver(ctx, &deliveries[i])
+		}
+		if len(deliveries) < batchSize {
+			return
+		}
+	}
+}
+
+func (w *Worker) deliver(ctx context.Context, delivery *models.WebhookDelivery) {
+	responseStatus, err := w.send(ctx, delivery)
+	delivery.ResponseStatus = responseStatus
+
+	now := time.Now().UTC()
+	switch {
+	case err == nil:
+		delivery.Status = "delivered"
+		delivery.DeliveredAt = &now
+		delivery.LastError = nil
+	case errors.Is(err, database.ErrWebhookNotFound) || delivery.Attempts >= maxAttempts:
+		message := err.Error()
+		delivery.Status = "failed"
+		delivery.LastError = &message
+		log.Printf("Giving up on webhook delivery %d after %d attempts: %v", delivery.ID, delivery.Attempts, err)
+	default:
+		message := err.Error()
+		delivery.LastError = &message
+		delivery.NextAttemptAt = now.Add(retryDelay(delivery.Attempts))
+		log.Printf("Error sending webhook delivery %d, retrying at %s: %v", delivery.ID,
+			delivery.NextAttemptAt.Format(time.RFC3339), err)
+	}
+
+	if _, err := w.store.UpdateWebhookDelivery(ctx, delivery); err != nil {
+		log.Printf("Error updating webhook delivery %d: %v", delivery.ID, err)
+	}
+}
+
+// send POSTs a delivery to its subscription and returns the response status,
+// if there was a response.
+func (w *Worker) send(ctx context.Context, delivery *models.WebhookDelivery) (*int, error) {
+	subscription, err := w.store.GetWebhookSubscription(ctx, delivery.SubscriptionID)
+	if err != nil {
+		return nil, err
+	}
+	secret, err := w.store.GetWebhookSecret(ctx, delivery.SubscriptionID)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, subscription.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return nil, err
+	}
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Mumuni-Webhooks/1.0")
+	req.Header.Set("X-Mumuni-Event", delivery.Event)
+	req.Header.Set("X-Mumuni-Delivery", strconv.Itoa(delivery.ID))
+	req.Header.Set("X-Mumuni-Timestamp", strconv.FormatInt(timestamp, 10))
+	req.Header.Set("X-Mumuni-Signature", Sign(secret, timestamp, delivery.Payload))
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	status := resp.StatusCode
+	if status < 200 || status > 299 {
+		return &status, fmt.Errorf("endpoint returned %s", resp.Status)
+	}
+	return &status, nil
+}
+
+// retryDelay is how long to wait after the given number of failed attempts.
+func retryDelay(attempts int) time.Duration {
+	delay := time.Minute << (attempts - 1)
+	if delay > time.Hour || delay <= 0 {
+		return time.Hour
+	}
+	return delay
+}